@@ -3,15 +3,16 @@ package main
 import (
 	"encoding/json"
 	"flag"
+	"fmt"
 	"log"
 	"math/rand"
 	"net/http"
 	"strconv"
 	"time"
 
-	"github.com/ShiraazMoollatjie/goluhn"
 	"github.com/caarlos0/env/v6"
 	"github.com/danilovkiri/dk-go-gophermart/internal/api/rest/v1/middleware"
+	"github.com/danilovkiri/dk-go-gophermart/internal/ordernum"
 	"github.com/go-chi/chi"
 )
 
@@ -26,6 +27,16 @@ type Order struct {
 	Accrual float64 `json:"accrual,omitempty"`
 }
 
+type GeneratedOrders struct {
+	Orders []string `json:"orders"`
+}
+
+// Limits of the order number generation endpoint.
+const (
+	defaultGeneratedLength = 16
+	maxGeneratedCount      = 100
+)
+
 type ServerConfig struct {
 	ServerAddress string `env:"RUN_ADDRESS"`
 }
@@ -84,7 +95,7 @@ func HandleMockAccrualServcie() http.HandlerFunc {
 
 		// mock normal behaviour
 		orderID := chi.URLParam(r, "orderID")
-		orderNumber, err := strconv.Atoi(orderID)
+		orderNumber, err := ordernum.Parse(orderID)
 		if err != nil {
 			log.Println("responding with error 400")
 			w.WriteHeader(http.StatusBadRequest)
@@ -95,7 +106,7 @@ func HandleMockAccrualServcie() http.HandlerFunc {
 			w.Write(resBody)
 			return
 		}
-		err = goluhn.Validate(orderID)
+		err = ordernum.Validate(orderID)
 		if err != nil {
 			log.Println("responding with error 422")
 			w.WriteHeader(http.StatusUnprocessableEntity)
@@ -149,11 +160,61 @@ func HandleMockAccrualServcie() http.HandlerFunc {
 	}
 }
 
+// HandleGenerateOrders mints valid Luhn order numbers starting with the prefix query parameter, count and length
+// query parameters define the number of order numbers and their length.
+func HandleGenerateOrders() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		query := r.URL.Query()
+		count, length := 1, defaultGeneratedLength
+		var err error
+		if query.Get("count") != "" {
+			count, err = strconv.Atoi(query.Get("count"))
+			if err != nil || count < 1 || count > maxGeneratedCount {
+				log.Println("responding with error 400")
+				w.WriteHeader(http.StatusBadRequest)
+				resBody, _ := json.Marshal(Response{Error: fmt.Sprintf("count must be an integer between 1 and %d", maxGeneratedCount)})
+				w.Write(resBody)
+				return
+			}
+		}
+		if query.Get("length") != "" {
+			length, err = strconv.Atoi(query.Get("length"))
+			if err != nil {
+				log.Println("responding with error 400")
+				w.WriteHeader(http.StatusBadRequest)
+				resBody, _ := json.Marshal(Response{Error: "length must be an integer"})
+				w.Write(resBody)
+				return
+			}
+		}
+		rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+		generated := GeneratedOrders{Orders: make([]string, 0, count)}
+		for i := 0; i < count; i++ {
+			orderNumber, err := ordernum.GenerateWithPrefix(rng, query.Get("prefix"), length)
+			if err != nil {
+				log.Println("responding with error 400")
+				w.WriteHeader(http.StatusBadRequest)
+				resBody, _ := json.Marshal(Response{Error: "Invalid prefix: " + err.Error()})
+				w.Write(resBody)
+				return
+			}
+			generated.Orders = append(generated.Orders, orderNumber)
+		}
+		log.Println("responding with status 200", generated)
+		w.WriteHeader(http.StatusOK)
+		resBody, _ := json.Marshal(generated)
+		w.Write(resBody)
+	}
+}
+
 func InitServer(cfg *ServerConfig) (server *http.Server, err error) {
 	r := chi.NewRouter()
 	r.Use(middleware.CompressHandle)
 	r.Use(middleware.DecompressHandle)
 	r.Get("/api/orders/{orderID}", HandleMockAccrualServcie())
+	// test helper minting valid order numbers for integration tests and manual QA
+	r.Get("/__test/generate", HandleGenerateOrders())
 	srv := &http.Server{
 		Addr:         cfg.ServerAddress,
 		Handler:      r,
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/config"
+	"github.com/danilovkiri/dk-go-gophermart/internal/service/processor/v1/processor"
+	"github.com/danilovkiri/dk-go-gophermart/internal/service/secretary/v1/secretary"
+	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/inpsql"
+	"github.com/rs/zerolog"
+)
+
+// reportLogins reports stored logins which are not normalized and groups of logins which collide once normalized.
+func reportLogins(ctx context.Context, cfg *config.Config, log *zerolog.Logger) error {
+	secretaryService, err := secretary.NewSecretaryService(cfg.SecretConfig)
+	if err != nil {
+		return err
+	}
+	db, err := sql.Open("pgx", cfg.StorageConfig.DatabaseDSN)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	users, err := inpsql.ListUserLogins(ctx, db)
+	if err != nil {
+		return err
+	}
+	groups := make(map[string][]string)
+	var unnormalized int
+	for _, user := range users {
+		login, err := secretaryService.Decode(user.Login)
+		if err != nil {
+			log.Warn().Err(err).Msg(fmt.Sprintf("could not decipher login of user %s", user.UserID))
+			continue
+		}
+		normalized := processor.NormalizeLogin(login)
+		if normalized != login {
+			unnormalized++
+		}
+		key := user.TenantID + "/" + normalized
+		groups[key] = append(groups[key], fmt.Sprintf("%q (%s)", login, user.UserID))
+	}
+	keys := make([]string, 0, len(groups))
+	for key, logins := range groups {
+		if len(logins) > 1 {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		log.Warn().Msg(fmt.Sprintf("near-duplicate logins for %s: %s", key, strings.Join(groups[key], ", ")))
+	}
+	log.Info().Msg(fmt.Sprintf("login report: %v users, %v not normalized, %v near-duplicate groups", len(users), unnormalized, len(keys)))
+	return nil
+}
@@ -2,15 +2,23 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"github.com/danilovkiri/dk-go-gophermart/internal/api/rest/v1"
+	"github.com/danilovkiri/dk-go-gophermart/internal/api/rest/v1/middleware"
+	"github.com/danilovkiri/dk-go-gophermart/internal/buildinfo"
 	"github.com/danilovkiri/dk-go-gophermart/internal/config"
 	"github.com/danilovkiri/dk-go-gophermart/internal/logger"
+	"github.com/danilovkiri/dk-go-gophermart/internal/restart"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
+	// embed timezone data so that DISPLAY_TIMEZONE works on hosts without it
+	_ "time/tzdata"
 )
 
 func main() {
@@ -19,6 +27,43 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// detect a subcommand preceding flags
+	var command string
+	if len(os.Args) > 1 && (os.Args[1] == "rotate-keys" || os.Args[1] == "login-report" || os.Args[1] == "recalc-balances" || os.Args[1] == "replay" || os.Args[1] == "seed") {
+		command = os.Args[1]
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+	}
+	// recalc-balances and replay only report discrepancies unless -apply is passed
+	var apply bool
+	if command == "recalc-balances" || command == "replay" {
+		args := os.Args[:1]
+		for _, arg := range os.Args[1:] {
+			if arg == "-apply" || arg == "--apply" {
+				apply = true
+				continue
+			}
+			args = append(args, arg)
+		}
+		os.Args = args
+	}
+	// seed creates 10 demo users unless -users=N is passed
+	seedCount := 10
+	if command == "seed" {
+		args := os.Args[:1]
+		for _, arg := range os.Args[1:] {
+			if value := strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "-"); strings.HasPrefix(value, "users=") {
+				count, err := strconv.Atoi(strings.TrimPrefix(value, "users="))
+				if err != nil || count <= 0 {
+					log.Fatal().Msg(fmt.Sprintf("invalid number of demo users %s", arg))
+				}
+				seedCount = count
+				continue
+			}
+			args = append(args, arg)
+		}
+		os.Args = args
+	}
+
 	// get configuration
 	cfg, err := config.NewConfiguration()
 	if err != nil {
@@ -26,8 +71,54 @@ func main() {
 	}
 	cfg.ParseFlags()
 
+	// apply or revert DB schema migrations without starting the server
+	if cfg.StorageConfig.MigrateOnly || cfg.StorageConfig.MigrateDown > 0 {
+		if err := migrateSchema(ctx, cfg, log); err != nil {
+			log.Fatal().Err(err).Msg("DB schema migration failed")
+		}
+		return
+	}
+
+	switch command {
+	case "rotate-keys":
+		if err := rotateKeys(ctx, cfg, log); err != nil {
+			log.Fatal().Err(err).Msg("secret key rotation failed")
+		}
+		return
+	case "login-report":
+		if err := reportLogins(ctx, cfg, log); err != nil {
+			log.Fatal().Err(err).Msg("login report failed")
+		}
+		return
+	case "recalc-balances":
+		if err := recalcBalances(ctx, cfg, log, apply); err != nil {
+			log.Fatal().Err(err).Msg("balance recalculation failed")
+		}
+		return
+	case "replay":
+		if err := replayEvents(ctx, cfg, log, apply); err != nil {
+			log.Fatal().Err(err).Msg("balance event replay failed")
+		}
+		return
+	case "seed":
+		if err := seedUsers(ctx, cfg, log, seedCount); err != nil {
+			log.Fatal().Err(err).Msg("seeding demo data failed")
+		}
+		return
+	}
+
+	buildInfo := buildinfo.Get()
+	log.Info().Msg(fmt.Sprintf("build version: %s, commit: %s, date: %s", buildInfo.Version, buildInfo.Commit, buildInfo.Date))
+
 	// initialize server
-	server, err := rest.InitServer(ctx, cfg, log, wg)
+	intakeHandler := middleware.NewIntakeHandler(cfg.QueueConfig.DrainPeriod)
+	server, err := rest.InitServer(ctx, cfg, log, wg, intakeHandler)
+	if err != nil {
+		log.Fatal().Err(err).Msg("")
+	}
+
+	// open a listening socket or inherit it from the parent process upon restart
+	listener, err := restart.Listen(server.Addr)
 	if err != nil {
 		log.Fatal().Err(err).Msg("")
 	}
@@ -39,6 +130,16 @@ func main() {
 	go func() {
 		//defer wg.Done()
 		<-done
+		// stop accepting new orders and withdrawals while letting the broker process the queued ones
+		if cfg.QueueConfig.DrainPeriod > 0 {
+			intakeHandler.Stop()
+			log.Info().Msg(fmt.Sprintf("server intake stopped, draining queue for %v", cfg.QueueConfig.DrainPeriod))
+			select {
+			case <-time.After(cfg.QueueConfig.DrainPeriod):
+			case <-done:
+				log.Info().Msg("queue draining interrupted")
+			}
+		}
 		log.Info().Msg("server shutdown attempted")
 		ctxTO, cancelTO := context.WithTimeout(ctx, 5*time.Second)
 		defer cancelTO()
@@ -48,9 +149,28 @@ func main() {
 		cancel()
 	}()
 
+	// set a listener for zero-downtime restart, the new process terminates this one once it is serving
+	upgrade := make(chan os.Signal, 1)
+	signal.Notify(upgrade, syscall.SIGUSR2)
+	go func() {
+		for range upgrade {
+			log.Info().Msg("server restart attempted")
+			process, err := restart.Spawn(listener)
+			if err != nil {
+				log.Error().Err(err).Msg("server restart failed")
+				continue
+			}
+			log.Info().Msg(fmt.Sprintf("server restart handed over to process %v", process.Pid))
+		}
+	}()
+
 	// start up the server
 	log.Info().Msg("server start attempted")
-	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+	err = restart.Ready()
+	if err != nil {
+		log.Error().Err(err).Msg("could not notify parent process")
+	}
+	if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
 		log.Fatal().Err(err).Msg("")
 	}
 
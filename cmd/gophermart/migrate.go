@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/config"
+	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/inpsql"
+	"github.com/rs/zerolog"
+)
+
+// migrateSchema applies pending DB schema migrations or reverts the latest ones if -migrate-down is passed.
+func migrateSchema(ctx context.Context, cfg *config.Config, log *zerolog.Logger) error {
+	db, err := sql.Open("pgx", cfg.StorageConfig.DatabaseDSN)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	migrator, err := inpsql.NewMigrator(db, log)
+	if err != nil {
+		return err
+	}
+	if cfg.StorageConfig.MigrateDown > 0 {
+		reverted, err := migrator.Down(ctx, cfg.StorageConfig.MigrateDown)
+		if err != nil {
+			return err
+		}
+		log.Info().Msg(fmt.Sprintf("%v DB schema migrations reverted", reverted))
+	} else {
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		log.Info().Msg(fmt.Sprintf("%v DB schema migrations applied", applied))
+	}
+	version, err := migrator.Version(ctx)
+	if err != nil {
+		return err
+	}
+	log.Info().Msg(fmt.Sprintf("DB schema version: %v", version))
+	return nil
+}
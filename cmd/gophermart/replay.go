@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/config"
+	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/inpsql"
+	"github.com/rs/zerolog"
+)
+
+// replayEvents rebuilds every balance from the balance event log and reports the differences,
+// the balance and snapshot tables are rewritten only if apply is true.
+func replayEvents(ctx context.Context, cfg *config.Config, log *zerolog.Logger, apply bool) error {
+	db, err := sql.Open("pgx", cfg.StorageConfig.DatabaseDSN)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	report, err := inpsql.ReplayBalanceEvents(ctx, db, apply)
+	if err != nil {
+		return err
+	}
+	for _, discrepancy := range report.Discrepancies {
+		log.Warn().Msg(fmt.Sprintf("balance of user %s: stored %v, replayed %v, difference %v", discrepancy.UserID, discrepancy.StoredAmount, discrepancy.ExpectedAmount, discrepancy.Difference))
+	}
+	log.Info().Msg(fmt.Sprintf("balance event replay: %v users checked, %v discrepancies, applied: %v", report.UsersChecked, len(report.Discrepancies), report.Applied))
+	return nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/config"
+	"github.com/danilovkiri/dk-go-gophermart/internal/service/hasher/v1/hasher"
+	"github.com/danilovkiri/dk-go-gophermart/internal/service/secretary/v1/secretary"
+	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/inpsql"
+	"github.com/rs/zerolog"
+)
+
+// rotateKeys re-ciphers stored user credentials with the current secret key, sealing logins with random nonces
+// and recomputing their digests, legacy ciphered passwords are replaced by their hashes. Previous keys must be
+// listed in PREVIOUS_SECRET_KEYS for the stored data to be deciphered.
+func rotateKeys(ctx context.Context, cfg *config.Config, log *zerolog.Logger) error {
+	secretaryService, err := secretary.NewSecretaryService(cfg.SecretConfig)
+	if err != nil {
+		return err
+	}
+	passwordHasher, err := hasher.NewBcryptHasher(cfg.SecretConfig)
+	if err != nil {
+		return err
+	}
+	db, err := sql.Open("pgx", cfg.StorageConfig.DatabaseDSN)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	recipher := func(msg string) (string, error) {
+		decoded, err := secretaryService.Decode(msg)
+		if err != nil {
+			return "", err
+		}
+		return secretaryService.Encode(decoded), nil
+	}
+	hashPassword := func(msg string) (string, error) {
+		password, err := secretaryService.Decode(msg)
+		if err != nil {
+			return "", err
+		}
+		return passwordHasher.Hash(password)
+	}
+	resealLogin := func(msg, loginHash string) (string, string, error) {
+		login, err := secretaryService.Decode(msg)
+		if err != nil {
+			return "", "", err
+		}
+		digest := secretaryService.LoginHash(login)
+		if secretaryService.Current(msg) && secretaryService.Sealed(msg) && digest == loginHash {
+			return msg, loginHash, nil
+		}
+		sealed, err := secretaryService.Seal(login)
+		if err != nil {
+			return "", "", err
+		}
+		return sealed, digest, nil
+	}
+	rotated, err := inpsql.RotateUserKeys(ctx, db, secretaryService.Current, recipher, passwordHasher.Hashed, hashPassword, resealLogin)
+	if err != nil {
+		return err
+	}
+	log.Info().Msg(fmt.Sprintf("secret keys rotated for %v users", rotated))
+	return nil
+}
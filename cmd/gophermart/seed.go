@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/config"
+	"github.com/danilovkiri/dk-go-gophermart/internal/ordernum"
+	"github.com/danilovkiri/dk-go-gophermart/internal/service/hasher/v1/hasher"
+	"github.com/danilovkiri/dk-go-gophermart/internal/service/processor/v1/processor"
+	"github.com/danilovkiri/dk-go-gophermart/internal/service/secretary/v1/secretary"
+	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/inpsql"
+	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/modelstorage"
+	"github.com/danilovkiri/dk-go-gophermart/internal/tenant"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// seedPassword is the password shared by all demo users.
+const seedPassword = "demo-password"
+
+// seedStatuses lists order statuses assigned to demo orders, PROCESSED ones are more frequent as in real histories.
+var seedStatuses = []string{"NEW", "PROCESSING", "INVALID", "PROCESSED", "PROCESSED", "PROCESSED"}
+
+// seedUsers creates demo users with order and withdrawal histories, their logins are demo-<n>@<seed run>
+// and all of them share the same password.
+func seedUsers(ctx context.Context, cfg *config.Config, log *zerolog.Logger, count int) error {
+	secretaryService, err := secretary.NewSecretaryService(cfg.SecretConfig)
+	if err != nil {
+		return err
+	}
+	passwordHasher, err := hasher.NewBcryptHasher(cfg.SecretConfig)
+	if err != nil {
+		return err
+	}
+	// bcrypt is slow by design, the shared password is hashed once for all users
+	passwordHash, err := passwordHasher.Hash(seedPassword)
+	if err != nil {
+		return err
+	}
+	db, err := sql.Open("pgx", cfg.StorageConfig.DatabaseDSN)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	run := strconv.FormatInt(time.Now().Unix(), 36)
+	now := time.Now()
+	var users []modelstorage.UserStorageEntry
+	var orders []modelstorage.OrderStorageEntry
+	var withdrawals []modelstorage.WithdrawalStorageEntry
+	for i := 0; i < count; i++ {
+		login := processor.NormalizeLogin(fmt.Sprintf("demo-%d@%s", i+1, run))
+		sealedLogin, err := secretaryService.Seal(login)
+		if err != nil {
+			return err
+		}
+		user := modelstorage.UserStorageEntry{
+			UserID:       uuid.New().String(),
+			Login:        sealedLogin,
+			LoginHash:    secretaryService.LoginHash(login),
+			Password:     passwordHash,
+			RegisteredAt: now.Add(-time.Duration(30+rng.Intn(60)) * 24 * time.Hour),
+			TenantID:     tenant.Default,
+		}
+		users = append(users, user)
+		var accrued float64
+		for j := rng.Intn(10); j >= 0; j-- {
+			order := modelstorage.OrderStorageEntry{
+				UserID:      user.UserID,
+				OrderNumber: ordernum.Generate(rng, 12),
+				Status:      seedStatuses[rng.Intn(len(seedStatuses))],
+				CreatedAt:   seedTime(rng, user.RegisteredAt, now),
+				TenantID:    tenant.Default,
+			}
+			if order.Status == "PROCESSED" {
+				order.Accrual = math.Round(rng.Float64()*100000) / 100
+				accrued += order.Accrual
+			}
+			orders = append(orders, order)
+		}
+		// withdrawals never exceed accruals so that balances stay non-negative
+		for j := rng.Intn(4); j > 0 && accrued >= 1; j-- {
+			amount := math.Floor(rng.Float64()*accrued/2*100) / 100
+			if amount <= 0 {
+				break
+			}
+			accrued -= amount
+			withdrawals = append(withdrawals, modelstorage.WithdrawalStorageEntry{
+				UserID:      user.UserID,
+				OrderNumber: ordernum.Generate(rng, 12),
+				Amount:      amount,
+				ProcessedAt: seedTime(rng, user.RegisteredAt, now),
+				Status:      modelstorage.WithdrawalProcessed,
+				TenantID:    tenant.Default,
+			})
+		}
+		log.Info().Msg(fmt.Sprintf("demo user %s prepared", login))
+	}
+	err = inpsql.SeedUsers(ctx, db, users, orders, withdrawals)
+	if err != nil {
+		return err
+	}
+	log.Info().Msg(fmt.Sprintf("seeded %v users with %v orders and %v withdrawals, password: %s", len(users), len(orders), len(withdrawals), seedPassword))
+	return nil
+}
+
+// seedTime returns a random moment between from and to.
+func seedTime(rng *rand.Rand, from, to time.Time) time.Time {
+	return from.Add(time.Duration(rng.Int63n(int64(to.Sub(from)))))
+}
@@ -0,0 +1,5 @@
+// Package integration runs the full user flow against a real server backed by disposable Postgres and mock accrual
+// containers. The tests are gated behind the integration build tag and need a Docker daemon:
+//
+//	go test -tags integration ./integration/...
+package integration
@@ -0,0 +1,310 @@
+//go:build integration
+
+package integration
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	rest "github.com/danilovkiri/dk-go-gophermart/internal/api/rest/v1"
+	"github.com/danilovkiri/dk-go-gophermart/internal/api/rest/v1/middleware"
+	"github.com/danilovkiri/dk-go-gophermart/internal/config"
+	"github.com/danilovkiri/dk-go-gophermart/internal/models/modeldto"
+	_ "github.com/jackc/pgx/v4/stdlib"
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// orderCount is the number of uploaded orders, the mock accrual service resolves each of them to a positive
+	// accrual with a probability of 1/4, so at least one is credited unless an extremely unlikely draw happens
+	orderCount = 30
+	// flowTimeout bounds waiting for accruals, the mock accrual service throttles 10% of requests for 60 seconds
+	flowTimeout = 3 * time.Minute
+)
+
+// environment defines addresses of the services run for a test.
+type environment struct {
+	serverURL  string
+	accrualURL string
+}
+
+// setUp starts Postgres and mock accrual containers and a server using them, everything is torn down on cleanup.
+func setUp(t *testing.T) *environment {
+	t.Helper()
+	pool, err := dockertest.NewPool("")
+	if err != nil {
+		t.Fatalf("could not connect to docker: %v", err)
+	}
+	if err := pool.Client.Ping(); err != nil {
+		t.Fatalf("docker is unavailable: %v", err)
+	}
+	pool.MaxWait = time.Minute
+
+	postgres, err := pool.RunWithOptions(&dockertest.RunOptions{
+		Repository: "postgres",
+		Tag:        "14-alpine",
+		Env:        []string{"POSTGRES_USER=gophermart", "POSTGRES_PASSWORD=gophermart", "POSTGRES_DB=gophermart"},
+	}, autoRemove)
+	if err != nil {
+		t.Fatalf("could not start postgres: %v", err)
+	}
+	t.Cleanup(func() { purge(t, pool, postgres) })
+	dsn := fmt.Sprintf("postgres://gophermart:gophermart@%s/gophermart?sslmode=disable", postgres.GetHostPort("5432/tcp"))
+	err = pool.Retry(func() error {
+		db, err := sql.Open("pgx", dsn)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		return db.Ping()
+	})
+	if err != nil {
+		t.Fatalf("postgres is not ready: %v", err)
+	}
+
+	accrualDir := buildAccrual(t)
+	accrual, err := pool.RunWithOptions(&dockertest.RunOptions{
+		Repository:   "alpine",
+		Tag:          "3.16",
+		Mounts:       []string{accrualDir + ":/accrual:ro"},
+		Cmd:          []string{"/accrual/accrual", "-a", ":7070"},
+		ExposedPorts: []string{"7070/tcp"},
+	}, autoRemove)
+	if err != nil {
+		t.Fatalf("could not start accrual: %v", err)
+	}
+	t.Cleanup(func() { purge(t, pool, accrual) })
+	accrualURL := "http://" + accrual.GetHostPort("7070/tcp")
+	err = pool.Retry(func() error {
+		resp, err := http.Get(accrualURL + "/__test/generate")
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("accrual is not ready: %v", err)
+	}
+
+	return &environment{serverURL: startServer(t, dsn, accrualURL), accrualURL: accrualURL}
+}
+
+// autoRemove removes containers once stopped so that an interrupted run leaves nothing behind.
+func autoRemove(config *docker.HostConfig) {
+	config.AutoRemove = true
+	config.RestartPolicy = docker.RestartPolicy{Name: "no"}
+}
+
+// purge stops and removes a container.
+func purge(t *testing.T, pool *dockertest.Pool, resource *dockertest.Resource) {
+	if err := pool.Purge(resource); err != nil {
+		t.Errorf("could not purge %s: %v", resource.Container.Name, err)
+	}
+}
+
+// buildAccrual builds the mock accrual service for Linux into a temporary directory mounted to its container.
+func buildAccrual(t *testing.T) string {
+	t.Helper()
+	_, file, _, _ := runtime.Caller(0)
+	dir := t.TempDir()
+	cmd := exec.Command("go", "build", "-o", filepath.Join(dir, "accrual"), "./cmd/accrual")
+	cmd.Dir = filepath.Join(filepath.Dir(file), "..")
+	cmd.Env = append(os.Environ(), "CGO_ENABLED=0", "GOOS=linux")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("could not build accrual: %v\n%s", err, output)
+	}
+	return dir
+}
+
+// startServer runs a server configured to poll accruals without delays and stops it on cleanup.
+func startServer(t *testing.T, dsn, accrualURL string) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, value := range map[string]string{
+		"RUN_ADDRESS":                   listener.Addr().String(),
+		"ACCRUAL_SYSTEM_ADDRESS":        accrualURL,
+		"DATABASE_URI":                  dsn,
+		"N_WORKERS":                     "4",
+		"N_RETRIES":                     "100",
+		"ACCRUAL_POLL_INTERVALS":        "100ms",
+		"ACCRUAL_UNKNOWN_ORDER_BACKOFF": "100ms",
+		"STORAGE_TIMEOUT":               "5s",
+		"REQUEST_TIMEOUT":               "10s",
+	} {
+		t.Setenv(key, value)
+	}
+	cfg, err := config.NewConfiguration()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := &sync.WaitGroup{}
+	log := zerolog.Nop()
+	server, err := rest.InitServer(ctx, cfg, &log, wg, middleware.NewIntakeHandler(cfg.QueueConfig.DrainPeriod))
+	if err != nil {
+		cancel()
+		t.Fatal(err)
+	}
+	served := make(chan error, 1)
+	go func() {
+		served <- server.Serve(listener)
+	}()
+	t.Cleanup(func() {
+		ctxTO, cancelTO := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancelTO()
+		if err := server.Shutdown(ctxTO); err != nil {
+			t.Errorf("server shutdown failed: %v", err)
+		}
+		cancel()
+		wg.Wait()
+		if err := <-served; err != nil && err != http.ErrServerClosed {
+			t.Errorf("server failed: %v", err)
+		}
+	})
+	return "http://" + listener.Addr().String()
+}
+
+// client sends requests on behalf of a user.
+type client struct {
+	t           *testing.T
+	accessToken string
+}
+
+// do sends a request and decodes a JSON response into out if it is not nil, it returns the response status.
+func (c *client) do(method, url, contentType, body string, out interface{}) int {
+	c.t.Helper()
+	req, err := http.NewRequest(method, url, bytes.NewBufferString(body))
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.accessToken != "" {
+		req.Header.Set("Authorization", c.accessToken)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if c.accessToken == "" {
+		c.accessToken = resp.Header.Get("Authorization")
+	}
+	if out != nil && resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			c.t.Fatalf("could not decode %s %s response: %v", method, url, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+// generateOrders mints valid order numbers with the mock accrual service.
+func generateOrders(t *testing.T, accrualURL string, count int) []string {
+	t.Helper()
+	resp, err := http.Get(fmt.Sprintf("%s/__test/generate?count=%d&length=12", accrualURL, count))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var generated struct {
+		Orders []string `json:"orders"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&generated); err != nil {
+		t.Fatal(err)
+	}
+	if len(generated.Orders) != count {
+		t.Fatalf("got %d generated orders, want %d", len(generated.Orders), count)
+	}
+	return generated.Orders
+}
+
+func TestAccrualAndWithdrawalFlow(t *testing.T) {
+	env := setUp(t)
+	user := &client{t: t}
+
+	// register
+	if status := user.do(http.MethodPost, env.serverURL+"/api/user/register", "application/json", `{"login":"integration","password":"secret"}`, nil); status != http.StatusOK {
+		t.Fatalf("register: got status %d, want %d", status, http.StatusOK)
+	}
+	if user.accessToken == "" {
+		t.Fatal("register: got no access token")
+	}
+
+	// upload orders
+	orders := generateOrders(t, env.accrualURL, orderCount+1)
+	for _, orderNumber := range orders[:orderCount] {
+		if status := user.do(http.MethodPost, env.serverURL+"/api/user/orders", "text/plain", orderNumber, nil); status != http.StatusAccepted {
+			t.Fatalf("upload order %s: got status %d, want %d", orderNumber, status, http.StatusAccepted)
+		}
+	}
+	if status := user.do(http.MethodPost, env.serverURL+"/api/user/orders", "text/plain", orders[0], nil); status != http.StatusOK {
+		t.Fatalf("upload order %s again: got status %d, want %d", orders[0], status, http.StatusOK)
+	}
+
+	// wait for accruals polled by the broker
+	var balance modeldto.Balance
+	deadline := time.Now().Add(flowTimeout)
+	for balance.CurrentAmount <= 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("no accrual was credited within %v", flowTimeout)
+		}
+		time.Sleep(500 * time.Millisecond)
+		if status := user.do(http.MethodGet, env.serverURL+"/api/user/balance", "", "", &balance); status != http.StatusOK {
+			t.Fatalf("get balance: got status %d, want %d", status, http.StatusOK)
+		}
+	}
+	var uploaded []modeldto.Order
+	if status := user.do(http.MethodGet, env.serverURL+"/api/user/orders", "", "", &uploaded); status != http.StatusOK {
+		t.Fatalf("get orders: got status %d, want %d", status, http.StatusOK)
+	}
+	var processed bool
+	for _, order := range uploaded {
+		processed = processed || (order.Status == "PROCESSED" && order.Accrual > 0)
+	}
+	if len(uploaded) != orderCount || !processed {
+		t.Fatalf("get orders: got %+v, want %d orders with a processed one", uploaded, orderCount)
+	}
+
+	// withdraw the credited amount
+	withdrawal := fmt.Sprintf(`{"order":%q,"sum":%v}`, orders[orderCount], balance.CurrentAmount)
+	if status := user.do(http.MethodPost, env.serverURL+"/api/user/balance/withdraw", "application/json", withdrawal, nil); status != http.StatusOK {
+		t.Fatalf("withdraw: got status %d, want %d", status, http.StatusOK)
+	}
+	if status := user.do(http.MethodPost, env.serverURL+"/api/user/balance/withdraw", "application/json", fmt.Sprintf(`{"order":%q,"sum":%v}`, orders[0], 1e9), nil); status != http.StatusPaymentRequired {
+		t.Fatalf("withdraw over balance: got status %d, want %d", status, http.StatusPaymentRequired)
+	}
+	var withdrawals []modeldto.Withdrawal
+	if status := user.do(http.MethodGet, env.serverURL+"/api/user/withdrawals", "", "", &withdrawals); status != http.StatusOK {
+		t.Fatalf("get withdrawals: got status %d, want %d", status, http.StatusOK)
+	}
+	if len(withdrawals) != 1 || withdrawals[0].OrderNumber != orders[orderCount] || withdrawals[0].WithdrawnAmount != balance.CurrentAmount {
+		t.Fatalf("get withdrawals: got %+v, want a single withdrawal of %v for order %s", withdrawals, balance.CurrentAmount, orders[orderCount])
+	}
+	var after modeldto.Balance
+	if status := user.do(http.MethodGet, env.serverURL+"/api/user/balance", "", "", &after); status != http.StatusOK {
+		t.Fatalf("get balance: got status %d, want %d", status, http.StatusOK)
+	}
+	if after.WithdrawnAmount != balance.CurrentAmount {
+		t.Fatalf("get balance: got withdrawn %v, want %v", after.WithdrawnAmount, balance.CurrentAmount)
+	}
+}
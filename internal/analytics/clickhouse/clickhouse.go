@@ -0,0 +1,208 @@
+// Package clickhouse provides export of order and withdrawal events to ClickHouse for analytical queries.
+//
+// Events are read from the outbox under a dedicated offset and inserted in batches over the HTTP interface.
+// Tables use ReplacingMergeTree keyed by the event ID, so rows inserted again after a failed offset commit
+// are collapsed by background merges and can be deduplicated at query time with FINAL.
+package clickhouse
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/config"
+	"github.com/danilovkiri/dk-go-gophermart/internal/metrics"
+	"github.com/danilovkiri/dk-go-gophermart/internal/outbox"
+	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/modelstorage"
+	"github.com/go-resty/resty/v2"
+	"github.com/rs/zerolog"
+)
+
+// relayName identifies the export offset in the outbox.
+const relayName = "clickhouse"
+
+// tableDefinitions lists the exported tables, %s is replaced with the database name.
+var tableDefinitions = []string{
+	`CREATE TABLE IF NOT EXISTS %s.order_events (
+		event_id     UInt64,
+		tenant_id    LowCardinality(String),
+		user_id      String,
+		order_number String,
+		status       LowCardinality(String),
+		accrual      Float64,
+		occurred_at  DateTime('UTC')
+	) ENGINE = ReplacingMergeTree ORDER BY (tenant_id, occurred_at, event_id)`,
+	`CREATE TABLE IF NOT EXISTS %s.withdrawal_events (
+		event_id     UInt64,
+		tenant_id    LowCardinality(String),
+		user_id      String,
+		order_number String,
+		sum          Float64,
+		occurred_at  DateTime('UTC')
+	) ENGINE = ReplacingMergeTree ORDER BY (tenant_id, occurred_at, event_id)`,
+}
+
+// orderRow defines a row of the order_events table.
+type orderRow struct {
+	EventID     int64   `json:"event_id"`
+	TenantID    string  `json:"tenant_id"`
+	UserID      string  `json:"user_id"`
+	OrderNumber string  `json:"order_number"`
+	Status      string  `json:"status"`
+	Accrual     float64 `json:"accrual"`
+	OccurredAt  string  `json:"occurred_at"`
+}
+
+// withdrawalRow defines a row of the withdrawal_events table.
+type withdrawalRow struct {
+	EventID     int64   `json:"event_id"`
+	TenantID    string  `json:"tenant_id"`
+	UserID      string  `json:"user_id"`
+	OrderNumber string  `json:"order_number"`
+	Sum         float64 `json:"sum"`
+	OccurredAt  string  `json:"occurred_at"`
+}
+
+// Exporter defines attributes of a struct available to its methods.
+type Exporter struct {
+	cfg     *config.ClickHouseConfig
+	log     *zerolog.Logger
+	metrics *metrics.Registry
+	store   outbox.Store
+	client  *resty.Client
+	// tablesReady is set once the tables were created, it is only accessed by the scheduled job
+	tablesReady bool
+}
+
+// InitExporter initializes a ClickHouse exporter.
+func InitExporter(cfg *config.ClickHouseConfig, log *zerolog.Logger, reg *metrics.Registry, store outbox.Store) *Exporter {
+	client := resty.New().
+		SetBaseURL(strings.TrimSuffix(cfg.URL, "/")).
+		SetTimeout(cfg.Timeout).
+		SetHeader("X-ClickHouse-User", cfg.User)
+	if cfg.Password != "" {
+		client.SetHeader("X-ClickHouse-Key", cfg.Password)
+	}
+	return &Exporter{
+		cfg:     cfg,
+		log:     log,
+		metrics: reg,
+		store:   store,
+		client:  client,
+	}
+}
+
+// Run exports pending events batch by batch until none are left, events of other kinds are skipped.
+func (e *Exporter) Run(ctx context.Context) error {
+	if !e.tablesReady {
+		for _, definition := range tableDefinitions {
+			err := e.exec(ctx, fmt.Sprintf(definition, e.cfg.Database), "")
+			if err != nil {
+				return err
+			}
+		}
+		e.tablesReady = true
+	}
+	for {
+		events, err := e.store.GetOutboxEvents(ctx, relayName, e.cfg.BatchSize)
+		if err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			return nil
+		}
+		var orders, withdrawals []string
+		for _, event := range events {
+			var row interface{}
+			switch event.Kind {
+			case modelstorage.OutboxOrderProcessed:
+				var data struct {
+					UserID  string  `json:"user_id"`
+					Order   string  `json:"order"`
+					Status  string  `json:"status"`
+					Accrual float64 `json:"accrual"`
+				}
+				err = json.Unmarshal([]byte(event.Payload), &data)
+				row = orderRow{EventID: event.ID, TenantID: event.TenantID, UserID: data.UserID, OrderNumber: data.Order, Status: data.Status, Accrual: data.Accrual, OccurredAt: formatTime(event.CreatedAt)}
+			case modelstorage.OutboxWithdrawalMade:
+				var data struct {
+					UserID string  `json:"user_id"`
+					Order  string  `json:"order"`
+					Sum    float64 `json:"sum"`
+				}
+				err = json.Unmarshal([]byte(event.Payload), &data)
+				row = withdrawalRow{EventID: event.ID, TenantID: event.TenantID, UserID: data.UserID, OrderNumber: data.Order, Sum: data.Sum, OccurredAt: formatTime(event.CreatedAt)}
+			default:
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("malformed outbox event %v: %w", event.ID, err)
+			}
+			line, err := json.Marshal(row)
+			if err != nil {
+				return err
+			}
+			if event.Kind == modelstorage.OutboxOrderProcessed {
+				orders = append(orders, string(line))
+			} else {
+				withdrawals = append(withdrawals, string(line))
+			}
+		}
+		err = e.insert(ctx, "order_events", orders)
+		if err != nil {
+			return err
+		}
+		err = e.insert(ctx, "withdrawal_events", withdrawals)
+		if err != nil {
+			return err
+		}
+		last := events[len(events)-1]
+		err = e.store.CommitOutboxOffset(ctx, relayName, last.TxID, last.ID)
+		if err != nil {
+			// the batch is inserted again upon the next run and collapsed by ClickHouse
+			return err
+		}
+		e.metrics.Counter("gophermart_clickhouse_exported_rows_total", "table", "order_events").Add(uint64(len(orders)))
+		e.metrics.Counter("gophermart_clickhouse_exported_rows_total", "table", "withdrawal_events").Add(uint64(len(withdrawals)))
+		e.log.Info().Msg(fmt.Sprintf("exported %v order and %v withdrawal events to ClickHouse up to event %v", len(orders), len(withdrawals), last.ID))
+		if len(events) < e.cfg.BatchSize {
+			return nil
+		}
+	}
+}
+
+// insert sends rows to a table in the JSONEachRow format within a single request.
+func (e *Exporter) insert(ctx context.Context, table string, rows []string) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	query := fmt.Sprintf("INSERT INTO %s.%s FORMAT JSONEachRow", e.cfg.Database, table)
+	return e.exec(ctx, query, strings.Join(rows, "\n"))
+}
+
+// exec runs a query passing the body as its data.
+func (e *Exporter) exec(ctx context.Context, query string, body string) error {
+	resp, err := e.client.R().
+		SetContext(ctx).
+		SetQueryParam("query", query).
+		SetBody(body).
+		Post("/")
+	if err != nil {
+		e.metrics.Counter("gophermart_clickhouse_failures_total").Inc()
+		return err
+	}
+	if resp.IsError() {
+		e.metrics.Counter("gophermart_clickhouse_failures_total").Inc()
+		return errors.New("clickhouse responded with status " + strconv.Itoa(resp.StatusCode()) + ": " + strings.TrimSpace(resp.String()))
+	}
+	return nil
+}
+
+// formatTime renders a timestamp as accepted by DateTime columns.
+func formatTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04:05")
+}
@@ -0,0 +1,115 @@
+// Package errors provides custom error types.
+
+package errors
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/errcodes"
+	chiMiddleware "github.com/go-chi/chi/middleware"
+	"github.com/rs/zerolog/log"
+)
+
+// ErrorResponse defines the error envelope returned by all endpoints.
+type ErrorResponse struct {
+	Code      errcodes.Code `json:"code"`
+	Message   string        `json:"message"`
+	Details   interface{}   `json:"details,omitempty"`
+	RequestID string        `json:"request_id,omitempty"`
+}
+
+// FieldError defines a single field-level validation failure reported in the error envelope details.
+type FieldError struct {
+	Field string `json:"field"`
+	Rule  string `json:"rule"`
+	Param string `json:"param,omitempty"`
+}
+
+// LoginThrottleDetails defines the error envelope details of failed and throttled logins.
+type LoginThrottleDetails struct {
+	RemainingAttempts int    `json:"remaining_attempts"`
+	LockedUntil       string `json:"locked_until,omitempty"`
+}
+
+// codeStatuses maps application error codes to HTTP statuses.
+var codeStatuses = map[errcodes.Code]int{
+	errcodes.InvalidRequest:          http.StatusBadRequest,
+	errcodes.Unauthorized:            http.StatusUnauthorized,
+	errcodes.SessionExpired:          http.StatusUnauthorized,
+	errcodes.InvalidCredentials:      http.StatusUnauthorized,
+	errcodes.UnknownUser:             http.StatusUnauthorized,
+	errcodes.Forbidden:               http.StatusForbidden,
+	errcodes.CaptchaFailed:           http.StatusForbidden,
+	errcodes.NotFound:                http.StatusNotFound,
+	errcodes.AlreadyExists:           http.StatusConflict,
+	errcodes.LoginTaken:              http.StatusConflict,
+	errcodes.DuplicateOrder:          http.StatusConflict,
+	errcodes.OrderOwnedByAnotherUser: http.StatusConflict,
+	errcodes.DuplicateRequest:        http.StatusConflict,
+	errcodes.MergeConflict:           http.StatusConflict,
+	errcodes.ConcurrentUpdate:        http.StatusConflict,
+	errcodes.OrderNotRecheckable:     http.StatusConflict,
+	errcodes.OrderNotSuspended:       http.StatusConflict,
+	errcodes.OrderInvalidNumber:      http.StatusUnprocessableEntity,
+	errcodes.InsufficientFunds:       http.StatusPaymentRequired,
+	errcodes.HoldNotActive:           http.StatusConflict,
+	errcodes.UnsupportedCurrency:     http.StatusBadRequest,
+	errcodes.TooManyRequests:         http.StatusTooManyRequests,
+	errcodes.UnsupportedMediaType:    http.StatusUnsupportedMediaType,
+	errcodes.PayloadTooLarge:         http.StatusRequestEntityTooLarge,
+	errcodes.Timeout:                 http.StatusGatewayTimeout,
+	errcodes.DeadlineExceeded:        http.StatusServiceUnavailable,
+	errcodes.StorageUnavailable:      http.StatusServiceUnavailable,
+	errcodes.ServiceUnavailable:      http.StatusServiceUnavailable,
+}
+
+// StatusOf returns the HTTP status corresponding to an application error code.
+func StatusOf(code errcodes.Code) int {
+	if status, ok := codeStatuses[code]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// MapError maps an application error to an HTTP status and an error code.
+func MapError(err error) (int, errcodes.Code) {
+	code := errcodes.Of(err)
+	return StatusOf(code), code
+}
+
+// WriteError maps an application error and writes it as an error envelope, server errors are written with a generic
+// message so that internals do not leak to clients, the error itself is logged along with the request ID.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	status, code := MapError(err)
+	message := err.Error()
+	if status >= http.StatusInternalServerError {
+		log.Error().Err(err).Str("request_id", chiMiddleware.GetReqID(r.Context())).Msg(fmt.Sprintf("request failed with status %v", status))
+		message = http.StatusText(status)
+	}
+	WriteErrorStatus(w, r, status, code, message, nil)
+}
+
+// WriteErrorCode writes an error envelope for an application error code.
+func WriteErrorCode(w http.ResponseWriter, r *http.Request, code errcodes.Code, message string, details interface{}) {
+	WriteErrorStatus(w, r, StatusOf(code), code, message, details)
+}
+
+// WriteErrorStatus writes an error envelope with an explicit HTTP status and error code.
+func WriteErrorStatus(w http.ResponseWriter, r *http.Request, status int, code errcodes.Code, message string, details interface{}) {
+	resBody, err := json.Marshal(ErrorResponse{
+		Code:      code,
+		Message:   message,
+		Details:   details,
+		RequestID: chiMiddleware.GetReqID(r.Context()),
+	})
+	if err != nil {
+		http.Error(w, message, status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	w.Write(resBody)
+}
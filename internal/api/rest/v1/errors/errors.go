@@ -2,12 +2,45 @@
 
 package errors
 
+import (
+	"fmt"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/errcodes"
+)
+
 type (
 	HandlersFoundNilArgument struct {
 		Msg string
 	}
+	BodyTooLargeError struct {
+		Limit int64
+	}
+	MalformedEncodingError struct {
+		Encoding string
+		Err      error
+	}
 )
 
 func (e *HandlersFoundNilArgument) Error() string {
 	return e.Msg
 }
+
+func (e *BodyTooLargeError) Error() string {
+	return fmt.Sprintf("decompressed request body exceeds %d bytes", e.Limit)
+}
+
+func (e *BodyTooLargeError) ErrorCode() errcodes.Code {
+	return errcodes.PayloadTooLarge
+}
+
+func (e *MalformedEncodingError) Error() string {
+	return fmt.Sprintf("malformed %s request body: %v", e.Encoding, e.Err)
+}
+
+func (e *MalformedEncodingError) ErrorCode() errcodes.Code {
+	return errcodes.InvalidRequest
+}
+
+func (e *MalformedEncodingError) Unwrap() error {
+	return e.Err
+}
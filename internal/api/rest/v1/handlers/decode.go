@@ -0,0 +1,61 @@
+// Package handlers provides API endpoint handling functionality.
+
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+
+	handlersErrors "github.com/danilovkiri/dk-go-gophermart/internal/api/rest/v1/errors"
+	"github.com/danilovkiri/dk-go-gophermart/internal/errcodes"
+	"github.com/go-playground/validator/v10"
+)
+
+// requestValidator validates decoded request bodies against the validate tags of modeldto types,
+// fields are reported by their JSON names.
+var requestValidator = newRequestValidator()
+
+// newRequestValidator initializes a request validator reporting fields by their JSON names.
+func newRequestValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	return v
+}
+
+// decodeRequest decodes a JSON request body and validates it, failures are written as an error envelope
+// with field-level details, in which case false is returned.
+func decodeRequest(w http.ResponseWriter, r *http.Request, body []byte, v interface{}) bool {
+	err := json.Unmarshal(body, v)
+	if err != nil {
+		handlersErrors.WriteErrorCode(w, r, errcodes.InvalidRequest, err.Error(), nil)
+		return false
+	}
+	err = requestValidator.Struct(v)
+	if err == nil {
+		return true
+	}
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		handlersErrors.WriteErrorCode(w, r, errcodes.InvalidRequest, err.Error(), nil)
+		return false
+	}
+	details := make([]handlersErrors.FieldError, 0, len(validationErrors))
+	for _, fieldError := range validationErrors {
+		details = append(details, handlersErrors.FieldError{
+			Field: fieldError.Field(),
+			Rule:  fieldError.Tag(),
+			Param: fieldError.Param(),
+		})
+	}
+	handlersErrors.WriteErrorCode(w, r, errcodes.InvalidRequest, "Request validation failed", details)
+	return false
+}
@@ -0,0 +1,55 @@
+// Package handlers provides API endpoint handling functionality.
+
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// encodeBufferSize defines the size of pooled response buffers.
+const encodeBufferSize = 32 * 1024
+
+// writerPool holds reusable buffered writers for encoding responses.
+var writerPool = sync.Pool{
+	New: func() interface{} {
+		return bufio.NewWriterSize(io.Discard, encodeBufferSize)
+	},
+}
+
+// writeJSONArray writes a JSON array of length elements retrieved via item, encoding them one by one through a pooled
+// buffer instead of marshaling the whole array at once. The elements are expected to be loaded already, only the encoded
+// output is bounded by the buffer size.
+func writeJSONArray(w http.ResponseWriter, length int, item func(i int) interface{}) error {
+	bw := writerPool.Get().(*bufio.Writer)
+	bw.Reset(w)
+	defer func() {
+		bw.Reset(io.Discard)
+		writerPool.Put(bw)
+	}()
+	encoder := json.NewEncoder(bw)
+	err := bw.WriteByte('[')
+	if err != nil {
+		return err
+	}
+	for i := 0; i < length; i++ {
+		if i > 0 {
+			err = bw.WriteByte(',')
+			if err != nil {
+				return err
+			}
+		}
+		err = encoder.Encode(item(i))
+		if err != nil {
+			return err
+		}
+	}
+	err = bw.WriteByte(']')
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
@@ -8,211 +8,1572 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"math"
+	"mime"
+	"net"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	handlersErrors "github.com/danilovkiri/dk-go-gophermart/internal/api/rest/v1/errors"
+	"github.com/danilovkiri/dk-go-gophermart/internal/api/rest/v1/middleware"
+	"github.com/danilovkiri/dk-go-gophermart/internal/auth"
+	"github.com/danilovkiri/dk-go-gophermart/internal/buildinfo"
 	"github.com/danilovkiri/dk-go-gophermart/internal/config"
+	"github.com/danilovkiri/dk-go-gophermart/internal/errcodes"
+	"github.com/danilovkiri/dk-go-gophermart/internal/metrics"
 	"github.com/danilovkiri/dk-go-gophermart/internal/models/modeldto"
+	"github.com/danilovkiri/dk-go-gophermart/internal/service/health/v1"
 	"github.com/danilovkiri/dk-go-gophermart/internal/service/processor/v1"
 	serviceErrors "github.com/danilovkiri/dk-go-gophermart/internal/service/processor/v1/errors"
 	storageErrors "github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/errors"
+	"github.com/danilovkiri/dk-go-gophermart/internal/tenant"
+	"github.com/go-chi/chi"
 	"github.com/rs/zerolog"
 )
 
 // Handler defines attributes of a struct available to its methods.
 type Handler struct {
 	service      processor.Processor
+	authStrategy middleware.AuthStrategy
 	serverConfig *config.ServerConfig
+	adminConfig  *config.AdminConfig
 	log          *zerolog.Logger
+	metrics      *metrics.Registry
+	health       health.Checker
+	recorder     *middleware.RecordHandler
 }
 
 // InitHandlers initializes a handler object.
-func InitHandlers(mainService processor.Processor, serverConfig *config.ServerConfig, log *zerolog.Logger) (*Handler, error) {
+func InitHandlers(mainService processor.Processor, authStrategy middleware.AuthStrategy, serverConfig *config.ServerConfig, adminConfig *config.AdminConfig, log *zerolog.Logger, reg *metrics.Registry, checker health.Checker, recorder *middleware.RecordHandler) (*Handler, error) {
 	if mainService == nil {
 		return nil, &handlersErrors.HandlersFoundNilArgument{Msg: "nil processor was passed to handlers initializer"}
 	}
-	return &Handler{service: mainService, serverConfig: serverConfig, log: log}, nil
+	if authStrategy == nil {
+		return nil, &handlersErrors.HandlersFoundNilArgument{Msg: "nil auth strategy was passed to handlers initializer"}
+	}
+	if reg == nil {
+		return nil, &handlersErrors.HandlersFoundNilArgument{Msg: "nil metrics registry was passed to handlers initializer"}
+	}
+	if checker == nil {
+		return nil, &handlersErrors.HandlersFoundNilArgument{Msg: "nil health checker was passed to handlers initializer"}
+	}
+	if recorder == nil {
+		return nil, &handlersErrors.HandlersFoundNilArgument{Msg: "nil request recorder was passed to handlers initializer"}
+	}
+	return &Handler{service: mainService, authStrategy: authStrategy, serverConfig: serverConfig, adminConfig: adminConfig, log: log, metrics: reg, health: checker, recorder: recorder}, nil
+}
+
+// HandleReadiness reports whether all dependencies are available.
+func (h *Handler) HandleReadiness() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !h.health.Ready() {
+			handlersErrors.WriteErrorCode(w, r, errcodes.ServiceUnavailable, "Not ready", nil)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// HandleGetHealth processes detailed dependency health query requests.
+func (h *Handler) HandleGetHealth() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report := h.health.Report()
+		resBody, err := json.Marshal(report)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleGetHealth failed")
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if h.health.Ready() {
+			w.WriteHeader(http.StatusOK)
+		} else {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		_, err = w.Write(resBody)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleGetHealth failed")
+		}
+	}
+}
+
+// HandleGetReconciliation processes balance reconciliation report requests.
+func (h *Handler) HandleGetReconciliation() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		report, err := h.service.GetReconciliationReport(ctx)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleGetReconciliation failed")
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		resBody, err := json.Marshal(report)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleGetReconciliation failed")
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, err = w.Write(resBody)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleGetReconciliation failed")
+		}
+	}
+}
+
+// HandleRecalculateBalances processes balance recalculation requests, fixes are stored only if apply=true is passed.
+func (h *Handler) HandleRecalculateBalances() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+		defer cancel()
+		apply := r.URL.Query().Get("apply") == "true"
+		report, err := h.service.RecalculateBalances(ctx, apply)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleRecalculateBalances failed")
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		resBody, err := json.Marshal(report)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleRecalculateBalances failed")
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, err = w.Write(resBody)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleRecalculateBalances failed")
+		}
+	}
+}
+
+// HandleRequeueOrders processes admin requests requeueing non-final orders matching filters in bulk.
+func (h *Handler) HandleRequeueOrders() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+		defer cancel()
+		if !hasContentType(r, "application/json") {
+			handlersErrors.WriteErrorCode(w, r, errcodes.InvalidRequest, "Invalid Content-Type", nil)
+			return
+		}
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleRequeueOrders failed")
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		var request modeldto.RequeueRequest
+		if !decodeRequest(w, r, b, &request) {
+			h.log.Error().Msg("HandleRequeueOrders failed")
+			return
+		}
+		report, err := h.service.RequeueOrders(ctx, request)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleRequeueOrders failed")
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		resBody, err := json.Marshal(report)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleRequeueOrders failed")
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, err = w.Write(resBody)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleRequeueOrders failed")
+		}
+	}
+}
+
+// HandleGetLoginThrottles processes admin requests listing logins with recent failed attempts.
+func (h *Handler) HandleGetLoginThrottles() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		throttles := h.service.GetLoginThrottles()
+		if len(throttles) == 0 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		resBody, err := json.Marshal(throttles)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleGetLoginThrottles failed")
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, err = w.Write(resBody)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleGetLoginThrottles failed")
+		}
+	}
+}
+
+// HandleSearchUsers processes admin requests looking users up by login, user ID or order number, limit and offset
+// query parameters select the page of results.
+func (h *Handler) HandleSearchUsers() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		query := r.URL.Query().Get("query")
+		if strings.TrimSpace(query) == "" {
+			handlersErrors.WriteErrorCode(w, r, errcodes.InvalidRequest, "Search query must not be empty", nil)
+			return
+		}
+		limit, offset, ok := parsePage(r)
+		if !ok {
+			handlersErrors.WriteErrorCode(w, r, errcodes.InvalidRequest, fmt.Sprintf("Invalid pagination, limit must be within 1-%d and offset must not be negative", maxPageLimit), nil)
+			return
+		}
+		result, err := h.service.SearchUsers(ctx, query, limit, offset)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleSearchUsers failed")
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		if len(result.Users) == 0 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		resBody, err := json.Marshal(result)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleSearchUsers failed")
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, err = w.Write(resBody)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleSearchUsers failed")
+		}
+	}
+}
+
+// HandleGetRecordedRequests processes admin requests listing recently failed requests to the recorded routes.
+func (h *Handler) HandleGetRecordedRequests() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recorded := h.recorder.Recorded()
+		if len(recorded) == 0 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		resBody, err := json.Marshal(recorded)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleGetRecordedRequests failed")
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, err = w.Write(resBody)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleGetRecordedRequests failed")
+		}
+	}
+}
+
+// HandleResetLoginThrottle processes admin requests lifting the lockout of a login, the tenant query parameter
+// selects the tenant the login belongs to.
+func (h *Handler) HandleResetLoginThrottle() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tenantID := r.URL.Query().Get("tenant")
+		if tenantID == "" {
+			tenantID = tenant.Default
+		}
+		if !h.service.ResetLoginThrottle(tenantID, chi.URLParam(r, "login")) {
+			handlersErrors.WriteErrorCode(w, r, errcodes.NotFound, "No failed login attempts recorded", nil)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// HandleMergeAccounts processes admin requests merging a duplicate donor account into a target one.
+func (h *Handler) HandleMergeAccounts() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		if !hasContentType(r, "application/json") {
+			handlersErrors.WriteErrorCode(w, r, errcodes.InvalidRequest, "Invalid Content-Type", nil)
+			return
+		}
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleMergeAccounts failed")
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		var request modeldto.AccountMergeRequest
+		if !decodeRequest(w, r, b, &request) {
+			h.log.Error().Msg("HandleMergeAccounts failed")
+			return
+		}
+		merge, err := h.service.MergeAccounts(ctx, request)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleMergeAccounts failed")
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		resBody, err := json.Marshal(merge)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleMergeAccounts failed")
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, err = w.Write(resBody)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleMergeAccounts failed")
+		}
+	}
+}
+
+// HandleIssuePartnerToken processes admin requests issuing a scoped token to a partner integration.
+func (h *Handler) HandleIssuePartnerToken() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), h.serverConfig.StorageTimeout)
+		defer cancel()
+		if !hasContentType(r, "application/json") {
+			handlersErrors.WriteErrorCode(w, r, errcodes.InvalidRequest, "Invalid Content-Type", nil)
+			return
+		}
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleIssuePartnerToken failed")
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		var request modeldto.PartnerTokenRequest
+		if !decodeRequest(w, r, b, &request) {
+			h.log.Error().Msg("HandleIssuePartnerToken failed")
+			return
+		}
+		token, err := h.service.IssuePartnerToken(ctx, request)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleIssuePartnerToken failed")
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		h.log.Info().Msg(fmt.Sprintf("partner token issued to %s for user %s with scopes %v", token.Partner, token.UserID, token.Scopes))
+		resBody, err := json.Marshal(token)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleIssuePartnerToken failed")
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, err = w.Write(resBody)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleIssuePartnerToken failed")
+		}
+	}
+}
+
+// HandleTransferOrder processes admin requests moving an order along with its accrual to another account.
+func (h *Handler) HandleTransferOrder() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), h.serverConfig.StorageTimeout)
+		defer cancel()
+		if !hasContentType(r, "application/json") {
+			handlersErrors.WriteErrorCode(w, r, errcodes.InvalidRequest, "Invalid Content-Type", nil)
+			return
+		}
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleTransferOrder failed")
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		var request modeldto.OrderTransferRequest
+		if !decodeRequest(w, r, b, &request) {
+			h.log.Error().Msg("HandleTransferOrder failed")
+			return
+		}
+		transfer, err := h.service.TransferOrder(ctx, chi.URLParam(r, "number"), request)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleTransferOrder failed")
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		resBody, err := json.Marshal(transfer)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleTransferOrder failed")
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, err = w.Write(resBody)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleTransferOrder failed")
+		}
+	}
+}
+
+// HandleAdjustBalance processes admin requests crediting or debiting a user's balance manually.
+func (h *Handler) HandleAdjustBalance() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), h.serverConfig.StorageTimeout)
+		defer cancel()
+		if !hasContentType(r, "application/json") {
+			handlersErrors.WriteErrorCode(w, r, errcodes.InvalidRequest, "Invalid Content-Type", nil)
+			return
+		}
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleAdjustBalance failed")
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		var request modeldto.BalanceAdjustmentRequest
+		if !decodeRequest(w, r, b, &request) {
+			h.log.Error().Msg("HandleAdjustBalance failed")
+			return
+		}
+		adjustment, err := h.service.AdjustBalance(ctx, chi.URLParam(r, "id"), request)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleAdjustBalance failed")
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		resBody, err := json.Marshal(adjustment)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleAdjustBalance failed")
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, err = w.Write(resBody)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleAdjustBalance failed")
+		}
+	}
+}
+
+// HandleEvaluateCashback processes admin dry-run requests evaluating cashback rules against a hypothetical order.
+func (h *Handler) HandleEvaluateCashback() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !hasContentType(r, "application/json") {
+			handlersErrors.WriteErrorCode(w, r, errcodes.InvalidRequest, "Invalid Content-Type", nil)
+			return
+		}
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleEvaluateCashback failed")
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		var request modeldto.CashbackEvaluationRequest
+		if !decodeRequest(w, r, b, &request) {
+			h.log.Error().Msg("HandleEvaluateCashback failed")
+			return
+		}
+		evaluation := h.service.EvaluateCashback(request)
+		resBody, err := json.Marshal(evaluation)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleEvaluateCashback failed")
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, err = w.Write(resBody)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleEvaluateCashback failed")
+		}
+	}
+}
+
+// HandlePreviewOrder processes dry-run requests estimating the accrual of a hypothetical order, the cashback rules
+// are applied as for an order uploaded now through the request channel and nothing is stored.
+func (h *Handler) HandlePreviewOrder() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, err := h.getUserID(r)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandlePreviewOrder failed")
+			handlersErrors.WriteErrorCode(w, r, errcodes.Unauthorized, err.Error(), nil)
+			return
+		}
+		if !hasContentType(r, "application/json") {
+			handlersErrors.WriteErrorCode(w, r, errcodes.InvalidRequest, "Invalid Content-Type", nil)
+			return
+		}
+		channel, err := parseClientChannel(r)
+		if err != nil {
+			handlersErrors.WriteErrorCode(w, r, errcodes.InvalidRequest, err.Error(), nil)
+			return
+		}
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandlePreviewOrder failed")
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		var preview modeldto.OrderPreviewRequest
+		if !decodeRequest(w, r, b, &preview) {
+			h.log.Error().Msg("HandlePreviewOrder failed")
+			return
+		}
+		evaluation := h.service.EvaluateCashback(modeldto.CashbackEvaluationRequest{Accrual: preview.Accrual, Channel: channel})
+		resBody, err := json.Marshal(evaluation)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandlePreviewOrder failed")
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, err = w.Write(resBody)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandlePreviewOrder failed")
+		}
+	}
+}
+
+// HandleGetSummary processes admin operational summary requests.
+func (h *Handler) HandleGetSummary() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+		summary, err := h.service.GetSummary(ctx, h.adminConfig.SummaryWindows)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleGetSummary failed")
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		resBody, err := json.Marshal(summary)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleGetSummary failed")
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, err = w.Write(resBody)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleGetSummary failed")
+		}
+	}
+}
+
+// HandleMetrics exposes runtime and application metrics in Prometheus text format.
+func (h *Handler) HandleMetrics() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		w.WriteHeader(http.StatusOK)
+		err := h.metrics.WritePrometheus(w)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleMetrics failed")
+		}
+	}
+}
+
+// HandleGetVersion processes build version query requests.
+func (h *Handler) HandleGetVersion() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resBody, err := json.Marshal(buildinfo.Get())
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleGetVersion failed")
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, err = w.Write(resBody)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleGetVersion failed")
+		}
+	}
+}
+
+// HandleRegister processes user register requests.
+func (h *Handler) HandleRegister() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), h.serverConfig.StorageTimeout)
+		defer cancel()
+		if !hasContentType(r, "application/json") {
+			handlersErrors.WriteErrorCode(w, r, errcodes.InvalidRequest, "Invalid Content-Type", nil)
+			return
+		}
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleRegister failed")
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		var credentials modeldto.User
+		if !decodeRequest(w, r, b, &credentials) {
+			h.log.Error().Msg("HandleRegister failed")
+			return
+		}
+		h.log.Info().Msg(fmt.Sprintf("new user register request detected for %s", credentials))
+		tokens, err := h.service.AddNewUser(ctx, credentials, clientInfo(r))
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleRegister failed")
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		h.writeTokens(w, r, tokens, "HandleRegister")
+	}
+}
+
+// HandleLogin processes user login requests.
+func (h *Handler) HandleLogin() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), h.serverConfig.StorageTimeout)
+		defer cancel()
+		if !hasContentType(r, "application/json") {
+			handlersErrors.WriteErrorCode(w, r, errcodes.InvalidRequest, "Invalid Content-Type", nil)
+			return
+		}
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleLogin failed")
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		var credentials modeldto.User
+		if !decodeRequest(w, r, b, &credentials) {
+			h.log.Error().Msg("HandleLogin failed")
+			return
+		}
+		h.log.Info().Msg(fmt.Sprintf("new login request detected for %s", credentials))
+		tokens, err := h.service.LoginUser(ctx, credentials, clientInfo(r))
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleLogin failed")
+			var lockedError *serviceErrors.ServiceLoginLocked
+			if errors.As(err, &lockedError) {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(time.Until(lockedError.LockedUntil).Seconds()))))
+				handlersErrors.WriteErrorCode(w, r, errcodes.TooManyRequests, "Too many failed login attempts", handlersErrors.LoginThrottleDetails{LockedUntil: lockedError.LockedUntil.UTC().Format(time.RFC3339)})
+				return
+			}
+			var invalidCredentialsError *serviceErrors.ServiceInvalidCredentials
+			if errors.As(err, &invalidCredentialsError) {
+				handlersErrors.WriteErrorCode(w, r, errcodes.InvalidCredentials, "Invalid login or password", handlersErrors.LoginThrottleDetails{RemainingAttempts: invalidCredentialsError.RemainingAttempts})
+				return
+			}
+			var notFoundError *storageErrors.NotFoundError
+			if errors.As(err, &notFoundError) {
+				handlersErrors.WriteErrorCode(w, r, errcodes.InvalidCredentials, "Invalid login or password", nil)
+				return
+			}
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		h.writeTokens(w, r, tokens, "HandleLogin")
+	}
+}
+
+// HandleRefresh processes requests exchanging a refresh token for a new access token, the refresh token is
+// rotated with every exchange.
+func (h *Handler) HandleRefresh() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), h.serverConfig.StorageTimeout)
+		defer cancel()
+		if !hasContentType(r, "application/json") {
+			handlersErrors.WriteErrorCode(w, r, errcodes.InvalidRequest, "Invalid Content-Type", nil)
+			return
+		}
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleRefresh failed")
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		var request modeldto.RefreshRequest
+		if !decodeRequest(w, r, b, &request) {
+			h.log.Error().Msg("HandleRefresh failed")
+			return
+		}
+		tokens, err := h.service.RefreshTokens(ctx, request.RefreshToken)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleRefresh failed")
+			var notFoundError *storageErrors.NotFoundError
+			if errors.As(err, &notFoundError) {
+				handlersErrors.WriteErrorCode(w, r, errcodes.Unauthorized, "Refresh token is unknown", nil)
+				return
+			}
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		h.writeTokens(w, r, tokens, "HandleRefresh")
+	}
+}
+
+// HandleLogout processes logout requests, the session of the access token is revoked along with its refresh token.
+func (h *Handler) HandleLogout() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), h.serverConfig.StorageTimeout)
+		defer cancel()
+		userID, err := h.getUserID(r)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleLogout failed")
+			handlersErrors.WriteErrorCode(w, r, errcodes.Unauthorized, err.Error(), nil)
+			return
+		}
+		sessionID, ok := auth.SessionIDFromContext(r.Context())
+		if !ok {
+			handlersErrors.WriteErrorCode(w, r, errcodes.InvalidRequest, "Access token is not bound to a session", nil)
+			return
+		}
+		err = h.service.Logout(ctx, userID, sessionID)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleLogout failed")
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// writeTokens hands the access token out through the auth strategy and responds with the refresh token.
+func (h *Handler) writeTokens(w http.ResponseWriter, r *http.Request, tokens *modeldto.Tokens, handlerName string) {
+	resBody, err := json.Marshal(tokens)
+	if err != nil {
+		h.log.Error().Err(err).Msg(handlerName + " failed")
+		handlersErrors.WriteError(w, r, err)
+		return
+	}
+	h.authStrategy.Issue(w, tokens.AccessToken)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(resBody)
+	if err != nil {
+		h.log.Error().Err(err).Msg(handlerName + " failed")
+	}
+}
+
+// HandleUpdateProfile processes user profile update requests.
+func (h *Handler) HandleUpdateProfile() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), h.serverConfig.StorageTimeout)
+		defer cancel()
+		userID, err := h.getUserID(r)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleUpdateProfile failed")
+			handlersErrors.WriteErrorCode(w, r, errcodes.Unauthorized, err.Error(), nil)
+			return
+		}
+		if !hasContentType(r, "application/json") {
+			handlersErrors.WriteErrorCode(w, r, errcodes.InvalidRequest, "Invalid Content-Type", nil)
+			return
+		}
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleUpdateProfile failed")
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		var update modeldto.ProfileUpdate
+		if !decodeRequest(w, r, b, &update) {
+			h.log.Error().Msg("HandleUpdateProfile failed")
+			return
+		}
+		profile, err := h.service.UpdateProfile(ctx, userID, update)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleUpdateProfile failed")
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		resBody, err := json.Marshal(profile)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleUpdateProfile failed")
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, err = w.Write(resBody)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleUpdateProfile failed")
+		}
+	}
+}
+
+// HandleAccrualCallback processes final order statuses pushed by the accrual service.
+func (h *Handler) HandleAccrualCallback() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), h.serverConfig.StorageTimeout)
+		defer cancel()
+		if !hasContentType(r, "application/json") {
+			handlersErrors.WriteErrorCode(w, r, errcodes.InvalidRequest, "Invalid Content-Type", nil)
+			return
+		}
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleAccrualCallback failed")
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		var callback modeldto.AccrualResponse
+		if !decodeRequest(w, r, b, &callback) {
+			h.log.Error().Msg("HandleAccrualCallback failed")
+			return
+		}
+		err = h.service.AcceptAccrual(ctx, callback)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleAccrualCallback failed")
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// HandleGetBalance processes balance query requests.
+func (h *Handler) HandleGetBalance() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), h.serverConfig.StorageTimeout)
+		defer cancel()
+		userID, err := h.getUserID(r)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleBalance failed")
+			handlersErrors.WriteErrorCode(w, r, errcodes.Unauthorized, err.Error(), nil)
+			return
+		}
+		balance, err := h.service.GetBalance(ctx, userID)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleBalance failed")
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		if !balance.UpdatedAt.IsZero() {
+			w.Header().Set("Last-Modified", balance.UpdatedAt.UTC().Format(http.TimeFormat))
+			if notModified(r, balance.UpdatedAt) {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+		resBody, err := json.Marshal(balance)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleBalance failed")
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, err = w.Write(resBody)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleBalance failed")
+		}
+	}
+}
+
+// HandleGetConvertedBalance processes balance query requests converting amounts to the requested currency.
+func (h *Handler) HandleGetConvertedBalance() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), h.serverConfig.StorageTimeout)
+		defer cancel()
+		userID, err := h.getUserID(r)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleGetConvertedBalance failed")
+			handlersErrors.WriteErrorCode(w, r, errcodes.Unauthorized, err.Error(), nil)
+			return
+		}
+		currency := r.URL.Query().Get("currency")
+		if currency == "" {
+			handlersErrors.WriteErrorCode(w, r, errcodes.InvalidRequest, "Currency is required", nil)
+			return
+		}
+		balance, err := h.service.GetConvertedBalance(ctx, userID, currency)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleGetConvertedBalance failed")
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		resBody, err := json.Marshal(balance)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleGetConvertedBalance failed")
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, err = w.Write(resBody)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleGetConvertedBalance failed")
+		}
+	}
+}
+
+// HandleGetWithdrawals processes withdrawals query requests.
+func (h *Handler) HandleGetWithdrawals() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), h.serverConfig.StorageTimeout)
+		defer cancel()
+		userID, err := h.getUserID(r)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleWithdrawals failed")
+			handlersErrors.WriteErrorCode(w, r, errcodes.Unauthorized, err.Error(), nil)
+			return
+		}
+		withdrawals, err := h.service.GetWithdrawals(ctx, userID, parseSort(r))
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleBalance failed")
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		if len(withdrawals) == 0 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		err = writeJSONArray(w, len(withdrawals), func(i int) interface{} { return withdrawals[i] })
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleWithdrawals failed")
+		}
+	}
+}
+
+// HandleGetSessions processes session listing requests.
+func (h *Handler) HandleGetSessions() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), h.serverConfig.StorageTimeout)
+		defer cancel()
+		userID, err := h.getUserID(r)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleGetSessions failed")
+			handlersErrors.WriteErrorCode(w, r, errcodes.Unauthorized, err.Error(), nil)
+			return
+		}
+		sessions, err := h.service.GetSessions(ctx, userID)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleGetSessions failed")
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		if len(sessions) == 0 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		err = writeJSONArray(w, len(sessions), func(i int) interface{} { return sessions[i] })
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleGetSessions failed")
+		}
+	}
+}
+
+// HandleCreateTelegramLink processes requests for a one-time code linking a Telegram chat to the user.
+func (h *Handler) HandleCreateTelegramLink() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), h.serverConfig.StorageTimeout)
+		defer cancel()
+		userID, err := h.getUserID(r)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleCreateTelegramLink failed")
+			handlersErrors.WriteErrorCode(w, r, errcodes.Unauthorized, err.Error(), nil)
+			return
+		}
+		link, err := h.service.CreateTelegramLinkCode(ctx, userID)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleCreateTelegramLink failed")
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		resBody, err := json.Marshal(link)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleCreateTelegramLink failed")
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, err = w.Write(resBody)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleCreateTelegramLink failed")
+		}
+	}
+}
+
+// HandleGetAlerts processes balance alert thresholds query requests.
+func (h *Handler) HandleGetAlerts() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), h.serverConfig.StorageTimeout)
+		defer cancel()
+		userID, err := h.getUserID(r)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleGetAlerts failed")
+			handlersErrors.WriteErrorCode(w, r, errcodes.Unauthorized, err.Error(), nil)
+			return
+		}
+		thresholds, err := h.service.GetAlertThresholds(ctx, userID)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleGetAlerts failed")
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		resBody, err := json.Marshal(thresholds)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleGetAlerts failed")
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, err = w.Write(resBody)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleGetAlerts failed")
+		}
+	}
+}
+
+// HandleSetAlerts processes balance alert thresholds update requests, omitted thresholds disable the corresponding
+// alerts.
+func (h *Handler) HandleSetAlerts() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), h.serverConfig.StorageTimeout)
+		defer cancel()
+		userID, err := h.getUserID(r)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleSetAlerts failed")
+			handlersErrors.WriteErrorCode(w, r, errcodes.Unauthorized, err.Error(), nil)
+			return
+		}
+		if !hasContentType(r, "application/json") {
+			handlersErrors.WriteErrorCode(w, r, errcodes.InvalidRequest, "Invalid Content-Type", nil)
+			return
+		}
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleSetAlerts failed")
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		var thresholds modeldto.AlertThresholds
+		if !decodeRequest(w, r, b, &thresholds) {
+			h.log.Error().Msg("HandleSetAlerts failed")
+			return
+		}
+		updated, err := h.service.SetAlertThresholds(ctx, userID, thresholds)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleSetAlerts failed")
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		resBody, err := json.Marshal(updated)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleSetAlerts failed")
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, err = w.Write(resBody)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleSetAlerts failed")
+		}
+	}
+}
+
+// HandleGetTransactions processes balance transaction history requests.
+func (h *Handler) HandleGetTransactions() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), h.serverConfig.StorageTimeout)
+		defer cancel()
+		userID, err := h.getUserID(r)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleGetTransactions failed")
+			handlersErrors.WriteErrorCode(w, r, errcodes.Unauthorized, err.Error(), nil)
+			return
+		}
+		transactions, err := h.service.GetTransactions(ctx, userID)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleGetTransactions failed")
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		if len(transactions) == 0 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		err = writeJSONArray(w, len(transactions), func(i int) interface{} { return transactions[i] })
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleGetTransactions failed")
+		}
+	}
+}
+
+// HandleGetOrders processes orders query requests.
+func (h *Handler) HandleGetOrders() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), h.serverConfig.StorageTimeout)
+		defer cancel()
+		userID, err := h.getUserID(r)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleGetOrders failed")
+			handlersErrors.WriteErrorCode(w, r, errcodes.Unauthorized, err.Error(), nil)
+			return
+		}
+		orders, err := h.service.GetOrders(ctx, userID, parseSort(r))
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleGetOrders failed")
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		if len(orders) == 0 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		err = writeJSONArray(w, len(orders), func(i int) interface{} { return orders[i] })
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleGetOrders failed")
+		}
+	}
+}
+
+// HandleNewWithdrawal processes new withdrawal requests.
+func (h *Handler) HandleNewWithdrawal() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), h.serverConfig.StorageTimeout)
+		defer cancel()
+		userID, err := h.getUserID(r)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleGetOrders failed")
+			handlersErrors.WriteErrorCode(w, r, errcodes.Unauthorized, err.Error(), nil)
+			return
+		}
+		if !hasContentType(r, "application/json") {
+			handlersErrors.WriteErrorCode(w, r, errcodes.InvalidRequest, "Invalid Content-Type", nil)
+			return
+		}
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleNewWithdrawal failed")
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		var newOrderWithdrawal modeldto.NewOrderWithdrawal
+		if !decodeRequest(w, r, b, &newOrderWithdrawal) {
+			h.log.Error().Msg("HandleNewWithdrawal failed")
+			return
+		}
+		// the confirmation may carry the withdrawn sum converted to a currency, an unknown one is rejected upfront
+		var converted *modeldto.ConvertedAmount
+		if currency := r.URL.Query().Get("currency"); currency != "" {
+			converted, err = h.service.ConvertAmount(currency, newOrderWithdrawal.Amount)
+			if err != nil {
+				h.log.Error().Err(err).Msg("HandleNewWithdrawal failed")
+				handlersErrors.WriteError(w, r, err)
+				return
+			}
+		}
+		h.log.Info().Msg(fmt.Sprintf("new withdrawal request detected for %v", newOrderWithdrawal))
+		result, err := h.service.AddNewWithdrawal(ctx, userID, newOrderWithdrawal, r.Header.Get("Idempotency-Key"))
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleNewWithdrawal failed")
+			var alreadyExistsAndViolatesError *storageErrors.AlreadyExistsAndViolatesError
+			if errors.As(err, &alreadyExistsAndViolatesError) {
+				// an order number of another user is treated as an illegal one for withdrawals
+				handlersErrors.WriteErrorStatus(w, r, http.StatusUnprocessableEntity, errcodes.OrderOwnedByAnotherUser, err.Error(), nil)
+				return
+			}
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		result.Converted = converted
+		if result.Status == "PENDING" || converted != nil {
+			resBody, err := json.Marshal(result)
+			if err != nil {
+				h.log.Error().Err(err).Msg("HandleNewWithdrawal failed")
+				handlersErrors.WriteError(w, r, err)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			if result.Status == "PENDING" {
+				w.WriteHeader(http.StatusAccepted)
+			} else {
+				w.WriteHeader(http.StatusOK)
+			}
+			_, err = w.Write(resBody)
+			if err != nil {
+				h.log.Error().Err(err).Msg("HandleNewWithdrawal failed")
+			}
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
 }
 
-// HandleRegister processes user register requests.
-func (h *Handler) HandleRegister() http.HandlerFunc {
+// HandleGetWithdrawal processes single withdrawal status query requests.
+func (h *Handler) HandleGetWithdrawal() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), h.serverConfig.StorageTimeout)
+		defer cancel()
+		userID, err := h.getUserID(r)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleGetWithdrawal failed")
+			handlersErrors.WriteErrorCode(w, r, errcodes.Unauthorized, err.Error(), nil)
+			return
+		}
+		orderNumber := chi.URLParam(r, "number")
+		withdrawal, err := h.service.GetWithdrawal(ctx, userID, orderNumber)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleGetWithdrawal failed")
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		resBody, err := json.Marshal(withdrawal)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleGetWithdrawal failed")
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, err = w.Write(resBody)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleGetWithdrawal failed")
+		}
+	}
+}
+
+// HandleNewHold processes balance hold requests placed for pending purchases.
+func (h *Handler) HandleNewHold() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		ctx, cancel := context.WithTimeout(r.Context(), 500*time.Millisecond)
+		ctx, cancel := context.WithTimeout(r.Context(), h.serverConfig.StorageTimeout)
 		defer cancel()
-		if r.Header.Get("Content-Type") != "application/json" {
-			http.Error(w, "Invalid Content-Type", http.StatusBadRequest)
+		userID, err := h.getUserID(r)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleNewHold failed")
+			handlersErrors.WriteErrorCode(w, r, errcodes.Unauthorized, err.Error(), nil)
+			return
+		}
+		if !hasContentType(r, "application/json") {
+			handlersErrors.WriteErrorCode(w, r, errcodes.InvalidRequest, "Invalid Content-Type", nil)
+			return
 		}
 		b, err := ioutil.ReadAll(r.Body)
 		if err != nil {
-			h.log.Error().Err(err).Msg("HandleRegister failed")
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			h.log.Error().Err(err).Msg("HandleNewHold failed")
+			handlersErrors.WriteError(w, r, err)
 			return
 		}
-		var credentials modeldto.User
-		err = json.Unmarshal(b, &credentials)
+		var newBalanceHold modeldto.NewBalanceHold
+		if !decodeRequest(w, r, b, &newBalanceHold) {
+			h.log.Error().Msg("HandleNewHold failed")
+			return
+		}
+		hold, err := h.service.ReserveBalance(ctx, userID, newBalanceHold)
 		if err != nil {
-			h.log.Error().Err(err).Msg("HandleRegister failed")
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			h.log.Error().Err(err).Msg("HandleNewHold failed")
+			handlersErrors.WriteError(w, r, err)
 			return
 		}
-		h.log.Info().Msg(fmt.Sprintf("new user register request detected for %s", credentials))
-		if len(credentials.Login) == 0 || len(credentials.Password) == 0 {
-			h.log.Error().Msg("HandleRegister failed")
-			http.Error(w, "Empty values are not allowed", http.StatusBadRequest)
+		resBody, err := json.Marshal(hold)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleNewHold failed")
+			handlersErrors.WriteError(w, r, err)
 			return
 		}
-		accessToken, err := h.service.AddNewUser(ctx, credentials)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		_, err = w.Write(resBody)
 		if err != nil {
-			h.log.Error().Err(err).Msg("HandleRegister failed")
-			var contextTimeoutExceededError *storageErrors.ContextTimeoutExceededError
-			var alreadyExistsError *storageErrors.AlreadyExistsError
-			if errors.As(err, &contextTimeoutExceededError) {
-				http.Error(w, err.Error(), http.StatusGatewayTimeout)
-			} else if errors.As(err, &alreadyExistsError) {
-				w.WriteHeader(http.StatusConflict)
-			} else {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-			}
+			h.log.Error().Err(err).Msg("HandleNewHold failed")
+		}
+	}
+}
+
+// HandleGetHolds processes balance holds query requests.
+func (h *Handler) HandleGetHolds() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), h.serverConfig.StorageTimeout)
+		defer cancel()
+		userID, err := h.getUserID(r)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleGetHolds failed")
+			handlersErrors.WriteErrorCode(w, r, errcodes.Unauthorized, err.Error(), nil)
+			return
+		}
+		holds, err := h.service.GetHolds(ctx, userID)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleGetHolds failed")
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		if len(holds) == 0 {
+			w.WriteHeader(http.StatusNoContent)
 			return
 		}
-		w.Header().Set("Authorization", "Bearer "+accessToken)
+		resBody, err := json.Marshal(holds)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleGetHolds failed")
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
+		_, err = w.Write(resBody)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleGetHolds failed")
+		}
 	}
 }
 
-// HandleLogin processes user login requests.
-func (h *Handler) HandleLogin() http.HandlerFunc {
+// HandleCaptureHold processes requests turning a balance hold into a withdrawal.
+func (h *Handler) HandleCaptureHold() http.HandlerFunc {
+	return h.handleResolveHold("HandleCaptureHold", h.service.CaptureHold)
+}
+
+// HandleReleaseHold processes requests dropping a balance hold.
+func (h *Handler) HandleReleaseHold() http.HandlerFunc {
+	return h.handleResolveHold("HandleReleaseHold", h.service.ReleaseHold)
+}
+
+// handleResolveHold resolves the balance hold identified by the id URL parameter and responds with its final state.
+func (h *Handler) handleResolveHold(name string, resolve func(ctx context.Context, userID string, holdID uint) (*modeldto.BalanceHold, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), h.serverConfig.StorageTimeout)
+		defer cancel()
+		userID, err := h.getUserID(r)
+		if err != nil {
+			h.log.Error().Err(err).Msg(name + " failed")
+			handlersErrors.WriteErrorCode(w, r, errcodes.Unauthorized, err.Error(), nil)
+			return
+		}
+		holdID, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 0)
+		if err != nil {
+			handlersErrors.WriteErrorCode(w, r, errcodes.InvalidRequest, "Invalid hold ID", nil)
+			return
+		}
+		hold, err := resolve(ctx, userID, uint(holdID))
+		if err != nil {
+			h.log.Error().Err(err).Msg(name + " failed")
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		resBody, err := json.Marshal(hold)
+		if err != nil {
+			h.log.Error().Err(err).Msg(name + " failed")
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, err = w.Write(resBody)
+		if err != nil {
+			h.log.Error().Err(err).Msg(name + " failed")
+		}
+	}
+}
+
+// HandleNewOrder processes new order requests, the order number is passed either as plain text or as a JSON object.
+func (h *Handler) HandleNewOrder() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		ctx, cancel := context.WithTimeout(r.Context(), 500*time.Millisecond)
+		ctx, cancel := context.WithTimeout(r.Context(), h.serverConfig.StorageTimeout)
 		defer cancel()
-		if r.Header.Get("Content-Type") != "application/json" {
-			http.Error(w, "Invalid Content-Type", http.StatusBadRequest)
+		userID, err := h.getUserID(r)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleNewOrder failed")
+			handlersErrors.WriteErrorCode(w, r, errcodes.Unauthorized, err.Error(), nil)
+			return
+		}
+		isJSON := hasContentType(r, "application/json")
+		if !isJSON && !hasContentType(r, "text/plain") {
+			handlersErrors.WriteErrorCode(w, r, errcodes.InvalidRequest, "Invalid Content-Type", nil)
+			return
 		}
 		b, err := ioutil.ReadAll(r.Body)
 		if err != nil {
-			h.log.Error().Err(err).Msg("HandleLogin failed")
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			h.log.Error().Err(err).Msg("HandleNewOrder failed")
+			handlersErrors.WriteError(w, r, err)
 			return
 		}
-		var credentials modeldto.User
-		err = json.Unmarshal(b, &credentials)
+		orderNumber := string(b)
+		if isJSON {
+			var newOrder modeldto.NewOrderRequest
+			if !decodeRequest(w, r, b, &newOrder) {
+				h.log.Error().Msg("HandleNewOrder failed")
+				return
+			}
+			orderNumber = newOrder.OrderNumber
+		}
+		h.log.Info().Msg(fmt.Sprintf("new order request detected for order %s", orderNumber))
+		metadata, err := h.parseOrderMetadata(r)
 		if err != nil {
-			h.log.Error().Err(err).Msg("HandleLogin failed")
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			handlersErrors.WriteErrorCode(w, r, errcodes.InvalidRequest, err.Error(), nil)
 			return
 		}
-		h.log.Info().Msg(fmt.Sprintf("new login request detected for %s", credentials))
-		if credentials.Login == "" || credentials.Password == "" {
-			h.log.Error().Msg("HandleRegister failed")
-			http.Error(w, "Empty values are not allowed", http.StatusBadRequest)
+		channel, err := parseClientChannel(r)
+		if err != nil {
+			handlersErrors.WriteErrorCode(w, r, errcodes.InvalidRequest, err.Error(), nil)
 			return
 		}
-		accessToken, err := h.service.LoginUser(ctx, credentials)
+		err = h.service.AddNewOrder(ctx, userID, modeldto.NewOrder{OrderNumber: orderNumber, Metadata: metadata, Channel: channel})
 		if err != nil {
-			h.log.Error().Err(err).Msg("HandleLogin failed")
-			var contextTimeoutExceededError *storageErrors.ContextTimeoutExceededError
-			var notFoundError *storageErrors.NotFoundError
-			if errors.As(err, &contextTimeoutExceededError) {
-				http.Error(w, err.Error(), http.StatusGatewayTimeout)
-			} else if errors.As(err, &notFoundError) {
-				w.WriteHeader(http.StatusUnauthorized)
-			} else {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
+			var alreadyExistsError *storageErrors.AlreadyExistsError
+			if errors.As(err, &alreadyExistsError) {
+				// the order was already uploaded by the same user
+				w.WriteHeader(http.StatusOK)
+				return
 			}
+			h.log.Error().Err(err).Msg("HandleNewOrder failed")
+			handlersErrors.WriteError(w, r, err)
 			return
 		}
-		w.Header().Set("Authorization", "Bearer "+accessToken)
-		w.WriteHeader(http.StatusOK)
+		w.WriteHeader(http.StatusAccepted)
 	}
 }
 
-// HandleGetBalance processes balance query requests.
-func (h *Handler) HandleGetBalance() http.HandlerFunc {
+// parseOrderMetadata retrieves an optional JSON object attached to an order via the X-Order-Metadata header.
+func (h *Handler) parseOrderMetadata(r *http.Request) (json.RawMessage, error) {
+	header := r.Header.Get("X-Order-Metadata")
+	if header == "" {
+		return nil, nil
+	}
+	if len(header) > h.serverConfig.OrderMetadataMaxSize {
+		return nil, fmt.Errorf("order metadata must not exceed %d bytes", h.serverConfig.OrderMetadataMaxSize)
+	}
+	var metadata map[string]interface{}
+	err := json.Unmarshal([]byte(header), &metadata)
+	if err != nil || metadata == nil {
+		return nil, errors.New("order metadata must be a JSON object")
+	}
+	return json.RawMessage(header), nil
+}
+
+// clientChannels lists accepted values of the X-Client-Channel header.
+var clientChannels = map[string]bool{"web": true, "mobile": true, "partner": true}
+
+// parseClientChannel retrieves the order upload channel from the X-Client-Channel header, "unknown" is used if absent.
+func parseClientChannel(r *http.Request) (string, error) {
+	channel := strings.ToLower(strings.TrimSpace(r.Header.Get("X-Client-Channel")))
+	if channel == "" {
+		return "unknown", nil
+	}
+	if !clientChannels[channel] {
+		return "", fmt.Errorf("unsupported client channel %s", channel)
+	}
+	return channel, nil
+}
+
+// HandleGetUserStats processes user order statistics requests.
+func (h *Handler) HandleGetUserStats() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		ctx, cancel := context.WithTimeout(r.Context(), 500*time.Millisecond)
+		ctx, cancel := context.WithTimeout(r.Context(), h.serverConfig.StorageTimeout)
 		defer cancel()
 		userID, err := h.getUserID(r)
 		if err != nil {
-			h.log.Error().Err(err).Msg("HandleBalance failed")
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			h.log.Error().Err(err).Msg("HandleGetUserStats failed")
+			handlersErrors.WriteErrorCode(w, r, errcodes.Unauthorized, err.Error(), nil)
 			return
 		}
-		balance, err := h.service.GetBalance(ctx, userID)
+		stats, err := h.service.GetUserStats(ctx, userID)
 		if err != nil {
-			h.log.Error().Err(err).Msg("HandleBalance failed")
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			h.log.Error().Err(err).Msg("HandleGetUserStats failed")
+			handlersErrors.WriteError(w, r, err)
 			return
 		}
-		resBody, err := json.Marshal(balance)
+		resBody, err := json.Marshal(stats)
 		if err != nil {
-			h.log.Error().Err(err).Msg("HandleBalance failed")
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			h.log.Error().Err(err).Msg("HandleGetUserStats failed")
+			handlersErrors.WriteError(w, r, err)
 			return
 		}
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
 		_, err = w.Write(resBody)
 		if err != nil {
-			h.log.Error().Err(err).Msg("HandleBalance failed")
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			h.log.Error().Err(err).Msg("HandleGetUserStats failed")
 		}
 	}
 }
 
-// HandleGetWithdrawals processes withdrawals query requests.
-func (h *Handler) HandleGetWithdrawals() http.HandlerFunc {
+// HandleGetOrder processes single order query requests.
+func (h *Handler) HandleGetOrder() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		ctx, cancel := context.WithTimeout(r.Context(), 500*time.Millisecond)
+		ctx, cancel := context.WithTimeout(r.Context(), h.serverConfig.StorageTimeout)
 		defer cancel()
 		userID, err := h.getUserID(r)
 		if err != nil {
-			h.log.Error().Err(err).Msg("HandleWithdrawals failed")
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			h.log.Error().Err(err).Msg("HandleGetOrder failed")
+			handlersErrors.WriteErrorCode(w, r, errcodes.Unauthorized, err.Error(), nil)
 			return
 		}
-		withdrawals, err := h.service.GetWithdrawals(ctx, userID)
+		order, err := h.service.GetOrder(ctx, userID, chi.URLParam(r, "number"))
 		if err != nil {
-			h.log.Error().Err(err).Msg("HandleBalance failed")
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		if len(withdrawals) == 0 {
-			w.WriteHeader(http.StatusNoContent)
+			h.log.Error().Err(err).Msg("HandleGetOrder failed")
+			handlersErrors.WriteError(w, r, err)
 			return
 		}
-		resBody, err := json.Marshal(withdrawals)
+		resBody, err := json.Marshal(order)
 		if err != nil {
-			h.log.Error().Err(err).Msg("HandleWithdrawals failed")
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			h.log.Error().Err(err).Msg("HandleGetOrder failed")
+			handlersErrors.WriteError(w, r, err)
 			return
 		}
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
 		_, err = w.Write(resBody)
 		if err != nil {
-			h.log.Error().Err(err).Msg("HandleWithdrawals failed")
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			h.log.Error().Err(err).Msg("HandleGetOrder failed")
 		}
 	}
 }
 
-// HandleGetOrders processes orders query requests.
-func (h *Handler) HandleGetOrders() http.HandlerFunc {
+// HandleRecheckOrder processes requests to check an INVALID order against the accrual service once more.
+func (h *Handler) HandleRecheckOrder() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		ctx, cancel := context.WithTimeout(r.Context(), 500*time.Millisecond)
+		ctx, cancel := context.WithTimeout(r.Context(), h.serverConfig.StorageTimeout)
 		defer cancel()
 		userID, err := h.getUserID(r)
 		if err != nil {
-			h.log.Error().Err(err).Msg("HandleGetOrders failed")
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			h.log.Error().Err(err).Msg("HandleRecheckOrder failed")
+			handlersErrors.WriteErrorCode(w, r, errcodes.Unauthorized, err.Error(), nil)
 			return
 		}
-		orders, err := h.service.GetOrders(ctx, userID)
+		err = h.service.RecheckOrder(ctx, userID, chi.URLParam(r, "number"))
 		if err != nil {
-			h.log.Error().Err(err).Msg("HandleGetOrders failed")
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			h.log.Error().Err(err).Msg("HandleRecheckOrder failed")
+			writeRecheckError(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+// HandleAdminRecheckOrder processes admin requests to check any INVALID order against the accrual service once more.
+func (h *Handler) HandleAdminRecheckOrder() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), h.serverConfig.StorageTimeout)
+		defer cancel()
+		err := h.service.AdminRecheckOrder(ctx, chi.URLParam(r, "number"))
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleAdminRecheckOrder failed")
+			writeRecheckError(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+// HandleGetSuspendedOrders processes admin requests listing orders whose accrual exceeds a cap and awaits approval.
+func (h *Handler) HandleGetSuspendedOrders() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), h.serverConfig.StorageTimeout)
+		defer cancel()
+		orders, err := h.service.GetSuspendedOrders(ctx)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleGetSuspendedOrders failed")
+			handlersErrors.WriteError(w, r, err)
 			return
 		}
 		if len(orders) == 0 {
@@ -221,126 +1582,186 @@ func (h *Handler) HandleGetOrders() http.HandlerFunc {
 		}
 		resBody, err := json.Marshal(orders)
 		if err != nil {
-			h.log.Error().Err(err).Msg("HandleGetOrders failed")
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			h.log.Error().Err(err).Msg("HandleGetSuspendedOrders failed")
+			handlersErrors.WriteError(w, r, err)
 			return
 		}
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
 		_, err = w.Write(resBody)
 		if err != nil {
-			h.log.Error().Err(err).Msg("HandleGetOrders failed")
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			h.log.Error().Err(err).Msg("HandleGetSuspendedOrders failed")
 		}
 	}
 }
 
-// HandleNewWithdrawal processes new withdrawal requests.
-func (h *Handler) HandleNewWithdrawal() http.HandlerFunc {
+// HandleGetAdminOrder processes admin requests viewing an order along with the latest accrual system response.
+func (h *Handler) HandleGetAdminOrder() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		ctx, cancel := context.WithTimeout(r.Context(), 500*time.Millisecond)
+		ctx, cancel := context.WithTimeout(r.Context(), h.serverConfig.StorageTimeout)
 		defer cancel()
-		userID, err := h.getUserID(r)
+		order, err := h.service.GetAdminOrder(ctx, chi.URLParam(r, "number"))
 		if err != nil {
-			h.log.Error().Err(err).Msg("HandleGetOrders failed")
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			h.log.Error().Err(err).Msg("HandleGetAdminOrder failed")
+			handlersErrors.WriteError(w, r, err)
 			return
 		}
-		if r.Header.Get("Content-Type") != "application/json" {
-			http.Error(w, "Invalid Content-Type", http.StatusBadRequest)
-		}
-		b, err := ioutil.ReadAll(r.Body)
+		resBody, err := json.Marshal(order)
 		if err != nil {
-			h.log.Error().Err(err).Msg("HandleNewWithdrawal failed")
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			h.log.Error().Err(err).Msg("HandleGetAdminOrder failed")
+			handlersErrors.WriteError(w, r, err)
 			return
 		}
-		var newOrderWithdrawal modeldto.NewOrderWithdrawal
-		err = json.Unmarshal(b, &newOrderWithdrawal)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, err = w.Write(resBody)
 		if err != nil {
-			h.log.Error().Err(err).Msg("HandleNewWithdrawal failed")
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			h.log.Error().Err(err).Msg("HandleGetAdminOrder failed")
+		}
+	}
+}
+
+// HandleApproveOrder processes admin requests crediting the suspended accrual of an order.
+func (h *Handler) HandleApproveOrder() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), h.serverConfig.StorageTimeout)
+		defer cancel()
+		err := h.service.ApproveOrder(ctx, chi.URLParam(r, "number"))
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleApproveOrder failed")
+			handlersErrors.WriteError(w, r, err)
 			return
 		}
-		h.log.Info().Msg(fmt.Sprintf("new withdrawal request detected for %v", newOrderWithdrawal))
-		err = h.service.AddNewWithdrawal(ctx, userID, newOrderWithdrawal)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// HandleRejectOrder processes admin requests invalidating an order with a suspended accrual.
+func (h *Handler) HandleRejectOrder() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), h.serverConfig.StorageTimeout)
+		defer cancel()
+		err := h.service.RejectOrder(ctx, chi.URLParam(r, "number"))
 		if err != nil {
-			h.log.Error().Err(err).Msg("HandleNewWithdrawal failed")
-			var contextTimeoutExceededError *storageErrors.ContextTimeoutExceededError
-			var alreadyExistsError *storageErrors.AlreadyExistsError
-			var serviceIllegalOrderNumber *serviceErrors.ServiceIllegalOrderNumber
-			var serviceNotEnoughFunds *serviceErrors.ServiceNotEnoughFunds
-			if errors.As(err, &contextTimeoutExceededError) {
-				http.Error(w, err.Error(), http.StatusGatewayTimeout)
-			} else if errors.As(err, &serviceIllegalOrderNumber) || errors.As(err, &alreadyExistsError) {
-				w.WriteHeader(http.StatusUnprocessableEntity)
-			} else if errors.As(err, &serviceNotEnoughFunds) {
-				http.Error(w, err.Error(), http.StatusPaymentRequired)
-			} else {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-			}
+			h.log.Error().Err(err).Msg("HandleRejectOrder failed")
+			handlersErrors.WriteError(w, r, err)
 			return
 		}
-		w.WriteHeader(http.StatusOK)
+		w.WriteHeader(http.StatusNoContent)
 	}
 }
 
-// HandleNewOrder processes new order requests.
-func (h *Handler) HandleNewOrder() http.HandlerFunc {
+// writeRecheckError writes a recheck failure advertising when rate limited rechecks are allowed again.
+func writeRecheckError(w http.ResponseWriter, r *http.Request, err error) {
+	var rateLimitedError *storageErrors.RecheckRateLimitedError
+	if errors.As(err, &rateLimitedError) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(rateLimitedError.RetryAfter.Seconds()))))
+	}
+	handlersErrors.WriteError(w, r, err)
+}
+
+// HandleGetOrderHistory processes order status history requests.
+func (h *Handler) HandleGetOrderHistory() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		ctx, cancel := context.WithTimeout(r.Context(), 500*time.Millisecond)
+		ctx, cancel := context.WithTimeout(r.Context(), h.serverConfig.StorageTimeout)
 		defer cancel()
 		userID, err := h.getUserID(r)
 		if err != nil {
-			h.log.Error().Err(err).Msg("HandleNewOrder failed")
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			h.log.Error().Err(err).Msg("HandleGetOrderHistory failed")
+			handlersErrors.WriteErrorCode(w, r, errcodes.Unauthorized, err.Error(), nil)
 			return
 		}
-		if r.Header.Get("Content-Type") != "text/plain" {
-			http.Error(w, "Invalid Content-Type", http.StatusBadRequest)
-		}
-		b, err := ioutil.ReadAll(r.Body)
+		history, err := h.service.GetOrderHistory(ctx, userID, chi.URLParam(r, "number"))
 		if err != nil {
-			h.log.Error().Err(err).Msg("HandleNewOrder failed")
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			h.log.Error().Err(err).Msg("HandleGetOrderHistory failed")
+			handlersErrors.WriteError(w, r, err)
 			return
 		}
-		orderNumber := string(b)
-		h.log.Info().Msg(fmt.Sprintf("new order request detected for order %s", orderNumber))
-		err = h.service.AddNewOrder(ctx, userID, orderNumber)
+		resBody, err := json.Marshal(history)
 		if err != nil {
-			h.log.Error().Err(err).Msg("HandleNewWithdrawal failed")
-			var contextTimeoutExceededError *storageErrors.ContextTimeoutExceededError
-			var alreadyExistsError *storageErrors.AlreadyExistsError
-			var alreadyExistsAndViolatesError *storageErrors.AlreadyExistsAndViolatesError
-			var serviceIllegalOrderNumber *serviceErrors.ServiceIllegalOrderNumber
-			if errors.As(err, &contextTimeoutExceededError) {
-				http.Error(w, err.Error(), http.StatusGatewayTimeout)
-			} else if errors.As(err, &serviceIllegalOrderNumber) {
-				w.WriteHeader(http.StatusUnprocessableEntity)
-			} else if errors.As(err, &alreadyExistsError) {
-				w.WriteHeader(http.StatusOK)
-			} else if errors.As(err, &alreadyExistsAndViolatesError) {
-				w.WriteHeader(http.StatusConflict)
-			} else {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-			}
+			h.log.Error().Err(err).Msg("HandleGetOrderHistory failed")
+			handlersErrors.WriteError(w, r, err)
 			return
 		}
-		w.WriteHeader(http.StatusAccepted)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, err = w.Write(resBody)
+		if err != nil {
+			h.log.Error().Err(err).Msg("HandleGetOrderHistory failed")
+		}
 	}
 }
 
-// getUserID retrieves user identifier from the request metadata.
-func (h *Handler) getUserID(r *http.Request) (string, error) {
-	accessToken := r.Header.Get("Authorization")
-	if len(accessToken) == 0 {
-		return "", errors.New("token authorization required")
+// parseSort retrieves listing sort options from the request query, they are validated by storage.
+func parseSort(r *http.Request) modeldto.Sort {
+	return modeldto.Sort{
+		Field: r.URL.Query().Get("sort"),
+		Order: r.URL.Query().Get("order"),
+	}
+}
+
+// Listing page sizes used unless the limit query parameter selects another one within maxPageLimit.
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// parsePage retrieves the limit and the offset of a listing page from the request query, it returns false if either
+// is malformed or out of range.
+func parsePage(r *http.Request) (int, int, bool) {
+	limit, offset := defaultPageLimit, 0
+	var err error
+	if value := r.URL.Query().Get("limit"); value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxPageLimit {
+			return 0, 0, false
+		}
+	}
+	if value := r.URL.Query().Get("offset"); value != "" {
+		offset, err = strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			return 0, 0, false
+		}
 	}
-	accessToken = strings.Replace(accessToken, "Bearer ", "", 1)
-	userID, err := h.service.GetUserID(accessToken)
+	return limit, offset, true
+}
+
+// hasContentType checks whether the request media type matches the expected one ignoring parameters.
+func hasContentType(r *http.Request, expected string) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+	return mediaType == expected
+}
+
+// clientInfo retrieves the client device description from the request metadata.
+func clientInfo(r *http.Request) modeldto.ClientInfo {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	return modeldto.ClientInfo{
+		UserAgent: r.UserAgent(),
+		IP:        ip,
+	}
+}
+
+// notModified reports whether the If-Modified-Since request header is not older than the modification time,
+// the header has a one second precision.
+func notModified(r *http.Request, modifiedAt time.Time) bool {
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
 	if err != nil {
-		return "", err
+		return false
+	}
+	return !modifiedAt.Truncate(time.Second).After(since)
+}
+
+// getUserID retrieves user identifier from the request metadata.
+func (h *Handler) getUserID(r *http.Request) (string, error) {
+	userID, ok := auth.UserIDFromContext(r.Context())
+	if !ok {
+		return "", auth.ErrTokenRequired
 	}
 	return userID, nil
 }
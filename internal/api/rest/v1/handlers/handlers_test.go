@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/api/rest/v1/middleware"
+	"github.com/danilovkiri/dk-go-gophermart/internal/auth"
+	"github.com/danilovkiri/dk-go-gophermart/internal/config"
+	"github.com/danilovkiri/dk-go-gophermart/internal/errcodes"
+	"github.com/danilovkiri/dk-go-gophermart/internal/metrics"
+	"github.com/danilovkiri/dk-go-gophermart/internal/mocks"
+	"github.com/danilovkiri/dk-go-gophermart/internal/models/modeldto"
+	serviceErrors "github.com/danilovkiri/dk-go-gophermart/internal/service/processor/v1/errors"
+	"github.com/danilovkiri/dk-go-gophermart/internal/service/secretary/v1/modelclaims"
+	storageErrors "github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/errors"
+	"github.com/go-chi/chi"
+	"github.com/rs/zerolog"
+	"go.uber.org/mock/gomock"
+)
+
+const (
+	testUserID      = "user-1"
+	testAccessToken = "valid-token"
+)
+
+// readyChecker reports all dependencies as available.
+type readyChecker struct{}
+
+func (readyChecker) Ready() bool { return true }
+
+func (readyChecker) Report() modeldto.HealthReport { return modeldto.HealthReport{} }
+
+// newTestRouter routes the user registration, login and order upload endpoints to handlers backed by mocks,
+// the order upload is authenticated with a mocked secretary accepting testAccessToken only.
+func newTestRouter(t *testing.T) (http.Handler, *mocks.MockProcessor) {
+	ctrl := gomock.NewController(t)
+	service := mocks.NewMockProcessor(ctrl)
+	sec := mocks.NewMockSecretary(ctrl)
+	sec.EXPECT().ValidateClaims(gomock.Any()).DoAndReturn(func(accessToken string) (*modelclaims.MyCustomClaims, error) {
+		if accessToken != testAccessToken {
+			return nil, errors.New("invalid token")
+		}
+		return &modelclaims.MyCustomClaims{UserID: testUserID}, nil
+	}).AnyTimes()
+	strategy := middleware.NewAuthStrategy(&config.AuthConfig{Mode: "bearer"})
+	log := zerolog.Nop()
+	h, err := InitHandlers(service, strategy, &config.ServerConfig{StorageTimeout: time.Second}, &config.AdminConfig{}, &log, metrics.NewRegistry(), readyChecker{}, middleware.NewRecordHandler(&config.RecorderConfig{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	authenticator, err := auth.NewAuthenticator(sec, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokenHandler, err := middleware.NewTokenHandler(authenticator, strategy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := chi.NewRouter()
+	r.Post("/api/user/register", h.HandleRegister())
+	r.Post("/api/user/login", h.HandleLogin())
+	r.With(tokenHandler.TokenHandle).Post("/api/user/orders", h.HandleNewOrder())
+	return r, service
+}
+
+// serve sends a request to the router and returns the recorded response.
+func serve(router http.Handler, path, contentType, accessToken, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHandleRegister(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		err         error
+		called      bool
+		wantStatus  int
+	}{
+		{name: "registered", contentType: "application/json", body: `{"login":"alice","password":"secret"}`, called: true, wantStatus: http.StatusOK},
+		{name: "login taken", contentType: "application/json", body: `{"login":"alice","password":"secret"}`, err: &storageErrors.AlreadyExistsError{ID: "alice", Code: errcodes.LoginTaken}, called: true, wantStatus: http.StatusConflict},
+		{name: "missing password", contentType: "application/json", body: `{"login":"alice"}`, wantStatus: http.StatusBadRequest},
+		{name: "malformed body", contentType: "application/json", body: `{"login":`, wantStatus: http.StatusBadRequest},
+		{name: "wrong content type", contentType: "text/plain", body: `{"login":"alice","password":"secret"}`, wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, service := newTestRouter(t)
+			if tt.called {
+				var tokens *modeldto.Tokens
+				if tt.err == nil {
+					tokens = &modeldto.Tokens{AccessToken: "access", RefreshToken: "refresh"}
+				}
+				service.EXPECT().AddNewUser(gomock.Any(), modeldto.User{Login: "alice", Password: "secret"}, gomock.Any()).Return(tokens, tt.err)
+			}
+			rec := serve(router, "/api/user/register", tt.contentType, "", tt.body)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("got status %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus == http.StatusOK && rec.Header().Get("Authorization") != "Bearer access" {
+				t.Fatalf("got Authorization header %q, want the issued access token", rec.Header().Get("Authorization"))
+			}
+		})
+	}
+}
+
+func TestHandleLogin(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		err         error
+		called      bool
+		wantStatus  int
+	}{
+		{name: "logged in", contentType: "application/json", body: `{"login":"alice","password":"secret"}`, called: true, wantStatus: http.StatusOK},
+		{name: "wrong password", contentType: "application/json", body: `{"login":"alice","password":"secret"}`, err: &serviceErrors.ServiceInvalidCredentials{Err: errors.New("invalid password"), RemainingAttempts: 2}, called: true, wantStatus: http.StatusUnauthorized},
+		{name: "unknown login", contentType: "application/json", body: `{"login":"alice","password":"secret"}`, err: &storageErrors.NotFoundError{Err: errors.New("no rows")}, called: true, wantStatus: http.StatusUnauthorized},
+		{name: "locked", contentType: "application/json", body: `{"login":"alice","password":"secret"}`, err: &serviceErrors.ServiceLoginLocked{LockedUntil: time.Now().Add(time.Minute)}, called: true, wantStatus: http.StatusTooManyRequests},
+		{name: "missing login", contentType: "application/json", body: `{"password":"secret"}`, wantStatus: http.StatusBadRequest},
+		{name: "wrong content type", contentType: "text/plain", body: `{"login":"alice","password":"secret"}`, wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, service := newTestRouter(t)
+			if tt.called {
+				var tokens *modeldto.Tokens
+				if tt.err == nil {
+					tokens = &modeldto.Tokens{AccessToken: "access", RefreshToken: "refresh"}
+				}
+				service.EXPECT().LoginUser(gomock.Any(), modeldto.User{Login: "alice", Password: "secret"}, gomock.Any()).Return(tokens, tt.err)
+			}
+			rec := serve(router, "/api/user/login", tt.contentType, "", tt.body)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("got status %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus == http.StatusTooManyRequests && rec.Header().Get("Retry-After") == "" {
+				t.Fatal("got no Retry-After header for a locked login")
+			}
+		})
+	}
+}
+
+func TestHandleNewOrder(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		accessToken string
+		body        string
+		err         error
+		called      bool
+		wantStatus  int
+	}{
+		{name: "accepted", contentType: "text/plain", accessToken: testAccessToken, body: "12345678903", called: true, wantStatus: http.StatusAccepted},
+		{name: "accepted as json", contentType: "application/json", accessToken: testAccessToken, body: `{"order":"12345678903"}`, called: true, wantStatus: http.StatusAccepted},
+		{name: "uploaded by the same user", contentType: "text/plain", accessToken: testAccessToken, body: "12345678903", err: &storageErrors.AlreadyExistsError{ID: "12345678903"}, called: true, wantStatus: http.StatusOK},
+		{name: "uploaded by another user", contentType: "text/plain", accessToken: testAccessToken, body: "12345678903", err: &storageErrors.AlreadyExistsAndViolatesError{ID: "12345678903"}, called: true, wantStatus: http.StatusConflict},
+		{name: "illegal number", contentType: "text/plain", accessToken: testAccessToken, body: "12345678903", err: &serviceErrors.ServiceIllegalOrderNumber{Msg: "illegal order number"}, called: true, wantStatus: http.StatusUnprocessableEntity},
+		{name: "wrong content type", contentType: "application/xml", accessToken: testAccessToken, body: "12345678903", wantStatus: http.StatusBadRequest},
+		{name: "no token", contentType: "text/plain", body: "12345678903", wantStatus: http.StatusUnauthorized},
+		{name: "invalid token", contentType: "text/plain", accessToken: "forged", body: "12345678903", wantStatus: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, service := newTestRouter(t)
+			if tt.called {
+				service.EXPECT().AddNewOrder(gomock.Any(), testUserID, gomock.Any()).DoAndReturn(func(_ interface{}, _ string, order modeldto.NewOrder) error {
+					if order.OrderNumber != "12345678903" {
+						t.Errorf("got order number %q, want 12345678903", order.OrderNumber)
+					}
+					return tt.err
+				})
+			}
+			rec := serve(router, "/api/user/orders", tt.contentType, tt.accessToken, tt.body)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("got status %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandleRegisterServerError(t *testing.T) {
+	router, service := newTestRouter(t)
+	service.EXPECT().AddNewUser(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, errors.New("pq: connection refused to 10.0.0.5:5432"))
+	rec := serve(router, "/api/user/register", "application/json", "", `{"login":"alice","password":"secret"}`)
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("got status %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+	if strings.Contains(rec.Body.String(), "10.0.0.5") {
+		t.Fatalf("got body %s, server errors must not leak internals", rec.Body.String())
+	}
+}
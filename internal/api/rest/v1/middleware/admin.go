@@ -0,0 +1,37 @@
+// Package middleware provides various middleware functionality.
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	handlersErrors "github.com/danilovkiri/dk-go-gophermart/internal/api/rest/v1/errors"
+	"github.com/danilovkiri/dk-go-gophermart/internal/config"
+	"github.com/danilovkiri/dk-go-gophermart/internal/errcodes"
+)
+
+// AdminHandler sets object structure.
+type AdminHandler struct {
+	cfg *config.AdminConfig
+}
+
+// NewAdminHandler initializes a new admin access handler.
+func NewAdminHandler(cfg *config.AdminConfig) *AdminHandler {
+	return &AdminHandler{cfg: cfg}
+}
+
+// AdminHandle provides admin token verification functionality.
+func (a *AdminHandler) AdminHandle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if a.cfg.AdminToken == "" {
+			handlersErrors.WriteErrorCode(w, r, errcodes.Forbidden, "Admin API is disabled", nil)
+			return
+		}
+		token := r.Header.Get("X-Admin-Token")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(a.cfg.AdminToken)) != 1 {
+			handlersErrors.WriteErrorCode(w, r, errcodes.Unauthorized, "Admin token authorization required", nil)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
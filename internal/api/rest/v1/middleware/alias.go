@@ -0,0 +1,27 @@
+// Package middleware provides various middleware functionality.
+package middleware
+
+import (
+	"net/http"
+)
+
+// AliasHandler sets object structure.
+type AliasHandler struct {
+	aliases map[string]string
+}
+
+// NewAliasHandler initializes a new route alias handler.
+func NewAliasHandler(aliases map[string]string) *AliasHandler {
+	return &AliasHandler{aliases: aliases}
+}
+
+// AliasHandle rewrites aliased request paths to their routes before routing.
+func (a *AliasHandler) AliasHandle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if route, ok := a.aliases[r.URL.Path]; ok {
+			r.URL.Path = route
+			r.URL.RawPath = ""
+		}
+		next.ServeHTTP(w, r)
+	})
+}
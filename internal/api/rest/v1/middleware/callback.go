@@ -0,0 +1,37 @@
+// Package middleware provides various middleware functionality.
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	handlersErrors "github.com/danilovkiri/dk-go-gophermart/internal/api/rest/v1/errors"
+	"github.com/danilovkiri/dk-go-gophermart/internal/config"
+	"github.com/danilovkiri/dk-go-gophermart/internal/errcodes"
+)
+
+// CallbackHandler sets object structure.
+type CallbackHandler struct {
+	cfg *config.ServerConfig
+}
+
+// NewCallbackHandler initializes a new accrual callback access handler.
+func NewCallbackHandler(cfg *config.ServerConfig) *CallbackHandler {
+	return &CallbackHandler{cfg: cfg}
+}
+
+// CallbackHandle provides accrual callback token verification functionality.
+func (c *CallbackHandler) CallbackHandle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if c.cfg.AccrualCallbackToken == "" {
+			handlersErrors.WriteErrorCode(w, r, errcodes.Forbidden, "Accrual callbacks are disabled", nil)
+			return
+		}
+		token := r.Header.Get("X-Callback-Token")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(c.cfg.AccrualCallbackToken)) != 1 {
+			handlersErrors.WriteErrorCode(w, r, errcodes.Unauthorized, "Callback token authorization required", nil)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,37 @@
+// Package middleware provides various middleware functionality.
+package middleware
+
+import (
+	"net"
+	"net/http"
+
+	handlersErrors "github.com/danilovkiri/dk-go-gophermart/internal/api/rest/v1/errors"
+	"github.com/danilovkiri/dk-go-gophermart/internal/service/captcha/v1"
+)
+
+// CaptchaHandler sets object structure.
+type CaptchaHandler struct {
+	verifier captcha.Verifier
+	header   string
+}
+
+// NewCaptchaHandler initializes a new CAPTCHA handler reading tokens from the given header.
+func NewCaptchaHandler(verifier captcha.Verifier, header string) *CaptchaHandler {
+	return &CaptchaHandler{verifier: verifier, header: header}
+}
+
+// CaptchaHandle rejects requests which carry no valid CAPTCHA token.
+func (c *CaptchaHandler) CaptchaHandle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		remoteIP, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			remoteIP = r.RemoteAddr
+		}
+		err = c.verifier.Verify(r.Context(), r.Header.Get(c.header), remoteIP)
+		if err != nil {
+			handlersErrors.WriteError(w, r, err)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
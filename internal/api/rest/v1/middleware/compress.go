@@ -2,54 +2,230 @@
 package middleware
 
 import (
+	"bytes"
 	"compress/gzip"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/danilovkiri/dk-go-gophermart/internal/config"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Supported content encodings.
+const (
+	EncodingGzip   = "gzip"
+	EncodingBrotli = "br"
+	EncodingZstd   = "zstd"
 )
 
-// Type gzipWriter redefines http.ResponseWriter changing its Writer method to use gzip.
-type gzipWriter struct {
+// Compressor sets object structure.
+type Compressor struct {
+	level     int
+	minSize   int
+	encodings []string
+}
+
+// defaultCompressor preserves the legacy gzip-only behaviour of CompressHandle.
+var defaultCompressor = &Compressor{
+	level:     gzip.BestSpeed,
+	encodings: []string{EncodingGzip},
+}
+
+// NewCompressor initializes a new compressing handler, encodings are listed in order of server preference.
+func NewCompressor(cfg *config.CompressConfig) *Compressor {
+	var encodings []string
+	for _, encoding := range strings.Split(cfg.Encodings, ",") {
+		encoding = strings.TrimSpace(strings.ToLower(encoding))
+		switch encoding {
+		case EncodingGzip, EncodingBrotli, EncodingZstd:
+			encodings = append(encodings, encoding)
+		}
+	}
+	return &Compressor{
+		level:     cfg.Level,
+		minSize:   cfg.MinSize,
+		encodings: encodings,
+	}
+}
+
+// compressWriter redefines http.ResponseWriter buffering the response until the minimum size is reached.
+type compressWriter struct {
 	http.ResponseWriter
-	Writer io.Writer
+	compressor *Compressor
+	encoding   string
+	status     int
+	buf        bytes.Buffer
+	encoder    io.WriteCloser
+	passed     bool
+}
+
+// WriteHeader defers writing the status code until the compression decision is made.
+func (w *compressWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
 }
 
 // Write method redefines default http.ResponseWriter Write method.
-func (w gzipWriter) Write(b []byte) (int, error) {
-	return w.Writer.Write(b)
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if w.encoder != nil {
+		return w.encoder.Write(b)
+	}
+	if w.passed {
+		return w.ResponseWriter.Write(b)
+	}
+	if w.Header().Get("Content-Encoding") != "" {
+		w.passThrough()
+		return w.ResponseWriter.Write(b)
+	}
+	w.buf.Write(b)
+	if w.buf.Len() < w.compressor.minSize {
+		return len(b), nil
+	}
+	err := w.startEncoding()
+	if err != nil {
+		return 0, err
+	}
+	return len(b), nil
 }
 
-// CompressHandle serves as a middleware handler implementing gzip compressing.
-func CompressHandle(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
-			next.ServeHTTP(w, r)
+// startEncoding writes headers and the buffered data through the selected encoder.
+func (w *compressWriter) startEncoding() error {
+	encoder, err := w.compressor.newEncoder(w.encoding, w.ResponseWriter)
+	if err != nil {
+		return err
+	}
+	w.encoder = encoder
+	w.Header().Set("Content-Encoding", w.encoding)
+	w.Header().Del("Content-Length")
+	w.ResponseWriter.WriteHeader(w.statusCode())
+	_, err = w.encoder.Write(w.buf.Bytes())
+	w.buf.Reset()
+	return err
+}
+
+// passThrough writes headers and the buffered data without compression.
+func (w *compressWriter) passThrough() {
+	w.passed = true
+	w.ResponseWriter.WriteHeader(w.statusCode())
+	if w.buf.Len() > 0 {
+		w.ResponseWriter.Write(w.buf.Bytes())
+		w.buf.Reset()
+	}
+}
+
+// statusCode returns the deferred status code.
+func (w *compressWriter) statusCode() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+// Close flushes the remaining data.
+func (w *compressWriter) Close() error {
+	if w.encoder != nil {
+		return w.encoder.Close()
+	}
+	if !w.passed {
+		w.passThrough()
+	}
+	return nil
+}
+
+// Flush sends the data written so far to the client, a response still below the minimum size is compressed anyway
+// as flushing handlers do not know their final response size.
+func (w *compressWriter) Flush() {
+	if w.encoder == nil && !w.passed {
+		if w.Header().Get("Content-Encoding") != "" {
+			w.passThrough()
+		} else if w.startEncoding() != nil {
 			return
 		}
-		gz, err := gzip.NewWriterLevel(w, gzip.BestSpeed)
-		if err != nil {
-			io.WriteString(w, err.Error())
+	}
+	if flusher, ok := w.encoder.(interface{ Flush() error }); ok {
+		if flusher.Flush() != nil {
 			return
 		}
-		defer gz.Close()
-		w.Header().Set("Content-Encoding", "gzip")
-		next.ServeHTTP(gzipWriter{ResponseWriter: w, Writer: gz}, r)
-	})
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
 }
 
-// DecompressHandle serves as a middleware handler implementing gzip decompressing.
-func DecompressHandle(next http.Handler) http.Handler {
+// CompressHandle serves as a middleware handler implementing gzip compressing.
+func CompressHandle(next http.Handler) http.Handler {
+	return defaultCompressor.CompressHandle(next)
+}
+
+// CompressHandle serves as a middleware handler implementing negotiated compressing.
+func (c *Compressor) CompressHandle(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if !strings.Contains(r.Header.Get("Content-Encoding"), "gzip") {
-			next.ServeHTTP(w, r)
-			return
+		// the response depends on the request encodings whether it is compressed or not
+		if len(c.encodings) > 0 {
+			w.Header().Add("Vary", "Accept-Encoding")
 		}
-		gz, err := gzip.NewReader(r.Body)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+		encoding := c.negotiate(r.Header.Get("Accept-Encoding"))
+		if encoding == "" {
+			next.ServeHTTP(w, r)
 			return
 		}
-		r.Body = gz
-		next.ServeHTTP(w, r)
+		cw := &compressWriter{ResponseWriter: w, compressor: c, encoding: encoding}
+		defer cw.Close()
+		next.ServeHTTP(cw, r)
 	})
 }
+
+// negotiate selects the most preferred server encoding accepted by the client.
+func (c *Compressor) negotiate(acceptEncoding string) string {
+	if acceptEncoding == "" {
+		return ""
+	}
+	accepted := make(map[string]float64)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		name := strings.ToLower(strings.TrimSpace(fields[0]))
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if value, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64); err == nil {
+					q = value
+				}
+			}
+		}
+		accepted[name] = q
+	}
+	for _, encoding := range c.encodings {
+		if q, ok := accepted[encoding]; ok && q > 0 {
+			return encoding
+		}
+	}
+	if q, ok := accepted["*"]; ok && q > 0 && len(c.encodings) > 0 {
+		return c.encodings[0]
+	}
+	return ""
+}
+
+// newEncoder creates a compressing writer for a given encoding.
+func (c *Compressor) newEncoder(encoding string, w io.Writer) (io.WriteCloser, error) {
+	switch encoding {
+	case EncodingBrotli:
+		level := c.level
+		if level < brotli.BestSpeed || level > brotli.BestCompression {
+			level = brotli.DefaultCompression
+		}
+		return brotli.NewWriterLevel(w, level), nil
+	case EncodingZstd:
+		return zstd.NewWriter(w, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(c.level)))
+	default:
+		level := c.level
+		if level < gzip.HuffmanOnly || level > gzip.BestCompression {
+			level = gzip.DefaultCompression
+		}
+		return gzip.NewWriterLevel(w, level)
+	}
+}
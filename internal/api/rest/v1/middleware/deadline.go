@@ -0,0 +1,114 @@
+// Package middleware provides various middleware functionality.
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	handlersErrors "github.com/danilovkiri/dk-go-gophermart/internal/api/rest/v1/errors"
+	"github.com/danilovkiri/dk-go-gophermart/internal/config"
+	"github.com/danilovkiri/dk-go-gophermart/internal/errcodes"
+)
+
+// DeadlineHandler sets object structure.
+type DeadlineHandler struct {
+	timeout    time.Duration
+	retryAfter time.Duration
+}
+
+// NewDeadlineHandler initializes a new request deadline handler.
+func NewDeadlineHandler(cfg *config.ServerConfig) *DeadlineHandler {
+	return &DeadlineHandler{timeout: cfg.RequestTimeout, retryAfter: cfg.RequestRetryAfter}
+}
+
+// deadlineWriter redefines http.ResponseWriter buffering the response until the handler returns in time.
+type deadlineWriter struct {
+	mu       sync.Mutex
+	header   http.Header
+	status   int
+	buf      bytes.Buffer
+	timedOut bool
+}
+
+// Header method redefines default http.ResponseWriter Header method.
+func (w *deadlineWriter) Header() http.Header {
+	return w.header
+}
+
+// WriteHeader method redefines default http.ResponseWriter WriteHeader method.
+func (w *deadlineWriter) WriteHeader(status int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut || w.status != 0 {
+		return
+	}
+	w.status = status
+}
+
+// Write method redefines default http.ResponseWriter Write method.
+func (w *deadlineWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.buf.Write(b)
+}
+
+// DeadlineHandle cancels the request context once the budget is exhausted and responds with 503 and Retry-After,
+// responses are buffered so that a handler running out of time never leaves a half-written response behind.
+func (d *DeadlineHandler) DeadlineHandle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if d.timeout <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), d.timeout)
+		defer cancel()
+		dw := &deadlineWriter{header: make(http.Header)}
+		done := make(chan struct{})
+		panicChan := make(chan interface{}, 1)
+		go func() {
+			defer func() {
+				if p := recover(); p != nil {
+					panicChan <- p
+				}
+			}()
+			next.ServeHTTP(dw, r.WithContext(ctx))
+			close(done)
+		}()
+		select {
+		case p := <-panicChan:
+			panic(p)
+		case <-done:
+			dw.mu.Lock()
+			defer dw.mu.Unlock()
+			for key, values := range dw.header {
+				w.Header()[key] = values
+			}
+			if dw.status == 0 {
+				dw.status = http.StatusOK
+			}
+			w.WriteHeader(dw.status)
+			w.Write(dw.buf.Bytes())
+		case <-ctx.Done():
+			dw.mu.Lock()
+			defer dw.mu.Unlock()
+			dw.timedOut = true
+			if r.Context().Err() != nil {
+				// the client has gone away, there is nobody to respond to
+				return
+			}
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(d.retryAfter.Seconds()))))
+			handlersErrors.WriteErrorCode(w, r, errcodes.DeadlineExceeded, "Request deadline exceeded", nil)
+		}
+	})
+}
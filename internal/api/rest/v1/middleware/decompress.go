@@ -0,0 +1,195 @@
+package middleware
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	handlersErrors "github.com/danilovkiri/dk-go-gophermart/internal/api/rest/v1/errors"
+	"github.com/danilovkiri/dk-go-gophermart/internal/config"
+	"github.com/danilovkiri/dk-go-gophermart/internal/errcodes"
+	"github.com/klauspost/compress/zstd"
+)
+
+// EncodingDeflate is the deflate content encoding, bodies are accepted both zlib-wrapped and raw.
+const EncodingDeflate = "deflate"
+
+// defaultDecompressMaxSize limits decompressed request bodies of the legacy DecompressHandle.
+const defaultDecompressMaxSize = 10 << 20
+
+// zstdMaxWindow limits the window size of zstd request bodies.
+const zstdMaxWindow = 8 << 20
+
+// acceptedEncodings is advertised to clients sending an unsupported content encoding.
+var acceptedEncodings = strings.Join([]string{EncodingGzip, EncodingDeflate, EncodingBrotli, EncodingZstd}, ", ")
+
+// Decompressor sets object structure.
+type Decompressor struct {
+	maxSize int64
+}
+
+// defaultDecompressor serves the legacy DecompressHandle.
+var defaultDecompressor = &Decompressor{maxSize: defaultDecompressMaxSize}
+
+// NewDecompressor initializes a new decompressing handler.
+func NewDecompressor(cfg *config.CompressConfig) *Decompressor {
+	return &Decompressor{maxSize: cfg.DecompressMaxSize}
+}
+
+// DecompressHandle serves as a middleware handler implementing request body decompressing.
+func DecompressHandle(next http.Handler) http.Handler {
+	return defaultDecompressor.DecompressHandle(next)
+}
+
+// DecompressHandle serves as a middleware handler decoding gzip, deflate, brotli and zstd request bodies, encodings
+// applied in sequence are decoded in reverse order. Unsupported encodings are rejected with 415, decoding failures
+// and bodies exceeding the size limit once decompressed fail reading the body with errors mapped to 400 and 413.
+func (d *Decompressor) DecompressHandle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var encodings []string
+		for _, encoding := range strings.Split(r.Header.Get("Content-Encoding"), ",") {
+			encoding = strings.ToLower(strings.TrimSpace(encoding))
+			if encoding != "" && encoding != "identity" {
+				encodings = append(encodings, encoding)
+			}
+		}
+		if len(encodings) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		body := &decodedBody{closers: []io.Closer{r.Body}}
+		reader := io.Reader(r.Body)
+		for i := len(encodings) - 1; i >= 0; i-- {
+			decoder, err := newDecoder(encodings[i], reader)
+			if errors.Is(err, errUnsupportedEncoding) {
+				body.Close()
+				w.Header().Set("Accept-Encoding", acceptedEncodings)
+				handlersErrors.WriteErrorCode(w, r, errcodes.UnsupportedMediaType, fmt.Sprintf("Unsupported Content-Encoding %q", encodings[i]), nil)
+				return
+			}
+			if err != nil {
+				body.Close()
+				handlersErrors.WriteError(w, r, &handlersErrors.MalformedEncodingError{Encoding: encodings[i], Err: err})
+				return
+			}
+			if closer, ok := decoder.(io.Closer); ok {
+				body.closers = append(body.closers, closer)
+			}
+			reader = &decodingReader{reader: decoder, encoding: encodings[i]}
+		}
+		body.reader, body.remaining, body.limit = reader, d.maxSize, d.maxSize
+		r.Body = body
+		r.Header.Del("Content-Encoding")
+		r.Header.Del("Content-Length")
+		r.ContentLength = -1
+		next.ServeHTTP(w, r)
+	})
+}
+
+// errUnsupportedEncoding is returned by newDecoder for unknown encodings.
+var errUnsupportedEncoding = errors.New("unsupported content encoding")
+
+// newDecoder creates a decompressing reader for a given encoding.
+func newDecoder(encoding string, r io.Reader) (io.Reader, error) {
+	switch encoding {
+	case EncodingGzip, "x-gzip":
+		return gzip.NewReader(r)
+	case EncodingDeflate:
+		return newDeflateReader(r)
+	case EncodingBrotli:
+		return brotli.NewReader(r), nil
+	case EncodingZstd:
+		// windows above the 8 MiB recommended for HTTP content coding are refused to bound memory usage
+		decoder, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1), zstd.WithDecoderLowmem(true), zstd.WithDecoderMaxWindow(zstdMaxWindow))
+		if err != nil {
+			return nil, err
+		}
+		return zstdCloser{decoder}, nil
+	default:
+		return nil, errUnsupportedEncoding
+	}
+}
+
+// newDeflateReader decodes deflate bodies sent either wrapped into zlib as the standard requires or raw
+// as some clients do, the zlib header is recognised by its compression method and check bits.
+func newDeflateReader(r io.Reader) (io.Reader, error) {
+	buffered := bufio.NewReader(r)
+	header, err := buffered.Peek(2)
+	if err == nil && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(buffered)
+	}
+	return flate.NewReader(buffered), nil
+}
+
+// zstdCloser adapts zstd.Decoder to io.Closer, the decoder releases its resources upon closing.
+type zstdCloser struct {
+	*zstd.Decoder
+}
+
+// Close closes the decoder.
+func (z zstdCloser) Close() error {
+	z.Decoder.Close()
+	return nil
+}
+
+// decodingReader reports decoding failures as malformed request bodies.
+type decodingReader struct {
+	reader   io.Reader
+	encoding string
+}
+
+// Read reads decoded data.
+func (d *decodingReader) Read(p []byte) (int, error) {
+	n, err := d.reader.Read(p)
+	if err != nil && err != io.EOF {
+		var malformed *handlersErrors.MalformedEncodingError
+		if !errors.As(err, &malformed) {
+			err = &handlersErrors.MalformedEncodingError{Encoding: d.encoding, Err: err}
+		}
+	}
+	return n, err
+}
+
+// decodedBody limits the size of a decoded request body and closes decoders along with the original body.
+type decodedBody struct {
+	reader    io.Reader
+	remaining int64
+	limit     int64
+	closers   []io.Closer
+}
+
+// Read reads decoded data failing once the size limit is exceeded.
+func (b *decodedBody) Read(p []byte) (int, error) {
+	if b.remaining <= 0 {
+		// a single byte beyond the limit tells an oversized body apart from one of exactly the limit size
+		n, err := b.reader.Read(make([]byte, 1))
+		if n > 0 {
+			return 0, &handlersErrors.BodyTooLargeError{Limit: b.limit}
+		}
+		return 0, err
+	}
+	if int64(len(p)) > b.remaining {
+		p = p[:b.remaining]
+	}
+	n, err := b.reader.Read(p)
+	b.remaining -= int64(n)
+	return n, err
+}
+
+// Close closes decoders and the original body.
+func (b *decodedBody) Close() error {
+	var err error
+	for i := len(b.closers) - 1; i >= 0; i-- {
+		if closeErr := b.closers[i].Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
@@ -0,0 +1,35 @@
+// Package middleware provides various middleware functionality.
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+
+	handlersErrors "github.com/danilovkiri/dk-go-gophermart/internal/api/rest/v1/errors"
+	"github.com/danilovkiri/dk-go-gophermart/internal/errcodes"
+	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1"
+)
+
+// DegradedHandler sets object structure.
+type DegradedHandler struct {
+	reporter storage.HealthReporter
+}
+
+// NewDegradedHandler initializes a new degraded mode handler.
+func NewDegradedHandler(reporter storage.HealthReporter) *DegradedHandler {
+	return &DegradedHandler{reporter: reporter}
+}
+
+// DegradedHandle rejects requests with 503 while storage is unhealthy.
+func (d *DegradedHandler) DegradedHandle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !d.reporter.Healthy() {
+			seconds := int(math.Ceil(d.reporter.RetryAfter().Seconds()))
+			w.Header().Set("Retry-After", strconv.Itoa(seconds))
+			handlersErrors.WriteErrorCode(w, r, errcodes.StorageUnavailable, "Storage is temporarily unavailable", nil)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/metrics"
+	"github.com/go-chi/chi"
+	chiMiddleware "github.com/go-chi/chi/middleware"
+	"github.com/rs/zerolog"
+)
+
+// unmatchedRoute labels requests not matching any route so that arbitrary URLs do not create new series.
+const unmatchedRoute = "unmatched"
+
+// InstrumentHandler sets object structure.
+type InstrumentHandler struct {
+	log     *zerolog.Logger
+	metrics *metrics.Registry
+}
+
+// NewInstrumentHandler initializes a new request metrics and access log handler.
+func NewInstrumentHandler(log *zerolog.Logger, reg *metrics.Registry) *InstrumentHandler {
+	return &InstrumentHandler{
+		log:     log,
+		metrics: reg,
+	}
+}
+
+// InstrumentHandle counts requests and their duration and writes an access log entry labelled by the chi route
+// pattern, e.g. /api/user/orders/{number}, rather than the raw URL to keep metric cardinality bounded. The pattern
+// is only known once routing is done, so it has to be mounted on the root router.
+func (c *InstrumentHandler) InstrumentHandle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := chiMiddleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+		duration := time.Since(start)
+		route := unmatchedRoute
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+		status := ww.Status()
+		if status == 0 {
+			// nothing was written, net/http replies with 200
+			status = http.StatusOK
+		}
+		c.metrics.Counter("gophermart_http_requests_total", "method", r.Method, "route", route, "status", strconv.Itoa(status)).Inc()
+		c.metrics.Counter("gophermart_http_request_duration_milliseconds_total", "method", r.Method, "route", route).Add(uint64(duration.Milliseconds()))
+		c.log.Info().
+			Str("request_id", chiMiddleware.GetReqID(r.Context())).
+			Str("method", r.Method).
+			Str("route", route).
+			Int("status", status).
+			Int("bytes", ww.BytesWritten()).
+			Dur("duration", duration).
+			Msg("request served")
+	})
+}
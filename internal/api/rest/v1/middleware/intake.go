@@ -0,0 +1,42 @@
+// Package middleware provides various middleware functionality.
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	handlersErrors "github.com/danilovkiri/dk-go-gophermart/internal/api/rest/v1/errors"
+	"github.com/danilovkiri/dk-go-gophermart/internal/errcodes"
+)
+
+// IntakeHandler sets object structure.
+type IntakeHandler struct {
+	stopped    int32
+	retryAfter time.Duration
+}
+
+// NewIntakeHandler initializes a new intake handler, retryAfter is advertised to clients once intake is stopped.
+func NewIntakeHandler(retryAfter time.Duration) *IntakeHandler {
+	return &IntakeHandler{retryAfter: retryAfter}
+}
+
+// Stop makes the handler reject all subsequent requests.
+func (i *IntakeHandler) Stop() {
+	atomic.StoreInt32(&i.stopped, 1)
+}
+
+// IntakeHandle rejects requests with 503 once intake is stopped during shutdown.
+func (i *IntakeHandler) IntakeHandle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&i.stopped) == 1 {
+			seconds := int(math.Ceil(i.retryAfter.Seconds()))
+			w.Header().Set("Retry-After", strconv.Itoa(seconds))
+			handlersErrors.WriteErrorCode(w, r, errcodes.ServiceUnavailable, "Service is shutting down", nil)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
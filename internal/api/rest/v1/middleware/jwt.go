@@ -3,43 +3,55 @@ package middleware
 
 import (
 	"errors"
-	"github.com/danilovkiri/dk-go-gophermart/internal/config"
-	"github.com/danilovkiri/dk-go-gophermart/internal/service/secretary/v1"
 	"net/http"
-	"strings"
+
+	handlersErrors "github.com/danilovkiri/dk-go-gophermart/internal/api/rest/v1/errors"
+	"github.com/danilovkiri/dk-go-gophermart/internal/auth"
+	"github.com/danilovkiri/dk-go-gophermart/internal/errcodes"
+	"github.com/danilovkiri/dk-go-gophermart/internal/tracing"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // TokenHandler sets object structure.
 type TokenHandler struct {
-	sec secretary.Secretary
-	cfg *config.SecretConfig
+	authenticator *auth.Authenticator
+	strategy      AuthStrategy
 }
 
 // NewTokenHandler initializes a new token handler.
-func NewTokenHandler(sec secretary.Secretary, cfg *config.SecretConfig) (*TokenHandler, error) {
-	if sec == nil {
-		return nil, errors.New("nil secretary object was found")
+func NewTokenHandler(authenticator *auth.Authenticator, strategy AuthStrategy) (*TokenHandler, error) {
+	if authenticator == nil {
+		return nil, errors.New("nil authenticator object was found")
+	}
+	if strategy == nil {
+		return nil, errors.New("nil auth strategy object was found")
 	}
 	return &TokenHandler{
-		sec: sec,
-		cfg: cfg,
+		authenticator: authenticator,
+		strategy:      strategy,
 	}, nil
 }
 
 // TokenHandle provides token handling functionality.
 func (c *TokenHandler) TokenHandle(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		tokenString := r.Header.Get("Authorization")
-		if len(tokenString) == 0 {
-			http.Error(w, "Token authorization required", http.StatusUnauthorized)
+		credentials, err := c.strategy.Credentials(r)
+		if err != nil {
+			handlersErrors.WriteErrorCode(w, r, errcodes.InvalidRequest, err.Error(), nil)
+			return
+		}
+		ctx, err := c.authenticator.Authenticate(r.Context(), credentials)
+		if errors.Is(err, auth.ErrTokenRequired) {
+			handlersErrors.WriteErrorCode(w, r, errcodes.Unauthorized, "Token authorization required", nil)
 			return
 		}
-		tokenString = strings.Replace(tokenString, "Bearer ", "", 1)
-		_, err := c.sec.ValidateToken(tokenString)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusUnauthorized)
+			handlersErrors.WriteErrorCode(w, r, errcodes.Unauthorized, err.Error(), nil)
 			return
 		}
-		next.ServeHTTP(w, r)
+		if userID, ok := auth.UserIDFromContext(ctx); ok {
+			trace.SpanFromContext(ctx).SetAttributes(tracing.UserIDHash(userID))
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
@@ -0,0 +1,160 @@
+// Package middleware provides various middleware functionality.
+package middleware
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/auth"
+	"github.com/danilovkiri/dk-go-gophermart/internal/tenant"
+)
+
+// cachedResponse is a stored response along with its expiration time.
+type cachedResponse struct {
+	status    int
+	header    http.Header
+	body      []byte
+	expiresAt time.Time
+}
+
+// ResponseCache sets object structure.
+type ResponseCache struct {
+	ttl       time.Duration
+	mu        sync.Mutex
+	responses map[string]map[string]cachedResponse
+	lastPurge time.Time
+}
+
+// NewResponseCache initializes a new response micro-cache, zero TTL disables caching.
+func NewResponseCache(ttl time.Duration) *ResponseCache {
+	return &ResponseCache{
+		ttl:       ttl,
+		responses: make(map[string]map[string]cachedResponse),
+		lastPurge: time.Now(),
+	}
+}
+
+// recordingWriter redefines http.ResponseWriter keeping a copy of the response.
+type recordingWriter struct {
+	http.ResponseWriter
+	status int
+	header http.Header
+	buf    bytes.Buffer
+}
+
+// record keeps the status and the headers set by the handler, they are copied before outer middleware
+// (e.g. compression) gets to alter them.
+func (w *recordingWriter) record(status int) {
+	if w.status != 0 {
+		return
+	}
+	w.status = status
+	w.header = w.ResponseWriter.Header().Clone()
+	w.header.Del("X-Cache")
+}
+
+// WriteHeader method redefines default http.ResponseWriter WriteHeader method.
+func (w *recordingWriter) WriteHeader(status int) {
+	w.record(status)
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write method redefines default http.ResponseWriter Write method.
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	w.record(http.StatusOK)
+	w.buf.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+// CacheHandle serves repeated authenticated GET requests from memory within the TTL, responses are keyed by user
+// and route. Any other request of a user drops the user's cached responses as it may change them.
+// Responses are marked private so that shared caches never store them.
+func (c *ResponseCache) CacheHandle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := auth.UserIDFromContext(r.Context())
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		userKey := tenant.FromContext(r.Context()) + "/" + userID
+		if r.Method != http.MethodGet {
+			next.ServeHTTP(w, r)
+			c.invalidate(userKey)
+			return
+		}
+		if c.ttl <= 0 {
+			w.Header().Set("Cache-Control", "private, no-cache")
+			next.ServeHTTP(w, r)
+			return
+		}
+		routeKey := r.URL.RequestURI()
+		if response, ok := c.get(userKey, routeKey); ok {
+			for key, values := range response.header {
+				w.Header()[key] = values
+			}
+			w.Header().Set("X-Cache", "HIT")
+			w.WriteHeader(response.status)
+			_, _ = w.Write(response.body)
+			return
+		}
+		w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", int(c.ttl.Seconds())))
+		w.Header().Set("X-Cache", "MISS")
+		recorder := &recordingWriter{ResponseWriter: w}
+		next.ServeHTTP(recorder, r)
+		// only successful responses are cached, errors should not outlive their cause
+		if recorder.status != http.StatusOK && recorder.status != http.StatusNoContent {
+			return
+		}
+		c.set(userKey, routeKey, cachedResponse{
+			status:    recorder.status,
+			header:    recorder.header,
+			body:      recorder.buf.Bytes(),
+			expiresAt: time.Now().Add(c.ttl),
+		})
+	})
+}
+
+// get retrieves a non-expired cached response.
+func (c *ResponseCache) get(userKey, routeKey string) (cachedResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	response, ok := c.responses[userKey][routeKey]
+	if !ok || time.Now().After(response.expiresAt) {
+		return cachedResponse{}, false
+	}
+	return response, true
+}
+
+// set stores a response, expired responses are purged once per TTL.
+func (c *ResponseCache) set(userKey, routeKey string, response cachedResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if now.Sub(c.lastPurge) > c.ttl {
+		for key, routes := range c.responses {
+			for route, cached := range routes {
+				if now.After(cached.expiresAt) {
+					delete(routes, route)
+				}
+			}
+			if len(routes) == 0 {
+				delete(c.responses, key)
+			}
+		}
+		c.lastPurge = now
+	}
+	if _, ok := c.responses[userKey]; !ok {
+		c.responses[userKey] = make(map[string]cachedResponse)
+	}
+	c.responses[userKey][routeKey] = response
+}
+
+// invalidate drops all cached responses of a user.
+func (c *ResponseCache) invalidate(userKey string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.responses, userKey)
+}
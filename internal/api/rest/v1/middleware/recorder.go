@@ -0,0 +1,202 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/config"
+	"github.com/danilovkiri/dk-go-gophermart/internal/models/modeldto"
+	"github.com/go-chi/chi"
+	chiMiddleware "github.com/go-chi/chi/middleware"
+)
+
+// redactedValue replaces values of sensitive JSON fields in recorded bodies.
+const redactedValue = "[REDACTED]"
+
+// sensitiveFields lists substrings of JSON field names whose values are never recorded.
+var sensitiveFields = []string{"password", "token", "secret", "captcha", "signature"}
+
+// RecordHandler sets object structure.
+type RecordHandler struct {
+	routes      map[string]struct{}
+	maxBodySize int
+	mu          sync.Mutex
+	entries     []modeldto.RecordedRequest
+	next        int
+	full        bool
+}
+
+// NewRecordHandler initializes a new failed request recorder, recording is disabled if no routes are configured.
+func NewRecordHandler(cfg *config.RecorderConfig) *RecordHandler {
+	routes := make(map[string]struct{}, len(cfg.Routes))
+	for _, route := range cfg.Routes {
+		route = strings.TrimSpace(route)
+		if route != "" {
+			routes[route] = struct{}{}
+		}
+	}
+	c := &RecordHandler{
+		routes:      routes,
+		maxBodySize: cfg.MaxBodySize,
+	}
+	if len(routes) > 0 {
+		c.entries = make([]modeldto.RecordedRequest, cfg.Size)
+	}
+	return c
+}
+
+// replayedBody serves the already read head of a request body followed by the rest of it.
+type replayedBody struct {
+	io.Reader
+	io.Closer
+}
+
+// capturingWriter redefines http.ResponseWriter keeping a copy of up to limit bytes of the response.
+type capturingWriter struct {
+	http.ResponseWriter
+	status    int
+	limit     int
+	buf       bytes.Buffer
+	truncated bool
+}
+
+// WriteHeader method redefines default http.ResponseWriter WriteHeader method.
+func (w *capturingWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write method redefines default http.ResponseWriter Write method.
+func (w *capturingWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if room := w.limit - w.buf.Len(); room < len(b) {
+		w.buf.Write(b[:room])
+		w.truncated = true
+	} else {
+		w.buf.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// RecordHandle keeps requests to the configured routes failing with a 4xx or 5xx status along with their
+// sanitized bodies for debugging integration issues. The route pattern is only known once routing is done,
+// so it has to be mounted on route groups rather than on the root router.
+func (c *RecordHandler) RecordHandle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := ""
+		if rctx := chi.RouteContext(r.Context()); rctx != nil {
+			route = rctx.RoutePattern()
+		}
+		if _, ok := c.routes[route]; !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		// the body is read up to the limit and put back in front of the rest so that the handler gets all of it
+		reqBody, err := ioutil.ReadAll(io.LimitReader(r.Body, int64(c.maxBodySize)+1))
+		r.Body = replayedBody{Reader: io.MultiReader(bytes.NewReader(reqBody), r.Body), Closer: r.Body}
+		truncated := err != nil || len(reqBody) > c.maxBodySize
+		if len(reqBody) > c.maxBodySize {
+			reqBody = reqBody[:c.maxBodySize]
+		}
+		cw := &capturingWriter{ResponseWriter: w, limit: c.maxBodySize}
+		next.ServeHTTP(cw, r)
+		if cw.status < http.StatusBadRequest {
+			return
+		}
+		c.add(modeldto.RecordedRequest{
+			RequestID:    chiMiddleware.GetReqID(r.Context()),
+			Method:       r.Method,
+			Route:        route,
+			Path:         r.URL.Path,
+			Status:       cw.status,
+			ContentType:  r.Header.Get("Content-Type"),
+			RequestBody:  sanitizeBody(reqBody),
+			ResponseBody: sanitizeBody(cw.buf.Bytes()),
+			Truncated:    truncated || cw.truncated,
+			RecordedAt:   time.Now().UTC().Format(time.RFC3339),
+		})
+	})
+}
+
+// add stores a recorded request overwriting the oldest one once the buffer is full.
+func (c *RecordHandler) add(entry modeldto.RecordedRequest) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[c.next] = entry
+	c.next = (c.next + 1) % len(c.entries)
+	if c.next == 0 {
+		c.full = true
+	}
+}
+
+// Recorded returns recorded requests, the most recent first.
+func (c *RecordHandler) Recorded() []modeldto.RecordedRequest {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	count := c.next
+	if c.full {
+		count = len(c.entries)
+	}
+	recorded := make([]modeldto.RecordedRequest, 0, count)
+	for i := 1; i <= count; i++ {
+		recorded = append(recorded, c.entries[(c.next-i+len(c.entries))%len(c.entries)])
+	}
+	return recorded
+}
+
+// sanitizeBody masks values of sensitive fields of a JSON body, bodies which cannot be decoded (e.g. truncated
+// ones) are dropped entirely if they mention a sensitive field.
+func sanitizeBody(b []byte) string {
+	var value interface{}
+	if json.Unmarshal(b, &value) != nil {
+		if isSensitiveField(string(b)) {
+			return redactedValue
+		}
+		return string(b)
+	}
+	sanitized, err := json.Marshal(redact(value))
+	if err != nil {
+		return redactedValue
+	}
+	return string(sanitized)
+}
+
+// redact replaces values of sensitive fields within a decoded JSON value.
+func redact(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if isSensitiveField(key) {
+				v[key] = redactedValue
+				continue
+			}
+			v[key] = redact(field)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redact(item)
+		}
+	}
+	return value
+}
+
+// isSensitiveField checks whether a JSON field name mentions a secret.
+func isSensitiveField(name string) bool {
+	name = strings.ToLower(name)
+	for _, field := range sensitiveFields {
+		if strings.Contains(name, field) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/auth"
+	"github.com/danilovkiri/dk-go-gophermart/internal/metrics"
+	"github.com/danilovkiri/dk-go-gophermart/internal/service/secretary/v1"
+	"github.com/danilovkiri/dk-go-gophermart/internal/tenant"
+	"github.com/rs/zerolog"
+)
+
+// RefreshHandler sets object structure.
+type RefreshHandler struct {
+	sec      secretary.Secretary
+	strategy AuthStrategy
+	window   time.Duration
+	log      *zerolog.Logger
+	metrics  *metrics.Registry
+}
+
+// NewRefreshHandler initializes a new sliding expiration handler, tokens expiring within window are refreshed.
+func NewRefreshHandler(sec secretary.Secretary, strategy AuthStrategy, window time.Duration, log *zerolog.Logger, reg *metrics.Registry) *RefreshHandler {
+	return &RefreshHandler{
+		sec:      sec,
+		strategy: strategy,
+		window:   window,
+		log:      log,
+		metrics:  reg,
+	}
+}
+
+// RefreshHandle issues a fresh token along with the response if the one authenticating the request is about
+// to expire, so that active users stay logged in. It has to follow TokenHandle, a failure to issue the token
+// does not affect the request. Scoped tokens of partner integrations are never refreshed.
+func (c *RefreshHandler) RefreshHandle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		expiresAt, ok := auth.ExpiresAtFromContext(ctx)
+		_, scoped := auth.ScopesFromContext(ctx)
+		if ok && !scoped && time.Until(expiresAt) < c.window {
+			userID, _ := auth.UserIDFromContext(ctx)
+			sessionID, _ := auth.SessionIDFromContext(ctx)
+			accessToken, err := c.sec.GetTokenForUser(userID, tenant.FromContext(ctx), sessionID)
+			if err != nil {
+				c.log.Error().Err(err).Msg("token refresh failed")
+			} else {
+				c.strategy.Issue(w, accessToken)
+				c.metrics.Counter("gophermart_auth_tokens_refreshed_total").Inc()
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,36 @@
+// Package middleware provides various middleware functionality.
+package middleware
+
+import (
+	"net/http"
+
+	handlersErrors "github.com/danilovkiri/dk-go-gophermart/internal/api/rest/v1/errors"
+	"github.com/danilovkiri/dk-go-gophermart/internal/auth"
+	"github.com/danilovkiri/dk-go-gophermart/internal/errcodes"
+	"github.com/danilovkiri/dk-go-gophermart/internal/metrics"
+)
+
+// ScopeHandler sets object structure.
+type ScopeHandler struct {
+	scope   string
+	metrics *metrics.Registry
+}
+
+// NewScopeHandler initializes a new scope handler for a route group reachable with scope, an empty scope
+// restricts the group to unscoped user tokens.
+func NewScopeHandler(scope string, reg *metrics.Registry) *ScopeHandler {
+	return &ScopeHandler{scope: scope, metrics: reg}
+}
+
+// ScopeHandle rejects requests authenticated with a scoped token which does not grant the scope of the route
+// group. It has to follow TokenHandle.
+func (c *ScopeHandler) ScopeHandle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !auth.Permits(r.Context(), c.scope) {
+			c.metrics.Counter("gophermart_auth_scope_denials_total").Inc()
+			handlersErrors.WriteErrorCode(w, r, errcodes.Forbidden, "Access token scope does not permit the request", nil)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	handlersErrors "github.com/danilovkiri/dk-go-gophermart/internal/api/rest/v1/errors"
+	"github.com/danilovkiri/dk-go-gophermart/internal/auth"
+	"github.com/danilovkiri/dk-go-gophermart/internal/config"
+	"github.com/danilovkiri/dk-go-gophermart/internal/errcodes"
+	"github.com/danilovkiri/dk-go-gophermart/internal/metrics"
+	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1"
+	storageErrors "github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/errors"
+	"github.com/rs/zerolog"
+)
+
+// SessionHandler sets object structure.
+type SessionHandler struct {
+	sessions    storage.Sessions
+	idleTimeout time.Duration
+	lifetime    time.Duration
+	timeout     time.Duration
+	log         *zerolog.Logger
+	metrics     *metrics.Registry
+}
+
+// NewSessionHandler initializes a new session expiration handler, session lookups are bounded by timeout.
+func NewSessionHandler(sessions storage.Sessions, cfg *config.AuthConfig, timeout time.Duration, log *zerolog.Logger, reg *metrics.Registry) *SessionHandler {
+	return &SessionHandler{
+		sessions:    sessions,
+		idleTimeout: cfg.SessionIdleTimeout,
+		lifetime:    cfg.SessionLifetime,
+		timeout:     timeout,
+		log:         log,
+		metrics:     reg,
+	}
+}
+
+// SessionHandle rejects requests whose session has outlived its absolute lifetime or has been inactive for
+// longer than the idle timeout, and records activity otherwise. It has to follow TokenHandle, tokens issued
+// before sessions were tracked carry no session and pass through until they expire.
+func (c *SessionHandler) SessionHandle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sessionID, ok := auth.SessionIDFromContext(r.Context())
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		userID, _ := auth.UserIDFromContext(r.Context())
+		ctx, cancel := context.WithTimeout(r.Context(), c.timeout)
+		err := c.sessions.TouchSession(ctx, userID, sessionID, c.idleTimeout, c.lifetime)
+		cancel()
+		if err != nil {
+			var expiredError *storageErrors.SessionExpiredError
+			var notFoundError *storageErrors.NotFoundError
+			switch {
+			case errors.As(err, &expiredError):
+				c.metrics.Counter("gophermart_auth_sessions_expired_total").Inc()
+				handlersErrors.WriteErrorCode(w, r, errcodes.SessionExpired, "Session has expired, log in again", nil)
+			case errors.As(err, &notFoundError):
+				handlersErrors.WriteErrorCode(w, r, errcodes.Unauthorized, "Session is unknown", nil)
+			default:
+				c.log.Error().Err(err).Msg("SessionHandle failed")
+				handlersErrors.WriteError(w, r, err)
+			}
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
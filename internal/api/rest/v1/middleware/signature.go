@@ -0,0 +1,102 @@
+// Package middleware provides various middleware functionality.
+package middleware
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+
+	handlersErrors "github.com/danilovkiri/dk-go-gophermart/internal/api/rest/v1/errors"
+	"github.com/danilovkiri/dk-go-gophermart/internal/cache/v1"
+	"github.com/danilovkiri/dk-go-gophermart/internal/config"
+	"github.com/danilovkiri/dk-go-gophermart/internal/errcodes"
+)
+
+// Partner request signing headers.
+const (
+	PartnerHeader   = "X-Partner-ID"
+	TimestampHeader = "X-Timestamp"
+	NonceHeader     = "X-Nonce"
+	SignatureHeader = "X-Signature"
+)
+
+// SignatureHandler sets object structure.
+type SignatureHandler struct {
+	cfg    *config.PartnerConfig
+	nonces cache.NonceStore
+}
+
+// NewSignatureHandler initializes a new partner request signature handler.
+func NewSignatureHandler(cfg *config.PartnerConfig, nonces cache.NonceStore) *SignatureHandler {
+	return &SignatureHandler{cfg: cfg, nonces: nonces}
+}
+
+// SignRequest computes a hex-encoded HMAC-SHA256 of the method, the request URI, the unix timestamp, the nonce
+// and the body as sent over the wire, joined with newlines.
+func SignRequest(secret, method, requestURI, timestamp, nonce string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(method + "\n" + requestURI + "\n" + timestamp + "\n" + nonce + "\n"))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignatureHandle provides partner request signature verification functionality, unsigned requests are passed
+// through unchanged.
+func (s *SignatureHandler) SignatureHandle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signature := r.Header.Get(SignatureHeader)
+		if signature == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		partnerID := r.Header.Get(PartnerHeader)
+		secret, ok := s.cfg.Secrets[partnerID]
+		if !ok {
+			handlersErrors.WriteErrorCode(w, r, errcodes.Unauthorized, "Unknown partner", nil)
+			return
+		}
+		timestamp := r.Header.Get(TimestampHeader)
+		unixTime, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			handlersErrors.WriteErrorCode(w, r, errcodes.Unauthorized, "Invalid request timestamp", nil)
+			return
+		}
+		skew := time.Since(time.Unix(unixTime, 0))
+		if skew > s.cfg.MaxSkew || skew < -s.cfg.MaxSkew {
+			handlersErrors.WriteErrorCode(w, r, errcodes.Unauthorized, "Request timestamp is outside the allowed window", nil)
+			return
+		}
+		nonce := r.Header.Get(NonceHeader)
+		if nonce == "" {
+			handlersErrors.WriteErrorCode(w, r, errcodes.Unauthorized, "Request nonce is required", nil)
+			return
+		}
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			handlersErrors.WriteErrorCode(w, r, errcodes.InvalidRequest, err.Error(), nil)
+			return
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		expected := SignRequest(secret, r.Method, r.URL.RequestURI(), timestamp, nonce, body)
+		if !hmac.Equal([]byte(signature), []byte(expected)) {
+			handlersErrors.WriteErrorCode(w, r, errcodes.Unauthorized, "Invalid request signature", nil)
+			return
+		}
+		// a nonce is remembered for as long as its timestamp may be accepted, so replays are caught in either direction
+		fresh, err := s.nonces.ReserveNonce(r.Context(), partnerID+":"+nonce, 2*s.cfg.MaxSkew)
+		if err != nil {
+			handlersErrors.WriteErrorCode(w, r, errcodes.ServiceUnavailable, "Request nonce could not be verified", nil)
+			return
+		}
+		if !fresh {
+			handlersErrors.WriteErrorCode(w, r, errcodes.Unauthorized, "Request nonce has already been used", nil)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/auth"
+	"github.com/danilovkiri/dk-go-gophermart/internal/config"
+)
+
+// AuthStrategy defines how access tokens are passed between clients and the server.
+type AuthStrategy interface {
+	// Credentials retrieves the credentials carried by a request, an empty string if there are none.
+	Credentials(r *http.Request) (string, error)
+	// Issue hands an access token out to the client.
+	Issue(w http.ResponseWriter, accessToken string)
+}
+
+// NewAuthStrategy initializes the auth strategy of the configured mode.
+func NewAuthStrategy(cfg *config.AuthConfig) AuthStrategy {
+	if cfg.Mode == "cookie" {
+		return &CookieStrategy{name: cfg.CookieName, secure: cfg.CookieSecure}
+	}
+	strategy := &BearerStrategy{}
+	if cfg.CookieFallback {
+		strategy.fallback = &CookieStrategy{name: cfg.CookieName, secure: cfg.CookieSecure}
+	}
+	return strategy
+}
+
+// BearerStrategy passes access tokens in the Authorization header with the Bearer scheme, the scheme is optional
+// for incoming tokens. Requests without the header may fall back to the token cookie.
+type BearerStrategy struct {
+	fallback *CookieStrategy
+}
+
+// Credentials returns the Authorization header value or the fallback cookie value, several Authorization
+// headers are rejected.
+func (s *BearerStrategy) Credentials(r *http.Request) (string, error) {
+	values := r.Header.Values("Authorization")
+	switch {
+	case len(values) > 1:
+		return "", auth.ErrMultipleCredentials
+	case len(values) == 1:
+		return values[0], nil
+	case s.fallback != nil:
+		return s.fallback.Credentials(r)
+	default:
+		return "", nil
+	}
+}
+
+// Issue sets the Authorization response header.
+func (s *BearerStrategy) Issue(w http.ResponseWriter, accessToken string) {
+	w.Header().Set("Authorization", "Bearer "+accessToken)
+}
+
+// CookieStrategy passes access tokens in an HTTP-only cookie, the cookie lives as long as the browser session
+// while the token itself expires on its own.
+type CookieStrategy struct {
+	name   string
+	secure bool
+}
+
+// Credentials returns the cookie value.
+func (s *CookieStrategy) Credentials(r *http.Request) (string, error) {
+	cookie, err := r.Cookie(s.name)
+	if err != nil {
+		return "", nil
+	}
+	return cookie.Value, nil
+}
+
+// Issue sets the cookie.
+func (s *CookieStrategy) Issue(w http.ResponseWriter, accessToken string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     s.name,
+		Value:    accessToken,
+		Path:     "/",
+		Secure:   s.secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
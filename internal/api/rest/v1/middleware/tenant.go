@@ -0,0 +1,37 @@
+// Package middleware provides various middleware functionality.
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	handlersErrors "github.com/danilovkiri/dk-go-gophermart/internal/api/rest/v1/errors"
+	"github.com/danilovkiri/dk-go-gophermart/internal/config"
+	"github.com/danilovkiri/dk-go-gophermart/internal/errcodes"
+	"github.com/danilovkiri/dk-go-gophermart/internal/tenant"
+)
+
+// TenantHandler sets object structure.
+type TenantHandler struct {
+	cfg *config.TenantConfig
+}
+
+// NewTenantHandler initializes a new tenant handler.
+func NewTenantHandler(cfg *config.TenantConfig) *TenantHandler {
+	return &TenantHandler{cfg: cfg}
+}
+
+// TenantHandle resolves a tenant from the request header for unauthenticated routes and rejects unknown tenants.
+func (t *TenantHandler) TenantHandle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenantID := r.Header.Get(t.cfg.Header)
+		if tenantID == "" {
+			tenantID = tenant.Default
+		}
+		if _, ok := t.cfg.AccrualAddresses[tenantID]; !ok && tenantID != tenant.Default {
+			handlersErrors.WriteErrorCode(w, r, errcodes.InvalidRequest, fmt.Sprintf("Unknown tenant %s", tenantID), nil)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(tenant.WithTenant(r.Context(), tenantID)))
+	})
+}
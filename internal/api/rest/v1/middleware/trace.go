@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TraceHandler sets object structure.
+type TraceHandler struct {
+	enabled bool
+}
+
+// NewTraceHandler initializes a new request tracing handler, requests are not traced unless enabled.
+func NewTraceHandler(enabled bool) *TraceHandler {
+	return &TraceHandler{
+		enabled: enabled,
+	}
+}
+
+// TraceHandle starts a server span for each request continuing the trace of an incoming traceparent header, so
+// that spans of the processor, storage and outbound calls made on behalf of the request join the caller's trace.
+// The span is named after the chi route pattern, which is only known once routing is done, so it has to be mounted
+// on the root router.
+func (c *TraceHandler) TraceHandle(next http.Handler) http.Handler {
+	if !c.enabled {
+		return next
+	}
+	routed := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r)
+		route := unmatchedRoute
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+		span := trace.SpanFromContext(r.Context())
+		span.SetName(r.Method + " " + route)
+		span.SetAttributes(semconv.HTTPRoute(route))
+	})
+	return otelhttp.NewHandler(routed, "", otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+		return r.Method
+	}))
+}
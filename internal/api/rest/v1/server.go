@@ -7,71 +7,344 @@ import (
 	"sync"
 	"time"
 
+	"github.com/danilovkiri/dk-go-gophermart/internal/analytics/clickhouse"
 	"github.com/danilovkiri/dk-go-gophermart/internal/api/rest/v1/handlers"
 	"github.com/danilovkiri/dk-go-gophermart/internal/api/rest/v1/middleware"
+	"github.com/danilovkiri/dk-go-gophermart/internal/auth"
+	"github.com/danilovkiri/dk-go-gophermart/internal/cache/v1"
+	"github.com/danilovkiri/dk-go-gophermart/internal/cache/v1/inmem"
+	"github.com/danilovkiri/dk-go-gophermart/internal/cache/v1/inredis"
+	"github.com/danilovkiri/dk-go-gophermart/internal/cashback"
 	"github.com/danilovkiri/dk-go-gophermart/internal/client"
 	"github.com/danilovkiri/dk-go-gophermart/internal/config"
+	"github.com/danilovkiri/dk-go-gophermart/internal/metrics"
+	"github.com/danilovkiri/dk-go-gophermart/internal/metrics/statsd"
+	"github.com/danilovkiri/dk-go-gophermart/internal/models/modeldto"
+	"github.com/danilovkiri/dk-go-gophermart/internal/models/modelqueue"
+	"github.com/danilovkiri/dk-go-gophermart/internal/outbox"
+	"github.com/danilovkiri/dk-go-gophermart/internal/report"
+	"github.com/danilovkiri/dk-go-gophermart/internal/scheduler"
 	"github.com/danilovkiri/dk-go-gophermart/internal/service/broker/v1/broker"
+	"github.com/danilovkiri/dk-go-gophermart/internal/service/broker/v1/withdrawer"
+	"github.com/danilovkiri/dk-go-gophermart/internal/service/captcha/v1/captcha"
+	"github.com/danilovkiri/dk-go-gophermart/internal/service/converter/v1/converter"
+	"github.com/danilovkiri/dk-go-gophermart/internal/service/hasher/v1/hasher"
+	healthService "github.com/danilovkiri/dk-go-gophermart/internal/service/health/v1"
+	"github.com/danilovkiri/dk-go-gophermart/internal/service/health/v1/health"
+	notifierService "github.com/danilovkiri/dk-go-gophermart/internal/service/notifier/v1"
+	"github.com/danilovkiri/dk-go-gophermart/internal/service/notifier/v1/notifier"
 	"github.com/danilovkiri/dk-go-gophermart/internal/service/processor/v1/processor"
 	"github.com/danilovkiri/dk-go-gophermart/internal/service/secretary/v1/secretary"
+	"github.com/danilovkiri/dk-go-gophermart/internal/service/validator/v1/validator"
+	storageService "github.com/danilovkiri/dk-go-gophermart/internal/storage/v1"
+	inmemStorage "github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/inmem"
 	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/inpsql"
+	"github.com/danilovkiri/dk-go-gophermart/internal/tracing"
 	"github.com/go-chi/chi"
+	chiMiddleware "github.com/go-chi/chi/middleware"
 	"github.com/rs/zerolog"
 )
 
+// storageBackend defines a set of methods storage backends provide to the server beyond storage.Storage.
+type storageBackend interface {
+	storageService.Storage
+	broker.QueueStore
+	outbox.Store
+	scheduler.Locker
+	RescanStalledOrders(ctx context.Context) error
+	ReconcileBalances(ctx context.Context) error
+}
+
 // InitServer returns a http.Server object ready to be listening and serving .
-func InitServer(ctx context.Context, cfg *config.Config, log *zerolog.Logger, wg *sync.WaitGroup) (server *http.Server, err error) {
+func InitServer(ctx context.Context, cfg *config.Config, log *zerolog.Logger, wg *sync.WaitGroup, intakeHandler *middleware.IntakeHandler) (server *http.Server, err error) {
+	// initialize metrics registry
+	reg := metrics.NewRegistry()
+	reg.RegisterRuntime()
+	if cfg.MetricsConfig.Sink == "statsd" {
+		exporter, err := statsd.InitExporter(ctx, cfg.MetricsConfig, log, wg, reg)
+		if err != nil {
+			return nil, err
+		}
+		exporter.ListenAndExport()
+	}
+
+	// initialize tracer, tracing stays disabled without a collector endpoint
+	tracingEnabled := cfg.TracingConfig.Endpoint != ""
+	if tracingEnabled {
+		tracer, err := tracing.InitTracer(ctx, cfg.TracingConfig, log, wg)
+		if err != nil {
+			return nil, err
+		}
+		tracer.ListenAndExport()
+	}
+
 	//initialize secretary
 	secretaryService, err := secretary.NewSecretaryService(cfg.SecretConfig)
 	if err != nil {
 		return nil, err
 	}
 
+	// initialize password hasher
+	passwordHasher, err := hasher.NewBcryptHasher(cfg.SecretConfig)
+	if err != nil {
+		return nil, err
+	}
+
 	// initialize token handler
-	tokenHandler, err := middleware.NewTokenHandler(secretaryService, cfg.SecretConfig)
+	authMonitor := auth.NewMonitor(cfg.AuthAlertConfig, log, reg)
+	authenticator, err := auth.NewAuthenticator(secretaryService, authMonitor)
+	if err != nil {
+		return nil, err
+	}
+	authStrategy := middleware.NewAuthStrategy(cfg.AuthConfig)
+	tokenHandler, err := middleware.NewTokenHandler(authenticator, authStrategy)
+	if err != nil {
+		return nil, err
+	}
+
+	// initialize cache
+	var serviceCache cache.Cache
+	pingers := make(map[string]healthService.Pinger)
+	switch cfg.CacheConfig.Backend {
+	case "redis":
+		redisCache, err := inredis.InitCache(ctx, cfg.CacheConfig, log)
+		if err != nil {
+			return nil, err
+		}
+		pingers["redis"] = redisCache
+		serviceCache = redisCache
+	default:
+		serviceCache = inmem.InitCache(cfg.CacheConfig)
+	}
+
+	// initialize cashback rules
+	cashbackEngine, err := cashback.ReadFile(cfg.CashbackConfig.RulesFile)
 	if err != nil {
 		return nil, err
 	}
 
 	// initialize storage
-	storage, err := inpsql.InitStorage(ctx, cfg.StorageConfig, log, wg)
+	var storage storageBackend
+	var queueIn, queueOut chan modelqueue.OrderQueueEntry
+	var withdrawalQueue chan modelqueue.WithdrawalQueueEntry
+	var events chan modeldto.Notification
+	switch cfg.StorageConfig.Backend {
+	case "memory":
+		memoryStorage := inmemStorage.InitStorage(ctx, cfg.StorageConfig, log, wg, reg, serviceCache, cashbackEngine)
+		queueIn, queueOut, withdrawalQueue, events = memoryStorage.QueueIn, memoryStorage.QueueOut, memoryStorage.WithdrawalQueue, memoryStorage.Events
+		storage = memoryStorage
+	default:
+		psqlStorage, err := inpsql.InitStorage(ctx, cfg.StorageConfig, log, wg, reg, serviceCache, cashbackEngine)
+		if err != nil {
+			return nil, err
+		}
+		queueIn, queueOut, withdrawalQueue, events = psqlStorage.QueueIn, psqlStorage.QueueOut, psqlStorage.WithdrawalQueue, psqlStorage.Events
+		pingers["postgres"] = psqlStorage
+		storage = psqlStorage
+	}
+
+	// initialize order number validator
+	orderValidator, err := validator.NewValidator(cfg.ValidationConfig, log)
+	if err != nil {
+		return nil, err
+	}
+
+	// initialize user notifier
+	var userNotifier notifierService.Notifier = notifier.NewLogNotifier(log, reg)
+	if cfg.TelegramConfig.BotToken != "" {
+		telegramNotifier, err := notifier.InitTelegramNotifier(ctx, cfg.TelegramConfig, log, wg, reg, storage)
+		if err != nil {
+			return nil, err
+		}
+		telegramNotifier.ListenAndServe()
+		userNotifier = notifier.NewMultiNotifier(userNotifier, telegramNotifier)
+	}
+	notifier.InitDispatcher(ctx, events, userNotifier, log, wg).ListenAndDispatch()
+
+	// initialize display timezone
+	location, err := time.LoadLocation(cfg.ServerConfig.DisplayTimezone)
+	if err != nil {
+		return nil, err
+	}
+
+	// initialize currency conversion rates
+	rateTable, err := converter.InitRateTable(ctx, cfg.CurrencyConfig, log, wg)
 	if err != nil {
 		return nil, err
 	}
+	rateTable.ListenAndReload()
 
 	// initialize main service
-	mainService, err := processor.InitService(storage, secretaryService)
+	mainService, err := processor.InitService(ctx, storage, secretaryService, passwordHasher, serviceCache, orderValidator, userNotifier, rateTable, cashbackEngine, auth.NewLoginThrottle(cfg.AuthConfig, reg), cfg.QueueConfig, cfg.AuthConfig, location)
 	if err != nil {
 		return nil, err
 	}
 
 	// initialize accrual client
-	brokerClient := client.InitClient(cfg.ServerConfig, log)
+	brokerClient := client.InitClient(cfg.ServerConfig, cfg.TenantConfig, log)
 
 	// initialize broker
-	brokerService := broker.InitBroker(ctx, storage.QueueIn, storage.QueueOut, log, wg, brokerClient, cfg.QueueConfig.WorkerNumber, cfg.QueueConfig.RetryNumber)
+	brokerService := broker.InitBroker(ctx, queueIn, queueOut, log, wg, brokerClient, storage, cfg.QueueConfig.WorkerNumber, cfg.QueueConfig.RetryNumber, cfg.QueueConfig.UnknownOrderBackoff, cfg.QueueConfig.PollIntervals, reg)
 	brokerService.ListenAndProcess()
 
+	// initialize asynchronous withdrawal processing
+	if cfg.QueueConfig.AsyncWithdrawals {
+		withdrawerService := withdrawer.InitWithdrawer(ctx, withdrawalQueue, storage, log, wg, reg, cfg.QueueConfig.WithdrawalWorkerNumber, cfg.QueueConfig.WithdrawalRetryNumber, cfg.QueueConfig.WithdrawalRetryBackoff)
+		withdrawerService.ListenAndProcess()
+	}
+
+	// initialize background jobs
+	var locker scheduler.Locker
+	if cfg.SchedulerConfig.LeaderElection {
+		locker = storage
+	}
+	jobScheduler := scheduler.InitScheduler(ctx, cfg.SchedulerConfig, log, wg, reg, locker)
+	jobScheduler.Register(scheduler.Job{Name: "orders_rescan", Interval: cfg.StorageConfig.RescanInterval, Run: storage.RescanStalledOrders})
+	jobScheduler.Register(scheduler.Job{Name: "balance_reconciliation", Interval: cfg.StorageConfig.ReconcileInterval, Exclusive: true, Run: storage.ReconcileBalances})
+	jobScheduler.Register(scheduler.Job{Name: "balance_holds_expiry", Interval: cfg.StorageConfig.HoldExpiryInterval, Exclusive: true, Run: storage.ExpireHolds})
+	if cfg.OutboxConfig.Bus != "none" {
+		relay, err := outbox.InitRelay(cfg.OutboxConfig, log, reg, storage)
+		if err != nil {
+			return nil, err
+		}
+		// the relay is exclusive so that concurrent instances do not publish the same events
+		jobScheduler.Register(scheduler.Job{Name: "outbox_relay", Interval: cfg.OutboxConfig.RelayInterval, Exclusive: true, Run: relay.Run})
+	}
+	if cfg.ClickHouseConfig.URL != "" {
+		exporter := clickhouse.InitExporter(cfg.ClickHouseConfig, log, reg, storage)
+		jobScheduler.Register(scheduler.Job{Name: "clickhouse_export", Interval: cfg.ClickHouseConfig.ExportInterval, Exclusive: true, Run: exporter.Run})
+	}
+	if cfg.ReportConfig.Period != "" {
+		emailNotifier, err := notifier.NewEmailNotifier(cfg.EmailConfig, reg)
+		if err != nil {
+			return nil, err
+		}
+		reporter := report.InitReporter(cfg.ReportConfig, log, reg, storage, emailNotifier)
+		jobScheduler.Register(scheduler.Job{Name: "admin_report", Interval: reporter.Interval(), Exclusive: true, Run: reporter.Run})
+	}
+	jobScheduler.ListenAndRun()
+
+	// initialize dependency health checker
+	pingers["accrual"] = brokerClient
+	checker := health.InitChecker(ctx, cfg.HealthConfig, log, wg, reg, pingers)
+	checker.ListenAndCheck()
+
+	// initialize registration CAPTCHA verifier
+	captchaVerifier, err := captcha.NewVerifier(cfg.CaptchaConfig)
+	if err != nil {
+		return nil, err
+	}
+	captchaHandler := middleware.NewCaptchaHandler(captchaVerifier, cfg.CaptchaConfig.Header)
+
+	// initialize failed request recorder
+	recordHandler := middleware.NewRecordHandler(cfg.RecorderConfig)
+
 	// initialize handlers
-	urlHandler, err := handlers.InitHandlers(mainService, cfg.ServerConfig, log)
+	urlHandler, err := handlers.InitHandlers(mainService, authStrategy, cfg.ServerConfig, cfg.AdminConfig, log, reg, checker, recordHandler)
 	if err != nil {
 		return nil, err
 	}
 
 	// initialize server and set routing
 	r := chi.NewRouter()
-	r.Use(middleware.CompressHandle)
-	r.Use(middleware.DecompressHandle)
+	r.Use(chiMiddleware.RequestID)
+	r.Use(middleware.NewInstrumentHandler(log, reg).InstrumentHandle)
+	r.Use(middleware.NewTraceHandler(tracingEnabled).TraceHandle)
+	r.Use(middleware.NewSignatureHandler(cfg.PartnerConfig, serviceCache).SignatureHandle) // verified before aliasing and decompression
+	r.Use(middleware.NewAliasHandler(cfg.ServerConfig.RouteAliases).AliasHandle)
+	r.Use(middleware.NewCompressor(cfg.CompressConfig).CompressHandle)
+	r.Use(middleware.NewDecompressor(cfg.CompressConfig).DecompressHandle)
 	loginGroup := r.Group(nil)
-	mainGroup := r.Group(nil)
-	mainGroup.Use(tokenHandler.TokenHandle) // authentication via cookie is not used for login.register routes
-	loginGroup.Post("/api/user/register", urlHandler.HandleRegister())
-	loginGroup.Post("/api/user/login", urlHandler.HandleLogin())
-	mainGroup.Post("/api/user/orders", urlHandler.HandleNewOrder())
-	mainGroup.Get("/api/user/orders", urlHandler.HandleGetOrders())
-	mainGroup.Get("/api/user/balance", urlHandler.HandleGetBalance())
-	mainGroup.Post("/api/user/balance/withdraw", urlHandler.HandleNewWithdrawal())
-	mainGroup.Get("/api/user/withdrawals", urlHandler.HandleGetWithdrawals())
+	userGroup := r.Group(nil)
+	adminGroup := r.Group(nil)
+	internalGroup := r.Group(nil)
+	if len(cfg.RecorderConfig.Routes) > 0 {
+		// admin routes are never recorded
+		loginGroup.Use(recordHandler.RecordHandle)
+		userGroup.Use(recordHandler.RecordHandle)
+		internalGroup.Use(recordHandler.RecordHandle)
+	}
+	degradedHandler := middleware.NewDegradedHandler(storage)
+	deadlineHandler := middleware.NewDeadlineHandler(cfg.ServerConfig)
+	userGroup.Use(deadlineHandler.DeadlineHandle)
+	userGroup.Use(degradedHandler.DegradedHandle)
+	userGroup.Use(tokenHandler.TokenHandle) // authentication is not used for login/register routes
+	if cfg.AuthConfig.SessionIdleTimeout > 0 || cfg.AuthConfig.SessionLifetime > 0 {
+		userGroup.Use(middleware.NewSessionHandler(storage, cfg.AuthConfig, cfg.ServerConfig.StorageTimeout, log, reg).SessionHandle)
+	}
+	if cfg.AuthConfig.RefreshWindow > 0 {
+		userGroup.Use(middleware.NewRefreshHandler(secretaryService, authStrategy, cfg.AuthConfig.RefreshWindow, log, reg).RefreshHandle)
+	}
+	// scoped tokens of partner integrations reach only the route groups of their scopes, the rest is
+	// reserved to user tokens; scopes are checked before cached responses are served
+	responseCache := middleware.NewResponseCache(cfg.CacheConfig.ResponseCacheTTL)
+	mainGroup := userGroup.Group(nil)
+	mainGroup.Use(middleware.NewScopeHandler("", reg).ScopeHandle)
+	ordersReadGroup := userGroup.Group(nil)
+	ordersReadGroup.Use(middleware.NewScopeHandler(auth.ScopeOrdersRead, reg).ScopeHandle)
+	ordersWriteGroup := userGroup.Group(nil)
+	ordersWriteGroup.Use(middleware.NewScopeHandler(auth.ScopeOrdersWrite, reg).ScopeHandle)
+	balanceReadGroup := userGroup.Group(nil)
+	balanceReadGroup.Use(middleware.NewScopeHandler(auth.ScopeBalanceRead, reg).ScopeHandle)
+	balanceWriteGroup := userGroup.Group(nil)
+	balanceWriteGroup.Use(middleware.NewScopeHandler(auth.ScopeBalanceWrite, reg).ScopeHandle)
+	for _, group := range []chi.Router{mainGroup, ordersReadGroup, ordersWriteGroup, balanceReadGroup, balanceWriteGroup} {
+		group.Use(responseCache.CacheHandle)
+	}
+	adminGroup.Use(middleware.NewAdminHandler(cfg.AdminConfig).AdminHandle)
+	internalGroup.Use(deadlineHandler.DeadlineHandle)
+	internalGroup.Use(degradedHandler.DegradedHandle)
+	internalGroup.Use(middleware.NewCallbackHandler(cfg.ServerConfig).CallbackHandle)
+	loginGroup.Get("/readyz", urlHandler.HandleReadiness())
+	loginGroup.Get("/metrics", urlHandler.HandleMetrics())
+	loginGroup.Get("/api/version", urlHandler.HandleGetVersion())
+	tenantHandler := middleware.NewTenantHandler(cfg.TenantConfig)
+	loginGroup.With(degradedHandler.DegradedHandle, tenantHandler.TenantHandle, captchaHandler.CaptchaHandle, deadlineHandler.DeadlineHandle).Post("/api/user/register", urlHandler.HandleRegister())
+	loginGroup.With(deadlineHandler.DeadlineHandle, degradedHandler.DegradedHandle, tenantHandler.TenantHandle).Post("/api/user/login", urlHandler.HandleLogin())
+	loginGroup.With(deadlineHandler.DeadlineHandle, degradedHandler.DegradedHandle, tenantHandler.TenantHandle).Post("/api/user/refresh", urlHandler.HandleRefresh())
+	mainGroup.Post("/api/user/logout", urlHandler.HandleLogout())
+	ordersWriteGroup.With(intakeHandler.IntakeHandle).Post("/api/user/orders", urlHandler.HandleNewOrder())
+	ordersReadGroup.Get("/api/user/orders", urlHandler.HandleGetOrders())
+	ordersReadGroup.Post("/api/user/orders/preview", urlHandler.HandlePreviewOrder())
+	ordersReadGroup.Get("/api/user/orders/{number}", urlHandler.HandleGetOrder())
+	ordersReadGroup.Get("/api/user/orders/{number}/history", urlHandler.HandleGetOrderHistory())
+	mainGroup.Post("/api/user/orders/{number}/recheck", urlHandler.HandleRecheckOrder())
+	balanceReadGroup.Get("/api/user/balance", urlHandler.HandleGetBalance())
+	balanceReadGroup.Get("/api/user/balance/converted", urlHandler.HandleGetConvertedBalance())
+	mainGroup.Get("/api/user/stats", urlHandler.HandleGetUserStats())
+	mainGroup.Get("/api/user/sessions", urlHandler.HandleGetSessions())
+	mainGroup.Patch("/api/user", urlHandler.HandleUpdateProfile())
+	mainGroup.Post("/api/user/telegram/link", urlHandler.HandleCreateTelegramLink())
+	mainGroup.Get("/api/user/alerts", urlHandler.HandleGetAlerts())
+	mainGroup.Put("/api/user/alerts", urlHandler.HandleSetAlerts())
+	mainGroup.With(intakeHandler.IntakeHandle).Post("/api/user/balance/withdraw", urlHandler.HandleNewWithdrawal())
+	balanceWriteGroup.Post("/api/user/balance/holds", urlHandler.HandleNewHold())
+	balanceReadGroup.Get("/api/user/balance/holds", urlHandler.HandleGetHolds())
+	mainGroup.Post("/api/user/balance/holds/{id}/capture", urlHandler.HandleCaptureHold())
+	balanceWriteGroup.Post("/api/user/balance/holds/{id}/release", urlHandler.HandleReleaseHold())
+	balanceReadGroup.Get("/api/user/withdrawals", urlHandler.HandleGetWithdrawals())
+	balanceReadGroup.Get("/api/user/transactions", urlHandler.HandleGetTransactions())
+	balanceReadGroup.Get("/api/user/withdrawals/{number}", urlHandler.HandleGetWithdrawal())
+	adminGroup.Get("/api/admin/health", urlHandler.HandleGetHealth())
+	adminGroup.Get("/api/admin/reconciliation", urlHandler.HandleGetReconciliation())
+	adminGroup.Post("/api/admin/balances/recalculate", urlHandler.HandleRecalculateBalances())
+	adminGroup.Get("/api/admin/summary", urlHandler.HandleGetSummary())
+	adminGroup.Get("/api/admin/users", urlHandler.HandleSearchUsers())
+	adminGroup.Post("/api/admin/partner-tokens", urlHandler.HandleIssuePartnerToken())
+	adminGroup.Post("/api/admin/users/merge", urlHandler.HandleMergeAccounts())
+	adminGroup.Post("/api/admin/users/{id}/adjustments", urlHandler.HandleAdjustBalance())
+	adminGroup.Post("/api/admin/orders/{number}/recheck", urlHandler.HandleAdminRecheckOrder())
+	adminGroup.Post("/api/admin/orders/{number}/transfer", urlHandler.HandleTransferOrder())
+	adminGroup.Get("/api/admin/orders/suspended", urlHandler.HandleGetSuspendedOrders())
+	adminGroup.Get("/api/admin/orders/{number}", urlHandler.HandleGetAdminOrder())
+	adminGroup.Post("/api/admin/orders/{number}/approve", urlHandler.HandleApproveOrder())
+	adminGroup.Post("/api/admin/orders/{number}/reject", urlHandler.HandleRejectOrder())
+	adminGroup.Post("/api/admin/orders/requeue", urlHandler.HandleRequeueOrders())
+	adminGroup.Get("/api/admin/recorded-requests", urlHandler.HandleGetRecordedRequests())
+	adminGroup.Get("/api/admin/login-throttles", urlHandler.HandleGetLoginThrottles())
+	adminGroup.Delete("/api/admin/login-throttles/{login}", urlHandler.HandleResetLoginThrottle())
+	adminGroup.Post("/api/admin/cashback/evaluate", urlHandler.HandleEvaluateCashback())
+	internalGroup.Post("/api/internal/accrual/callback", urlHandler.HandleAccrualCallback())
 
 	srv := &http.Server{
 		Addr:         cfg.ServerConfig.ServerAddress,
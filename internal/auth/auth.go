@@ -0,0 +1,98 @@
+// Package auth provides transport-agnostic access token authentication.
+//
+// HTTP middleware passes the Authorization header value to Authenticator.Authenticate, other transports
+// (gRPC interceptors reading the authorization metadata, WebSocket or SSE handshakes) are expected to do the same
+// so that all of them share a single authentication code path.
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/service/secretary/v1"
+	"github.com/danilovkiri/dk-go-gophermart/internal/tenant"
+)
+
+// Authentication errors.
+var (
+	// ErrTokenRequired is returned when no access token is supplied.
+	ErrTokenRequired = errors.New("token authorization required")
+	// ErrMultipleCredentials is returned when a request carries several Authorization headers, none of them
+	// is trusted as it is ambiguous which one proxies and the server would pick.
+	ErrMultipleCredentials = errors.New("multiple Authorization headers are not allowed")
+)
+
+// bearerScheme is the optional prefix of access tokens, it is matched case-insensitively.
+const bearerScheme = "Bearer "
+
+type contextKey struct{}
+
+type expiryKey struct{}
+
+type sessionKey struct{}
+
+// Authenticator sets object structure.
+type Authenticator struct {
+	sec     secretary.Secretary
+	monitor *Monitor
+}
+
+// NewAuthenticator initializes a new authenticator, failures are reported to monitor unless it is nil.
+func NewAuthenticator(sec secretary.Secretary, monitor *Monitor) (*Authenticator, error) {
+	if sec == nil {
+		return nil, errors.New("nil secretary object was found")
+	}
+	return &Authenticator{sec: sec, monitor: monitor}, nil
+}
+
+// Authenticate validates an access token optionally prefixed with the Bearer scheme and returns a copy of ctx
+// carrying the user identifier, the tenant, the session identifier, the scopes and the expiration time from the
+// token claims.
+func (a *Authenticator) Authenticate(ctx context.Context, credentials string) (context.Context, error) {
+	accessToken := strings.TrimSpace(credentials)
+	if len(accessToken) >= len(bearerScheme) && strings.EqualFold(accessToken[:len(bearerScheme)], bearerScheme) {
+		accessToken = strings.TrimSpace(accessToken[len(bearerScheme):])
+	}
+	if accessToken == "" {
+		a.record(ErrTokenRequired)
+		return nil, ErrTokenRequired
+	}
+	claims, err := a.sec.ValidateClaims(accessToken)
+	if err != nil {
+		a.record(err)
+		return nil, err
+	}
+	ctx = tenant.WithTenant(ctx, claims.TenantID)
+	ctx = context.WithValue(ctx, expiryKey{}, time.Unix(claims.ExpiresAt, 0))
+	ctx = context.WithValue(ctx, sessionKey{}, claims.SessionID)
+	ctx = context.WithValue(ctx, scopeKey{}, claims.Scopes)
+	return context.WithValue(ctx, contextKey{}, claims.UserID), nil
+}
+
+// record reports an authentication failure to the monitor.
+func (a *Authenticator) record(err error) {
+	if a.monitor != nil {
+		a.monitor.Record(err)
+	}
+}
+
+// UserIDFromContext retrieves the authenticated user identifier from ctx.
+func UserIDFromContext(ctx context.Context) (string, bool) {
+	userID, ok := ctx.Value(contextKey{}).(string)
+	return userID, ok && userID != ""
+}
+
+// ExpiresAtFromContext retrieves the expiration time of the authenticated access token from ctx.
+func ExpiresAtFromContext(ctx context.Context) (time.Time, bool) {
+	expiresAt, ok := ctx.Value(expiryKey{}).(time.Time)
+	return expiresAt, ok
+}
+
+// SessionIDFromContext retrieves the session identifier of the authenticated access token from ctx, tokens issued
+// before sessions were tracked carry none.
+func SessionIDFromContext(ctx context.Context) (string, bool) {
+	sessionID, ok := ctx.Value(sessionKey{}).(string)
+	return sessionID, ok && sessionID != ""
+}
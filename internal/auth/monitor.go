@@ -0,0 +1,153 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/config"
+	"github.com/danilovkiri/dk-go-gophermart/internal/metrics"
+	"github.com/go-resty/resty/v2"
+	"github.com/golang-jwt/jwt"
+	"github.com/rs/zerolog"
+)
+
+// Authentication failure reasons reported as the reason label of gophermart_auth_failures_total.
+const (
+	ReasonMissing   = "missing"
+	ReasonMalformed = "malformed"
+	ReasonSignature = "signature"
+	ReasonExpired   = "expired"
+	ReasonInvalid   = "invalid"
+)
+
+// Alert defines the webhook payload sent when a failure rate exceeds its threshold.
+type Alert struct {
+	Reason    string `json:"reason"`
+	Count     int    `json:"count"`
+	Threshold int    `json:"threshold"`
+	Window    string `json:"window"`
+	FiredAt   string `json:"fired_at"`
+}
+
+// window counts failures of a single reason within the current alerting window.
+type window struct {
+	start time.Time
+	count int
+	fired bool
+}
+
+// Monitor sets object structure.
+type Monitor struct {
+	cfg     *config.AuthAlertConfig
+	log     *zerolog.Logger
+	metrics *metrics.Registry
+	client  *resty.Client
+	mu      sync.Mutex
+	windows map[string]*window
+}
+
+// NewMonitor initializes a new authentication failure monitor.
+func NewMonitor(cfg *config.AuthAlertConfig, log *zerolog.Logger, reg *metrics.Registry) *Monitor {
+	return &Monitor{
+		cfg:     cfg,
+		log:     log,
+		metrics: reg,
+		client:  resty.New().SetTimeout(cfg.WebhookTimeout),
+		windows: make(map[string]*window),
+	}
+}
+
+// Record counts an authentication failure and fires an alert once per window when its rate reaches the threshold.
+func (m *Monitor) Record(err error) {
+	reason := classify(err)
+	m.metrics.Counter("gophermart_auth_failures_total", "reason", reason).Inc()
+	threshold := m.threshold(reason)
+	if threshold == 0 {
+		return
+	}
+	now := time.Now()
+	m.mu.Lock()
+	w, ok := m.windows[reason]
+	if !ok || now.Sub(w.start) >= m.cfg.Window {
+		w = &window{start: now}
+		m.windows[reason] = w
+	}
+	w.count++
+	fire := w.count >= threshold && !w.fired
+	if fire {
+		w.fired = true
+	}
+	count := w.count
+	m.mu.Unlock()
+	if fire {
+		m.alert(Alert{
+			Reason:    reason,
+			Count:     count,
+			Threshold: threshold,
+			Window:    m.cfg.Window.String(),
+			FiredAt:   now.Format(time.RFC3339),
+		})
+	}
+}
+
+// threshold returns the alerting threshold for reason, zero means alerting is disabled.
+func (m *Monitor) threshold(reason string) int {
+	switch reason {
+	case ReasonSignature:
+		return m.cfg.SignatureThreshold
+	case ReasonExpired:
+		return m.cfg.ExpiredThreshold
+	case ReasonMalformed, ReasonInvalid:
+		return m.cfg.InvalidThreshold
+	default:
+		return 0
+	}
+}
+
+// alert logs the alert and posts it to the configured webhook without blocking the request.
+func (m *Monitor) alert(a Alert) {
+	m.metrics.Counter("gophermart_auth_alerts_total", "reason", a.Reason).Inc()
+	m.log.Error().
+		Str("reason", a.Reason).
+		Int("count", a.Count).
+		Int("threshold", a.Threshold).
+		Str("window", a.Window).
+		Msg("authentication failure rate exceeded threshold")
+	if m.cfg.WebhookURL == "" {
+		return
+	}
+	go func() {
+		resp, err := m.client.R().SetContext(context.Background()).SetBody(a).Post(m.cfg.WebhookURL)
+		if err != nil {
+			m.log.Warn().Err(err).Msg("could not send authentication alert")
+			return
+		}
+		if resp.IsError() {
+			m.log.Warn().Str("status", resp.Status()).Msg("could not send authentication alert")
+		}
+	}()
+}
+
+// classify maps a token validation error to a failure reason.
+func classify(err error) string {
+	if errors.Is(err, ErrTokenRequired) {
+		return ReasonMissing
+	}
+	var validationErr *jwt.ValidationError
+	if !errors.As(err, &validationErr) {
+		return ReasonInvalid
+	}
+	switch {
+	case validationErr.Errors&jwt.ValidationErrorMalformed != 0:
+		return ReasonMalformed
+	// an unverifiable token has an unknown key identifier or an unexpected signing method
+	case validationErr.Errors&(jwt.ValidationErrorSignatureInvalid|jwt.ValidationErrorUnverifiable) != 0:
+		return ReasonSignature
+	case validationErr.Errors&jwt.ValidationErrorExpired != 0:
+		return ReasonExpired
+	default:
+		return ReasonInvalid
+	}
+}
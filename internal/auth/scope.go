@@ -0,0 +1,49 @@
+package auth
+
+import "context"
+
+// Access token scopes granted to partner integrations, tokens without scopes are issued to users themselves and
+// grant full access.
+const (
+	ScopeOrdersRead   = "orders:read"
+	ScopeOrdersWrite  = "orders:write"
+	ScopeBalanceRead  = "balance:read"
+	ScopeBalanceWrite = "balance:write"
+)
+
+// knownScopes lists scopes which may be granted.
+var knownScopes = map[string]bool{
+	ScopeOrdersRead:   true,
+	ScopeOrdersWrite:  true,
+	ScopeBalanceRead:  true,
+	ScopeBalanceWrite: true,
+}
+
+type scopeKey struct{}
+
+// KnownScope reports whether scope may be granted.
+func KnownScope(scope string) bool {
+	return knownScopes[scope]
+}
+
+// ScopesFromContext retrieves the scopes of the authenticated access token from ctx, it returns false for
+// unscoped tokens granting full access.
+func ScopesFromContext(ctx context.Context) ([]string, bool) {
+	scopes, ok := ctx.Value(scopeKey{}).([]string)
+	return scopes, ok && len(scopes) > 0
+}
+
+// Permits reports whether the authenticated access token in ctx grants scope, unscoped tokens grant every scope
+// and an empty scope is granted to unscoped tokens only.
+func Permits(ctx context.Context, scope string) bool {
+	scopes, ok := ScopesFromContext(ctx)
+	if !ok {
+		return true
+	}
+	for _, granted := range scopes {
+		if scope != "" && granted == scope {
+			return true
+		}
+	}
+	return false
+}
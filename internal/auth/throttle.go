@@ -0,0 +1,157 @@
+package auth
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/config"
+	"github.com/danilovkiri/dk-go-gophermart/internal/metrics"
+)
+
+// throttlePruneInterval defines how often expired login attempt records are dropped.
+const throttlePruneInterval = time.Minute
+
+// LoginStatus defines the throttling state of a login.
+type LoginStatus struct {
+	Key               string
+	Failures          int
+	RemainingAttempts int
+	// LockedUntil is zero unless the login is locked out
+	LockedUntil time.Time
+}
+
+// Locked checks whether the login is locked out.
+func (s LoginStatus) Locked() bool {
+	return !s.LockedUntil.IsZero()
+}
+
+// attempts counts failed logins of a single key.
+type attempts struct {
+	failures    int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+// LoginThrottle locks logins out for a period once they fail too many times in a row, failures older than
+// the lockout period are forgotten. Counters are kept per instance.
+type LoginThrottle struct {
+	maxAttempts int
+	lockout     time.Duration
+	metrics     *metrics.Registry
+	mu          sync.Mutex
+	attempts    map[string]*attempts
+	lastPrune   time.Time
+}
+
+// NewLoginThrottle initializes a new login throttle, zero maximum attempts disable throttling.
+func NewLoginThrottle(cfg *config.AuthConfig, reg *metrics.Registry) *LoginThrottle {
+	return &LoginThrottle{
+		maxAttempts: cfg.LoginMaxAttempts,
+		lockout:     cfg.LoginLockout,
+		metrics:     reg,
+		attempts:    make(map[string]*attempts),
+	}
+}
+
+// Enabled checks whether logins are throttled.
+func (t *LoginThrottle) Enabled() bool {
+	return t.maxAttempts > 0
+}
+
+// Status returns the throttling state of key.
+func (t *LoginThrottle) Status(key string) LoginStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.status(key, time.Now())
+}
+
+// Fail counts a failed login of key and locks it out once the maximum number of attempts is reached.
+func (t *LoginThrottle) Fail(key string) LoginStatus {
+	if !t.Enabled() {
+		return LoginStatus{Key: key}
+	}
+	now := time.Now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.prune(now)
+	a, ok := t.attempts[key]
+	if !ok || t.expired(a, now) {
+		a = &attempts{}
+		t.attempts[key] = a
+	}
+	a.failures++
+	a.lastFailure = now
+	if a.failures >= t.maxAttempts {
+		a.lockedUntil = now.Add(t.lockout)
+		t.metrics.Counter("gophermart_auth_lockouts_total").Inc()
+	}
+	return t.status(key, now)
+}
+
+// Succeed forgets failed logins of key.
+func (t *LoginThrottle) Succeed(key string) {
+	t.Reset(key)
+}
+
+// Reset forgets failed logins of key lifting its lockout, it returns false if there were none.
+func (t *LoginThrottle) Reset(key string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, ok := t.attempts[key]
+	delete(t.attempts, key)
+	return ok
+}
+
+// Snapshot returns the throttling state of every key with recent failures sorted by key.
+func (t *LoginThrottle) Snapshot() []LoginStatus {
+	now := time.Now()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	statuses := make([]LoginStatus, 0, len(t.attempts))
+	for key, a := range t.attempts {
+		if !t.expired(a, now) {
+			statuses = append(statuses, t.status(key, now))
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Key < statuses[j].Key })
+	return statuses
+}
+
+// status builds the throttling state of key, must be called under lock.
+func (t *LoginThrottle) status(key string, now time.Time) LoginStatus {
+	status := LoginStatus{Key: key, RemainingAttempts: t.maxAttempts}
+	a, ok := t.attempts[key]
+	if !ok || t.expired(a, now) {
+		return status
+	}
+	status.Failures = a.failures
+	if now.Before(a.lockedUntil) {
+		status.RemainingAttempts = 0
+		status.LockedUntil = a.lockedUntil
+		return status
+	}
+	status.RemainingAttempts = t.maxAttempts - a.failures
+	return status
+}
+
+// expired checks whether failures of a record are forgotten, must be called under lock.
+func (t *LoginThrottle) expired(a *attempts, now time.Time) bool {
+	if !a.lockedUntil.IsZero() {
+		return !now.Before(a.lockedUntil)
+	}
+	return now.Sub(a.lastFailure) >= t.lockout
+}
+
+// prune drops expired records at most once per throttlePruneInterval, must be called under lock.
+func (t *LoginThrottle) prune(now time.Time) {
+	if now.Sub(t.lastPrune) < throttlePruneInterval {
+		return
+	}
+	t.lastPrune = now
+	for key, a := range t.attempts {
+		if t.expired(a, now) {
+			delete(t.attempts, key)
+		}
+	}
+}
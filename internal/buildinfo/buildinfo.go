@@ -0,0 +1,32 @@
+// Package buildinfo provides build-time information injected via linker flags.
+//
+// Example: go build -ldflags "-X github.com/danilovkiri/dk-go-gophermart/internal/buildinfo.Version=v1.0.0"
+
+package buildinfo
+
+import "runtime"
+
+// Build-time variables overwritten by linker flags.
+var (
+	Version = "N/A"
+	Commit  = "N/A"
+	Date    = "N/A"
+)
+
+// Info defines build information exposed via API.
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	Date      string `json:"date"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get returns the current build information.
+func Get() Info {
+	return Info{
+		Version:   Version,
+		Commit:    Commit,
+		Date:      Date,
+		GoVersion: runtime.Version(),
+	}
+}
@@ -0,0 +1,178 @@
+// Package inmem provides an in-process LRU cache with TTL-based expiration.
+
+package inmem
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/config"
+	"github.com/danilovkiri/dk-go-gophermart/internal/models/modeldto"
+)
+
+// Key prefixes separating cached data types.
+const (
+	balancePrefix     = "balance:"
+	ordersPrefix      = "orders:"
+	idempotencyPrefix = "idempotency:"
+	noncePrefix       = "nonce:"
+)
+
+// cacheEntry defines a single cached value.
+type cacheEntry struct {
+	key       string
+	value     interface{}
+	expiresAt time.Time
+}
+
+// Cache defines attributes of a struct available to its methods.
+type Cache struct {
+	mu       sync.Mutex
+	cfg      *config.CacheConfig
+	capacity int
+	order    *list.List
+	items    map[string]*list.Element
+}
+
+// InitCache initializes an in-process LRU cache.
+func InitCache(cfg *config.CacheConfig) *Cache {
+	return &Cache{
+		cfg:      cfg,
+		capacity: cfg.CacheSize,
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+// GetBalance retrieves a non-expired balance from cache.
+func (c *Cache) GetBalance(_ context.Context, userID string) (*modeldto.Balance, bool) {
+	value, ok := c.get(balancePrefix + userID)
+	if !ok {
+		return nil, false
+	}
+	balance := value.(modeldto.Balance)
+	return &balance, true
+}
+
+// SetBalance stores a balance in cache.
+func (c *Cache) SetBalance(_ context.Context, userID string, balance modeldto.Balance) {
+	c.set(balancePrefix+userID, balance, c.cfg.BalanceCacheTTL)
+}
+
+// InvalidateBalance removes a balance from cache.
+func (c *Cache) InvalidateBalance(_ context.Context, userID string) {
+	c.remove(balancePrefix + userID)
+}
+
+// GetOrders retrieves a non-expired list of orders from cache.
+func (c *Cache) GetOrders(_ context.Context, userID string) ([]modeldto.Order, bool) {
+	value, ok := c.get(ordersPrefix + userID)
+	if !ok {
+		return nil, false
+	}
+	orders := value.([]modeldto.Order)
+	return append([]modeldto.Order(nil), orders...), true
+}
+
+// SetOrders stores a list of orders in cache.
+func (c *Cache) SetOrders(_ context.Context, userID string, orders []modeldto.Order) {
+	c.set(ordersPrefix+userID, append([]modeldto.Order(nil), orders...), c.cfg.OrdersCacheTTL)
+}
+
+// InvalidateOrders removes a list of orders from cache.
+func (c *Cache) InvalidateOrders(_ context.Context, userID string) {
+	c.remove(ordersPrefix + userID)
+}
+
+// ReserveKey stores an idempotency key, it returns false if the key has already been reserved.
+func (c *Cache) ReserveKey(_ context.Context, key string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.items[idempotencyPrefix+key]; ok {
+		if time.Now().Before(element.Value.(*cacheEntry).expiresAt) {
+			return false, nil
+		}
+		c.removeElement(element)
+	}
+	c.setLocked(idempotencyPrefix+key, true, c.cfg.IdempotencyKeyTTL)
+	return true, nil
+}
+
+// ReleaseKey removes an idempotency key allowing the request to be retried.
+func (c *Cache) ReleaseKey(_ context.Context, key string) {
+	c.remove(idempotencyPrefix + key)
+}
+
+// ReserveNonce stores a request nonce for ttl, it returns false if the nonce has already been seen.
+func (c *Cache) ReserveNonce(_ context.Context, nonce string, ttl time.Duration) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.items[noncePrefix+nonce]; ok {
+		if time.Now().Before(element.Value.(*cacheEntry).expiresAt) {
+			return false, nil
+		}
+		c.removeElement(element)
+	}
+	c.setLocked(noncePrefix+nonce, true, ttl)
+	return true, nil
+}
+
+// get retrieves a non-expired value from cache.
+func (c *Cache) get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	entry := element.Value.(*cacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.removeElement(element)
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return entry.value, true
+}
+
+// set stores a value in cache.
+func (c *Cache) set(key string, value interface{}, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.setLocked(key, value, ttl)
+}
+
+// setLocked stores a value in cache evicting the least recently used entries if capacity is exceeded, must be called under lock.
+func (c *Cache) setLocked(key string, value interface{}, ttl time.Duration) {
+	if c.capacity <= 0 {
+		return
+	}
+	if element, ok := c.items[key]; ok {
+		entry := element.Value.(*cacheEntry)
+		entry.value = value
+		entry.expiresAt = time.Now().Add(ttl)
+		c.order.MoveToFront(element)
+		return
+	}
+	element := c.order.PushFront(&cacheEntry{key: key, value: value, expiresAt: time.Now().Add(ttl)})
+	c.items[key] = element
+	for c.order.Len() > c.capacity {
+		c.removeElement(c.order.Back())
+	}
+}
+
+// remove removes a value from cache.
+func (c *Cache) remove(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.items[key]; ok {
+		c.removeElement(element)
+	}
+}
+
+// removeElement removes an element from both the list and the index, must be called under lock.
+func (c *Cache) removeElement(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.items, element.Value.(*cacheEntry).key)
+}
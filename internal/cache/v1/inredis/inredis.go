@@ -0,0 +1,158 @@
+// Package inredis provides a Redis-backed cache shared between multiple service instances.
+
+package inredis
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/config"
+	"github.com/danilovkiri/dk-go-gophermart/internal/models/modeldto"
+	"github.com/go-redis/redis/v8"
+	"github.com/rs/zerolog"
+)
+
+// Key prefixes separating cached data types.
+const (
+	keyPrefix         = "gophermart:"
+	balancePrefix     = keyPrefix + "balance:"
+	ordersPrefix      = keyPrefix + "orders:"
+	idempotencyPrefix = keyPrefix + "idempotency:"
+	noncePrefix       = keyPrefix + "nonce:"
+)
+
+// Cache defines attributes of a struct available to its methods.
+type Cache struct {
+	cfg    *config.CacheConfig
+	client *redis.Client
+	log    *zerolog.Logger
+}
+
+// InitCache initializes a Redis cache client and verifies the connection.
+func InitCache(ctx context.Context, cfg *config.CacheConfig, log *zerolog.Logger) (*Cache, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     cfg.RedisAddress,
+		Password: cfg.RedisPassword,
+		DB:       cfg.RedisDB,
+	})
+	err := client.Ping(ctx).Err()
+	if err != nil {
+		return nil, err
+	}
+	log.Info().Msg("redis cache connection was established")
+	go func() {
+		<-ctx.Done()
+		err := client.Close()
+		if err != nil {
+			log.Error().Err(err).Msg("could not close redis cache connection")
+			return
+		}
+		log.Info().Msg("redis cache connection was closed")
+	}()
+	return &Cache{cfg: cfg, client: client, log: log}, nil
+}
+
+// Ping verifies that Redis connection is alive.
+func (c *Cache) Ping(ctx context.Context) error {
+	return c.client.Ping(ctx).Err()
+}
+
+// balanceEntry defines a cached balance, the time of its last change is omitted by the API representation.
+type balanceEntry struct {
+	modeldto.Balance
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// GetBalance retrieves a balance from cache.
+func (c *Cache) GetBalance(ctx context.Context, userID string) (*modeldto.Balance, bool) {
+	var entry balanceEntry
+	if !c.get(ctx, balancePrefix+userID, &entry) {
+		return nil, false
+	}
+	balance := entry.Balance
+	balance.UpdatedAt = entry.UpdatedAt
+	return &balance, true
+}
+
+// SetBalance stores a balance in cache.
+func (c *Cache) SetBalance(ctx context.Context, userID string, balance modeldto.Balance) {
+	c.set(ctx, balancePrefix+userID, balanceEntry{Balance: balance, UpdatedAt: balance.UpdatedAt}, c.cfg.BalanceCacheTTL)
+}
+
+// InvalidateBalance removes a balance from cache.
+func (c *Cache) InvalidateBalance(ctx context.Context, userID string) {
+	c.remove(ctx, balancePrefix+userID)
+}
+
+// GetOrders retrieves a list of orders from cache.
+func (c *Cache) GetOrders(ctx context.Context, userID string) ([]modeldto.Order, bool) {
+	var orders []modeldto.Order
+	if !c.get(ctx, ordersPrefix+userID, &orders) {
+		return nil, false
+	}
+	return orders, true
+}
+
+// SetOrders stores a list of orders in cache.
+func (c *Cache) SetOrders(ctx context.Context, userID string, orders []modeldto.Order) {
+	c.set(ctx, ordersPrefix+userID, orders, c.cfg.OrdersCacheTTL)
+}
+
+// InvalidateOrders removes a list of orders from cache.
+func (c *Cache) InvalidateOrders(ctx context.Context, userID string) {
+	c.remove(ctx, ordersPrefix+userID)
+}
+
+// ReserveKey stores an idempotency key, it returns false if the key has already been reserved.
+func (c *Cache) ReserveKey(ctx context.Context, key string) (bool, error) {
+	return c.client.SetNX(ctx, idempotencyPrefix+key, 1, c.cfg.IdempotencyKeyTTL).Result()
+}
+
+// ReleaseKey removes an idempotency key allowing the request to be retried.
+func (c *Cache) ReleaseKey(ctx context.Context, key string) {
+	c.remove(ctx, idempotencyPrefix+key)
+}
+
+// ReserveNonce stores a request nonce for ttl, it returns false if the nonce has already been seen.
+func (c *Cache) ReserveNonce(ctx context.Context, nonce string, ttl time.Duration) (bool, error) {
+	return c.client.SetNX(ctx, noncePrefix+nonce, 1, ttl).Result()
+}
+
+// get retrieves and decodes a value from cache, Redis failures are logged and treated as cache misses.
+func (c *Cache) get(ctx context.Context, key string, value interface{}) bool {
+	data, err := c.client.Get(ctx, key).Bytes()
+	if err != nil {
+		if err != redis.Nil {
+			c.log.Warn().Err(err).Msg("redis cache lookup failed")
+		}
+		return false
+	}
+	err = json.Unmarshal(data, value)
+	if err != nil {
+		c.log.Warn().Err(err).Msg("redis cache entry decoding failed")
+		return false
+	}
+	return true
+}
+
+// set encodes and stores a value in cache.
+func (c *Cache) set(ctx context.Context, key string, value interface{}, ttl time.Duration) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		c.log.Warn().Err(err).Msg("redis cache entry encoding failed")
+		return
+	}
+	err = c.client.Set(ctx, key, data, ttl).Err()
+	if err != nil {
+		c.log.Warn().Err(err).Msg("redis cache update failed")
+	}
+}
+
+// remove deletes a value from cache.
+func (c *Cache) remove(ctx context.Context, key string) {
+	err := c.client.Del(ctx, key).Err()
+	if err != nil {
+		c.log.Warn().Err(err).Msg("redis cache invalidation failed")
+	}
+}
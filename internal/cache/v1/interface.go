@@ -0,0 +1,43 @@
+// Package cache provides caching functionality for frequently queried data.
+
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/models/modeldto"
+)
+
+// BalanceCache defines a set of methods for types implementing BalanceCache.
+type BalanceCache interface {
+	GetBalance(ctx context.Context, userID string) (*modeldto.Balance, bool)
+	SetBalance(ctx context.Context, userID string, balance modeldto.Balance)
+	InvalidateBalance(ctx context.Context, userID string)
+}
+
+// OrdersCache defines a set of methods for types implementing OrdersCache.
+type OrdersCache interface {
+	GetOrders(ctx context.Context, userID string) ([]modeldto.Order, bool)
+	SetOrders(ctx context.Context, userID string, orders []modeldto.Order)
+	InvalidateOrders(ctx context.Context, userID string)
+}
+
+// IdempotencyStore defines a set of methods for types implementing IdempotencyStore.
+type IdempotencyStore interface {
+	ReserveKey(ctx context.Context, key string) (bool, error)
+	ReleaseKey(ctx context.Context, key string)
+}
+
+// NonceStore defines a set of methods for types implementing NonceStore.
+type NonceStore interface {
+	ReserveNonce(ctx context.Context, nonce string, ttl time.Duration) (bool, error)
+}
+
+// Cache defines a set of methods for types implementing Cache.
+type Cache interface {
+	BalanceCache
+	OrdersCache
+	IdempotencyStore
+	NonceStore
+}
@@ -0,0 +1,137 @@
+// Package cashback provides a config-driven rules engine adjusting accruals credited for processed orders.
+//
+// Rules are read from a YAML file and evaluated in order, the first rule matching an order is applied:
+//
+//	rules:
+//	  - name: summer-mobile
+//	    channels: [mobile]
+//	    min_accrual: 100
+//	    from: 2026-06-01T00:00:00Z
+//	    to: 2026-09-01T00:00:00Z
+//	    multiplier: 1.5
+//	    bonus: 10
+//
+// Omitted bounds are not checked, an omitted multiplier leaves the accrual unchanged before the bonus is added.
+package cashback
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Rule defines a single cashback campaign.
+type Rule struct {
+	Name string `yaml:"name"`
+	// Channels lists order upload channels the rule applies to, any channel matches if empty
+	Channels []string `yaml:"channels"`
+	// MinAccrual and MaxAccrual bound the accrual reported by the accrual system, zero MaxAccrual is unbounded
+	MinAccrual float64 `yaml:"min_accrual"`
+	MaxAccrual float64 `yaml:"max_accrual"`
+	// From and To bound the order upload time, To is exclusive
+	From       time.Time `yaml:"from"`
+	To         time.Time `yaml:"to"`
+	Multiplier float64   `yaml:"multiplier"`
+	Bonus      float64   `yaml:"bonus"`
+}
+
+// Order defines the order attributes rules are matched against.
+type Order struct {
+	Accrual    float64
+	Channel    string
+	UploadedAt time.Time
+}
+
+// Result defines the outcome of rule evaluation, Rule is empty if no rule matched.
+type Result struct {
+	BaseAccrual float64
+	Accrual     float64
+	Rule        string
+}
+
+// Engine defines attributes of a struct available to its methods.
+type Engine struct {
+	rules []Rule
+}
+
+// NewEngine initializes a rules engine, an engine without rules credits accruals unchanged.
+func NewEngine(rules []Rule) (*Engine, error) {
+	for i, rule := range rules {
+		if rule.Name == "" {
+			return nil, fmt.Errorf("cashback rule %d must have a name", i)
+		}
+		if rule.Multiplier < 0 || rule.Bonus < 0 {
+			return nil, fmt.Errorf("cashback rule %s must not decrease accruals below zero", rule.Name)
+		}
+		if rule.MaxAccrual != 0 && rule.MaxAccrual < rule.MinAccrual {
+			return nil, fmt.Errorf("cashback rule %s has an empty accrual range", rule.Name)
+		}
+		if !rule.From.IsZero() && !rule.To.IsZero() && !rule.To.After(rule.From) {
+			return nil, fmt.Errorf("cashback rule %s has an empty date window", rule.Name)
+		}
+	}
+	return &Engine{rules: rules}, nil
+}
+
+// ReadFile reads rules from a YAML file, an empty path yields an engine without rules.
+func ReadFile(path string) (*Engine, error) {
+	if path == "" {
+		return NewEngine(nil)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Rules []Rule `yaml:"rules"`
+	}
+	err = yaml.Unmarshal(data, &file)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse cashback rules: %w", err)
+	}
+	return NewEngine(file.Rules)
+}
+
+// Apply evaluates rules against an order and returns the accrual to be credited.
+func (e *Engine) Apply(order Order) Result {
+	result := Result{BaseAccrual: order.Accrual, Accrual: order.Accrual}
+	for _, rule := range e.rules {
+		if !rule.matches(order) {
+			continue
+		}
+		multiplier := rule.Multiplier
+		if multiplier == 0 {
+			multiplier = 1
+		}
+		// amounts are stored as NUMERIC(10, 2)
+		result.Accrual = math.Round((order.Accrual*multiplier+rule.Bonus)*100) / 100
+		result.Rule = rule.Name
+		return result
+	}
+	return result
+}
+
+// matches reports whether all conditions of the rule hold for an order.
+func (r *Rule) matches(order Order) bool {
+	if order.Accrual < r.MinAccrual || (r.MaxAccrual != 0 && order.Accrual > r.MaxAccrual) {
+		return false
+	}
+	if !r.From.IsZero() && order.UploadedAt.Before(r.From) {
+		return false
+	}
+	if !r.To.IsZero() && !order.UploadedAt.Before(r.To) {
+		return false
+	}
+	if len(r.Channels) == 0 {
+		return true
+	}
+	for _, channel := range r.Channels {
+		if channel == order.Channel {
+			return true
+		}
+	}
+	return false
+}
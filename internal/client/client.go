@@ -3,33 +3,148 @@ package client
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strconv"
+	"sync"
+	"time"
 
 	"github.com/danilovkiri/dk-go-gophermart/internal/config"
+	"github.com/danilovkiri/dk-go-gophermart/internal/tenant"
+	"github.com/danilovkiri/dk-go-gophermart/internal/tracing"
 	"github.com/go-resty/resty/v2"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
+// endpoint tracks the health of a single Accrual Service address.
+type endpoint struct {
+	address  string
+	failures int
+	// downSince is set once the address is considered unavailable
+	downSince time.Time
+}
+
 // Client defines attributes of a struct available to its methods.
 type Client struct {
 	client       *resty.Client
 	serverConfig *config.ServerConfig
+	tenantConfig *config.TenantConfig
 	log          *zerolog.Logger
+	mu           sync.Mutex
+	endpoints    []*endpoint
 }
 
 // InitClient initializes a resty client.
-func InitClient(serverConfig *config.ServerConfig, log *zerolog.Logger) *Client {
-	accrualClient := resty.New()
-	log.Info().Msg("accrual service client initialized")
-	return &Client{client: accrualClient, serverConfig: serverConfig, log: log}
+func InitClient(serverConfig *config.ServerConfig, tenantConfig *config.TenantConfig, log *zerolog.Logger) *Client {
+	accrualClient := resty.New().SetTimeout(serverConfig.AccrualTimeout)
+	accrualClient.SetTransport(otelhttp.NewTransport(accrualClient.GetClient().Transport))
+	var endpoints []*endpoint
+	seen := make(map[string]bool)
+	for _, address := range append([]string{serverConfig.AccrualAddress}, serverConfig.AccrualFallbackAddresses...) {
+		if address == "" || seen[address] {
+			continue
+		}
+		seen[address] = true
+		endpoints = append(endpoints, &endpoint{address: address})
+	}
+	log.Info().Msg(fmt.Sprintf("accrual service client initialized with %v addresses", len(endpoints)))
+	return &Client{client: accrualClient, serverConfig: serverConfig, tenantConfig: tenantConfig, log: log, endpoints: endpoints}
+}
+
+// candidates returns the default addresses to try in priority order: available ones and unavailable ones due
+// for a retry. All addresses are returned if none qualifies so that requests are never refused locally.
+func (c *Client) candidates() []*endpoint {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var candidates []*endpoint
+	for _, e := range c.endpoints {
+		if e.downSince.IsZero() || time.Since(e.downSince) >= c.serverConfig.AccrualFailbackInterval {
+			candidates = append(candidates, e)
+		}
+	}
+	if len(candidates) == 0 {
+		return c.endpoints
+	}
+	return candidates
+}
+
+// recordSuccess marks an address available failing back to it if it has been unavailable.
+func (c *Client) recordSuccess(e *endpoint) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !e.downSince.IsZero() {
+		c.log.Info().Msg(fmt.Sprintf("accrual service %s is available again", e.address))
+	}
+	e.failures = 0
+	e.downSince = time.Time{}
+}
+
+// recordFailure counts a failed attempt, the address is marked unavailable once the threshold is reached and
+// its retry is postponed by another interval if it fails again.
+func (c *Client) recordFailure(e *endpoint) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e.failures++
+	if e.failures < c.serverConfig.AccrualFailoverThreshold {
+		return
+	}
+	if e.downSince.IsZero() {
+		c.log.Warn().Msg(fmt.Sprintf("accrual service %s is unavailable, failing over", e.address))
+	}
+	e.downSince = time.Now()
+}
+
+// do runs a request against the default addresses in priority order until one of them responds without
+// a server error. Tenant-specific addresses are used as they are.
+func (c *Client) do(ctx context.Context, request func(address string) (*resty.Response, error)) (*resty.Response, error) {
+	if address, ok := c.tenantConfig.AccrualAddresses[tenant.FromContext(ctx)]; ok {
+		return request(address)
+	}
+	var response *resty.Response
+	err := errors.New("no accrual service address configured")
+	for _, e := range c.candidates() {
+		response, err = request(e.address)
+		if err == nil && response.StatusCode() < 500 {
+			c.recordSuccess(e)
+			return response, nil
+		}
+		if ctx.Err() != nil {
+			// the caller has given up, which tells nothing about the address
+			return nil, ctx.Err()
+		}
+		c.recordFailure(e)
+	}
+	return response, err
+}
+
+// Ping verifies that the Accrual Service is reachable, any HTTP response is considered healthy.
+func (c *Client) Ping(ctx context.Context) error {
+	_, err := c.do(ctx, func(address string) (*resty.Response, error) {
+		return c.client.R().SetContext(ctx).Get(address)
+	})
+	return err
 }
 
 // GetAccrual executes accrual retrieval query for a given order Luhn-compliant identifier.
 func (c *Client) GetAccrual(ctx context.Context, orderNumber int) (*resty.Response, error) {
 	log.Info().Msg(fmt.Sprintf("sending request for order %v", orderNumber))
-	response, err := c.client.R().SetContext(ctx).SetPathParams(map[string]string{"orderNumber": strconv.Itoa(orderNumber)}).Get(c.serverConfig.AccrualAddress + "/api/orders/{orderNumber}")
+	response, err := c.do(ctx, func(address string) (*resty.Response, error) {
+		// every attempt is a span of its own so that failovers show up in the trace, the HTTP client span
+		// propagating the trace context is started by the transport
+		ctx, span := tracing.Start(ctx, "accrual.GetAccrual", tracing.AttrOrderNumber.String(strconv.Itoa(orderNumber)))
+		defer span.End()
+		response, err := c.client.R().SetContext(ctx).SetPathParams(map[string]string{"orderNumber": strconv.Itoa(orderNumber)}).Get(address + "/api/orders/{orderNumber}")
+		if err != nil {
+			tracing.RecordError(span, err)
+			return nil, err
+		}
+		if response.StatusCode() >= 500 {
+			tracing.RecordError(span, errors.New(response.Status()))
+		}
+		return response, nil
+	})
 	if err != nil {
 		c.log.Err(err).Msg(fmt.Sprintf("accrual retrieval from service failed for order %v", orderNumber))
 		return nil, err
@@ -0,0 +1,57 @@
+// Package errcodes provides a catalog of machine-readable application error codes.
+
+package errcodes
+
+import "errors"
+
+// Code defines a machine-readable application error code.
+type Code string
+
+// Application error codes.
+const (
+	InvalidRequest          Code = "INVALID_REQUEST"
+	Unauthorized            Code = "UNAUTHORIZED"
+	SessionExpired          Code = "SESSION_EXPIRED"
+	InvalidCredentials      Code = "INVALID_CREDENTIALS"
+	UnknownUser             Code = "UNKNOWN_USER"
+	Forbidden               Code = "FORBIDDEN"
+	NotFound                Code = "NOT_FOUND"
+	AlreadyExists           Code = "ALREADY_EXISTS"
+	LoginTaken              Code = "LOGIN_TAKEN"
+	DuplicateOrder          Code = "DUPLICATE_ORDER"
+	OrderOwnedByAnotherUser Code = "ORDER_OWNED_BY_ANOTHER_USER"
+	OrderInvalidNumber      Code = "ORDER_INVALID_NUMBER"
+	OrderNotRecheckable     Code = "ORDER_NOT_RECHECKABLE"
+	OrderNotSuspended       Code = "ORDER_NOT_SUSPENDED"
+	InsufficientFunds       Code = "INSUFFICIENT_FUNDS"
+	HoldNotActive           Code = "HOLD_NOT_ACTIVE"
+	UnsupportedCurrency     Code = "UNSUPPORTED_CURRENCY"
+	DuplicateRequest        Code = "DUPLICATE_REQUEST"
+	MergeConflict           Code = "MERGE_CONFLICT"
+	ConcurrentUpdate        Code = "CONCURRENT_UPDATE"
+	CaptchaFailed           Code = "CAPTCHA_FAILED"
+	TooManyRequests         Code = "TOO_MANY_REQUESTS"
+	UnsupportedMediaType    Code = "UNSUPPORTED_MEDIA_TYPE"
+	PayloadTooLarge         Code = "PAYLOAD_TOO_LARGE"
+	Timeout                 Code = "TIMEOUT"
+	DeadlineExceeded        Code = "DEADLINE_EXCEEDED"
+	StorageError            Code = "STORAGE_ERROR"
+	StorageUnavailable      Code = "STORAGE_UNAVAILABLE"
+	ServiceUnavailable      Code = "SERVICE_UNAVAILABLE"
+	Internal                Code = "INTERNAL_ERROR"
+	DependencyMisconfigured Code = "DEPENDENCY_MISCONFIGURED"
+)
+
+// Coder defines a set of methods for errors carrying an application error code.
+type Coder interface {
+	ErrorCode() Code
+}
+
+// Of retrieves the application error code from an error chain, Internal is returned if none was found.
+func Of(err error) Code {
+	var coder Coder
+	if errors.As(err, &coder) {
+		return coder.ErrorCode()
+	}
+	return Internal
+}
@@ -0,0 +1,225 @@
+// Package fixtures provides loading of declarative YAML snapshots into storage for integration tests.
+//
+// A snapshot lists users together with their orders, withdrawals and the expected balance:
+//
+//	users:
+//	  - login: alice
+//	    password: secret
+//	    tenant: acme
+//	    orders:
+//	      - number: "12345678903"
+//	        status: PROCESSED
+//	        accrual: 500
+//	      - number: "9278923470"
+//	        status: NEW
+//	        queue: true
+//	    withdrawals:
+//	      - order: "2377225624"
+//	        sum: 100
+//	    balance: 400
+//
+// Snapshots are loaded through the storage interface only, so any storage implementation can be seeded.
+package fixtures
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"time"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/models/modeldto"
+	"github.com/danilovkiri/dk-go-gophermart/internal/models/modelqueue"
+	"github.com/danilovkiri/dk-go-gophermart/internal/ordernum"
+	"github.com/danilovkiri/dk-go-gophermart/internal/service/hasher/v1"
+	"github.com/danilovkiri/dk-go-gophermart/internal/service/processor/v1/processor"
+	"github.com/danilovkiri/dk-go-gophermart/internal/service/secretary/v1"
+	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1"
+	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/modelstorage"
+	"github.com/danilovkiri/dk-go-gophermart/internal/tenant"
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+)
+
+// resolvePollInterval defines how often resolved orders are checked to have reached their final status.
+const resolvePollInterval = 10 * time.Millisecond
+
+type (
+	// Snapshot is a declarative storage state.
+	Snapshot struct {
+		Users []User `yaml:"users"`
+	}
+
+	// User is a user record along with its history, ID is generated if omitted.
+	User struct {
+		ID          string       `yaml:"id"`
+		Login       string       `yaml:"login"`
+		Password    string       `yaml:"password"`
+		Tenant      string       `yaml:"tenant"`
+		Orders      []Order      `yaml:"orders"`
+		Withdrawals []Withdrawal `yaml:"withdrawals"`
+		Balance     *float64     `yaml:"balance"`
+	}
+
+	// Order is an uploaded order, Queue sends a non-final order to the accrual broker.
+	Order struct {
+		Number  string  `yaml:"number"`
+		Status  string  `yaml:"status"`
+		Accrual float64 `yaml:"accrual"`
+		Channel string  `yaml:"channel"`
+		Queue   bool    `yaml:"queue"`
+	}
+
+	// Withdrawal is a processed withdrawal.
+	Withdrawal struct {
+		Order string  `yaml:"order"`
+		Sum   float64 `yaml:"sum"`
+	}
+)
+
+// ReadFile parses a snapshot from a YAML file.
+func ReadFile(path string) (*Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse parses a snapshot from YAML.
+func Parse(data []byte) (*Snapshot, error) {
+	var snapshot Snapshot
+	err := yaml.Unmarshal(data, &snapshot)
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range snapshot.Users {
+		if user.Login == "" {
+			return nil, fmt.Errorf("fixture user login must not be empty")
+		}
+		for _, order := range user.Orders {
+			switch order.Status {
+			case "", "NEW", "PROCESSING", "PROCESSED", "INVALID":
+			default:
+				return nil, fmt.Errorf("unknown status %s of fixture order %s", order.Status, order.Number)
+			}
+		}
+	}
+	return &snapshot, nil
+}
+
+// Load stores a snapshot and returns user IDs keyed by login, sec and passwordHasher cipher logins and hash passwords
+// the way the processor does and may be nil for storing them as is with logins being their own digests.
+// Final orders are resolved and awaited before withdrawals are stored, declared balances are verified at last.
+func Load(ctx context.Context, st storage.Storage, snapshot *Snapshot, sec secretary.Secretary, passwordHasher hasher.Hasher) (map[string]string, error) {
+	userIDs := make(map[string]string, len(snapshot.Users))
+	for _, user := range snapshot.Users {
+		userID := user.ID
+		if userID == "" {
+			userID = uuid.New().String()
+		}
+		userCtx := tenant.WithTenant(ctx, user.Tenant)
+		login := processor.NormalizeLogin(user.Login)
+		credentials := modeldto.User{Login: login, Password: user.Password}
+		lookup := modelstorage.LoginLookup{Hash: login}
+		if sec != nil {
+			sealedLogin, err := sec.Seal(login)
+			if err != nil {
+				return nil, fmt.Errorf("ciphering fixture user %s: %w", user.Login, err)
+			}
+			credentials.Login = sealedLogin
+			lookup.Hash = sec.LoginHash(login)
+		}
+		if passwordHasher != nil {
+			passwordHash, err := passwordHasher.Hash(user.Password)
+			if err != nil {
+				return nil, fmt.Errorf("hashing password of fixture user %s: %w", user.Login, err)
+			}
+			credentials.Password = passwordHash
+		}
+		err := st.AddNewUser(userCtx, credentials, lookup, userID)
+		if err != nil {
+			return nil, fmt.Errorf("loading fixture user %s: %w", user.Login, err)
+		}
+		userIDs[user.Login] = userID
+		err = loadOrders(userCtx, st, userID, user.Orders)
+		if err != nil {
+			return nil, fmt.Errorf("loading orders of fixture user %s: %w", user.Login, err)
+		}
+		for _, withdrawal := range user.Withdrawals {
+			err = st.AddNewWithdrawal(userCtx, userID, modeldto.NewOrderWithdrawal{OrderNumber: withdrawal.Order, Amount: withdrawal.Sum})
+			if err != nil {
+				return nil, fmt.Errorf("loading withdrawal %s of fixture user %s: %w", withdrawal.Order, user.Login, err)
+			}
+		}
+		if user.Balance == nil {
+			continue
+		}
+		amount, err := st.GetCurrentAmount(userCtx, userID)
+		if err != nil {
+			return nil, fmt.Errorf("checking balance of fixture user %s: %w", user.Login, err)
+		}
+		if math.Abs(amount-*user.Balance) >= 0.005 {
+			return nil, fmt.Errorf("balance of fixture user %s is %v, expected %v", user.Login, amount, *user.Balance)
+		}
+	}
+	return userIDs, nil
+}
+
+// loadOrders stores user's orders, final ones are resolved and awaited to get their accruals credited.
+func loadOrders(ctx context.Context, st storage.Storage, userID string, orders []Order) error {
+	for _, order := range orders {
+		orderNumber, err := ordernum.Parse(order.Number)
+		if err != nil {
+			return fmt.Errorf("illegal order number %s", order.Number)
+		}
+		channel := order.Channel
+		if channel == "" {
+			channel = "unknown"
+		}
+		err = st.AddNewOrder(ctx, userID, orderNumber, "", channel)
+		if err != nil {
+			return err
+		}
+		switch order.Status {
+		case "PROCESSED", "INVALID", "PROCESSING":
+			err = st.ResolveOrder(ctx, orderNumber, order.Status, order.Accrual)
+			if err != nil {
+				return err
+			}
+			err = awaitStatus(ctx, st, userID, orderNumber, order.Status)
+			if err != nil {
+				return err
+			}
+		}
+		if order.Queue && order.Status != "PROCESSED" && order.Status != "INVALID" {
+			st.SendToQueue(modelqueue.OrderQueueEntry{
+				TenantID:    tenant.FromContext(ctx),
+				UserID:      userID,
+				OrderNumber: orderNumber,
+				OrderStatus: "NEW",
+			})
+		}
+	}
+	return nil
+}
+
+// awaitStatus waits for an order to reach the given status as resolved orders are updated asynchronously.
+func awaitStatus(ctx context.Context, st storage.Storage, userID string, orderNumber int, status string) error {
+	ticker := time.NewTicker(resolvePollInterval)
+	defer ticker.Stop()
+	for {
+		order, err := st.GetOrder(ctx, userID, orderNumber)
+		if err != nil {
+			return err
+		}
+		if order.Status == status {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("order %v did not reach status %s: %w", orderNumber, status, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,235 @@
+// Package metrics provides a lightweight registry of runtime and application metrics.
+
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Counter defines a monotonically increasing metric.
+type Counter struct {
+	value uint64
+}
+
+// Inc increments the counter by one.
+func (c *Counter) Inc() {
+	atomic.AddUint64(&c.value, 1)
+}
+
+// Add increments the counter by delta.
+func (c *Counter) Add(delta uint64) {
+	atomic.AddUint64(&c.value, delta)
+}
+
+// Value returns the current counter value.
+func (c *Counter) Value() uint64 {
+	return atomic.LoadUint64(&c.value)
+}
+
+// Gauge defines a metric which can arbitrarily go up and down.
+type Gauge struct {
+	value int64
+}
+
+// Set sets the gauge to a given value.
+func (g *Gauge) Set(value int64) {
+	atomic.StoreInt64(&g.value, value)
+}
+
+// Add adds delta (which might be negative) to the gauge.
+func (g *Gauge) Add(delta int64) {
+	atomic.AddInt64(&g.value, delta)
+}
+
+// Value returns the current gauge value.
+func (g *Gauge) Value() int64 {
+	return atomic.LoadInt64(&g.value)
+}
+
+// Metric kinds reported in samples.
+const (
+	KindCounter = "counter"
+	KindGauge   = "gauge"
+)
+
+// Sample defines a single metric value captured by Registry.Snapshot.
+type Sample struct {
+	Key    string
+	Name   string
+	Labels []string
+	Kind   string
+	Value  float64
+}
+
+// series defines the name and label pairs a metric key was built from.
+type series struct {
+	name   string
+	labels []string
+}
+
+// Registry defines attributes of a struct available to its methods.
+type Registry struct {
+	mu         sync.RWMutex
+	counters   map[string]*Counter
+	gauges     map[string]*Gauge
+	gaugeFuncs map[string]func() float64
+	series     map[string]series
+}
+
+// NewRegistry initializes an empty metrics registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		counters:   make(map[string]*Counter),
+		gauges:     make(map[string]*Gauge),
+		gaugeFuncs: make(map[string]func() float64),
+		series:     make(map[string]series),
+	}
+}
+
+// Counter returns a counter for a given name and label pairs creating it if necessary.
+func (r *Registry) Counter(name string, labels ...string) *Counter {
+	key := metricKey(name, labels)
+	r.mu.RLock()
+	c, ok := r.counters[key]
+	r.mu.RUnlock()
+	if ok {
+		return c
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if c, ok = r.counters[key]; !ok {
+		c = &Counter{}
+		r.counters[key] = c
+		r.series[key] = series{name: name, labels: labels}
+	}
+	return c
+}
+
+// Gauge returns a gauge for a given name and label pairs creating it if necessary.
+func (r *Registry) Gauge(name string, labels ...string) *Gauge {
+	key := metricKey(name, labels)
+	r.mu.RLock()
+	g, ok := r.gauges[key]
+	r.mu.RUnlock()
+	if ok {
+		return g
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if g, ok = r.gauges[key]; !ok {
+		g = &Gauge{}
+		r.gauges[key] = g
+		r.series[key] = series{name: name, labels: labels}
+	}
+	return g
+}
+
+// GaugeFunc registers a gauge whose value is computed upon each exposition.
+func (r *Registry) GaugeFunc(name string, f func() float64, labels ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := metricKey(name, labels)
+	r.gaugeFuncs[key] = f
+	r.series[key] = series{name: name, labels: labels}
+}
+
+// Snapshot returns the current values of all registered metrics sorted by key, it is used by push-based exporters.
+func (r *Registry) Snapshot() []Sample {
+	r.mu.RLock()
+	samples := make([]Sample, 0, len(r.counters)+len(r.gauges)+len(r.gaugeFuncs))
+	for key, c := range r.counters {
+		s := r.series[key]
+		samples = append(samples, Sample{Key: key, Name: s.name, Labels: s.labels, Kind: KindCounter, Value: float64(c.Value())})
+	}
+	for key, g := range r.gauges {
+		s := r.series[key]
+		samples = append(samples, Sample{Key: key, Name: s.name, Labels: s.labels, Kind: KindGauge, Value: float64(g.Value())})
+	}
+	funcs := make(map[string]func() float64, len(r.gaugeFuncs))
+	for key, f := range r.gaugeFuncs {
+		funcs[key] = f
+	}
+	names := make(map[string]series, len(funcs))
+	for key := range funcs {
+		names[key] = r.series[key]
+	}
+	r.mu.RUnlock()
+	for key, f := range funcs {
+		s := names[key]
+		samples = append(samples, Sample{Key: key, Name: s.name, Labels: s.labels, Kind: KindGauge, Value: f()})
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i].Key < samples[j].Key })
+	return samples
+}
+
+// RegisterRuntime registers goroutine, memory and GC gauges.
+func (r *Registry) RegisterRuntime() {
+	var mu sync.Mutex
+	var stats runtime.MemStats
+	readStats := func() runtime.MemStats {
+		mu.Lock()
+		defer mu.Unlock()
+		runtime.ReadMemStats(&stats)
+		return stats
+	}
+	r.GaugeFunc("go_goroutines", func() float64 { return float64(runtime.NumGoroutine()) })
+	r.GaugeFunc("go_memstats_heap_alloc_bytes", func() float64 { return float64(readStats().HeapAlloc) })
+	r.GaugeFunc("go_memstats_sys_bytes", func() float64 { return float64(readStats().Sys) })
+	r.GaugeFunc("go_gc_cycles_total", func() float64 { return float64(readStats().NumGC) })
+	r.GaugeFunc("go_gc_pause_total_seconds", func() float64 { return float64(readStats().PauseTotalNs) / 1e9 })
+	r.GaugeFunc("go_gc_last_pause_seconds", func() float64 {
+		s := readStats()
+		return float64(s.PauseNs[(s.NumGC+255)%256]) / 1e9
+	})
+}
+
+// WritePrometheus writes all registered metrics in Prometheus text exposition format.
+func (r *Registry) WritePrometheus(w io.Writer) error {
+	r.mu.RLock()
+	lines := make([]string, 0, len(r.counters)+len(r.gauges)+len(r.gaugeFuncs))
+	for key, c := range r.counters {
+		lines = append(lines, fmt.Sprintf("%s %d", key, c.Value()))
+	}
+	for key, g := range r.gauges {
+		lines = append(lines, fmt.Sprintf("%s %d", key, g.Value()))
+	}
+	funcs := make(map[string]func() float64, len(r.gaugeFuncs))
+	for key, f := range r.gaugeFuncs {
+		funcs[key] = f
+	}
+	r.mu.RUnlock()
+	for key, f := range funcs {
+		lines = append(lines, fmt.Sprintf("%s %v", key, f()))
+	}
+	sort.Strings(lines)
+	for _, line := range lines {
+		if _, err := io.WriteString(w, line+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// metricKey builds a unique metric identifier from its name and label pairs.
+func metricKey(name string, labels []string) string {
+	if len(labels) < 2 {
+		return name
+	}
+	pairs := make([]string, 0, len(labels)/2)
+	for i := 0; i+1 < len(labels); i += 2 {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", labels[i], labels[i+1]))
+	}
+	return name + "{" + strings.Join(pairs, ",") + "}"
+}
+
+// Common metric names shared across packages.
+const (
+	OrderQueueSize       = "gophermart_order_queue_size"
+	OrderQueueInProgress = "gophermart_order_queue_in_progress"
+)
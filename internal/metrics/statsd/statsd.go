@@ -0,0 +1,125 @@
+// Package statsd provides a push-based exporter of registry metrics to StatsD or Datadog agents.
+
+package statsd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/config"
+	"github.com/danilovkiri/dk-go-gophermart/internal/metrics"
+	"github.com/rs/zerolog"
+)
+
+// maxPacketSize keeps datagrams below the typical Ethernet MTU.
+const maxPacketSize = 1432
+
+// Exporter defines attributes of a struct available to its methods.
+type Exporter struct {
+	ctx      context.Context
+	cfg      *config.MetricsConfig
+	log      *zerolog.Logger
+	wg       *sync.WaitGroup
+	registry *metrics.Registry
+	conn     net.Conn
+	reported map[string]float64
+}
+
+// InitExporter initializes a StatsD exporter sending datagrams to the configured agent.
+func InitExporter(ctx context.Context, cfg *config.MetricsConfig, log *zerolog.Logger, wg *sync.WaitGroup, reg *metrics.Registry) (*Exporter, error) {
+	conn, err := net.Dial("udp", net.JoinHostPort(cfg.StatsDHost, strconv.Itoa(cfg.StatsDPort)))
+	if err != nil {
+		return nil, err
+	}
+	return &Exporter{
+		ctx:      ctx,
+		cfg:      cfg,
+		log:      log,
+		wg:       wg,
+		registry: reg,
+		conn:     conn,
+		reported: make(map[string]float64),
+	}, nil
+}
+
+// ListenAndExport periodically flushes registry metrics until the context is cancelled.
+func (e *Exporter) ListenAndExport() {
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		defer e.conn.Close()
+		e.log.Info().Msg("started statsd metrics export")
+		ticker := time.NewTicker(e.cfg.StatsDFlushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-e.ctx.Done():
+				e.flush()
+				e.log.Info().Msg("stopped statsd metrics export")
+				return
+			case <-ticker.C:
+				e.flush()
+			}
+		}
+	}()
+}
+
+// flush sends counters as deltas since the previous flush and gauges as absolute values.
+func (e *Exporter) flush() {
+	var packet strings.Builder
+	for _, sample := range e.registry.Snapshot() {
+		value := sample.Value
+		metricType := "g"
+		if sample.Kind == metrics.KindCounter {
+			metricType = "c"
+			value -= e.reported[sample.Key]
+			e.reported[sample.Key] = sample.Value
+			if value == 0 {
+				continue
+			}
+		}
+		line := e.format(sample, value, metricType)
+		if packet.Len() > 0 && packet.Len()+len(line)+1 > maxPacketSize {
+			e.send(packet.String())
+			packet.Reset()
+		}
+		if packet.Len() > 0 {
+			packet.WriteByte('\n')
+		}
+		packet.WriteString(line)
+	}
+	if packet.Len() > 0 {
+		e.send(packet.String())
+	}
+}
+
+// format renders a sample as a StatsD line, labels become DogStatsD tags or are folded into the metric name.
+func (e *Exporter) format(sample metrics.Sample, value float64, metricType string) string {
+	name := e.cfg.StatsDPrefix + sample.Name
+	var tags []string
+	for i := 0; i+1 < len(sample.Labels); i += 2 {
+		if e.cfg.StatsDTags {
+			tags = append(tags, sample.Labels[i]+":"+sample.Labels[i+1])
+		} else {
+			name += "." + sample.Labels[i+1]
+		}
+	}
+	line := fmt.Sprintf("%s:%s|%s", name, strconv.FormatFloat(value, 'f', -1, 64), metricType)
+	if len(tags) > 0 {
+		line += "|#" + strings.Join(tags, ",")
+	}
+	return line
+}
+
+// send writes a single datagram, StatsD delivery is best-effort so failures are only logged.
+func (e *Exporter) send(packet string) {
+	_, err := e.conn.Write([]byte(packet))
+	if err != nil {
+		e.log.Warn().Err(err).Msg("could not send statsd metrics")
+	}
+}
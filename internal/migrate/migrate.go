@@ -0,0 +1,236 @@
+// Package migrate provides versioned schema migrations for SQL databases.
+//
+// Migrations are read from SQL files named <version>_<name>.up.sql along with optional <version>_<name>.down.sql
+// reverting them. Applied versions are recorded in the schema_migrations table, every migration is applied or
+// reverted within its own transaction together with its record, so a failed migration leaves no trace.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// createTableQuery creates the table recording applied migrations.
+const createTableQuery = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version    BIGINT    NOT NULL PRIMARY KEY,
+	name       TEXT      NOT NULL,
+	applied_at TIMESTAMP NOT NULL
+);`
+
+// Migration is a single schema change, an empty Down makes it irreversible.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Load reads migrations from the dir directory of fsys ordered by their versions.
+func Load(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+		base := strings.TrimSuffix(entry.Name(), ".sql")
+		direction := path.Ext(base)
+		if direction != ".up" && direction != ".down" {
+			return nil, fmt.Errorf("migration %s must end with .up.sql or .down.sql", entry.Name())
+		}
+		parts := strings.SplitN(strings.TrimSuffix(base, direction), "_", 2)
+		version, err := strconv.Atoi(parts[0])
+		if err != nil || version <= 0 || len(parts) != 2 || parts[1] == "" {
+			return nil, fmt.Errorf("migration %s must be named <version>_<name>%s.sql", entry.Name(), direction)
+		}
+		data, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: parts[1]}
+			byVersion[version] = migration
+		}
+		if migration.Name != parts[1] {
+			return nil, fmt.Errorf("migrations %s and %s share version %d", migration.Name, parts[1], version)
+		}
+		if direction == ".up" {
+			migration.Up = string(data)
+		} else {
+			migration.Down = string(data)
+		}
+	}
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up script", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// Migrator defines attributes of a struct available to its methods.
+type Migrator struct {
+	db         *sql.DB
+	log        *zerolog.Logger
+	migrations []Migration
+	// lock is executed first in every migration transaction to serialize concurrent migrators, it is
+	// database specific and may be empty
+	lock string
+}
+
+// NewMigrator initializes a migrator of db, lock is a statement taking a transaction-scoped lock.
+func NewMigrator(db *sql.DB, log *zerolog.Logger, migrations []Migration, lock string) *Migrator {
+	return &Migrator{
+		db:         db,
+		log:        log,
+		migrations: migrations,
+		lock:       lock,
+	}
+}
+
+// Up applies pending migrations in the order of their versions and returns the number of applied ones.
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	_, err := m.db.ExecContext(ctx, createTableQuery)
+	if err != nil {
+		return 0, err
+	}
+	applied := 0
+	for _, migration := range m.migrations {
+		done, err := m.apply(ctx, migration)
+		if err != nil {
+			return applied, fmt.Errorf("applying migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+		if done {
+			m.log.Info().Msg(fmt.Sprintf("migration %d_%s applied", migration.Version, migration.Name))
+			applied++
+		}
+	}
+	return applied, nil
+}
+
+// Down reverts up to steps latest applied migrations and returns the number of reverted ones.
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	_, err := m.db.ExecContext(ctx, createTableQuery)
+	if err != nil {
+		return 0, err
+	}
+	reverted := 0
+	for reverted < steps {
+		migration, err := m.revert(ctx)
+		if err != nil {
+			return reverted, err
+		}
+		if migration == nil {
+			break
+		}
+		m.log.Info().Msg(fmt.Sprintf("migration %d_%s reverted", migration.Version, migration.Name))
+		reverted++
+	}
+	return reverted, nil
+}
+
+// Version returns the latest applied migration version, it is zero if none were applied.
+func (m *Migrator) Version(ctx context.Context) (int, error) {
+	var version int
+	err := m.db.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&version)
+	if err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+// apply runs a migration unless it is already applied, it reports whether the migration was run.
+func (m *Migrator) apply(ctx context.Context, migration Migration) (bool, error) {
+	tx, err := m.begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	var exists bool
+	err = tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)", migration.Version).Scan(&exists)
+	if err != nil || exists {
+		return false, err
+	}
+	_, err = tx.ExecContext(ctx, migration.Up)
+	if err != nil {
+		return false, err
+	}
+	_, err = tx.ExecContext(ctx, "INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)", migration.Version, migration.Name, time.Now().UTC())
+	if err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// revert runs the down script of the latest applied migration, it returns nil if no migrations are applied.
+func (m *Migrator) revert(ctx context.Context) (*Migration, error) {
+	tx, err := m.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	var version int
+	err = tx.QueryRowContext(ctx, "SELECT version FROM schema_migrations ORDER BY version DESC LIMIT 1").Scan(&version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var migration *Migration
+	for i := range m.migrations {
+		if m.migrations[i].Version == version {
+			migration = &m.migrations[i]
+		}
+	}
+	if migration == nil {
+		return nil, fmt.Errorf("migration %d is unknown to this build", version)
+	}
+	if migration.Down == "" {
+		return nil, fmt.Errorf("migration %d_%s is irreversible", migration.Version, migration.Name)
+	}
+	_, err = tx.ExecContext(ctx, migration.Down)
+	if err != nil {
+		return nil, fmt.Errorf("reverting migration %d_%s: %w", migration.Version, migration.Name, err)
+	}
+	_, err = tx.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = $1", version)
+	if err != nil {
+		return nil, err
+	}
+	return migration, tx.Commit()
+}
+
+// begin starts a migration transaction holding the migration lock.
+func (m *Migrator) begin(ctx context.Context) (*sql.Tx, error) {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	if m.lock != "" {
+		_, err = tx.ExecContext(ctx, m.lock)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+	return tx, nil
+}
@@ -0,0 +1,123 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/danilovkiri/dk-go-gophermart/internal/service/broker/v1/broker (interfaces: AccrualProvider,QueueStore)
+//
+// Generated by this command:
+//
+//	mockgen -destination=broker.go -package=mocks github.com/danilovkiri/dk-go-gophermart/internal/service/broker/v1/broker AccrualProvider,QueueStore
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	modelqueue "github.com/danilovkiri/dk-go-gophermart/internal/models/modelqueue"
+	modelstorage "github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/modelstorage"
+	resty "github.com/go-resty/resty/v2"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockAccrualProvider is a mock of AccrualProvider interface.
+type MockAccrualProvider struct {
+	ctrl     *gomock.Controller
+	recorder *MockAccrualProviderMockRecorder
+}
+
+// MockAccrualProviderMockRecorder is the mock recorder for MockAccrualProvider.
+type MockAccrualProviderMockRecorder struct {
+	mock *MockAccrualProvider
+}
+
+// NewMockAccrualProvider creates a new mock instance.
+func NewMockAccrualProvider(ctrl *gomock.Controller) *MockAccrualProvider {
+	mock := &MockAccrualProvider{ctrl: ctrl}
+	mock.recorder = &MockAccrualProviderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAccrualProvider) EXPECT() *MockAccrualProviderMockRecorder {
+	return m.recorder
+}
+
+// GetAccrual mocks base method.
+func (m *MockAccrualProvider) GetAccrual(arg0 context.Context, arg1 int) (*resty.Response, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAccrual", arg0, arg1)
+	ret0, _ := ret[0].(*resty.Response)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAccrual indicates an expected call of GetAccrual.
+func (mr *MockAccrualProviderMockRecorder) GetAccrual(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccrual", reflect.TypeOf((*MockAccrualProvider)(nil).GetAccrual), arg0, arg1)
+}
+
+// MockQueueStore is a mock of QueueStore interface.
+type MockQueueStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockQueueStoreMockRecorder
+}
+
+// MockQueueStoreMockRecorder is the mock recorder for MockQueueStore.
+type MockQueueStoreMockRecorder struct {
+	mock *MockQueueStore
+}
+
+// NewMockQueueStore creates a new mock instance.
+func NewMockQueueStore(ctrl *gomock.Controller) *MockQueueStore {
+	mock := &MockQueueStore{ctrl: ctrl}
+	mock.recorder = &MockQueueStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockQueueStore) EXPECT() *MockQueueStoreMockRecorder {
+	return m.recorder
+}
+
+// SaveAccrualResponse mocks base method.
+func (m *MockQueueStore) SaveAccrualResponse(arg0 context.Context, arg1 modelstorage.AccrualResponseStorageEntry) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveAccrualResponse", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveAccrualResponse indicates an expected call of SaveAccrualResponse.
+func (mr *MockQueueStoreMockRecorder) SaveAccrualResponse(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveAccrualResponse", reflect.TypeOf((*MockQueueStore)(nil).SaveAccrualResponse), arg0, arg1)
+}
+
+// SaveRetryState mocks base method.
+func (m *MockQueueStore) SaveRetryState(arg0 context.Context, arg1 modelqueue.OrderQueueEntry) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveRetryState", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveRetryState indicates an expected call of SaveRetryState.
+func (mr *MockQueueStoreMockRecorder) SaveRetryState(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveRetryState", reflect.TypeOf((*MockQueueStore)(nil).SaveRetryState), arg0, arg1)
+}
+
+// TakeResolved mocks base method.
+func (m *MockQueueStore) TakeResolved(arg0 int) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TakeResolved", arg0)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// TakeResolved indicates an expected call of TakeResolved.
+func (mr *MockQueueStoreMockRecorder) TakeResolved(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TakeResolved", reflect.TypeOf((*MockQueueStore)(nil).TakeResolved), arg0)
+}
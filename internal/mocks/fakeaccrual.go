@@ -0,0 +1,160 @@
+package mocks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/models/modeldto"
+	"github.com/go-resty/resty/v2"
+)
+
+// Step defines a single scripted reply of FakeAccrual.
+type Step struct {
+	// StatusCode is the HTTP status of the reply, 200 if zero
+	StatusCode int
+	// Status and Accrual make up the body of a 200 reply
+	Status  string
+	Accrual float64
+	// RetryAfter is sent as the Retry-After header of a 429 reply
+	RetryAfter int
+	// Body replaces the generated body if set, e.g. to reply with malformed data
+	Body string
+	// Err fails the call as a transport error would
+	Err error
+}
+
+// Reply returns a step replying with an order status and accrual.
+func Reply(status string, accrual float64) Step {
+	return Step{Status: status, Accrual: accrual}
+}
+
+// TooManyRequests returns a step rate limiting the caller for the given number of seconds.
+func TooManyRequests(retryAfter int) Step {
+	return Step{StatusCode: http.StatusTooManyRequests, RetryAfter: retryAfter}
+}
+
+// NotRegistered returns a step replying that the order is unknown to the Accrual Service.
+func NotRegistered() Step {
+	return Step{StatusCode: http.StatusNoContent}
+}
+
+// Fail returns a step failing the call with err.
+func Fail(err error) Step {
+	return Step{Err: err}
+}
+
+// callKey is the request context key of the scripted call being served.
+type callKey struct{}
+
+// scriptedCall defines the step consumed by a call.
+type scriptedCall struct {
+	orderNumber int
+	step        Step
+}
+
+// FakeAccrual is an in-process Accrual Service implementing broker.AccrualProvider, it replays scripted steps
+// per order. Each call consumes the next step of the order, the last step is repeated once the script is exhausted
+// and orders without a script are not registered. Replies are built by resty over an in-memory transport, so they
+// are indistinguishable from real ones to the broker.
+type FakeAccrual struct {
+	client  *resty.Client
+	mu      sync.Mutex
+	scripts map[int][]Step
+	calls   map[int]int
+}
+
+// NewFakeAccrual initializes a fake Accrual Service without scripts.
+func NewFakeAccrual() *FakeAccrual {
+	fake := &FakeAccrual{
+		scripts: make(map[int][]Step),
+		calls:   make(map[int]int),
+	}
+	fake.client = resty.New().SetTransport(roundTripper(fake.serve))
+	return fake
+}
+
+// Script sets the steps replayed for an order replacing the previous ones.
+func (f *FakeAccrual) Script(orderNumber int, steps ...Step) *FakeAccrual {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.scripts[orderNumber] = steps
+	return f
+}
+
+// Calls returns the number of calls made for an order.
+func (f *FakeAccrual) Calls(orderNumber int) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[orderNumber]
+}
+
+// GetAccrual replies with the next scripted step of the order.
+func (f *FakeAccrual) GetAccrual(ctx context.Context, orderNumber int) (*resty.Response, error) {
+	step := f.next(orderNumber)
+	if step.Err != nil {
+		return nil, step.Err
+	}
+	ctx = context.WithValue(ctx, callKey{}, scriptedCall{orderNumber: orderNumber, step: step})
+	return f.client.R().SetContext(ctx).Get("http://accrual.fake/api/orders/" + strconv.Itoa(orderNumber))
+}
+
+// next consumes the next step of an order.
+func (f *FakeAccrual) next(orderNumber int) Step {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	call := f.calls[orderNumber]
+	f.calls[orderNumber]++
+	steps := f.scripts[orderNumber]
+	if len(steps) == 0 {
+		return NotRegistered()
+	}
+	if call >= len(steps) {
+		return steps[len(steps)-1]
+	}
+	return steps[call]
+}
+
+// serve renders the step carried by the request context into an HTTP response.
+func (f *FakeAccrual) serve(r *http.Request) (*http.Response, error) {
+	call := r.Context().Value(callKey{}).(scriptedCall)
+	statusCode := call.step.StatusCode
+	if statusCode == 0 {
+		statusCode = http.StatusOK
+	}
+	header := make(http.Header)
+	body := []byte(call.step.Body)
+	switch {
+	case statusCode == http.StatusTooManyRequests:
+		header.Set("Retry-After", strconv.Itoa(call.step.RetryAfter))
+	case statusCode == http.StatusOK && call.step.Body == "":
+		var err error
+		body, err = json.Marshal(modeldto.AccrualResponse{OrderNumber: strconv.Itoa(call.orderNumber), OrderStatus: call.step.Status, Accrual: call.step.Accrual})
+		if err != nil {
+			return nil, err
+		}
+		header.Set("Content-Type", "application/json")
+	}
+	return &http.Response{
+		StatusCode: statusCode,
+		Status:     strconv.Itoa(statusCode) + " " + http.StatusText(statusCode),
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     header,
+		Body:       io.NopCloser(bytes.NewReader(body)),
+		Request:    r,
+	}, nil
+}
+
+// roundTripper adapts a function to http.RoundTripper.
+type roundTripper func(r *http.Request) (*http.Response, error)
+
+// RoundTrip implements http.RoundTripper.
+func (rt roundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	return rt(r)
+}
@@ -0,0 +1,9 @@
+// Package mocks provides gomock mocks of the core interfaces for unit tests, they are generated by mockgen
+// from go.uber.org/mock and must not be edited by hand. FakeAccrual is a hand-written scriptable Accrual Service
+// for tests going through several polls of an order.
+package mocks
+
+//go:generate mockgen -destination=storage.go -package=mocks github.com/danilovkiri/dk-go-gophermart/internal/storage/v1 Storage
+//go:generate mockgen -destination=processor.go -package=mocks github.com/danilovkiri/dk-go-gophermart/internal/service/processor/v1 Processor
+//go:generate mockgen -destination=secretary.go -package=mocks github.com/danilovkiri/dk-go-gophermart/internal/service/secretary/v1 Secretary
+//go:generate mockgen -destination=broker.go -package=mocks github.com/danilovkiri/dk-go-gophermart/internal/service/broker/v1/broker AccrualProvider,QueueStore
@@ -0,0 +1,692 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/danilovkiri/dk-go-gophermart/internal/service/processor/v1 (interfaces: Processor)
+//
+// Generated by this command:
+//
+//	mockgen -destination=processor.go -package=mocks github.com/danilovkiri/dk-go-gophermart/internal/service/processor/v1 Processor
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	modeldto "github.com/danilovkiri/dk-go-gophermart/internal/models/modeldto"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockProcessor is a mock of Processor interface.
+type MockProcessor struct {
+	ctrl     *gomock.Controller
+	recorder *MockProcessorMockRecorder
+}
+
+// MockProcessorMockRecorder is the mock recorder for MockProcessor.
+type MockProcessorMockRecorder struct {
+	mock *MockProcessor
+}
+
+// NewMockProcessor creates a new mock instance.
+func NewMockProcessor(ctrl *gomock.Controller) *MockProcessor {
+	mock := &MockProcessor{ctrl: ctrl}
+	mock.recorder = &MockProcessorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockProcessor) EXPECT() *MockProcessorMockRecorder {
+	return m.recorder
+}
+
+// AcceptAccrual mocks base method.
+func (m *MockProcessor) AcceptAccrual(arg0 context.Context, arg1 modeldto.AccrualResponse) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AcceptAccrual", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AcceptAccrual indicates an expected call of AcceptAccrual.
+func (mr *MockProcessorMockRecorder) AcceptAccrual(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AcceptAccrual", reflect.TypeOf((*MockProcessor)(nil).AcceptAccrual), arg0, arg1)
+}
+
+// AddNewOrder mocks base method.
+func (m *MockProcessor) AddNewOrder(arg0 context.Context, arg1 string, arg2 modeldto.NewOrder) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddNewOrder", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddNewOrder indicates an expected call of AddNewOrder.
+func (mr *MockProcessorMockRecorder) AddNewOrder(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddNewOrder", reflect.TypeOf((*MockProcessor)(nil).AddNewOrder), arg0, arg1, arg2)
+}
+
+// AddNewUser mocks base method.
+func (m *MockProcessor) AddNewUser(arg0 context.Context, arg1 modeldto.User, arg2 modeldto.ClientInfo) (*modeldto.Tokens, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddNewUser", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*modeldto.Tokens)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddNewUser indicates an expected call of AddNewUser.
+func (mr *MockProcessorMockRecorder) AddNewUser(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddNewUser", reflect.TypeOf((*MockProcessor)(nil).AddNewUser), arg0, arg1, arg2)
+}
+
+// AddNewWithdrawal mocks base method.
+func (m *MockProcessor) AddNewWithdrawal(arg0 context.Context, arg1 string, arg2 modeldto.NewOrderWithdrawal, arg3 string) (*modeldto.Withdrawal, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddNewWithdrawal", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(*modeldto.Withdrawal)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddNewWithdrawal indicates an expected call of AddNewWithdrawal.
+func (mr *MockProcessorMockRecorder) AddNewWithdrawal(arg0, arg1, arg2, arg3 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddNewWithdrawal", reflect.TypeOf((*MockProcessor)(nil).AddNewWithdrawal), arg0, arg1, arg2, arg3)
+}
+
+// AdjustBalance mocks base method.
+func (m *MockProcessor) AdjustBalance(arg0 context.Context, arg1 string, arg2 modeldto.BalanceAdjustmentRequest) (*modeldto.BalanceAdjustment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AdjustBalance", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*modeldto.BalanceAdjustment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AdjustBalance indicates an expected call of AdjustBalance.
+func (mr *MockProcessorMockRecorder) AdjustBalance(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AdjustBalance", reflect.TypeOf((*MockProcessor)(nil).AdjustBalance), arg0, arg1, arg2)
+}
+
+// AdminRecheckOrder mocks base method.
+func (m *MockProcessor) AdminRecheckOrder(arg0 context.Context, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AdminRecheckOrder", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AdminRecheckOrder indicates an expected call of AdminRecheckOrder.
+func (mr *MockProcessorMockRecorder) AdminRecheckOrder(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AdminRecheckOrder", reflect.TypeOf((*MockProcessor)(nil).AdminRecheckOrder), arg0, arg1)
+}
+
+// ApproveOrder mocks base method.
+func (m *MockProcessor) ApproveOrder(arg0 context.Context, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApproveOrder", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ApproveOrder indicates an expected call of ApproveOrder.
+func (mr *MockProcessorMockRecorder) ApproveOrder(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApproveOrder", reflect.TypeOf((*MockProcessor)(nil).ApproveOrder), arg0, arg1)
+}
+
+// CaptureHold mocks base method.
+func (m *MockProcessor) CaptureHold(arg0 context.Context, arg1 string, arg2 uint) (*modeldto.BalanceHold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CaptureHold", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*modeldto.BalanceHold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CaptureHold indicates an expected call of CaptureHold.
+func (mr *MockProcessorMockRecorder) CaptureHold(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CaptureHold", reflect.TypeOf((*MockProcessor)(nil).CaptureHold), arg0, arg1, arg2)
+}
+
+// ConvertAmount mocks base method.
+func (m *MockProcessor) ConvertAmount(arg0 string, arg1 float64) (*modeldto.ConvertedAmount, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConvertAmount", arg0, arg1)
+	ret0, _ := ret[0].(*modeldto.ConvertedAmount)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConvertAmount indicates an expected call of ConvertAmount.
+func (mr *MockProcessorMockRecorder) ConvertAmount(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConvertAmount", reflect.TypeOf((*MockProcessor)(nil).ConvertAmount), arg0, arg1)
+}
+
+// CreateTelegramLinkCode mocks base method.
+func (m *MockProcessor) CreateTelegramLinkCode(arg0 context.Context, arg1 string) (*modeldto.TelegramLink, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateTelegramLinkCode", arg0, arg1)
+	ret0, _ := ret[0].(*modeldto.TelegramLink)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateTelegramLinkCode indicates an expected call of CreateTelegramLinkCode.
+func (mr *MockProcessorMockRecorder) CreateTelegramLinkCode(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateTelegramLinkCode", reflect.TypeOf((*MockProcessor)(nil).CreateTelegramLinkCode), arg0, arg1)
+}
+
+// EvaluateCashback mocks base method.
+func (m *MockProcessor) EvaluateCashback(arg0 modeldto.CashbackEvaluationRequest) *modeldto.CashbackEvaluation {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EvaluateCashback", arg0)
+	ret0, _ := ret[0].(*modeldto.CashbackEvaluation)
+	return ret0
+}
+
+// EvaluateCashback indicates an expected call of EvaluateCashback.
+func (mr *MockProcessorMockRecorder) EvaluateCashback(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EvaluateCashback", reflect.TypeOf((*MockProcessor)(nil).EvaluateCashback), arg0)
+}
+
+// GetAdminOrder mocks base method.
+func (m *MockProcessor) GetAdminOrder(arg0 context.Context, arg1 string) (*modeldto.AdminOrder, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAdminOrder", arg0, arg1)
+	ret0, _ := ret[0].(*modeldto.AdminOrder)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAdminOrder indicates an expected call of GetAdminOrder.
+func (mr *MockProcessorMockRecorder) GetAdminOrder(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAdminOrder", reflect.TypeOf((*MockProcessor)(nil).GetAdminOrder), arg0, arg1)
+}
+
+// GetAlertThresholds mocks base method.
+func (m *MockProcessor) GetAlertThresholds(arg0 context.Context, arg1 string) (*modeldto.AlertThresholds, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAlertThresholds", arg0, arg1)
+	ret0, _ := ret[0].(*modeldto.AlertThresholds)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAlertThresholds indicates an expected call of GetAlertThresholds.
+func (mr *MockProcessorMockRecorder) GetAlertThresholds(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAlertThresholds", reflect.TypeOf((*MockProcessor)(nil).GetAlertThresholds), arg0, arg1)
+}
+
+// GetBalance mocks base method.
+func (m *MockProcessor) GetBalance(arg0 context.Context, arg1 string) (*modeldto.Balance, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBalance", arg0, arg1)
+	ret0, _ := ret[0].(*modeldto.Balance)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBalance indicates an expected call of GetBalance.
+func (mr *MockProcessorMockRecorder) GetBalance(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBalance", reflect.TypeOf((*MockProcessor)(nil).GetBalance), arg0, arg1)
+}
+
+// GetConvertedBalance mocks base method.
+func (m *MockProcessor) GetConvertedBalance(arg0 context.Context, arg1, arg2 string) (*modeldto.ConvertedBalance, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetConvertedBalance", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*modeldto.ConvertedBalance)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetConvertedBalance indicates an expected call of GetConvertedBalance.
+func (mr *MockProcessorMockRecorder) GetConvertedBalance(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetConvertedBalance", reflect.TypeOf((*MockProcessor)(nil).GetConvertedBalance), arg0, arg1, arg2)
+}
+
+// GetHolds mocks base method.
+func (m *MockProcessor) GetHolds(arg0 context.Context, arg1 string) ([]modeldto.BalanceHold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetHolds", arg0, arg1)
+	ret0, _ := ret[0].([]modeldto.BalanceHold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetHolds indicates an expected call of GetHolds.
+func (mr *MockProcessorMockRecorder) GetHolds(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHolds", reflect.TypeOf((*MockProcessor)(nil).GetHolds), arg0, arg1)
+}
+
+// GetLoginThrottles mocks base method.
+func (m *MockProcessor) GetLoginThrottles() []modeldto.LoginThrottle {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLoginThrottles")
+	ret0, _ := ret[0].([]modeldto.LoginThrottle)
+	return ret0
+}
+
+// GetLoginThrottles indicates an expected call of GetLoginThrottles.
+func (mr *MockProcessorMockRecorder) GetLoginThrottles() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLoginThrottles", reflect.TypeOf((*MockProcessor)(nil).GetLoginThrottles))
+}
+
+// GetOrder mocks base method.
+func (m *MockProcessor) GetOrder(arg0 context.Context, arg1, arg2 string) (*modeldto.Order, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOrder", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*modeldto.Order)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOrder indicates an expected call of GetOrder.
+func (mr *MockProcessorMockRecorder) GetOrder(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrder", reflect.TypeOf((*MockProcessor)(nil).GetOrder), arg0, arg1, arg2)
+}
+
+// GetOrderHistory mocks base method.
+func (m *MockProcessor) GetOrderHistory(arg0 context.Context, arg1, arg2 string) (*modeldto.OrderHistory, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOrderHistory", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*modeldto.OrderHistory)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOrderHistory indicates an expected call of GetOrderHistory.
+func (mr *MockProcessorMockRecorder) GetOrderHistory(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrderHistory", reflect.TypeOf((*MockProcessor)(nil).GetOrderHistory), arg0, arg1, arg2)
+}
+
+// GetOrders mocks base method.
+func (m *MockProcessor) GetOrders(arg0 context.Context, arg1 string, arg2 modeldto.Sort) ([]modeldto.Order, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOrders", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]modeldto.Order)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOrders indicates an expected call of GetOrders.
+func (mr *MockProcessorMockRecorder) GetOrders(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrders", reflect.TypeOf((*MockProcessor)(nil).GetOrders), arg0, arg1, arg2)
+}
+
+// GetReconciliationReport mocks base method.
+func (m *MockProcessor) GetReconciliationReport(arg0 context.Context) (*modeldto.ReconciliationReport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetReconciliationReport", arg0)
+	ret0, _ := ret[0].(*modeldto.ReconciliationReport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetReconciliationReport indicates an expected call of GetReconciliationReport.
+func (mr *MockProcessorMockRecorder) GetReconciliationReport(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetReconciliationReport", reflect.TypeOf((*MockProcessor)(nil).GetReconciliationReport), arg0)
+}
+
+// GetSessions mocks base method.
+func (m *MockProcessor) GetSessions(arg0 context.Context, arg1 string) ([]modeldto.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSessions", arg0, arg1)
+	ret0, _ := ret[0].([]modeldto.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSessions indicates an expected call of GetSessions.
+func (mr *MockProcessorMockRecorder) GetSessions(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSessions", reflect.TypeOf((*MockProcessor)(nil).GetSessions), arg0, arg1)
+}
+
+// GetSummary mocks base method.
+func (m *MockProcessor) GetSummary(arg0 context.Context, arg1 []time.Duration) (*modeldto.AdminSummary, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSummary", arg0, arg1)
+	ret0, _ := ret[0].(*modeldto.AdminSummary)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSummary indicates an expected call of GetSummary.
+func (mr *MockProcessorMockRecorder) GetSummary(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSummary", reflect.TypeOf((*MockProcessor)(nil).GetSummary), arg0, arg1)
+}
+
+// GetSuspendedOrders mocks base method.
+func (m *MockProcessor) GetSuspendedOrders(arg0 context.Context) ([]modeldto.SuspendedOrder, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSuspendedOrders", arg0)
+	ret0, _ := ret[0].([]modeldto.SuspendedOrder)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSuspendedOrders indicates an expected call of GetSuspendedOrders.
+func (mr *MockProcessorMockRecorder) GetSuspendedOrders(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSuspendedOrders", reflect.TypeOf((*MockProcessor)(nil).GetSuspendedOrders), arg0)
+}
+
+// GetTransactions mocks base method.
+func (m *MockProcessor) GetTransactions(arg0 context.Context, arg1 string) ([]modeldto.Transaction, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTransactions", arg0, arg1)
+	ret0, _ := ret[0].([]modeldto.Transaction)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTransactions indicates an expected call of GetTransactions.
+func (mr *MockProcessorMockRecorder) GetTransactions(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTransactions", reflect.TypeOf((*MockProcessor)(nil).GetTransactions), arg0, arg1)
+}
+
+// GetUserStats mocks base method.
+func (m *MockProcessor) GetUserStats(arg0 context.Context, arg1 string) (*modeldto.UserStats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserStats", arg0, arg1)
+	ret0, _ := ret[0].(*modeldto.UserStats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserStats indicates an expected call of GetUserStats.
+func (mr *MockProcessorMockRecorder) GetUserStats(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserStats", reflect.TypeOf((*MockProcessor)(nil).GetUserStats), arg0, arg1)
+}
+
+// GetWithdrawal mocks base method.
+func (m *MockProcessor) GetWithdrawal(arg0 context.Context, arg1, arg2 string) (*modeldto.Withdrawal, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWithdrawal", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*modeldto.Withdrawal)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetWithdrawal indicates an expected call of GetWithdrawal.
+func (mr *MockProcessorMockRecorder) GetWithdrawal(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWithdrawal", reflect.TypeOf((*MockProcessor)(nil).GetWithdrawal), arg0, arg1, arg2)
+}
+
+// GetWithdrawals mocks base method.
+func (m *MockProcessor) GetWithdrawals(arg0 context.Context, arg1 string, arg2 modeldto.Sort) ([]modeldto.Withdrawal, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWithdrawals", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]modeldto.Withdrawal)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetWithdrawals indicates an expected call of GetWithdrawals.
+func (mr *MockProcessorMockRecorder) GetWithdrawals(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWithdrawals", reflect.TypeOf((*MockProcessor)(nil).GetWithdrawals), arg0, arg1, arg2)
+}
+
+// IssuePartnerToken mocks base method.
+func (m *MockProcessor) IssuePartnerToken(arg0 context.Context, arg1 modeldto.PartnerTokenRequest) (*modeldto.PartnerToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IssuePartnerToken", arg0, arg1)
+	ret0, _ := ret[0].(*modeldto.PartnerToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IssuePartnerToken indicates an expected call of IssuePartnerToken.
+func (mr *MockProcessorMockRecorder) IssuePartnerToken(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IssuePartnerToken", reflect.TypeOf((*MockProcessor)(nil).IssuePartnerToken), arg0, arg1)
+}
+
+// LoginUser mocks base method.
+func (m *MockProcessor) LoginUser(arg0 context.Context, arg1 modeldto.User, arg2 modeldto.ClientInfo) (*modeldto.Tokens, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoginUser", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*modeldto.Tokens)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LoginUser indicates an expected call of LoginUser.
+func (mr *MockProcessorMockRecorder) LoginUser(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoginUser", reflect.TypeOf((*MockProcessor)(nil).LoginUser), arg0, arg1, arg2)
+}
+
+// Logout mocks base method.
+func (m *MockProcessor) Logout(arg0 context.Context, arg1, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Logout", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Logout indicates an expected call of Logout.
+func (mr *MockProcessorMockRecorder) Logout(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Logout", reflect.TypeOf((*MockProcessor)(nil).Logout), arg0, arg1, arg2)
+}
+
+// MergeAccounts mocks base method.
+func (m *MockProcessor) MergeAccounts(arg0 context.Context, arg1 modeldto.AccountMergeRequest) (*modeldto.AccountMerge, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MergeAccounts", arg0, arg1)
+	ret0, _ := ret[0].(*modeldto.AccountMerge)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MergeAccounts indicates an expected call of MergeAccounts.
+func (mr *MockProcessorMockRecorder) MergeAccounts(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MergeAccounts", reflect.TypeOf((*MockProcessor)(nil).MergeAccounts), arg0, arg1)
+}
+
+// RecalculateBalances mocks base method.
+func (m *MockProcessor) RecalculateBalances(arg0 context.Context, arg1 bool) (*modeldto.ReconciliationReport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecalculateBalances", arg0, arg1)
+	ret0, _ := ret[0].(*modeldto.ReconciliationReport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RecalculateBalances indicates an expected call of RecalculateBalances.
+func (mr *MockProcessorMockRecorder) RecalculateBalances(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecalculateBalances", reflect.TypeOf((*MockProcessor)(nil).RecalculateBalances), arg0, arg1)
+}
+
+// RecheckOrder mocks base method.
+func (m *MockProcessor) RecheckOrder(arg0 context.Context, arg1, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecheckOrder", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecheckOrder indicates an expected call of RecheckOrder.
+func (mr *MockProcessorMockRecorder) RecheckOrder(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecheckOrder", reflect.TypeOf((*MockProcessor)(nil).RecheckOrder), arg0, arg1, arg2)
+}
+
+// RefreshTokens mocks base method.
+func (m *MockProcessor) RefreshTokens(arg0 context.Context, arg1 string) (*modeldto.Tokens, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RefreshTokens", arg0, arg1)
+	ret0, _ := ret[0].(*modeldto.Tokens)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RefreshTokens indicates an expected call of RefreshTokens.
+func (mr *MockProcessorMockRecorder) RefreshTokens(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RefreshTokens", reflect.TypeOf((*MockProcessor)(nil).RefreshTokens), arg0, arg1)
+}
+
+// RejectOrder mocks base method.
+func (m *MockProcessor) RejectOrder(arg0 context.Context, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RejectOrder", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RejectOrder indicates an expected call of RejectOrder.
+func (mr *MockProcessorMockRecorder) RejectOrder(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RejectOrder", reflect.TypeOf((*MockProcessor)(nil).RejectOrder), arg0, arg1)
+}
+
+// ReleaseHold mocks base method.
+func (m *MockProcessor) ReleaseHold(arg0 context.Context, arg1 string, arg2 uint) (*modeldto.BalanceHold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseHold", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*modeldto.BalanceHold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReleaseHold indicates an expected call of ReleaseHold.
+func (mr *MockProcessorMockRecorder) ReleaseHold(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseHold", reflect.TypeOf((*MockProcessor)(nil).ReleaseHold), arg0, arg1, arg2)
+}
+
+// RequeueOrders mocks base method.
+func (m *MockProcessor) RequeueOrders(arg0 context.Context, arg1 modeldto.RequeueRequest) (*modeldto.RequeueReport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RequeueOrders", arg0, arg1)
+	ret0, _ := ret[0].(*modeldto.RequeueReport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RequeueOrders indicates an expected call of RequeueOrders.
+func (mr *MockProcessorMockRecorder) RequeueOrders(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RequeueOrders", reflect.TypeOf((*MockProcessor)(nil).RequeueOrders), arg0, arg1)
+}
+
+// ReserveBalance mocks base method.
+func (m *MockProcessor) ReserveBalance(arg0 context.Context, arg1 string, arg2 modeldto.NewBalanceHold) (*modeldto.BalanceHold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReserveBalance", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*modeldto.BalanceHold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReserveBalance indicates an expected call of ReserveBalance.
+func (mr *MockProcessorMockRecorder) ReserveBalance(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReserveBalance", reflect.TypeOf((*MockProcessor)(nil).ReserveBalance), arg0, arg1, arg2)
+}
+
+// ResetLoginThrottle mocks base method.
+func (m *MockProcessor) ResetLoginThrottle(arg0, arg1 string) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResetLoginThrottle", arg0, arg1)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// ResetLoginThrottle indicates an expected call of ResetLoginThrottle.
+func (mr *MockProcessorMockRecorder) ResetLoginThrottle(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetLoginThrottle", reflect.TypeOf((*MockProcessor)(nil).ResetLoginThrottle), arg0, arg1)
+}
+
+// SearchUsers mocks base method.
+func (m *MockProcessor) SearchUsers(arg0 context.Context, arg1 string, arg2, arg3 int) (*modeldto.UserSearchResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SearchUsers", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(*modeldto.UserSearchResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SearchUsers indicates an expected call of SearchUsers.
+func (mr *MockProcessorMockRecorder) SearchUsers(arg0, arg1, arg2, arg3 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchUsers", reflect.TypeOf((*MockProcessor)(nil).SearchUsers), arg0, arg1, arg2, arg3)
+}
+
+// SetAlertThresholds mocks base method.
+func (m *MockProcessor) SetAlertThresholds(arg0 context.Context, arg1 string, arg2 modeldto.AlertThresholds) (*modeldto.AlertThresholds, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetAlertThresholds", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*modeldto.AlertThresholds)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetAlertThresholds indicates an expected call of SetAlertThresholds.
+func (mr *MockProcessorMockRecorder) SetAlertThresholds(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetAlertThresholds", reflect.TypeOf((*MockProcessor)(nil).SetAlertThresholds), arg0, arg1, arg2)
+}
+
+// TransferOrder mocks base method.
+func (m *MockProcessor) TransferOrder(arg0 context.Context, arg1 string, arg2 modeldto.OrderTransferRequest) (*modeldto.OrderTransfer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TransferOrder", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*modeldto.OrderTransfer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TransferOrder indicates an expected call of TransferOrder.
+func (mr *MockProcessorMockRecorder) TransferOrder(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TransferOrder", reflect.TypeOf((*MockProcessor)(nil).TransferOrder), arg0, arg1, arg2)
+}
+
+// UpdateProfile mocks base method.
+func (m *MockProcessor) UpdateProfile(arg0 context.Context, arg1 string, arg2 modeldto.ProfileUpdate) (*modeldto.Profile, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateProfile", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*modeldto.Profile)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateProfile indicates an expected call of UpdateProfile.
+func (mr *MockProcessorMockRecorder) UpdateProfile(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateProfile", reflect.TypeOf((*MockProcessor)(nil).UpdateProfile), arg0, arg1, arg2)
+}
@@ -0,0 +1,219 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/danilovkiri/dk-go-gophermart/internal/service/secretary/v1 (interfaces: Secretary)
+//
+// Generated by this command:
+//
+//	mockgen -destination=secretary.go -package=mocks github.com/danilovkiri/dk-go-gophermart/internal/service/secretary/v1 Secretary
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	reflect "reflect"
+	time "time"
+
+	modelclaims "github.com/danilovkiri/dk-go-gophermart/internal/service/secretary/v1/modelclaims"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockSecretary is a mock of Secretary interface.
+type MockSecretary struct {
+	ctrl     *gomock.Controller
+	recorder *MockSecretaryMockRecorder
+}
+
+// MockSecretaryMockRecorder is the mock recorder for MockSecretary.
+type MockSecretaryMockRecorder struct {
+	mock *MockSecretary
+}
+
+// NewMockSecretary creates a new mock instance.
+func NewMockSecretary(ctrl *gomock.Controller) *MockSecretary {
+	mock := &MockSecretary{ctrl: ctrl}
+	mock.recorder = &MockSecretaryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSecretary) EXPECT() *MockSecretaryMockRecorder {
+	return m.recorder
+}
+
+// Current mocks base method.
+func (m *MockSecretary) Current(arg0 string) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Current", arg0)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// Current indicates an expected call of Current.
+func (mr *MockSecretaryMockRecorder) Current(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Current", reflect.TypeOf((*MockSecretary)(nil).Current), arg0)
+}
+
+// Decode mocks base method.
+func (m *MockSecretary) Decode(arg0 string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Decode", arg0)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Decode indicates an expected call of Decode.
+func (mr *MockSecretaryMockRecorder) Decode(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Decode", reflect.TypeOf((*MockSecretary)(nil).Decode), arg0)
+}
+
+// Encode mocks base method.
+func (m *MockSecretary) Encode(arg0 string) string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Encode", arg0)
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// Encode indicates an expected call of Encode.
+func (mr *MockSecretaryMockRecorder) Encode(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Encode", reflect.TypeOf((*MockSecretary)(nil).Encode), arg0)
+}
+
+// EncodeWithKey mocks base method.
+func (m *MockSecretary) EncodeWithKey(arg0, arg1 string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EncodeWithKey", arg0, arg1)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EncodeWithKey indicates an expected call of EncodeWithKey.
+func (mr *MockSecretaryMockRecorder) EncodeWithKey(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EncodeWithKey", reflect.TypeOf((*MockSecretary)(nil).EncodeWithKey), arg0, arg1)
+}
+
+// GetScopedToken mocks base method.
+func (m *MockSecretary) GetScopedToken(arg0, arg1, arg2 string, arg3 []string, arg4 time.Duration) (string, time.Time, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetScopedToken", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(time.Time)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetScopedToken indicates an expected call of GetScopedToken.
+func (mr *MockSecretaryMockRecorder) GetScopedToken(arg0, arg1, arg2, arg3, arg4 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetScopedToken", reflect.TypeOf((*MockSecretary)(nil).GetScopedToken), arg0, arg1, arg2, arg3, arg4)
+}
+
+// GetTokenForUser mocks base method.
+func (m *MockSecretary) GetTokenForUser(arg0, arg1, arg2 string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTokenForUser", arg0, arg1, arg2)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTokenForUser indicates an expected call of GetTokenForUser.
+func (mr *MockSecretaryMockRecorder) GetTokenForUser(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTokenForUser", reflect.TypeOf((*MockSecretary)(nil).GetTokenForUser), arg0, arg1, arg2)
+}
+
+// KeyIDs mocks base method.
+func (m *MockSecretary) KeyIDs() []string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "KeyIDs")
+	ret0, _ := ret[0].([]string)
+	return ret0
+}
+
+// KeyIDs indicates an expected call of KeyIDs.
+func (mr *MockSecretaryMockRecorder) KeyIDs() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "KeyIDs", reflect.TypeOf((*MockSecretary)(nil).KeyIDs))
+}
+
+// LoginHash mocks base method.
+func (m *MockSecretary) LoginHash(arg0 string) string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoginHash", arg0)
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// LoginHash indicates an expected call of LoginHash.
+func (mr *MockSecretaryMockRecorder) LoginHash(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoginHash", reflect.TypeOf((*MockSecretary)(nil).LoginHash), arg0)
+}
+
+// NewRefreshToken mocks base method.
+func (m *MockSecretary) NewRefreshToken() (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NewRefreshToken")
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// NewRefreshToken indicates an expected call of NewRefreshToken.
+func (mr *MockSecretaryMockRecorder) NewRefreshToken() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewRefreshToken", reflect.TypeOf((*MockSecretary)(nil).NewRefreshToken))
+}
+
+// NewToken mocks base method.
+func (m *MockSecretary) NewToken(arg0, arg1 string) (string, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NewToken", arg0, arg1)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// NewToken indicates an expected call of NewToken.
+func (mr *MockSecretaryMockRecorder) NewToken(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewToken", reflect.TypeOf((*MockSecretary)(nil).NewToken), arg0, arg1)
+}
+
+// Seal mocks base method.
+func (m *MockSecretary) Seal(arg0 string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Seal", arg0)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Seal indicates an expected call of Seal.
+func (mr *MockSecretaryMockRecorder) Seal(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Seal", reflect.TypeOf((*MockSecretary)(nil).Seal), arg0)
+}
+
+// ValidateClaims mocks base method.
+func (m *MockSecretary) ValidateClaims(arg0 string) (*modelclaims.MyCustomClaims, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ValidateClaims", arg0)
+	ret0, _ := ret[0].(*modelclaims.MyCustomClaims)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ValidateClaims indicates an expected call of ValidateClaims.
+func (mr *MockSecretaryMockRecorder) ValidateClaims(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidateClaims", reflect.TypeOf((*MockSecretary)(nil).ValidateClaims), arg0)
+}
@@ -0,0 +1,848 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/danilovkiri/dk-go-gophermart/internal/storage/v1 (interfaces: Storage)
+//
+// Generated by this command:
+//
+//	mockgen -destination=storage.go -package=mocks github.com/danilovkiri/dk-go-gophermart/internal/storage/v1 Storage
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	modeldto "github.com/danilovkiri/dk-go-gophermart/internal/models/modeldto"
+	modelqueue "github.com/danilovkiri/dk-go-gophermart/internal/models/modelqueue"
+	modelstorage "github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/modelstorage"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockStorage is a mock of Storage interface.
+type MockStorage struct {
+	ctrl     *gomock.Controller
+	recorder *MockStorageMockRecorder
+}
+
+// MockStorageMockRecorder is the mock recorder for MockStorage.
+type MockStorageMockRecorder struct {
+	mock *MockStorage
+}
+
+// NewMockStorage creates a new mock instance.
+func NewMockStorage(ctrl *gomock.Controller) *MockStorage {
+	mock := &MockStorage{ctrl: ctrl}
+	mock.recorder = &MockStorageMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockStorage) EXPECT() *MockStorageMockRecorder {
+	return m.recorder
+}
+
+// AddAdjustment mocks base method.
+func (m *MockStorage) AddAdjustment(arg0 context.Context, arg1 string, arg2 modeldto.BalanceAdjustmentRequest) (*modelstorage.BalanceEventStorageEntry, float64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddAdjustment", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*modelstorage.BalanceEventStorageEntry)
+	ret1, _ := ret[1].(float64)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// AddAdjustment indicates an expected call of AddAdjustment.
+func (mr *MockStorageMockRecorder) AddAdjustment(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddAdjustment", reflect.TypeOf((*MockStorage)(nil).AddAdjustment), arg0, arg1, arg2)
+}
+
+// AddHold mocks base method.
+func (m *MockStorage) AddHold(arg0 context.Context, arg1 string, arg2 int, arg3 float64) (*modelstorage.BalanceHoldStorageEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddHold", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(*modelstorage.BalanceHoldStorageEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddHold indicates an expected call of AddHold.
+func (mr *MockStorageMockRecorder) AddHold(arg0, arg1, arg2, arg3 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddHold", reflect.TypeOf((*MockStorage)(nil).AddHold), arg0, arg1, arg2, arg3)
+}
+
+// AddNewOrder mocks base method.
+func (m *MockStorage) AddNewOrder(arg0 context.Context, arg1 string, arg2 int, arg3, arg4 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddNewOrder", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddNewOrder indicates an expected call of AddNewOrder.
+func (mr *MockStorageMockRecorder) AddNewOrder(arg0, arg1, arg2, arg3, arg4 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddNewOrder", reflect.TypeOf((*MockStorage)(nil).AddNewOrder), arg0, arg1, arg2, arg3, arg4)
+}
+
+// AddNewUser mocks base method.
+func (m *MockStorage) AddNewUser(arg0 context.Context, arg1 modeldto.User, arg2 modelstorage.LoginLookup, arg3 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddNewUser", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddNewUser indicates an expected call of AddNewUser.
+func (mr *MockStorageMockRecorder) AddNewUser(arg0, arg1, arg2, arg3 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddNewUser", reflect.TypeOf((*MockStorage)(nil).AddNewUser), arg0, arg1, arg2, arg3)
+}
+
+// AddNewWithdrawal mocks base method.
+func (m *MockStorage) AddNewWithdrawal(arg0 context.Context, arg1 string, arg2 modeldto.NewOrderWithdrawal) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddNewWithdrawal", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddNewWithdrawal indicates an expected call of AddNewWithdrawal.
+func (mr *MockStorageMockRecorder) AddNewWithdrawal(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddNewWithdrawal", reflect.TypeOf((*MockStorage)(nil).AddNewWithdrawal), arg0, arg1, arg2)
+}
+
+// AddPendingWithdrawal mocks base method.
+func (m *MockStorage) AddPendingWithdrawal(arg0 context.Context, arg1 string, arg2 modeldto.NewOrderWithdrawal) (uint, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddPendingWithdrawal", arg0, arg1, arg2)
+	ret0, _ := ret[0].(uint)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddPendingWithdrawal indicates an expected call of AddPendingWithdrawal.
+func (mr *MockStorageMockRecorder) AddPendingWithdrawal(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddPendingWithdrawal", reflect.TypeOf((*MockStorage)(nil).AddPendingWithdrawal), arg0, arg1, arg2)
+}
+
+// AddSession mocks base method.
+func (m *MockStorage) AddSession(arg0 context.Context, arg1 modelstorage.SessionStorageEntry) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddSession", arg0, arg1)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddSession indicates an expected call of AddSession.
+func (mr *MockStorageMockRecorder) AddSession(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddSession", reflect.TypeOf((*MockStorage)(nil).AddSession), arg0, arg1)
+}
+
+// CaptureHold mocks base method.
+func (m *MockStorage) CaptureHold(arg0 context.Context, arg1 string, arg2 uint) (*modelstorage.BalanceHoldStorageEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CaptureHold", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*modelstorage.BalanceHoldStorageEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CaptureHold indicates an expected call of CaptureHold.
+func (mr *MockStorageMockRecorder) CaptureHold(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CaptureHold", reflect.TypeOf((*MockStorage)(nil).CaptureHold), arg0, arg1, arg2)
+}
+
+// CheckUser mocks base method.
+func (m *MockStorage) CheckUser(arg0 context.Context, arg1 modelstorage.LoginLookup) (*modelstorage.UserStorageEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckUser", arg0, arg1)
+	ret0, _ := ret[0].(*modelstorage.UserStorageEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CheckUser indicates an expected call of CheckUser.
+func (mr *MockStorageMockRecorder) CheckUser(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckUser", reflect.TypeOf((*MockStorage)(nil).CheckUser), arg0, arg1)
+}
+
+// ConfirmWithdrawal mocks base method.
+func (m *MockStorage) ConfirmWithdrawal(arg0 context.Context, arg1 string, arg2 uint) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConfirmWithdrawal", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ConfirmWithdrawal indicates an expected call of ConfirmWithdrawal.
+func (mr *MockStorageMockRecorder) ConfirmWithdrawal(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConfirmWithdrawal", reflect.TypeOf((*MockStorage)(nil).ConfirmWithdrawal), arg0, arg1, arg2)
+}
+
+// ExpireHolds mocks base method.
+func (m *MockStorage) ExpireHolds(arg0 context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExpireHolds", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ExpireHolds indicates an expected call of ExpireHolds.
+func (mr *MockStorageMockRecorder) ExpireHolds(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExpireHolds", reflect.TypeOf((*MockStorage)(nil).ExpireHolds), arg0)
+}
+
+// FailWithdrawal mocks base method.
+func (m *MockStorage) FailWithdrawal(arg0 context.Context, arg1 string, arg2 uint) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FailWithdrawal", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// FailWithdrawal indicates an expected call of FailWithdrawal.
+func (mr *MockStorageMockRecorder) FailWithdrawal(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FailWithdrawal", reflect.TypeOf((*MockStorage)(nil).FailWithdrawal), arg0, arg1, arg2)
+}
+
+// GetAccrualResponse mocks base method.
+func (m *MockStorage) GetAccrualResponse(arg0 context.Context, arg1 int) (*modelstorage.AccrualResponseStorageEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAccrualResponse", arg0, arg1)
+	ret0, _ := ret[0].(*modelstorage.AccrualResponseStorageEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAccrualResponse indicates an expected call of GetAccrualResponse.
+func (mr *MockStorageMockRecorder) GetAccrualResponse(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccrualResponse", reflect.TypeOf((*MockStorage)(nil).GetAccrualResponse), arg0, arg1)
+}
+
+// GetAlertThresholds mocks base method.
+func (m *MockStorage) GetAlertThresholds(arg0 context.Context, arg1 string) (*modelstorage.AlertThresholdsStorageEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAlertThresholds", arg0, arg1)
+	ret0, _ := ret[0].(*modelstorage.AlertThresholdsStorageEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAlertThresholds indicates an expected call of GetAlertThresholds.
+func (mr *MockStorageMockRecorder) GetAlertThresholds(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAlertThresholds", reflect.TypeOf((*MockStorage)(nil).GetAlertThresholds), arg0, arg1)
+}
+
+// GetAnyOrder mocks base method.
+func (m *MockStorage) GetAnyOrder(arg0 context.Context, arg1 int) (*modelstorage.OrderStorageEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAnyOrder", arg0, arg1)
+	ret0, _ := ret[0].(*modelstorage.OrderStorageEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAnyOrder indicates an expected call of GetAnyOrder.
+func (mr *MockStorageMockRecorder) GetAnyOrder(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAnyOrder", reflect.TypeOf((*MockStorage)(nil).GetAnyOrder), arg0, arg1)
+}
+
+// GetBalanceAmounts mocks base method.
+func (m *MockStorage) GetBalanceAmounts(arg0 context.Context, arg1 string) (*modelstorage.BalanceAmountsStorageEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBalanceAmounts", arg0, arg1)
+	ret0, _ := ret[0].(*modelstorage.BalanceAmountsStorageEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBalanceAmounts indicates an expected call of GetBalanceAmounts.
+func (mr *MockStorageMockRecorder) GetBalanceAmounts(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBalanceAmounts", reflect.TypeOf((*MockStorage)(nil).GetBalanceAmounts), arg0, arg1)
+}
+
+// GetBalanceEvents mocks base method.
+func (m *MockStorage) GetBalanceEvents(arg0 context.Context, arg1 string) ([]modelstorage.BalanceEventStorageEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBalanceEvents", arg0, arg1)
+	ret0, _ := ret[0].([]modelstorage.BalanceEventStorageEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBalanceEvents indicates an expected call of GetBalanceEvents.
+func (mr *MockStorageMockRecorder) GetBalanceEvents(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBalanceEvents", reflect.TypeOf((*MockStorage)(nil).GetBalanceEvents), arg0, arg1)
+}
+
+// GetCurrentAmount mocks base method.
+func (m *MockStorage) GetCurrentAmount(arg0 context.Context, arg1 string) (float64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCurrentAmount", arg0, arg1)
+	ret0, _ := ret[0].(float64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCurrentAmount indicates an expected call of GetCurrentAmount.
+func (mr *MockStorageMockRecorder) GetCurrentAmount(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCurrentAmount", reflect.TypeOf((*MockStorage)(nil).GetCurrentAmount), arg0, arg1)
+}
+
+// GetHolds mocks base method.
+func (m *MockStorage) GetHolds(arg0 context.Context, arg1 string) ([]modelstorage.BalanceHoldStorageEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetHolds", arg0, arg1)
+	ret0, _ := ret[0].([]modelstorage.BalanceHoldStorageEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetHolds indicates an expected call of GetHolds.
+func (mr *MockStorageMockRecorder) GetHolds(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHolds", reflect.TypeOf((*MockStorage)(nil).GetHolds), arg0, arg1)
+}
+
+// GetOrder mocks base method.
+func (m *MockStorage) GetOrder(arg0 context.Context, arg1 string, arg2 int) (*modelstorage.OrderStorageEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOrder", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*modelstorage.OrderStorageEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOrder indicates an expected call of GetOrder.
+func (mr *MockStorageMockRecorder) GetOrder(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrder", reflect.TypeOf((*MockStorage)(nil).GetOrder), arg0, arg1, arg2)
+}
+
+// GetOrderStatusHistory mocks base method.
+func (m *MockStorage) GetOrderStatusHistory(arg0 context.Context, arg1 string, arg2 int) ([]modelstorage.OrderStatusHistoryStorageEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOrderStatusHistory", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]modelstorage.OrderStatusHistoryStorageEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOrderStatusHistory indicates an expected call of GetOrderStatusHistory.
+func (mr *MockStorageMockRecorder) GetOrderStatusHistory(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrderStatusHistory", reflect.TypeOf((*MockStorage)(nil).GetOrderStatusHistory), arg0, arg1, arg2)
+}
+
+// GetOrders mocks base method.
+func (m *MockStorage) GetOrders(arg0 context.Context, arg1 string, arg2 modeldto.Sort) ([]modelstorage.OrderStorageEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOrders", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]modelstorage.OrderStorageEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOrders indicates an expected call of GetOrders.
+func (mr *MockStorageMockRecorder) GetOrders(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrders", reflect.TypeOf((*MockStorage)(nil).GetOrders), arg0, arg1, arg2)
+}
+
+// GetReconciliationReport mocks base method.
+func (m *MockStorage) GetReconciliationReport(arg0 context.Context) (*modeldto.ReconciliationReport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetReconciliationReport", arg0)
+	ret0, _ := ret[0].(*modeldto.ReconciliationReport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetReconciliationReport indicates an expected call of GetReconciliationReport.
+func (mr *MockStorageMockRecorder) GetReconciliationReport(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetReconciliationReport", reflect.TypeOf((*MockStorage)(nil).GetReconciliationReport), arg0)
+}
+
+// GetReport mocks base method.
+func (m *MockStorage) GetReport(arg0 context.Context, arg1, arg2 time.Time) (*modeldto.AdminReport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetReport", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*modeldto.AdminReport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetReport indicates an expected call of GetReport.
+func (mr *MockStorageMockRecorder) GetReport(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetReport", reflect.TypeOf((*MockStorage)(nil).GetReport), arg0, arg1, arg2)
+}
+
+// GetSessions mocks base method.
+func (m *MockStorage) GetSessions(arg0 context.Context, arg1 string) ([]modelstorage.SessionStorageEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSessions", arg0, arg1)
+	ret0, _ := ret[0].([]modelstorage.SessionStorageEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSessions indicates an expected call of GetSessions.
+func (mr *MockStorageMockRecorder) GetSessions(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSessions", reflect.TypeOf((*MockStorage)(nil).GetSessions), arg0, arg1)
+}
+
+// GetSummary mocks base method.
+func (m *MockStorage) GetSummary(arg0 context.Context, arg1 []time.Duration) (*modeldto.AdminSummary, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSummary", arg0, arg1)
+	ret0, _ := ret[0].(*modeldto.AdminSummary)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSummary indicates an expected call of GetSummary.
+func (mr *MockStorageMockRecorder) GetSummary(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSummary", reflect.TypeOf((*MockStorage)(nil).GetSummary), arg0, arg1)
+}
+
+// GetSuspendedOrders mocks base method.
+func (m *MockStorage) GetSuspendedOrders(arg0 context.Context) ([]modelstorage.OrderStorageEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSuspendedOrders", arg0)
+	ret0, _ := ret[0].([]modelstorage.OrderStorageEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSuspendedOrders indicates an expected call of GetSuspendedOrders.
+func (mr *MockStorageMockRecorder) GetSuspendedOrders(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSuspendedOrders", reflect.TypeOf((*MockStorage)(nil).GetSuspendedOrders), arg0)
+}
+
+// GetTelegramChatID mocks base method.
+func (m *MockStorage) GetTelegramChatID(arg0 context.Context, arg1 string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTelegramChatID", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTelegramChatID indicates an expected call of GetTelegramChatID.
+func (mr *MockStorageMockRecorder) GetTelegramChatID(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTelegramChatID", reflect.TypeOf((*MockStorage)(nil).GetTelegramChatID), arg0, arg1)
+}
+
+// GetUser mocks base method.
+func (m *MockStorage) GetUser(arg0 context.Context, arg1 string) (*modelstorage.UserStorageEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUser", arg0, arg1)
+	ret0, _ := ret[0].(*modelstorage.UserStorageEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUser indicates an expected call of GetUser.
+func (mr *MockStorageMockRecorder) GetUser(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUser", reflect.TypeOf((*MockStorage)(nil).GetUser), arg0, arg1)
+}
+
+// GetUserStats mocks base method.
+func (m *MockStorage) GetUserStats(arg0 context.Context, arg1 string) ([]modelstorage.ChannelStatsStorageEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserStats", arg0, arg1)
+	ret0, _ := ret[0].([]modelstorage.ChannelStatsStorageEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserStats indicates an expected call of GetUserStats.
+func (mr *MockStorageMockRecorder) GetUserStats(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserStats", reflect.TypeOf((*MockStorage)(nil).GetUserStats), arg0, arg1)
+}
+
+// GetWithdrawal mocks base method.
+func (m *MockStorage) GetWithdrawal(arg0 context.Context, arg1, arg2 string) (*modelstorage.WithdrawalStorageEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWithdrawal", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*modelstorage.WithdrawalStorageEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetWithdrawal indicates an expected call of GetWithdrawal.
+func (mr *MockStorageMockRecorder) GetWithdrawal(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWithdrawal", reflect.TypeOf((*MockStorage)(nil).GetWithdrawal), arg0, arg1, arg2)
+}
+
+// GetWithdrawals mocks base method.
+func (m *MockStorage) GetWithdrawals(arg0 context.Context, arg1 string, arg2 modeldto.Sort) ([]modelstorage.WithdrawalStorageEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWithdrawals", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]modelstorage.WithdrawalStorageEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetWithdrawals indicates an expected call of GetWithdrawals.
+func (mr *MockStorageMockRecorder) GetWithdrawals(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWithdrawals", reflect.TypeOf((*MockStorage)(nil).GetWithdrawals), arg0, arg1, arg2)
+}
+
+// GetWithdrawnAmount mocks base method.
+func (m *MockStorage) GetWithdrawnAmount(arg0 context.Context, arg1 string) (float64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWithdrawnAmount", arg0, arg1)
+	ret0, _ := ret[0].(float64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetWithdrawnAmount indicates an expected call of GetWithdrawnAmount.
+func (mr *MockStorageMockRecorder) GetWithdrawnAmount(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWithdrawnAmount", reflect.TypeOf((*MockStorage)(nil).GetWithdrawnAmount), arg0, arg1)
+}
+
+// Healthy mocks base method.
+func (m *MockStorage) Healthy() bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Healthy")
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// Healthy indicates an expected call of Healthy.
+func (mr *MockStorageMockRecorder) Healthy() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Healthy", reflect.TypeOf((*MockStorage)(nil).Healthy))
+}
+
+// LinkTelegramChat mocks base method.
+func (m *MockStorage) LinkTelegramChat(arg0 context.Context, arg1 string, arg2 int64) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LinkTelegramChat", arg0, arg1, arg2)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LinkTelegramChat indicates an expected call of LinkTelegramChat.
+func (mr *MockStorageMockRecorder) LinkTelegramChat(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LinkTelegramChat", reflect.TypeOf((*MockStorage)(nil).LinkTelegramChat), arg0, arg1, arg2)
+}
+
+// MergeUsers mocks base method.
+func (m *MockStorage) MergeUsers(arg0 context.Context, arg1, arg2 string) (*modeldto.AccountMerge, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MergeUsers", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*modeldto.AccountMerge)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MergeUsers indicates an expected call of MergeUsers.
+func (mr *MockStorageMockRecorder) MergeUsers(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MergeUsers", reflect.TypeOf((*MockStorage)(nil).MergeUsers), arg0, arg1, arg2)
+}
+
+// RecalculateBalances mocks base method.
+func (m *MockStorage) RecalculateBalances(arg0 context.Context, arg1 bool) (*modeldto.ReconciliationReport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecalculateBalances", arg0, arg1)
+	ret0, _ := ret[0].(*modeldto.ReconciliationReport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RecalculateBalances indicates an expected call of RecalculateBalances.
+func (mr *MockStorageMockRecorder) RecalculateBalances(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecalculateBalances", reflect.TypeOf((*MockStorage)(nil).RecalculateBalances), arg0, arg1)
+}
+
+// RecheckOrder mocks base method.
+func (m *MockStorage) RecheckOrder(arg0 context.Context, arg1 string, arg2 int, arg3 *modelstorage.RecheckLimit) (*modelstorage.OrderStorageEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecheckOrder", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(*modelstorage.OrderStorageEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RecheckOrder indicates an expected call of RecheckOrder.
+func (mr *MockStorageMockRecorder) RecheckOrder(arg0, arg1, arg2, arg3 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecheckOrder", reflect.TypeOf((*MockStorage)(nil).RecheckOrder), arg0, arg1, arg2, arg3)
+}
+
+// ReleaseHold mocks base method.
+func (m *MockStorage) ReleaseHold(arg0 context.Context, arg1 string, arg2 uint) (*modelstorage.BalanceHoldStorageEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseHold", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*modelstorage.BalanceHoldStorageEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReleaseHold indicates an expected call of ReleaseHold.
+func (mr *MockStorageMockRecorder) ReleaseHold(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseHold", reflect.TypeOf((*MockStorage)(nil).ReleaseHold), arg0, arg1, arg2)
+}
+
+// RequeueOrders mocks base method.
+func (m *MockStorage) RequeueOrders(arg0 context.Context, arg1 modelstorage.RequeueFilter) (*modeldto.RequeueReport, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RequeueOrders", arg0, arg1)
+	ret0, _ := ret[0].(*modeldto.RequeueReport)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RequeueOrders indicates an expected call of RequeueOrders.
+func (mr *MockStorageMockRecorder) RequeueOrders(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RequeueOrders", reflect.TypeOf((*MockStorage)(nil).RequeueOrders), arg0, arg1)
+}
+
+// ResolveOrder mocks base method.
+func (m *MockStorage) ResolveOrder(arg0 context.Context, arg1 int, arg2 string, arg3 float64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResolveOrder", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ResolveOrder indicates an expected call of ResolveOrder.
+func (mr *MockStorageMockRecorder) ResolveOrder(arg0, arg1, arg2, arg3 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResolveOrder", reflect.TypeOf((*MockStorage)(nil).ResolveOrder), arg0, arg1, arg2, arg3)
+}
+
+// RetryAfter mocks base method.
+func (m *MockStorage) RetryAfter() time.Duration {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RetryAfter")
+	ret0, _ := ret[0].(time.Duration)
+	return ret0
+}
+
+// RetryAfter indicates an expected call of RetryAfter.
+func (mr *MockStorageMockRecorder) RetryAfter() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RetryAfter", reflect.TypeOf((*MockStorage)(nil).RetryAfter))
+}
+
+// ReviewSuspendedOrder mocks base method.
+func (m *MockStorage) ReviewSuspendedOrder(arg0 context.Context, arg1 int, arg2 bool) (*modelstorage.OrderStorageEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReviewSuspendedOrder", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*modelstorage.OrderStorageEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReviewSuspendedOrder indicates an expected call of ReviewSuspendedOrder.
+func (mr *MockStorageMockRecorder) ReviewSuspendedOrder(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReviewSuspendedOrder", reflect.TypeOf((*MockStorage)(nil).ReviewSuspendedOrder), arg0, arg1, arg2)
+}
+
+// RevokeSession mocks base method.
+func (m *MockStorage) RevokeSession(arg0 context.Context, arg1, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeSession", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeSession indicates an expected call of RevokeSession.
+func (mr *MockStorageMockRecorder) RevokeSession(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeSession", reflect.TypeOf((*MockStorage)(nil).RevokeSession), arg0, arg1, arg2)
+}
+
+// RotateRefreshToken mocks base method.
+func (m *MockStorage) RotateRefreshToken(arg0 context.Context, arg1, arg2 string, arg3 time.Time, arg4 time.Duration) (*modelstorage.SessionStorageEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RotateRefreshToken", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].(*modelstorage.SessionStorageEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RotateRefreshToken indicates an expected call of RotateRefreshToken.
+func (mr *MockStorageMockRecorder) RotateRefreshToken(arg0, arg1, arg2, arg3, arg4 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RotateRefreshToken", reflect.TypeOf((*MockStorage)(nil).RotateRefreshToken), arg0, arg1, arg2, arg3, arg4)
+}
+
+// SaveAccrualResponse mocks base method.
+func (m *MockStorage) SaveAccrualResponse(arg0 context.Context, arg1 modelstorage.AccrualResponseStorageEntry) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveAccrualResponse", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveAccrualResponse indicates an expected call of SaveAccrualResponse.
+func (mr *MockStorageMockRecorder) SaveAccrualResponse(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveAccrualResponse", reflect.TypeOf((*MockStorage)(nil).SaveAccrualResponse), arg0, arg1)
+}
+
+// SearchUsers mocks base method.
+func (m *MockStorage) SearchUsers(arg0 context.Context, arg1 modelstorage.UserSearch) ([]modelstorage.UserStorageEntry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SearchUsers", arg0, arg1)
+	ret0, _ := ret[0].([]modelstorage.UserStorageEntry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SearchUsers indicates an expected call of SearchUsers.
+func (mr *MockStorageMockRecorder) SearchUsers(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchUsers", reflect.TypeOf((*MockStorage)(nil).SearchUsers), arg0, arg1)
+}
+
+// SendToQueue mocks base method.
+func (m *MockStorage) SendToQueue(arg0 modelqueue.OrderQueueEntry) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SendToQueue", arg0)
+}
+
+// SendToQueue indicates an expected call of SendToQueue.
+func (mr *MockStorageMockRecorder) SendToQueue(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendToQueue", reflect.TypeOf((*MockStorage)(nil).SendToQueue), arg0)
+}
+
+// SendWithdrawalToQueue mocks base method.
+func (m *MockStorage) SendWithdrawalToQueue(arg0 context.Context, arg1 modelqueue.WithdrawalQueueEntry) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SendWithdrawalToQueue", arg0, arg1)
+}
+
+// SendWithdrawalToQueue indicates an expected call of SendWithdrawalToQueue.
+func (mr *MockStorageMockRecorder) SendWithdrawalToQueue(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendWithdrawalToQueue", reflect.TypeOf((*MockStorage)(nil).SendWithdrawalToQueue), arg0, arg1)
+}
+
+// SetAlertThresholds mocks base method.
+func (m *MockStorage) SetAlertThresholds(arg0 context.Context, arg1 string, arg2 modelstorage.AlertThresholdsStorageEntry) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetAlertThresholds", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetAlertThresholds indicates an expected call of SetAlertThresholds.
+func (mr *MockStorageMockRecorder) SetAlertThresholds(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetAlertThresholds", reflect.TypeOf((*MockStorage)(nil).SetAlertThresholds), arg0, arg1, arg2)
+}
+
+// SetTelegramLinkCode mocks base method.
+func (m *MockStorage) SetTelegramLinkCode(arg0 context.Context, arg1, arg2 string, arg3 time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetTelegramLinkCode", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetTelegramLinkCode indicates an expected call of SetTelegramLinkCode.
+func (mr *MockStorageMockRecorder) SetTelegramLinkCode(arg0, arg1, arg2, arg3 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetTelegramLinkCode", reflect.TypeOf((*MockStorage)(nil).SetTelegramLinkCode), arg0, arg1, arg2, arg3)
+}
+
+// TouchSession mocks base method.
+func (m *MockStorage) TouchSession(arg0 context.Context, arg1, arg2 string, arg3, arg4 time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TouchSession", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// TouchSession indicates an expected call of TouchSession.
+func (mr *MockStorageMockRecorder) TouchSession(arg0, arg1, arg2, arg3, arg4 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TouchSession", reflect.TypeOf((*MockStorage)(nil).TouchSession), arg0, arg1, arg2, arg3, arg4)
+}
+
+// TransferOrder mocks base method.
+func (m *MockStorage) TransferOrder(arg0 context.Context, arg1 int, arg2, arg3 string) (*modeldto.OrderTransfer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TransferOrder", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(*modeldto.OrderTransfer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TransferOrder indicates an expected call of TransferOrder.
+func (mr *MockStorageMockRecorder) TransferOrder(arg0, arg1, arg2, arg3 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TransferOrder", reflect.TypeOf((*MockStorage)(nil).TransferOrder), arg0, arg1, arg2, arg3)
+}
+
+// UpdatePasswordHash mocks base method.
+func (m *MockStorage) UpdatePasswordHash(arg0 context.Context, arg1, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePasswordHash", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdatePasswordHash indicates an expected call of UpdatePasswordHash.
+func (mr *MockStorageMockRecorder) UpdatePasswordHash(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePasswordHash", reflect.TypeOf((*MockStorage)(nil).UpdatePasswordHash), arg0, arg1, arg2)
+}
+
+// UpdateUserProfile mocks base method.
+func (m *MockStorage) UpdateUserProfile(arg0 context.Context, arg1 string, arg2 modelstorage.UserStorageEntry, arg3 *modelstorage.LoginLookup, arg4 []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateUserProfile", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateUserProfile indicates an expected call of UpdateUserProfile.
+func (mr *MockStorageMockRecorder) UpdateUserProfile(arg0, arg1, arg2, arg3, arg4 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUserProfile", reflect.TypeOf((*MockStorage)(nil).UpdateUserProfile), arg0, arg1, arg2, arg3, arg4)
+}
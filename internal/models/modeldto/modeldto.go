@@ -2,33 +2,343 @@
 
 package modeldto
 
+import (
+	"encoding/json"
+	"time"
+)
+
 type (
 	User struct {
-		Login    string `json:"login,omitempty"`
-		Password string `json:"password,omitempty"`
+		Login    string `json:"login,omitempty" validate:"required,max=256"`
+		Password string `json:"password,omitempty" validate:"required,max=256"`
 	}
 	Balance struct {
-		CurrentAmount   float64 `json:"current"`
-		WithdrawnAmount float64 `json:"withdrawn"`
+		CurrentAmount   float64   `json:"current"`
+		WithdrawnAmount float64   `json:"withdrawn"`
+		HeldAmount      float64   `json:"held,omitempty"`
+		UpdatedAt       time.Time `json:"-"`
 	}
 	Withdrawal struct {
-		OrderNumber     string  `json:"order"`
-		WithdrawnAmount float64 `json:"sum"`
-		ProcessedAt     string  `json:"processed_at"`
+		OrderNumber     string           `json:"order"`
+		WithdrawnAmount float64          `json:"sum"`
+		ProcessedAt     string           `json:"processed_at"`
+		Status          string           `json:"status,omitempty"`
+		Converted       *ConvertedAmount `json:"converted,omitempty"`
+	}
+	ConvertedAmount struct {
+		Currency string  `json:"currency"`
+		Rate     float64 `json:"rate"`
+		Amount   float64 `json:"amount"`
+	}
+	ConvertedBalance struct {
+		Currency        string  `json:"currency"`
+		Rate            float64 `json:"rate"`
+		CurrentAmount   float64 `json:"current"`
+		WithdrawnAmount float64 `json:"withdrawn"`
 	}
 	Order struct {
+		OrderNumber string          `json:"number"`
+		Status      string          `json:"status"`
+		Accrual     float64         `json:"accrual,omitempty"`
+		UploadedAt  string          `json:"uploaded_at"`
+		Metadata    json.RawMessage `json:"metadata,omitempty"`
+	}
+	OrderHistory struct {
+		OrderNumber string                  `json:"number"`
+		Status      string                  `json:"status"`
+		UploadedAt  string                  `json:"uploaded_at"`
+		Transitions []OrderStatusTransition `json:"transitions"`
+	}
+	OrderStatusTransition struct {
+		From      string `json:"from"`
+		To        string `json:"to"`
+		Source    string `json:"source"`
+		ChangedAt string `json:"changed_at"`
+	}
+	NewOrder struct {
+		OrderNumber string
+		Metadata    json.RawMessage
+		Channel     string
+	}
+	ProfileUpdate struct {
+		Login *string `json:"login,omitempty" validate:"omitempty,min=1,max=256"`
+		Email *string `json:"email,omitempty" validate:"omitempty,max=256,email|len=0"`
+		Phone *string `json:"phone,omitempty" validate:"omitempty,e164|len=0"`
+	}
+	Profile struct {
+		Login string `json:"login"`
+		Email string `json:"email,omitempty"`
+		Phone string `json:"phone,omitempty"`
+	}
+	AccountMergeRequest struct {
+		DonorID  string `json:"donor_id" validate:"required"`
+		TargetID string `json:"target_id" validate:"required,nefield=DonorID"`
+	}
+	PartnerTokenRequest struct {
+		Partner string   `json:"partner" validate:"required"`
+		UserID  string   `json:"user_id" validate:"required"`
+		Tenant  string   `json:"tenant"`
+		Scopes  []string `json:"scopes" validate:"required,min=1"`
+	}
+	OrderTransferRequest struct {
+		TargetID string `json:"target_id" validate:"required"`
+		Comment  string `json:"comment" validate:"max=500"`
+	}
+	BalanceAdjustmentRequest struct {
+		Amount     float64 `json:"amount" validate:"required,gt=-100000000,lt=100000000"`
+		ReasonCode string  `json:"reason_code" validate:"required,oneof=compensation goodwill correction"`
+		Comment    string  `json:"comment" validate:"max=500"`
+	}
+	RequeueRequest struct {
+		Status     string `json:"status" validate:"omitempty,oneof=NEW PROCESSING"`
+		OlderThan  string `json:"older_than"`
+		UserID     string `json:"user_id"`
+		NumberFrom string `json:"number_from" validate:"omitempty,numeric"`
+		NumberTo   string `json:"number_to" validate:"omitempty,numeric"`
+	}
+	CashbackEvaluationRequest struct {
+		Accrual    float64   `json:"accrual" validate:"gte=0"`
+		Channel    string    `json:"channel"`
+		UploadedAt time.Time `json:"uploaded_at"`
+	}
+	OrderPreviewRequest struct {
+		// Accrual is the hypothetical accrual reported by the accrual system, campaigns are applied on top of it
+		Accrual float64 `json:"accrual" validate:"gt=0"`
+	}
+	CashbackEvaluation struct {
+		BaseAccrual float64 `json:"base_accrual"`
+		Accrual     float64 `json:"accrual"`
+		Rule        string  `json:"rule,omitempty"`
+	}
+	BalanceAdjustment struct {
+		UserID     string  `json:"user_id"`
+		Amount     float64 `json:"amount"`
+		ReasonCode string  `json:"reason_code"`
+		Comment    string  `json:"comment,omitempty"`
+		Balance    float64 `json:"balance"`
+		CreatedAt  string  `json:"created_at"`
+	}
+	RequeueReport struct {
+		Matched  int `json:"matched"`
+		Requeued int `json:"requeued"`
+	}
+	PartnerToken struct {
+		Partner   string   `json:"partner"`
+		UserID    string   `json:"user_id"`
+		Scopes    []string `json:"scopes"`
+		Token     string   `json:"token"`
+		ExpiresAt string   `json:"expires_at"`
+	}
+	AccountMerge struct {
+		DonorID          string  `json:"donor_id"`
+		TargetID         string  `json:"target_id"`
+		OrdersMoved      int64   `json:"orders_moved"`
+		WithdrawalsMoved int64   `json:"withdrawals_moved"`
+		AmountMoved      float64 `json:"amount_moved"`
+	}
+	AdminOrder struct {
 		OrderNumber string  `json:"number"`
+		UserID      string  `json:"user_id"`
+		Tenant      string  `json:"tenant"`
 		Status      string  `json:"status"`
-		Accrual     float64 `json:"accrual,omitempty"`
+		Accrual     float64 `json:"accrual"`
 		UploadedAt  string  `json:"uploaded_at"`
+		// AccrualResponse is the latest raw response of the accrual system polled for the order
+		AccrualResponse *AccrualResponseRecord `json:"accrual_response,omitempty"`
+	}
+	AccrualResponseRecord struct {
+		StatusCode int    `json:"status_code"`
+		Body       string `json:"body"`
+		ReceivedAt string `json:"received_at"`
+	}
+	SuspendedOrder struct {
+		OrderNumber string  `json:"number"`
+		UserID      string  `json:"user_id"`
+		Tenant      string  `json:"tenant"`
+		Accrual     float64 `json:"accrual"`
+		UploadedAt  string  `json:"uploaded_at"`
+	}
+	UserSummary struct {
+		UserID       string   `json:"user_id"`
+		Login        string   `json:"login"`
+		Tenant       string   `json:"tenant"`
+		RegisteredAt string   `json:"registered_at"`
+		Deactivated  bool     `json:"deactivated,omitempty"`
+		MatchedBy    []string `json:"matched_by"`
+	}
+	UserSearchResult struct {
+		Users      []UserSummary `json:"users"`
+		NextOffset *int          `json:"next_offset,omitempty"`
+	}
+	OrderTransfer struct {
+		OrderNumber string  `json:"number"`
+		Status      string  `json:"status"`
+		FromID      string  `json:"from_id"`
+		ToID        string  `json:"to_id"`
+		AmountMoved float64 `json:"amount_moved"`
+		Comment     string  `json:"comment,omitempty"`
+	}
+	NewOrderRequest struct {
+		OrderNumber string `json:"order" validate:"required"`
 	}
 	NewOrderWithdrawal struct {
+		OrderNumber string  `json:"order" validate:"required"`
+		Amount      float64 `json:"sum" validate:"gt=0"`
+	}
+	NewBalanceHold struct {
+		OrderNumber string  `json:"order" validate:"required"`
+		Amount      float64 `json:"sum" validate:"gt=0"`
+	}
+	BalanceHold struct {
+		ID          uint    `json:"id"`
 		OrderNumber string  `json:"order"`
 		Amount      float64 `json:"sum"`
+		Status      string  `json:"status"`
+		CreatedAt   string  `json:"created_at"`
+		ExpiresAt   string  `json:"expires_at"`
 	}
 	AccrualResponse struct {
-		OrderNumber string  `json:"order"`
-		OrderStatus string  `json:"status"`
-		Accrual     float64 `json:"accrual,omitempty"`
+		OrderNumber string  `json:"order" validate:"required"`
+		OrderStatus string  `json:"status" validate:"required"`
+		Accrual     float64 `json:"accrual,omitempty" validate:"gte=0"`
+	}
+)
+
+type (
+	HealthReport struct {
+		Status       string             `json:"status"`
+		Dependencies []DependencyHealth `json:"dependencies"`
+	}
+	DependencyHealth struct {
+		Name        string `json:"name"`
+		Status      string `json:"status"`
+		Since       string `json:"since"`
+		LastChecked string `json:"last_checked"`
+		LastError   string `json:"last_error,omitempty"`
+		Transitions int    `json:"transitions"`
+	}
+)
+
+type (
+	ReconciliationReport struct {
+		CheckedAt     string               `json:"checked_at"`
+		UsersChecked  int                  `json:"users_checked"`
+		Applied       bool                 `json:"applied"`
+		Discrepancies []BalanceDiscrepancy `json:"discrepancies"`
+	}
+	BalanceDiscrepancy struct {
+		UserID         string  `json:"user_id"`
+		StoredAmount   float64 `json:"stored"`
+		ExpectedAmount float64 `json:"expected"`
+		Difference     float64 `json:"difference"`
+	}
+)
+
+type (
+	AdminSummary struct {
+		GeneratedAt string          `json:"generated_at"`
+		Users       int             `json:"users"`
+		Orders      map[string]int  `json:"orders"`
+		Channels    map[string]int  `json:"channels"`
+		Queue       QueueSummary    `json:"queue"`
+		Windows     []WindowSummary `json:"windows"`
+	}
+	AdminReport struct {
+		Period        string         `json:"period"`
+		From          string         `json:"from"`
+		To            string         `json:"to"`
+		Registrations int            `json:"registrations"`
+		Orders        map[string]int `json:"orders"`
+		Abandoned     int            `json:"abandoned"`
+		Accrued       float64        `json:"accrued"`
+		Withdrawn     float64        `json:"withdrawn"`
+	}
+	QueueSummary struct {
+		Orders      int `json:"orders"`
+		Withdrawals int `json:"withdrawals"`
+	}
+	WindowSummary struct {
+		Window    string  `json:"window"`
+		Issued    float64 `json:"issued"`
+		Withdrawn float64 `json:"withdrawn"`
 	}
 )
+
+type (
+	UserStats struct {
+		Channels []ChannelStats `json:"channels"`
+	}
+	ChannelStats struct {
+		Channel string  `json:"channel"`
+		Orders  int     `json:"orders"`
+		Accrual float64 `json:"accrual"`
+	}
+)
+
+type (
+	Tokens struct {
+		// AccessToken is handed out by the auth strategy and never rendered
+		AccessToken      string `json:"-"`
+		RefreshToken     string `json:"refresh_token"`
+		RefreshExpiresAt string `json:"refresh_expires_at"`
+	}
+	RefreshRequest struct {
+		RefreshToken string `json:"refresh_token" validate:"required"`
+	}
+	ClientInfo struct {
+		UserAgent string
+		IP        string
+	}
+	LoginThrottle struct {
+		Tenant            string `json:"tenant"`
+		Login             string `json:"login"`
+		Failures          int    `json:"failures"`
+		RemainingAttempts int    `json:"remaining_attempts"`
+		LockedUntil       string `json:"locked_until,omitempty"`
+	}
+	Session struct {
+		UserAgent string `json:"user_agent"`
+		IP        string `json:"ip"`
+		CreatedAt string `json:"created_at"`
+		LastSeen  string `json:"last_seen"`
+	}
+	TelegramLink struct {
+		Code      string `json:"code"`
+		ExpiresAt string `json:"expires_at"`
+	}
+	Transaction struct {
+		Kind      string  `json:"kind"`
+		Amount    float64 `json:"amount"`
+		Reference string  `json:"reference"`
+		CreatedAt string  `json:"created_at"`
+	}
+	AlertThresholds struct {
+		LowBalance   *float64 `json:"low_balance" validate:"omitempty,gte=0,lt=100000000"`
+		LargeAccrual *float64 `json:"large_accrual" validate:"omitempty,gt=0,lt=100000000"`
+	}
+	Notification struct {
+		Kind    string
+		UserID  string
+		Message string
+		// Email addresses a notification to a mailbox rather than to a user
+		Email string
+	}
+	RecordedRequest struct {
+		RequestID    string `json:"request_id"`
+		Method       string `json:"method"`
+		Route        string `json:"route"`
+		Path         string `json:"path"`
+		Status       int    `json:"status"`
+		ContentType  string `json:"content_type,omitempty"`
+		RequestBody  string `json:"request_body,omitempty"`
+		ResponseBody string `json:"response_body,omitempty"`
+		Truncated    bool   `json:"truncated,omitempty"`
+		RecordedAt   string `json:"recorded_at"`
+	}
+)
+
+// Sort defines listing sort options, empty values select defaults.
+type Sort struct {
+	Field string
+	Order string
+}
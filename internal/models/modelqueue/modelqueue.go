@@ -4,12 +4,39 @@ package modelqueue
 
 import "time"
 
+// Sources of order status updates.
+const (
+	SourcePolling  = "polling"
+	SourceCallback = "callback"
+)
+
 type OrderQueueEntry struct {
+	TenantID    string
 	UserID      string
 	OrderNumber int
 	OrderStatus string
 	RetryCount  int
-	Accrual     float64
-	LastChecked time.Time
-	RetryAfter  time.Duration
+	// InvalidCount counts malformed accrual responses separately from transport errors
+	InvalidCount int
+	Accrual      float64
+	LastChecked  time.Time
+	RetryAfter   time.Duration
+	// PollStep indexes the polling interval schedule, it grows while the order status stays the same
+	PollStep int
+	Dequeued bool
+	// Abandoned marks an order whose polling was given up upon exceeding the retry limit, its status is left unchanged
+	Abandoned bool
+	// Source names the component which observed the status update, it is recorded to the order status history
+	Source string
+	// TraceParent propagates the trace of the request which queued the order to accrual polling
+	TraceParent string
+}
+
+type WithdrawalQueueEntry struct {
+	ID          uint
+	TenantID    string
+	UserID      string
+	OrderNumber string
+	Amount      float64
+	RetryCount  int
 }
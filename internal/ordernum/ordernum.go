@@ -0,0 +1,139 @@
+// Package ordernum provides parsing, Luhn validation and generation of order numbers.
+
+package ordernum
+
+import (
+	"errors"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// MaxLength is the maximum number of digits of an order number, as longer ones never fit into an int64.
+const MaxLength = 19
+
+// Order number errors.
+var (
+	ErrEmpty    = errors.New("order number is empty")
+	ErrNotDigit = errors.New("order number must consist of digits only")
+	ErrTooLong  = errors.New("order number exceeds the supported range")
+	ErrChecksum = errors.New("order number has an invalid Luhn checksum")
+)
+
+// Normalize trims surrounding whitespace of an order number and checks that the rest consists of digits only.
+func Normalize(orderNumber string) (string, error) {
+	orderNumber = strings.TrimSpace(orderNumber)
+	if orderNumber == "" {
+		return "", ErrEmpty
+	}
+	if len(orderNumber) > MaxLength {
+		return "", ErrTooLong
+	}
+	for _, r := range orderNumber {
+		if r < '0' || r > '9' {
+			return "", ErrNotDigit
+		}
+	}
+	return orderNumber, nil
+}
+
+// Parse normalizes an order number and converts it to its numeric representation.
+func Parse(orderNumber string) (int, error) {
+	orderNumber, err := Normalize(orderNumber)
+	if err != nil {
+		return 0, err
+	}
+	number, err := strconv.Atoi(orderNumber)
+	if err != nil {
+		// 19-digit numbers beyond the int64 range
+		return 0, ErrTooLong
+	}
+	return number, nil
+}
+
+// Validate normalizes an order number and verifies its Luhn checksum.
+func Validate(orderNumber string) error {
+	orderNumber, err := Normalize(orderNumber)
+	if err != nil {
+		return err
+	}
+	if checksum(orderNumber, false) != 0 {
+		return ErrChecksum
+	}
+	return nil
+}
+
+// CheckDigit returns the digit completing a string of digits to a valid Luhn number.
+func CheckDigit(digits string) int {
+	return (10 - checksum(digits, true)) % 10
+}
+
+// Generate returns a random order number of the given length with a valid Luhn checksum and no leading zero,
+// the length is clamped to [2, MaxLength-1] so that any result fits into an int64.
+func Generate(rng *rand.Rand, length int) int {
+	if length < 2 {
+		length = 2
+	}
+	if length > MaxLength-1 {
+		length = MaxLength - 1
+	}
+	digits := make([]byte, length-1)
+	digits[0] = byte('1' + rng.Intn(9))
+	for i := 1; i < len(digits); i++ {
+		digits[i] = byte('0' + rng.Intn(10))
+	}
+	number, _ := strconv.Atoi(string(digits))
+	return number*10 + CheckDigit(string(digits))
+}
+
+// GenerateWithPrefix returns a random order number starting with prefix with a valid Luhn checksum, random digits
+// fill it up to the given length, which is raised to fit the prefix along with the check digit and clamped to
+// MaxLength-1 like in Generate. Prefixes which leave no room for the check digit fail with ErrTooLong.
+func GenerateWithPrefix(rng *rand.Rand, prefix string, length int) (string, error) {
+	if prefix != "" {
+		var err error
+		prefix, err = Normalize(prefix)
+		if err != nil {
+			return "", err
+		}
+	}
+	if len(prefix) > MaxLength-2 {
+		return "", ErrTooLong
+	}
+	if length < len(prefix)+1 {
+		length = len(prefix) + 1
+	}
+	if length < 2 {
+		length = 2
+	}
+	if length > MaxLength-1 {
+		length = MaxLength - 1
+	}
+	digits := make([]byte, length-1)
+	copy(digits, prefix)
+	for i := len(prefix); i < len(digits); i++ {
+		if i == 0 {
+			digits[i] = byte('1' + rng.Intn(9))
+			continue
+		}
+		digits[i] = byte('0' + rng.Intn(10))
+	}
+	return string(digits) + strconv.Itoa(CheckDigit(string(digits))), nil
+}
+
+// checksum returns the Luhn sum modulo 10 of digits, which are doubled starting from the rightmost one
+// if the check digit is yet to be appended and from the second rightmost one otherwise.
+func checksum(digits string, withoutCheckDigit bool) int {
+	sum := 0
+	for i := 0; i < len(digits); i++ {
+		digit := int(digits[len(digits)-1-i] - '0')
+		if (i%2 == 0) == withoutCheckDigit {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+	}
+	return sum % 10
+}
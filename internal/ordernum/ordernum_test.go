@@ -0,0 +1,98 @@
+package ordernum
+
+import (
+	"errors"
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func FuzzNormalize(f *testing.F) {
+	for _, seed := range []string{"", " ", "12345678903", " 79927398713\n", "0042", "12a4", "-1", "+1", "１２３", "99999999999999999999"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, orderNumber string) {
+		normalized, err := Normalize(orderNumber)
+		if err != nil {
+			if !errors.Is(err, ErrEmpty) && !errors.Is(err, ErrNotDigit) && !errors.Is(err, ErrTooLong) {
+				t.Fatalf("Normalize(%q) returned an unexpected error: %v", orderNumber, err)
+			}
+			return
+		}
+		if normalized != strings.TrimSpace(orderNumber) {
+			t.Fatalf("Normalize(%q) = %q, expected the trimmed input", orderNumber, normalized)
+		}
+		if normalized == "" || len(normalized) > MaxLength {
+			t.Fatalf("Normalize(%q) = %q, expected between 1 and %d digits", orderNumber, normalized, MaxLength)
+		}
+		for _, r := range normalized {
+			if r < '0' || r > '9' {
+				t.Fatalf("Normalize(%q) = %q, expected digits only", orderNumber, normalized)
+			}
+		}
+		again, err := Normalize(normalized)
+		if err != nil || again != normalized {
+			t.Fatalf("Normalize is not idempotent for %q: got %q, %v", normalized, again, err)
+		}
+	})
+}
+
+func FuzzValidate(f *testing.F) {
+	f.Add("79927398713", int64(1), 16, "")
+	f.Add("79927398710", int64(2), 2, "4")
+	f.Add("", int64(3), 0, "12")
+	f.Add("abc", int64(4), 40, "123456789012345678")
+	f.Fuzz(func(t *testing.T, orderNumber string, seed int64, length int, prefix string) {
+		// arbitrary input must never panic and fail with known errors only
+		err := Validate(orderNumber)
+		if err != nil && !errors.Is(err, ErrEmpty) && !errors.Is(err, ErrNotDigit) && !errors.Is(err, ErrTooLong) && !errors.Is(err, ErrChecksum) {
+			t.Fatalf("Validate(%q) returned an unexpected error: %v", orderNumber, err)
+		}
+		rng := rand.New(rand.NewSource(seed))
+		generated := strconv.Itoa(Generate(rng, length))
+		if err := Validate(generated); err != nil {
+			t.Fatalf("Validate(Generate(%d)) = %v for %s", length, err, generated)
+		}
+		withPrefix, err := GenerateWithPrefix(rng, prefix, length)
+		if err != nil {
+			return
+		}
+		if err := Validate(withPrefix); err != nil {
+			t.Fatalf("Validate(GenerateWithPrefix(%q, %d)) = %v for %s", prefix, length, err, withPrefix)
+		}
+		if !strings.HasPrefix(withPrefix, strings.TrimSpace(prefix)) {
+			t.Fatalf("GenerateWithPrefix(%q, %d) = %s, expected the prefix", prefix, length, withPrefix)
+		}
+		if _, err := Parse(withPrefix); err != nil {
+			t.Fatalf("Parse(GenerateWithPrefix(%q, %d)) = %v for %s", prefix, length, err, withPrefix)
+		}
+	})
+}
+
+func FuzzParse(f *testing.F) {
+	for _, seed := range []string{"", "0", "007", " 12345678903 ", "9223372036854775807", "9223372036854775808", "1e5", "0x1F"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, orderNumber string) {
+		number, err := Parse(orderNumber)
+		normalized, normalizeErr := Normalize(orderNumber)
+		if normalizeErr != nil {
+			if !errors.Is(err, normalizeErr) {
+				t.Fatalf("Parse(%q) = %v, Normalize failed with %v", orderNumber, err, normalizeErr)
+			}
+			return
+		}
+		expected, rangeErr := strconv.ParseInt(normalized, 10, 64)
+		if rangeErr != nil {
+			// digits only, so the only possible failure is the int64 range
+			if !errors.Is(err, ErrTooLong) {
+				t.Fatalf("Parse(%q) = %d, %v, expected ErrTooLong", orderNumber, number, err)
+			}
+			return
+		}
+		if err != nil || int64(number) != expected {
+			t.Fatalf("Parse(%q) = %d, %v, expected %d as normalized to %q", orderNumber, number, err, expected, normalized)
+		}
+	})
+}
@@ -0,0 +1,166 @@
+package outbox
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// natsPublisher publishes messages over the NATS client protocol, a batch is confirmed by a PING/PONG round trip
+// as the server processes commands of a connection in order.
+type natsPublisher struct {
+	addr   string
+	user   *url.Userinfo
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// newNATSPublisher parses a nats://[user[:password]@]host[:port] URL, the connection is established lazily.
+func newNATSPublisher(rawURL string) (*natsPublisher, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "nats" || u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid NATS URL %q, expected nats://host:port", rawURL)
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), "4222")
+	}
+	return &natsPublisher{addr: addr, user: u.User}, nil
+}
+
+// Publish sends messages and waits for the server to confirm them, the connection is dropped upon any failure
+// and re-established by the next call.
+func (p *natsPublisher) Publish(ctx context.Context, messages []Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.conn == nil {
+		err := p.connect(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	err := p.publish(ctx, messages)
+	if err != nil {
+		p.conn.Close()
+		p.conn = nil
+	}
+	return err
+}
+
+// connect dials the server and performs the protocol handshake.
+func (p *natsPublisher) connect(ctx context.Context) error {
+	dialer := net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", p.addr)
+	if err != nil {
+		return err
+	}
+	p.conn, p.reader = conn, bufio.NewReader(conn)
+	err = p.handshake(ctx)
+	if err != nil {
+		conn.Close()
+		p.conn = nil
+		return err
+	}
+	return nil
+}
+
+// handshake reads the server INFO and sends CONNECT with optional credentials.
+func (p *natsPublisher) handshake(ctx context.Context) error {
+	p.setDeadline(ctx)
+	line, err := p.reader.ReadString('\n')
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(line, "INFO") {
+		return fmt.Errorf("unexpected NATS greeting %q", strings.TrimSpace(line))
+	}
+	options := map[string]interface{}{"verbose": false, "pedantic": false, "name": "gophermart", "lang": "go"}
+	if p.user != nil {
+		if password, ok := p.user.Password(); ok {
+			options["user"], options["pass"] = p.user.Username(), password
+		} else {
+			options["auth_token"] = p.user.Username()
+		}
+	}
+	body, err := json.Marshal(options)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(p.conn, "CONNECT %s\r\nPING\r\n", body)
+	if err != nil {
+		return err
+	}
+	return p.awaitPong()
+}
+
+// publish writes all messages followed by PING, the PONG reply means the preceding messages were processed.
+func (p *natsPublisher) publish(ctx context.Context, messages []Message) error {
+	p.setDeadline(ctx)
+	writer := bufio.NewWriter(p.conn)
+	for _, message := range messages {
+		_, err := fmt.Fprintf(writer, "PUB %s %d\r\n", message.Subject, len(message.Payload))
+		if err != nil {
+			return err
+		}
+		_, err = writer.Write(message.Payload)
+		if err != nil {
+			return err
+		}
+		_, err = writer.WriteString("\r\n")
+		if err != nil {
+			return err
+		}
+	}
+	_, err := writer.WriteString("PING\r\n")
+	if err != nil {
+		return err
+	}
+	err = writer.Flush()
+	if err != nil {
+		return err
+	}
+	return p.awaitPong()
+}
+
+// awaitPong reads server commands until PONG answering server pings, errors reported by the server fail the call.
+func (p *natsPublisher) awaitPong() error {
+	for {
+		line, err := p.reader.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			_, err = p.conn.Write([]byte("PONG\r\n"))
+			if err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return errors.New("NATS server error: " + strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "-ERR")), "'"))
+		}
+		// INFO updates and +OK acknowledgements are ignored
+	}
+}
+
+// setDeadline bounds connection I/O by the context deadline.
+func (p *natsPublisher) setDeadline(ctx context.Context) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Time{}
+	}
+	p.conn.SetDeadline(deadline)
+}
@@ -0,0 +1,124 @@
+// Package outbox provides relaying of committed domain events to a message bus.
+//
+// Events are recorded to the outbox table within the transactions producing them and published in batches,
+// the relay offset is advanced only once a batch is confirmed by the bus, so delivery is at-least-once
+// and consumers are expected to deduplicate events by their ID.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/config"
+	"github.com/danilovkiri/dk-go-gophermart/internal/metrics"
+	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/modelstorage"
+	"github.com/rs/zerolog"
+)
+
+// Store defines a set of methods for types implementing Store.
+type Store interface {
+	GetOutboxEvents(ctx context.Context, relay string, limit int) ([]modelstorage.OutboxEventStorageEntry, error)
+	CommitOutboxOffset(ctx context.Context, relay string, txID, id int64) error
+}
+
+// Publisher defines a set of methods for types implementing Publisher.
+type Publisher interface {
+	// Publish returns once all messages are accepted by the bus
+	Publish(ctx context.Context, messages []Message) error
+}
+
+// Message defines a single message sent to the bus.
+type Message struct {
+	Subject string
+	Payload []byte
+}
+
+// envelope defines the published representation of a domain event.
+type envelope struct {
+	ID         int64           `json:"id"`
+	Kind       string          `json:"kind"`
+	TenantID   string          `json:"tenant_id"`
+	OccurredAt string          `json:"occurred_at"`
+	Data       json.RawMessage `json:"data"`
+}
+
+// Relay defines attributes of a struct available to its methods.
+type Relay struct {
+	cfg       *config.OutboxConfig
+	log       *zerolog.Logger
+	metrics   *metrics.Registry
+	store     Store
+	publisher Publisher
+}
+
+// InitRelay initializes an outbox relay publishing to the configured bus.
+func InitRelay(cfg *config.OutboxConfig, log *zerolog.Logger, reg *metrics.Registry, store Store) (*Relay, error) {
+	var publisher Publisher
+	switch cfg.Bus {
+	case "nats":
+		natsPublisher, err := newNATSPublisher(cfg.BusURL)
+		if err != nil {
+			return nil, err
+		}
+		publisher = natsPublisher
+	default:
+		return nil, fmt.Errorf("unknown outbox bus %q", cfg.Bus)
+	}
+	return &Relay{
+		cfg:       cfg,
+		log:       log,
+		metrics:   reg,
+		store:     store,
+		publisher: publisher,
+	}, nil
+}
+
+// Run publishes pending events batch by batch until none are left, the offset of each published batch is committed
+// before the next one is read.
+func (r *Relay) Run(ctx context.Context) error {
+	for {
+		events, err := r.store.GetOutboxEvents(ctx, r.cfg.Bus, r.cfg.BatchSize)
+		if err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			return nil
+		}
+		messages := make([]Message, 0, len(events))
+		for _, event := range events {
+			payload, err := json.Marshal(envelope{
+				ID:         event.ID,
+				Kind:       event.Kind,
+				TenantID:   event.TenantID,
+				OccurredAt: event.CreatedAt.Format(time.RFC3339),
+				Data:       json.RawMessage(event.Payload),
+			})
+			if err != nil {
+				return err
+			}
+			messages = append(messages, Message{Subject: r.cfg.SubjectPrefix + event.Kind, Payload: payload})
+		}
+		ctxTO, cancel := context.WithTimeout(ctx, r.cfg.PublishTimeout)
+		err = r.publisher.Publish(ctxTO, messages)
+		cancel()
+		if err != nil {
+			r.metrics.Counter("gophermart_outbox_publish_failures_total").Inc()
+			return err
+		}
+		for _, event := range events {
+			r.metrics.Counter("gophermart_outbox_published_total", "kind", event.Kind).Inc()
+		}
+		last := events[len(events)-1]
+		err = r.store.CommitOutboxOffset(ctx, r.cfg.Bus, last.TxID, last.ID)
+		if err != nil {
+			// the batch is published again upon the next run
+			return err
+		}
+		r.log.Info().Msg(fmt.Sprintf("relayed %v outbox events up to event %v", len(events), last.ID))
+		if len(events) < r.cfg.BatchSize {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,107 @@
+// Package report provides scheduled activity reports sent to admins.
+//
+// A report covers the period preceding its run: registrations, uploaded orders by their current status,
+// abandoned orders which are still not final, and points accrued and withdrawn. It is sent through the notifier
+// subsystem to every configured recipient.
+package report
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/config"
+	"github.com/danilovkiri/dk-go-gophermart/internal/metrics"
+	"github.com/danilovkiri/dk-go-gophermart/internal/models/modeldto"
+	"github.com/danilovkiri/dk-go-gophermart/internal/service/notifier/v1"
+	"github.com/rs/zerolog"
+)
+
+// NotificationAdminReport is the kind of report notifications.
+const NotificationAdminReport = "admin_report"
+
+// periods maps report periods to the time span covered by a report.
+var periods = map[string]time.Duration{
+	"daily":  24 * time.Hour,
+	"weekly": 7 * 24 * time.Hour,
+}
+
+// Store defines a set of methods for types implementing Store.
+type Store interface {
+	GetReport(ctx context.Context, from, to time.Time) (*modeldto.AdminReport, error)
+}
+
+// Reporter defines attributes of a struct available to its methods.
+type Reporter struct {
+	cfg      *config.ReportConfig
+	log      *zerolog.Logger
+	metrics  *metrics.Registry
+	store    Store
+	notifier notifier.Notifier
+}
+
+// InitReporter initializes a reporter sending reports through target.
+func InitReporter(cfg *config.ReportConfig, log *zerolog.Logger, reg *metrics.Registry, store Store, target notifier.Notifier) *Reporter {
+	return &Reporter{
+		cfg:      cfg,
+		log:      log,
+		metrics:  reg,
+		store:    store,
+		notifier: target,
+	}
+}
+
+// Interval returns the time span covered by a report, reports are to be sent at the same interval. It is zero
+// if reports are disabled.
+func (r *Reporter) Interval() time.Duration {
+	return periods[r.cfg.Period]
+}
+
+// Run aggregates the report of the period preceding the call and sends it to every recipient, a failure to reach
+// one recipient does not stop the others and is reported once all of them are tried.
+func (r *Reporter) Run(ctx context.Context) error {
+	to := time.Now()
+	report, err := r.store.GetReport(ctx, to.Add(-r.Interval()), to)
+	if err != nil {
+		return err
+	}
+	report.Period = r.cfg.Period
+	message := formatReport(report)
+	var failed []string
+	for _, recipient := range r.cfg.Recipients {
+		err = r.notifier.Notify(ctx, modeldto.Notification{Kind: NotificationAdminReport, Message: message, Email: recipient})
+		if err != nil {
+			r.log.Error().Err(err).Msg(fmt.Sprintf("could not send %s report to %s", r.cfg.Period, recipient))
+			failed = append(failed, recipient)
+		}
+	}
+	r.metrics.Counter("gophermart_admin_reports_total", "period", r.cfg.Period).Inc()
+	if len(failed) > 0 {
+		return fmt.Errorf("%s report was not sent to %s", r.cfg.Period, strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// formatReport renders a report as plain text.
+func formatReport(report *modeldto.AdminReport) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Gophermart %s report from %s to %s\n\n", report.Period, report.From, report.To)
+	fmt.Fprintf(&b, "Registrations: %d\n", report.Registrations)
+	statuses := make([]string, 0, len(report.Orders))
+	total := 0
+	for status, count := range report.Orders {
+		statuses = append(statuses, status)
+		total += count
+	}
+	sort.Strings(statuses)
+	fmt.Fprintf(&b, "Orders uploaded: %d\n", total)
+	for _, status := range statuses {
+		fmt.Fprintf(&b, "  %s: %d\n", status, report.Orders[status])
+	}
+	fmt.Fprintf(&b, "Abandoned orders: %d\n", report.Abandoned)
+	fmt.Fprintf(&b, "Points accrued: %.2f\n", report.Accrued)
+	fmt.Fprintf(&b, "Points withdrawn: %.2f\n", report.Withdrawn)
+	return b.String()
+}
@@ -0,0 +1,79 @@
+// Package restart provides zero-downtime restarts by handing the listening socket over to a new process.
+
+package restart
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// Environment variables used to pass the inherited socket and the parent process to a child.
+const (
+	envListenFD  = "GOPHERMART_LISTEN_FD"
+	envParentPID = "GOPHERMART_PARENT_PID"
+)
+
+// inheritedFD is the descriptor of the first entry of exec.Cmd.ExtraFiles in a child process.
+const inheritedFD = 3
+
+// Listen returns a listener inherited from the parent process if any, otherwise it opens a new one.
+func Listen(address string) (net.Listener, error) {
+	fdValue := os.Getenv(envListenFD)
+	if fdValue == "" {
+		return net.Listen("tcp", address)
+	}
+	fd, err := strconv.Atoi(fdValue)
+	if err != nil {
+		return nil, fmt.Errorf("invalid inherited listener descriptor %q: %w", fdValue, err)
+	}
+	file := os.NewFile(uintptr(fd), "listener")
+	defer file.Close()
+	return net.FileListener(file)
+}
+
+// Ready notifies the parent process, if any, that the child is serving so that the parent can drain and exit.
+func Ready() error {
+	pidValue := os.Getenv(envParentPID)
+	if pidValue == "" {
+		return nil
+	}
+	pid, err := strconv.Atoi(pidValue)
+	if err != nil {
+		return fmt.Errorf("invalid parent process ID %q: %w", pidValue, err)
+	}
+	return syscall.Kill(pid, syscall.SIGTERM)
+}
+
+// Spawn starts a new instance of the current binary which inherits the listening socket.
+func Spawn(ln net.Listener) (*os.Process, error) {
+	tcpListener, ok := ln.(*net.TCPListener)
+	if !ok {
+		return nil, errors.New("only TCP listeners can be handed over")
+	}
+	file, err := tcpListener.File()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	env := make([]string, 0, len(os.Environ())+2)
+	for _, value := range os.Environ() {
+		if strings.HasPrefix(value, envListenFD+"=") || strings.HasPrefix(value, envParentPID+"=") {
+			continue
+		}
+		env = append(env, value)
+	}
+	env = append(env, fmt.Sprintf("%s=%d", envListenFD, inheritedFD), fmt.Sprintf("%s=%d", envParentPID, os.Getpid()))
+	return os.StartProcess(executable, os.Args, &os.ProcAttr{
+		Env:   env,
+		Files: []*os.File{os.Stdin, os.Stdout, os.Stderr, file},
+	})
+}
@@ -0,0 +1,134 @@
+// Package scheduler provides periodic execution of background jobs.
+//
+// Each job runs on its own jittered interval so that instances started together do not hit the DB in lockstep.
+// Exclusive jobs are additionally guarded by a distributed lock so that only one instance of a multi-instance
+// deployment runs them at a time, the other instances skip the tick.
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/config"
+	"github.com/danilovkiri/dk-go-gophermart/internal/metrics"
+	"github.com/rs/zerolog"
+)
+
+// Locker defines a set of methods for types implementing Locker.
+type Locker interface {
+	TryLock(ctx context.Context, name string) (unlock func(), acquired bool, err error)
+}
+
+// Job defines a periodically executed task.
+type Job struct {
+	Name     string
+	Interval time.Duration
+	// Exclusive jobs run on a single instance at a time
+	Exclusive bool
+	Run       func(ctx context.Context) error
+}
+
+// Scheduler defines attributes of a struct available to its methods.
+type Scheduler struct {
+	ctx     context.Context
+	cfg     *config.SchedulerConfig
+	log     *zerolog.Logger
+	wg      *sync.WaitGroup
+	metrics *metrics.Registry
+	locker  Locker
+	jobs    []Job
+}
+
+// InitScheduler initializes a scheduler, exclusive jobs run unguarded if locker is nil.
+func InitScheduler(ctx context.Context, cfg *config.SchedulerConfig, log *zerolog.Logger, wg *sync.WaitGroup, reg *metrics.Registry, locker Locker) *Scheduler {
+	return &Scheduler{
+		ctx:     ctx,
+		cfg:     cfg,
+		log:     log,
+		wg:      wg,
+		metrics: reg,
+		locker:  locker,
+	}
+}
+
+// Register adds a job, jobs with a non-positive interval are disabled.
+func (s *Scheduler) Register(job Job) {
+	if job.Interval <= 0 {
+		s.log.Info().Msg(fmt.Sprintf("scheduled job %s is disabled", job.Name))
+		return
+	}
+	s.jobs = append(s.jobs, job)
+}
+
+// ListenAndRun starts all registered jobs, they stop once the context is cancelled and a running job is awaited.
+func (s *Scheduler) ListenAndRun() {
+	for _, job := range s.jobs {
+		job := job
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.loop(job)
+		}()
+	}
+}
+
+// loop runs a single job until the context is cancelled.
+func (s *Scheduler) loop(job Job) {
+	s.log.Info().Msg(fmt.Sprintf("started scheduled job %s", job.Name))
+	timer := time.NewTimer(s.jitter(job.Interval))
+	defer timer.Stop()
+	for {
+		select {
+		case <-s.ctx.Done():
+			s.log.Info().Msg(fmt.Sprintf("stopped scheduled job %s", job.Name))
+			return
+		case <-timer.C:
+		}
+		s.run(job)
+		timer.Reset(s.jitter(job.Interval))
+	}
+}
+
+// run executes a job once recording its outcome.
+func (s *Scheduler) run(job Job) {
+	if job.Exclusive && s.locker != nil {
+		unlock, acquired, err := s.locker.TryLock(s.ctx, "scheduler:"+job.Name)
+		if err != nil {
+			s.log.Warn().Err(err).Msg(fmt.Sprintf("could not acquire lock for scheduled job %s", job.Name))
+			s.metrics.Counter("gophermart_scheduler_runs_total", "job", job.Name, "result", "error").Inc()
+			return
+		}
+		if !acquired {
+			// another instance is running the job
+			s.metrics.Counter("gophermart_scheduler_runs_total", "job", job.Name, "result", "skipped").Inc()
+			return
+		}
+		defer unlock()
+	}
+	start := time.Now()
+	err := job.Run(s.ctx)
+	s.metrics.Gauge("gophermart_scheduler_last_duration_ms", "job", job.Name).Set(time.Since(start).Milliseconds())
+	if err != nil && s.ctx.Err() != nil {
+		// the job was interrupted by shutdown
+		return
+	}
+	if err != nil {
+		s.log.Warn().Err(err).Msg(fmt.Sprintf("scheduled job %s failed", job.Name))
+		s.metrics.Counter("gophermart_scheduler_runs_total", "job", job.Name, "result", "error").Inc()
+		return
+	}
+	s.metrics.Counter("gophermart_scheduler_runs_total", "job", job.Name, "result", "ok").Inc()
+	s.metrics.Gauge("gophermart_scheduler_last_success_timestamp", "job", job.Name).Set(time.Now().Unix())
+}
+
+// jitter spreads an interval uniformly by the configured fraction in both directions.
+func (s *Scheduler) jitter(interval time.Duration) time.Duration {
+	spread := float64(interval) * s.cfg.Jitter
+	if spread <= 0 {
+		return interval
+	}
+	return interval + time.Duration((rand.Float64()*2-1)*spread)
+}
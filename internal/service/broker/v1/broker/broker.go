@@ -5,19 +5,43 @@ package broker
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
 	"sync"
 	"time"
 
-	"github.com/danilovkiri/dk-go-gophermart/internal/client"
+	"github.com/danilovkiri/dk-go-gophermart/internal/metrics"
 	"github.com/danilovkiri/dk-go-gophermart/internal/models/modeldto"
 	"github.com/danilovkiri/dk-go-gophermart/internal/models/modelqueue"
+	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/modelstorage"
+	"github.com/danilovkiri/dk-go-gophermart/internal/tenant"
+	"github.com/danilovkiri/dk-go-gophermart/internal/tracing"
+	"github.com/go-resty/resty/v2"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"golang.org/x/sync/errgroup"
 )
 
+// AccrualProvider defines a set of methods for types querying order accruals.
+type AccrualProvider interface {
+	GetAccrual(ctx context.Context, orderNumber int) (*resty.Response, error)
+}
+
+// QueueStore defines a set of methods for types tracking queued orders outside of memory.
+type QueueStore interface {
+	TakeResolved(orderNumber int) bool
+	SaveRetryState(ctx context.Context, record modelqueue.OrderQueueEntry) error
+	SaveAccrualResponse(ctx context.Context, response modelstorage.AccrualResponseStorageEntry) error
+}
+
+// maxRecordedBody limits the size of recorded accrual responses.
+const maxRecordedBody = 64 << 10
+
+// maxPostponePause bounds the time a worker holds an order which is not due yet before putting it back to queue,
+// a queue of waiting orders is thus cycled at a bounded rate without spinning or stalling due orders behind them.
+const maxPostponePause = 100 * time.Millisecond
+
 // Broker defines attributes of a struct available to its methods.
 type Broker struct {
 	ctx           context.Context
@@ -25,9 +49,13 @@ type Broker struct {
 	queueIn       chan modelqueue.OrderQueueEntry
 	queueOut      chan modelqueue.OrderQueueEntry
 	wg            *sync.WaitGroup
-	accrualClient *client.Client
+	accrualClient AccrualProvider
+	store         QueueStore
 	workerNumber  int
 	retryNumber   int
+	unknownDelay  time.Duration
+	pollIntervals []time.Duration
+	metrics       *metrics.Registry
 }
 
 // GetAccrualWorker defines attributes of a struct available to its methods.
@@ -37,12 +65,16 @@ type GetAccrualWorker struct {
 	log           *zerolog.Logger
 	queueIn       chan modelqueue.OrderQueueEntry
 	queueOut      chan modelqueue.OrderQueueEntry
-	accrualClient *client.Client
+	accrualClient AccrualProvider
+	store         QueueStore
 	retryNumber   int
+	unknownDelay  time.Duration
+	pollIntervals []time.Duration
+	metrics       *metrics.Registry
 }
 
 // InitBroker initializes a queue management service.
-func InitBroker(ctx context.Context, queueIn chan modelqueue.OrderQueueEntry, queueOut chan modelqueue.OrderQueueEntry, log *zerolog.Logger, wg *sync.WaitGroup, accrualClient *client.Client, nWorkers int, nRetries int) *Broker {
+func InitBroker(ctx context.Context, queueIn chan modelqueue.OrderQueueEntry, queueOut chan modelqueue.OrderQueueEntry, log *zerolog.Logger, wg *sync.WaitGroup, accrualClient AccrualProvider, store QueueStore, nWorkers int, nRetries int, unknownDelay time.Duration, pollIntervals []time.Duration, reg *metrics.Registry) *Broker {
 	broker := Broker{
 		ctx:           ctx,
 		log:           log,
@@ -50,8 +82,12 @@ func InitBroker(ctx context.Context, queueIn chan modelqueue.OrderQueueEntry, qu
 		queueOut:      queueOut,
 		wg:            wg,
 		accrualClient: accrualClient,
+		store:         store,
 		workerNumber:  nWorkers,
 		retryNumber:   nRetries,
+		unknownDelay:  unknownDelay,
+		pollIntervals: pollIntervals,
+		metrics:       reg,
 	}
 	return &broker
 }
@@ -64,7 +100,8 @@ func (b *Broker) ListenAndProcess() {
 		defer b.wg.Done()
 		g, _ := errgroup.WithContext(b.ctx)
 		for i := 0; i < b.workerNumber+1; i++ {
-			w := &GetAccrualWorker{ID: i, ctx: b.ctx, queueIn: b.queueIn, queueOut: b.queueOut, log: b.log, accrualClient: b.accrualClient, retryNumber: b.retryNumber}
+			workerLog := b.log.With().Int("worker_id", i).Logger()
+			w := &GetAccrualWorker{ID: i, ctx: b.ctx, queueIn: b.queueIn, queueOut: b.queueOut, log: &workerLog, accrualClient: b.accrualClient, store: b.store, retryNumber: b.retryNumber, unknownDelay: b.unknownDelay, pollIntervals: b.pollIntervals, metrics: b.metrics}
 			g.Go(w.processAsync)
 		}
 		<-b.ctx.Done()
@@ -82,102 +119,261 @@ func (b *Broker) ListenAndProcess() {
 
 // processAsync processes data from queue and manages its usage.
 func (w *GetAccrualWorker) processAsync() error {
+	w.log.Info().Msg("accrual worker started")
+	defer w.log.Info().Msg("accrual worker stopped")
+	inProgress := w.metrics.Gauge(metrics.OrderQueueInProgress)
+	currentOrder := w.metrics.Gauge("gophermart_accrual_worker_current_order", "worker", strconv.Itoa(w.ID))
 	for record := range w.queueIn {
-		// check retry-after timeout, if nonzero and not finished - put back to queue
-		if record.RetryAfter != 0 && time.Since(record.LastChecked) < record.RetryAfter {
-			w.queueIn <- record
-			continue
+		inProgress.Add(1)
+		currentOrder.Set(int64(record.OrderNumber))
+		stop := w.handle(record)
+		currentOrder.Set(0)
+		inProgress.Add(-1)
+		if stop {
+			return nil
 		}
+	}
+	return nil
+}
 
-		// wait for at least 10 seconds before querying the same order again
-		// stop waiting upon ctx.Done()
-		for time.Since(record.LastChecked) < 10*time.Second {
-			select {
-			case <-w.ctx.Done():
-				return nil
-			default:
+// getAccrual queries the accrual service within a span joining the trace of the request which queued the order.
+func (w *GetAccrualWorker) getAccrual(record modelqueue.OrderQueueEntry) (*resty.Response, error) {
+	ctx, span := tracing.StartFromTraceParent(tenant.WithTenant(w.ctx, record.TenantID), record.TraceParent, "broker.PollAccrual",
+		tracing.AttrOrderNumber.String(strconv.Itoa(record.OrderNumber)),
+		tracing.AttrTenantID.String(record.TenantID),
+		tracing.UserIDHash(record.UserID),
+		tracing.AttrRetryCount.Int(record.RetryCount),
+	)
+	defer span.End()
+	resp, err := w.accrualClient.GetAccrual(ctx, record.OrderNumber)
+	tracing.RecordError(span, err)
+	return resp, err
+}
 
-			}
-		}
+// handle processes a single order entry retrieved from queue, it returns true if processing must be stopped.
+func (w *GetAccrualWorker) handle(record modelqueue.OrderQueueEntry) bool {
+	// orders which are not due yet, either because of a retry-after timeout or the current polling interval,
+	// are put back to queue so that the worker is free to take due ones
+	remaining := w.pollInterval(record.PollStep) - time.Since(record.LastChecked)
+	if record.RetryAfter != 0 && record.RetryAfter-time.Since(record.LastChecked) > remaining {
+		remaining = record.RetryAfter - time.Since(record.LastChecked)
+	}
+	if remaining > 0 {
+		return w.postpone(record, remaining)
+	}
 
-		// retrieve status and accrual updates via client
-		statusMap := map[string]string{
-			"INVALID":    "INVALID",
-			"PROCESSED":  "PROCESSED",
-			"PROCESSING": "PROCESSING",
-			"REGISTERED": "NEW",
-		}
-		resp, err := w.accrualClient.GetAccrual(w.ctx, record.OrderNumber)
-		if err != nil || (resp != nil && (resp.StatusCode() != 429 && resp.StatusCode() != 200)) {
-			if record.RetryCount >= w.retryNumber {
-				// abandon processing if w.retryNumber retries were unsuccessfully performed
-				w.log.Warn().Msg(fmt.Sprintf("WID %v, order %v — abandoning due to retry limit exceeding", w.ID, record.OrderNumber))
-				finalRecord := modelqueue.OrderQueueEntry{
-					UserID:      record.UserID,
-					OrderNumber: record.OrderNumber,
-					OrderStatus: record.OrderStatus,
-					Accrual:     record.Accrual,
-				}
-				w.queueOut <- finalRecord
-				continue
-			} else {
-				// put back to queue if querying resulted in error, increment RetryCount, set LastChecked to time.Now()
-				w.log.Warn().Msg(fmt.Sprintf("WID %v, order %v — could not process, sending back to queue", w.ID, record.OrderNumber))
-				record.RetryCount += 1
-				record.LastChecked = time.Now()
-				w.queueIn <- record
-				continue
-			}
-		}
+	// drop orders whose final status was pushed via callback
+	if w.dropResolved(record) {
+		return false
+	}
 
-		if resp.StatusCode() == 429 {
-			seconds, _ := strconv.Atoi(resp.Header().Get("Retry-After"))
-			w.log.Warn().Msg(fmt.Sprintf("WID %v, order %v — request delay by %v, sending back to queue", w.ID, record.OrderNumber, seconds))
-			retryAfter := time.Duration(int(time.Second) * seconds)
-			record.LastChecked = time.Now()
-			record.RetryAfter = retryAfter
-			w.queueIn <- record
-			continue
+	// retrieve status and accrual updates via client
+	statusMap := map[string]string{
+		"INVALID":    "INVALID",
+		"PROCESSED":  "PROCESSED",
+		"PROCESSING": "PROCESSING",
+		"REGISTERED": "NEW",
+	}
+	resp, err := w.getAccrual(record)
+	if err == nil {
+		w.recordResponse(record, resp)
+	}
+	// the order is not registered in the accrual service yet, wait longer without spending retries
+	if err == nil && (resp.StatusCode() == 204 || resp.StatusCode() == 404) {
+		w.log.Info().Msg(fmt.Sprintf("WID %v, order %v — not registered yet, delaying by %v", w.ID, record.OrderNumber, w.unknownDelay))
+		record.LastChecked = time.Now()
+		record.RetryAfter = w.unknownDelay
+		w.requeue(record)
+		return false
+	}
+	if err != nil || (resp != nil && (resp.StatusCode() != 429 && resp.StatusCode() != 200)) {
+		w.metrics.Counter("gophermart_accrual_worker_failed_total", "worker", strconv.Itoa(w.ID)).Inc()
+		if record.RetryCount >= w.retryNumber {
+			// abandon processing if w.retryNumber retries were unsuccessfully performed
+			w.abandon(record)
+			return false
 		}
+		// put back to queue if querying resulted in error, increment RetryCount, set LastChecked to time.Now()
+		w.log.Warn().Msg(fmt.Sprintf("WID %v, order %v — could not process, sending back to queue", w.ID, record.OrderNumber))
+		record.RetryCount += 1
+		record.LastChecked = time.Now()
+		w.requeue(record)
+		return false
+	}
 
-		var accrualResponse modeldto.AccrualResponse
-		err = json.Unmarshal(resp.Body(), &accrualResponse)
-		if err != nil {
-			w.log.Err(err).Msg(fmt.Sprintf("WID %v, order %v — could not parse response body", w.ID, record.OrderNumber))
-			// put back to queue if querying resulted in error, increment RetryCount, set LastChecked to time.Now()
-			w.log.Warn().Msg(fmt.Sprintf("WID %v, order %v — could not process, sending back to queue", w.ID, record.OrderNumber))
-			record.RetryCount += 1
-			record.LastChecked = time.Now()
-			record.RetryAfter = 0
-			w.queueIn <- record
-			continue
+	if resp.StatusCode() == 429 {
+		seconds, _ := strconv.Atoi(resp.Header().Get("Retry-After"))
+		w.log.Warn().Msg(fmt.Sprintf("WID %v, order %v — request delay by %v, sending back to queue", w.ID, record.OrderNumber, seconds))
+		retryAfter := time.Duration(int(time.Second) * seconds)
+		record.LastChecked = time.Now()
+		record.RetryAfter = retryAfter
+		w.requeue(record)
+		return false
+	}
+
+	var accrualResponse modeldto.AccrualResponse
+	err = json.Unmarshal(resp.Body(), &accrualResponse)
+	if err == nil {
+		err = validateAccrualResponse(accrualResponse, record.OrderNumber)
+	}
+	if err != nil {
+		w.log.Err(err).Str("body", string(resp.Body())).Msg(fmt.Sprintf("WID %v, order %v — invalid response body", w.ID, record.OrderNumber))
+		w.metrics.Counter("gophermart_accrual_invalid_responses_total").Inc()
+		w.metrics.Counter("gophermart_accrual_worker_failed_total", "worker", strconv.Itoa(w.ID)).Inc()
+		if record.InvalidCount >= w.retryNumber {
+			// abandon processing if w.retryNumber invalid responses were received
+			w.abandon(record)
+			return false
 		}
-		newStatus := statusMap[accrualResponse.OrderStatus]
-		newAccrual := accrualResponse.Accrual
-		// put back to queue if no updates were found, set LastChecked to time.Now()
-		if newStatus == record.OrderStatus {
-			w.log.Info().Msg(fmt.Sprintf("WID %v, order %v — no updates, sending back to queue", w.ID, record.OrderNumber))
-			record.LastChecked = time.Now()
-			record.RetryAfter = 0
-			w.queueIn <- record
-		} else {
-			// if status update was found, send for DB update
-			w.log.Info().Msg(fmt.Sprintf("WID %v, order %v — updated, sending to DB", w.ID, record.OrderNumber))
-			finalRecord := modelqueue.OrderQueueEntry{
-				UserID:      record.UserID,
-				OrderNumber: record.OrderNumber,
-				OrderStatus: newStatus,
-				Accrual:     newAccrual,
-			}
-			w.queueOut <- finalRecord
-			// if status update is not final, put back to queue, set LastChecked to time.Now()
-			if newStatus != "PROCESSED" && newStatus != "INVALID" {
-				w.log.Info().Msg(fmt.Sprintf("WID %v, order %v — update is not final, sending back to queue", w.ID, record.OrderNumber))
-				record.LastChecked = time.Now()
-				record.RetryAfter = 0
-				w.queueIn <- record
-			}
+		// put back to queue if the response was invalid, increment InvalidCount, set LastChecked to time.Now()
+		w.log.Warn().Msg(fmt.Sprintf("WID %v, order %v — could not process, sending back to queue", w.ID, record.OrderNumber))
+		record.InvalidCount += 1
+		record.LastChecked = time.Now()
+		record.RetryAfter = 0
+		w.requeue(record)
+		return false
+	}
+	w.metrics.Counter("gophermart_accrual_worker_processed_total", "worker", strconv.Itoa(w.ID)).Inc()
+	newStatus := statusMap[accrualResponse.OrderStatus]
+	newAccrual := accrualResponse.Accrual
+	// put back to queue if no updates were found, set LastChecked to time.Now() and poll less often
+	if newStatus == record.OrderStatus {
+		w.log.Info().Msg(fmt.Sprintf("WID %v, order %v — no updates, sending back to queue", w.ID, record.OrderNumber))
+		record.LastChecked = time.Now()
+		record.RetryAfter = 0
+		if record.PollStep < len(w.pollIntervals)-1 {
+			record.PollStep += 1
 		}
+		w.requeue(record)
+		return false
+	}
+	// the callback might have arrived while querying
+	if w.dropResolved(record) {
+		return false
+	}
+	// if status update was found, send for DB update
+	w.log.Info().Msg(fmt.Sprintf("WID %v, order %v — updated, sending to DB", w.ID, record.OrderNumber))
+	finalRecord := modelqueue.OrderQueueEntry{
+		TenantID:    record.TenantID,
+		UserID:      record.UserID,
+		OrderNumber: record.OrderNumber,
+		OrderStatus: newStatus,
+		Accrual:     newAccrual,
+		Source:      modelqueue.SourcePolling,
+	}
+	finalRecord.Dequeued = newStatus == "PROCESSED" || newStatus == "INVALID"
+	w.queueOut <- finalRecord
+	// if status update is not final, put back to queue, set LastChecked to time.Now() and reset polling interval
+	if !finalRecord.Dequeued {
+		w.log.Info().Msg(fmt.Sprintf("WID %v, order %v — update is not final, sending back to queue", w.ID, record.OrderNumber))
+		record.OrderStatus = newStatus
+		record.LastChecked = time.Now()
+		record.RetryAfter = 0
+		record.PollStep = 0
+		w.requeue(record)
+		return false
+	}
+	w.metrics.Gauge(metrics.OrderQueueSize).Add(-1)
+	return false
+}
+
+// dropResolved removes an order resolved via callback from processing, it returns true if the order was dropped.
+func (w *GetAccrualWorker) dropResolved(record modelqueue.OrderQueueEntry) bool {
+	if !w.store.TakeResolved(record.OrderNumber) {
+		return false
+	}
+	w.log.Info().Msg(fmt.Sprintf("WID %v, order %v — resolved via callback, stopped polling", w.ID, record.OrderNumber))
+	w.metrics.Gauge(metrics.OrderQueueSize).Add(-1)
+	return true
+}
+
+// abandon stops processing an order leaving its status unchanged, the order is marked abandoned in storage so that
+// stalled order rescans do not put it back to queue.
+func (w *GetAccrualWorker) abandon(record modelqueue.OrderQueueEntry) {
+	w.log.Warn().Msg(fmt.Sprintf("WID %v, order %v — abandoning due to retry limit exceeding", w.ID, record.OrderNumber))
+	finalRecord := modelqueue.OrderQueueEntry{
+		TenantID:    record.TenantID,
+		UserID:      record.UserID,
+		OrderNumber: record.OrderNumber,
+		OrderStatus: record.OrderStatus,
+		Accrual:     record.Accrual,
+		Dequeued:    true,
+		Abandoned:   true,
+		Source:      modelqueue.SourcePolling,
+	}
+	w.queueOut <- finalRecord
+	w.metrics.Gauge(metrics.OrderQueueSize).Add(-1)
+}
+
+// validateAccrualResponse checks that an accrual response echoes the requested order and carries sane values.
+func validateAccrualResponse(resp modeldto.AccrualResponse, orderNumber int) error {
+	if resp.OrderNumber != strconv.Itoa(orderNumber) {
+		return fmt.Errorf("order number %q does not match the requested one", resp.OrderNumber)
+	}
+	switch resp.OrderStatus {
+	case "REGISTERED", "INVALID", "PROCESSING", "PROCESSED":
+	default:
+		return fmt.Errorf("unknown order status %q", resp.OrderStatus)
+	}
+	if resp.Accrual < 0 {
+		return errors.New("accrual must not be negative")
 	}
 	return nil
 }
+
+// postpone puts an order which is due in remaining time back to queue after a pause of at most maxPostponePause,
+// it returns true if processing must be stopped.
+func (w *GetAccrualWorker) postpone(record modelqueue.OrderQueueEntry, remaining time.Duration) bool {
+	pause := remaining
+	if pause > maxPostponePause {
+		pause = maxPostponePause
+	}
+	timer := time.NewTimer(pause)
+	defer timer.Stop()
+	select {
+	case <-w.ctx.Done():
+		return true
+	case <-timer.C:
+	}
+	w.queueIn <- record
+	return false
+}
+
+// pollInterval returns the polling interval for a schedule step, 10 seconds are used if no schedule was configured.
+func (w *GetAccrualWorker) pollInterval(step int) time.Duration {
+	if len(w.pollIntervals) == 0 {
+		return 10 * time.Second
+	}
+	if step >= len(w.pollIntervals) {
+		step = len(w.pollIntervals) - 1
+	}
+	return w.pollIntervals[step]
+}
+
+// recordResponse stores a raw accrual response of an order as evidence for disputes with the accrual system,
+// failures are logged without affecting processing.
+func (w *GetAccrualWorker) recordResponse(record modelqueue.OrderQueueEntry, resp *resty.Response) {
+	body := resp.Body()
+	if len(body) > maxRecordedBody {
+		body = body[:maxRecordedBody]
+	}
+	err := w.store.SaveAccrualResponse(w.ctx, modelstorage.AccrualResponseStorageEntry{
+		OrderNumber: record.OrderNumber,
+		TenantID:    record.TenantID,
+		StatusCode:  resp.StatusCode(),
+		Body:        body,
+		ReceivedAt:  resp.ReceivedAt(),
+	})
+	if err != nil {
+		w.log.Warn().Err(err).Msg(fmt.Sprintf("WID %v, order %v — could not record accrual response", w.ID, record.OrderNumber))
+	}
+}
+
+// requeue persists retry state of an order and puts it back to queue.
+func (w *GetAccrualWorker) requeue(record modelqueue.OrderQueueEntry) {
+	err := w.store.SaveRetryState(w.ctx, record)
+	if err != nil {
+		w.log.Warn().Err(err).Msg(fmt.Sprintf("WID %v, order %v — could not save retry state", w.ID, record.OrderNumber))
+	}
+	w.queueIn <- record
+}
@@ -0,0 +1,299 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/metrics"
+	"github.com/danilovkiri/dk-go-gophermart/internal/mocks"
+	"github.com/danilovkiri/dk-go-gophermart/internal/models/modelqueue"
+	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/modelstorage"
+	"github.com/go-resty/resty/v2"
+	"github.com/rs/zerolog"
+	"go.uber.org/mock/gomock"
+)
+
+const testOrderNumber = 12345678903
+
+// roundTripperFunc serves requests of a resty client without network.
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+// accrualResponse builds a response of the accrual service as received by its client.
+func accrualResponse(t *testing.T, status int, header http.Header, body string) *resty.Response {
+	t.Helper()
+	client := resty.New().SetTransport(roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		if header == nil {
+			header = http.Header{}
+		}
+		return &http.Response{StatusCode: status, Header: header, Body: io.NopCloser(strings.NewReader(body)), Request: r}, nil
+	}))
+	resp, err := client.R().Get("http://accrual/api/orders/12345678903")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+// newTestWorker builds a worker backed by mocks with buffered queues so that a single handle call never blocks.
+func newTestWorker(t *testing.T, ctx context.Context) (*GetAccrualWorker, *mocks.MockAccrualProvider, *mocks.MockQueueStore) {
+	ctrl := gomock.NewController(t)
+	provider := mocks.NewMockAccrualProvider(ctrl)
+	store := mocks.NewMockQueueStore(ctrl)
+	log := zerolog.Nop()
+	w := &GetAccrualWorker{
+		ctx:           ctx,
+		log:           &log,
+		queueIn:       make(chan modelqueue.OrderQueueEntry, 1),
+		queueOut:      make(chan modelqueue.OrderQueueEntry, 1),
+		accrualClient: provider,
+		store:         store,
+		retryNumber:   3,
+		unknownDelay:  time.Minute,
+		pollIntervals: []time.Duration{10 * time.Second, 30 * time.Second, 2 * time.Minute},
+		metrics:       metrics.NewRegistry(),
+	}
+	return w, provider, store
+}
+
+func TestHandleNotRegistered(t *testing.T) {
+	for _, status := range []int{http.StatusNoContent, http.StatusNotFound} {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+			w, provider, store := newTestWorker(t, context.Background())
+			store.EXPECT().TakeResolved(testOrderNumber).Return(false)
+			provider.EXPECT().GetAccrual(gomock.Any(), testOrderNumber).Return(accrualResponse(t, status, nil, ""), nil)
+			store.EXPECT().SaveAccrualResponse(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, response modelstorage.AccrualResponseStorageEntry) error {
+				if response.StatusCode != status {
+					t.Errorf("got recorded status %d, want %d", response.StatusCode, status)
+				}
+				return nil
+			})
+			store.EXPECT().SaveRetryState(gomock.Any(), gomock.Any()).Return(nil)
+
+			if w.handle(modelqueue.OrderQueueEntry{OrderNumber: testOrderNumber, OrderStatus: "NEW", RetryCount: 1}) {
+				t.Fatal("handle requested to stop")
+			}
+			record := <-w.queueIn
+			if record.RetryAfter != w.unknownDelay {
+				t.Fatalf("got retry after %v, want %v", record.RetryAfter, w.unknownDelay)
+			}
+			if record.RetryCount != 1 {
+				t.Fatalf("got retry count %d, an unregistered order must not spend retries", record.RetryCount)
+			}
+			if len(w.queueOut) != 0 {
+				t.Fatal("an unregistered order was sent for update")
+			}
+		})
+	}
+}
+
+func TestHandleTooManyRequests(t *testing.T) {
+	w, provider, store := newTestWorker(t, context.Background())
+	store.EXPECT().TakeResolved(testOrderNumber).Return(false)
+	provider.EXPECT().GetAccrual(gomock.Any(), testOrderNumber).Return(accrualResponse(t, http.StatusTooManyRequests, http.Header{"Retry-After": {"60"}}, "No more than N requests per minute allowed"), nil)
+	store.EXPECT().SaveAccrualResponse(gomock.Any(), gomock.Any()).Return(nil)
+	store.EXPECT().SaveRetryState(gomock.Any(), gomock.Any()).Return(nil)
+
+	if w.handle(modelqueue.OrderQueueEntry{OrderNumber: testOrderNumber, OrderStatus: "NEW"}) {
+		t.Fatal("handle requested to stop")
+	}
+	record := <-w.queueIn
+	if record.RetryAfter != time.Minute {
+		t.Fatalf("got retry after %v, want the Retry-After header value", record.RetryAfter)
+	}
+	if record.RetryCount != 0 {
+		t.Fatalf("got retry count %d, throttling must not spend retries", record.RetryCount)
+	}
+}
+
+func TestHandleStatusUpdate(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		wantStatus   string
+		wantAccrual  float64
+		wantDequeued bool
+	}{
+		{name: "processed", body: `{"order":"12345678903","status":"PROCESSED","accrual":729.98}`, wantStatus: "PROCESSED", wantAccrual: 729.98, wantDequeued: true},
+		{name: "invalid", body: `{"order":"12345678903","status":"INVALID"}`, wantStatus: "INVALID", wantDequeued: true},
+		{name: "processing", body: `{"order":"12345678903","status":"PROCESSING"}`, wantStatus: "PROCESSING"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, provider, store := newTestWorker(t, context.Background())
+			store.EXPECT().TakeResolved(testOrderNumber).Return(false).Times(2)
+			provider.EXPECT().GetAccrual(gomock.Any(), testOrderNumber).Return(accrualResponse(t, http.StatusOK, nil, tt.body), nil)
+			store.EXPECT().SaveAccrualResponse(gomock.Any(), gomock.Any()).Return(nil)
+			if !tt.wantDequeued {
+				store.EXPECT().SaveRetryState(gomock.Any(), gomock.Any()).Return(nil)
+			}
+
+			if w.handle(modelqueue.OrderQueueEntry{OrderNumber: testOrderNumber, OrderStatus: "NEW", PollStep: 2}) {
+				t.Fatal("handle requested to stop")
+			}
+			update := <-w.queueOut
+			if update.OrderStatus != tt.wantStatus || update.Accrual != tt.wantAccrual || update.Dequeued != tt.wantDequeued {
+				t.Fatalf("got update %+v, want status %s, accrual %v, dequeued %v", update, tt.wantStatus, tt.wantAccrual, tt.wantDequeued)
+			}
+			if update.Source != modelqueue.SourcePolling {
+				t.Fatalf("got update source %v, want polling", update.Source)
+			}
+			if tt.wantDequeued {
+				if len(w.queueIn) != 0 {
+					t.Fatal("an order with a final status was put back to queue")
+				}
+				return
+			}
+			record := <-w.queueIn
+			if record.OrderStatus != tt.wantStatus || record.PollStep != 0 {
+				t.Fatalf("got requeued %+v, want status %s and the polling interval reset", record, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestHandleNoUpdates(t *testing.T) {
+	w, provider, store := newTestWorker(t, context.Background())
+	store.EXPECT().TakeResolved(testOrderNumber).Return(false)
+	provider.EXPECT().GetAccrual(gomock.Any(), testOrderNumber).Return(accrualResponse(t, http.StatusOK, nil, `{"order":"12345678903","status":"PROCESSING"}`), nil)
+	store.EXPECT().SaveAccrualResponse(gomock.Any(), gomock.Any()).Return(nil)
+	store.EXPECT().SaveRetryState(gomock.Any(), gomock.Any()).Return(nil)
+
+	if w.handle(modelqueue.OrderQueueEntry{OrderNumber: testOrderNumber, OrderStatus: "PROCESSING"}) {
+		t.Fatal("handle requested to stop")
+	}
+	record := <-w.queueIn
+	if record.PollStep != 1 {
+		t.Fatalf("got poll step %d, want the next polling interval", record.PollStep)
+	}
+	if len(w.queueOut) != 0 {
+		t.Fatal("an unchanged order was sent for update")
+	}
+}
+
+func TestHandleResolvedViaCallback(t *testing.T) {
+	w, _, store := newTestWorker(t, context.Background())
+	store.EXPECT().TakeResolved(testOrderNumber).Return(true)
+
+	if w.handle(modelqueue.OrderQueueEntry{OrderNumber: testOrderNumber, OrderStatus: "NEW"}) {
+		t.Fatal("handle requested to stop")
+	}
+	if len(w.queueIn) != 0 || len(w.queueOut) != 0 {
+		t.Fatal("an order resolved via callback was processed")
+	}
+}
+
+func TestHandleNotDue(t *testing.T) {
+	w, _, _ := newTestWorker(t, context.Background())
+	record := modelqueue.OrderQueueEntry{OrderNumber: testOrderNumber, OrderStatus: "NEW", LastChecked: time.Now(), PollStep: 2}
+
+	start := time.Now()
+	if w.handle(record) {
+		t.Fatal("handle requested to stop")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("an order which is not due held the worker for %v", elapsed)
+	}
+	if requeued := <-w.queueIn; requeued.OrderNumber != testOrderNumber {
+		t.Fatalf("got requeued order %d, want %d", requeued.OrderNumber, testOrderNumber)
+	}
+}
+
+func TestHandleNotDueStopped(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	w, _, _ := newTestWorker(t, ctx)
+
+	if !w.handle(modelqueue.OrderQueueEntry{OrderNumber: testOrderNumber, LastChecked: time.Now()}) {
+		t.Fatal("handle did not request to stop after cancellation")
+	}
+}
+
+// Ensure, that the fake Accrual Service does implement AccrualProvider.
+var _ AccrualProvider = &mocks.FakeAccrual{}
+
+func TestHandleScriptedPolls(t *testing.T) {
+	w, _, store := newTestWorker(t, context.Background())
+	fake := mocks.NewFakeAccrual().Script(testOrderNumber,
+		mocks.NotRegistered(),
+		mocks.Fail(errors.New("connection reset")),
+		mocks.TooManyRequests(30),
+		mocks.Reply("PROCESSING", 0),
+		mocks.Reply("PROCESSING", 0),
+		mocks.Reply("PROCESSED", 500),
+	)
+	w.accrualClient = fake
+	store.EXPECT().TakeResolved(testOrderNumber).Return(false).AnyTimes()
+	store.EXPECT().SaveAccrualResponse(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	store.EXPECT().SaveRetryState(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+	// poll handles the record as soon as it is due and returns the requeued one, if any
+	poll := func(record modelqueue.OrderQueueEntry) (modelqueue.OrderQueueEntry, bool) {
+		t.Helper()
+		record.LastChecked = time.Time{}
+		if w.handle(record) {
+			t.Fatal("handle requested to stop")
+		}
+		select {
+		case requeued := <-w.queueIn:
+			return requeued, true
+		default:
+			return modelqueue.OrderQueueEntry{}, false
+		}
+	}
+
+	record, _ := poll(modelqueue.OrderQueueEntry{OrderNumber: testOrderNumber, OrderStatus: "NEW"})
+	if record.RetryAfter != w.unknownDelay || record.RetryCount != 0 {
+		t.Fatalf("after an unregistered reply got %+v, want a delay without spending retries", record)
+	}
+	record, _ = poll(record)
+	if record.RetryCount != 1 {
+		t.Fatalf("after a failed call got retry count %d, want 1", record.RetryCount)
+	}
+	record, _ = poll(record)
+	if record.RetryAfter != 30*time.Second || record.RetryCount != 1 {
+		t.Fatalf("after a throttled call got %+v, want a 30s delay without spending retries", record)
+	}
+	record.RetryAfter = 0
+	record, _ = poll(record)
+	if update := <-w.queueOut; update.OrderStatus != "PROCESSING" || update.Dequeued {
+		t.Fatalf("got update %+v, want a non-final PROCESSING update", update)
+	}
+	record, _ = poll(record)
+	if len(w.queueOut) != 0 || record.PollStep != 1 {
+		t.Fatalf("after an unchanged status got %+v, want no update and the next polling interval", record)
+	}
+	if _, requeued := poll(record); requeued {
+		t.Fatal("an order with a final status was put back to queue")
+	}
+	if update := <-w.queueOut; update.OrderStatus != "PROCESSED" || update.Accrual != 500 || !update.Dequeued {
+		t.Fatalf("got update %+v, want the final PROCESSED update", update)
+	}
+	if calls := fake.Calls(testOrderNumber); calls != 6 {
+		t.Fatalf("got %d accrual calls, want 6", calls)
+	}
+}
+
+func TestHandleAbandon(t *testing.T) {
+	w, provider, store := newTestWorker(t, context.Background())
+	store.EXPECT().TakeResolved(testOrderNumber).Return(false)
+	provider.EXPECT().GetAccrual(gomock.Any(), testOrderNumber).Return(nil, errors.New("connection refused"))
+
+	if w.handle(modelqueue.OrderQueueEntry{OrderNumber: testOrderNumber, OrderStatus: "PROCESSING", RetryCount: w.retryNumber}) {
+		t.Fatal("handle requested to stop")
+	}
+	if len(w.queueIn) != 0 {
+		t.Fatal("an order exceeding the retry limit was put back to queue")
+	}
+	if update := <-w.queueOut; update.OrderStatus != "PROCESSING" || !update.Dequeued || !update.Abandoned {
+		t.Fatalf("got update %+v, want an abandoned update leaving the status unchanged", update)
+	}
+}
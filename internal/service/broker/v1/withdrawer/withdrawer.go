@@ -0,0 +1,101 @@
+// Package withdrawer provides asynchronous withdrawal processing functionality.
+
+package withdrawer
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/errcodes"
+	"github.com/danilovkiri/dk-go-gophermart/internal/metrics"
+	"github.com/danilovkiri/dk-go-gophermart/internal/models/modelqueue"
+	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1"
+	"github.com/danilovkiri/dk-go-gophermart/internal/tenant"
+	"github.com/rs/zerolog"
+)
+
+// Withdrawer defines attributes of a struct available to its methods.
+type Withdrawer struct {
+	ctx          context.Context
+	log          *zerolog.Logger
+	wg           *sync.WaitGroup
+	queue        chan modelqueue.WithdrawalQueueEntry
+	storage      storage.AsyncWithdrawal
+	metrics      *metrics.Registry
+	workerNumber int
+	retryNumber  int
+	retryBackoff time.Duration
+}
+
+// InitWithdrawer initializes an asynchronous withdrawal processing service.
+func InitWithdrawer(ctx context.Context, queue chan modelqueue.WithdrawalQueueEntry, st storage.AsyncWithdrawal, log *zerolog.Logger, wg *sync.WaitGroup, reg *metrics.Registry, nWorkers int, nRetries int, retryBackoff time.Duration) *Withdrawer {
+	return &Withdrawer{
+		ctx:          ctx,
+		log:          log,
+		wg:           wg,
+		queue:        queue,
+		storage:      st,
+		metrics:      reg,
+		workerNumber: nWorkers,
+		retryNumber:  nRetries,
+		retryBackoff: retryBackoff,
+	}
+}
+
+// ListenAndProcess starts withdrawal workers.
+func (wd *Withdrawer) ListenAndProcess() {
+	for i := 0; i < wd.workerNumber; i++ {
+		wd.wg.Add(1)
+		go func(id int) {
+			defer wd.wg.Done()
+			wd.log.Info().Msg(fmt.Sprintf("withdrawal worker %v started", id))
+			for {
+				select {
+				case <-wd.ctx.Done():
+					wd.log.Info().Msg(fmt.Sprintf("withdrawal worker %v stopped", id))
+					return
+				case record := <-wd.queue:
+					wd.process(id, record)
+				}
+			}
+		}(i)
+	}
+}
+
+// process confirms a single pending withdrawal, retrying transient failures.
+func (wd *Withdrawer) process(id int, record modelqueue.WithdrawalQueueEntry) {
+	ctx := tenant.WithTenant(wd.ctx, record.TenantID)
+	err := wd.storage.ConfirmWithdrawal(ctx, record.UserID, record.ID)
+	if err == nil {
+		wd.metrics.Counter("gophermart_withdrawals_async_total", "result", "processed").Inc()
+		return
+	}
+	if wd.ctx.Err() != nil {
+		return
+	}
+	permanent := errcodes.Of(err) == errcodes.InsufficientFunds || errcodes.Of(err) == errcodes.OrderOwnedByAnotherUser
+	if permanent || record.RetryCount >= wd.retryNumber {
+		wd.log.Warn().Err(err).Msg(fmt.Sprintf("WWID %v, order %s — withdrawal failed permanently", id, record.OrderNumber))
+		err = wd.storage.FailWithdrawal(ctx, record.UserID, record.ID)
+		if err != nil {
+			wd.log.Error().Err(err).Msg(fmt.Sprintf("WWID %v, order %s — could not mark withdrawal as failed", id, record.OrderNumber))
+		}
+		wd.metrics.Counter("gophermart_withdrawals_async_total", "result", "failed").Inc()
+		return
+	}
+	record.RetryCount++
+	wd.log.Warn().Err(err).Msg(fmt.Sprintf("WWID %v, order %s — withdrawal retry %v scheduled", id, record.OrderNumber, record.RetryCount))
+	wd.metrics.Counter("gophermart_withdrawals_async_total", "result", "retried").Inc()
+	// retry asynchronously so that the worker is not blocked during backoff
+	wd.wg.Add(1)
+	go func() {
+		defer wd.wg.Done()
+		select {
+		case <-wd.ctx.Done():
+		case <-time.After(wd.retryBackoff * time.Duration(record.RetryCount)):
+			wd.storage.SendWithdrawalToQueue(wd.ctx, record)
+		}
+	}()
+}
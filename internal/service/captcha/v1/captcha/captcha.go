@@ -0,0 +1,123 @@
+// Package captcha provides CAPTCHA verification functionality.
+
+package captcha
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/config"
+	"github.com/danilovkiri/dk-go-gophermart/internal/errcodes"
+	"github.com/danilovkiri/dk-go-gophermart/internal/service/captcha/v1"
+	"github.com/go-resty/resty/v2"
+)
+
+// CAPTCHA providers selectable via config.
+const (
+	ProviderNone      = "none"
+	ProviderHCaptcha  = "hcaptcha"
+	ProviderReCaptcha = "recaptcha"
+)
+
+// default verification endpoints of the supported providers.
+var verifyURLs = map[string]string{
+	ProviderHCaptcha:  "https://hcaptcha.com/siteverify",
+	ProviderReCaptcha: "https://www.google.com/recaptcha/api/siteverify",
+}
+
+// RejectedError is returned when a CAPTCHA token is missing or was not accepted by the provider.
+type RejectedError struct {
+	Reasons []string
+}
+
+func (e *RejectedError) Error() string {
+	if len(e.Reasons) == 0 {
+		return "captcha verification failed"
+	}
+	return fmt.Sprintf("captcha verification failed: %s", strings.Join(e.Reasons, ", "))
+}
+
+func (e *RejectedError) ErrorCode() errcodes.Code {
+	return errcodes.CaptchaFailed
+}
+
+// UnavailableError is returned when the CAPTCHA provider could not be queried.
+type UnavailableError struct {
+	Err error
+}
+
+func (e *UnavailableError) Error() string {
+	return fmt.Sprintf("captcha provider is unavailable: %s", e.Err.Error())
+}
+
+func (e *UnavailableError) ErrorCode() errcodes.Code {
+	return errcodes.ServiceUnavailable
+}
+
+// NoopVerifier accepts every request, it is used when CAPTCHA verification is disabled.
+type NoopVerifier struct{}
+
+// Verify accepts any token.
+func (v *NoopVerifier) Verify(ctx context.Context, token string, remoteIP string) error {
+	return nil
+}
+
+// SiteVerifier verifies tokens against a siteverify endpoint shared by hCaptcha and reCAPTCHA.
+type SiteVerifier struct {
+	client    *resty.Client
+	secret    string
+	verifyURL string
+}
+
+// siteVerifyResponse defines the response body of a siteverify endpoint.
+type siteVerifyResponse struct {
+	Success    bool     `json:"success"`
+	ErrorCodes []string `json:"error-codes"`
+}
+
+// Verify checks a CAPTCHA token with the provider.
+func (v *SiteVerifier) Verify(ctx context.Context, token string, remoteIP string) error {
+	if token == "" {
+		return &RejectedError{Reasons: []string{"missing-input-response"}}
+	}
+	form := map[string]string{
+		"secret":   v.secret,
+		"response": token,
+	}
+	if remoteIP != "" {
+		form["remoteip"] = remoteIP
+	}
+	var result siteVerifyResponse
+	resp, err := v.client.R().SetContext(ctx).SetFormData(form).SetResult(&result).Post(v.verifyURL)
+	if err != nil {
+		return &UnavailableError{Err: err}
+	}
+	if resp.IsError() {
+		return &UnavailableError{Err: fmt.Errorf("unexpected status %s", resp.Status())}
+	}
+	if !result.Success {
+		return &RejectedError{Reasons: result.ErrorCodes}
+	}
+	return nil
+}
+
+// NewVerifier initializes a CAPTCHA verifier for the configured provider.
+func NewVerifier(cfg *config.CaptchaConfig) (captcha.Verifier, error) {
+	switch cfg.Provider {
+	case ProviderNone, "":
+		return &NoopVerifier{}, nil
+	case ProviderHCaptcha, ProviderReCaptcha:
+		if cfg.Secret == "" {
+			return nil, errors.New("captcha secret must be set for captcha verification")
+		}
+		verifyURL := cfg.VerifyURL
+		if verifyURL == "" {
+			verifyURL = verifyURLs[cfg.Provider]
+		}
+		return &SiteVerifier{client: resty.New().SetTimeout(cfg.Timeout), secret: cfg.Secret, verifyURL: verifyURL}, nil
+	default:
+		return nil, fmt.Errorf("unknown captcha provider %s", cfg.Provider)
+	}
+}
@@ -0,0 +1,10 @@
+// Package captcha provides CAPTCHA verification functionality.
+
+package captcha
+
+import "context"
+
+// Verifier defines a set of methods for types implementing Verifier.
+type Verifier interface {
+	Verify(ctx context.Context, token string, remoteIP string) error
+}
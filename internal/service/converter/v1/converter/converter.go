@@ -0,0 +1,147 @@
+// Package converter provides points-to-currency conversion functionality.
+
+package converter
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/config"
+	"github.com/danilovkiri/dk-go-gophermart/internal/errcodes"
+	"github.com/rs/zerolog"
+	"gopkg.in/yaml.v3"
+)
+
+// UnsupportedCurrencyError is returned when no conversion rate is configured for a currency.
+type UnsupportedCurrencyError struct {
+	Currency string
+}
+
+func (e *UnsupportedCurrencyError) Error() string {
+	return fmt.Sprintf("no conversion rate is configured for currency %q", e.Currency)
+}
+
+func (e *UnsupportedCurrencyError) ErrorCode() errcodes.Code {
+	return errcodes.UnsupportedCurrency
+}
+
+// ratesFile defines the layout of a conversion rate file, rates are currency units per point.
+type ratesFile struct {
+	Rates map[string]float64 `yaml:"rates"`
+}
+
+// RateTable defines attributes of a struct available to its methods.
+type RateTable struct {
+	ctx      context.Context
+	cfg      *config.CurrencyConfig
+	log      *zerolog.Logger
+	wg       *sync.WaitGroup
+	mu       sync.RWMutex
+	rates    map[string]float64
+	modified time.Time
+}
+
+// InitRateTable initializes a conversion rate table from the configured rates and the rate file if one is set.
+func InitRateTable(ctx context.Context, cfg *config.CurrencyConfig, log *zerolog.Logger, wg *sync.WaitGroup) (*RateTable, error) {
+	t := &RateTable{
+		ctx:   ctx,
+		cfg:   cfg,
+		log:   log,
+		wg:    wg,
+		rates: cfg.Rates,
+	}
+	if cfg.RatesFile != "" {
+		_, err := t.reload()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// Rate returns the number of currency units a single point is worth.
+func (t *RateTable) Rate(currency string) (float64, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	rate, ok := t.rates[strings.ToUpper(currency)]
+	if !ok {
+		return 0, &UnsupportedCurrencyError{Currency: currency}
+	}
+	return rate, nil
+}
+
+// ListenAndReload periodically re-reads the rate file once it has been modified, so that rates are updated
+// without a restart.
+func (t *RateTable) ListenAndReload() {
+	if t.cfg.RatesFile == "" {
+		return
+	}
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		t.log.Info().Msg("started conversion rate reloading")
+		ticker := time.NewTicker(t.cfg.ReloadInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-t.ctx.Done():
+				t.log.Info().Msg("stopped conversion rate reloading")
+				return
+			case <-ticker.C:
+			}
+			reloaded, err := t.reload()
+			if err != nil {
+				// keep serving the previous rates until the file is fixed
+				t.log.Error().Err(err).Msg("could not reload conversion rates")
+				continue
+			}
+			if reloaded {
+				t.log.Info().Msg("conversion rates reloaded")
+			}
+		}
+	}()
+}
+
+// reload replaces the rates with the ones from the rate file if it changed since the previous read, rates
+// configured via environment are kept unless the file overrides them.
+func (t *RateTable) reload() (bool, error) {
+	info, err := os.Stat(t.cfg.RatesFile)
+	if err != nil {
+		return false, err
+	}
+	t.mu.RLock()
+	unchanged := info.ModTime().Equal(t.modified)
+	t.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+	data, err := ioutil.ReadFile(t.cfg.RatesFile)
+	if err != nil {
+		return false, err
+	}
+	var file ratesFile
+	err = yaml.Unmarshal(data, &file)
+	if err != nil {
+		return false, fmt.Errorf("could not parse conversion rates: %w", err)
+	}
+	rates := make(map[string]float64, len(t.cfg.Rates)+len(file.Rates))
+	for currency, rate := range t.cfg.Rates {
+		rates[currency] = rate
+	}
+	for currency, rate := range file.Rates {
+		if rate <= 0 {
+			return false, fmt.Errorf("conversion rate for %s must be positive, got %v", currency, rate)
+		}
+		rates[strings.ToUpper(currency)] = rate
+	}
+	t.mu.Lock()
+	t.rates = rates
+	t.modified = info.ModTime()
+	t.mu.Unlock()
+	return true, nil
+}
@@ -0,0 +1,8 @@
+// Package converter provides points-to-currency conversion functionality.
+
+package converter
+
+// Converter defines a set of methods for types implementing Converter.
+type Converter interface {
+	Rate(currency string) (float64, error)
+}
@@ -0,0 +1,65 @@
+// Package hasher provides password hashing functionality.
+
+package hasher
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/config"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// bcryptPrefixes lists the version prefixes of bcrypt hashes, stored passwords without them are legacy ciphertext.
+var bcryptPrefixes = []string{"$2a$", "$2b$", "$2y$"}
+
+// BcryptHasher hashes passwords with bcrypt, the salt and the cost are kept within every hash.
+type BcryptHasher struct {
+	cost int
+}
+
+// NewBcryptHasher initializes a bcrypt password hasher.
+func NewBcryptHasher(cfg *config.SecretConfig) (*BcryptHasher, error) {
+	if cfg.PasswordHashCost < bcrypt.MinCost || cfg.PasswordHashCost > bcrypt.MaxCost {
+		return nil, fmt.Errorf("password hash cost must be within [%d, %d], got %d", bcrypt.MinCost, bcrypt.MaxCost, cfg.PasswordHashCost)
+	}
+	return &BcryptHasher{cost: cfg.PasswordHashCost}, nil
+}
+
+// Hash returns a salted hash of a password, bcrypt only takes the first 72 bytes of a password into account.
+func (h *BcryptHasher) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// Verify reports whether a password matches its hash.
+func (h *BcryptHasher) Verify(hash, password string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Hashed reports whether a stored password is a hash rather than legacy ciphertext awaiting migration.
+func (h *BcryptHasher) Hashed(stored string) bool {
+	for _, prefix := range bcryptPrefixes {
+		if strings.HasPrefix(stored, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// NeedsRehash reports whether a hash was computed with a cost other than the configured one.
+func (h *BcryptHasher) NeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost != h.cost
+}
@@ -0,0 +1,11 @@
+// Package hasher provides password hashing functionality.
+
+package hasher
+
+// Hasher defines a set of methods for types implementing Hasher.
+type Hasher interface {
+	Hash(password string) (string, error)
+	Verify(hash, password string) (bool, error)
+	Hashed(stored string) bool
+	NeedsRehash(hash string) bool
+}
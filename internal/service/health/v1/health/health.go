@@ -0,0 +1,165 @@
+// Package health provides dependency health checking functionality.
+
+package health
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/config"
+	"github.com/danilovkiri/dk-go-gophermart/internal/metrics"
+	"github.com/danilovkiri/dk-go-gophermart/internal/models/modeldto"
+	"github.com/danilovkiri/dk-go-gophermart/internal/service/health/v1"
+	"github.com/rs/zerolog"
+)
+
+// Dependency statuses.
+const (
+	StatusUnknown = "UNKNOWN"
+	StatusUp      = "UP"
+	StatusDown    = "DOWN"
+)
+
+// dependencyState defines the tracked state of a single dependency.
+type dependencyState struct {
+	pinger      health.Pinger
+	status      string
+	since       time.Time
+	lastChecked time.Time
+	lastError   string
+	transitions int
+}
+
+// Checker defines attributes of a struct available to its methods.
+type Checker struct {
+	mu      sync.RWMutex
+	ctx     context.Context
+	cfg     *config.HealthConfig
+	log     *zerolog.Logger
+	wg      *sync.WaitGroup
+	metrics *metrics.Registry
+	deps    map[string]*dependencyState
+}
+
+// InitChecker initializes a dependency health checking service.
+func InitChecker(ctx context.Context, cfg *config.HealthConfig, log *zerolog.Logger, wg *sync.WaitGroup, reg *metrics.Registry, pingers map[string]health.Pinger) *Checker {
+	deps := make(map[string]*dependencyState, len(pingers))
+	for name, pinger := range pingers {
+		deps[name] = &dependencyState{pinger: pinger, status: StatusUnknown, since: time.Now()}
+	}
+	return &Checker{
+		ctx:     ctx,
+		cfg:     cfg,
+		log:     log,
+		wg:      wg,
+		metrics: reg,
+		deps:    deps,
+	}
+}
+
+// ListenAndCheck performs an initial check and starts periodic dependency checking.
+func (c *Checker) ListenAndCheck() {
+	c.checkAll()
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		c.log.Info().Msg("started dependency health checking")
+		ticker := time.NewTicker(c.cfg.CheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-c.ctx.Done():
+				c.log.Info().Msg("stopped dependency health checking")
+				return
+			case <-ticker.C:
+				c.checkAll()
+			}
+		}
+	}()
+}
+
+// Ready returns true if all dependencies are up.
+func (c *Checker) Ready() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, dep := range c.deps {
+		if dep.status != StatusUp {
+			return false
+		}
+	}
+	return true
+}
+
+// Report returns a detailed health report for all dependencies.
+func (c *Checker) Report() modeldto.HealthReport {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	report := modeldto.HealthReport{Status: StatusUp}
+	for name, dep := range c.deps {
+		if dep.status != StatusUp {
+			report.Status = StatusDown
+		}
+		report.Dependencies = append(report.Dependencies, modeldto.DependencyHealth{
+			Name:        name,
+			Status:      dep.status,
+			Since:       dep.since.Format(time.RFC3339),
+			LastChecked: dep.lastChecked.Format(time.RFC3339),
+			LastError:   dep.lastError,
+			Transitions: dep.transitions,
+		})
+	}
+	sort.Slice(report.Dependencies, func(i, j int) bool {
+		return report.Dependencies[i].Name < report.Dependencies[j].Name
+	})
+	return report
+}
+
+// checkAll pings every dependency and records status transitions.
+func (c *Checker) checkAll() {
+	c.mu.RLock()
+	names := make([]string, 0, len(c.deps))
+	for name := range c.deps {
+		names = append(names, name)
+	}
+	c.mu.RUnlock()
+	for _, name := range names {
+		c.check(name)
+	}
+}
+
+// check pings a single dependency and records its status.
+func (c *Checker) check(name string) {
+	c.mu.RLock()
+	dep := c.deps[name]
+	c.mu.RUnlock()
+	ctx, cancel := context.WithTimeout(c.ctx, c.cfg.CheckTimeout)
+	defer cancel()
+	err := dep.pinger.Ping(ctx)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	newStatus := StatusUp
+	dep.lastError = ""
+	if err != nil {
+		newStatus = StatusDown
+		dep.lastError = err.Error()
+	}
+	dep.lastChecked = time.Now()
+	if newStatus != dep.status {
+		c.log.Warn().Msg(fmt.Sprintf("dependency %s changed status from %s to %s", name, dep.status, newStatus))
+		if dep.status != StatusUnknown {
+			dep.transitions++
+			c.metrics.Counter("gophermart_health_transitions_total", "dependency", name).Inc()
+		}
+		dep.status = newStatus
+		dep.since = dep.lastChecked
+	}
+	var up int64
+	if newStatus == StatusUp {
+		up = 1
+	}
+	c.metrics.Gauge("gophermart_health_up", "dependency", name).Set(up)
+}
@@ -0,0 +1,20 @@
+// Package health provides dependency health checking functionality.
+
+package health
+
+import (
+	"context"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/models/modeldto"
+)
+
+// Pinger defines a set of methods for dependencies which can be health-checked.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Checker defines a set of methods for types implementing Checker.
+type Checker interface {
+	Ready() bool
+	Report() modeldto.HealthReport
+}
@@ -0,0 +1,14 @@
+// Package notifier provides user notification functionality.
+
+package notifier
+
+import (
+	"context"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/models/modeldto"
+)
+
+// Notifier defines a set of methods for types implementing Notifier.
+type Notifier interface {
+	Notify(ctx context.Context, notification modeldto.Notification) error
+}
@@ -0,0 +1,69 @@
+// Package notifier provides user notification functionality.
+
+package notifier
+
+import (
+	"context"
+	"sync"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/models/modeldto"
+	"github.com/danilovkiri/dk-go-gophermart/internal/service/notifier/v1"
+	"github.com/rs/zerolog"
+)
+
+// MultiNotifier delivers notifications through several channels, a failing channel does not stop the others.
+type MultiNotifier struct {
+	notifiers []notifier.Notifier
+}
+
+// NewMultiNotifier initializes a notifier fanning out to all notifiers.
+func NewMultiNotifier(notifiers ...notifier.Notifier) *MultiNotifier {
+	return &MultiNotifier{notifiers: notifiers}
+}
+
+// Notify delivers a notification through every channel and returns the first error.
+func (n *MultiNotifier) Notify(ctx context.Context, notification modeldto.Notification) error {
+	var firstErr error
+	for _, target := range n.notifiers {
+		err := target.Notify(ctx, notification)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Dispatcher defines attributes of a struct available to its methods.
+type Dispatcher struct {
+	ctx      context.Context
+	events   <-chan modeldto.Notification
+	notifier notifier.Notifier
+	log      *zerolog.Logger
+	wg       *sync.WaitGroup
+}
+
+// InitDispatcher initializes a dispatcher delivering storage events to users.
+func InitDispatcher(ctx context.Context, events <-chan modeldto.Notification, target notifier.Notifier, log *zerolog.Logger, wg *sync.WaitGroup) *Dispatcher {
+	return &Dispatcher{ctx: ctx, events: events, notifier: target, log: log, wg: wg}
+}
+
+// ListenAndDispatch delivers events until the context is cancelled.
+func (d *Dispatcher) ListenAndDispatch() {
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		d.log.Info().Msg("started dispatching user notifications")
+		for {
+			select {
+			case <-d.ctx.Done():
+				d.log.Info().Msg("stopped dispatching user notifications")
+				return
+			case event := <-d.events:
+				err := d.notifier.Notify(d.ctx, event)
+				if err != nil {
+					d.log.Warn().Err(err).Msg("could not deliver user notification")
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,65 @@
+// Package notifier provides user notification functionality.
+
+package notifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/config"
+	"github.com/danilovkiri/dk-go-gophermart/internal/metrics"
+	"github.com/danilovkiri/dk-go-gophermart/internal/models/modeldto"
+)
+
+// EmailNotifier sends notifications addressed to a mailbox through an SMTP server, others are ignored.
+type EmailNotifier struct {
+	cfg     *config.EmailConfig
+	auth    smtp.Auth
+	metrics *metrics.Registry
+}
+
+// NewEmailNotifier initializes an SMTP notifier.
+func NewEmailNotifier(cfg *config.EmailConfig, reg *metrics.Registry) (*EmailNotifier, error) {
+	if cfg.Address == "" {
+		return nil, errors.New("SMTP address must be set for email notifications")
+	}
+	n := &EmailNotifier{cfg: cfg, metrics: reg}
+	if cfg.User != "" {
+		host, _, err := net.SplitHostPort(cfg.Address)
+		if err != nil {
+			return nil, err
+		}
+		n.auth = smtp.PlainAuth("", cfg.User, cfg.Password, host)
+	}
+	return n, nil
+}
+
+// Notify sends a notification as a plain text email, the notification kind makes up the subject.
+func (n *EmailNotifier) Notify(ctx context.Context, notification modeldto.Notification) error {
+	if notification.Email == "" {
+		return nil
+	}
+	// header values must not carry line breaks injecting further headers
+	if strings.ContainsAny(notification.Email, "\r\n") {
+		return fmt.Errorf("illegal email address %q", notification.Email)
+	}
+	var msg strings.Builder
+	msg.WriteString("From: " + n.cfg.From + "\r\n")
+	msg.WriteString("To: " + notification.Email + "\r\n")
+	msg.WriteString("Subject: Gophermart " + strings.ReplaceAll(notification.Kind, "_", " ") + "\r\n")
+	msg.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(notification.Message, "\n", "\r\n"))
+	err := smtp.SendMail(n.cfg.Address, n.auth, n.cfg.From, []string{notification.Email}, []byte(msg.String()))
+	if err != nil {
+		n.metrics.Counter("gophermart_emails_total", "result", "failed").Inc()
+		return err
+	}
+	n.metrics.Counter("gophermart_emails_total", "result", "sent").Inc()
+	return nil
+}
@@ -0,0 +1,30 @@
+// Package notifier provides user notification functionality.
+
+package notifier
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/metrics"
+	"github.com/danilovkiri/dk-go-gophermart/internal/models/modeldto"
+	"github.com/rs/zerolog"
+)
+
+// LogNotifier emits notifications as log records and counts them by kind.
+type LogNotifier struct {
+	log     *zerolog.Logger
+	metrics *metrics.Registry
+}
+
+// NewLogNotifier initializes a log-based notifier.
+func NewLogNotifier(log *zerolog.Logger, reg *metrics.Registry) *LogNotifier {
+	return &LogNotifier{log: log, metrics: reg}
+}
+
+// Notify logs a notification.
+func (n *LogNotifier) Notify(ctx context.Context, notification modeldto.Notification) error {
+	n.metrics.Counter("gophermart_notifications_total", "kind", notification.Kind).Inc()
+	n.log.Info().Msg(fmt.Sprintf("notification %s for user %s: %s", notification.Kind, notification.UserID, notification.Message))
+	return nil
+}
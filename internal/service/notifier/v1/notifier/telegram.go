@@ -0,0 +1,266 @@
+// Package notifier provides user notification functionality.
+
+package notifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/config"
+	"github.com/danilovkiri/dk-go-gophermart/internal/metrics"
+	"github.com/danilovkiri/dk-go-gophermart/internal/models/modeldto"
+	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1"
+	storageErrors "github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/errors"
+	"github.com/go-resty/resty/v2"
+	"github.com/rs/zerolog"
+)
+
+// telegramResponse defines the envelope of Bot API responses.
+type telegramResponse struct {
+	OK          bool   `json:"ok"`
+	Description string `json:"description"`
+	Parameters  struct {
+		RetryAfter int `json:"retry_after"`
+	} `json:"parameters"`
+}
+
+// telegramUpdates defines the getUpdates response body.
+type telegramUpdates struct {
+	telegramResponse
+	Result []struct {
+		UpdateID int64 `json:"update_id"`
+		Message  *struct {
+			Text string `json:"text"`
+			Chat struct {
+				ID int64 `json:"id"`
+			} `json:"chat"`
+		} `json:"message"`
+	} `json:"result"`
+}
+
+// telegramError is returned when the Bot API rejects a request, RetryAfter is set for rate limited requests.
+type telegramError struct {
+	Status      int
+	Description string
+	RetryAfter  time.Duration
+}
+
+func (e *telegramError) Error() string {
+	return fmt.Sprintf("telegram bot API responded with %d: %s", e.Status, e.Description)
+}
+
+// TelegramNotifier pushes notifications to Telegram chats linked by users via one-time codes.
+type TelegramNotifier struct {
+	ctx     context.Context
+	cfg     *config.TelegramConfig
+	log     *zerolog.Logger
+	wg      *sync.WaitGroup
+	metrics *metrics.Registry
+	chats   storage.TelegramChats
+	client  *resty.Client
+	queue   chan modeldto.Notification
+	limiter *time.Ticker
+}
+
+// InitTelegramNotifier initializes a Telegram Bot API notifier.
+func InitTelegramNotifier(ctx context.Context, cfg *config.TelegramConfig, log *zerolog.Logger, wg *sync.WaitGroup, reg *metrics.Registry, chats storage.TelegramChats) (*TelegramNotifier, error) {
+	if cfg.BotToken == "" {
+		return nil, errors.New("telegram bot token must be set for telegram notifications")
+	}
+	return &TelegramNotifier{
+		ctx:     ctx,
+		cfg:     cfg,
+		log:     log,
+		wg:      wg,
+		metrics: reg,
+		chats:   chats,
+		client:  resty.New().SetBaseURL(strings.TrimSuffix(cfg.APIURL, "/") + "/bot" + cfg.BotToken).SetTimeout(cfg.PollTimeout + 10*time.Second),
+		queue:   make(chan modeldto.Notification, cfg.QueueSize),
+		limiter: time.NewTicker(time.Second / time.Duration(cfg.RateLimit)),
+	}, nil
+}
+
+// Notify queues a notification for delivery, notifications are dropped while the queue is full.
+func (n *TelegramNotifier) Notify(ctx context.Context, notification modeldto.Notification) error {
+	select {
+	case n.queue <- notification:
+	default:
+		n.metrics.Counter("gophermart_telegram_messages_total", "result", "dropped").Inc()
+	}
+	return nil
+}
+
+// ListenAndServe starts delivering queued notifications and, if enabled, redeeming link codes sent to the bot.
+func (n *TelegramNotifier) ListenAndServe() {
+	n.wg.Add(1)
+	go func() {
+		defer n.wg.Done()
+		defer n.limiter.Stop()
+		n.log.Info().Msg("started telegram notification delivery")
+		for {
+			select {
+			case <-n.ctx.Done():
+				n.log.Info().Msg("stopped telegram notification delivery")
+				return
+			case notification := <-n.queue:
+				n.deliver(notification)
+			}
+		}
+	}()
+	if !n.cfg.PollUpdates {
+		return
+	}
+	n.wg.Add(1)
+	go func() {
+		defer n.wg.Done()
+		n.log.Info().Msg("started telegram updates polling")
+		n.pollUpdates()
+		n.log.Info().Msg("stopped telegram updates polling")
+	}()
+}
+
+// deliver sends a notification to the chat linked by the user, users without a linked chat are skipped.
+func (n *TelegramNotifier) deliver(notification modeldto.Notification) {
+	chatID, err := n.chats.GetTelegramChatID(n.ctx, notification.UserID)
+	var notFoundError *storageErrors.NotFoundError
+	if errors.As(err, &notFoundError) {
+		n.metrics.Counter("gophermart_telegram_messages_total", "result", "unlinked").Inc()
+		return
+	}
+	if err != nil {
+		n.log.Warn().Err(err).Msg("could not look up telegram chat")
+		n.metrics.Counter("gophermart_telegram_messages_total", "result", "failed").Inc()
+		return
+	}
+	err = n.sendWithRetry(chatID, notification.Message)
+	if err != nil {
+		n.log.Warn().Err(err).Msg(fmt.Sprintf("could not deliver %s notification via telegram", notification.Kind))
+		n.metrics.Counter("gophermart_telegram_messages_total", "result", "failed").Inc()
+		return
+	}
+	n.metrics.Counter("gophermart_telegram_messages_total", "result", "sent").Inc()
+}
+
+// sendWithRetry sends a message retrying rate limited requests and server failures with exponential backoff.
+func (n *TelegramNotifier) sendWithRetry(chatID int64, text string) error {
+	backoff := n.cfg.RetryBackoff
+	var err error
+	for attempt := 0; attempt <= n.cfg.RetryNumber; attempt++ {
+		err = n.send(chatID, text)
+		if err == nil {
+			return nil
+		}
+		wait := backoff
+		var apiErr *telegramError
+		if errors.As(err, &apiErr) {
+			if apiErr.Status != http.StatusTooManyRequests && apiErr.Status < http.StatusInternalServerError {
+				// the chat is gone or the bot was blocked, retrying won't help
+				return err
+			}
+			if apiErr.RetryAfter > 0 {
+				wait = apiErr.RetryAfter
+			}
+		}
+		select {
+		case <-n.ctx.Done():
+			return n.ctx.Err()
+		case <-time.After(wait):
+		}
+		backoff *= 2
+	}
+	return err
+}
+
+// send issues a single rate limited sendMessage request.
+func (n *TelegramNotifier) send(chatID int64, text string) error {
+	select {
+	case <-n.ctx.Done():
+		return n.ctx.Err()
+	case <-n.limiter.C:
+	}
+	var result telegramResponse
+	resp, err := n.client.R().
+		SetContext(n.ctx).
+		SetBody(map[string]interface{}{"chat_id": chatID, "text": text}).
+		SetResult(&result).
+		SetError(&result).
+		Post("/sendMessage")
+	if err != nil {
+		return err
+	}
+	if resp.IsError() || !result.OK {
+		return &telegramError{
+			Status:      resp.StatusCode(),
+			Description: result.Description,
+			RetryAfter:  time.Duration(result.Parameters.RetryAfter) * time.Second,
+		}
+	}
+	return nil
+}
+
+// pollUpdates long-polls the Bot API for messages and redeems link codes sent as "/start <code>" or "<code>".
+func (n *TelegramNotifier) pollUpdates() {
+	var offset int64
+	for n.ctx.Err() == nil {
+		var updates telegramUpdates
+		resp, err := n.client.R().
+			SetContext(n.ctx).
+			SetQueryParam("offset", strconv.FormatInt(offset, 10)).
+			SetQueryParam("timeout", strconv.Itoa(int(n.cfg.PollTimeout.Seconds()))).
+			SetResult(&updates).
+			SetError(&updates).
+			Get("/getUpdates")
+		if err == nil && (resp.IsError() || !updates.OK) {
+			err = &telegramError{Status: resp.StatusCode(), Description: updates.Description}
+		}
+		if err != nil {
+			if n.ctx.Err() != nil {
+				return
+			}
+			n.log.Warn().Err(err).Msg("could not poll telegram updates")
+			select {
+			case <-n.ctx.Done():
+				return
+			case <-time.After(n.cfg.RetryBackoff):
+			}
+			continue
+		}
+		for _, update := range updates.Result {
+			offset = update.UpdateID + 1
+			if update.Message == nil {
+				continue
+			}
+			n.redeem(update.Message.Chat.ID, update.Message.Text)
+		}
+	}
+}
+
+// redeem links a chat to the owner of a link code and replies with the outcome.
+func (n *TelegramNotifier) redeem(chatID int64, text string) {
+	code := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(text), "/start"))
+	reply := "Send the link code from your Gophermart account to receive notifications here."
+	if code != "" {
+		_, err := n.chats.LinkTelegramChat(n.ctx, code, chatID)
+		var notFoundError *storageErrors.NotFoundError
+		switch {
+		case errors.As(err, &notFoundError):
+			reply = "The link code is invalid or has expired, request a new one in your Gophermart account."
+		case err != nil:
+			n.log.Warn().Err(err).Msg("could not link telegram chat")
+			reply = "Your chat could not be linked right now, please try again later."
+		default:
+			n.metrics.Counter("gophermart_telegram_links_total").Inc()
+			reply = "Your chat is linked, order and balance notifications will be sent here."
+		}
+	}
+	err := n.sendWithRetry(chatID, reply)
+	if err != nil {
+		n.log.Warn().Err(err).Msg("could not reply to telegram chat")
+	}
+}
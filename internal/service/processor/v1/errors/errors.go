@@ -2,6 +2,13 @@
 
 package errors
 
+import (
+	"fmt"
+	"time"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/errcodes"
+)
+
 type (
 	ServiceFoundNilArgument struct {
 		Msg string
@@ -12,16 +19,110 @@ type (
 	ServiceNotEnoughFunds struct {
 		Msg string
 	}
+	ServiceDuplicateRequest struct {
+		Msg string
+	}
+	ServiceIllegalLogin struct {
+		Msg string
+	}
+	ServiceIllegalAccrualStatus struct {
+		Msg string
+	}
+	ServiceIllegalFilter struct {
+		Msg string
+	}
+	ServiceIllegalScope struct {
+		Msg string
+	}
+	ServiceLoginLocked struct {
+		LockedUntil time.Time
+	}
+	ServiceInvalidCredentials struct {
+		Err               error
+		RemainingAttempts int
+	}
 )
 
 func (e *ServiceFoundNilArgument) Error() string {
 	return e.Msg
 }
 
+func (e *ServiceFoundNilArgument) ErrorCode() errcodes.Code {
+	return errcodes.DependencyMisconfigured
+}
+
 func (e *ServiceIllegalOrderNumber) Error() string {
 	return e.Msg
 }
 
+func (e *ServiceIllegalOrderNumber) ErrorCode() errcodes.Code {
+	return errcodes.OrderInvalidNumber
+}
+
 func (e *ServiceNotEnoughFunds) Error() string {
 	return e.Msg
 }
+
+func (e *ServiceNotEnoughFunds) ErrorCode() errcodes.Code {
+	return errcodes.InsufficientFunds
+}
+
+func (e *ServiceDuplicateRequest) Error() string {
+	return e.Msg
+}
+
+func (e *ServiceDuplicateRequest) ErrorCode() errcodes.Code {
+	return errcodes.DuplicateRequest
+}
+
+func (e *ServiceIllegalLogin) Error() string {
+	return e.Msg
+}
+
+func (e *ServiceIllegalLogin) ErrorCode() errcodes.Code {
+	return errcodes.InvalidRequest
+}
+
+func (e *ServiceIllegalAccrualStatus) Error() string {
+	return e.Msg
+}
+
+func (e *ServiceIllegalAccrualStatus) ErrorCode() errcodes.Code {
+	return errcodes.InvalidRequest
+}
+
+func (e *ServiceIllegalFilter) Error() string {
+	return e.Msg
+}
+
+func (e *ServiceIllegalFilter) ErrorCode() errcodes.Code {
+	return errcodes.InvalidRequest
+}
+
+func (e *ServiceIllegalScope) Error() string {
+	return e.Msg
+}
+
+func (e *ServiceIllegalScope) ErrorCode() errcodes.Code {
+	return errcodes.InvalidRequest
+}
+
+func (e *ServiceLoginLocked) Error() string {
+	return fmt.Sprintf("too many failed login attempts, locked until %s", e.LockedUntil.Format(time.RFC3339))
+}
+
+func (e *ServiceLoginLocked) ErrorCode() errcodes.Code {
+	return errcodes.TooManyRequests
+}
+
+func (e *ServiceInvalidCredentials) Error() string {
+	return fmt.Sprintf("%s: %d login attempts remaining", e.Err.Error(), e.RemainingAttempts)
+}
+
+func (e *ServiceInvalidCredentials) Unwrap() error {
+	return e.Err
+}
+
+func (e *ServiceInvalidCredentials) ErrorCode() errcodes.Code {
+	return errcodes.InvalidCredentials
+}
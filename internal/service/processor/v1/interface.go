@@ -4,18 +4,55 @@ package processor
 
 import (
 	"context"
+	"time"
 
 	"github.com/danilovkiri/dk-go-gophermart/internal/models/modeldto"
 )
 
 // Processor defines a set of methods for types implementing Processor.
 type Processor interface {
-	AddNewUser(ctx context.Context, credentials modeldto.User) (string, error)
-	LoginUser(ctx context.Context, credentials modeldto.User) (string, error)
+	AddNewUser(ctx context.Context, credentials modeldto.User, client modeldto.ClientInfo) (*modeldto.Tokens, error)
+	LoginUser(ctx context.Context, credentials modeldto.User, client modeldto.ClientInfo) (*modeldto.Tokens, error)
+	RefreshTokens(ctx context.Context, refreshToken string) (*modeldto.Tokens, error)
+	Logout(ctx context.Context, userID, sessionID string) error
+	GetLoginThrottles() []modeldto.LoginThrottle
+	ResetLoginThrottle(tenantID, login string) bool
+	SearchUsers(ctx context.Context, query string, limit, offset int) (*modeldto.UserSearchResult, error)
+	GetSessions(ctx context.Context, userID string) ([]modeldto.Session, error)
+	UpdateProfile(ctx context.Context, userID string, update modeldto.ProfileUpdate) (*modeldto.Profile, error)
+	CreateTelegramLinkCode(ctx context.Context, userID string) (*modeldto.TelegramLink, error)
+	GetAlertThresholds(ctx context.Context, userID string) (*modeldto.AlertThresholds, error)
+	SetAlertThresholds(ctx context.Context, userID string, thresholds modeldto.AlertThresholds) (*modeldto.AlertThresholds, error)
+	AcceptAccrual(ctx context.Context, callback modeldto.AccrualResponse) error
 	GetBalance(ctx context.Context, userID string) (*modeldto.Balance, error)
-	GetWithdrawals(ctx context.Context, userID string) ([]modeldto.Withdrawal, error)
-	GetOrders(ctx context.Context, userID string) ([]modeldto.Order, error)
-	AddNewWithdrawal(ctx context.Context, userID string, withdrawal modeldto.NewOrderWithdrawal) error
-	AddNewOrder(ctx context.Context, userID string, orderNumber string) error
-	GetUserID(accessToken string) (string, error)
+	GetConvertedBalance(ctx context.Context, userID string, currency string) (*modeldto.ConvertedBalance, error)
+	ConvertAmount(currency string, amount float64) (*modeldto.ConvertedAmount, error)
+	GetWithdrawals(ctx context.Context, userID string, sort modeldto.Sort) ([]modeldto.Withdrawal, error)
+	GetTransactions(ctx context.Context, userID string) ([]modeldto.Transaction, error)
+	GetOrders(ctx context.Context, userID string, sort modeldto.Sort) ([]modeldto.Order, error)
+	AddNewWithdrawal(ctx context.Context, userID string, withdrawal modeldto.NewOrderWithdrawal, idempotencyKey string) (*modeldto.Withdrawal, error)
+	GetWithdrawal(ctx context.Context, userID string, orderNumber string) (*modeldto.Withdrawal, error)
+	ReserveBalance(ctx context.Context, userID string, hold modeldto.NewBalanceHold) (*modeldto.BalanceHold, error)
+	GetHolds(ctx context.Context, userID string) ([]modeldto.BalanceHold, error)
+	CaptureHold(ctx context.Context, userID string, holdID uint) (*modeldto.BalanceHold, error)
+	ReleaseHold(ctx context.Context, userID string, holdID uint) (*modeldto.BalanceHold, error)
+	AddNewOrder(ctx context.Context, userID string, order modeldto.NewOrder) error
+	GetOrder(ctx context.Context, userID string, orderNumber string) (*modeldto.Order, error)
+	GetOrderHistory(ctx context.Context, userID string, orderNumber string) (*modeldto.OrderHistory, error)
+	RecheckOrder(ctx context.Context, userID string, orderNumber string) error
+	AdminRecheckOrder(ctx context.Context, orderNumber string) error
+	GetSuspendedOrders(ctx context.Context) ([]modeldto.SuspendedOrder, error)
+	GetAdminOrder(ctx context.Context, orderNumber string) (*modeldto.AdminOrder, error)
+	ApproveOrder(ctx context.Context, orderNumber string) error
+	RejectOrder(ctx context.Context, orderNumber string) error
+	RequeueOrders(ctx context.Context, request modeldto.RequeueRequest) (*modeldto.RequeueReport, error)
+	GetUserStats(ctx context.Context, userID string) (*modeldto.UserStats, error)
+	GetReconciliationReport(ctx context.Context) (*modeldto.ReconciliationReport, error)
+	RecalculateBalances(ctx context.Context, apply bool) (*modeldto.ReconciliationReport, error)
+	IssuePartnerToken(ctx context.Context, request modeldto.PartnerTokenRequest) (*modeldto.PartnerToken, error)
+	MergeAccounts(ctx context.Context, request modeldto.AccountMergeRequest) (*modeldto.AccountMerge, error)
+	TransferOrder(ctx context.Context, orderNumber string, request modeldto.OrderTransferRequest) (*modeldto.OrderTransfer, error)
+	AdjustBalance(ctx context.Context, userID string, request modeldto.BalanceAdjustmentRequest) (*modeldto.BalanceAdjustment, error)
+	EvaluateCashback(request modeldto.CashbackEvaluationRequest) *modeldto.CashbackEvaluation
+	GetSummary(ctx context.Context, windows []time.Duration) (*modeldto.AdminSummary, error)
 }
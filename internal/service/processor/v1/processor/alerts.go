@@ -0,0 +1,29 @@
+// Package processor provides intermediary layer functionality between the DB and API endpoint handlers.
+
+package processor
+
+import (
+	"context"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/models/modeldto"
+	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/modelstorage"
+)
+
+// GetAlertThresholds retrieves the balance alert thresholds of a user.
+func (proc *Processor) GetAlertThresholds(ctx context.Context, userID string) (*modeldto.AlertThresholds, error) {
+	thresholds, err := proc.storage.GetAlertThresholds(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &modeldto.AlertThresholds{LowBalance: thresholds.LowBalance, LargeAccrual: thresholds.LargeAccrual}, nil
+}
+
+// SetAlertThresholds replaces the balance alert thresholds of a user, omitted thresholds disable the corresponding
+// alerts.
+func (proc *Processor) SetAlertThresholds(ctx context.Context, userID string, thresholds modeldto.AlertThresholds) (*modeldto.AlertThresholds, error) {
+	err := proc.storage.SetAlertThresholds(ctx, userID, modelstorage.AlertThresholdsStorageEntry{LowBalance: thresholds.LowBalance, LargeAccrual: thresholds.LargeAccrual})
+	if err != nil {
+		return nil, err
+	}
+	return &thresholds, nil
+}
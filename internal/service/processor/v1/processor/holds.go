@@ -0,0 +1,80 @@
+// Package processor provides intermediary layer functionality between the DB and API endpoint handlers.
+
+package processor
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/models/modeldto"
+	"github.com/danilovkiri/dk-go-gophermart/internal/ordernum"
+	serviceErrors "github.com/danilovkiri/dk-go-gophermart/internal/service/processor/v1/errors"
+	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/modelstorage"
+)
+
+// ReserveBalance processes balance hold requests, the order number is validated the same way as for withdrawals.
+func (proc *Processor) ReserveBalance(ctx context.Context, userID string, hold modeldto.NewBalanceHold) (*modeldto.BalanceHold, error) {
+	orderNumber, err := ordernum.Normalize(hold.OrderNumber)
+	if err == nil {
+		err = proc.validator.Validate(orderNumber)
+	}
+	if err != nil {
+		return nil, &serviceErrors.ServiceIllegalOrderNumber{Msg: fmt.Sprintf("illegal order number %s", hold.OrderNumber)}
+	}
+	orderNumberInt, err := ordernum.Parse(orderNumber)
+	if err != nil {
+		return nil, &serviceErrors.ServiceIllegalOrderNumber{Msg: fmt.Sprintf("illegal order number %s", hold.OrderNumber)}
+	}
+	entry, err := proc.storage.AddHold(ctx, userID, orderNumberInt, hold.Amount)
+	if err != nil {
+		return nil, err
+	}
+	responseHold := proc.toHoldDTO(*entry)
+	return &responseHold, nil
+}
+
+// GetHolds processes balance holds query requests.
+func (proc *Processor) GetHolds(ctx context.Context, userID string) ([]modeldto.BalanceHold, error) {
+	holds, err := proc.storage.GetHolds(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	var responseHolds []modeldto.BalanceHold
+	for _, hold := range holds {
+		responseHolds = append(responseHolds, proc.toHoldDTO(hold))
+	}
+	return responseHolds, nil
+}
+
+// CaptureHold processes balance hold capture requests.
+func (proc *Processor) CaptureHold(ctx context.Context, userID string, holdID uint) (*modeldto.BalanceHold, error) {
+	entry, err := proc.storage.CaptureHold(ctx, userID, holdID)
+	if err != nil {
+		return nil, err
+	}
+	responseHold := proc.toHoldDTO(*entry)
+	return &responseHold, nil
+}
+
+// ReleaseHold processes balance hold release requests.
+func (proc *Processor) ReleaseHold(ctx context.Context, userID string, holdID uint) (*modeldto.BalanceHold, error) {
+	entry, err := proc.storage.ReleaseHold(ctx, userID, holdID)
+	if err != nil {
+		return nil, err
+	}
+	responseHold := proc.toHoldDTO(*entry)
+	return &responseHold, nil
+}
+
+// toHoldDTO converts a stored balance hold to its response representation.
+func (proc *Processor) toHoldDTO(hold modelstorage.BalanceHoldStorageEntry) modeldto.BalanceHold {
+	return modeldto.BalanceHold{
+		ID:          hold.ID,
+		OrderNumber: strconv.Itoa(hold.OrderNumber),
+		Amount:      hold.Amount,
+		Status:      hold.Status,
+		CreatedAt:   proc.formatTime(hold.CreatedAt),
+		ExpiresAt:   proc.formatTime(hold.ExpiresAt),
+	}
+}
@@ -0,0 +1,43 @@
+// Package processor provides intermediary layer functionality between the DB and API endpoint handlers.
+
+package processor
+
+import (
+	"context"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/models/modeldto"
+)
+
+// GetTransactions processes balance transaction history requests.
+func (proc *Processor) GetTransactions(ctx context.Context, userID string) ([]modeldto.Transaction, error) {
+	events, err := proc.storage.GetBalanceEvents(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	var transactions []modeldto.Transaction
+	for _, event := range events {
+		transactions = append(transactions, modeldto.Transaction{
+			Kind:      event.Kind,
+			Amount:    event.Amount,
+			Reference: event.Reference,
+			CreatedAt: proc.formatTime(event.CreatedAt),
+		})
+	}
+	return transactions, nil
+}
+
+// AdjustBalance processes admin manual balance adjustment requests.
+func (proc *Processor) AdjustBalance(ctx context.Context, userID string, request modeldto.BalanceAdjustmentRequest) (*modeldto.BalanceAdjustment, error) {
+	event, balance, err := proc.storage.AddAdjustment(ctx, userID, request)
+	if err != nil {
+		return nil, err
+	}
+	return &modeldto.BalanceAdjustment{
+		UserID:     userID,
+		Amount:     event.Amount,
+		ReasonCode: event.Reference,
+		Comment:    request.Comment,
+		Balance:    balance,
+		CreatedAt:  proc.formatTime(event.CreatedAt),
+	}, nil
+}
@@ -0,0 +1,72 @@
+// Package processor provides intermediary layer functionality between the DB and API endpoint handlers.
+
+package processor
+
+import (
+	"strings"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/models/modeldto"
+	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/modelstorage"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// NormalizeLogin trims surrounding whitespace, applies NFKC normalization and case folding so that visually
+// identical logins such as "User" and "user" map to the same account.
+func NormalizeLogin(login string) string {
+	return cases.Fold().String(norm.NFKC.String(strings.TrimSpace(login)))
+}
+
+// loginCandidates returns logins to look up upon authentication, the raw login is kept for accounts
+// registered before normalization was introduced.
+func loginCandidates(login string) []string {
+	normalized := NormalizeLogin(login)
+	if normalized == login {
+		return []string{normalized}
+	}
+	return []string{normalized, login}
+}
+
+// loginLookup builds the lookup of a login, its ciphertexts under every known key match users stored before
+// login digests were introduced until rotate-keys is run.
+func (proc *Processor) loginLookup(login string) (*modelstorage.LoginLookup, error) {
+	lookup := modelstorage.LoginLookup{Hash: proc.secretary.LoginHash(login)}
+	for _, keyID := range proc.secretary.KeyIDs() {
+		legacyLogin, err := proc.secretary.EncodeWithKey(keyID, login)
+		if err != nil {
+			return nil, err
+		}
+		lookup.Legacy = append(lookup.Legacy, legacyLogin)
+	}
+	return &lookup, nil
+}
+
+// loginThrottleKey identifies a normalized login of a tenant for login throttling as logins are unique per tenant.
+func loginThrottleKey(tenantID, login string) string {
+	return tenantID + "\x00" + login
+}
+
+// GetLoginThrottles processes admin requests listing logins with recent failed attempts.
+func (proc *Processor) GetLoginThrottles() []modeldto.LoginThrottle {
+	var throttles []modeldto.LoginThrottle
+	for _, status := range proc.throttle.Snapshot() {
+		parts := strings.SplitN(status.Key, "\x00", 2)
+		throttle := modeldto.LoginThrottle{
+			Tenant:            parts[0],
+			Login:             parts[1],
+			Failures:          status.Failures,
+			RemainingAttempts: status.RemainingAttempts,
+		}
+		if status.Locked() {
+			throttle.LockedUntil = proc.formatTime(status.LockedUntil)
+		}
+		throttles = append(throttles, throttle)
+	}
+	return throttles
+}
+
+// ResetLoginThrottle processes admin requests lifting the lockout of a login, it returns false if the login had
+// no failed attempts.
+func (proc *Processor) ResetLoginThrottle(tenantID, login string) bool {
+	return proc.throttle.Reset(loginThrottleKey(tenantID, NormalizeLogin(login)))
+}
@@ -0,0 +1,39 @@
+// Package processor provides intermediary layer functionality between the DB and API endpoint handlers.
+
+package processor
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/auth"
+	"github.com/danilovkiri/dk-go-gophermart/internal/models/modeldto"
+	serviceErrors "github.com/danilovkiri/dk-go-gophermart/internal/service/processor/v1/errors"
+	"github.com/danilovkiri/dk-go-gophermart/internal/tenant"
+)
+
+// IssuePartnerToken processes admin requests issuing a scoped token which lets a partner integration act on behalf
+// of a user within the granted scopes only.
+func (proc *Processor) IssuePartnerToken(ctx context.Context, request modeldto.PartnerTokenRequest) (*modeldto.PartnerToken, error) {
+	for _, scope := range request.Scopes {
+		if !auth.KnownScope(scope) {
+			return nil, &serviceErrors.ServiceIllegalScope{Msg: fmt.Sprintf("unknown scope %q", scope)}
+		}
+	}
+	ctx = tenant.WithTenant(ctx, request.Tenant)
+	user, err := proc.storage.GetUser(ctx, request.UserID)
+	if err != nil {
+		return nil, err
+	}
+	token, expiresAt, err := proc.secretary.GetScopedToken(user.UserID, user.TenantID, request.Partner, request.Scopes, proc.authCfg.PartnerTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+	return &modeldto.PartnerToken{
+		Partner:   request.Partner,
+		UserID:    user.UserID,
+		Scopes:    request.Scopes,
+		Token:     token,
+		ExpiresAt: proc.formatTime(expiresAt),
+	}, nil
+}
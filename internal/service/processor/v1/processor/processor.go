@@ -4,95 +4,404 @@ package processor
 
 import (
 	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"sort"
+	"math"
 	"strconv"
+	"strings"
 	"time"
 
-	"github.com/ShiraazMoollatjie/goluhn"
+	"github.com/danilovkiri/dk-go-gophermart/internal/auth"
+	"github.com/danilovkiri/dk-go-gophermart/internal/cache/v1"
+	"github.com/danilovkiri/dk-go-gophermart/internal/cashback"
+	"github.com/danilovkiri/dk-go-gophermart/internal/config"
 	"github.com/danilovkiri/dk-go-gophermart/internal/models/modeldto"
 	"github.com/danilovkiri/dk-go-gophermart/internal/models/modelqueue"
+	"github.com/danilovkiri/dk-go-gophermart/internal/ordernum"
+	"github.com/danilovkiri/dk-go-gophermart/internal/service/converter/v1"
+	"github.com/danilovkiri/dk-go-gophermart/internal/service/hasher/v1"
+	"github.com/danilovkiri/dk-go-gophermart/internal/service/notifier/v1"
 	serviceErrors "github.com/danilovkiri/dk-go-gophermart/internal/service/processor/v1/errors"
 	"github.com/danilovkiri/dk-go-gophermart/internal/service/secretary/v1"
+	"github.com/danilovkiri/dk-go-gophermart/internal/service/validator/v1"
 	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1"
+	storageErrors "github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/errors"
+	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/modelstorage"
+	"github.com/danilovkiri/dk-go-gophermart/internal/tenant"
+	"github.com/danilovkiri/dk-go-gophermart/internal/tracing"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Processor defines attributes of a struct available to its methods.
 type Processor struct {
+	// ctx is the service context, it outlives requests handing work over to background processing
+	ctx       context.Context
 	storage   storage.Storage
 	secretary secretary.Secretary
+	hasher    hasher.Hasher
+	cache     cache.Cache
+	validator validator.Validator
+	notifier  notifier.Notifier
+	converter converter.Converter
+	cashback  *cashback.Engine
+	cfg       *config.QueueConfig
+	authCfg   *config.AuthConfig
+	throttle  *auth.LoginThrottle
+	// location defines the timezone timestamps are rendered in
+	location *time.Location
 }
 
 // InitService initializes an intermediary service for data processing.
-func InitService(st storage.Storage, sec secretary.Secretary) (*Processor, error) {
+func InitService(ctx context.Context, st storage.Storage, sec secretary.Secretary, passwordHasher hasher.Hasher, serviceCache cache.Cache, orderValidator validator.Validator, userNotifier notifier.Notifier, rateConverter converter.Converter, cashbackEngine *cashback.Engine, loginThrottle *auth.LoginThrottle, cfg *config.QueueConfig, authCfg *config.AuthConfig, location *time.Location) (*Processor, error) {
 	if st == nil {
 		return nil, &serviceErrors.ServiceFoundNilArgument{Msg: "nil storage was passed to service initializer"}
 	}
 	if sec == nil {
 		return nil, &serviceErrors.ServiceFoundNilArgument{Msg: "nil secretary was passed to service initializer"}
 	}
+	if passwordHasher == nil {
+		return nil, &serviceErrors.ServiceFoundNilArgument{Msg: "nil password hasher was passed to service initializer"}
+	}
+	if serviceCache == nil {
+		return nil, &serviceErrors.ServiceFoundNilArgument{Msg: "nil cache was passed to service initializer"}
+	}
+	if orderValidator == nil {
+		return nil, &serviceErrors.ServiceFoundNilArgument{Msg: "nil validator was passed to service initializer"}
+	}
+	if userNotifier == nil {
+		return nil, &serviceErrors.ServiceFoundNilArgument{Msg: "nil notifier was passed to service initializer"}
+	}
+	if rateConverter == nil {
+		return nil, &serviceErrors.ServiceFoundNilArgument{Msg: "nil converter was passed to service initializer"}
+	}
+	if cashbackEngine == nil {
+		return nil, &serviceErrors.ServiceFoundNilArgument{Msg: "nil cashback engine was passed to service initializer"}
+	}
+	if loginThrottle == nil {
+		return nil, &serviceErrors.ServiceFoundNilArgument{Msg: "nil login throttle was passed to service initializer"}
+	}
 	processor := &Processor{
+		ctx:       ctx,
 		storage:   st,
 		secretary: sec,
+		hasher:    passwordHasher,
+		cache:     serviceCache,
+		validator: orderValidator,
+		notifier:  userNotifier,
+		converter: rateConverter,
+		cashback:  cashbackEngine,
+		throttle:  loginThrottle,
+		cfg:       cfg,
+		authCfg:   authCfg,
+		location:  location,
 	}
 	return processor, nil
 }
 
-// GetUserID retrieves deciphered user identifier from token.
-func (proc *Processor) GetUserID(accessToken string) (string, error) {
-	return proc.secretary.ValidateToken(accessToken)
+// AddNewUser processes user register requests, logins are normalized before ciphering and sealed with a random
+// nonce, users are looked up by login digests instead. Passwords are stored as salted hashes.
+func (proc *Processor) AddNewUser(ctx context.Context, credentials modeldto.User, client modeldto.ClientInfo) (*modeldto.Tokens, error) {
+	login := NormalizeLogin(credentials.Login)
+	if login == "" {
+		return nil, &serviceErrors.ServiceIllegalLogin{Msg: "login must not be empty"}
+	}
+	sessionID := uuid.New().String()
+	accessToken, userID, err := proc.secretary.NewToken(tenant.FromContext(ctx), sessionID)
+	if err != nil {
+		return nil, err
+	}
+	lookup, err := proc.loginLookup(login)
+	if err != nil {
+		return nil, err
+	}
+	sealedLogin, err := proc.secretary.Seal(login)
+	if err != nil {
+		return nil, err
+	}
+	passwordHash, err := proc.hasher.Hash(credentials.Password)
+	if err != nil {
+		return nil, err
+	}
+	cipheredCredentials := modeldto.User{
+		Login:    sealedLogin,
+		Password: passwordHash,
+	}
+	err = proc.storage.AddNewUser(ctx, cipheredCredentials, *lookup, userID)
+	if err != nil {
+		return nil, err
+	}
+	session := newSession(userID, sessionID, client)
+	refreshToken, err := proc.issueRefreshToken(&session)
+	if err != nil {
+		return nil, err
+	}
+	_, err = proc.storage.AddSession(ctx, session)
+	if err != nil {
+		return nil, err
+	}
+	return proc.newTokens(accessToken, refreshToken, session.RefreshExpiresAt), nil
+}
+
+// LoginUser processes user login requests, users are looked up by login digests and passwords are verified against
+// their stored hashes. Legacy ciphered passwords are deciphered for comparison, so credentials stored under previous
+// keys are matched until they are rotated, and replaced by hashes once matched.
+func (proc *Processor) LoginUser(ctx context.Context, credentials modeldto.User, client modeldto.ClientInfo) (tokens *modeldto.Tokens, err error) {
+	throttleKey := loginThrottleKey(tenant.FromContext(ctx), NormalizeLogin(credentials.Login))
+	if status := proc.throttle.Status(throttleKey); status.Locked() {
+		return nil, &serviceErrors.ServiceLoginLocked{LockedUntil: status.LockedUntil}
+	}
+	for _, login := range loginCandidates(credentials.Login) {
+		var lookup *modelstorage.LoginLookup
+		lookup, err = proc.loginLookup(login)
+		if err != nil {
+			return nil, err
+		}
+		var user *modelstorage.UserStorageEntry
+		user, err = proc.storage.CheckUser(ctx, *lookup)
+		var notFoundError *storageErrors.NotFoundError
+		if errors.As(err, &notFoundError) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		var matched bool
+		matched, err = proc.verifyPassword(ctx, user, credentials.Password)
+		if err != nil {
+			return nil, err
+		}
+		if !matched {
+			err = &storageErrors.NotFoundError{Err: nil}
+			continue
+		}
+		sessionID := uuid.New().String()
+		var accessToken string
+		accessToken, err = proc.secretary.GetTokenForUser(user.UserID, tenant.FromContext(ctx), sessionID)
+		if err != nil {
+			return nil, err
+		}
+		session := newSession(user.UserID, sessionID, client)
+		var refreshToken string
+		refreshToken, err = proc.issueRefreshToken(&session)
+		if err != nil {
+			return nil, err
+		}
+		err = proc.recordLogin(ctx, session, client)
+		if err != nil {
+			return nil, err
+		}
+		proc.throttle.Succeed(throttleKey)
+		return proc.newTokens(accessToken, refreshToken, session.RefreshExpiresAt), nil
+	}
+	var notFoundError *storageErrors.NotFoundError
+	if errors.As(err, &notFoundError) && proc.throttle.Enabled() {
+		status := proc.throttle.Fail(throttleKey)
+		if status.Locked() {
+			return nil, &serviceErrors.ServiceLoginLocked{LockedUntil: status.LockedUntil}
+		}
+		return nil, &serviceErrors.ServiceInvalidCredentials{Err: err, RemainingAttempts: status.RemainingAttempts}
+	}
+	return nil, err
 }
 
-// AddNewUser processes user register requests.
-func (proc *Processor) AddNewUser(ctx context.Context, credentials modeldto.User) (string, error) {
-	accessToken, userID, err := proc.secretary.NewToken()
+// verifyPassword matches a password against the stored one of a user, legacy ciphered passwords and hashes of
+// an outdated cost are rehashed once matched.
+func (proc *Processor) verifyPassword(ctx context.Context, user *modelstorage.UserStorageEntry, password string) (bool, error) {
+	if proc.hasher.Hashed(user.Password) {
+		matched, err := proc.hasher.Verify(user.Password, password)
+		if err != nil || !matched {
+			return false, err
+		}
+		if proc.hasher.NeedsRehash(user.Password) {
+			proc.rehashPassword(ctx, user.UserID, password)
+		}
+		return true, nil
+	}
+	storedPassword, err := proc.secretary.Decode(user.Password)
 	if err != nil {
-		return "", err
+		return false, err
 	}
-	cipheredCredentials := modeldto.User{
-		Login:    proc.secretary.Encode(credentials.Login),
-		Password: proc.secretary.Encode(credentials.Password),
+	if subtle.ConstantTimeCompare([]byte(storedPassword), []byte(password)) != 1 {
+		return false, nil
 	}
-	err = proc.storage.AddNewUser(ctx, cipheredCredentials, userID)
+	proc.rehashPassword(ctx, user.UserID, password)
+	return true, nil
+}
+
+// rehashPassword stores a fresh hash of a verified password, a failure does not affect the login as the stored
+// password keeps being accepted and is rehashed upon the next one.
+func (proc *Processor) rehashPassword(ctx context.Context, userID, password string) {
+	passwordHash, err := proc.hasher.Hash(password)
 	if err != nil {
-		return "", err
+		return
 	}
-	return accessToken, nil
+	_ = proc.storage.UpdatePasswordHash(ctx, userID, passwordHash)
 }
 
-// LoginUser processes user login requests.
-func (proc *Processor) LoginUser(ctx context.Context, credentials modeldto.User) (string, error) {
-	cipheredCredentials := modeldto.User{
-		Login:    proc.secretary.Encode(credentials.Login),
-		Password: proc.secretary.Encode(credentials.Password),
+// recordLogin stores a session for an issued token and notifies the user when it comes from an unseen device.
+func (proc *Processor) recordLogin(ctx context.Context, session modelstorage.SessionStorageEntry, client modeldto.ClientInfo) error {
+	newDevice, err := proc.storage.AddSession(ctx, session)
+	if err != nil {
+		return err
+	}
+	if !newDevice {
+		return nil
 	}
-	userID, err := proc.storage.CheckUser(ctx, cipheredCredentials)
+	return proc.notifier.Notify(ctx, modeldto.Notification{
+		Kind:    "new_device_login",
+		UserID:  session.UserID,
+		Message: fmt.Sprintf("login from a new device %q at %s", client.UserAgent, client.IP),
+	})
+}
+
+// RefreshTokens processes refresh token exchange requests, the access token is issued for the session of the
+// refresh token which is replaced by a new one.
+func (proc *Processor) RefreshTokens(ctx context.Context, refreshToken string) (*modeldto.Tokens, error) {
+	var rotated modelstorage.SessionStorageEntry
+	newRefreshToken, err := proc.issueRefreshToken(&rotated)
+	if err != nil {
+		return nil, err
+	}
+	session, err := proc.storage.RotateRefreshToken(ctx, refreshTokenHash(refreshToken), rotated.RefreshHash, rotated.RefreshExpiresAt, proc.authCfg.SessionLifetime)
+	if err != nil {
+		return nil, err
+	}
+	accessToken, err := proc.secretary.GetTokenForUser(session.UserID, tenant.FromContext(ctx), session.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	return proc.newTokens(accessToken, newRefreshToken, session.RefreshExpiresAt), nil
+}
+
+// Logout processes logout requests revoking the session of the access token.
+func (proc *Processor) Logout(ctx context.Context, userID, sessionID string) error {
+	return proc.storage.RevokeSession(ctx, userID, sessionID)
+}
+
+// issueRefreshToken generates a refresh token for a session, only its digest is kept in the session.
+func (proc *Processor) issueRefreshToken(session *modelstorage.SessionStorageEntry) (string, error) {
+	refreshToken, err := proc.secretary.NewRefreshToken()
 	if err != nil {
 		return "", err
 	}
-	return proc.secretary.GetTokenForUser(userID)
+	session.RefreshHash = refreshTokenHash(refreshToken)
+	session.RefreshExpiresAt = time.Now().Add(proc.authCfg.RefreshTokenTTL)
+	return refreshToken, nil
 }
 
-// GetBalance processes balance query requests.
-func (proc *Processor) GetBalance(ctx context.Context, userID string) (*modeldto.Balance, error) {
-	currentAmount, err := proc.storage.GetCurrentAmount(ctx, userID)
+// newTokens builds the tokens handed out upon login or refresh.
+func (proc *Processor) newTokens(accessToken, refreshToken string, refreshExpiresAt time.Time) *modeldto.Tokens {
+	return &modeldto.Tokens{
+		AccessToken:      accessToken,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: proc.formatTime(refreshExpiresAt),
+	}
+}
+
+// refreshTokenHash returns the digest of a refresh token, refresh tokens are random enough to need no key.
+func refreshTokenHash(refreshToken string) string {
+	digest := sha256.Sum256([]byte(refreshToken))
+	return hex.EncodeToString(digest[:])
+}
+
+// newSession builds a session storage entry, devices are fingerprinted by their User-Agent.
+func newSession(userID, sessionID string, client modeldto.ClientInfo) modelstorage.SessionStorageEntry {
+	fingerprint := sha256.Sum256([]byte(client.UserAgent))
+	return modelstorage.SessionStorageEntry{
+		UserID:      userID,
+		UserAgent:   client.UserAgent,
+		IP:          client.IP,
+		Fingerprint: hex.EncodeToString(fingerprint[:]),
+		SessionID:   sessionID,
+	}
+}
+
+// GetSessions processes session listing requests.
+func (proc *Processor) GetSessions(ctx context.Context, userID string) ([]modeldto.Session, error) {
+	sessions, err := proc.storage.GetSessions(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
-	withdrawnAmount, err := proc.storage.GetWithdrawnAmount(ctx, userID)
+	var responseSessions []modeldto.Session
+	for _, session := range sessions {
+		responseSessions = append(responseSessions, modeldto.Session{
+			UserAgent: session.UserAgent,
+			IP:        session.IP,
+			CreatedAt: proc.formatTime(session.CreatedAt),
+			LastSeen:  proc.formatTime(session.LastSeen),
+		})
+	}
+	return responseSessions, nil
+}
+
+// GetBalance processes balance query requests.
+func (proc *Processor) GetBalance(ctx context.Context, userID string) (*modeldto.Balance, error) {
+	ctx, span := startSpan(ctx, "processor.GetBalance", userID)
+	defer span.End()
+	if balance, ok := proc.cache.GetBalance(ctx, userID); ok {
+		return balance, nil
+	}
+	amounts, err := proc.storage.GetBalanceAmounts(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
 	balance := modeldto.Balance{
-		CurrentAmount:   currentAmount,
-		WithdrawnAmount: withdrawnAmount,
+		CurrentAmount:   amounts.CurrentAmount,
+		WithdrawnAmount: amounts.WithdrawnAmount,
+		HeldAmount:      amounts.HeldAmount,
+		UpdatedAt:       amounts.UpdatedAt,
 	}
+	proc.cache.SetBalance(ctx, userID, balance)
 	return &balance, nil
 }
 
+// GetConvertedBalance processes converted balance query requests.
+func (proc *Processor) GetConvertedBalance(ctx context.Context, userID string, currency string) (*modeldto.ConvertedBalance, error) {
+	rate, err := proc.converter.Rate(currency)
+	if err != nil {
+		return nil, err
+	}
+	balance, err := proc.GetBalance(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &modeldto.ConvertedBalance{
+		Currency:        strings.ToUpper(currency),
+		Rate:            rate,
+		CurrentAmount:   convert(balance.CurrentAmount, rate),
+		WithdrawnAmount: convert(balance.WithdrawnAmount, rate),
+	}, nil
+}
+
+// ConvertAmount converts an amount of points to a currency.
+func (proc *Processor) ConvertAmount(currency string, amount float64) (*modeldto.ConvertedAmount, error) {
+	rate, err := proc.converter.Rate(currency)
+	if err != nil {
+		return nil, err
+	}
+	return &modeldto.ConvertedAmount{
+		Currency: strings.ToUpper(currency),
+		Rate:     rate,
+		Amount:   convert(amount, rate),
+	}, nil
+}
+
+// convert converts points to currency units rounding to cents.
+func convert(amount float64, rate float64) float64 {
+	return math.Round(amount*rate*100) / 100
+}
+
 // GetWithdrawals processes withdrawals query requests.
-func (proc *Processor) GetWithdrawals(ctx context.Context, userID string) ([]modeldto.Withdrawal, error) {
-	withdrawals, err := proc.storage.GetWithdrawals(ctx, userID)
+func (proc *Processor) GetWithdrawals(ctx context.Context, userID string, sort modeldto.Sort) ([]modeldto.Withdrawal, error) {
+	ctx, span := startSpan(ctx, "processor.GetWithdrawals", userID)
+	defer span.End()
+	withdrawals, err := proc.storage.GetWithdrawals(ctx, userID, sort)
 	if err != nil {
 		return nil, err
 	}
@@ -101,80 +410,447 @@ func (proc *Processor) GetWithdrawals(ctx context.Context, userID string) ([]mod
 		responseWithdrawal := modeldto.Withdrawal{
 			OrderNumber:     strconv.Itoa(withdrawal.OrderNumber),
 			WithdrawnAmount: withdrawal.Amount,
-			ProcessedAt:     withdrawal.ProcessedAt,
+			ProcessedAt:     proc.formatTime(withdrawal.ProcessedAt),
+			Status:          withdrawal.Status,
 		}
 		responseWithdrawals = append(responseWithdrawals, responseWithdrawal)
 	}
-	sort.Slice(responseWithdrawals, func(i, j int) bool {
-		time1, _ := time.Parse(time.RFC3339, responseWithdrawals[i].ProcessedAt)
-		time2, _ := time.Parse(time.RFC3339, responseWithdrawals[j].ProcessedAt)
-		return time1.Before(time2)
-	})
 	return responseWithdrawals, nil
 }
 
-// GetOrders processes orders query requests.
-func (proc *Processor) GetOrders(ctx context.Context, userID string) ([]modeldto.Order, error) {
-	orders, err := proc.storage.GetOrders(ctx, userID)
+// GetOrders processes orders query requests, only the default ordering is cached.
+func (proc *Processor) GetOrders(ctx context.Context, userID string, sort modeldto.Sort) ([]modeldto.Order, error) {
+	ctx, span := startSpan(ctx, "processor.GetOrders", userID)
+	defer span.End()
+	cacheable := sort == modeldto.Sort{}
+	if cacheable {
+		if responseOrders, ok := proc.cache.GetOrders(ctx, userID); ok {
+			return responseOrders, nil
+		}
+	}
+	orders, err := proc.storage.GetOrders(ctx, userID, sort)
 	if err != nil {
 		return nil, err
 	}
 	var responseOrders []modeldto.Order
 	for _, order := range orders {
-		responseOrder := modeldto.Order{
-			OrderNumber: strconv.Itoa(order.OrderNumber),
-			Status:      order.Status,
-			Accrual:     order.Accrual,
-			UploadedAt:  order.CreatedAt,
-		}
-		responseOrders = append(responseOrders, responseOrder)
+		responseOrders = append(responseOrders, proc.toOrderDTO(order))
+	}
+	if cacheable {
+		proc.cache.SetOrders(ctx, userID, responseOrders)
 	}
-	sort.Slice(responseOrders, func(i, j int) bool {
-		time1, _ := time.Parse(time.RFC3339, responseOrders[i].UploadedAt)
-		time2, _ := time.Parse(time.RFC3339, responseOrders[j].UploadedAt)
-		return time1.Before(time2)
-	})
 	return responseOrders, nil
 }
 
-// AddNewWithdrawal processes new withdrawal requests.
-func (proc *Processor) AddNewWithdrawal(ctx context.Context, userID string, withdrawal modeldto.NewOrderWithdrawal) error {
-	err := goluhn.Validate(withdrawal.OrderNumber)
+// AddNewWithdrawal processes new withdrawal requests, in asynchronous mode the withdrawal is accepted as PENDING and debited later.
+func (proc *Processor) AddNewWithdrawal(ctx context.Context, userID string, withdrawal modeldto.NewOrderWithdrawal, idempotencyKey string) (result *modeldto.Withdrawal, err error) {
+	ctx, span := startSpan(ctx, "processor.AddNewWithdrawal", userID, tracing.AttrOrderNumber.String(withdrawal.OrderNumber))
+	defer span.End()
+	withdrawal.OrderNumber, err = ordernum.Normalize(withdrawal.OrderNumber)
+	if err == nil {
+		err = proc.validator.Validate(withdrawal.OrderNumber)
+	}
 	if err != nil {
-		return &serviceErrors.ServiceIllegalOrderNumber{Msg: fmt.Sprintf("illegal order number %s", withdrawal.OrderNumber)}
+		return nil, &serviceErrors.ServiceIllegalOrderNumber{Msg: fmt.Sprintf("illegal order number %s", withdrawal.OrderNumber)}
+	}
+	if idempotencyKey != "" {
+		key := "withdrawal:" + userID + ":" + idempotencyKey
+		reserved, err := proc.cache.ReserveKey(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		if !reserved {
+			return nil, &serviceErrors.ServiceDuplicateRequest{Msg: fmt.Sprintf("request with idempotency key %s was already processed", idempotencyKey)}
+		}
+		// release the key upon failure so that the request can be retried
+		defer func() {
+			if err != nil {
+				proc.cache.ReleaseKey(ctx, key)
+			}
+		}()
 	}
 	currentAmount, err := proc.storage.GetCurrentAmount(ctx, userID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if currentAmount < withdrawal.Amount {
-		return &serviceErrors.ServiceNotEnoughFunds{Msg: fmt.Sprintf("not enough funds are available, present - %v, required - %v", currentAmount, withdrawal.Amount)}
+		return nil, &serviceErrors.ServiceNotEnoughFunds{Msg: fmt.Sprintf("not enough funds are available, present - %v, required - %v", currentAmount, withdrawal.Amount)}
+	}
+	result = &modeldto.Withdrawal{
+		OrderNumber:     withdrawal.OrderNumber,
+		WithdrawnAmount: withdrawal.Amount,
+		ProcessedAt:     proc.formatTime(time.Now()),
+		Status:          "PROCESSED",
+	}
+	if proc.cfg.AsyncWithdrawals {
+		var withdrawalID uint
+		withdrawalID, err = proc.storage.AddPendingWithdrawal(ctx, userID, withdrawal)
+		if err != nil {
+			return nil, err
+		}
+		// the withdrawal is already stored as pending, enqueueing must not give up once the request is over
+		proc.storage.SendWithdrawalToQueue(proc.ctx, modelqueue.WithdrawalQueueEntry{
+			ID:          withdrawalID,
+			TenantID:    tenant.FromContext(ctx),
+			UserID:      userID,
+			OrderNumber: withdrawal.OrderNumber,
+			Amount:      withdrawal.Amount,
+		})
+		result.Status = "PENDING"
+		return result, nil
 	}
 	err = proc.storage.AddNewWithdrawal(ctx, userID, withdrawal)
+	var negativeBalanceError *storageErrors.NegativeBalanceError
+	var insufficientFundsError *storageErrors.InsufficientFundsError
+	if errors.As(err, &negativeBalanceError) || errors.As(err, &insufficientFundsError) {
+		// a concurrent withdrawal has spent the funds after the balance check
+		return nil, &serviceErrors.ServiceNotEnoughFunds{Msg: fmt.Sprintf("not enough funds are available, required - %v", withdrawal.Amount)}
+	}
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return nil
+	return result, nil
+}
+
+// GetWithdrawal processes single withdrawal status query requests.
+func (proc *Processor) GetWithdrawal(ctx context.Context, userID, orderNumber string) (*modeldto.Withdrawal, error) {
+	ctx, span := startSpan(ctx, "processor.GetWithdrawal", userID, tracing.AttrOrderNumber.String(orderNumber))
+	defer span.End()
+	withdrawal, err := proc.storage.GetWithdrawal(ctx, userID, orderNumber)
+	if err != nil {
+		return nil, err
+	}
+	return &modeldto.Withdrawal{
+		OrderNumber:     strconv.Itoa(withdrawal.OrderNumber),
+		WithdrawnAmount: withdrawal.Amount,
+		ProcessedAt:     proc.formatTime(withdrawal.ProcessedAt),
+		Status:          withdrawal.Status,
+	}, nil
+}
+
+// GetOrder processes single order query requests.
+func (proc *Processor) GetOrder(ctx context.Context, userID, orderNumber string) (*modeldto.Order, error) {
+	ctx, span := startSpan(ctx, "processor.GetOrder", userID, tracing.AttrOrderNumber.String(orderNumber))
+	defer span.End()
+	orderNumberInt, err := ordernum.Parse(orderNumber)
+	if err != nil {
+		return nil, &serviceErrors.ServiceIllegalOrderNumber{Msg: fmt.Sprintf("illegal order number %s", orderNumber)}
+	}
+	order, err := proc.storage.GetOrder(ctx, userID, orderNumberInt)
+	if err != nil {
+		return nil, err
+	}
+	responseOrder := proc.toOrderDTO(*order)
+	return &responseOrder, nil
+}
+
+// GetOrderHistory processes order status history requests, transitions are listed in chronological order.
+func (proc *Processor) GetOrderHistory(ctx context.Context, userID, orderNumber string) (*modeldto.OrderHistory, error) {
+	ctx, span := startSpan(ctx, "processor.GetOrderHistory", userID, tracing.AttrOrderNumber.String(orderNumber))
+	defer span.End()
+	orderNumberInt, err := ordernum.Parse(orderNumber)
+	if err != nil {
+		return nil, &serviceErrors.ServiceIllegalOrderNumber{Msg: fmt.Sprintf("illegal order number %s", orderNumber)}
+	}
+	// the order lookup tells a missing order apart from one which has not changed its status yet
+	order, err := proc.storage.GetOrder(ctx, userID, orderNumberInt)
+	if err != nil {
+		return nil, err
+	}
+	history, err := proc.storage.GetOrderStatusHistory(ctx, userID, orderNumberInt)
+	if err != nil {
+		return nil, err
+	}
+	responseHistory := modeldto.OrderHistory{
+		OrderNumber: orderNumber,
+		Status:      order.Status,
+		UploadedAt:  proc.formatTime(order.CreatedAt),
+		Transitions: []modeldto.OrderStatusTransition{},
+	}
+	for _, transition := range history {
+		responseHistory.Transitions = append(responseHistory.Transitions, modeldto.OrderStatusTransition{
+			From:      transition.FromStatus,
+			To:        transition.ToStatus,
+			Source:    transition.Source,
+			ChangedAt: proc.formatTime(transition.ChangedAt),
+		})
+	}
+	return &responseHistory, nil
+}
+
+// GetUserStats processes user order statistics requests.
+func (proc *Processor) GetUserStats(ctx context.Context, userID string) (*modeldto.UserStats, error) {
+	stats, err := proc.storage.GetUserStats(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	responseStats := modeldto.UserStats{Channels: []modeldto.ChannelStats{}}
+	for _, channelStats := range stats {
+		responseStats.Channels = append(responseStats.Channels, modeldto.ChannelStats{
+			Channel: channelStats.Channel,
+			Orders:  channelStats.Orders,
+			Accrual: channelStats.Accrual,
+		})
+	}
+	return &responseStats, nil
+}
+
+// AcceptAccrual processes final order statuses pushed by the accrual service.
+func (proc *Processor) AcceptAccrual(ctx context.Context, callback modeldto.AccrualResponse) error {
+	ctx, span := tracing.Start(ctx, "processor.AcceptAccrual", tracing.AttrOrderNumber.String(callback.OrderNumber))
+	defer span.End()
+	orderNumber, err := ordernum.Parse(callback.OrderNumber)
+	if err != nil {
+		return &serviceErrors.ServiceIllegalOrderNumber{Msg: fmt.Sprintf("illegal order number %s", callback.OrderNumber)}
+	}
+	if callback.OrderStatus != "PROCESSED" && callback.OrderStatus != "INVALID" {
+		return &serviceErrors.ServiceIllegalAccrualStatus{Msg: fmt.Sprintf("only final statuses are accepted, got %q", callback.OrderStatus)}
+	}
+	if callback.Accrual < 0 {
+		return &serviceErrors.ServiceIllegalAccrualStatus{Msg: "accrual must not be negative"}
+	}
+	return proc.storage.ResolveOrder(ctx, orderNumber, callback.OrderStatus, callback.Accrual)
+}
+
+// startSpan starts a processor span of a user's request, the user is identified by the hash of their ID only.
+func startSpan(ctx context.Context, name, userID string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if userID != "" {
+		attrs = append(attrs, tracing.UserIDHash(userID))
+	}
+	return tracing.Start(ctx, name, attrs...)
+}
+
+// formatTime renders a timestamp in RFC3339 within the display timezone.
+func (proc *Processor) formatTime(t time.Time) string {
+	return t.In(proc.location).Format(time.RFC3339)
+}
+
+// toOrderDTO converts an order storage entry to its transfer representation.
+func (proc *Processor) toOrderDTO(order modelstorage.OrderStorageEntry) modeldto.Order {
+	responseOrder := modeldto.Order{
+		OrderNumber: strconv.Itoa(order.OrderNumber),
+		Status:      order.Status,
+		Accrual:     order.Accrual,
+		UploadedAt:  proc.formatTime(order.CreatedAt),
+	}
+	// accruals awaiting approval are not credited yet
+	if order.Status == "SUSPENDED" {
+		responseOrder.Accrual = 0
+	}
+	if order.Metadata != "" {
+		responseOrder.Metadata = json.RawMessage(order.Metadata)
+	}
+	return responseOrder
 }
 
 // AddNewOrder processes new order requests.
-func (proc *Processor) AddNewOrder(ctx context.Context, userID, orderNumber string) error {
-	err := goluhn.Validate(orderNumber)
+func (proc *Processor) AddNewOrder(ctx context.Context, userID string, order modeldto.NewOrder) error {
+	ctx, span := startSpan(ctx, "processor.AddNewOrder", userID, tracing.AttrOrderNumber.String(order.OrderNumber))
+	defer span.End()
+	orderNumber, err := ordernum.Normalize(order.OrderNumber)
+	if err != nil {
+		return &serviceErrors.ServiceIllegalOrderNumber{Msg: fmt.Sprintf("illegal order number %s", order.OrderNumber)}
+	}
+	err = proc.validator.Validate(orderNumber)
 	if err != nil {
 		return &serviceErrors.ServiceIllegalOrderNumber{Msg: fmt.Sprintf("illegal order number %s", orderNumber)}
 	}
-	orderNumberInt, err := strconv.Atoi(orderNumber)
+	orderNumberInt, err := ordernum.Parse(orderNumber)
 	if err != nil {
 		return &serviceErrors.ServiceIllegalOrderNumber{Msg: fmt.Sprintf("illegal order number %s", orderNumber)}
 	}
-	err = proc.storage.AddNewOrder(ctx, userID, orderNumberInt)
+	err = proc.storage.AddNewOrder(ctx, userID, orderNumberInt, string(order.Metadata), order.Channel)
 	if err != nil {
 		return err
 	}
+	proc.cache.InvalidateOrders(ctx, userID)
 	proc.storage.SendToQueue(modelqueue.OrderQueueEntry{
+		TenantID:    tenant.FromContext(ctx),
 		UserID:      userID,
 		OrderNumber: orderNumberInt,
 		OrderStatus: "NEW",
+		TraceParent: tracing.TraceParent(ctx),
 	})
 	return nil
 }
+
+// GetReconciliationReport processes balance reconciliation report requests.
+func (proc *Processor) GetReconciliationReport(ctx context.Context) (*modeldto.ReconciliationReport, error) {
+	return proc.storage.GetReconciliationReport(ctx)
+}
+
+// RecalculateBalances processes balance recalculation requests.
+func (proc *Processor) RecalculateBalances(ctx context.Context, apply bool) (*modeldto.ReconciliationReport, error) {
+	return proc.storage.RecalculateBalances(ctx, apply)
+}
+
+// MergeAccounts processes admin account merge requests.
+func (proc *Processor) MergeAccounts(ctx context.Context, request modeldto.AccountMergeRequest) (*modeldto.AccountMerge, error) {
+	return proc.storage.MergeUsers(ctx, request.DonorID, request.TargetID)
+}
+
+// TransferOrder processes admin requests moving an order uploaded under a wrong account to another one.
+func (proc *Processor) TransferOrder(ctx context.Context, orderNumber string, request modeldto.OrderTransferRequest) (*modeldto.OrderTransfer, error) {
+	orderNumberInt, err := ordernum.Parse(orderNumber)
+	if err != nil {
+		return nil, &serviceErrors.ServiceIllegalOrderNumber{Msg: fmt.Sprintf("illegal order number %s", orderNumber)}
+	}
+	return proc.storage.TransferOrder(ctx, orderNumberInt, request.TargetID, request.Comment)
+}
+
+// GetSummary processes admin operational summary requests.
+func (proc *Processor) GetSummary(ctx context.Context, windows []time.Duration) (*modeldto.AdminSummary, error) {
+	return proc.storage.GetSummary(ctx, windows)
+}
+
+// EvaluateCashback evaluates cashback rules against a hypothetical order without crediting anything.
+func (proc *Processor) EvaluateCashback(request modeldto.CashbackEvaluationRequest) *modeldto.CashbackEvaluation {
+	uploadedAt := request.UploadedAt
+	if uploadedAt.IsZero() {
+		uploadedAt = time.Now()
+	}
+	channel := request.Channel
+	if channel == "" {
+		channel = "unknown"
+	}
+	result := proc.cashback.Apply(cashback.Order{Accrual: request.Accrual, Channel: channel, UploadedAt: uploadedAt})
+	return &modeldto.CashbackEvaluation{
+		BaseAccrual: result.BaseAccrual,
+		Accrual:     result.Accrual,
+		Rule:        result.Rule,
+	}
+}
+
+// RecheckOrder processes requests to check a user's INVALID order against the accrual service once more, rechecks
+// are rate limited.
+func (proc *Processor) RecheckOrder(ctx context.Context, userID, orderNumber string) error {
+	return proc.recheckOrder(ctx, userID, orderNumber, &modelstorage.RecheckLimit{Interval: proc.cfg.RecheckInterval, PerUser: proc.cfg.RecheckUserLimit})
+}
+
+// AdminRecheckOrder processes admin requests to check any INVALID order against the accrual service once more.
+func (proc *Processor) AdminRecheckOrder(ctx context.Context, orderNumber string) error {
+	return proc.recheckOrder(ctx, "", orderNumber, nil)
+}
+
+// recheckOrder resets an INVALID order and queues it.
+func (proc *Processor) recheckOrder(ctx context.Context, userID, orderNumber string, limit *modelstorage.RecheckLimit) error {
+	ctx, span := startSpan(ctx, "processor.RecheckOrder", userID, tracing.AttrOrderNumber.String(orderNumber))
+	defer span.End()
+	orderNumberInt, err := ordernum.Parse(orderNumber)
+	if err != nil {
+		return &serviceErrors.ServiceIllegalOrderNumber{Msg: fmt.Sprintf("illegal order number %s", orderNumber)}
+	}
+	order, err := proc.storage.RecheckOrder(ctx, userID, orderNumberInt, limit)
+	if err != nil {
+		return err
+	}
+	proc.cache.InvalidateOrders(ctx, order.UserID)
+	proc.storage.SendToQueue(modelqueue.OrderQueueEntry{
+		TenantID:    order.TenantID,
+		UserID:      order.UserID,
+		OrderNumber: order.OrderNumber,
+		OrderStatus: order.Status,
+		TraceParent: tracing.TraceParent(ctx),
+	})
+	return nil
+}
+
+// GetSuspendedOrders processes admin requests listing orders whose accrual awaits approval.
+func (proc *Processor) GetSuspendedOrders(ctx context.Context) ([]modeldto.SuspendedOrder, error) {
+	orders, err := proc.storage.GetSuspendedOrders(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var responseOrders []modeldto.SuspendedOrder
+	for _, order := range orders {
+		responseOrders = append(responseOrders, modeldto.SuspendedOrder{
+			OrderNumber: strconv.Itoa(order.OrderNumber),
+			UserID:      order.UserID,
+			Tenant:      order.TenantID,
+			Accrual:     order.Accrual,
+			UploadedAt:  proc.formatTime(order.CreatedAt),
+		})
+	}
+	return responseOrders, nil
+}
+
+// GetAdminOrder processes admin requests viewing an order of any tenant along with the latest raw response
+// of the accrual system, if any was received.
+func (proc *Processor) GetAdminOrder(ctx context.Context, orderNumber string) (*modeldto.AdminOrder, error) {
+	orderNumberInt, err := ordernum.Parse(orderNumber)
+	if err != nil {
+		return nil, &serviceErrors.ServiceIllegalOrderNumber{Msg: fmt.Sprintf("illegal order number %s", orderNumber)}
+	}
+	order, err := proc.storage.GetAnyOrder(ctx, orderNumberInt)
+	if err != nil {
+		return nil, err
+	}
+	responseOrder := modeldto.AdminOrder{
+		OrderNumber: strconv.Itoa(order.OrderNumber),
+		UserID:      order.UserID,
+		Tenant:      order.TenantID,
+		Status:      order.Status,
+		Accrual:     order.Accrual,
+		UploadedAt:  proc.formatTime(order.CreatedAt),
+	}
+	response, err := proc.storage.GetAccrualResponse(ctx, orderNumberInt)
+	var notFoundError *storageErrors.NotFoundError
+	if err != nil && !errors.As(err, &notFoundError) {
+		return nil, err
+	}
+	if err == nil {
+		responseOrder.AccrualResponse = &modeldto.AccrualResponseRecord{
+			StatusCode: response.StatusCode,
+			Body:       string(response.Body),
+			ReceivedAt: proc.formatTime(response.ReceivedAt),
+		}
+	}
+	return &responseOrder, nil
+}
+
+// ApproveOrder processes admin requests crediting the suspended accrual of an order.
+func (proc *Processor) ApproveOrder(ctx context.Context, orderNumber string) error {
+	return proc.reviewOrder(ctx, orderNumber, true)
+}
+
+// RejectOrder processes admin requests invalidating an order with a suspended accrual.
+func (proc *Processor) RejectOrder(ctx context.Context, orderNumber string) error {
+	return proc.reviewOrder(ctx, orderNumber, false)
+}
+
+// reviewOrder resolves a suspended order.
+func (proc *Processor) reviewOrder(ctx context.Context, orderNumber string, approve bool) error {
+	orderNumberInt, err := ordernum.Parse(orderNumber)
+	if err != nil {
+		return &serviceErrors.ServiceIllegalOrderNumber{Msg: fmt.Sprintf("illegal order number %s", orderNumber)}
+	}
+	_, err = proc.storage.ReviewSuspendedOrder(ctx, orderNumberInt, approve)
+	return err
+}
+
+// RequeueOrders processes admin requests requeueing non-final orders in bulk, orders are matched by status, age,
+// owner and number range.
+func (proc *Processor) RequeueOrders(ctx context.Context, request modeldto.RequeueRequest) (*modeldto.RequeueReport, error) {
+	filter := modelstorage.RequeueFilter{Status: request.Status, UserID: request.UserID, CreatedTo: time.Now()}
+	if request.OlderThan != "" {
+		olderThan, err := time.ParseDuration(request.OlderThan)
+		if err != nil || olderThan < 0 {
+			return nil, &serviceErrors.ServiceIllegalFilter{Msg: fmt.Sprintf("illegal order age %s", request.OlderThan)}
+		}
+		filter.CreatedTo = filter.CreatedTo.Add(-olderThan)
+	}
+	var err error
+	if request.NumberFrom != "" {
+		filter.NumberFrom, err = ordernum.Parse(request.NumberFrom)
+		if err != nil {
+			return nil, &serviceErrors.ServiceIllegalFilter{Msg: fmt.Sprintf("illegal order number %s", request.NumberFrom)}
+		}
+	}
+	if request.NumberTo != "" {
+		filter.NumberTo, err = ordernum.Parse(request.NumberTo)
+		if err != nil || filter.NumberTo < filter.NumberFrom {
+			return nil, &serviceErrors.ServiceIllegalFilter{Msg: fmt.Sprintf("illegal order number %s", request.NumberTo)}
+		}
+	}
+	return proc.storage.RequeueOrders(ctx, filter)
+}
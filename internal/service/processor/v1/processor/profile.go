@@ -0,0 +1,103 @@
+// Package processor provides intermediary layer functionality between the DB and API endpoint handlers.
+
+package processor
+
+import (
+	"context"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/models/modeldto"
+	serviceErrors "github.com/danilovkiri/dk-go-gophermart/internal/service/processor/v1/errors"
+	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/modelstorage"
+)
+
+// UpdateProfile processes profile update requests, the login and contact details are re-ciphered with the current
+// key and a legacy ciphered password is replaced by its hash. Omitted fields are left intact.
+func (proc *Processor) UpdateProfile(ctx context.Context, userID string, update modeldto.ProfileUpdate) (*modeldto.Profile, error) {
+	user, err := proc.storage.GetUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	login, err := proc.secretary.Decode(user.Login)
+	if err != nil {
+		return nil, err
+	}
+	profile := modeldto.Profile{Login: login}
+	profile.Email, err = proc.decodeOptional(user.Email)
+	if err != nil {
+		return nil, err
+	}
+	profile.Phone, err = proc.decodeOptional(user.Phone)
+	if err != nil {
+		return nil, err
+	}
+	var changes []string
+	var lookup *modelstorage.LoginLookup
+	if update.Login != nil {
+		newLogin := NormalizeLogin(*update.Login)
+		if newLogin == "" {
+			return nil, &serviceErrors.ServiceIllegalLogin{Msg: "login must not be empty"}
+		}
+		if newLogin != profile.Login {
+			profile.Login = newLogin
+			changes = append(changes, "login")
+			lookup, err = proc.loginLookup(newLogin)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	if update.Email != nil && *update.Email != profile.Email {
+		profile.Email = *update.Email
+		changes = append(changes, "email")
+	}
+	if update.Phone != nil && *update.Phone != profile.Phone {
+		profile.Phone = *update.Phone
+		changes = append(changes, "phone")
+	}
+	if len(changes) == 0 {
+		return &profile, nil
+	}
+	sealedLogin, err := proc.secretary.Seal(profile.Login)
+	if err != nil {
+		return nil, err
+	}
+	password := user.Password
+	if !proc.hasher.Hashed(password) {
+		decodedPassword, err := proc.secretary.Decode(password)
+		if err != nil {
+			return nil, err
+		}
+		password, err = proc.hasher.Hash(decodedPassword)
+		if err != nil {
+			return nil, err
+		}
+	}
+	cipheredProfile := modelstorage.UserStorageEntry{
+		Login:     sealedLogin,
+		LoginHash: proc.secretary.LoginHash(profile.Login),
+		Password:  password,
+		Email:     proc.encodeOptional(profile.Email),
+		Phone:     proc.encodeOptional(profile.Phone),
+	}
+	err = proc.storage.UpdateUserProfile(ctx, userID, cipheredProfile, lookup, changes)
+	if err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// encodeOptional ciphers a non-empty value, empty values are kept as is.
+func (proc *Processor) encodeOptional(data string) string {
+	if data == "" {
+		return ""
+	}
+	return proc.secretary.Encode(data)
+}
+
+// decodeOptional deciphers a non-empty value, empty values are kept as is.
+func (proc *Processor) decodeOptional(msg string) (string, error) {
+	if msg == "" {
+		return "", nil
+	}
+	return proc.secretary.Decode(msg)
+}
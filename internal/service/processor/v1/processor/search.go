@@ -0,0 +1,75 @@
+// Package processor provides intermediary layer functionality between the DB and API endpoint handlers.
+
+package processor
+
+import (
+	"context"
+	"strings"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/models/modeldto"
+	"github.com/danilovkiri/dk-go-gophermart/internal/ordernum"
+	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/modelstorage"
+)
+
+// User search match kinds reported for every found user.
+const (
+	MatchLogin  = "login"
+	MatchUserID = "user_id"
+	MatchOrder  = "order"
+)
+
+// SearchUsers processes admin requests looking users of any tenant up by login, user ID or order number, the query
+// is matched against all of them. A page of up to limit users starting at offset is returned along with the offset
+// of the next page if there is one.
+func (proc *Processor) SearchUsers(ctx context.Context, query string, limit, offset int) (*modeldto.UserSearchResult, error) {
+	query = strings.TrimSpace(query)
+	search := modelstorage.UserSearch{UserID: query, Limit: limit + 1, Offset: offset}
+	loginHashes := make(map[string]bool)
+	for _, login := range loginCandidates(query) {
+		lookup, err := proc.loginLookup(login)
+		if err != nil {
+			return nil, err
+		}
+		search.Lookups = append(search.Lookups, *lookup)
+		loginHashes[lookup.Hash] = true
+	}
+	orderNumber, err := ordernum.Parse(query)
+	if err == nil {
+		search.OrderNumber = orderNumber
+	}
+	users, err := proc.storage.SearchUsers(ctx, search)
+	if err != nil {
+		return nil, err
+	}
+	result := modeldto.UserSearchResult{Users: []modeldto.UserSummary{}}
+	if len(users) > limit {
+		users = users[:limit]
+		nextOffset := offset + limit
+		result.NextOffset = &nextOffset
+	}
+	for _, user := range users {
+		login, err := proc.secretary.Decode(user.Login)
+		if err != nil {
+			return nil, err
+		}
+		summary := modeldto.UserSummary{
+			UserID:       user.UserID,
+			Login:        login,
+			Tenant:       user.TenantID,
+			RegisteredAt: proc.formatTime(user.RegisteredAt),
+			Deactivated:  user.Deactivated,
+		}
+		// users stored before login digests were introduced are matched by their legacy ciphertexts
+		if loginHashes[user.LoginHash] || (user.LoginHash == "" && NormalizeLogin(login) == NormalizeLogin(query)) {
+			summary.MatchedBy = append(summary.MatchedBy, MatchLogin)
+		}
+		if user.UserID == query {
+			summary.MatchedBy = append(summary.MatchedBy, MatchUserID)
+		}
+		if len(summary.MatchedBy) == 0 {
+			summary.MatchedBy = append(summary.MatchedBy, MatchOrder)
+		}
+		result.Users = append(result.Users, summary)
+	}
+	return &result, nil
+}
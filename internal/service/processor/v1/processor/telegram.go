@@ -0,0 +1,31 @@
+// Package processor provides intermediary layer functionality between the DB and API endpoint handlers.
+
+package processor
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"time"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/models/modeldto"
+)
+
+// telegramLinkCodeTTL defines how long a Telegram link code can be redeemed.
+const telegramLinkCodeTTL = 10 * time.Minute
+
+// CreateTelegramLinkCode issues a one-time code which links the Telegram chat it is sent from to the user.
+func (proc *Processor) CreateTelegramLinkCode(ctx context.Context, userID string) (*modeldto.TelegramLink, error) {
+	b := make([]byte, 5)
+	_, err := rand.Read(b)
+	if err != nil {
+		return nil, err
+	}
+	code := base32.StdEncoding.EncodeToString(b)
+	expiresAt := time.Now().Add(telegramLinkCodeTTL)
+	err = proc.storage.SetTelegramLinkCode(ctx, userID, code, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return &modeldto.TelegramLink{Code: code, ExpiresAt: proc.formatTime(expiresAt)}, nil
+}
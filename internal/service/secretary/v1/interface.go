@@ -1,15 +1,24 @@
 // Package secretary provides methods for ciphering.
 package secretary
 
-import "net/http"
+import (
+	"time"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/service/secretary/v1/modelclaims"
+)
 
 // Secretary defines a set of methods for types implementing Secretary.
 type Secretary interface {
 	Encode(data string) string
+	Seal(data string) (string, error)
 	Decode(msg string) (string, error)
-	NewCookie() (*http.Cookie, string)
-	GetCookieForUser(userID string) *http.Cookie
-	ValidateToken(accessToken string) (string, error)
-	NewToken() (string, string, error)
-	GetTokenForUser(userID string) (string, error)
+	LoginHash(login string) string
+	EncodeWithKey(keyID, data string) (string, error)
+	KeyIDs() []string
+	Current(msg string) bool
+	ValidateClaims(accessToken string) (*modelclaims.MyCustomClaims, error)
+	NewToken(tenantID, sessionID string) (string, string, error)
+	GetTokenForUser(userID, tenantID, sessionID string) (string, error)
+	GetScopedToken(userID, tenantID, partner string, scopes []string, ttl time.Duration) (string, time.Time, error)
+	NewRefreshToken() (string, error)
 }
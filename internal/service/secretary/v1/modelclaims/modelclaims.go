@@ -5,6 +5,13 @@ package modelclaims
 import "github.com/golang-jwt/jwt"
 
 type MyCustomClaims struct {
-	UserID string `json:"userID"`
+	UserID   string `json:"userID"`
+	TenantID string `json:"tenantID,omitempty"`
+	// SessionID links the token to a server-side session, tokens issued before sessions were tracked carry none
+	SessionID string `json:"sid,omitempty"`
+	// Partner and Scopes are set for tokens issued to partner integrations which may only reach routes granted
+	// to the scopes, user tokens carry neither
+	Partner string   `json:"partner,omitempty"`
+	Scopes  []string `json:"scope,omitempty"`
 	jwt.StandardClaims
 }
@@ -4,11 +4,13 @@ package secretary
 import (
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
 	"errors"
 	"fmt"
-	"net/http"
+	"strings"
 	"time"
 
 	"github.com/danilovkiri/dk-go-gophermart/internal/config"
@@ -17,16 +19,58 @@ import (
 	"github.com/google/uuid"
 )
 
+// keySeparator separates a key ID from ciphertext, ciphertext without a key ID belongs to the legacy unversioned key.
+const keySeparator = ":"
+
+// sealedPrefix marks ciphertext sealed with a random nonce which is stored in front of it, it never occurs in hex.
+const sealedPrefix = "~"
+
 // Secretary defines object structure and its attributes.
 type Secretary struct {
 	aesgcm cipher.AEAD
 	nonce  []byte
 	key    []byte
+	keyID  string
+	// previous holds retired keys by their IDs, they are only used for deciphering and token validation
+	previous map[string]*Secretary
+	// loginHashKey keys login digests, it is independent of ciphering keys so that rotating them keeps digests intact
+	loginHashKey []byte
 }
 
 // NewSecretaryService initializes a secretary service with ciphering functionality.
 func NewSecretaryService(c *config.SecretConfig) (*Secretary, error) {
-	key := sha256.Sum256([]byte(c.SecretKey))
+	s, err := newSecretary(c.SecretKey, c.SecretKeyID)
+	if err != nil {
+		return nil, err
+	}
+	s.loginHashKey = []byte(c.LoginHashKey)
+	if c.LoginHashKey == "" {
+		s.loginHashKey = []byte(c.SecretKey)
+	}
+	s.previous = make(map[string]*Secretary, len(c.PreviousSecretKeys))
+	for _, pair := range c.PreviousSecretKeys {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid previous secret key, expected id=key")
+		}
+		if parts[0] == s.keyID {
+			return nil, fmt.Errorf("previous secret key ID %q clashes with the current one", parts[0])
+		}
+		previous, err := newSecretary(parts[1], parts[0])
+		if err != nil {
+			return nil, err
+		}
+		s.previous[parts[0]] = previous
+	}
+	return s, nil
+}
+
+// newSecretary initializes ciphering functionality for a single key.
+func newSecretary(secretKey, keyID string) (*Secretary, error) {
+	if strings.Contains(keyID, keySeparator) {
+		return nil, fmt.Errorf("secret key ID %q must not contain %q", keyID, keySeparator)
+	}
+	key := sha256.Sum256([]byte(secretKey))
 	aesblock, err := aes.NewCipher(key[:])
 	if err != nil {
 		return nil, err
@@ -39,94 +83,205 @@ func NewSecretaryService(c *config.SecretConfig) (*Secretary, error) {
 	return &Secretary{
 		aesgcm: aesgcm,
 		nonce:  nonce,
-		key:    []byte(c.SecretKey),
+		key:    []byte(secretKey),
+		keyID:  keyID,
 	}, nil
 }
 
-// Encode ciphers data using the previously established cipher.
+// KeyIDs returns the current key ID followed by the IDs of previous keys.
+func (s *Secretary) KeyIDs() []string {
+	keyIDs := []string{s.keyID}
+	for keyID := range s.previous {
+		keyIDs = append(keyIDs, keyID)
+	}
+	return keyIDs
+}
+
+// withKey returns a secretary for the given key ID.
+func (s *Secretary) withKey(keyID string) (*Secretary, error) {
+	if keyID == s.keyID {
+		return s, nil
+	}
+	if previous, ok := s.previous[keyID]; ok {
+		return previous, nil
+	}
+	return nil, fmt.Errorf("unknown secret key ID %q", keyID)
+}
+
+// Encode ciphers data using the current key, the key ID is prepended to ciphertext.
 func (s *Secretary) Encode(data string) string {
-	encoded := s.aesgcm.Seal(nil, s.nonce, []byte(data), nil)
-	return hex.EncodeToString(encoded)
+	encoded := hex.EncodeToString(s.aesgcm.Seal(nil, s.nonce, []byte(data), nil))
+	if s.keyID == "" {
+		return encoded
+	}
+	return s.keyID + keySeparator + encoded
 }
 
-// Decode deciphers data using the previously established cipher.
-func (s *Secretary) Decode(msg string) (string, error) {
-	msgBytes, err := hex.DecodeString(msg)
+// EncodeWithKey ciphers data using the key with the given ID.
+func (s *Secretary) EncodeWithKey(keyID, data string) (string, error) {
+	keySecretary, err := s.withKey(keyID)
 	if err != nil {
 		return "", err
 	}
-	decoded, err := s.aesgcm.Open(nil, s.nonce, msgBytes, nil)
+	return keySecretary.Encode(data), nil
+}
+
+// Seal ciphers data using the current key and a random nonce, so that equal data produce distinct ciphertext.
+func (s *Secretary) Seal(data string) (string, error) {
+	nonce := make([]byte, s.aesgcm.NonceSize())
+	_, err := rand.Read(nonce)
 	if err != nil {
 		return "", err
 	}
-	return string(decoded), nil
+	sealed := sealedPrefix + hex.EncodeToString(s.aesgcm.Seal(nonce, nonce, []byte(data), nil))
+	if s.keyID == "" {
+		return sealed, nil
+	}
+	return s.keyID + keySeparator + sealed, nil
 }
 
-// NewCookie generates a new userID and a corresponding encoded cookie.
-func (s *Secretary) NewCookie() (*http.Cookie, string) {
-	userID := uuid.New().String()
-	token := s.Encode(userID)
-	newCookie := &http.Cookie{
-		Name:    "userID",
-		Value:   token,
-		Path:    "/",
-		Expires: time.Now().Add(30 * time.Minute),
-		//Expires: time.Now().Add(30 * time.Second),
+// Sealed reports whether data was ciphered with a random nonce.
+func (s *Secretary) Sealed(msg string) bool {
+	if idx := strings.Index(msg, keySeparator); idx >= 0 {
+		msg = msg[idx+len(keySeparator):]
 	}
-	return newCookie, userID
+	return strings.HasPrefix(msg, sealedPrefix)
+}
+
+// LoginHash returns a deterministic keyed digest of a login used for looking users up instead of its ciphertext.
+func (s *Secretary) LoginHash(login string) string {
+	mac := hmac.New(sha256.New, s.loginHashKey)
+	mac.Write([]byte(login))
+	return hex.EncodeToString(mac.Sum(nil))
 }
 
-// GetCookieForUser generates an encoded cookie for a userID.
-func (s *Secretary) GetCookieForUser(userID string) *http.Cookie {
-	token := s.Encode(userID)
-	userCookie := &http.Cookie{
-		Name:    "userID",
-		Value:   token,
-		Path:    "/",
-		Expires: time.Now().Add(30 * time.Minute),
+// Decode deciphers data ciphered either by Encode or by Seal using the key referenced by its ID.
+func (s *Secretary) Decode(msg string) (string, error) {
+	var keyID string
+	if idx := strings.Index(msg, keySeparator); idx >= 0 {
+		keyID, msg = msg[:idx], msg[idx+len(keySeparator):]
+	}
+	keySecretary, err := s.withKey(keyID)
+	if err != nil {
+		return "", err
 	}
-	return userCookie
+	sealed := strings.HasPrefix(msg, sealedPrefix)
+	msgBytes, err := hex.DecodeString(strings.TrimPrefix(msg, sealedPrefix))
+	if err != nil {
+		return "", err
+	}
+	nonce := keySecretary.nonce
+	if sealed {
+		if len(msgBytes) < len(nonce) {
+			return "", errors.New("sealed ciphertext is too short")
+		}
+		nonce, msgBytes = msgBytes[:len(nonce)], msgBytes[len(nonce):]
+	}
+	decoded, err := keySecretary.aesgcm.Open(nil, nonce, msgBytes, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(decoded), nil
+}
+
+// Current reports whether data was ciphered with the current key.
+func (s *Secretary) Current(msg string) bool {
+	idx := strings.Index(msg, keySeparator)
+	if idx < 0 {
+		return s.keyID == ""
+	}
+	return msg[:idx] == s.keyID
 }
 
-func (s *Secretary) ValidateToken(accessToken string) (string, error) {
+// ValidateClaims verifies an access token and returns its claims.
+func (s *Secretary) ValidateClaims(accessToken string) (*modelclaims.MyCustomClaims, error) {
 	token, err := jwt.ParseWithClaims(accessToken, &modelclaims.MyCustomClaims{}, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
-		return s.key, nil
+		keyID, _ := token.Header["kid"].(string)
+		keySecretary, err := s.withKey(keyID)
+		if err != nil {
+			return nil, err
+		}
+		return keySecretary.key, nil
 	})
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	if claims, ok := token.Claims.(*modelclaims.MyCustomClaims); ok && token.Valid {
-		return claims.UserID, nil
+		return claims, nil
 	}
-	return "", errors.New("invalid access token")
+	return nil, errors.New("invalid access token")
 }
 
-func (s *Secretary) NewToken() (string, string, error) {
+func (s *Secretary) NewToken(tenantID, sessionID string) (string, string, error) {
 	userID := uuid.New().String()
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, &modelclaims.MyCustomClaims{
-		UserID: userID,
+		UserID:    userID,
+		TenantID:  tenantID,
+		SessionID: sessionID,
 		StandardClaims: jwt.StandardClaims{
 			IssuedAt:  time.Now().Unix(),
 			ExpiresAt: time.Now().Add(30 * time.Minute).Unix(),
 		},
 	})
-	accessToken, err := token.SignedString(s.key)
+	accessToken, err := s.sign(token)
 	if err != nil {
 		return "", "", err
 	}
 	return accessToken, userID, nil
 }
 
-func (s *Secretary) GetTokenForUser(userID string) (string, error) {
+func (s *Secretary) GetTokenForUser(userID, tenantID, sessionID string) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, &modelclaims.MyCustomClaims{
-		UserID: userID,
+		UserID:    userID,
+		TenantID:  tenantID,
+		SessionID: sessionID,
 		StandardClaims: jwt.StandardClaims{
 			IssuedAt:  time.Now().Unix(),
 			ExpiresAt: time.Now().Add(30 * time.Minute).Unix(),
 		},
 	})
+	return s.sign(token)
+}
+
+// GetScopedToken issues a token acting on behalf of a user for a partner integration, it grants only the given
+// scopes and carries no session.
+func (s *Secretary) GetScopedToken(userID, tenantID, partner string, scopes []string, ttl time.Duration) (string, time.Time, error) {
+	expiresAt := time.Now().Add(ttl)
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, &modelclaims.MyCustomClaims{
+		UserID:   userID,
+		TenantID: tenantID,
+		Partner:  partner,
+		Scopes:   scopes,
+		StandardClaims: jwt.StandardClaims{
+			IssuedAt:  time.Now().Unix(),
+			ExpiresAt: expiresAt.Unix(),
+		},
+	})
+	accessToken, err := s.sign(token)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return accessToken, expiresAt, nil
+}
+
+// NewRefreshToken generates an opaque refresh token, it carries no claims and is only meaningful to the session
+// storing its digest.
+func (s *Secretary) NewRefreshToken() (string, error) {
+	token := make([]byte, 32)
+	_, err := rand.Read(token)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(token), nil
+}
+
+// sign signs a token with the current key, the key ID is passed in the token header.
+func (s *Secretary) sign(token *jwt.Token) (string, error) {
+	if s.keyID != "" {
+		token.Header["kid"] = s.keyID
+	}
 	return token.SignedString(s.key)
 }
@@ -0,0 +1,8 @@
+// Package validator provides order number validation strategies.
+
+package validator
+
+// Validator defines a set of methods for types implementing Validator.
+type Validator interface {
+	Validate(orderNumber string) error
+}
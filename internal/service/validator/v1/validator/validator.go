@@ -0,0 +1,91 @@
+// Package validator provides order number validation strategies.
+
+package validator
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/config"
+	"github.com/danilovkiri/dk-go-gophermart/internal/ordernum"
+	"github.com/danilovkiri/dk-go-gophermart/internal/service/validator/v1"
+	"github.com/rs/zerolog"
+)
+
+// Validation strategies selectable via config.
+const (
+	StrategyLuhn   = "luhn"
+	StrategyLength = "length"
+	StrategyRegex  = "regex"
+)
+
+// LuhnValidator validates order numbers using the Luhn algorithm, a soft one accepts invalid checksums with a warning.
+type LuhnValidator struct {
+	soft bool
+	log  *zerolog.Logger
+}
+
+// Validate checks the Luhn checksum of an order number, malformed numbers are rejected in soft mode as well.
+func (v *LuhnValidator) Validate(orderNumber string) error {
+	err := ordernum.Validate(orderNumber)
+	if v.soft && errors.Is(err, ordernum.ErrChecksum) {
+		v.log.Warn().Str("order", orderNumber).Msg("order number with invalid Luhn checksum accepted")
+		return nil
+	}
+	return err
+}
+
+// LengthValidator validates order numbers by their length only.
+type LengthValidator struct {
+	minLength int
+	maxLength int
+}
+
+// Validate checks that an order number length is within the configured bounds.
+func (v *LengthValidator) Validate(orderNumber string) error {
+	if len(orderNumber) < v.minLength || len(orderNumber) > v.maxLength {
+		return fmt.Errorf("order number length must be between %d and %d", v.minLength, v.maxLength)
+	}
+	return nil
+}
+
+// RegexValidator validates order numbers against a custom regular expression.
+type RegexValidator struct {
+	re *regexp.Regexp
+}
+
+// Validate checks that an order number matches the configured regular expression.
+func (v *RegexValidator) Validate(orderNumber string) error {
+	if !v.re.MatchString(orderNumber) {
+		return fmt.Errorf("order number does not match %s", v.re.String())
+	}
+	return nil
+}
+
+// NewValidator initializes an order number validator for the configured strategy.
+func NewValidator(cfg *config.ValidationConfig, log *zerolog.Logger) (validator.Validator, error) {
+	switch cfg.Strategy {
+	case StrategyLuhn, "":
+		if cfg.Soft {
+			log.Warn().Msg("soft order number validation enabled, invalid Luhn checksums are accepted")
+		}
+		return &LuhnValidator{soft: cfg.Soft, log: log}, nil
+	case StrategyLength:
+		if cfg.MinLength <= 0 || cfg.MaxLength < cfg.MinLength {
+			return nil, fmt.Errorf("invalid order number length bounds %d-%d", cfg.MinLength, cfg.MaxLength)
+		}
+		return &LengthValidator{minLength: cfg.MinLength, maxLength: cfg.MaxLength}, nil
+	case StrategyRegex:
+		if cfg.Regex == "" {
+			return nil, errors.New("order number regex must be set for regex validation strategy")
+		}
+		re, err := regexp.Compile(cfg.Regex)
+		if err != nil {
+			return nil, err
+		}
+		return &RegexValidator{re: re}, nil
+	default:
+		return nil, fmt.Errorf("unknown order number validation strategy %s", cfg.Strategy)
+	}
+}
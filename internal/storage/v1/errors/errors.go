@@ -4,6 +4,9 @@ package errors
 
 import (
 	"fmt"
+	"time"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/errcodes"
 )
 
 type (
@@ -11,8 +14,9 @@ type (
 		Err error
 	}
 	AlreadyExistsError struct {
-		Err error
-		ID  string
+		Err  error
+		ID   string
+		Code errcodes.Code
 	}
 	AlreadyExistsAndViolatesError struct {
 		Err error
@@ -30,32 +34,205 @@ type (
 	ScanningPSQLError struct {
 		Err error
 	}
+	ConnectionPSQLError struct {
+		Err      error
+		Attempts int
+	}
+	InsufficientFundsError struct {
+		Available float64
+		Required  float64
+	}
+	UnknownUserError struct {
+		Err error
+		ID  string
+	}
+	NegativeBalanceError struct {
+		Err error
+		ID  string
+	}
+	IllegalSortError struct {
+		Msg string
+	}
+	MergeConflictError struct {
+		Msg string
+	}
+	ConcurrentUpdateError struct {
+		ID       string
+		Attempts int
+	}
+	RecheckNotAllowedError struct {
+		ID     string
+		Status string
+	}
+	OrderNotSuspendedError struct {
+		ID     string
+		Status string
+	}
+	HoldNotActiveError struct {
+		ID     string
+		Status string
+	}
+	RecheckRateLimitedError struct {
+		ID         string
+		RetryAfter time.Duration
+	}
+	SessionExpiredError struct {
+		ID     string
+		Reason string
+	}
 )
 
 func (e *StatementPSQLError) Error() string {
 	return fmt.Sprintf("%s: could not compile", e.Err.Error())
 }
 
+func (e *StatementPSQLError) ErrorCode() errcodes.Code {
+	return errcodes.StorageError
+}
+
 func (e *AlreadyExistsError) Error() string {
 	return fmt.Sprintf("%s: already exists", e.ID)
 }
 
+func (e *AlreadyExistsError) ErrorCode() errcodes.Code {
+	if e.Code == "" {
+		return errcodes.AlreadyExists
+	}
+	return e.Code
+}
+
 func (e *AlreadyExistsAndViolatesError) Error() string {
 	return fmt.Sprintf("%s: already exists", e.ID)
 }
 
+func (e *AlreadyExistsAndViolatesError) ErrorCode() errcodes.Code {
+	return errcodes.OrderOwnedByAnotherUser
+}
+
 func (e *ExecutionPSQLError) Error() string {
 	return fmt.Sprintf("%s: could not execute", e.Err.Error())
 }
 
+func (e *ExecutionPSQLError) ErrorCode() errcodes.Code {
+	return errcodes.StorageError
+}
+
 func (e *ContextTimeoutExceededError) Error() string {
 	return fmt.Sprintf("%s: context timeout exceeded", e.Err.Error())
 }
 
+func (e *ContextTimeoutExceededError) ErrorCode() errcodes.Code {
+	return errcodes.Timeout
+}
+
 func (e *NotFoundError) Error() string {
 	return "not found in storage"
 }
 
+func (e *NotFoundError) ErrorCode() errcodes.Code {
+	return errcodes.NotFound
+}
+
 func (e *ScanningPSQLError) Error() string {
 	return fmt.Sprintf("%s: could not scan rows", e.Err.Error())
 }
+
+func (e *ScanningPSQLError) ErrorCode() errcodes.Code {
+	return errcodes.StorageError
+}
+
+func (e *ConnectionPSQLError) Error() string {
+	return fmt.Sprintf("%s: could not connect after %d attempts", e.Err.Error(), e.Attempts)
+}
+
+func (e *ConnectionPSQLError) ErrorCode() errcodes.Code {
+	return errcodes.StorageUnavailable
+}
+
+func (e *InsufficientFundsError) Error() string {
+	return fmt.Sprintf("not enough funds are available, present - %v, required - %v", e.Available, e.Required)
+}
+
+func (e *InsufficientFundsError) ErrorCode() errcodes.Code {
+	return errcodes.InsufficientFunds
+}
+
+func (e *UnknownUserError) Error() string {
+	return fmt.Sprintf("%s: user does not exist", e.ID)
+}
+
+func (e *UnknownUserError) ErrorCode() errcodes.Code {
+	return errcodes.UnknownUser
+}
+
+func (e *ConcurrentUpdateError) Error() string {
+	return fmt.Sprintf("%s: balance was modified concurrently %d times in a row", e.ID, e.Attempts)
+}
+
+func (e *ConcurrentUpdateError) ErrorCode() errcodes.Code {
+	return errcodes.ConcurrentUpdate
+}
+
+func (e *NegativeBalanceError) Error() string {
+	return fmt.Sprintf("%s: balance would become negative", e.ID)
+}
+
+func (e *NegativeBalanceError) ErrorCode() errcodes.Code {
+	return errcodes.InsufficientFunds
+}
+
+func (e *IllegalSortError) Error() string {
+	return e.Msg
+}
+
+func (e *IllegalSortError) ErrorCode() errcodes.Code {
+	return errcodes.InvalidRequest
+}
+
+func (e *MergeConflictError) Error() string {
+	return e.Msg
+}
+
+func (e *MergeConflictError) ErrorCode() errcodes.Code {
+	return errcodes.MergeConflict
+}
+
+func (e *RecheckNotAllowedError) Error() string {
+	return fmt.Sprintf("%s: order is %s, only INVALID orders can be rechecked", e.ID, e.Status)
+}
+
+func (e *RecheckNotAllowedError) ErrorCode() errcodes.Code {
+	return errcodes.OrderNotRecheckable
+}
+
+func (e *RecheckRateLimitedError) Error() string {
+	return fmt.Sprintf("%s: recheck limit exceeded, retry in %v", e.ID, e.RetryAfter)
+}
+
+func (e *RecheckRateLimitedError) ErrorCode() errcodes.Code {
+	return errcodes.TooManyRequests
+}
+
+func (e *SessionExpiredError) Error() string {
+	return fmt.Sprintf("%s: session expired due to %s", e.ID, e.Reason)
+}
+
+func (e *SessionExpiredError) ErrorCode() errcodes.Code {
+	return errcodes.SessionExpired
+}
+
+func (e *OrderNotSuspendedError) Error() string {
+	return fmt.Sprintf("%s: order is %s, only SUSPENDED orders can be reviewed", e.ID, e.Status)
+}
+
+func (e *OrderNotSuspendedError) ErrorCode() errcodes.Code {
+	return errcodes.OrderNotSuspended
+}
+
+func (e *HoldNotActiveError) Error() string {
+	return fmt.Sprintf("%s: hold is %s, only ACTIVE holds can be captured or released", e.ID, e.Status)
+}
+
+func (e *HoldNotActiveError) ErrorCode() errcodes.Code {
+	return errcodes.HoldNotActive
+}
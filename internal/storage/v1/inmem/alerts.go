@@ -0,0 +1,47 @@
+// Package inmem provides an in-process storage keeping all data in memory.
+
+package inmem
+
+import (
+	"context"
+
+	storageErrors "github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/errors"
+	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/modelstorage"
+	"github.com/danilovkiri/dk-go-gophermart/internal/tenant"
+)
+
+// GetAlertThresholds retrieves the alert thresholds of a user, unset thresholds are nil.
+func (s *Storage) GetAlertThresholds(ctx context.Context, userID string) (*modelstorage.AlertThresholdsStorageEntry, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	user, ok := s.tenantUser(userID, tenant.FromContext(ctx))
+	if !ok {
+		err := &storageErrors.NotFoundError{}
+		s.log.Error().Err(err).Msg("getting alert thresholds failed")
+		return nil, err
+	}
+	thresholds := user.thresholds
+	s.log.Info().Msg("getting alert thresholds done")
+	return &thresholds, nil
+}
+
+// SetAlertThresholds replaces the alert thresholds of a user, nil thresholds disable the corresponding alerts.
+func (s *Storage) SetAlertThresholds(ctx context.Context, userID string, thresholds modelstorage.AlertThresholdsStorageEntry) error {
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	user, ok := s.tenantUser(userID, tenant.FromContext(ctx))
+	if !ok {
+		err := &storageErrors.NotFoundError{}
+		s.log.Error().Err(err).Msg("setting alert thresholds failed")
+		return err
+	}
+	user.thresholds = thresholds
+	s.log.Info().Msg("setting alert thresholds done")
+	return nil
+}
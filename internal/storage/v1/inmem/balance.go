@@ -0,0 +1,113 @@
+// Package inmem provides an in-process storage keeping all data in memory.
+
+package inmem
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/models/modeldto"
+	storageErrors "github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/errors"
+	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/modelstorage"
+)
+
+// tenantBalance retrieves the balance of a user of the tenant, the caller must hold the lock.
+func (s *Storage) tenantBalance(userID, tenantID string) (*balanceRecord, bool) {
+	if _, ok := s.tenantUser(userID, tenantID); !ok {
+		return nil, false
+	}
+	balance, ok := s.balances[userID]
+	return balance, ok
+}
+
+// heldAmount sums active holds of a user which have not lapsed by now, the caller must hold the lock.
+func (s *Storage) heldAmount(userID string, now time.Time) float64 {
+	var held float64
+	for _, hold := range s.holds {
+		if hold.entry.UserID == userID && hold.entry.Status == modelstorage.HoldActive && hold.entry.ExpiresAt.After(now) {
+			held += hold.entry.Amount
+		}
+	}
+	return held
+}
+
+// checkBalance makes sure that the available amount of a user's balance, i.e. the balance less active holds, covers
+// a debit of delta along with a new hold of the given amount. Credits always pass, the caller must hold the lock.
+func (s *Storage) checkBalance(userID, tenantID string, delta, hold float64) error {
+	balance, ok := s.tenantBalance(userID, tenantID)
+	if !ok {
+		return &storageErrors.UnknownUserError{ID: userID}
+	}
+	available := balance.amount - s.heldAmount(userID, time.Now())
+	if (delta < 0 || hold > 0) && available+delta-hold < 0 {
+		return &storageErrors.InsufficientFundsError{Available: available, Required: hold - delta}
+	}
+	return nil
+}
+
+// adjustBalance applies delta to a user's balance, debits must be checked with checkBalance beforehand.
+// The caller must hold the write lock.
+func (s *Storage) adjustBalance(userID string, delta float64) {
+	balance, ok := s.balances[userID]
+	if !ok {
+		return
+	}
+	balance.amount += delta
+	balance.updatedAt = time.Now()
+}
+
+// addBalanceEvent appends a balance-affecting event to the event log, the event belongs to the tenant of the user.
+// The caller must hold the write lock.
+func (s *Storage) addBalanceEvent(userID, kind string, amount float64, reference string) modelstorage.BalanceEventStorageEntry {
+	event := eventRecord{
+		entry:  modelstorage.BalanceEventStorageEntry{ID: int64(s.nextID()), Kind: kind, Amount: amount, Reference: reference, CreatedAt: time.Now()},
+		userID: userID,
+	}
+	if user, ok := s.users[userID]; ok {
+		event.tenantID = user.entry.TenantID
+	}
+	s.events = append(s.events, event)
+	return event.entry
+}
+
+// balanceAlerts evaluates the alert thresholds of a user against an applied balance change of delta and returns
+// the notifications to emit once the lock is released. A low balance alert fires only when the balance drops below
+// the threshold, not for every debit while it stays below. The caller must hold the lock.
+func (s *Storage) balanceAlerts(userID string, delta float64, reference string) []modeldto.Notification {
+	user, userFound := s.users[userID]
+	balance, balanceFound := s.balances[userID]
+	if !userFound || !balanceFound {
+		return nil
+	}
+	amount, thresholds := balance.amount, user.thresholds
+	var notifications []modeldto.Notification
+	if thresholds.LargeAccrual != nil && delta >= *thresholds.LargeAccrual {
+		notifications = append(notifications, modeldto.Notification{Kind: modelstorage.NotificationLargeAccrual, UserID: userID, Message: fmt.Sprintf("accrual of %v for order %s exceeds your threshold of %v", delta, reference, *thresholds.LargeAccrual)})
+	}
+	if thresholds.LowBalance != nil && delta < 0 && amount < *thresholds.LowBalance && amount-delta >= *thresholds.LowBalance {
+		notifications = append(notifications, modeldto.Notification{Kind: modelstorage.NotificationLowBalance, UserID: userID, Message: fmt.Sprintf("balance dropped to %v below your threshold of %v after order %s", amount, *thresholds.LowBalance, reference)})
+	}
+	return notifications
+}
+
+// exceedsAccrualCaps returns the cap an accrual credited to a user would exceed or an empty string if it is within
+// both caps, the daily cap covers accruals credited within the last 24 hours. The caller must hold the lock.
+func (s *Storage) exceedsAccrualCaps(userID string, accrual float64) string {
+	if s.cfg.AccrualOrderCap > 0 && accrual > s.cfg.AccrualOrderCap {
+		return modelstorage.CapOrder
+	}
+	if s.cfg.AccrualDailyCap <= 0 {
+		return ""
+	}
+	since := time.Now().Add(-24 * time.Hour)
+	var credited float64
+	for _, event := range s.events {
+		if event.userID == userID && event.entry.Kind == modelstorage.EventAccrualCredited && event.entry.CreatedAt.After(since) {
+			credited += event.entry.Amount
+		}
+	}
+	if credited+accrual > s.cfg.AccrualDailyCap {
+		return modelstorage.CapDaily
+	}
+	return ""
+}
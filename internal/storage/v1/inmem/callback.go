@@ -0,0 +1,66 @@
+// Package inmem provides an in-process storage keeping all data in memory.
+
+package inmem
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/models/modelqueue"
+	storageErrors "github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/errors"
+)
+
+// ResolveOrder feeds a final order status pushed by the accrual service to the processed orders queue.
+// Orders already in a final status are ignored, queued orders are marked resolved to suppress further polling.
+func (s *Storage) ResolveOrder(ctx context.Context, orderNumber int, status string, accrual float64) error {
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+	s.mu.RLock()
+	order, ok := s.orders[orderNumber]
+	var entry modelqueue.OrderQueueEntry
+	var current string
+	if ok {
+		entry = modelqueue.OrderQueueEntry{TenantID: order.entry.TenantID, UserID: order.entry.UserID, OrderNumber: orderNumber}
+		current = order.entry.Status
+	}
+	s.mu.RUnlock()
+	if !ok {
+		err := &storageErrors.NotFoundError{}
+		s.log.Error().Err(err).Msg(fmt.Sprintf("getting order owner failed for order %v", orderNumber))
+		return err
+	}
+	if isFinal(current) {
+		s.log.Info().Msg(fmt.Sprintf("order %v is already final, ignoring callback", orderNumber))
+		return nil
+	}
+	if s.isQueued(orderNumber) {
+		s.markResolved(orderNumber)
+	}
+	entry.OrderStatus, entry.Accrual, entry.Dequeued, entry.Source = status, accrual, true, modelqueue.SourceCallback
+	select {
+	case <-ctx.Done():
+		return &storageErrors.ContextTimeoutExceededError{Err: ctx.Err()}
+	case s.QueueOut <- entry:
+	}
+	s.log.Info().Msg(fmt.Sprintf("order %v resolved via callback", orderNumber))
+	return nil
+}
+
+// markResolved marks a queued order as resolved via callback.
+func (s *Storage) markResolved(orderNumber int) {
+	s.queuedMu.Lock()
+	defer s.queuedMu.Unlock()
+	s.resolved[orderNumber] = struct{}{}
+}
+
+// TakeResolved reports whether an order was resolved via callback and clears the mark, polling workers drop such orders.
+func (s *Storage) TakeResolved(orderNumber int) bool {
+	s.queuedMu.Lock()
+	defer s.queuedMu.Unlock()
+	if _, ok := s.resolved[orderNumber]; !ok {
+		return false
+	}
+	delete(s.resolved, orderNumber)
+	return true
+}
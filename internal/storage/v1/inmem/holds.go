@@ -0,0 +1,178 @@
+// Package inmem provides an in-process storage keeping all data in memory.
+
+package inmem
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/errcodes"
+	"github.com/danilovkiri/dk-go-gophermart/internal/models/modeldto"
+	storageErrors "github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/errors"
+	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/modelstorage"
+	"github.com/danilovkiri/dk-go-gophermart/internal/tenant"
+)
+
+// AddHold places a hold on part of a user's balance for a pending purchase of an order, the held amount stays
+// on the balance but can not be spent until the hold is released or expires.
+func (s *Storage) AddHold(ctx context.Context, userID string, orderNumber int, amount float64) (*modelstorage.BalanceHoldStorageEntry, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	hold, err := s.addHold(userID, tenant.FromContext(ctx), orderNumber, amount)
+	s.mu.Unlock()
+	if err != nil {
+		s.log.Error().Err(err).Msg("adding balance hold failed")
+		return nil, err
+	}
+	s.log.Info().Msg(fmt.Sprintf("adding balance hold done for order %v", orderNumber))
+	s.cache.InvalidateBalance(ctx, userID)
+	return hold, nil
+}
+
+// addHold stores a new active hold, an order has at most one active hold. The caller must hold the write lock.
+func (s *Storage) addHold(userID, tenantID string, orderNumber int, amount float64) (*modelstorage.BalanceHoldStorageEntry, error) {
+	now := time.Now()
+	// a lapsed hold of the order must not block a new one until it is marked expired
+	s.expireHolds(func(hold *holdRecord) bool { return hold.entry.OrderNumber == orderNumber }, now)
+	err := s.checkBalance(userID, tenantID, 0, amount)
+	if err != nil {
+		return nil, err
+	}
+	for _, hold := range s.holds {
+		if hold.entry.OrderNumber == orderNumber && hold.entry.Status == modelstorage.HoldActive {
+			return nil, &storageErrors.AlreadyExistsError{ID: strconv.Itoa(orderNumber), Code: errcodes.AlreadyExists}
+		}
+	}
+	hold := &holdRecord{
+		entry:    modelstorage.BalanceHoldStorageEntry{ID: s.nextID(), UserID: userID, OrderNumber: orderNumber, Amount: amount, Status: modelstorage.HoldActive, CreatedAt: now, ExpiresAt: now.Add(s.cfg.HoldTTL)},
+		tenantID: tenantID,
+	}
+	s.holds = append(s.holds, hold)
+	entry := hold.entry
+	return &entry, nil
+}
+
+// GetHolds retrieves a user's balance holds, the most recent first.
+func (s *Storage) GetHolds(ctx context.Context, userID string) ([]modelstorage.BalanceHoldStorageEntry, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+	tenantID := tenant.FromContext(ctx)
+	s.mu.RLock()
+	var holds []modelstorage.BalanceHoldStorageEntry
+	for _, hold := range s.holds {
+		if hold.entry.UserID == userID && hold.tenantID == tenantID {
+			holds = append(holds, hold.entry)
+		}
+	}
+	s.mu.RUnlock()
+	sort.SliceStable(holds, func(i, j int) bool {
+		return ordered(compareTimes(holds[i].CreatedAt, holds[j].CreatedAt), compareIDs(holds[i].ID, holds[j].ID), true)
+	})
+	s.log.Info().Msg("getting balance holds done")
+	return holds, nil
+}
+
+// CaptureHold turns an active hold into a processed withdrawal of the held amount for its order.
+func (s *Storage) CaptureHold(ctx context.Context, userID string, holdID uint) (*modelstorage.BalanceHoldStorageEntry, error) {
+	return s.resolveHold(ctx, userID, holdID, true)
+}
+
+// ReleaseHold drops an active hold making the held amount available again.
+func (s *Storage) ReleaseHold(ctx context.Context, userID string, holdID uint) (*modelstorage.BalanceHoldStorageEntry, error) {
+	return s.resolveHold(ctx, userID, holdID, false)
+}
+
+// resolveHold captures or releases an active hold, holds which are no longer active are rejected with
+// HoldNotActiveError.
+func (s *Storage) resolveHold(ctx context.Context, userID string, holdID uint, capture bool) (*modelstorage.BalanceHoldStorageEntry, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+	status := modelstorage.HoldReleased
+	if capture {
+		status = modelstorage.HoldCaptured
+	}
+	s.mu.Lock()
+	hold, alerts, err := s.resolveHoldLocked(userID, tenant.FromContext(ctx), holdID, status)
+	s.mu.Unlock()
+	if err != nil {
+		s.log.Error().Err(err).Msg("resolving balance hold failed")
+		return nil, err
+	}
+	s.log.Info().Msg(fmt.Sprintf("resolving balance hold done, hold %v is %s", holdID, status))
+	s.cache.InvalidateBalance(ctx, userID)
+	if capture {
+		s.cache.InvalidateOrders(ctx, userID)
+		s.emit(modeldto.Notification{Kind: "balance_changed", UserID: userID, Message: fmt.Sprintf("balance debited with %v for order %v", hold.Amount, hold.OrderNumber)})
+		for _, alert := range alerts {
+			s.emit(alert)
+		}
+	}
+	return hold, nil
+}
+
+// resolveHoldLocked moves an active hold to status, captured holds are withdrawn. The caller must hold the write lock.
+func (s *Storage) resolveHoldLocked(userID, tenantID string, holdID uint, status string) (*modelstorage.BalanceHoldStorageEntry, []modeldto.Notification, error) {
+	var hold *holdRecord
+	for _, candidate := range s.holds {
+		if candidate.entry.ID == holdID && candidate.entry.UserID == userID && candidate.tenantID == tenantID {
+			hold = candidate
+		}
+	}
+	if hold == nil {
+		return nil, nil, &storageErrors.NotFoundError{}
+	}
+	current := hold.entry.Status
+	if current == modelstorage.HoldActive && !hold.entry.ExpiresAt.After(time.Now()) {
+		current = modelstorage.HoldExpired
+	}
+	if current != modelstorage.HoldActive {
+		return nil, nil, &storageErrors.HoldNotActiveError{ID: strconv.Itoa(int(holdID)), Status: current}
+	}
+	// the hold is resolved first so that it no longer counts against the balance debited by the capture
+	hold.entry.Status = status
+	var alerts []modeldto.Notification
+	if status == modelstorage.HoldCaptured {
+		var err error
+		alerts, err = s.withdraw(userID, tenantID, modeldto.NewOrderWithdrawal{OrderNumber: strconv.Itoa(hold.entry.OrderNumber), Amount: hold.entry.Amount})
+		if err != nil {
+			hold.entry.Status = modelstorage.HoldActive
+			return nil, nil, err
+		}
+	}
+	entry := hold.entry
+	return &entry, alerts, nil
+}
+
+// ExpireHolds marks active holds past their expiration as expired.
+func (s *Storage) ExpireHolds(ctx context.Context) error {
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	expired := s.expireHolds(func(*holdRecord) bool { return true }, time.Now())
+	s.mu.Unlock()
+	if expired > 0 {
+		s.log.Info().Msg(fmt.Sprintf("%v balance holds expired", expired))
+	}
+	return nil
+}
+
+// expireHolds marks lapsed active holds matching filter as expired and returns their number, the caller must hold
+// the write lock.
+func (s *Storage) expireHolds(filter func(*holdRecord) bool, now time.Time) int {
+	var expired int
+	for _, hold := range s.holds {
+		if hold.entry.Status == modelstorage.HoldActive && !hold.entry.ExpiresAt.After(now) && filter(hold) {
+			hold.entry.Status = modelstorage.HoldExpired
+			expired++
+		}
+	}
+	return expired
+}
@@ -0,0 +1,719 @@
+// Package inmem provides an in-process storage keeping all data in memory.
+//
+// It implements the same behavior as the relational storage for tests and local development, data is lost upon
+// restart. A single lock serializes writes, so every multi-step operation is validated before it modifies anything
+// and thus applies atomically just like a transaction. The audit log is not kept as nothing reads it back.
+
+package inmem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/cache/v1"
+	"github.com/danilovkiri/dk-go-gophermart/internal/cashback"
+	"github.com/danilovkiri/dk-go-gophermart/internal/config"
+	"github.com/danilovkiri/dk-go-gophermart/internal/errcodes"
+	"github.com/danilovkiri/dk-go-gophermart/internal/metrics"
+	"github.com/danilovkiri/dk-go-gophermart/internal/models/modeldto"
+	"github.com/danilovkiri/dk-go-gophermart/internal/models/modelqueue"
+	storageErrors "github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/errors"
+	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/modelstorage"
+	"github.com/danilovkiri/dk-go-gophermart/internal/tenant"
+	"github.com/rs/zerolog"
+)
+
+// errLoginTaken is reported when a login belongs to another user of the tenant.
+var errLoginTaken = errors.New("login is taken")
+
+// userRecord defines a stored user along with the settings kept in the users table of the relational storage.
+type userRecord struct {
+	entry      modelstorage.UserStorageEntry
+	thresholds modelstorage.AlertThresholdsStorageEntry
+	// linkCode is a pending one-time Telegram link code, the chat is nil until a code is redeemed
+	linkCode      string
+	linkExpiresAt time.Time
+	chatID        *int64
+}
+
+// balanceRecord defines a stored balance.
+type balanceRecord struct {
+	amount    float64
+	updatedAt time.Time
+}
+
+// orderRecord defines a stored order along with the attributes not exposed by OrderStorageEntry.
+type orderRecord struct {
+	entry        modelstorage.OrderStorageEntry
+	channel      string
+	cashbackRule string
+	recheckedAt  time.Time
+	// abandonedAt is set once polling of the order was given up, rescans skip abandoned orders
+	abandonedAt time.Time
+}
+
+// holdRecord defines a stored balance hold.
+type holdRecord struct {
+	entry    modelstorage.BalanceHoldStorageEntry
+	tenantID string
+}
+
+// eventRecord defines a stored balance event.
+type eventRecord struct {
+	entry    modelstorage.BalanceEventStorageEntry
+	userID   string
+	tenantID string
+}
+
+// historyRecord defines a stored order status change.
+type historyRecord struct {
+	entry       modelstorage.OrderStatusHistoryStorageEntry
+	orderNumber int
+}
+
+// sessionRecord defines a stored session.
+type sessionRecord struct {
+	entry    modelstorage.SessionStorageEntry
+	tenantID string
+	revoked  bool
+}
+
+// Storage defines attributes of a struct available to its methods.
+type Storage struct {
+	// mu guards all stored data below, lastID emulates identity columns shared by all records
+	mu          sync.RWMutex
+	lastID      uint
+	users       map[string]*userRecord
+	balances    map[string]*balanceRecord
+	orders      map[int]*orderRecord
+	withdrawals []*modelstorage.WithdrawalStorageEntry
+	holds       []*holdRecord
+	events      []eventRecord
+	history     []historyRecord
+	sessions    []*sessionRecord
+	outbox      []modelstorage.OutboxEventStorageEntry
+	offsets     map[string]int64
+	responses   map[int]modelstorage.AccrualResponseStorageEntry
+	locksMu     sync.Mutex
+	locks       map[string]struct{}
+	queuedMu    sync.Mutex
+	queued      map[int]struct{}
+	// resolved holds queued orders whose final status was pushed via callback
+	resolved map[int]struct{}
+	cfg      *config.StorageConfig
+	log      *zerolog.Logger
+	metrics  *metrics.Registry
+	cache    cache.Cache
+	cashback *cashback.Engine
+	// reconcileReport holds the latest balance reconciliation result
+	reconcileMu     sync.RWMutex
+	reconcileReport *modeldto.ReconciliationReport
+	QueueIn         chan modelqueue.OrderQueueEntry
+	QueueOut        chan modelqueue.OrderQueueEntry
+	// WithdrawalQueue is buffered and never closed, its consumers stop upon context cancellation
+	WithdrawalQueue chan modelqueue.WithdrawalQueueEntry
+	// Events carries user notifications about processed orders and balance changes, it is buffered and never closed
+	Events chan modeldto.Notification
+}
+
+// InitStorage initializes an in-memory storage handling service.
+func InitStorage(ctx context.Context, cfg *config.StorageConfig, log *zerolog.Logger, wg *sync.WaitGroup, reg *metrics.Registry, storageCache cache.Cache, cashbackEngine *cashback.Engine) *Storage {
+	st := Storage{
+		users:     make(map[string]*userRecord),
+		balances:  make(map[string]*balanceRecord),
+		orders:    make(map[int]*orderRecord),
+		offsets:   make(map[string]int64),
+		responses: make(map[int]modelstorage.AccrualResponseStorageEntry),
+		locks:     make(map[string]struct{}),
+		queued:    make(map[int]struct{}),
+		resolved:  make(map[int]struct{}),
+		cfg:       cfg,
+		log:       log,
+		metrics:   reg,
+		cache:     storageCache,
+		cashback:  cashbackEngine,
+		QueueIn:   make(chan modelqueue.OrderQueueEntry),
+		QueueOut:  make(chan modelqueue.OrderQueueEntry),
+
+		WithdrawalQueue: make(chan modelqueue.WithdrawalQueueEntry, cfg.WithdrawalQueueSize),
+		Events:          make(chan modeldto.Notification, cfg.EventQueueSize),
+	}
+	log.Warn().Msg("in-memory storage is used, data will be lost upon restart")
+
+	// listen for processed orders from queueOut and update them in storage
+	wg.Add(1)
+	go func() {
+		log.Info().Msg("started listening to queue for processed orders")
+		defer wg.Done()
+		for record := range st.QueueOut {
+			if record.Dequeued {
+				st.untrackQueued(record.OrderNumber)
+			}
+			var err error
+			if record.Abandoned {
+				st.abandonOrder(record.TenantID, record.OrderNumber)
+			} else {
+				err = st.updateOrder(tenant.WithTenant(ctx, record.TenantID), record.OrderNumber, record.OrderStatus, record.Accrual, record.Source)
+			}
+			if err != nil {
+				log.Warn().Err(err).Msg(fmt.Sprintf("could not update order %v", record.OrderNumber))
+			}
+		}
+		log.Info().Msg("stopped listening to queue for processed orders")
+	}()
+	return &st
+}
+
+// Healthy always returns true as there is no connection to lose.
+func (s *Storage) Healthy() bool {
+	return true
+}
+
+// RetryAfter returns the suggested delay before retrying requests while storage is unhealthy.
+func (s *Storage) RetryAfter() time.Duration {
+	return s.cfg.RetryAfter
+}
+
+// nextID returns the next record identifier, the caller must hold the write lock.
+func (s *Storage) nextID() uint {
+	s.lastID++
+	return s.lastID
+}
+
+// checkContext reports a done context the same way the relational storage does.
+func checkContext(ctx context.Context) error {
+	if ctx.Err() != nil {
+		return &storageErrors.ContextTimeoutExceededError{Err: ctx.Err()}
+	}
+	return nil
+}
+
+// matchesLogin checks whether a user is identified by lookup, users stored without a digest are matched
+// by their legacy ciphertexts.
+func matchesLogin(user *userRecord, lookup modelstorage.LoginLookup) bool {
+	if user.entry.LoginHash != "" {
+		return user.entry.LoginHash == lookup.Hash
+	}
+	for _, legacy := range lookup.Legacy {
+		if user.entry.Login == legacy {
+			return true
+		}
+	}
+	return false
+}
+
+// loginTaken checks whether a login identified by lookup belongs to a user of the tenant other than userID,
+// the caller must hold the lock.
+func (s *Storage) loginTaken(tenantID, userID string, lookup modelstorage.LoginLookup) bool {
+	for _, user := range s.users {
+		if user.entry.TenantID == tenantID && user.entry.UserID != userID && matchesLogin(user, lookup) {
+			return true
+		}
+	}
+	return false
+}
+
+// tenantUser retrieves a user of the tenant, the caller must hold the lock.
+func (s *Storage) tenantUser(userID, tenantID string) (*userRecord, bool) {
+	user, ok := s.users[userID]
+	if !ok || user.entry.TenantID != tenantID {
+		return nil, false
+	}
+	return user, true
+}
+
+// AddNewUser adds a new user along with their balance, registrations of a taken login fail with AlreadyExistsError.
+// The login is stored along with its digest from lookup.
+func (s *Storage) AddNewUser(ctx context.Context, credentials modeldto.User, lookup modelstorage.LoginLookup, userID string) error {
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+	tenantID := tenant.FromContext(ctx)
+	s.mu.Lock()
+	if s.loginTaken(tenantID, "", lookup) {
+		s.mu.Unlock()
+		err := &storageErrors.AlreadyExistsError{Err: errLoginTaken, ID: credentials.Login, Code: errcodes.LoginTaken}
+		s.log.Error().Err(err).Msg(fmt.Sprintf("adding new user failed for %s", credentials.Login))
+		return err
+	}
+	registeredAt := time.Now()
+	s.users[userID] = &userRecord{entry: modelstorage.UserStorageEntry{
+		ID:           s.nextID(),
+		UserID:       userID,
+		Login:        credentials.Login,
+		LoginHash:    lookup.Hash,
+		Password:     credentials.Password,
+		RegisteredAt: registeredAt,
+		TenantID:     tenantID,
+	}}
+	s.balances[userID] = &balanceRecord{updatedAt: registeredAt}
+	s.addOutboxEvent(tenantID, modelstorage.OutboxUserRegistered, map[string]interface{}{"user_id": userID, "registered_at": registeredAt})
+	s.mu.Unlock()
+	s.log.Info().Msg(fmt.Sprintf("adding new user done for %s", credentials.Login))
+	return nil
+}
+
+// CheckUser retrieves the ciphered credentials of an active user by their login digest, users stored before digests
+// were introduced are matched by the legacy ciphertexts of lookup.
+func (s *Storage) CheckUser(ctx context.Context, lookup modelstorage.LoginLookup) (*modelstorage.UserStorageEntry, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+	tenantID := tenant.FromContext(ctx)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, user := range s.users {
+		if user.entry.TenantID == tenantID && !user.entry.Deactivated && matchesLogin(user, lookup) {
+			s.log.Info().Msg("user authentication done")
+			return &modelstorage.UserStorageEntry{
+				ID:           user.entry.ID,
+				UserID:       user.entry.UserID,
+				Login:        user.entry.Login,
+				Password:     user.entry.Password,
+				RegisteredAt: user.entry.RegisteredAt,
+			}, nil
+		}
+	}
+	err := &storageErrors.NotFoundError{}
+	s.log.Error().Err(err).Msg("user authentication failed")
+	return nil, err
+}
+
+// GetCurrentAmount retrieves the current user's balance available for spending, i.e. less active holds.
+func (s *Storage) GetCurrentAmount(ctx context.Context, userID string) (float64, error) {
+	if err := checkContext(ctx); err != nil {
+		return 0, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	balance, ok := s.tenantBalance(userID, tenant.FromContext(ctx))
+	if !ok {
+		err := &storageErrors.NotFoundError{}
+		s.log.Error().Err(err).Msg("getting current balance failed")
+		return 0, err
+	}
+	s.log.Info().Msg("getting current balance done")
+	return balance.amount - s.heldAmount(userID, time.Now()), nil
+}
+
+// GetWithdrawnAmount retrieves the current user's withdrawn balance.
+func (s *Storage) GetWithdrawnAmount(ctx context.Context, userID string) (float64, error) {
+	if err := checkContext(ctx); err != nil {
+		return 0, err
+	}
+	tenantID := tenant.FromContext(ctx)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var withdrawn float64
+	for _, withdrawal := range s.withdrawals {
+		if withdrawal.UserID == userID && withdrawal.TenantID == tenantID && withdrawal.Status == modelstorage.WithdrawalProcessed {
+			withdrawn += withdrawal.Amount
+		}
+	}
+	s.log.Info().Msg("getting withdrawn balance done")
+	return withdrawn, nil
+}
+
+// GetBalanceAmounts retrieves both the current and the withdrawn user's balance along with the time of its last change.
+func (s *Storage) GetBalanceAmounts(ctx context.Context, userID string) (*modelstorage.BalanceAmountsStorageEntry, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	balance, ok := s.tenantBalance(userID, tenant.FromContext(ctx))
+	if !ok {
+		err := &storageErrors.NotFoundError{}
+		s.log.Error().Err(err).Msg("getting balance amounts failed")
+		return nil, err
+	}
+	amounts := modelstorage.BalanceAmountsStorageEntry{
+		CurrentAmount: balance.amount,
+		HeldAmount:    s.heldAmount(userID, time.Now()),
+		UpdatedAt:     balance.updatedAt,
+	}
+	for _, withdrawal := range s.withdrawals {
+		if withdrawal.UserID == userID && withdrawal.Status == modelstorage.WithdrawalProcessed {
+			amounts.WithdrawnAmount += withdrawal.Amount
+		}
+	}
+	s.log.Info().Msg("getting balance amounts done")
+	return &amounts, nil
+}
+
+// GetWithdrawals retrieves a user's history of withdrawals.
+func (s *Storage) GetWithdrawals(ctx context.Context, userID string, sorting modeldto.Sort) ([]modelstorage.WithdrawalStorageEntry, error) {
+	less, err := withdrawalLess(sorting)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+	tenantID := tenant.FromContext(ctx)
+	s.mu.RLock()
+	var withdrawals []modelstorage.WithdrawalStorageEntry
+	for _, withdrawal := range s.withdrawals {
+		if withdrawal.UserID == userID && withdrawal.TenantID == tenantID {
+			entry := *withdrawal
+			entry.TenantID = ""
+			withdrawals = append(withdrawals, entry)
+		}
+	}
+	s.mu.RUnlock()
+	sort.SliceStable(withdrawals, func(i, j int) bool { return less(&withdrawals[i], &withdrawals[j]) })
+	s.log.Info().Msg("getting withdrawals done")
+	return withdrawals, nil
+}
+
+// GetOrders retrieves a user's history of orders.
+func (s *Storage) GetOrders(ctx context.Context, userID string, sorting modeldto.Sort) ([]modelstorage.OrderStorageEntry, error) {
+	less, err := orderLess(sorting)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+	tenantID := tenant.FromContext(ctx)
+	s.mu.RLock()
+	var orders []modelstorage.OrderStorageEntry
+	for _, order := range s.orders {
+		if order.entry.UserID == userID && order.entry.TenantID == tenantID {
+			orders = append(orders, userOrder(order))
+		}
+	}
+	s.mu.RUnlock()
+	sort.SliceStable(orders, func(i, j int) bool { return less(&orders[i], &orders[j]) })
+	s.log.Info().Msg("getting orders done")
+	return orders, nil
+}
+
+// userOrder copies the attributes of an order exposed to its owner.
+func userOrder(order *orderRecord) modelstorage.OrderStorageEntry {
+	return modelstorage.OrderStorageEntry{
+		ID:          order.entry.ID,
+		UserID:      order.entry.UserID,
+		OrderNumber: order.entry.OrderNumber,
+		Status:      order.entry.Status,
+		Accrual:     order.entry.Accrual,
+		CreatedAt:   order.entry.CreatedAt,
+		Metadata:    order.entry.Metadata,
+	}
+}
+
+// AddNewWithdrawal adds a new processed withdrawal debiting the balance.
+func (s *Storage) AddNewWithdrawal(ctx context.Context, userID string, withdrawal modeldto.NewOrderWithdrawal) error {
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	alerts, err := s.withdraw(userID, tenant.FromContext(ctx), withdrawal)
+	s.mu.Unlock()
+	if err != nil {
+		s.log.Error().Err(err).Msg("processing new withdrawal order failed")
+		return err
+	}
+	s.log.Info().Msg("processing new withdrawal order done")
+	s.cache.InvalidateBalance(ctx, userID)
+	s.cache.InvalidateOrders(ctx, userID)
+	s.emit(modeldto.Notification{Kind: "balance_changed", UserID: userID, Message: fmt.Sprintf("balance debited with %v for order %s", withdrawal.Amount, withdrawal.OrderNumber)})
+	for _, alert := range alerts {
+		s.emit(alert)
+	}
+	return nil
+}
+
+// withdraw stores a processed withdrawal and debits the balance, it returns balance alerts to be emitted once
+// the lock is released. Nothing is modified if it fails, the caller must hold the write lock.
+func (s *Storage) withdraw(userID, tenantID string, withdrawal modeldto.NewOrderWithdrawal) ([]modeldto.Notification, error) {
+	orderNumber, err := strconv.Atoi(withdrawal.OrderNumber)
+	if err != nil {
+		return nil, &storageErrors.ExecutionPSQLError{Err: err}
+	}
+	err = s.checkWithdrawalOrder(userID, tenantID, orderNumber)
+	if err != nil {
+		return nil, err
+	}
+	err = s.checkBalance(userID, tenantID, -withdrawal.Amount, 0)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	s.ensureWithdrawalOrder(userID, tenantID, orderNumber, now)
+	s.withdrawals = append(s.withdrawals, &modelstorage.WithdrawalStorageEntry{
+		ID:          s.nextID(),
+		UserID:      userID,
+		OrderNumber: orderNumber,
+		Amount:      withdrawal.Amount,
+		ProcessedAt: now,
+		Status:      modelstorage.WithdrawalProcessed,
+		TenantID:    tenantID,
+	})
+	s.adjustBalance(userID, -withdrawal.Amount)
+	s.addBalanceEvent(userID, modelstorage.EventWithdrawal, -withdrawal.Amount, withdrawal.OrderNumber)
+	alerts := s.balanceAlerts(userID, -withdrawal.Amount, withdrawal.OrderNumber)
+	s.addOutboxEvent(tenantID, modelstorage.OutboxWithdrawalMade, map[string]interface{}{"user_id": userID, "order": withdrawal.OrderNumber, "sum": withdrawal.Amount})
+	return alerts, nil
+}
+
+// SendToQueue sends an order to processing queue.
+func (s *Storage) SendToQueue(item modelqueue.OrderQueueEntry) {
+	if !s.trackQueued(item.OrderNumber) {
+		return
+	}
+	s.metrics.Gauge(metrics.OrderQueueSize).Add(1)
+	s.QueueIn <- item
+}
+
+// queuedCount returns the number of orders currently present in the queue.
+func (s *Storage) queuedCount() int {
+	s.queuedMu.Lock()
+	defer s.queuedMu.Unlock()
+	return len(s.queued)
+}
+
+// trackQueued marks an order as present in the queue, it returns false if the order was already queued.
+func (s *Storage) trackQueued(orderNumber int) bool {
+	s.queuedMu.Lock()
+	defer s.queuedMu.Unlock()
+	if _, ok := s.queued[orderNumber]; ok {
+		return false
+	}
+	s.queued[orderNumber] = struct{}{}
+	return true
+}
+
+// untrackQueued marks an order as absent from the queue.
+func (s *Storage) untrackQueued(orderNumber int) {
+	s.queuedMu.Lock()
+	defer s.queuedMu.Unlock()
+	delete(s.queued, orderNumber)
+}
+
+// isQueued checks whether an order is present in the queue.
+func (s *Storage) isQueued(orderNumber int) bool {
+	s.queuedMu.Lock()
+	defer s.queuedMu.Unlock()
+	_, ok := s.queued[orderNumber]
+	return ok
+}
+
+// RescanStalledOrders finds non-final orders absent from the queue and re-enqueues them, abandoned orders are left
+// out until they are requeued by an administrator.
+func (s *Storage) RescanStalledOrders(ctx context.Context) error {
+	var requeued int
+	for _, stalledOrder := range s.getStalledOrders(func(order *orderRecord) bool { return order.abandonedAt.IsZero() }) {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if s.isQueued(stalledOrder.OrderNumber) {
+			continue
+		}
+		s.SendToQueue(stalledQueueEntry(stalledOrder))
+		requeued++
+	}
+	s.metrics.Counter("gophermart_orders_rescan_requeued_total").Add(uint64(requeued))
+	if requeued > 0 {
+		s.log.Warn().Msg(fmt.Sprintf("%v stalled orders absent from queue were re-enqueued", requeued))
+	}
+	return nil
+}
+
+// AddNewOrder adds a new order, orders registered earlier fail with AlreadyExistsError if they belong to the same
+// user and with AlreadyExistsAndViolatesError otherwise.
+func (s *Storage) AddNewOrder(ctx context.Context, userID string, orderNumber int, metadata string, channel string) error {
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	err := s.addNewOrder(userID, tenant.FromContext(ctx), orderNumber, metadata, channel)
+	s.mu.Unlock()
+	if err != nil {
+		s.log.Error().Err(err).Msg(fmt.Sprintf("adding new order failed for order %v", orderNumber))
+		return err
+	}
+	s.log.Info().Msg(fmt.Sprintf("adding new order done for order %v", orderNumber))
+	return nil
+}
+
+// addNewOrder stores a new order, the caller must hold the write lock.
+func (s *Storage) addNewOrder(userID, tenantID string, orderNumber int, metadata string, channel string) error {
+	if _, ok := s.users[userID]; !ok {
+		return &storageErrors.UnknownUserError{ID: userID}
+	}
+	if order, ok := s.orders[orderNumber]; ok {
+		if order.entry.UserID == userID {
+			return &storageErrors.AlreadyExistsError{ID: strconv.Itoa(orderNumber), Code: errcodes.DuplicateOrder}
+		}
+		return &storageErrors.AlreadyExistsAndViolatesError{ID: strconv.Itoa(orderNumber)}
+	}
+	s.orders[orderNumber] = &orderRecord{
+		entry: modelstorage.OrderStorageEntry{
+			ID:          s.nextID(),
+			UserID:      userID,
+			OrderNumber: orderNumber,
+			Status:      "NEW",
+			CreatedAt:   time.Now(),
+			TenantID:    tenantID,
+			Metadata:    metadata,
+		},
+		channel: channel,
+	}
+	return nil
+}
+
+// getStalledOrders retrieves non-final orders matching filter ordered by their registration.
+func (s *Storage) getStalledOrders(filter func(*orderRecord) bool) []modelstorage.OrderStorageEntry {
+	s.mu.RLock()
+	var stalledOrders []modelstorage.OrderStorageEntry
+	for _, order := range s.orders {
+		if !isFinal(order.entry.Status) && filter(order) {
+			stalledOrders = append(stalledOrders, order.entry)
+		}
+	}
+	s.mu.RUnlock()
+	sort.SliceStable(stalledOrders, func(i, j int) bool { return stalledOrders[i].ID < stalledOrders[j].ID })
+	return stalledOrders
+}
+
+// isFinal checks whether an order status is no longer updated by the accrual service.
+func isFinal(status string) bool {
+	return status == "PROCESSED" || status == "INVALID" || status == modelstorage.OrderSuspended
+}
+
+// stalledQueueEntry builds a queue entry for a stalled order restoring its retry state.
+func stalledQueueEntry(order modelstorage.OrderStorageEntry) modelqueue.OrderQueueEntry {
+	return modelqueue.OrderQueueEntry{
+		TenantID:     order.TenantID,
+		UserID:       order.UserID,
+		OrderNumber:  order.OrderNumber,
+		OrderStatus:  order.Status,
+		RetryCount:   order.Retry.RetryCount,
+		InvalidCount: order.Retry.InvalidCount,
+		PollStep:     order.Retry.PollStep,
+		LastChecked:  order.Retry.LastCheckedAt,
+		RetryAfter:   time.Duration(order.Retry.RetryAfterMs) * time.Millisecond,
+	}
+}
+
+// SaveRetryState stores retry metadata of a queued order, an order being retried is no longer considered abandoned.
+func (s *Storage) SaveRetryState(ctx context.Context, record modelqueue.OrderQueueEntry) error {
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	order, ok := s.orders[record.OrderNumber]
+	if ok && order.entry.TenantID == record.TenantID {
+		order.entry.Retry = modelstorage.OrderRetryStorageEntry{
+			RetryCount:    record.RetryCount,
+			InvalidCount:  record.InvalidCount,
+			PollStep:      record.PollStep,
+			LastCheckedAt: record.LastChecked,
+			RetryAfterMs:  record.RetryAfter.Milliseconds(),
+		}
+		order.abandonedAt = time.Time{}
+	}
+	return nil
+}
+
+// abandonOrder marks a non-final order whose polling was given up so that stalled order rescans skip it.
+func (s *Storage) abandonOrder(tenantID string, orderNumber int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	order, ok := s.orders[orderNumber]
+	if ok && order.entry.TenantID == tenantID && !isFinal(order.entry.Status) {
+		order.abandonedAt = time.Now()
+	}
+}
+
+// updateOrder updates an order with the status observed by source, the accrual is credited to the current order
+// owner after cashback rules matching the order are applied. Orders already in a final status are left intact
+// and accruals exceeding the configured caps are held in the SUSPENDED status pending admin approval instead.
+func (s *Storage) updateOrder(ctx context.Context, orderNumber int, status string, accrual float64, source string) error {
+	tenantID := tenant.FromContext(ctx)
+	// non-final statuses carry no accrual and do not modify the balance
+	if status != "PROCESSED" {
+		accrual = 0
+	}
+	s.mu.Lock()
+	order, ok := s.orders[orderNumber]
+	if !ok || order.entry.TenantID != tenantID {
+		s.mu.Unlock()
+		err := &storageErrors.NotFoundError{}
+		s.log.Error().Err(err).Msg(fmt.Sprintf("updating order failed for order %v", orderNumber))
+		return err
+	}
+	userID, previousStatus := order.entry.UserID, order.entry.Status
+	if isFinal(previousStatus) {
+		s.mu.Unlock()
+		s.log.Warn().Msg(fmt.Sprintf("updating order skipped for order %v, it is already %s, %s update to %s ignored", orderNumber, previousStatus, source, status))
+		s.metrics.Counter("gophermart_order_updates_skipped_total", "source", source).Inc()
+		return nil
+	}
+	var rule string
+	if status == "PROCESSED" {
+		result := s.cashback.Apply(cashback.Order{Accrual: accrual, Channel: order.channel, UploadedAt: order.entry.CreatedAt})
+		accrual, rule = result.Accrual, result.Rule
+		if rule != "" {
+			s.metrics.Counter("gophermart_cashback_applied_total", "rule", rule).Inc()
+		}
+		exceeded := s.exceedsAccrualCaps(userID, accrual)
+		if exceeded != "" {
+			s.log.Warn().Msg(fmt.Sprintf("accrual of %v for order %v exceeds the %s cap, suspending it", accrual, orderNumber, exceeded))
+			s.metrics.Counter("gophermart_accruals_suspended_total", "cap", exceeded).Inc()
+			status = modelstorage.OrderSuspended
+		}
+	}
+	if previousStatus != status {
+		s.addHistory(orderNumber, previousStatus, status, source)
+	}
+	order.entry.Status, order.entry.Accrual, order.cashbackRule = status, accrual, rule
+	var alerts []modeldto.Notification
+	if status == "PROCESSED" && accrual > 0 {
+		s.adjustBalance(userID, accrual)
+		s.addBalanceEvent(userID, modelstorage.EventAccrualCredited, accrual, strconv.Itoa(orderNumber))
+		alerts = s.balanceAlerts(userID, accrual, strconv.Itoa(orderNumber))
+	}
+	if previousStatus != status && (status == "PROCESSED" || status == "INVALID") {
+		s.addOutboxEvent(tenantID, modelstorage.OutboxOrderProcessed, map[string]interface{}{"user_id": userID, "order": strconv.Itoa(orderNumber), "status": status, "accrual": accrual})
+	}
+	s.mu.Unlock()
+	s.log.Info().Msg(fmt.Sprintf("updating order done for order %v", orderNumber))
+	s.cache.InvalidateBalance(ctx, userID)
+	s.cache.InvalidateOrders(ctx, userID)
+	if status == "PROCESSED" || status == "INVALID" {
+		s.emit(modeldto.Notification{Kind: "order_processed", UserID: userID, Message: fmt.Sprintf("order %v is %s", orderNumber, status)})
+	}
+	if status == "PROCESSED" && accrual > 0 {
+		s.emit(modeldto.Notification{Kind: "balance_changed", UserID: userID, Message: fmt.Sprintf("balance credited with %v for order %v", accrual, orderNumber)})
+	}
+	for _, alert := range alerts {
+		s.emit(alert)
+	}
+	return nil
+}
+
+// addHistory records an order status change, the caller must hold the write lock.
+func (s *Storage) addHistory(orderNumber int, from, to, source string) {
+	s.history = append(s.history, historyRecord{
+		entry:       modelstorage.OrderStatusHistoryStorageEntry{FromStatus: from, ToStatus: to, Source: source, ChangedAt: time.Now()},
+		orderNumber: orderNumber,
+	})
+}
+
+// emit publishes a user notification without blocking, notifications are dropped while the buffer is full
+// as they must never hold up balance updates.
+func (s *Storage) emit(notification modeldto.Notification) {
+	select {
+	case s.Events <- notification:
+	default:
+		s.metrics.Counter("gophermart_events_dropped_total", "kind", notification.Kind).Inc()
+	}
+}
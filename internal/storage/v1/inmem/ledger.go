@@ -0,0 +1,66 @@
+// Package inmem provides an in-process storage keeping all data in memory.
+
+package inmem
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/models/modeldto"
+	storageErrors "github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/errors"
+	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/modelstorage"
+	"github.com/danilovkiri/dk-go-gophermart/internal/tenant"
+)
+
+// GetBalanceEvents retrieves a user's balance events, newest first.
+func (s *Storage) GetBalanceEvents(ctx context.Context, userID string) ([]modelstorage.BalanceEventStorageEntry, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+	tenantID := tenant.FromContext(ctx)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var events []modelstorage.BalanceEventStorageEntry
+	for i := len(s.events) - 1; i >= 0; i-- {
+		if s.events[i].userID == userID && s.events[i].tenantID == tenantID {
+			events = append(events, s.events[i].entry)
+		}
+	}
+	s.log.Info().Msg("getting balance events done")
+	return events, nil
+}
+
+// AddAdjustment credits or debits an active user's balance by an administrator, the adjustment is recorded
+// in the balance event log under its reason code. It returns the recorded event and the resulting balance,
+// debits exceeding the balance fail with InsufficientFundsError.
+func (s *Storage) AddAdjustment(ctx context.Context, userID string, adjustment modeldto.BalanceAdjustmentRequest) (*modelstorage.BalanceEventStorageEntry, float64, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, 0, err
+	}
+	s.mu.Lock()
+	event, balance, err := s.addAdjustment(userID, adjustment)
+	s.mu.Unlock()
+	if err != nil {
+		s.log.Error().Err(err).Msg(fmt.Sprintf("adjusting balance failed for user %s", userID))
+		return nil, 0, err
+	}
+	s.log.Info().Msg(fmt.Sprintf("adjusting balance done for user %s", userID))
+	s.cache.InvalidateBalance(ctx, userID)
+	s.emit(modeldto.Notification{Kind: "balance_changed", UserID: userID, Message: fmt.Sprintf("balance adjusted by %v (%s)", adjustment.Amount, adjustment.ReasonCode)})
+	return event, balance, nil
+}
+
+// addAdjustment applies an adjustment to an active user's balance, the caller must hold the write lock.
+func (s *Storage) addAdjustment(userID string, adjustment modeldto.BalanceAdjustmentRequest) (*modelstorage.BalanceEventStorageEntry, float64, error) {
+	user, ok := s.users[userID]
+	if !ok || user.entry.Deactivated {
+		return nil, 0, &storageErrors.NotFoundError{}
+	}
+	err := s.checkBalance(userID, user.entry.TenantID, adjustment.Amount, 0)
+	if err != nil {
+		return nil, 0, err
+	}
+	s.adjustBalance(userID, adjustment.Amount)
+	event := s.addBalanceEvent(userID, modelstorage.EventAdjustment, adjustment.Amount, adjustment.ReasonCode)
+	return &event, s.balances[userID].amount, nil
+}
@@ -0,0 +1,24 @@
+// Package inmem provides an in-process storage keeping all data in memory.
+
+package inmem
+
+import (
+	"context"
+)
+
+// TryLock acquires a named lock without waiting, the lock is held until unlock is called. Locks are local
+// to the process as the data is not shared with other instances anyway.
+func (s *Storage) TryLock(_ context.Context, name string) (func(), bool, error) {
+	s.locksMu.Lock()
+	defer s.locksMu.Unlock()
+	if _, ok := s.locks[name]; ok {
+		return nil, false, nil
+	}
+	s.locks[name] = struct{}{}
+	unlock := func() {
+		s.locksMu.Lock()
+		defer s.locksMu.Unlock()
+		delete(s.locks, name)
+	}
+	return unlock, true, nil
+}
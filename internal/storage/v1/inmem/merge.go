@@ -0,0 +1,90 @@
+// Package inmem provides an in-process storage keeping all data in memory.
+
+package inmem
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/models/modeldto"
+	storageErrors "github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/errors"
+	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/modelstorage"
+)
+
+// MergeUsers moves orders, withdrawals and the balance of the donor account to the target account of the same tenant
+// and deactivates the donor account. Donor accounts with pending withdrawals are not merged as their processing is
+// bound to the donor.
+func (s *Storage) MergeUsers(ctx context.Context, donorID, targetID string) (*modeldto.AccountMerge, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	merge, err := s.mergeUsers(donorID, targetID)
+	s.mu.Unlock()
+	if err != nil {
+		s.log.Error().Err(err).Msg(fmt.Sprintf("merging account %s into %s failed", donorID, targetID))
+		return nil, err
+	}
+	s.log.Info().Msg(fmt.Sprintf("merging account %s into %s done", donorID, targetID))
+	for _, userID := range []string{donorID, targetID} {
+		s.cache.InvalidateOrders(ctx, userID)
+		s.cache.InvalidateBalance(ctx, userID)
+	}
+	return merge, nil
+}
+
+// mergeUsers validates and applies an account merge, the caller must hold the write lock.
+func (s *Storage) mergeUsers(donorID, targetID string) (*modeldto.AccountMerge, error) {
+	donor, donorFound := s.users[donorID]
+	target, targetFound := s.users[targetID]
+	for _, user := range []*userRecord{donor, target} {
+		if user != nil && user.entry.Deactivated {
+			return nil, &storageErrors.MergeConflictError{Msg: fmt.Sprintf("account %s is deactivated", user.entry.UserID)}
+		}
+	}
+	if !donorFound || !targetFound {
+		return nil, &storageErrors.NotFoundError{}
+	}
+	if donor.entry.TenantID != target.entry.TenantID {
+		return nil, &storageErrors.MergeConflictError{Msg: "accounts belong to different tenants"}
+	}
+	var pending int
+	for _, withdrawal := range s.withdrawals {
+		if withdrawal.UserID == donorID && withdrawal.Status == modelstorage.WithdrawalPending {
+			pending++
+		}
+	}
+	if pending > 0 {
+		return nil, &storageErrors.MergeConflictError{Msg: fmt.Sprintf("account %s has %v pending withdrawals", donorID, pending)}
+	}
+	merge := modeldto.AccountMerge{DonorID: donorID, TargetID: targetID}
+	for _, order := range s.orders {
+		if order.entry.UserID == donorID {
+			order.entry.UserID = targetID
+			merge.OrdersMoved++
+		}
+	}
+	for _, withdrawal := range s.withdrawals {
+		if withdrawal.UserID == donorID {
+			withdrawal.UserID = targetID
+			merge.WithdrawalsMoved++
+		}
+	}
+	// holds of the donor are dropped as its balance is moved as a whole
+	for _, hold := range s.holds {
+		if hold.entry.UserID == donorID && hold.entry.Status == modelstorage.HoldActive {
+			hold.entry.Status = modelstorage.HoldReleased
+		}
+	}
+	if balance, ok := s.balances[donorID]; ok {
+		merge.AmountMoved = balance.amount
+	}
+	s.adjustBalance(donorID, -merge.AmountMoved)
+	s.adjustBalance(targetID, merge.AmountMoved)
+	if merge.AmountMoved != 0 {
+		s.addBalanceEvent(donorID, modelstorage.EventTransferOut, -merge.AmountMoved, targetID)
+		s.addBalanceEvent(targetID, modelstorage.EventTransferIn, merge.AmountMoved, donorID)
+	}
+	donor.entry.Deactivated = true
+	return &merge, nil
+}
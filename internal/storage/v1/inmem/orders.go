@@ -0,0 +1,82 @@
+// Package inmem provides an in-process storage keeping all data in memory.
+
+package inmem
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	storageErrors "github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/errors"
+	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/modelstorage"
+	"github.com/danilovkiri/dk-go-gophermart/internal/tenant"
+)
+
+// GetOrder retrieves a single order of a user.
+func (s *Storage) GetOrder(ctx context.Context, userID string, orderNumber int) (*modelstorage.OrderStorageEntry, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	order, ok := s.orders[orderNumber]
+	if !ok || order.entry.UserID != userID || order.entry.TenantID != tenant.FromContext(ctx) {
+		s.mu.RUnlock()
+		err := &storageErrors.NotFoundError{}
+		s.log.Error().Err(err).Msg(fmt.Sprintf("getting order failed for order %v", orderNumber))
+		return nil, err
+	}
+	entry := userOrder(order)
+	s.mu.RUnlock()
+	s.log.Info().Msg(fmt.Sprintf("getting order done for order %v", orderNumber))
+	return &entry, nil
+}
+
+// GetOrderStatusHistory retrieves status transitions of a single user's order in chronological order.
+func (s *Storage) GetOrderStatusHistory(ctx context.Context, userID string, orderNumber int) ([]modelstorage.OrderStatusHistoryStorageEntry, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var history []modelstorage.OrderStatusHistoryStorageEntry
+	order, ok := s.orders[orderNumber]
+	if ok && order.entry.UserID == userID && order.entry.TenantID == tenant.FromContext(ctx) {
+		for _, change := range s.history {
+			if change.orderNumber == orderNumber {
+				history = append(history, change.entry)
+			}
+		}
+	}
+	s.log.Info().Msg(fmt.Sprintf("getting order status history done for order %v", orderNumber))
+	return history, nil
+}
+
+// GetUserStats retrieves the number of orders and the total accrual of a user per upload channel.
+func (s *Storage) GetUserStats(ctx context.Context, userID string) ([]modelstorage.ChannelStatsStorageEntry, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+	tenantID := tenant.FromContext(ctx)
+	s.mu.RLock()
+	byChannel := make(map[string]*modelstorage.ChannelStatsStorageEntry)
+	for _, order := range s.orders {
+		if order.entry.UserID != userID || order.entry.TenantID != tenantID {
+			continue
+		}
+		channelStats, ok := byChannel[order.channel]
+		if !ok {
+			channelStats = &modelstorage.ChannelStatsStorageEntry{Channel: order.channel}
+			byChannel[order.channel] = channelStats
+		}
+		channelStats.Orders++
+		channelStats.Accrual += order.entry.Accrual
+	}
+	s.mu.RUnlock()
+	var stats []modelstorage.ChannelStatsStorageEntry
+	for _, channelStats := range byChannel {
+		stats = append(stats, *channelStats)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Channel < stats[j].Channel })
+	s.log.Info().Msg("getting user stats done")
+	return stats, nil
+}
@@ -0,0 +1,62 @@
+// Package inmem provides an in-process storage keeping all data in memory.
+
+package inmem
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/modelstorage"
+)
+
+// addOutboxEvent records a domain event, the payload is stored as JSON. Every event gets its own writing
+// transaction ID as events become visible at once. The caller must hold the write lock.
+func (s *Storage) addOutboxEvent(tenantID, kind string, payload interface{}) {
+	// payloads are maps of plain values which always marshal
+	payloadJSON, _ := json.Marshal(payload)
+	id := int64(s.nextID())
+	s.outbox = append(s.outbox, modelstorage.OutboxEventStorageEntry{
+		ID:        id,
+		TxID:      id,
+		TenantID:  tenantID,
+		Kind:      kind,
+		Payload:   string(payloadJSON),
+		CreatedAt: time.Now(),
+	})
+}
+
+// GetOutboxEvents retrieves domain events following the offset of a relay in the order they were recorded.
+func (s *Storage) GetOutboxEvents(ctx context.Context, relay string, limit int) ([]modelstorage.OutboxEventStorageEntry, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	offset := s.offsets[relay]
+	var events []modelstorage.OutboxEventStorageEntry
+	for _, event := range s.outbox {
+		if len(events) >= limit {
+			break
+		}
+		if event.ID > offset {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+// CommitOutboxOffset stores the position of the last event published by a relay, the offset never moves back.
+func (s *Storage) CommitOutboxOffset(ctx context.Context, relay string, _, id int64) error {
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if id > s.offsets[relay] {
+		s.offsets[relay] = id
+	}
+	s.log.Info().Msg(fmt.Sprintf("committing outbox offset done for relay %s at event %v", relay, id))
+	return nil
+}
@@ -0,0 +1,92 @@
+// Package inmem provides an in-process storage keeping all data in memory.
+
+package inmem
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/errcodes"
+	storageErrors "github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/errors"
+	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/modelstorage"
+	"github.com/danilovkiri/dk-go-gophermart/internal/tenant"
+)
+
+// GetUser retrieves a user's ciphered credentials and contact details.
+func (s *Storage) GetUser(ctx context.Context, userID string) (*modelstorage.UserStorageEntry, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	user, ok := s.tenantUser(userID, tenant.FromContext(ctx))
+	var entry modelstorage.UserStorageEntry
+	if ok {
+		entry = modelstorage.UserStorageEntry{
+			ID:           user.entry.ID,
+			UserID:       user.entry.UserID,
+			Login:        user.entry.Login,
+			Password:     user.entry.Password,
+			RegisteredAt: user.entry.RegisteredAt,
+			TenantID:     user.entry.TenantID,
+			Email:        user.entry.Email,
+			Phone:        user.entry.Phone,
+		}
+	}
+	s.mu.RUnlock()
+	if !ok {
+		err := &storageErrors.NotFoundError{}
+		s.log.Error().Err(err).Msg("getting user failed")
+		return nil, err
+	}
+	s.log.Info().Msg("getting user done")
+	return &entry, nil
+}
+
+// UpdateUserProfile stores the re-ciphered login, the password and contact details of a user. The update is rejected
+// if the new login identified by lookup, which is nil unless the login changes, belongs to another user of the tenant.
+func (s *Storage) UpdateUserProfile(ctx context.Context, userID string, profile modelstorage.UserStorageEntry, lookup *modelstorage.LoginLookup, changes []string) error {
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+	tenantID := tenant.FromContext(ctx)
+	s.mu.Lock()
+	var err error
+	user, ok := s.tenantUser(userID, tenantID)
+	switch {
+	case lookup != nil && s.loginTaken(tenantID, userID, *lookup):
+		err = &storageErrors.AlreadyExistsError{Err: errLoginTaken, ID: userID, Code: errcodes.LoginTaken}
+	case !ok:
+		err = &storageErrors.NotFoundError{}
+	default:
+		user.entry.Login, user.entry.LoginHash, user.entry.Password = profile.Login, profile.LoginHash, profile.Password
+		user.entry.Email, user.entry.Phone = profile.Email, profile.Phone
+	}
+	s.mu.Unlock()
+	if err != nil {
+		s.log.Error().Err(err).Msg("updating user profile failed")
+		return err
+	}
+	s.log.Info().Msg(fmt.Sprintf("updating user profile done for fields %v", changes))
+	return nil
+}
+
+// UpdatePasswordHash replaces the stored password of a user with a hash of it, it is used for migrating legacy
+// ciphered passwords and for rehashing them with another cost.
+func (s *Storage) UpdatePasswordHash(ctx context.Context, userID, hash string) error {
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	user, ok := s.tenantUser(userID, tenant.FromContext(ctx))
+	if ok {
+		user.entry.Password = hash
+	}
+	s.mu.Unlock()
+	if !ok {
+		err := &storageErrors.NotFoundError{}
+		s.log.Error().Err(err).Msg(fmt.Sprintf("updating password hash failed for user %s", userID))
+		return err
+	}
+	s.log.Info().Msg(fmt.Sprintf("updating password hash done for user %s", userID))
+	return nil
+}
@@ -0,0 +1,74 @@
+// Package inmem provides an in-process storage keeping all data in memory.
+
+package inmem
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	storageErrors "github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/errors"
+	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/modelstorage"
+	"github.com/danilovkiri/dk-go-gophermart/internal/tenant"
+)
+
+// RecheckOrder resets an INVALID order to NEW along with its retry state so that it can be queued for another
+// accrual check. A user's own order is looked up within the tenant while an empty userID looks the order up
+// by its number alone on behalf of an administrator. Rechecks are subject to limit unless it is nil: the same
+// order is rechecked at most once per interval and a user rechecks at most PerUser orders per interval.
+func (s *Storage) RecheckOrder(ctx context.Context, userID string, orderNumber int, limit *modelstorage.RecheckLimit) (*modelstorage.OrderStorageEntry, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+	source := modelstorage.SourceAdminRecheck
+	if userID != "" {
+		source = modelstorage.SourceUserRecheck
+	}
+	s.mu.Lock()
+	order, err := s.recheckOrder(userID, tenant.FromContext(ctx), orderNumber, limit, source)
+	s.mu.Unlock()
+	if err != nil {
+		s.log.Error().Err(err).Msg(fmt.Sprintf("rechecking order failed for order %v", orderNumber))
+		return nil, err
+	}
+	s.log.Info().Msg(fmt.Sprintf("rechecking order done for order %v", orderNumber))
+	s.metrics.Counter("gophermart_order_rechecks_total", "source", source).Inc()
+	return order, nil
+}
+
+// recheckOrder validates and applies an order recheck, the caller must hold the write lock.
+func (s *Storage) recheckOrder(userID, tenantID string, orderNumber int, limit *modelstorage.RecheckLimit, source string) (*modelstorage.OrderStorageEntry, error) {
+	record, ok := s.orders[orderNumber]
+	if !ok || userID != "" && (record.entry.UserID != userID || record.entry.TenantID != tenantID) {
+		return nil, &storageErrors.NotFoundError{}
+	}
+	if record.entry.Status != "INVALID" {
+		return nil, &storageErrors.RecheckNotAllowedError{ID: strconv.Itoa(orderNumber), Status: record.entry.Status}
+	}
+	now := time.Now()
+	if limit != nil {
+		if !record.recheckedAt.IsZero() && now.Sub(record.recheckedAt) < limit.Interval {
+			return nil, &storageErrors.RecheckRateLimitedError{ID: strconv.Itoa(orderNumber), RetryAfter: record.recheckedAt.Add(limit.Interval).Sub(now)}
+		}
+		var rechecked int
+		var earliest time.Time
+		since := now.Add(-limit.Interval)
+		for _, order := range s.orders {
+			if order.entry.UserID == record.entry.UserID && order.recheckedAt.After(since) {
+				rechecked++
+				if earliest.IsZero() || order.recheckedAt.Before(earliest) {
+					earliest = order.recheckedAt
+				}
+			}
+		}
+		if rechecked >= limit.PerUser {
+			return nil, &storageErrors.RecheckRateLimitedError{ID: strconv.Itoa(orderNumber), RetryAfter: earliest.Add(limit.Interval).Sub(now)}
+		}
+	}
+	s.addHistory(orderNumber, record.entry.Status, "NEW", source)
+	record.entry.Status, record.entry.Accrual, record.cashbackRule = "NEW", 0, ""
+	record.entry.Retry = modelstorage.OrderRetryStorageEntry{}
+	record.recheckedAt = now
+	return &modelstorage.OrderStorageEntry{UserID: record.entry.UserID, OrderNumber: orderNumber, Status: "NEW", TenantID: record.entry.TenantID}, nil
+}
@@ -0,0 +1,134 @@
+// Package inmem provides an in-process storage keeping all data in memory.
+
+package inmem
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/models/modeldto"
+	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/modelstorage"
+)
+
+// GetReconciliationReport returns the latest reconciliation report, running reconciliation if none is available yet.
+func (s *Storage) GetReconciliationReport(ctx context.Context) (*modeldto.ReconciliationReport, error) {
+	s.reconcileMu.RLock()
+	report := s.reconcileReport
+	s.reconcileMu.RUnlock()
+	if report != nil {
+		return report, nil
+	}
+	return s.reconcileBalances(ctx)
+}
+
+// ReconcileBalances reconciles stored balances against orders, withdrawals and manual adjustments refreshing the latest
+// report.
+func (s *Storage) ReconcileBalances(ctx context.Context) error {
+	_, err := s.reconcileBalances(ctx)
+	return err
+}
+
+// reconcileBalances compares stored balances to the ones recomputed from orders, withdrawals and manual adjustments.
+func (s *Storage) reconcileBalances(ctx context.Context) (*modeldto.ReconciliationReport, error) {
+	if err := checkContext(ctx); err != nil {
+		s.log.Error().Err(err).Msg("reconciling balances failed")
+		return nil, err
+	}
+	s.mu.RLock()
+	report := newReconciliationReport(s.balanceDiscrepancies())
+	s.mu.RUnlock()
+	s.reconcileMu.Lock()
+	s.reconcileReport = report
+	s.reconcileMu.Unlock()
+	s.metrics.Counter("gophermart_reconciliation_runs_total").Inc()
+	s.metrics.Gauge("gophermart_reconciliation_users_checked").Set(int64(report.UsersChecked))
+	s.metrics.Gauge("gophermart_reconciliation_discrepancies").Set(int64(len(report.Discrepancies)))
+	if len(report.Discrepancies) > 0 {
+		s.log.Warn().Msg(fmt.Sprintf("reconciling balances found %v discrepancies", len(report.Discrepancies)))
+	} else {
+		s.log.Info().Msg("reconciling balances done")
+	}
+	return report, nil
+}
+
+// RecalculateBalances recomputes every balance from orders minus withdrawals plus manual adjustments and stores
+// the results if apply is true.
+func (s *Storage) RecalculateBalances(ctx context.Context, apply bool) (*modeldto.ReconciliationReport, error) {
+	if err := checkContext(ctx); err != nil {
+		s.log.Error().Err(err).Msg("recalculating balances failed")
+		return nil, err
+	}
+	s.mu.Lock()
+	report := newReconciliationReport(s.balanceDiscrepancies())
+	if apply {
+		for _, discrepancy := range report.Discrepancies {
+			s.adjustBalance(discrepancy.UserID, discrepancy.ExpectedAmount-discrepancy.StoredAmount)
+			s.addBalanceEvent(discrepancy.UserID, modelstorage.EventAdjustment, -discrepancy.Difference, modelstorage.ReasonRecalculation)
+		}
+		report.Applied = true
+	}
+	s.mu.Unlock()
+	if apply {
+		for _, discrepancy := range report.Discrepancies {
+			s.cache.InvalidateBalance(ctx, discrepancy.UserID)
+		}
+		s.reconcileMu.Lock()
+		s.reconcileReport = nil
+		s.reconcileMu.Unlock()
+	}
+	s.log.Info().Msg(fmt.Sprintf("recalculating balances done, %v discrepancies, applied: %v", len(report.Discrepancies), apply))
+	return report, nil
+}
+
+// balanceDiscrepancies recomputes each user's balance as the sum of credited accruals minus the sum of processed
+// withdrawals plus the sum of manual adjustments, the caller must hold the lock.
+func (s *Storage) balanceDiscrepancies() []modelstorage.BalanceDiscrepancyStorageEntry {
+	expected := make(map[string]float64, len(s.balances))
+	for _, order := range s.orders {
+		if order.entry.Status == "PROCESSED" {
+			expected[order.entry.UserID] += order.entry.Accrual
+		}
+	}
+	for _, withdrawal := range s.withdrawals {
+		if withdrawal.Status == modelstorage.WithdrawalProcessed {
+			expected[withdrawal.UserID] -= withdrawal.Amount
+		}
+	}
+	for _, event := range s.events {
+		if event.entry.Kind == modelstorage.EventAdjustment && event.entry.Reference != modelstorage.ReasonRecalculation {
+			expected[event.userID] += event.entry.Amount
+		}
+	}
+	entries := make([]modelstorage.BalanceDiscrepancyStorageEntry, 0, len(s.balances))
+	for userID, balance := range s.balances {
+		entries = append(entries, modelstorage.BalanceDiscrepancyStorageEntry{UserID: userID, StoredAmount: balance.amount, ExpectedAmount: expected[userID]})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].UserID < entries[j].UserID })
+	return entries
+}
+
+// newReconciliationReport builds a report listing balances which differ from the recomputed ones.
+func newReconciliationReport(entries []modelstorage.BalanceDiscrepancyStorageEntry) *modeldto.ReconciliationReport {
+	report := &modeldto.ReconciliationReport{
+		CheckedAt:     time.Now().Format(time.RFC3339),
+		UsersChecked:  len(entries),
+		Discrepancies: []modeldto.BalanceDiscrepancy{},
+	}
+	for _, entry := range entries {
+		// amounts are rounded to cents just like the relational storage stores them, anything below is a float artifact
+		difference := math.Round((entry.StoredAmount-entry.ExpectedAmount)*100) / 100
+		if difference == 0 {
+			continue
+		}
+		report.Discrepancies = append(report.Discrepancies, modeldto.BalanceDiscrepancy{
+			UserID:         entry.UserID,
+			StoredAmount:   entry.StoredAmount,
+			ExpectedAmount: entry.ExpectedAmount,
+			Difference:     difference,
+		})
+	}
+	return report
+}
@@ -0,0 +1,44 @@
+// Package inmem provides an in-process storage keeping all data in memory.
+
+package inmem
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/models/modeldto"
+	storageErrors "github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/errors"
+	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/modelstorage"
+)
+
+// RequeueOrders sends non-final orders matching filter to the processing queue restoring their retry state, orders
+// already present in the queue are counted as matched but not requeued. Empty filter values match any order.
+func (s *Storage) RequeueOrders(ctx context.Context, filter modelstorage.RequeueFilter) (*modeldto.RequeueReport, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+	orders := s.getStalledOrders(func(order *orderRecord) bool {
+		return (filter.Status == "" || order.entry.Status == filter.Status) &&
+			order.entry.CreatedAt.Before(filter.CreatedTo) &&
+			(filter.UserID == "" || order.entry.UserID == filter.UserID) &&
+			order.entry.OrderNumber >= filter.NumberFrom &&
+			(filter.NumberTo == 0 || order.entry.OrderNumber <= filter.NumberTo)
+	})
+	var report modeldto.RequeueReport
+	for _, order := range orders {
+		if ctx.Err() != nil {
+			err := &storageErrors.ContextTimeoutExceededError{Err: ctx.Err()}
+			s.log.Error().Err(err).Msg("requeueing orders failed")
+			return nil, err
+		}
+		report.Matched++
+		if s.isQueued(order.OrderNumber) {
+			continue
+		}
+		s.SendToQueue(stalledQueueEntry(order))
+		report.Requeued++
+	}
+	s.log.Info().Msg(fmt.Sprintf("requeueing orders done, %v matched, %v requeued", report.Matched, report.Requeued))
+	s.metrics.Counter("gophermart_orders_bulk_requeued_total").Add(uint64(report.Requeued))
+	return &report, nil
+}
@@ -0,0 +1,61 @@
+// Package inmem provides an in-process storage keeping all data in memory.
+
+package inmem
+
+import (
+	"context"
+	"fmt"
+
+	storageErrors "github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/errors"
+	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/modelstorage"
+)
+
+// SaveAccrualResponse stores the raw accrual system response received for an order replacing the previous one.
+func (s *Storage) SaveAccrualResponse(ctx context.Context, response modelstorage.AccrualResponseStorageEntry) error {
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responses[response.OrderNumber] = response
+	return nil
+}
+
+// GetAccrualResponse retrieves the latest raw accrual system response received for an order of any tenant.
+func (s *Storage) GetAccrualResponse(ctx context.Context, orderNumber int) (*modelstorage.AccrualResponseStorageEntry, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	response, ok := s.responses[orderNumber]
+	s.mu.RUnlock()
+	if !ok {
+		err := &storageErrors.NotFoundError{}
+		s.log.Error().Err(err).Msg(fmt.Sprintf("getting accrual response failed for order %v", orderNumber))
+		return nil, err
+	}
+	s.log.Info().Msg(fmt.Sprintf("getting accrual response done for order %v", orderNumber))
+	return &response, nil
+}
+
+// GetAnyOrder retrieves an order of any user and tenant.
+func (s *Storage) GetAnyOrder(ctx context.Context, orderNumber int) (*modelstorage.OrderStorageEntry, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	order, ok := s.orders[orderNumber]
+	var entry modelstorage.OrderStorageEntry
+	if ok {
+		entry = userOrder(order)
+		entry.TenantID = order.entry.TenantID
+	}
+	s.mu.RUnlock()
+	if !ok {
+		err := &storageErrors.NotFoundError{}
+		s.log.Error().Err(err).Msg(fmt.Sprintf("getting order failed for order %v", orderNumber))
+		return nil, err
+	}
+	s.log.Info().Msg(fmt.Sprintf("getting order done for order %v", orderNumber))
+	return &entry, nil
+}
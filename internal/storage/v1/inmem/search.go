@@ -0,0 +1,56 @@
+// Package inmem provides an in-process storage keeping all data in memory.
+
+package inmem
+
+import (
+	"context"
+	"sort"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/modelstorage"
+)
+
+// SearchUsers returns a page of users of any tenant matching the search, deactivated ones included, ordered by
+// registration. Logins are matched by their digests or, for users stored before digests were introduced,
+// by their legacy ciphertexts.
+func (s *Storage) SearchUsers(ctx context.Context, search modelstorage.UserSearch) ([]modelstorage.UserStorageEntry, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	var orderOwner string
+	if order, ok := s.orders[search.OrderNumber]; ok && search.OrderNumber != 0 {
+		orderOwner = order.entry.UserID
+	}
+	var users []modelstorage.UserStorageEntry
+	for _, user := range s.users {
+		matched := user.entry.UserID == search.UserID || user.entry.UserID == orderOwner
+		for _, lookup := range search.Lookups {
+			matched = matched || matchesLogin(user, lookup)
+		}
+		if matched {
+			users = append(users, modelstorage.UserStorageEntry{
+				ID:           user.entry.ID,
+				UserID:       user.entry.UserID,
+				Login:        user.entry.Login,
+				LoginHash:    user.entry.LoginHash,
+				RegisteredAt: user.entry.RegisteredAt,
+				TenantID:     user.entry.TenantID,
+				Deactivated:  user.entry.Deactivated,
+			})
+		}
+	}
+	s.mu.RUnlock()
+	sort.SliceStable(users, func(i, j int) bool {
+		return ordered(compareTimes(users[i].RegisteredAt, users[j].RegisteredAt), compareIDs(users[i].ID, users[j].ID), false)
+	})
+	if search.Offset >= len(users) {
+		users = nil
+	} else {
+		users = users[search.Offset:]
+	}
+	if search.Limit < len(users) {
+		users = users[:search.Limit]
+	}
+	s.log.Info().Msg("searching users done")
+	return users, nil
+}
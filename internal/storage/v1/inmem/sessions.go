@@ -0,0 +1,177 @@
+// Package inmem provides an in-process storage keeping all data in memory.
+
+package inmem
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	storageErrors "github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/errors"
+	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/modelstorage"
+	"github.com/danilovkiri/dk-go-gophermart/internal/tenant"
+)
+
+// sessionTouchInterval defines how often the activity of a session is written.
+const sessionTouchInterval = time.Minute
+
+// AddSession records an issued token, it returns true if the user has earlier sessions and none of them share its fingerprint.
+func (s *Storage) AddSession(ctx context.Context, session modelstorage.SessionStorageEntry) (bool, error) {
+	if err := checkContext(ctx); err != nil {
+		return false, err
+	}
+	tenantID := tenant.FromContext(ctx)
+	s.mu.Lock()
+	if _, ok := s.users[session.UserID]; !ok {
+		s.mu.Unlock()
+		err := &storageErrors.UnknownUserError{ID: session.UserID}
+		s.log.Error().Err(err).Msg(fmt.Sprintf("adding session failed for user %s", session.UserID))
+		return false, err
+	}
+	var total, matched int
+	for _, stored := range s.sessions {
+		if stored.tenantID == tenantID && stored.entry.UserID == session.UserID {
+			total++
+			if stored.entry.Fingerprint == session.Fingerprint {
+				matched++
+			}
+		}
+	}
+	now := time.Now()
+	session.ID, session.CreatedAt, session.LastSeen = s.nextID(), now, now
+	s.sessions = append(s.sessions, &sessionRecord{entry: session, tenantID: tenantID})
+	s.mu.Unlock()
+	s.log.Info().Msg(fmt.Sprintf("adding session done for user %s", session.UserID))
+	return total > 0 && matched == 0, nil
+}
+
+// GetSessions retrieves a user's history of issued tokens.
+func (s *Storage) GetSessions(ctx context.Context, userID string) ([]modelstorage.SessionStorageEntry, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+	tenantID := tenant.FromContext(ctx)
+	s.mu.RLock()
+	var sessions []modelstorage.SessionStorageEntry
+	for _, stored := range s.sessions {
+		if stored.tenantID == tenantID && stored.entry.UserID == userID {
+			session := stored.entry
+			session.RefreshHash, session.RefreshExpiresAt = "", time.Time{}
+			sessions = append(sessions, session)
+		}
+	}
+	s.mu.RUnlock()
+	sort.SliceStable(sessions, func(i, j int) bool { return sessions[i].CreatedAt.After(sessions[j].CreatedAt) })
+	s.log.Info().Msg("getting sessions done")
+	return sessions, nil
+}
+
+// TouchSession verifies that a session is not revoked, neither older than lifetime nor inactive for idleTimeout
+// and records the activity, zero durations disable the corresponding check. Activity is written at most once per
+// sessionTouchInterval just like the relational storage does.
+func (s *Storage) TouchSession(ctx context.Context, userID, sessionID string, idleTimeout, lifetime time.Duration) error {
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+	tenantID := tenant.FromContext(ctx)
+	s.mu.Lock()
+	var session *sessionRecord
+	for _, stored := range s.sessions {
+		if stored.entry.SessionID == sessionID && stored.entry.UserID == userID && stored.tenantID == tenantID {
+			session = stored
+		}
+	}
+	now := time.Now()
+	var err error
+	switch {
+	case session == nil || sessionID == "":
+		err = &storageErrors.NotFoundError{}
+	case session.revoked:
+		err = &storageErrors.SessionExpiredError{ID: sessionID, Reason: "revocation"}
+	case lifetime > 0 && now.Sub(session.entry.CreatedAt) >= lifetime:
+		err = &storageErrors.SessionExpiredError{ID: sessionID, Reason: "lifetime"}
+	case idleTimeout > 0 && now.Sub(session.entry.LastSeen) >= idleTimeout:
+		err = &storageErrors.SessionExpiredError{ID: sessionID, Reason: "inactivity"}
+	case now.Sub(session.entry.LastSeen) >= sessionTouchInterval:
+		session.entry.LastSeen = now
+	}
+	s.mu.Unlock()
+	if err != nil {
+		s.log.Error().Err(err).Msg(fmt.Sprintf("touching session failed for user %s", userID))
+		return err
+	}
+	return nil
+}
+
+// RotateRefreshToken exchanges the refresh token with the given digest for a new one expiring at expiresAt and
+// records the session activity, so that a refresh token can be used only once. Refresh tokens of revoked sessions,
+// expired ones and those of sessions older than lifetime are rejected with SessionExpiredError, a zero lifetime
+// disables the latter check.
+func (s *Storage) RotateRefreshToken(ctx context.Context, refreshHash, newRefreshHash string, expiresAt time.Time, lifetime time.Duration) (*modelstorage.SessionStorageEntry, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+	tenantID := tenant.FromContext(ctx)
+	s.mu.Lock()
+	var session *sessionRecord
+	for _, stored := range s.sessions {
+		if refreshHash != "" && stored.entry.RefreshHash == refreshHash && stored.tenantID == tenantID {
+			session = stored
+		}
+	}
+	now := time.Now()
+	var err error
+	switch {
+	case session == nil:
+		err = &storageErrors.NotFoundError{}
+	case session.revoked:
+		err = &storageErrors.SessionExpiredError{ID: session.entry.SessionID, Reason: "revocation"}
+	case !session.entry.RefreshExpiresAt.After(now):
+		err = &storageErrors.SessionExpiredError{ID: session.entry.SessionID, Reason: "refresh token expiration"}
+	case lifetime > 0 && now.Sub(session.entry.CreatedAt) >= lifetime:
+		err = &storageErrors.SessionExpiredError{ID: session.entry.SessionID, Reason: "lifetime"}
+	}
+	if err != nil {
+		s.mu.Unlock()
+		s.log.Error().Err(err).Msg("rotating refresh token failed")
+		return nil, err
+	}
+	session.entry.RefreshHash, session.entry.RefreshExpiresAt, session.entry.LastSeen = newRefreshHash, expiresAt, now
+	rotated := modelstorage.SessionStorageEntry{
+		ID:               session.entry.ID,
+		UserID:           session.entry.UserID,
+		SessionID:        session.entry.SessionID,
+		CreatedAt:        session.entry.CreatedAt,
+		LastSeen:         now,
+		RefreshHash:      newRefreshHash,
+		RefreshExpiresAt: expiresAt,
+	}
+	s.mu.Unlock()
+	s.log.Info().Msg(fmt.Sprintf("rotating refresh token done for user %s", rotated.UserID))
+	return &rotated, nil
+}
+
+// RevokeSession revokes a session along with its refresh token, access tokens of the session are rejected from then on.
+func (s *Storage) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+	tenantID := tenant.FromContext(ctx)
+	s.mu.Lock()
+	var revoked bool
+	for _, stored := range s.sessions {
+		if stored.entry.SessionID == sessionID && stored.entry.UserID == userID && stored.tenantID == tenantID && !stored.revoked {
+			stored.revoked, stored.entry.RefreshHash = true, ""
+			revoked = true
+		}
+	}
+	s.mu.Unlock()
+	if !revoked {
+		err := &storageErrors.NotFoundError{}
+		s.log.Error().Err(err).Msg(fmt.Sprintf("revoking session failed for user %s", userID))
+		return err
+	}
+	s.log.Info().Msg(fmt.Sprintf("revoking session done for user %s", userID))
+	return nil
+}
@@ -0,0 +1,119 @@
+// Package inmem provides an in-process storage keeping all data in memory.
+
+package inmem
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/models/modeldto"
+	storageErrors "github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/errors"
+	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/modelstorage"
+)
+
+// Whitelisted sorting fields mapped to comparisons of the corresponding attributes, the same fields as
+// the relational storage accepts.
+var (
+	orderSortFields = map[string]func(a, b *modelstorage.OrderStorageEntry) int{
+		"uploaded_at": func(a, b *modelstorage.OrderStorageEntry) int { return compareTimes(a.CreatedAt, b.CreatedAt) },
+		"accrual":     func(a, b *modelstorage.OrderStorageEntry) int { return compareFloats(a.Accrual, b.Accrual) },
+		"status":      func(a, b *modelstorage.OrderStorageEntry) int { return strings.Compare(a.Status, b.Status) },
+	}
+	withdrawalSortFields = map[string]func(a, b *modelstorage.WithdrawalStorageEntry) int{
+		"processed_at": func(a, b *modelstorage.WithdrawalStorageEntry) int { return compareTimes(a.ProcessedAt, b.ProcessedAt) },
+		"sum":          func(a, b *modelstorage.WithdrawalStorageEntry) int { return compareFloats(a.Amount, b.Amount) },
+		"status":       func(a, b *modelstorage.WithdrawalStorageEntry) int { return strings.Compare(a.Status, b.Status) },
+	}
+)
+
+// orderLess builds an ordering of orders from whitelisted sorting options, ties are broken by insertion order.
+func orderLess(sorting modeldto.Sort) (func(a, b *modelstorage.OrderStorageEntry) bool, error) {
+	field, desc, err := sortOptions(sorting, "uploaded_at", func(field string) bool {
+		_, ok := orderSortFields[field]
+		return ok
+	})
+	if err != nil {
+		return nil, err
+	}
+	compare := orderSortFields[field]
+	return func(a, b *modelstorage.OrderStorageEntry) bool {
+		return ordered(compare(a, b), compareIDs(a.ID, b.ID), desc)
+	}, nil
+}
+
+// withdrawalLess builds an ordering of withdrawals from whitelisted sorting options, ties are broken by insertion order.
+func withdrawalLess(sorting modeldto.Sort) (func(a, b *modelstorage.WithdrawalStorageEntry) bool, error) {
+	field, desc, err := sortOptions(sorting, "processed_at", func(field string) bool {
+		_, ok := withdrawalSortFields[field]
+		return ok
+	})
+	if err != nil {
+		return nil, err
+	}
+	compare := withdrawalSortFields[field]
+	return func(a, b *modelstorage.WithdrawalStorageEntry) bool {
+		return ordered(compare(a, b), compareIDs(a.ID, b.ID), desc)
+	}, nil
+}
+
+// sortOptions validates sorting options against the known fields and returns the field to sort by and whether
+// the order is descending.
+func sortOptions(sorting modeldto.Sort, defaultField string, known func(string) bool) (string, bool, error) {
+	field := sorting.Field
+	if field == "" {
+		field = defaultField
+	}
+	if !known(field) {
+		return "", false, &storageErrors.IllegalSortError{Msg: fmt.Sprintf("unsupported sort field %q", sorting.Field)}
+	}
+	switch sorting.Order {
+	case "", "asc":
+		return field, false, nil
+	case "desc":
+		return field, true, nil
+	default:
+		return "", false, &storageErrors.IllegalSortError{Msg: fmt.Sprintf("unsupported sort order %q", sorting.Order)}
+	}
+}
+
+// ordered reports whether a record precedes another one given the comparison of their sorting field and IDs.
+func ordered(byField, byID int, desc bool) bool {
+	if byField == 0 {
+		byField = byID
+	}
+	if desc {
+		return byField > 0
+	}
+	return byField < 0
+}
+
+func compareTimes(a, b time.Time) int {
+	switch {
+	case a.Before(b):
+		return -1
+	case a.After(b):
+		return 1
+	}
+	return 0
+}
+
+func compareFloats(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func compareIDs(a, b uint) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
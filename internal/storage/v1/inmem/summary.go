@@ -0,0 +1,95 @@
+// Package inmem provides an in-process storage keeping all data in memory.
+
+package inmem
+
+import (
+	"context"
+	"time"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/models/modeldto"
+	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/modelstorage"
+)
+
+// GetSummary collects operational counters, points issued and withdrawn are aggregated over each of the given windows.
+func (s *Storage) GetSummary(ctx context.Context, windows []time.Duration) (*modeldto.AdminSummary, error) {
+	if err := checkContext(ctx); err != nil {
+		s.log.Error().Err(err).Msg("getting summary failed")
+		return nil, err
+	}
+	now := time.Now()
+	summary := modeldto.AdminSummary{
+		GeneratedAt: now.Format(time.RFC3339),
+		Orders:      make(map[string]int),
+		Channels:    make(map[string]int),
+		Queue: modeldto.QueueSummary{
+			Orders:      s.queuedCount(),
+			Withdrawals: len(s.WithdrawalQueue),
+		},
+		Windows: []modeldto.WindowSummary{},
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	summary.Users = len(s.users)
+	for _, order := range s.orders {
+		summary.Orders[order.entry.Status]++
+		summary.Channels[order.channel]++
+	}
+	for _, window := range windows {
+		since := now.Add(-window)
+		windowSummary := modeldto.WindowSummary{Window: window.String()}
+		// orders carry no processing timestamp, accruals are attributed to the order creation time
+		for _, order := range s.orders {
+			if order.entry.Status == "PROCESSED" && !order.entry.CreatedAt.Before(since) {
+				windowSummary.Issued += order.entry.Accrual
+			}
+		}
+		for _, withdrawal := range s.withdrawals {
+			if withdrawal.Status == modelstorage.WithdrawalProcessed && !withdrawal.ProcessedAt.Before(since) {
+				windowSummary.Withdrawn += withdrawal.Amount
+			}
+		}
+		summary.Windows = append(summary.Windows, windowSummary)
+	}
+	s.log.Info().Msg("getting summary done")
+	return &summary, nil
+}
+
+// GetReport aggregates activity between from and to: registrations, orders uploaded by their current status,
+// abandoned orders among them, i.e. still not final, and points credited as accruals and withdrawn.
+func (s *Storage) GetReport(ctx context.Context, from, to time.Time) (*modeldto.AdminReport, error) {
+	if err := checkContext(ctx); err != nil {
+		s.log.Error().Err(err).Msg("getting report failed")
+		return nil, err
+	}
+	within := func(t time.Time) bool { return !t.Before(from) && t.Before(to) }
+	report := modeldto.AdminReport{
+		From:   from.Format(time.RFC3339),
+		To:     to.Format(time.RFC3339),
+		Orders: make(map[string]int),
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, user := range s.users {
+		if within(user.entry.RegisteredAt) {
+			report.Registrations++
+		}
+	}
+	for _, order := range s.orders {
+		if within(order.entry.CreatedAt) {
+			report.Orders[order.entry.Status]++
+		}
+	}
+	report.Abandoned = report.Orders["NEW"] + report.Orders["PROCESSING"]
+	for _, event := range s.events {
+		if event.entry.Kind == modelstorage.EventAccrualCredited && within(event.entry.CreatedAt) {
+			report.Accrued += event.entry.Amount
+		}
+	}
+	for _, withdrawal := range s.withdrawals {
+		if withdrawal.Status == modelstorage.WithdrawalProcessed && within(withdrawal.ProcessedAt) {
+			report.Withdrawn += withdrawal.Amount
+		}
+	}
+	s.log.Info().Msg("getting report done")
+	return &report, nil
+}
@@ -0,0 +1,94 @@
+// Package inmem provides an in-process storage keeping all data in memory.
+
+package inmem
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/models/modeldto"
+	storageErrors "github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/errors"
+	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/modelstorage"
+)
+
+// GetSuspendedOrders retrieves orders of all tenants held in the SUSPENDED status pending admin approval.
+func (s *Storage) GetSuspendedOrders(ctx context.Context) ([]modelstorage.OrderStorageEntry, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	var orders []modelstorage.OrderStorageEntry
+	for _, order := range s.orders {
+		if order.entry.Status == modelstorage.OrderSuspended {
+			orders = append(orders, modelstorage.OrderStorageEntry{
+				ID:          order.entry.ID,
+				UserID:      order.entry.UserID,
+				OrderNumber: order.entry.OrderNumber,
+				Status:      order.entry.Status,
+				Accrual:     order.entry.Accrual,
+				CreatedAt:   order.entry.CreatedAt,
+				TenantID:    order.entry.TenantID,
+			})
+		}
+	}
+	s.mu.RUnlock()
+	sort.SliceStable(orders, func(i, j int) bool {
+		return ordered(compareTimes(orders[i].CreatedAt, orders[j].CreatedAt), compareIDs(orders[i].ID, orders[j].ID), false)
+	})
+	s.log.Info().Msg("getting suspended orders done")
+	return orders, nil
+}
+
+// ReviewSuspendedOrder resolves a SUSPENDED order: an approved order becomes PROCESSED and its accrual is credited
+// to the owner, a rejected one becomes INVALID without any accrual. The decision is recorded to the order status history.
+func (s *Storage) ReviewSuspendedOrder(ctx context.Context, orderNumber int, approve bool) (*modelstorage.OrderStorageEntry, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+	status, source := "INVALID", modelstorage.SourceAdminRejection
+	if approve {
+		status, source = "PROCESSED", modelstorage.SourceAdminApproval
+	}
+	reference := strconv.Itoa(orderNumber)
+	s.mu.Lock()
+	record, ok := s.orders[orderNumber]
+	var err error
+	switch {
+	case !ok:
+		err = &storageErrors.NotFoundError{}
+	case record.entry.Status != modelstorage.OrderSuspended:
+		err = &storageErrors.OrderNotSuspendedError{ID: reference, Status: record.entry.Status}
+	}
+	if err != nil {
+		s.mu.Unlock()
+		s.log.Error().Err(err).Msg(fmt.Sprintf("reviewing suspended order failed for order %v", orderNumber))
+		return nil, err
+	}
+	if !approve {
+		record.entry.Accrual = 0
+	}
+	s.addHistory(orderNumber, record.entry.Status, status, source)
+	record.entry.Status = status
+	order := modelstorage.OrderStorageEntry{UserID: record.entry.UserID, OrderNumber: orderNumber, Status: status, Accrual: record.entry.Accrual, TenantID: record.entry.TenantID}
+	var alerts []modeldto.Notification
+	if order.Accrual > 0 {
+		s.adjustBalance(order.UserID, order.Accrual)
+		s.addBalanceEvent(order.UserID, modelstorage.EventAccrualCredited, order.Accrual, reference)
+		alerts = s.balanceAlerts(order.UserID, order.Accrual, reference)
+	}
+	s.addOutboxEvent(order.TenantID, modelstorage.OutboxOrderProcessed, map[string]interface{}{"user_id": order.UserID, "order": reference, "status": status, "accrual": order.Accrual})
+	s.mu.Unlock()
+	s.log.Info().Msg(fmt.Sprintf("reviewing suspended order done for order %v, it is %s", orderNumber, order.Status))
+	s.cache.InvalidateOrders(ctx, order.UserID)
+	s.cache.InvalidateBalance(ctx, order.UserID)
+	s.emit(modeldto.Notification{Kind: "order_processed", UserID: order.UserID, Message: fmt.Sprintf("order %v is %s", orderNumber, order.Status)})
+	if order.Accrual > 0 {
+		s.emit(modeldto.Notification{Kind: "balance_changed", UserID: order.UserID, Message: fmt.Sprintf("balance credited with %v for order %v", order.Accrual, orderNumber)})
+	}
+	for _, alert := range alerts {
+		s.emit(alert)
+	}
+	return &order, nil
+}
@@ -0,0 +1,64 @@
+// Package inmem provides an in-process storage keeping all data in memory.
+
+package inmem
+
+import (
+	"context"
+	"time"
+
+	storageErrors "github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/errors"
+	"github.com/danilovkiri/dk-go-gophermart/internal/tenant"
+)
+
+// SetTelegramLinkCode stores a one-time code linking a Telegram chat to a user, it replaces any pending code.
+func (s *Storage) SetTelegramLinkCode(ctx context.Context, userID string, code string, expiresAt time.Time) error {
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	user, ok := s.tenantUser(userID, tenant.FromContext(ctx))
+	if !ok {
+		err := &storageErrors.NotFoundError{}
+		s.log.Error().Err(err).Msg("setting telegram link code failed")
+		return err
+	}
+	user.linkCode, user.linkExpiresAt = code, expiresAt
+	s.log.Info().Msg("setting telegram link code done")
+	return nil
+}
+
+// LinkTelegramChat redeems a non-expired link code storing the chat of the code owner and returns the owner.
+func (s *Storage) LinkTelegramChat(ctx context.Context, code string, chatID int64) (string, error) {
+	if err := checkContext(ctx); err != nil {
+		return "", err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for _, user := range s.users {
+		if code != "" && user.linkCode == code && user.linkExpiresAt.After(now) {
+			user.chatID = &chatID
+			user.linkCode, user.linkExpiresAt = "", time.Time{}
+			s.log.Info().Msg("linking telegram chat done")
+			return user.entry.UserID, nil
+		}
+	}
+	err := &storageErrors.NotFoundError{}
+	s.log.Error().Err(err).Msg("linking telegram chat failed")
+	return "", err
+}
+
+// GetTelegramChatID retrieves the Telegram chat linked to a user, NotFoundError is returned if none is linked.
+func (s *Storage) GetTelegramChatID(ctx context.Context, userID string) (int64, error) {
+	if err := checkContext(ctx); err != nil {
+		return 0, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	user, ok := s.users[userID]
+	if !ok || user.chatID == nil {
+		return 0, &storageErrors.NotFoundError{}
+	}
+	return *user.chatID, nil
+}
@@ -0,0 +1,76 @@
+// Package inmem provides an in-process storage keeping all data in memory.
+
+package inmem
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/models/modeldto"
+	storageErrors "github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/errors"
+	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/modelstorage"
+)
+
+// TransferOrder moves an order to the target account of the same tenant, the accrual of a PROCESSED order is debited
+// from the current owner and credited to the target. Non-final orders are credited to their owner at the time of
+// processing, so they are moved as is.
+func (s *Storage) TransferOrder(ctx context.Context, orderNumber int, targetID, comment string) (*modeldto.OrderTransfer, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	transfer, err := s.transferOrder(orderNumber, targetID, comment)
+	s.mu.Unlock()
+	if err != nil {
+		s.log.Error().Err(err).Msg(fmt.Sprintf("transferring order %v to %s failed", orderNumber, targetID))
+		return nil, err
+	}
+	s.log.Info().Msg(fmt.Sprintf("transferring order %v to %s done", orderNumber, targetID))
+	for _, userID := range []string{transfer.FromID, targetID} {
+		s.cache.InvalidateOrders(ctx, userID)
+		s.cache.InvalidateBalance(ctx, userID)
+	}
+	return transfer, nil
+}
+
+// transferOrder validates and applies an order transfer, the caller must hold the write lock.
+func (s *Storage) transferOrder(orderNumber int, targetID, comment string) (*modeldto.OrderTransfer, error) {
+	order, ok := s.orders[orderNumber]
+	if !ok {
+		return nil, &storageErrors.NotFoundError{}
+	}
+	transfer := modeldto.OrderTransfer{OrderNumber: strconv.Itoa(orderNumber), Status: order.entry.Status, FromID: order.entry.UserID, ToID: targetID, Comment: comment}
+	if transfer.FromID == targetID {
+		return nil, &storageErrors.MergeConflictError{Msg: fmt.Sprintf("order %v already belongs to account %s", orderNumber, targetID)}
+	}
+	target, ok := s.users[targetID]
+	if !ok {
+		return nil, &storageErrors.NotFoundError{}
+	}
+	if target.entry.Deactivated {
+		return nil, &storageErrors.MergeConflictError{Msg: fmt.Sprintf("account %s is deactivated", targetID)}
+	}
+	tenantID := order.entry.TenantID
+	if target.entry.TenantID != tenantID {
+		return nil, &storageErrors.MergeConflictError{Msg: "order and account belong to different tenants"}
+	}
+	accrual := order.entry.Accrual
+	if transfer.Status == "PROCESSED" && accrual > 0 {
+		// the owner may have spent the accrual already, in which case the transfer fails with insufficient funds
+		err := s.checkBalance(transfer.FromID, tenantID, -accrual, 0)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := s.tenantBalance(targetID, tenantID); !ok {
+			return nil, &storageErrors.UnknownUserError{ID: targetID}
+		}
+		s.adjustBalance(transfer.FromID, -accrual)
+		s.adjustBalance(targetID, accrual)
+		s.addBalanceEvent(transfer.FromID, modelstorage.EventTransferOut, -accrual, transfer.OrderNumber)
+		s.addBalanceEvent(targetID, modelstorage.EventTransferIn, accrual, transfer.OrderNumber)
+		transfer.AmountMoved = accrual
+	}
+	order.entry.UserID = targetID
+	return &transfer, nil
+}
@@ -0,0 +1,176 @@
+// Package inmem provides an in-process storage keeping all data in memory.
+
+package inmem
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/models/modeldto"
+	"github.com/danilovkiri/dk-go-gophermart/internal/models/modelqueue"
+	storageErrors "github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/errors"
+	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/modelstorage"
+	"github.com/danilovkiri/dk-go-gophermart/internal/tenant"
+)
+
+// SendWithdrawalToQueue sends a pending withdrawal to the settlement queue, it gives up upon context cancellation.
+func (s *Storage) SendWithdrawalToQueue(ctx context.Context, item modelqueue.WithdrawalQueueEntry) {
+	select {
+	case s.WithdrawalQueue <- item:
+	case <-ctx.Done():
+	}
+}
+
+// AddPendingWithdrawal adds a new withdrawal in the PENDING status without debiting the balance.
+func (s *Storage) AddPendingWithdrawal(ctx context.Context, userID string, withdrawal modeldto.NewOrderWithdrawal) (uint, error) {
+	if err := checkContext(ctx); err != nil {
+		return 0, err
+	}
+	orderNumber, err := strconv.Atoi(withdrawal.OrderNumber)
+	if err != nil {
+		return 0, &storageErrors.ExecutionPSQLError{Err: err}
+	}
+	s.mu.Lock()
+	if _, ok := s.users[userID]; !ok {
+		s.mu.Unlock()
+		err := &storageErrors.UnknownUserError{ID: userID}
+		s.log.Error().Err(err).Msg("adding pending withdrawal failed")
+		return 0, err
+	}
+	withdrawalID := s.nextID()
+	s.withdrawals = append(s.withdrawals, &modelstorage.WithdrawalStorageEntry{
+		ID:          withdrawalID,
+		UserID:      userID,
+		OrderNumber: orderNumber,
+		Amount:      withdrawal.Amount,
+		ProcessedAt: time.Now(),
+		Status:      modelstorage.WithdrawalPending,
+		TenantID:    tenant.FromContext(ctx),
+	})
+	s.mu.Unlock()
+	s.log.Info().Msg(fmt.Sprintf("adding pending withdrawal done for order %s", withdrawal.OrderNumber))
+	return withdrawalID, nil
+}
+
+// checkWithdrawalOrder makes sure that the order paid by a withdrawal is either unknown or registered by the user,
+// the caller must hold the lock.
+func (s *Storage) checkWithdrawalOrder(userID, tenantID string, orderNumber int) error {
+	if _, ok := s.users[userID]; !ok {
+		return &storageErrors.UnknownUserError{ID: userID}
+	}
+	order, ok := s.orders[orderNumber]
+	if ok && (order.entry.UserID != userID || order.entry.TenantID != tenantID) {
+		return &storageErrors.AlreadyExistsAndViolatesError{ID: strconv.Itoa(orderNumber)}
+	}
+	return nil
+}
+
+// ensureWithdrawalOrder registers the order paid by a withdrawal unless the user has already registered it,
+// it must be checked with checkWithdrawalOrder beforehand. The caller must hold the write lock.
+func (s *Storage) ensureWithdrawalOrder(userID, tenantID string, orderNumber int, now time.Time) {
+	if _, ok := s.orders[orderNumber]; ok {
+		return
+	}
+	s.orders[orderNumber] = &orderRecord{
+		entry: modelstorage.OrderStorageEntry{
+			ID:          s.nextID(),
+			UserID:      userID,
+			OrderNumber: orderNumber,
+			Status:      "PROCESSED",
+			CreatedAt:   now,
+			TenantID:    tenantID,
+		},
+		channel: "unknown",
+	}
+}
+
+// ConfirmWithdrawal debits the balance for a pending withdrawal and marks it as processed, withdrawals which are
+// no longer pending are left intact.
+func (s *Storage) ConfirmWithdrawal(ctx context.Context, userID string, withdrawalID uint) error {
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+	tenantID := tenant.FromContext(ctx)
+	s.mu.Lock()
+	pending := s.pendingWithdrawal(userID, tenantID, withdrawalID)
+	if pending == nil {
+		s.mu.Unlock()
+		s.log.Warn().Msg(fmt.Sprintf("confirming withdrawal skipped for withdrawal %v, it is not pending", withdrawalID))
+		return nil
+	}
+	reference := strconv.Itoa(pending.OrderNumber)
+	err := s.checkWithdrawalOrder(userID, tenantID, pending.OrderNumber)
+	if err == nil {
+		err = s.checkBalance(userID, tenantID, -pending.Amount, 0)
+	}
+	if err != nil {
+		s.mu.Unlock()
+		s.log.Error().Err(err).Msg(fmt.Sprintf("confirming withdrawal failed for withdrawal %v", withdrawalID))
+		return err
+	}
+	now := time.Now()
+	s.ensureWithdrawalOrder(userID, tenantID, pending.OrderNumber, now)
+	s.adjustBalance(userID, -pending.Amount)
+	pending.Status, pending.ProcessedAt = modelstorage.WithdrawalProcessed, now
+	s.addBalanceEvent(userID, modelstorage.EventWithdrawal, -pending.Amount, reference)
+	alerts := s.balanceAlerts(userID, -pending.Amount, reference)
+	s.addOutboxEvent(tenantID, modelstorage.OutboxWithdrawalMade, map[string]interface{}{"user_id": userID, "order": reference, "sum": pending.Amount})
+	amount := pending.Amount
+	s.mu.Unlock()
+	s.log.Info().Msg(fmt.Sprintf("confirming withdrawal done for withdrawal %v", withdrawalID))
+	s.cache.InvalidateBalance(ctx, userID)
+	s.cache.InvalidateOrders(ctx, userID)
+	s.emit(modeldto.Notification{Kind: "balance_changed", UserID: userID, Message: fmt.Sprintf("balance debited with %v for order %v", amount, reference)})
+	for _, alert := range alerts {
+		s.emit(alert)
+	}
+	return nil
+}
+
+// FailWithdrawal marks a pending withdrawal as failed, the balance is left intact.
+func (s *Storage) FailWithdrawal(ctx context.Context, userID string, withdrawalID uint) error {
+	if err := checkContext(ctx); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	if pending := s.pendingWithdrawal(userID, tenant.FromContext(ctx), withdrawalID); pending != nil {
+		pending.Status = modelstorage.WithdrawalFailed
+	}
+	s.mu.Unlock()
+	s.log.Warn().Msg(fmt.Sprintf("withdrawal %v was marked as failed", withdrawalID))
+	return nil
+}
+
+// pendingWithdrawal retrieves a pending withdrawal of a user, it returns nil if there is none. The caller must
+// hold the lock.
+func (s *Storage) pendingWithdrawal(userID, tenantID string, withdrawalID uint) *modelstorage.WithdrawalStorageEntry {
+	for _, withdrawal := range s.withdrawals {
+		if withdrawal.ID == withdrawalID && withdrawal.UserID == userID && withdrawal.TenantID == tenantID && withdrawal.Status == modelstorage.WithdrawalPending {
+			return withdrawal
+		}
+	}
+	return nil
+}
+
+// GetWithdrawal retrieves the latest withdrawal of a user for an order.
+func (s *Storage) GetWithdrawal(ctx context.Context, userID, orderNumber string) (*modelstorage.WithdrawalStorageEntry, error) {
+	if err := checkContext(ctx); err != nil {
+		return nil, err
+	}
+	tenantID := tenant.FromContext(ctx)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var latest *modelstorage.WithdrawalStorageEntry
+	for _, withdrawal := range s.withdrawals {
+		if withdrawal.UserID == userID && strconv.Itoa(withdrawal.OrderNumber) == orderNumber && withdrawal.TenantID == tenantID {
+			latest = withdrawal
+		}
+	}
+	if latest == nil {
+		return nil, &storageErrors.NotFoundError{}
+	}
+	entry := *latest
+	return &entry, nil
+}
@@ -0,0 +1,110 @@
+// Package inpsql provides functionality for operating a relational DB.
+
+package inpsql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/models/modeldto"
+	storageErrors "github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/errors"
+	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/modelstorage"
+	"github.com/danilovkiri/dk-go-gophermart/internal/tenant"
+)
+
+// GetAlertThresholds retrieves the alert thresholds of a user, unset thresholds are nil.
+func (s *Storage) GetAlertThresholds(ctx context.Context, userID string) (*modelstorage.AlertThresholdsStorageEntry, error) {
+	selectStmt, err := s.DB.PrepareContext(ctx, "SELECT low_balance_threshold, large_accrual_threshold FROM users WHERE user_id = $1 AND tenant_id = $2")
+	if err != nil {
+		return nil, &storageErrors.StatementPSQLError{Err: err}
+	}
+	defer selectStmt.Close()
+	chanOk := make(chan *modelstorage.AlertThresholdsStorageEntry)
+	chanEr := make(chan error)
+	go func() {
+		var thresholds modelstorage.AlertThresholdsStorageEntry
+		err := selectStmt.QueryRowContext(ctx, userID, tenant.FromContext(ctx)).Scan(&thresholds.LowBalance, &thresholds.LargeAccrual)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				chanEr <- &storageErrors.NotFoundError{Err: err}
+				return
+			}
+			chanEr <- &storageErrors.ScanningPSQLError{Err: err}
+			return
+		}
+		chanOk <- &thresholds
+	}()
+	select {
+	case <-ctx.Done():
+		s.log.Error().Err(ctx.Err()).Msg("getting alert thresholds failed")
+		return nil, &storageErrors.ContextTimeoutExceededError{Err: ctx.Err()}
+	case methodErr := <-chanEr:
+		s.log.Error().Err(methodErr).Msg("getting alert thresholds failed")
+		return nil, methodErr
+	case thresholds := <-chanOk:
+		s.log.Info().Msg("getting alert thresholds done")
+		return thresholds, nil
+	}
+}
+
+// SetAlertThresholds replaces the alert thresholds of a user, nil thresholds disable the corresponding alerts.
+func (s *Storage) SetAlertThresholds(ctx context.Context, userID string, thresholds modelstorage.AlertThresholdsStorageEntry) error {
+	updStmt, err := s.DB.PrepareContext(ctx, "UPDATE users SET low_balance_threshold = $1, large_accrual_threshold = $2 WHERE user_id = $3 AND tenant_id = $4")
+	if err != nil {
+		return &storageErrors.StatementPSQLError{Err: err}
+	}
+	defer updStmt.Close()
+	chanOk := make(chan bool)
+	chanEr := make(chan error)
+	go func() {
+		res, err := updStmt.ExecContext(ctx, thresholds.LowBalance, thresholds.LargeAccrual, userID, tenant.FromContext(ctx))
+		if err != nil {
+			chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
+			return
+		}
+		affected, err := res.RowsAffected()
+		if err != nil {
+			chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
+			return
+		}
+		if affected == 0 {
+			chanEr <- &storageErrors.NotFoundError{Err: sql.ErrNoRows}
+			return
+		}
+		chanOk <- true
+	}()
+	select {
+	case <-ctx.Done():
+		s.log.Error().Err(ctx.Err()).Msg("setting alert thresholds failed")
+		return &storageErrors.ContextTimeoutExceededError{Err: ctx.Err()}
+	case methodErr := <-chanEr:
+		s.log.Error().Err(methodErr).Msg("setting alert thresholds failed")
+		return methodErr
+	case <-chanOk:
+		s.log.Info().Msg("setting alert thresholds done")
+		return nil
+	}
+}
+
+// balanceAlerts evaluates the alert thresholds of a user against a balance change of delta within the transaction
+// applying it and returns the notifications to emit once the transaction is committed. A low balance alert fires
+// only when the balance drops below the threshold, not for every debit while it stays below.
+func balanceAlerts(ctx context.Context, tx *sql.Tx, userID string, delta float64, reference string) ([]modeldto.Notification, error) {
+	var amount float64
+	var thresholds modelstorage.AlertThresholdsStorageEntry
+	err := tx.QueryRowContext(ctx, `SELECT b.amount, u.low_balance_threshold, u.large_accrual_threshold
+		FROM balance b JOIN users u ON u.user_id = b.user_id WHERE b.user_id = $1`, userID).Scan(&amount, &thresholds.LowBalance, &thresholds.LargeAccrual)
+	if err != nil {
+		return nil, &storageErrors.ScanningPSQLError{Err: err}
+	}
+	var notifications []modeldto.Notification
+	if thresholds.LargeAccrual != nil && delta >= *thresholds.LargeAccrual {
+		notifications = append(notifications, modeldto.Notification{Kind: modelstorage.NotificationLargeAccrual, UserID: userID, Message: fmt.Sprintf("accrual of %v for order %s exceeds your threshold of %v", delta, reference, *thresholds.LargeAccrual)})
+	}
+	if thresholds.LowBalance != nil && delta < 0 && amount < *thresholds.LowBalance && amount-delta >= *thresholds.LowBalance {
+		notifications = append(notifications, modeldto.Notification{Kind: modelstorage.NotificationLowBalance, UserID: userID, Message: fmt.Sprintf("balance dropped to %v below your threshold of %v after order %s", amount, *thresholds.LowBalance, reference)})
+	}
+	return notifications, nil
+}
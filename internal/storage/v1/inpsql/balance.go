@@ -0,0 +1,69 @@
+// Package inpsql provides functionality for operating a relational DB.
+
+package inpsql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	storageErrors "github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/errors"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgerrcode"
+)
+
+// adjustBalance applies delta to a user's balance with optimistic concurrency control: the balance is read
+// without locking and written only if its version is unchanged since the read. A conflicting write makes it
+// re-read and retry within the same transaction, as each statement of a READ COMMITTED transaction sees
+// the latest committed version, so the row lock is held only from the write until the transaction ends.
+// Debits exceeding the available amount, i.e. the balance less active holds, fail with InsufficientFundsError.
+func (s *Storage) adjustBalance(ctx context.Context, tx *sql.Tx, userID, tenantID string, delta float64) error {
+	return s.updateBalance(ctx, tx, userID, tenantID, delta, 0)
+}
+
+// holdBalance checks that amount is available for a new hold and bumps the balance version so that debits
+// racing with the hold re-read the available amount.
+func (s *Storage) holdBalance(ctx context.Context, tx *sql.Tx, userID, tenantID string, amount float64) error {
+	return s.updateBalance(ctx, tx, userID, tenantID, 0, amount)
+}
+
+// updateBalance applies delta to a user's balance making sure that the available amount covers the debit
+// along with a new hold of the given amount.
+func (s *Storage) updateBalance(ctx context.Context, tx *sql.Tx, userID, tenantID string, delta, hold float64) error {
+	for attempt := 1; ; attempt++ {
+		var amount, held float64
+		var version int64
+		err := tx.QueryRowContext(ctx, "SELECT b.amount, b.version, "+heldAmountQuery+" FROM balance b WHERE b.user_id = $1 AND b.tenant_id = $2", userID, tenantID).Scan(&amount, &version, &held)
+		if err != nil {
+			return &storageErrors.ScanningPSQLError{Err: err}
+		}
+		if (delta < 0 || hold > 0) && amount-held+delta-hold < 0 {
+			return &storageErrors.InsufficientFundsError{Available: amount - held, Required: hold - delta}
+		}
+		result, err := tx.ExecContext(ctx, "UPDATE balance SET amount = (amount + $1), version = version + 1, updated_at = $5 WHERE user_id = $2 AND tenant_id = $3 AND version = $4", delta, userID, tenantID, version, time.Now())
+		if err != nil {
+			if err, ok := err.(*pgconn.PgError); ok && err.Code == pgerrcode.CheckViolation {
+				return &storageErrors.NegativeBalanceError{Err: err, ID: userID}
+			}
+			return &storageErrors.ExecutionPSQLError{Err: err}
+		}
+		updated, err := result.RowsAffected()
+		if err != nil {
+			return &storageErrors.ExecutionPSQLError{Err: err}
+		}
+		if updated == 1 {
+			return nil
+		}
+		s.metrics.Counter("gophermart_balance_conflicts_total").Inc()
+		if attempt > s.cfg.BalanceRetryNumber {
+			return &storageErrors.ConcurrentUpdateError{ID: userID, Attempts: attempt}
+		}
+		s.log.Warn().Msg(fmt.Sprintf("balance of user %s was modified concurrently, retrying", userID))
+		select {
+		case <-ctx.Done():
+			return &storageErrors.ContextTimeoutExceededError{Err: ctx.Err()}
+		case <-time.After(time.Duration(attempt) * s.cfg.BalanceRetryBackoff):
+		}
+	}
+}
@@ -0,0 +1,98 @@
+// Package inpsql provides functionality for operating a relational DB.
+
+package inpsql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/models/modelqueue"
+	storageErrors "github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/errors"
+	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/modelstorage"
+)
+
+// ResolveOrder feeds a final order status pushed by the accrual service to the processed orders queue.
+// Orders already in a final status are ignored, queued orders are marked resolved to suppress further polling.
+func (s *Storage) ResolveOrder(ctx context.Context, orderNumber int, status string, accrual float64) error {
+	order, err := s.getOrderOwner(ctx, orderNumber)
+	if err != nil {
+		return err
+	}
+	if order.Status == "PROCESSED" || order.Status == "INVALID" || order.Status == modelstorage.OrderSuspended {
+		s.log.Info().Msg(fmt.Sprintf("order %v is already final, ignoring callback", orderNumber))
+		return nil
+	}
+	if s.isQueued(orderNumber) {
+		s.markResolved(orderNumber)
+	}
+	select {
+	case <-ctx.Done():
+		return &storageErrors.ContextTimeoutExceededError{Err: ctx.Err()}
+	case s.QueueOut <- modelqueue.OrderQueueEntry{
+		TenantID:    order.TenantID,
+		UserID:      order.UserID,
+		OrderNumber: orderNumber,
+		OrderStatus: status,
+		Accrual:     accrual,
+		Dequeued:    true,
+		Source:      modelqueue.SourceCallback,
+	}:
+	}
+	s.log.Info().Msg(fmt.Sprintf("order %v resolved via callback", orderNumber))
+	return nil
+}
+
+// getOrderOwner retrieves an order by its number regardless of its tenant.
+func (s *Storage) getOrderOwner(ctx context.Context, orderNumber int) (*modelstorage.OrderStorageEntry, error) {
+	selectStmt, err := s.DB.PrepareContext(ctx, "SELECT tenant_id, user_id, status FROM orders WHERE order_number = $1")
+	if err != nil {
+		return nil, &storageErrors.StatementPSQLError{Err: err}
+	}
+	defer selectStmt.Close()
+	chanOk := make(chan modelstorage.OrderStorageEntry)
+	chanEr := make(chan error)
+	go func() {
+		var queryOutput modelstorage.OrderStorageEntry
+		err := selectStmt.QueryRowContext(ctx, orderNumber).Scan(&queryOutput.TenantID, &queryOutput.UserID, &queryOutput.Status)
+		if errors.Is(err, sql.ErrNoRows) {
+			chanEr <- &storageErrors.NotFoundError{Err: err}
+			return
+		}
+		if err != nil {
+			chanEr <- &storageErrors.ScanningPSQLError{Err: err}
+			return
+		}
+		chanOk <- queryOutput
+	}()
+	select {
+	case <-ctx.Done():
+		s.log.Error().Err(ctx.Err()).Msg(fmt.Sprintf("getting order owner failed for order %v", orderNumber))
+		return nil, &storageErrors.ContextTimeoutExceededError{Err: ctx.Err()}
+	case methodErr := <-chanEr:
+		s.log.Error().Err(methodErr).Msg(fmt.Sprintf("getting order owner failed for order %v", orderNumber))
+		return nil, methodErr
+	case order := <-chanOk:
+		s.log.Info().Msg(fmt.Sprintf("getting order owner done for order %v", orderNumber))
+		return &order, nil
+	}
+}
+
+// markResolved marks a queued order as resolved via callback.
+func (s *Storage) markResolved(orderNumber int) {
+	s.queuedMu.Lock()
+	defer s.queuedMu.Unlock()
+	s.resolved[orderNumber] = struct{}{}
+}
+
+// TakeResolved reports whether an order was resolved via callback and clears the mark, polling workers drop such orders.
+func (s *Storage) TakeResolved(orderNumber int) bool {
+	s.queuedMu.Lock()
+	defer s.queuedMu.Unlock()
+	if _, ok := s.resolved[orderNumber]; !ok {
+		return false
+	}
+	delete(s.resolved, orderNumber)
+	return true
+}
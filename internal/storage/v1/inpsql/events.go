@@ -0,0 +1,89 @@
+// Package inpsql provides functionality for operating a relational DB.
+
+package inpsql
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/models/modeldto"
+	storageErrors "github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/errors"
+	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/modelstorage"
+)
+
+// replayQuery folds the balance event log into per user amounts next to the stored balances.
+const replayQuery = `SELECT b.user_id, b.amount, COALESCE(SUM(e.amount), 0) AS expected
+FROM balance b LEFT JOIN balance_events e ON e.user_id = b.user_id
+GROUP BY b.user_id, b.amount`
+
+// addBalanceEvent appends a balance-affecting event to the event log within the transaction modifying the balance,
+// the event belongs to the tenant of the user.
+func addBalanceEvent(ctx context.Context, tx *sql.Tx, userID, kind string, amount float64, reference string) error {
+	_, err := tx.ExecContext(ctx, `INSERT INTO balance_events (user_id, tenant_id, kind, amount, reference, created_at)
+		SELECT user_id, tenant_id, $2, $3, $4, $5 FROM users WHERE user_id = $1`, userID, kind, amount, reference, time.Now())
+	if err != nil {
+		return &storageErrors.ExecutionPSQLError{Err: err}
+	}
+	return nil
+}
+
+// ReplayBalanceEvents rebuilds every balance from the event log and reports the ones which differ from the stored amounts,
+// the balance and snapshot tables are rewritten only if apply is true.
+// The event log is locked against new events for the duration of the transaction so that the fold stays consistent.
+func ReplayBalanceEvents(ctx context.Context, db *sql.DB, apply bool) (*modeldto.ReconciliationReport, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, &storageErrors.ExecutionPSQLError{Err: err}
+	}
+	defer tx.Rollback()
+	_, err = tx.ExecContext(ctx, "LOCK TABLE balance_events IN SHARE MODE")
+	if err != nil {
+		return nil, &storageErrors.ExecutionPSQLError{Err: err}
+	}
+	rows, err := tx.QueryContext(ctx, replayQuery)
+	if err != nil {
+		return nil, &storageErrors.ExecutionPSQLError{Err: err}
+	}
+	var entries []modelstorage.BalanceDiscrepancyStorageEntry
+	for rows.Next() {
+		var entry modelstorage.BalanceDiscrepancyStorageEntry
+		err = rows.Scan(&entry.UserID, &entry.StoredAmount, &entry.ExpectedAmount)
+		if err != nil {
+			rows.Close()
+			return nil, &storageErrors.ScanningPSQLError{Err: err}
+		}
+		entries = append(entries, entry)
+	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return nil, &storageErrors.ScanningPSQLError{Err: err}
+	}
+	report := newReconciliationReport(entries)
+	if !apply {
+		return report, nil
+	}
+	now := time.Now()
+	for _, discrepancy := range report.Discrepancies {
+		_, err = tx.ExecContext(ctx, "UPDATE balance SET amount = $1, version = version + 1, updated_at = $3 WHERE user_id = $2", discrepancy.ExpectedAmount, discrepancy.UserID, now)
+		if err != nil {
+			return nil, &storageErrors.ExecutionPSQLError{Err: err}
+		}
+	}
+	_, err = tx.ExecContext(ctx, "DELETE FROM balance_snapshots")
+	if err != nil {
+		return nil, &storageErrors.ExecutionPSQLError{Err: err}
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO balance_snapshots (user_id, tenant_id, amount, last_event_id, taken_at)
+		SELECT user_id, tenant_id, SUM(amount), MAX(id), $1 FROM balance_events GROUP BY user_id, tenant_id`, now)
+	if err != nil {
+		return nil, &storageErrors.ExecutionPSQLError{Err: err}
+	}
+	err = tx.Commit()
+	if err != nil {
+		return nil, &storageErrors.ExecutionPSQLError{Err: err}
+	}
+	report.Applied = true
+	return report, nil
+}
@@ -0,0 +1,234 @@
+// Package inpsql provides functionality for operating a relational DB.
+
+package inpsql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/errcodes"
+	"github.com/danilovkiri/dk-go-gophermart/internal/models/modeldto"
+	storageErrors "github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/errors"
+	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/modelstorage"
+	"github.com/danilovkiri/dk-go-gophermart/internal/tenant"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgerrcode"
+)
+
+// heldAmountQuery sums active holds of the user of the balance row aliased as b.
+const heldAmountQuery = `(SELECT COALESCE(SUM(h.amount), 0) FROM balance_holds h WHERE h.user_id = b.user_id AND h.status = 'ACTIVE' AND h.expires_at > now())`
+
+// AddHold places a hold on part of a user's balance for a pending purchase of an order, the held amount stays
+// on the balance but can not be spent until the hold is released or expires.
+func (s *Storage) AddHold(ctx context.Context, userID string, orderNumber int, amount float64) (*modelstorage.BalanceHoldStorageEntry, error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, &storageErrors.ExecutionPSQLError{Err: err}
+	}
+	defer tx.Rollback()
+	tenantID := tenant.FromContext(ctx)
+	chanOk := make(chan modelstorage.BalanceHoldStorageEntry)
+	chanEr := make(chan error)
+	go func() {
+		// a lapsed hold of the order must not block a new one until it is marked expired
+		_, err := tx.ExecContext(ctx, "UPDATE balance_holds SET status = $1, resolved_at = expires_at WHERE order_number = $2 AND status = $3 AND expires_at <= now()", modelstorage.HoldExpired, orderNumber, modelstorage.HoldActive)
+		if err != nil {
+			chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
+			return
+		}
+		err = s.holdBalance(ctx, tx, userID, tenantID, amount)
+		if err != nil {
+			chanEr <- err
+			return
+		}
+		now := time.Now()
+		hold := modelstorage.BalanceHoldStorageEntry{UserID: userID, OrderNumber: orderNumber, Amount: amount, Status: modelstorage.HoldActive, CreatedAt: now, ExpiresAt: now.Add(s.cfg.HoldTTL)}
+		err = tx.QueryRowContext(ctx, "INSERT INTO balance_holds (user_id, tenant_id, order_number, amount, status, created_at, expires_at) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id",
+			userID, tenantID, orderNumber, amount, modelstorage.HoldActive, hold.CreatedAt, hold.ExpiresAt).Scan(&hold.ID)
+		if err != nil {
+			if err, ok := err.(*pgconn.PgError); ok && err.Code == pgerrcode.UniqueViolation {
+				chanEr <- &storageErrors.AlreadyExistsError{Err: err, ID: strconv.Itoa(orderNumber), Code: errcodes.AlreadyExists}
+				return
+			}
+			if err, ok := err.(*pgconn.PgError); ok && err.Code == pgerrcode.ForeignKeyViolation {
+				chanEr <- &storageErrors.UnknownUserError{Err: err, ID: userID}
+				return
+			}
+			chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
+			return
+		}
+		chanOk <- hold
+	}()
+	select {
+	case <-ctx.Done():
+		s.log.Error().Err(ctx.Err()).Msg("adding balance hold failed")
+		return nil, &storageErrors.ContextTimeoutExceededError{Err: ctx.Err()}
+	case methodErr := <-chanEr:
+		s.log.Error().Err(methodErr).Msg("adding balance hold failed")
+		return nil, methodErr
+	case hold := <-chanOk:
+		s.log.Info().Msg(fmt.Sprintf("adding balance hold done for order %v", orderNumber))
+		defer s.cache.InvalidateBalance(ctx, userID)
+		err = tx.Commit()
+		if err != nil {
+			return nil, err
+		}
+		return &hold, nil
+	}
+}
+
+// GetHolds retrieves a user's balance holds, the most recent first.
+func (s *Storage) GetHolds(ctx context.Context, userID string) ([]modelstorage.BalanceHoldStorageEntry, error) {
+	selectStmt, err := s.DB.PrepareContext(ctx, "SELECT id, user_id, order_number, amount, status, created_at, expires_at FROM balance_holds WHERE user_id = $1 AND tenant_id = $2 ORDER BY created_at DESC, id DESC")
+	if err != nil {
+		return nil, &storageErrors.StatementPSQLError{Err: err}
+	}
+	defer selectStmt.Close()
+	chanOk := make(chan []modelstorage.BalanceHoldStorageEntry)
+	chanEr := make(chan error)
+	go func() {
+		rows, err := selectStmt.QueryContext(ctx, userID, tenant.FromContext(ctx))
+		if err != nil {
+			chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
+			return
+		}
+		defer rows.Close()
+		var queryOutput []modelstorage.BalanceHoldStorageEntry
+		for rows.Next() {
+			var queryOutputRow modelstorage.BalanceHoldStorageEntry
+			err = rows.Scan(&queryOutputRow.ID, &queryOutputRow.UserID, &queryOutputRow.OrderNumber, &queryOutputRow.Amount, &queryOutputRow.Status, &queryOutputRow.CreatedAt, &queryOutputRow.ExpiresAt)
+			if err != nil {
+				chanEr <- &storageErrors.ScanningPSQLError{Err: err}
+				return
+			}
+			queryOutput = append(queryOutput, queryOutputRow)
+		}
+		err = rows.Err()
+		if err != nil {
+			chanEr <- &storageErrors.ScanningPSQLError{Err: err}
+			return
+		}
+		chanOk <- queryOutput
+	}()
+	select {
+	case <-ctx.Done():
+		s.log.Error().Err(ctx.Err()).Msg("getting balance holds failed")
+		return nil, &storageErrors.ContextTimeoutExceededError{Err: ctx.Err()}
+	case methodErr := <-chanEr:
+		s.log.Error().Err(methodErr).Msg("getting balance holds failed")
+		return nil, methodErr
+	case holds := <-chanOk:
+		s.log.Info().Msg("getting balance holds done")
+		return holds, nil
+	}
+}
+
+// CaptureHold turns an active hold into a processed withdrawal of the held amount for its order within a single
+// transaction.
+func (s *Storage) CaptureHold(ctx context.Context, userID string, holdID uint) (*modelstorage.BalanceHoldStorageEntry, error) {
+	return s.resolveHold(ctx, userID, holdID, true)
+}
+
+// ReleaseHold drops an active hold making the held amount available again.
+func (s *Storage) ReleaseHold(ctx context.Context, userID string, holdID uint) (*modelstorage.BalanceHoldStorageEntry, error) {
+	return s.resolveHold(ctx, userID, holdID, false)
+}
+
+// resolveHold captures or releases an active hold, holds which are no longer active are rejected with
+// HoldNotActiveError.
+func (s *Storage) resolveHold(ctx context.Context, userID string, holdID uint, capture bool) (*modelstorage.BalanceHoldStorageEntry, error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, &storageErrors.ExecutionPSQLError{Err: err}
+	}
+	defer tx.Rollback()
+	tenantID := tenant.FromContext(ctx)
+	status := modelstorage.HoldReleased
+	if capture {
+		status = modelstorage.HoldCaptured
+	}
+	chanOk := make(chan modelstorage.BalanceHoldStorageEntry)
+	chanEr := make(chan error)
+	var alerts []modeldto.Notification
+	go func() {
+		var hold modelstorage.BalanceHoldStorageEntry
+		err := tx.QueryRowContext(ctx, "SELECT id, user_id, order_number, amount, status, created_at, expires_at FROM balance_holds WHERE id = $1 AND user_id = $2 AND tenant_id = $3 FOR UPDATE",
+			holdID, userID, tenantID).Scan(&hold.ID, &hold.UserID, &hold.OrderNumber, &hold.Amount, &hold.Status, &hold.CreatedAt, &hold.ExpiresAt)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				chanEr <- &storageErrors.NotFoundError{Err: err}
+				return
+			}
+			chanEr <- &storageErrors.ScanningPSQLError{Err: err}
+			return
+		}
+		if hold.Status == modelstorage.HoldActive && !hold.ExpiresAt.After(time.Now()) {
+			hold.Status = modelstorage.HoldExpired
+		}
+		if hold.Status != modelstorage.HoldActive {
+			chanEr <- &storageErrors.HoldNotActiveError{ID: strconv.Itoa(int(holdID)), Status: hold.Status}
+			return
+		}
+		// the hold is resolved first so that it no longer counts against the balance debited by the capture
+		_, err = tx.ExecContext(ctx, "UPDATE balance_holds SET status = $1, resolved_at = $2 WHERE id = $3", status, time.Now(), holdID)
+		if err != nil {
+			chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
+			return
+		}
+		hold.Status = status
+		if capture {
+			alerts, err = s.withdraw(ctx, tx, userID, tenantID, modeldto.NewOrderWithdrawal{OrderNumber: strconv.Itoa(hold.OrderNumber), Amount: hold.Amount})
+			if err != nil {
+				chanEr <- err
+				return
+			}
+		}
+		chanOk <- hold
+	}()
+	select {
+	case <-ctx.Done():
+		s.log.Error().Err(ctx.Err()).Msg("resolving balance hold failed")
+		return nil, &storageErrors.ContextTimeoutExceededError{Err: ctx.Err()}
+	case methodErr := <-chanEr:
+		s.log.Error().Err(methodErr).Msg("resolving balance hold failed")
+		return nil, methodErr
+	case hold := <-chanOk:
+		s.log.Info().Msg(fmt.Sprintf("resolving balance hold done, hold %v is %s", holdID, status))
+		if capture {
+			defer s.cache.InvalidateOrders(ctx, userID)
+		}
+		defer s.cache.InvalidateBalance(ctx, userID)
+		err = tx.Commit()
+		if err != nil {
+			return nil, err
+		}
+		if capture {
+			s.emit(modeldto.Notification{Kind: "balance_changed", UserID: userID, Message: fmt.Sprintf("balance debited with %v for order %v", hold.Amount, hold.OrderNumber)})
+			for _, alert := range alerts {
+				s.emit(alert)
+			}
+		}
+		return &hold, nil
+	}
+}
+
+// ExpireHolds marks active holds past their expiration as expired.
+func (s *Storage) ExpireHolds(ctx context.Context) error {
+	result, err := s.DB.ExecContext(ctx, "UPDATE balance_holds SET status = $1, resolved_at = expires_at WHERE status = $2 AND expires_at <= now()", modelstorage.HoldExpired, modelstorage.HoldActive)
+	if err != nil {
+		s.log.Error().Err(err).Msg("expiring balance holds failed")
+		return &storageErrors.ExecutionPSQLError{Err: err}
+	}
+	expired, err := result.RowsAffected()
+	if err != nil {
+		return &storageErrors.ExecutionPSQLError{Err: err}
+	}
+	if expired > 0 {
+		s.log.Info().Msg(fmt.Sprintf("%v balance holds expired", expired))
+	}
+	return nil
+}
@@ -4,39 +4,76 @@ package inpsql
 
 import (
 	"context"
-	"crypto/sha256"
-	"crypto/subtle"
 	"database/sql"
+	"database/sql/driver"
 	"errors"
 	"fmt"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/XSAM/otelsql"
+	"github.com/danilovkiri/dk-go-gophermart/internal/cache/v1"
+	"github.com/danilovkiri/dk-go-gophermart/internal/cashback"
 	"github.com/danilovkiri/dk-go-gophermart/internal/config"
+	"github.com/danilovkiri/dk-go-gophermart/internal/errcodes"
+	"github.com/danilovkiri/dk-go-gophermart/internal/metrics"
 	"github.com/danilovkiri/dk-go-gophermart/internal/models/modeldto"
 	"github.com/danilovkiri/dk-go-gophermart/internal/models/modelqueue"
 	storageErrors "github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/errors"
 	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/modelstorage"
+	"github.com/danilovkiri/dk-go-gophermart/internal/tenant"
 	"github.com/jackc/pgconn"
 	"github.com/jackc/pgerrcode"
 	_ "github.com/jackc/pgx/v4/stdlib"
 	"github.com/rs/zerolog"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Storage defines attributes of a struct available to its methods.
 type Storage struct {
 	mu       sync.Mutex
+	healthy  int32
+	queuedMu sync.Mutex
+	queued   map[int]struct{}
+	// resolved holds queued orders whose final status was pushed via callback
+	resolved map[int]struct{}
 	cfg      *config.StorageConfig
 	DB       *sql.DB
 	log      *zerolog.Logger
-	QueueIn  chan modelqueue.OrderQueueEntry
-	QueueOut chan modelqueue.OrderQueueEntry
+	metrics  *metrics.Registry
+	cache    cache.Cache
+	cashback *cashback.Engine
+	// reconcileReport holds the latest balance reconciliation result
+	reconcileMu     sync.RWMutex
+	reconcileReport *modeldto.ReconciliationReport
+	QueueIn         chan modelqueue.OrderQueueEntry
+	QueueOut        chan modelqueue.OrderQueueEntry
+	// WithdrawalQueue is buffered and never closed, its consumers stop upon context cancellation
+	WithdrawalQueue chan modelqueue.WithdrawalQueueEntry
+	// Events carries user notifications about processed orders and balance changes, it is buffered and never closed
+	Events chan modeldto.Notification
 }
 
 // InitStorage initializes a storage handling service.
-func InitStorage(ctx context.Context, cfg *config.StorageConfig, log *zerolog.Logger, wg *sync.WaitGroup) (*Storage, error) {
-	db, err := sql.Open("pgx", cfg.DatabaseDSN)
+func InitStorage(ctx context.Context, cfg *config.StorageConfig, log *zerolog.Logger, wg *sync.WaitGroup, reg *metrics.Registry, storageCache cache.Cache, cashbackEngine *cashback.Engine) (*Storage, error) {
+	// statements are recorded as spans of the traced requests running them
+	db, err := otelsql.Open("pgx", cfg.DatabaseDSN,
+		otelsql.WithAttributes(semconv.DBSystemPostgreSQL),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{
+			DisableErrSkip:       true,
+			OmitConnResetSession: true,
+			OmitConnPrepare:      true,
+			OmitRows:             true,
+			OmitConnectorConnect: true,
+			// background jobs outside of a traced request are not recorded
+			SpanFilter: func(ctx context.Context, _ otelsql.Method, _ string, _ []driver.NamedValue) bool {
+				return trace.SpanContextFromContext(ctx).IsValid()
+			},
+		}),
+	)
 	if err != nil {
 		log.Fatal().Err(err).Msg("could not prepare a DB connection")
 	}
@@ -47,14 +84,34 @@ func InitStorage(ctx context.Context, cfg *config.StorageConfig, log *zerolog.Lo
 		cfg:      cfg,
 		DB:       db,
 		log:      log,
+		metrics:  reg,
+		cache:    storageCache,
+		cashback: cashbackEngine,
+		queued:   make(map[int]struct{}),
+		resolved: make(map[int]struct{}),
 		QueueIn:  queueIn,
 		QueueOut: queueOut,
+
+		WithdrawalQueue: make(chan modelqueue.WithdrawalQueueEntry, cfg.WithdrawalQueueSize),
+		Events:          make(chan modeldto.Notification, cfg.EventQueueSize),
+	}
+	err = st.waitForDB(ctx)
+	if err != nil {
+		return nil, err
 	}
-	err = st.createTables(ctx)
+	err = st.migrateSchema(ctx)
 	if err != nil {
-		log.Fatal().Err(err).Msg("could not create DB tables")
+		log.Fatal().Err(err).Msg("could not migrate DB schema")
 	}
 	log.Info().Msg("PSQL DB connection was established")
+	atomic.StoreInt32(&st.healthy, 1)
+
+	// monitor DB connection and reconnect upon failures
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		st.monitorConnection(ctx)
+	}()
 
 	// send unprocessed orders from DB to queueIn upon initialization
 	wg.Add(1)
@@ -65,13 +122,23 @@ func InitStorage(ctx context.Context, cfg *config.StorageConfig, log *zerolog.Lo
 			log.Fatal().Err(err).Msg("could not retrieve stalled orders")
 		}
 		for _, stalledOrder := range stalledOrders {
-			st.SendToQueue(modelqueue.OrderQueueEntry{
-				UserID:      stalledOrder.UserID,
-				OrderNumber: stalledOrder.OrderNumber,
-				OrderStatus: stalledOrder.Status,
-			})
+			st.SendToQueue(stalledQueueEntry(stalledOrder))
 		}
 		log.Info().Msg(fmt.Sprintf("%v stalled orders were sent for processing", len(stalledOrders)))
+		pendingWithdrawals, err := st.getPendingWithdrawals(ctx)
+		if err != nil {
+			log.Fatal().Err(err).Msg("could not retrieve pending withdrawals")
+		}
+		for _, pendingWithdrawal := range pendingWithdrawals {
+			st.SendWithdrawalToQueue(ctx, modelqueue.WithdrawalQueueEntry{
+				ID:          pendingWithdrawal.ID,
+				TenantID:    pendingWithdrawal.TenantID,
+				UserID:      pendingWithdrawal.UserID,
+				OrderNumber: strconv.Itoa(pendingWithdrawal.OrderNumber),
+				Amount:      pendingWithdrawal.Amount,
+			})
+		}
+		log.Info().Msg(fmt.Sprintf("%v pending withdrawals were sent for processing", len(pendingWithdrawals)))
 		<-ctx.Done()
 		err = st.DB.Close()
 		if err != nil {
@@ -86,7 +153,15 @@ func InitStorage(ctx context.Context, cfg *config.StorageConfig, log *zerolog.Lo
 		log.Info().Msg("started listening to queue for processed orders")
 		defer wg.Done()
 		for record := range st.QueueOut {
-			err := st.updateOrder(ctx, record.OrderNumber, record.OrderStatus, record.Accrual, record.UserID)
+			if record.Dequeued {
+				st.untrackQueued(record.OrderNumber)
+			}
+			var err error
+			if record.Abandoned {
+				err = st.abandonOrder(tenant.WithTenant(ctx, record.TenantID), record.OrderNumber)
+			} else {
+				err = st.updateOrder(tenant.WithTenant(ctx, record.TenantID), record.OrderNumber, record.OrderStatus, record.Accrual, record.UserID, record.Source)
+			}
 			if err != nil {
 				log.Warn().Err(err).Msg(fmt.Sprintf("could not update order %v", record.OrderNumber))
 			}
@@ -96,41 +171,114 @@ func InitStorage(ctx context.Context, cfg *config.StorageConfig, log *zerolog.Lo
 	return &st, nil
 }
 
-// AddNewUser adds a new user to DB.
-func (s *Storage) AddNewUser(ctx context.Context, credentials modeldto.User, userID string) error {
-	newUserStmt, err := s.DB.PrepareContext(ctx, "INSERT INTO users (user_id, login, password, registered_at) VALUES ($1, $2, $3, $4)")
-	if err != nil {
-		return &storageErrors.StatementPSQLError{Err: err}
+// Ping verifies that DB connection is alive.
+func (s *Storage) Ping(ctx context.Context) error {
+	return s.DB.PingContext(ctx)
+}
+
+// Healthy returns false if DB connection is considered lost.
+func (s *Storage) Healthy() bool {
+	return atomic.LoadInt32(&s.healthy) == 1
+}
+
+// RetryAfter returns the suggested delay before retrying requests while DB is unhealthy.
+func (s *Storage) RetryAfter() time.Duration {
+	return s.cfg.RetryAfter
+}
+
+// monitorConnection periodically pings DB marking the storage unhealthy upon failure and reconnecting with backoff.
+func (s *Storage) monitorConnection(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.MonitorInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		pingCtx, cancel := context.WithTimeout(ctx, s.cfg.MonitorInterval)
+		err := s.DB.PingContext(pingCtx)
+		cancel()
+		if err == nil {
+			continue
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		atomic.StoreInt32(&s.healthy, 0)
+		s.metrics.Counter("gophermart_storage_connection_lost_total").Inc()
+		s.log.Error().Err(err).Msg("PSQL DB connection was lost, switching to degraded mode")
+		// database/sql discards broken connections, so successful pings indicate that a fresh connection was established
+		backoff := s.cfg.ConnectBackoff
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			err = s.DB.PingContext(ctx)
+			if err == nil {
+				break
+			}
+			s.log.Warn().Err(err).Msg(fmt.Sprintf("PSQL DB reconnection failed, retrying in %v", backoff))
+			backoff *= 2
+			if backoff > s.cfg.ConnectMaxBackoff {
+				backoff = s.cfg.ConnectMaxBackoff
+			}
+		}
+		atomic.StoreInt32(&s.healthy, 1)
+		s.log.Info().Msg("PSQL DB connection was re-established")
 	}
-	defer newUserStmt.Close()
-	newBalanceStmt, err := s.DB.PrepareContext(ctx, "INSERT INTO balance (user_id, amount) VALUES ($1, $2)")
+}
+
+// AddNewUser adds a new user along with their balance in a single transaction, registrations of a taken login
+// including concurrent ones fail with AlreadyExistsError. The login is stored along with its digest from lookup.
+func (s *Storage) AddNewUser(ctx context.Context, credentials modeldto.User, lookup modelstorage.LoginLookup, userID string) error {
+	tx, err := s.DB.BeginTx(ctx, nil)
 	if err != nil {
-		return &storageErrors.StatementPSQLError{Err: err}
+		return &storageErrors.ExecutionPSQLError{Err: err}
 	}
-	defer newBalanceStmt.Close()
-	chanOk := make(chan bool)
-	chanEr := make(chan error)
+	defer tx.Rollback()
+	tenantID := tenant.FromContext(ctx)
+	// channels are buffered so that the goroutine does not leak once the context is done
+	chanOk := make(chan bool, 1)
+	chanEr := make(chan error, 1)
 	go func() {
-		s.mu.Lock()
-		defer s.mu.Unlock()
-		_, err := newUserStmt.ExecContext(ctx, userID, credentials.Login, credentials.Password, time.Now().Format(time.RFC3339))
+		if len(lookup.Legacy) > 0 {
+			var taken bool
+			err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM users WHERE tenant_id = $1 AND login_hash IS NULL AND login = ANY($2))", tenantID, lookup.Legacy).Scan(&taken)
+			if err != nil {
+				chanEr <- &storageErrors.ScanningPSQLError{Err: err}
+				return
+			}
+			if taken {
+				chanEr <- &storageErrors.AlreadyExistsError{Err: errors.New("login is taken"), ID: credentials.Login, Code: errcodes.LoginTaken}
+				return
+			}
+		}
+		registeredAt := time.Now()
+		var id int64
+		err := tx.QueryRowContext(ctx, `INSERT INTO users (user_id, login, login_hash, password, registered_at, tenant_id) VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (tenant_id, login_hash) DO NOTHING RETURNING id`, userID, credentials.Login, lookup.Hash, credentials.Password, registeredAt, tenantID).Scan(&id)
 		if err != nil {
-			if err, ok := err.(*pgconn.PgError); ok && err.Code == pgerrcode.UniqueViolation {
-				chanEr <- &storageErrors.AlreadyExistsError{Err: err, ID: credentials.Login}
+			if errors.Is(err, sql.ErrNoRows) {
+				// a concurrent registration of the same login waits for the other transaction and lands here
+				chanEr <- &storageErrors.AlreadyExistsError{Err: err, ID: credentials.Login, Code: errcodes.LoginTaken}
 				return
 			}
 			chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
 			return
 		}
-		_, err = newBalanceStmt.ExecContext(ctx, userID, 0)
+		_, err = tx.ExecContext(ctx, "INSERT INTO balance (user_id, amount, tenant_id) VALUES ($1, $2, $3)", userID, 0, tenantID)
 		if err != nil {
-			if err, ok := err.(*pgconn.PgError); ok && err.Code == pgerrcode.UniqueViolation {
-				chanEr <- &storageErrors.AlreadyExistsError{Err: err, ID: credentials.Login}
-				return
-			}
 			chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
 			return
 		}
+		err = addOutboxEvent(ctx, tx, tenantID, modelstorage.OutboxUserRegistered, map[string]interface{}{"user_id": userID, "registered_at": registeredAt})
+		if err != nil {
+			chanEr <- err
+			return
+		}
 		chanOk <- true
 	}()
 
@@ -142,25 +290,32 @@ func (s *Storage) AddNewUser(ctx context.Context, credentials modeldto.User, use
 		s.log.Error().Err(methodErr).Msg(fmt.Sprintf("adding new user failed for %s", credentials.Login))
 		return methodErr
 	case <-chanOk:
+		err = tx.Commit()
+		if err != nil {
+			s.log.Error().Err(err).Msg(fmt.Sprintf("adding new user failed for %s", credentials.Login))
+			return &storageErrors.ExecutionPSQLError{Err: err}
+		}
 		s.log.Info().Msg(fmt.Sprintf("adding new user done for %s", credentials.Login))
 		return nil
 	}
 }
 
-// CheckUser checks whether a user exists in DB.
-func (s *Storage) CheckUser(ctx context.Context, credentials modeldto.User) (string, error) {
-	selectStmt, err := s.DB.PrepareContext(ctx, "SELECT * FROM users WHERE login = $1")
+// CheckUser retrieves the ciphered credentials of an active user by their login digest, users stored before digests
+// were introduced are matched by the legacy ciphertexts of lookup.
+func (s *Storage) CheckUser(ctx context.Context, lookup modelstorage.LoginLookup) (*modelstorage.UserStorageEntry, error) {
+	selectStmt, err := s.DB.PrepareContext(ctx, `SELECT id, user_id, login, password, registered_at FROM users
+		WHERE tenant_id = $1 AND deactivated_at IS NULL AND (login_hash = $2 OR (login_hash IS NULL AND login = ANY($3)))`)
 	if err != nil {
-		return "", &storageErrors.StatementPSQLError{Err: err}
+		return nil, &storageErrors.StatementPSQLError{Err: err}
 	}
 	defer selectStmt.Close()
-	chanOk := make(chan string)
+	chanOk := make(chan *modelstorage.UserStorageEntry)
 	chanEr := make(chan error)
 	go func() {
 		s.mu.Lock()
 		defer s.mu.Unlock()
 		var queryOutput modelstorage.UserStorageEntry
-		err := selectStmt.QueryRowContext(ctx, credentials.Login).Scan(&queryOutput.ID, &queryOutput.UserID, &queryOutput.Login, &queryOutput.Password, &queryOutput.RegisteredAt)
+		err := selectStmt.QueryRowContext(ctx, tenant.FromContext(ctx), lookup.Hash, lookup.Legacy).Scan(&queryOutput.ID, &queryOutput.UserID, &queryOutput.Login, &queryOutput.Password, &queryOutput.RegisteredAt)
 		if err != nil {
 			switch {
 			case errors.Is(err, sql.ErrNoRows):
@@ -171,31 +326,25 @@ func (s *Storage) CheckUser(ctx context.Context, credentials modeldto.User) (str
 				return
 			}
 		}
-		passwordHash := sha256.Sum256([]byte(credentials.Password))
-		expectedPasswordHash := sha256.Sum256([]byte(queryOutput.Password))
-		passwordMatch := subtle.ConstantTimeCompare(passwordHash[:], expectedPasswordHash[:]) == 1
-		if !passwordMatch {
-			chanEr <- &storageErrors.NotFoundError{Err: nil}
-		}
-		chanOk <- queryOutput.UserID
+		chanOk <- &queryOutput
 	}()
 
 	select {
 	case <-ctx.Done():
 		s.log.Error().Err(ctx.Err()).Msg("user authentication failed")
-		return "", &storageErrors.ContextTimeoutExceededError{Err: ctx.Err()}
+		return nil, &storageErrors.ContextTimeoutExceededError{Err: ctx.Err()}
 	case methodErr := <-chanEr:
 		s.log.Error().Err(methodErr).Msg("user authentication failed")
-		return "", methodErr
-	case userID := <-chanOk:
+		return nil, methodErr
+	case user := <-chanOk:
 		s.log.Info().Msg("user authentication done")
-		return userID, nil
+		return user, nil
 	}
 }
 
-// GetCurrentAmount retrieves the current user's balance from DB.
+// GetCurrentAmount retrieves the current user's balance available for spending, i.e. less active holds, from DB.
 func (s *Storage) GetCurrentAmount(ctx context.Context, userID string) (float64, error) {
-	selectStmt, err := s.DB.PrepareContext(ctx, "SELECT * FROM balance WHERE user_id = $1")
+	selectStmt, err := s.DB.PrepareContext(ctx, "SELECT b.id, b.user_id, b.amount - "+heldAmountQuery+" FROM balance b WHERE b.user_id = $1 AND b.tenant_id = $2")
 	if err != nil {
 		return 0, &storageErrors.StatementPSQLError{Err: err}
 	}
@@ -206,7 +355,7 @@ func (s *Storage) GetCurrentAmount(ctx context.Context, userID string) (float64,
 		s.mu.Lock()
 		defer s.mu.Unlock()
 		var queryOutput modelstorage.BalanceStorageEntry
-		err := selectStmt.QueryRowContext(ctx, userID).Scan(&queryOutput.ID, &queryOutput.UserID, &queryOutput.Amount)
+		err := selectStmt.QueryRowContext(ctx, userID, tenant.FromContext(ctx)).Scan(&queryOutput.ID, &queryOutput.UserID, &queryOutput.Amount)
 		if err != nil {
 			switch {
 			case errors.Is(err, sql.ErrNoRows):
@@ -234,7 +383,7 @@ func (s *Storage) GetCurrentAmount(ctx context.Context, userID string) (float64,
 
 // GetWithdrawnAmount retrieves the current user's withdrawn balance from DB.
 func (s *Storage) GetWithdrawnAmount(ctx context.Context, userID string) (float64, error) {
-	selectStmt, err := s.DB.PrepareContext(ctx, "SELECT * FROM withdrawals WHERE user_id = $1")
+	selectStmt, err := s.DB.PrepareContext(ctx, "SELECT COALESCE(SUM(amount), 0) FROM withdrawals WHERE user_id = $1 AND tenant_id = $2 AND status = 'PROCESSED'")
 	if err != nil {
 		return 0, &storageErrors.StatementPSQLError{Err: err}
 	}
@@ -244,29 +393,11 @@ func (s *Storage) GetWithdrawnAmount(ctx context.Context, userID string) (float6
 	go func() {
 		s.mu.Lock()
 		defer s.mu.Unlock()
-		rows, err := selectStmt.QueryContext(ctx, userID)
-		if err != nil {
-			chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
-			return
-		}
-		defer rows.Close()
-		var queryOutput []modelstorage.WithdrawalStorageEntry
-		for rows.Next() {
-			var queryOutputRow modelstorage.WithdrawalStorageEntry
-			err = rows.Scan(&queryOutputRow.ID, &queryOutputRow.UserID, &queryOutputRow.OrderNumber, &queryOutputRow.Amount, &queryOutputRow.ProcessedAt)
-			if err != nil {
-				chanEr <- &storageErrors.ScanningPSQLError{Err: err}
-				return
-			}
-			queryOutput = append(queryOutput, queryOutputRow)
-		}
-		err = rows.Err()
+		var withdrawnAmount float64
+		err := selectStmt.QueryRowContext(ctx, userID, tenant.FromContext(ctx)).Scan(&withdrawnAmount)
 		if err != nil {
 			chanEr <- &storageErrors.ScanningPSQLError{Err: err}
-		}
-		var withdrawnAmount float64
-		for _, entry := range queryOutput {
-			withdrawnAmount += entry.Amount
+			return
 		}
 		chanOk <- withdrawnAmount
 	}()
@@ -283,9 +414,54 @@ func (s *Storage) GetWithdrawnAmount(ctx context.Context, userID string) (float6
 	}
 }
 
+// GetBalanceAmounts retrieves both the current and the withdrawn user's balance along with the time of its last change
+// from DB in a single query.
+func (s *Storage) GetBalanceAmounts(ctx context.Context, userID string) (*modelstorage.BalanceAmountsStorageEntry, error) {
+	selectStmt, err := s.DB.PrepareContext(ctx, `SELECT b.amount, COALESCE((SELECT SUM(w.amount) FROM withdrawals w WHERE w.user_id = b.user_id AND w.status = 'PROCESSED'), 0), `+heldAmountQuery+`, b.updated_at
+		FROM balance b WHERE b.user_id = $1 AND b.tenant_id = $2`)
+	if err != nil {
+		return nil, &storageErrors.StatementPSQLError{Err: err}
+	}
+	defer selectStmt.Close()
+	chanOk := make(chan modelstorage.BalanceAmountsStorageEntry)
+	chanEr := make(chan error)
+	go func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		var queryOutput modelstorage.BalanceAmountsStorageEntry
+		err := selectStmt.QueryRowContext(ctx, userID, tenant.FromContext(ctx)).Scan(&queryOutput.CurrentAmount, &queryOutput.WithdrawnAmount, &queryOutput.HeldAmount, &queryOutput.UpdatedAt)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				chanEr <- &storageErrors.NotFoundError{Err: err}
+				return
+			default:
+				chanEr <- &storageErrors.ScanningPSQLError{Err: err}
+				return
+			}
+		}
+		chanOk <- queryOutput
+	}()
+	select {
+	case <-ctx.Done():
+		s.log.Error().Err(ctx.Err()).Msg("getting balance amounts failed")
+		return nil, &storageErrors.ContextTimeoutExceededError{Err: ctx.Err()}
+	case methodErr := <-chanEr:
+		s.log.Error().Err(methodErr).Msg("getting balance amounts failed")
+		return nil, methodErr
+	case amounts := <-chanOk:
+		s.log.Info().Msg("getting balance amounts done")
+		return &amounts, nil
+	}
+}
+
 // GetWithdrawals retrieves a user's history of withdrawals from DB.
-func (s *Storage) GetWithdrawals(ctx context.Context, userID string) ([]modelstorage.WithdrawalStorageEntry, error) {
-	selectStmt, err := s.DB.PrepareContext(ctx, "SELECT * FROM withdrawals WHERE user_id = $1")
+func (s *Storage) GetWithdrawals(ctx context.Context, userID string, sort modeldto.Sort) ([]modelstorage.WithdrawalStorageEntry, error) {
+	orderBy, err := orderByClause(sort, withdrawalSortColumns, "processed_at")
+	if err != nil {
+		return nil, err
+	}
+	selectStmt, err := s.DB.PrepareContext(ctx, "SELECT id, user_id, order_number, amount, processed_at, status FROM withdrawals WHERE user_id = $1 AND tenant_id = $2"+orderBy)
 	if err != nil {
 		return nil, &storageErrors.StatementPSQLError{Err: err}
 	}
@@ -295,7 +471,7 @@ func (s *Storage) GetWithdrawals(ctx context.Context, userID string) ([]modelsto
 	go func() {
 		s.mu.Lock()
 		defer s.mu.Unlock()
-		rows, err := selectStmt.QueryContext(ctx, userID)
+		rows, err := selectStmt.QueryContext(ctx, userID, tenant.FromContext(ctx))
 		if err != nil {
 			chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
 			return
@@ -304,7 +480,7 @@ func (s *Storage) GetWithdrawals(ctx context.Context, userID string) ([]modelsto
 		var queryOutput []modelstorage.WithdrawalStorageEntry
 		for rows.Next() {
 			var queryOutputRow modelstorage.WithdrawalStorageEntry
-			err = rows.Scan(&queryOutputRow.ID, &queryOutputRow.UserID, &queryOutputRow.OrderNumber, &queryOutputRow.Amount, &queryOutputRow.ProcessedAt)
+			err = rows.Scan(&queryOutputRow.ID, &queryOutputRow.UserID, &queryOutputRow.OrderNumber, &queryOutputRow.Amount, &queryOutputRow.ProcessedAt, &queryOutputRow.Status)
 			if err != nil {
 				chanEr <- &storageErrors.ScanningPSQLError{Err: err}
 				return
@@ -331,8 +507,12 @@ func (s *Storage) GetWithdrawals(ctx context.Context, userID string) ([]modelsto
 }
 
 // GetOrders retrieves a user's history of orders from DB.
-func (s *Storage) GetOrders(ctx context.Context, userID string) ([]modelstorage.OrderStorageEntry, error) {
-	selectStmt, err := s.DB.PrepareContext(ctx, "SELECT * FROM orders WHERE user_id = $1")
+func (s *Storage) GetOrders(ctx context.Context, userID string, sort modeldto.Sort) ([]modelstorage.OrderStorageEntry, error) {
+	orderBy, err := orderByClause(sort, orderSortColumns, "uploaded_at")
+	if err != nil {
+		return nil, err
+	}
+	selectStmt, err := s.DB.PrepareContext(ctx, "SELECT id, user_id, order_number, status, accrual, created_at, COALESCE(metadata::text, '') FROM orders WHERE user_id = $1 AND tenant_id = $2"+orderBy)
 	if err != nil {
 		return nil, &storageErrors.StatementPSQLError{Err: err}
 	}
@@ -342,7 +522,7 @@ func (s *Storage) GetOrders(ctx context.Context, userID string) ([]modelstorage.
 	go func() {
 		s.mu.Lock()
 		defer s.mu.Unlock()
-		rows, err := selectStmt.QueryContext(ctx, userID)
+		rows, err := selectStmt.QueryContext(ctx, userID, tenant.FromContext(ctx))
 		if err != nil {
 			chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
 			return
@@ -351,7 +531,7 @@ func (s *Storage) GetOrders(ctx context.Context, userID string) ([]modelstorage.
 		var queryOutput []modelstorage.OrderStorageEntry
 		for rows.Next() {
 			var queryOutputRow modelstorage.OrderStorageEntry
-			err = rows.Scan(&queryOutputRow.ID, &queryOutputRow.UserID, &queryOutputRow.OrderNumber, &queryOutputRow.Status, &queryOutputRow.Accrual, &queryOutputRow.CreatedAt)
+			err = rows.Scan(&queryOutputRow.ID, &queryOutputRow.UserID, &queryOutputRow.OrderNumber, &queryOutputRow.Status, &queryOutputRow.Accrual, &queryOutputRow.CreatedAt, &queryOutputRow.Metadata)
 			if err != nil {
 				chanEr <- &storageErrors.ScanningPSQLError{Err: err}
 				return
@@ -379,49 +559,20 @@ func (s *Storage) GetOrders(ctx context.Context, userID string) ([]modelstorage.
 
 // AddNewWithdrawal adds a new withdrawal event to DB.
 func (s *Storage) AddNewWithdrawal(ctx context.Context, userID string, withdrawal modeldto.NewOrderWithdrawal) error {
-	newOrderStmt, err := s.DB.PrepareContext(ctx, "INSERT INTO orders (user_id, order_number, status, accrual, created_at) VALUES ($1, $2, $3, $4, $5)")
-	if err != nil {
-		return &storageErrors.StatementPSQLError{Err: err}
-	}
-	defer newOrderStmt.Close()
-	newWithdrawalStmt, err := s.DB.PrepareContext(ctx, "INSERT INTO withdrawals (user_id, order_number, amount, processed_at) VALUES ($1, $2, $3, $4)")
-	if err != nil {
-		return &storageErrors.StatementPSQLError{Err: err}
-	}
-	defer newWithdrawalStmt.Close()
-	updBalanceStmt, err := s.DB.PrepareContext(ctx, "UPDATE balance SET amount = (amount - $1) WHERE user_id = $2")
-	if err != nil {
-		return &storageErrors.StatementPSQLError{Err: err}
-	}
-	defer updBalanceStmt.Close()
 	tx, err := s.DB.BeginTx(ctx, nil)
 	if err != nil {
 		return &storageErrors.ExecutionPSQLError{Err: err}
 	}
 	defer tx.Rollback()
-	txNewOrderStmt := tx.StmtContext(ctx, newOrderStmt)
-	txNewWithdrawalStmt := tx.StmtContext(ctx, newWithdrawalStmt)
-	txUpdBalanceStmt := tx.StmtContext(ctx, updBalanceStmt)
+	tenantID := tenant.FromContext(ctx)
 	chanOk := make(chan bool)
 	chanEr := make(chan error)
+	var alerts []modeldto.Notification
 	go func() {
-		_, err = txNewOrderStmt.ExecContext(ctx, userID, withdrawal.OrderNumber, "PROCESSED", 0.0, time.Now().Format(time.RFC3339))
-		if err != nil {
-			if err, ok := err.(*pgconn.PgError); ok && err.Code == pgerrcode.UniqueViolation {
-				chanEr <- &storageErrors.AlreadyExistsError{Err: err, ID: withdrawal.OrderNumber}
-			}
-			chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
-		}
-		_, err = txNewWithdrawalStmt.ExecContext(ctx, userID, withdrawal.OrderNumber, withdrawal.Amount, time.Now().Format(time.RFC3339))
+		alerts, err = s.withdraw(ctx, tx, userID, tenantID, withdrawal)
 		if err != nil {
-			if err, ok := err.(*pgconn.PgError); ok && err.Code == pgerrcode.UniqueViolation {
-				chanEr <- &storageErrors.AlreadyExistsError{Err: err, ID: withdrawal.OrderNumber}
-			}
-			chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
-		}
-		_, err = txUpdBalanceStmt.ExecContext(ctx, withdrawal.Amount, userID)
-		if err != nil {
-			chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
+			chanEr <- err
+			return
 		}
 		chanOk <- true
 	}()
@@ -434,22 +585,124 @@ func (s *Storage) AddNewWithdrawal(ctx context.Context, userID string, withdrawa
 		return methodErr
 	case <-chanOk:
 		s.log.Info().Msg("processing new withdrawal order done")
-		return tx.Commit()
+		defer s.cache.InvalidateOrders(ctx, userID)
+		defer s.cache.InvalidateBalance(ctx, userID)
+		err = tx.Commit()
+		if err != nil {
+			return err
+		}
+		s.emit(modeldto.Notification{Kind: "balance_changed", UserID: userID, Message: fmt.Sprintf("balance debited with %v for order %s", withdrawal.Amount, withdrawal.OrderNumber)})
+		for _, alert := range alerts {
+			s.emit(alert)
+		}
+		return nil
 	}
 }
 
+// withdraw stores a processed withdrawal and debits the balance within the transaction, it returns balance alerts
+// to be emitted once the transaction is committed.
+func (s *Storage) withdraw(ctx context.Context, tx *sql.Tx, userID, tenantID string, withdrawal modeldto.NewOrderWithdrawal) ([]modeldto.Notification, error) {
+	err := ensureWithdrawalOrder(ctx, tx, userID, withdrawal.OrderNumber, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.ExecContext(ctx, "INSERT INTO withdrawals (user_id, order_number, amount, processed_at, status, tenant_id) VALUES ($1, $2, $3, $4, 'PROCESSED', $5)", userID, withdrawal.OrderNumber, withdrawal.Amount, time.Now(), tenantID)
+	if err != nil {
+		return nil, &storageErrors.ExecutionPSQLError{Err: err}
+	}
+	err = s.adjustBalance(ctx, tx, userID, tenantID, -withdrawal.Amount)
+	if err != nil {
+		return nil, err
+	}
+	err = addBalanceEvent(ctx, tx, userID, modelstorage.EventWithdrawal, -withdrawal.Amount, withdrawal.OrderNumber)
+	if err != nil {
+		return nil, err
+	}
+	alerts, err := balanceAlerts(ctx, tx, userID, -withdrawal.Amount, withdrawal.OrderNumber)
+	if err != nil {
+		return nil, err
+	}
+	err = addOutboxEvent(ctx, tx, tenantID, modelstorage.OutboxWithdrawalMade, map[string]interface{}{"user_id": userID, "order": withdrawal.OrderNumber, "sum": withdrawal.Amount})
+	if err != nil {
+		return nil, err
+	}
+	return alerts, nil
+}
+
 // SendToQueue sends an order to processing queue.
 func (s *Storage) SendToQueue(item modelqueue.OrderQueueEntry) {
+	if !s.trackQueued(item.OrderNumber) {
+		return
+	}
+	s.metrics.Gauge(metrics.OrderQueueSize).Add(1)
 	s.QueueIn <- item
 }
 
-// AddNewOrder adds a new order event to DB.
-func (s *Storage) AddNewOrder(ctx context.Context, userID string, orderNumber int) error {
-	selectStmt, err := s.DB.PrepareContext(ctx, "SELECT * FROM orders WHERE order_number = $1")
+// queuedCount returns the number of orders currently present in the queue.
+func (s *Storage) queuedCount() int {
+	s.queuedMu.Lock()
+	defer s.queuedMu.Unlock()
+	return len(s.queued)
+}
+
+// trackQueued marks an order as present in the queue, it returns false if the order was already queued.
+func (s *Storage) trackQueued(orderNumber int) bool {
+	s.queuedMu.Lock()
+	defer s.queuedMu.Unlock()
+	if _, ok := s.queued[orderNumber]; ok {
+		return false
+	}
+	s.queued[orderNumber] = struct{}{}
+	return true
+}
+
+// untrackQueued marks an order as absent from the queue.
+func (s *Storage) untrackQueued(orderNumber int) {
+	s.queuedMu.Lock()
+	defer s.queuedMu.Unlock()
+	delete(s.queued, orderNumber)
+}
+
+// isQueued checks whether an order is present in the queue.
+func (s *Storage) isQueued(orderNumber int) bool {
+	s.queuedMu.Lock()
+	defer s.queuedMu.Unlock()
+	_, ok := s.queued[orderNumber]
+	return ok
+}
+
+// RescanStalledOrders finds non-final orders absent from the queue and re-enqueues them, the queue is
+// per instance so every instance rescans on its own.
+func (s *Storage) RescanStalledOrders(ctx context.Context) error {
+	stalledOrders, err := s.getStalledOrders(ctx)
+	if err != nil {
+		return err
+	}
+	var requeued int
+	for _, stalledOrder := range stalledOrders {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if s.isQueued(stalledOrder.OrderNumber) {
+			continue
+		}
+		s.SendToQueue(stalledQueueEntry(stalledOrder))
+		requeued++
+	}
+	s.metrics.Counter("gophermart_orders_rescan_requeued_total").Add(uint64(requeued))
+	if requeued > 0 {
+		s.log.Warn().Msg(fmt.Sprintf("%v stalled orders absent from queue were re-enqueued", requeued))
+	}
+	return nil
+}
+
+// AddNewOrder adds a new order event to DB, empty metadata is stored as NULL.
+func (s *Storage) AddNewOrder(ctx context.Context, userID string, orderNumber int, metadata string, channel string) error {
+	selectStmt, err := s.DB.PrepareContext(ctx, "SELECT id, user_id, order_number, status, accrual, created_at FROM orders WHERE order_number = $1")
 	if err != nil {
 		return &storageErrors.StatementPSQLError{Err: err}
 	}
-	newOrderStmt, err := s.DB.PrepareContext(ctx, "INSERT INTO orders (user_id, order_number, status, accrual, created_at) VALUES ($1, $2, $3, $4, $5)")
+	newOrderStmt, err := s.DB.PrepareContext(ctx, "INSERT INTO orders (user_id, order_number, status, accrual, created_at, tenant_id, metadata, channel) VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, '')::jsonb, $8)")
 	if err != nil {
 		return &storageErrors.StatementPSQLError{Err: err}
 	}
@@ -458,8 +711,12 @@ func (s *Storage) AddNewOrder(ctx context.Context, userID string, orderNumber in
 	chanOk := make(chan bool)
 	chanEr := make(chan error)
 	go func() {
-		_, err = newOrderStmt.ExecContext(ctx, userID, orderNumber, "NEW", 0.0, time.Now().Format(time.RFC3339))
+		_, err = newOrderStmt.ExecContext(ctx, userID, orderNumber, "NEW", 0.0, time.Now(), tenant.FromContext(ctx), metadata, channel)
 		if err != nil {
+			if err, ok := err.(*pgconn.PgError); ok && err.Code == pgerrcode.ForeignKeyViolation {
+				chanEr <- &storageErrors.UnknownUserError{Err: err, ID: userID}
+				return
+			}
 			if err, ok := err.(*pgconn.PgError); ok && err.Code == pgerrcode.UniqueViolation {
 				// distinguish http.StatusOK from http.Conflict
 				var queryOutput modelstorage.OrderStorageEntry
@@ -468,7 +725,7 @@ func (s *Storage) AddNewOrder(ctx context.Context, userID string, orderNumber in
 					chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
 				} else {
 					if queryOutput.UserID == userID {
-						chanEr <- &storageErrors.AlreadyExistsError{Err: err, ID: strconv.Itoa(orderNumber)}
+						chanEr <- &storageErrors.AlreadyExistsError{Err: err, ID: strconv.Itoa(orderNumber), Code: errcodes.DuplicateOrder}
 					}
 					chanEr <- &storageErrors.AlreadyExistsAndViolatesError{Err: err, ID: strconv.Itoa(orderNumber)}
 				}
@@ -491,9 +748,10 @@ func (s *Storage) AddNewOrder(ctx context.Context, userID string, orderNumber in
 	}
 }
 
-// getStalledOrders retrieves all unprocessed orders from DB upon server startup and sends them to queue for processing.
+// getStalledOrders retrieves all unprocessed orders from DB upon server startup and sends them to queue for processing,
+// abandoned orders are left out until they are requeued by an administrator.
 func (s *Storage) getStalledOrders(ctx context.Context) ([]modelstorage.OrderStorageEntry, error) {
-	selectStmt, err := s.DB.PrepareContext(ctx, "SELECT * FROM orders WHERE status NOT IN ('PROCESSED', 'INVALID')")
+	selectStmt, err := s.DB.PrepareContext(ctx, "SELECT id, user_id, order_number, status, accrual, created_at, tenant_id, retry_count, invalid_count, poll_step, COALESCE(last_checked_at, to_timestamp(0)), retry_after_ms FROM orders WHERE status NOT IN ('PROCESSED', 'INVALID', 'SUSPENDED') AND abandoned_at IS NULL")
 	if err != nil {
 		return nil, &storageErrors.StatementPSQLError{Err: err}
 	}
@@ -512,7 +770,8 @@ func (s *Storage) getStalledOrders(ctx context.Context) ([]modelstorage.OrderSto
 		var queryOutput []modelstorage.OrderStorageEntry
 		for rows.Next() {
 			var queryOutputRow modelstorage.OrderStorageEntry
-			err = rows.Scan(&queryOutputRow.ID, &queryOutputRow.UserID, &queryOutputRow.OrderNumber, &queryOutputRow.Status, &queryOutputRow.Accrual, &queryOutputRow.CreatedAt)
+			err = rows.Scan(&queryOutputRow.ID, &queryOutputRow.UserID, &queryOutputRow.OrderNumber, &queryOutputRow.Status, &queryOutputRow.Accrual, &queryOutputRow.CreatedAt, &queryOutputRow.TenantID,
+				&queryOutputRow.Retry.RetryCount, &queryOutputRow.Retry.InvalidCount, &queryOutputRow.Retry.PollStep, &queryOutputRow.Retry.LastCheckedAt, &queryOutputRow.Retry.RetryAfterMs)
 			if err != nil {
 				chanEr <- &storageErrors.ScanningPSQLError{Err: err}
 				return
@@ -538,37 +797,199 @@ func (s *Storage) getStalledOrders(ctx context.Context) ([]modelstorage.OrderSto
 	}
 }
 
-// updateOrder updates order entry in DB.
-func (s *Storage) updateOrder(ctx context.Context, orderNumber int, status string, accrual float64, userID string) error {
-	updOrderStmt, err := s.DB.PrepareContext(ctx, "UPDATE orders SET status = $1, accrual = $2 WHERE order_number = $3")
+// stalledQueueEntry builds a queue entry for a stalled order restoring its persisted retry state.
+func stalledQueueEntry(order modelstorage.OrderStorageEntry) modelqueue.OrderQueueEntry {
+	entry := modelqueue.OrderQueueEntry{
+		TenantID:     order.TenantID,
+		UserID:       order.UserID,
+		OrderNumber:  order.OrderNumber,
+		OrderStatus:  order.Status,
+		RetryCount:   order.Retry.RetryCount,
+		InvalidCount: order.Retry.InvalidCount,
+		PollStep:     order.Retry.PollStep,
+		RetryAfter:   time.Duration(order.Retry.RetryAfterMs) * time.Millisecond,
+	}
+	if order.Retry.LastCheckedAt.Unix() > 0 {
+		entry.LastChecked = order.Retry.LastCheckedAt
+	}
+	return entry
+}
+
+// SaveRetryState stores retry metadata of a queued order so that it survives restarts, an order being retried
+// is no longer considered abandoned.
+func (s *Storage) SaveRetryState(ctx context.Context, record modelqueue.OrderQueueEntry) error {
+	updateStmt, err := s.DB.PrepareContext(ctx, "UPDATE orders SET retry_count = $1, invalid_count = $2, poll_step = $3, last_checked_at = $4, retry_after_ms = $5, abandoned_at = NULL WHERE order_number = $6 AND tenant_id = $7")
+	if err != nil {
+		return &storageErrors.StatementPSQLError{Err: err}
+	}
+	defer updateStmt.Close()
+	chanOk := make(chan bool)
+	chanEr := make(chan error)
+	go func() {
+		_, err := updateStmt.ExecContext(ctx, record.RetryCount, record.InvalidCount, record.PollStep, record.LastChecked, record.RetryAfter.Milliseconds(), record.OrderNumber, record.TenantID)
+		if err != nil {
+			chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
+			return
+		}
+		chanOk <- true
+	}()
+	select {
+	case <-ctx.Done():
+		s.log.Error().Err(ctx.Err()).Msg(fmt.Sprintf("saving retry state failed for order %v", record.OrderNumber))
+		return &storageErrors.ContextTimeoutExceededError{Err: ctx.Err()}
+	case methodErr := <-chanEr:
+		s.log.Error().Err(methodErr).Msg(fmt.Sprintf("saving retry state failed for order %v", record.OrderNumber))
+		return methodErr
+	case <-chanOk:
+		return nil
+	}
+}
+
+// abandonOrder marks a non-final order whose polling was given up so that stalled order rescans skip it.
+func (s *Storage) abandonOrder(ctx context.Context, orderNumber int) error {
+	updateStmt, err := s.DB.PrepareContext(ctx, "UPDATE orders SET abandoned_at = $1 WHERE order_number = $2 AND tenant_id = $3 AND status NOT IN ('PROCESSED', 'INVALID', 'SUSPENDED')")
+	if err != nil {
+		return &storageErrors.StatementPSQLError{Err: err}
+	}
+	defer updateStmt.Close()
+	chanOk := make(chan bool)
+	chanEr := make(chan error)
+	go func() {
+		_, err := updateStmt.ExecContext(ctx, time.Now(), orderNumber, tenant.FromContext(ctx))
+		if err != nil {
+			chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
+			return
+		}
+		chanOk <- true
+	}()
+	select {
+	case <-ctx.Done():
+		s.log.Error().Err(ctx.Err()).Msg(fmt.Sprintf("abandoning order failed for order %v", orderNumber))
+		return &storageErrors.ContextTimeoutExceededError{Err: ctx.Err()}
+	case methodErr := <-chanEr:
+		s.log.Error().Err(methodErr).Msg(fmt.Sprintf("abandoning order failed for order %v", orderNumber))
+		return methodErr
+	case <-chanOk:
+		s.log.Info().Msg(fmt.Sprintf("abandoning order done for order %v", orderNumber))
+		return nil
+	}
+}
+
+// updateOrder updates order entry in DB, the accrual is credited to the current order owner
+// as the order may have been reassigned upon an account merge since it was queued.
+// Accruals of processed orders are adjusted by cashback rules matching the order.
+// Status changes are recorded to the order status history along with the source which observed them.
+// Orders already in a final status are left intact, so that stale or duplicate updates neither overwrite
+// the status nor credit the accrual twice, the balance is credited only upon the transition to PROCESSED.
+// Accruals exceeding the configured caps are held in the SUSPENDED status pending admin approval instead.
+func (s *Storage) updateOrder(ctx context.Context, orderNumber int, status string, accrual float64, userID string, source string) error {
+	selectStmt, err := s.DB.PrepareContext(ctx, "SELECT user_id, status, channel, created_at FROM orders WHERE order_number = $1 AND tenant_id = $2 FOR UPDATE")
+	if err != nil {
+		return &storageErrors.StatementPSQLError{Err: err}
+	}
+	defer selectStmt.Close()
+	updOrderStmt, err := s.DB.PrepareContext(ctx, "UPDATE orders SET status = $1, accrual = $2, cashback_rule = $3 WHERE order_number = $4 AND tenant_id = $5 AND status NOT IN ('PROCESSED', 'INVALID', 'SUSPENDED')")
 	if err != nil {
 		return &storageErrors.StatementPSQLError{Err: err}
 	}
 	defer updOrderStmt.Close()
-	updBalanceStmt, err := s.DB.PrepareContext(ctx, "UPDATE balance SET amount = (amount + $1) WHERE user_id = $2")
+	insHistoryStmt, err := s.DB.PrepareContext(ctx, "INSERT INTO order_status_history (order_number, tenant_id, from_status, to_status, source, changed_at) VALUES ($1, $2, $3, $4, $5, $6)")
 	if err != nil {
 		return &storageErrors.StatementPSQLError{Err: err}
 	}
-	defer updBalanceStmt.Close()
+	defer insHistoryStmt.Close()
 	tx, err := s.DB.BeginTx(ctx, nil)
 	if err != nil {
 		return &storageErrors.ExecutionPSQLError{Err: err}
 	}
 	defer tx.Rollback()
+	txSelectStmt := tx.StmtContext(ctx, selectStmt)
 	txUpdOrderStmt := tx.StmtContext(ctx, updOrderStmt)
-	txUpdBalanceStmt := tx.StmtContext(ctx, updBalanceStmt)
+	txInsHistoryStmt := tx.StmtContext(ctx, insHistoryStmt)
+	tenantID := tenant.FromContext(ctx)
+	// non-final statuses carry no accrual and do not modify the balance
+	if status != "PROCESSED" {
+		accrual = 0
+	}
 	chanOk := make(chan bool)
 	chanEr := make(chan error)
+	var previousStatus string
+	var alerts []modeldto.Notification
 	go func() {
 		s.mu.Lock()
 		defer s.mu.Unlock()
-		_, err = txUpdOrderStmt.ExecContext(ctx, status, accrual, orderNumber)
+		order := cashback.Order{Accrual: accrual}
+		err = txSelectStmt.QueryRowContext(ctx, orderNumber, tenantID).Scan(&userID, &previousStatus, &order.Channel, &order.UploadedAt)
+		if err != nil {
+			chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
+			return
+		}
+		if previousStatus == "PROCESSED" || previousStatus == "INVALID" || previousStatus == modelstorage.OrderSuspended {
+			chanOk <- false
+			return
+		}
+		var rule string
+		if status == "PROCESSED" {
+			result := s.cashback.Apply(order)
+			accrual, rule = result.Accrual, result.Rule
+			if rule != "" {
+				s.metrics.Counter("gophermart_cashback_applied_total", "rule", rule).Inc()
+			}
+			exceeded, err := s.exceedsAccrualCaps(ctx, tx, userID, accrual)
+			if err != nil {
+				chanEr <- err
+				return
+			}
+			if exceeded != "" {
+				s.log.Warn().Msg(fmt.Sprintf("accrual of %v for order %v exceeds the %s cap, suspending it", accrual, orderNumber, exceeded))
+				s.metrics.Counter("gophermart_accruals_suspended_total", "cap", exceeded).Inc()
+				status = modelstorage.OrderSuspended
+			}
+		}
+		if previousStatus != status {
+			_, err = txInsHistoryStmt.ExecContext(ctx, orderNumber, tenantID, previousStatus, status, source, time.Now())
+			if err != nil {
+				chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
+				return
+			}
+		}
+		result, err := txUpdOrderStmt.ExecContext(ctx, status, accrual, rule, orderNumber, tenantID)
 		if err != nil {
 			chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
+			return
 		}
-		_, err = txUpdBalanceStmt.ExecContext(ctx, accrual, userID)
+		updated, err := result.RowsAffected()
 		if err != nil {
 			chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
+			return
+		}
+		if updated == 0 {
+			chanOk <- false
+			return
+		}
+		if status == "PROCESSED" && accrual > 0 {
+			err = s.adjustBalance(ctx, tx, userID, tenantID, accrual)
+			if err != nil {
+				chanEr <- err
+				return
+			}
+			err = addBalanceEvent(ctx, tx, userID, modelstorage.EventAccrualCredited, accrual, strconv.Itoa(orderNumber))
+			if err != nil {
+				chanEr <- err
+				return
+			}
+			alerts, err = balanceAlerts(ctx, tx, userID, accrual, strconv.Itoa(orderNumber))
+			if err != nil {
+				chanEr <- err
+				return
+			}
+		}
+		if previousStatus != status && (status == "PROCESSED" || status == "INVALID") {
+			err = addOutboxEvent(ctx, tx, tenantID, modelstorage.OutboxOrderProcessed, map[string]interface{}{"user_id": userID, "order": strconv.Itoa(orderNumber), "status": status, "accrual": accrual})
+			if err != nil {
+				chanEr <- err
+				return
+			}
 		}
 		chanOk <- true
 	}()
@@ -580,51 +1001,63 @@ func (s *Storage) updateOrder(ctx context.Context, orderNumber int, status strin
 	case methodErr := <-chanEr:
 		s.log.Error().Err(methodErr).Msg(fmt.Sprintf("updating order failed for order %v", orderNumber))
 		return methodErr
-	case <-chanOk:
+	case updated := <-chanOk:
+		if !updated {
+			s.log.Warn().Msg(fmt.Sprintf("updating order skipped for order %v, it is already %s, %s update to %s ignored", orderNumber, previousStatus, source, status))
+			s.metrics.Counter("gophermart_order_updates_skipped_total", "source", source).Inc()
+			return nil
+		}
 		s.log.Info().Msg(fmt.Sprintf("updating order done for order %v", orderNumber))
-		return tx.Commit()
+		defer s.cache.InvalidateOrders(ctx, userID)
+		defer s.cache.InvalidateBalance(ctx, userID)
+		err = tx.Commit()
+		if err != nil {
+			return err
+		}
+		if status == "PROCESSED" || status == "INVALID" {
+			s.emit(modeldto.Notification{Kind: "order_processed", UserID: userID, Message: fmt.Sprintf("order %v is %s", orderNumber, status)})
+		}
+		if status == "PROCESSED" && accrual > 0 {
+			s.emit(modeldto.Notification{Kind: "balance_changed", UserID: userID, Message: fmt.Sprintf("balance credited with %v for order %v", accrual, orderNumber)})
+		}
+		for _, alert := range alerts {
+			s.emit(alert)
+		}
+		return nil
 	}
 }
 
-// createTables creates DB tables if not exist.
-func (s *Storage) createTables(ctx context.Context) error {
-	var queries []string
-	query := `CREATE TABLE IF NOT EXISTS users (
-		id            BIGSERIAL   NOT NULL UNIQUE,
-		user_id       TEXT        NOT NULL UNIQUE,
-		login         TEXT        NOT NULL UNIQUE,
-		password      TEXT        NOT NULL,
-		registered_at TIMESTAMPTZ NOT NULL  
-	);`
-	queries = append(queries, query)
-	query = `CREATE TABLE IF NOT EXISTS orders (
-		id           BIGSERIAL      NOT NULL UNIQUE,
-		user_id      TEXT           NOT NULL,
-		order_number BIGINT         NOT NULL UNIQUE,
-		status		 TEXT 		    NOT NULL,
-		accrual	     NUMERIC(10, 2) NOT NULL,
-		created_at   TIMESTAMPTZ    NOT NULL  
-	);`
-	queries = append(queries, query)
-	query = `CREATE TABLE IF NOT EXISTS balance (
-		id      BIGSERIAL      NOT NULL UNIQUE,
-		user_id TEXT           NOT NULL UNIQUE,
-		amount  NUMERIC(10, 2) NOT NULL
-	);`
-	queries = append(queries, query)
-	query = `CREATE TABLE IF NOT EXISTS withdrawals (
-		id           BIGSERIAL      NOT NULL UNIQUE,
-		user_id      TEXT           NOT NULL,
-		order_number BIGINT         NOT NULL UNIQUE,
-		amount       NUMERIC(10, 2) NOT NULL,
-		processed_at TIMESTAMPTZ    NOT NULL 
-	);`
-	queries = append(queries, query)
-	for _, subquery := range queries {
-		_, err := s.DB.ExecContext(ctx, subquery)
-		if err != nil {
-			return err
+// emit publishes a user notification without blocking, notifications are dropped while the buffer is full
+// as they must never hold up balance updates.
+func (s *Storage) emit(notification modeldto.Notification) {
+	select {
+	case s.Events <- notification:
+	default:
+		s.metrics.Counter("gophermart_events_dropped_total", "kind", notification.Kind).Inc()
+	}
+}
+
+// waitForDB pings DB with exponential backoff until it becomes available or the maximum wait is exceeded.
+func (s *Storage) waitForDB(ctx context.Context) error {
+	deadline := time.Now().Add(s.cfg.ConnectMaxWait)
+	backoff := s.cfg.ConnectBackoff
+	for attempt := 1; ; attempt++ {
+		err := s.DB.PingContext(ctx)
+		if err == nil {
+			return nil
+		}
+		if time.Now().Add(backoff).After(deadline) {
+			return &storageErrors.ConnectionPSQLError{Err: err, Attempts: attempt}
+		}
+		s.log.Warn().Err(err).Msg(fmt.Sprintf("DB is not available yet, attempt %v, retrying in %v", attempt, backoff))
+		select {
+		case <-ctx.Done():
+			return &storageErrors.ContextTimeoutExceededError{Err: ctx.Err()}
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > s.cfg.ConnectMaxBackoff {
+			backoff = s.cfg.ConnectMaxBackoff
 		}
 	}
-	return nil
 }
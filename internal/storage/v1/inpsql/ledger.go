@@ -0,0 +1,131 @@
+// Package inpsql provides functionality for operating a relational DB.
+
+package inpsql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/models/modeldto"
+	storageErrors "github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/errors"
+	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/modelstorage"
+	"github.com/danilovkiri/dk-go-gophermart/internal/tenant"
+)
+
+// AuditBalanceAdjusted is the audit log action recorded upon manual balance adjustments.
+const AuditBalanceAdjusted = "balance_adjusted"
+
+// GetBalanceEvents retrieves a user's balance events, newest first.
+func (s *Storage) GetBalanceEvents(ctx context.Context, userID string) ([]modelstorage.BalanceEventStorageEntry, error) {
+	selectStmt, err := s.DB.PrepareContext(ctx, "SELECT id, kind, amount, reference, created_at FROM balance_events WHERE user_id = $1 AND tenant_id = $2 ORDER BY id DESC")
+	if err != nil {
+		return nil, &storageErrors.StatementPSQLError{Err: err}
+	}
+	defer selectStmt.Close()
+	chanOk := make(chan []modelstorage.BalanceEventStorageEntry)
+	chanEr := make(chan error)
+	go func() {
+		rows, err := selectStmt.QueryContext(ctx, userID, tenant.FromContext(ctx))
+		if err != nil {
+			chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
+			return
+		}
+		defer rows.Close()
+		var queryOutput []modelstorage.BalanceEventStorageEntry
+		for rows.Next() {
+			var queryOutputRow modelstorage.BalanceEventStorageEntry
+			err = rows.Scan(&queryOutputRow.ID, &queryOutputRow.Kind, &queryOutputRow.Amount, &queryOutputRow.Reference, &queryOutputRow.CreatedAt)
+			if err != nil {
+				chanEr <- &storageErrors.ScanningPSQLError{Err: err}
+				return
+			}
+			queryOutput = append(queryOutput, queryOutputRow)
+		}
+		err = rows.Err()
+		if err != nil {
+			chanEr <- &storageErrors.ScanningPSQLError{Err: err}
+			return
+		}
+		chanOk <- queryOutput
+	}()
+	select {
+	case <-ctx.Done():
+		s.log.Error().Err(ctx.Err()).Msg("getting balance events failed")
+		return nil, &storageErrors.ContextTimeoutExceededError{Err: ctx.Err()}
+	case methodErr := <-chanEr:
+		s.log.Error().Err(methodErr).Msg("getting balance events failed")
+		return nil, methodErr
+	case events := <-chanOk:
+		s.log.Info().Msg("getting balance events done")
+		return events, nil
+	}
+}
+
+// AddAdjustment credits or debits an active user's balance by an administrator, the adjustment is recorded
+// in the balance event log under its reason code and in the audit log along with the comment. It returns
+// the recorded event and the resulting balance, debits exceeding the balance fail with InsufficientFundsError.
+func (s *Storage) AddAdjustment(ctx context.Context, userID string, adjustment modeldto.BalanceAdjustmentRequest) (*modelstorage.BalanceEventStorageEntry, float64, error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, 0, &storageErrors.ExecutionPSQLError{Err: err}
+	}
+	defer tx.Rollback()
+	chanOk := make(chan float64)
+	chanEr := make(chan error)
+	event := modelstorage.BalanceEventStorageEntry{Kind: modelstorage.EventAdjustment, Amount: adjustment.Amount, Reference: adjustment.ReasonCode}
+	go func() {
+		var tenantID string
+		err := tx.QueryRowContext(ctx, "SELECT tenant_id FROM users WHERE user_id = $1 AND deactivated_at IS NULL FOR UPDATE", userID).Scan(&tenantID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				chanEr <- &storageErrors.NotFoundError{Err: err}
+				return
+			}
+			chanEr <- &storageErrors.ScanningPSQLError{Err: err}
+			return
+		}
+		err = s.adjustBalance(ctx, tx, userID, tenantID, adjustment.Amount)
+		if err != nil {
+			chanEr <- err
+			return
+		}
+		err = tx.QueryRowContext(ctx, `INSERT INTO balance_events (user_id, tenant_id, kind, amount, reference, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at`, userID, tenantID, event.Kind, event.Amount, event.Reference, time.Now()).Scan(&event.ID, &event.CreatedAt)
+		if err != nil {
+			chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
+			return
+		}
+		err = addAuditEntry(ctx, tx, userID, tenantID, AuditBalanceAdjusted, adjustment)
+		if err != nil {
+			chanEr <- err
+			return
+		}
+		var balance float64
+		err = tx.QueryRowContext(ctx, "SELECT amount FROM balance WHERE user_id = $1", userID).Scan(&balance)
+		if err != nil {
+			chanEr <- &storageErrors.ScanningPSQLError{Err: err}
+			return
+		}
+		chanOk <- balance
+	}()
+	select {
+	case <-ctx.Done():
+		s.log.Error().Err(ctx.Err()).Msg(fmt.Sprintf("adjusting balance failed for user %s", userID))
+		return nil, 0, &storageErrors.ContextTimeoutExceededError{Err: ctx.Err()}
+	case methodErr := <-chanEr:
+		s.log.Error().Err(methodErr).Msg(fmt.Sprintf("adjusting balance failed for user %s", userID))
+		return nil, 0, methodErr
+	case balance := <-chanOk:
+		s.log.Info().Msg(fmt.Sprintf("adjusting balance done for user %s", userID))
+		defer s.cache.InvalidateBalance(ctx, userID)
+		err = tx.Commit()
+		if err != nil {
+			return nil, 0, err
+		}
+		s.emit(modeldto.Notification{Kind: "balance_changed", UserID: userID, Message: fmt.Sprintf("balance adjusted by %v (%s)", adjustment.Amount, adjustment.ReasonCode)})
+		return &event, balance, nil
+	}
+}
@@ -0,0 +1,38 @@
+// Package inpsql provides functionality for operating a relational DB.
+
+package inpsql
+
+import (
+	"context"
+	"fmt"
+
+	storageErrors "github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/errors"
+)
+
+// TryLock acquires a session-level advisory lock without waiting, the lock is held by a dedicated connection
+// until unlock is called and is released by the DB if the connection is lost.
+func (s *Storage) TryLock(ctx context.Context, name string) (func(), bool, error) {
+	conn, err := s.DB.Conn(ctx)
+	if err != nil {
+		return nil, false, &storageErrors.ExecutionPSQLError{Err: err}
+	}
+	var acquired bool
+	err = conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock(hashtext($1))", name).Scan(&acquired)
+	if err != nil || !acquired {
+		conn.Close()
+		if err != nil {
+			s.log.Error().Err(err).Msg(fmt.Sprintf("acquiring lock failed for %s", name))
+			return nil, false, &storageErrors.ExecutionPSQLError{Err: err}
+		}
+		return nil, false, nil
+	}
+	unlock := func() {
+		defer conn.Close()
+		// the job context may already be cancelled upon shutdown while the lock still has to be released
+		_, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock(hashtext($1))", name)
+		if err != nil {
+			s.log.Warn().Err(err).Msg(fmt.Sprintf("releasing lock failed for %s", name))
+		}
+	}
+	return unlock, true, nil
+}
@@ -0,0 +1,175 @@
+// Package inpsql provides functionality for operating a relational DB.
+
+package inpsql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/models/modeldto"
+	storageErrors "github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/errors"
+	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/modelstorage"
+)
+
+// Audit log actions recorded upon account merges.
+const (
+	AuditAccountMergedInto = "account_merged_into"
+	AuditAccountMergedFrom = "account_merged_from"
+)
+
+// MergeUsers moves orders, withdrawals and the balance of the donor account to the target account of the same tenant
+// and deactivates the donor account within a single transaction, both accounts get an audit record.
+// Donor accounts with pending withdrawals are not merged as their processing is bound to the donor.
+func (s *Storage) MergeUsers(ctx context.Context, donorID, targetID string) (*modeldto.AccountMerge, error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, &storageErrors.ExecutionPSQLError{Err: err}
+	}
+	defer tx.Rollback()
+	chanOk := make(chan modeldto.AccountMerge)
+	chanEr := make(chan error)
+	go func() {
+		// lock both accounts in a stable order to avoid deadlocks between concurrent merges
+		rows, err := tx.QueryContext(ctx, "SELECT user_id, tenant_id, deactivated_at IS NOT NULL FROM users WHERE user_id IN ($1, $2) ORDER BY user_id FOR UPDATE", donorID, targetID)
+		if err != nil {
+			chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
+			return
+		}
+		tenants := make(map[string]string)
+		for rows.Next() {
+			var userID, tenantID string
+			var deactivated bool
+			err = rows.Scan(&userID, &tenantID, &deactivated)
+			if err != nil {
+				rows.Close()
+				chanEr <- &storageErrors.ScanningPSQLError{Err: err}
+				return
+			}
+			if deactivated {
+				rows.Close()
+				chanEr <- &storageErrors.MergeConflictError{Msg: fmt.Sprintf("account %s is deactivated", userID)}
+				return
+			}
+			tenants[userID] = tenantID
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			chanEr <- &storageErrors.ScanningPSQLError{Err: err}
+			return
+		}
+		donorTenantID, donorFound := tenants[donorID]
+		targetTenantID, targetFound := tenants[targetID]
+		if !donorFound || !targetFound {
+			chanEr <- &storageErrors.NotFoundError{Err: sql.ErrNoRows}
+			return
+		}
+		if donorTenantID != targetTenantID {
+			chanEr <- &storageErrors.MergeConflictError{Msg: "accounts belong to different tenants"}
+			return
+		}
+		var pending int
+		err = tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM withdrawals WHERE user_id = $1 AND status = $2", donorID, modelstorage.WithdrawalPending).Scan(&pending)
+		if err != nil {
+			chanEr <- &storageErrors.ScanningPSQLError{Err: err}
+			return
+		}
+		if pending > 0 {
+			chanEr <- &storageErrors.MergeConflictError{Msg: fmt.Sprintf("account %s has %v pending withdrawals", donorID, pending)}
+			return
+		}
+		merge := modeldto.AccountMerge{DonorID: donorID, TargetID: targetID}
+		result, err := tx.ExecContext(ctx, "UPDATE orders SET user_id = $1 WHERE user_id = $2", targetID, donorID)
+		if err != nil {
+			chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
+			return
+		}
+		merge.OrdersMoved, err = result.RowsAffected()
+		if err != nil {
+			chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
+			return
+		}
+		result, err = tx.ExecContext(ctx, "UPDATE withdrawals SET user_id = $1 WHERE user_id = $2", targetID, donorID)
+		if err != nil {
+			chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
+			return
+		}
+		merge.WithdrawalsMoved, err = result.RowsAffected()
+		if err != nil {
+			chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
+			return
+		}
+		err = tx.QueryRowContext(ctx, "SELECT amount FROM balance WHERE user_id = $1 FOR UPDATE", donorID).Scan(&merge.AmountMoved)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			chanEr <- &storageErrors.ScanningPSQLError{Err: err}
+			return
+		}
+		now := time.Now()
+		// holds of the donor are dropped as its balance is moved as a whole
+		_, err = tx.ExecContext(ctx, "UPDATE balance_holds SET status = $1, resolved_at = $2 WHERE user_id = $3 AND status = $4", modelstorage.HoldReleased, now, donorID, modelstorage.HoldActive)
+		if err != nil {
+			chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
+			return
+		}
+		_, err = tx.ExecContext(ctx, "UPDATE balance SET amount = 0, version = version + 1, updated_at = $2 WHERE user_id = $1", donorID, now)
+		if err != nil {
+			chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
+			return
+		}
+		_, err = tx.ExecContext(ctx, "UPDATE balance SET amount = (amount + $1), version = version + 1, updated_at = $3 WHERE user_id = $2", merge.AmountMoved, targetID, now)
+		if err != nil {
+			chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
+			return
+		}
+		if merge.AmountMoved != 0 {
+			err = addBalanceEvent(ctx, tx, donorID, modelstorage.EventTransferOut, -merge.AmountMoved, targetID)
+			if err != nil {
+				chanEr <- err
+				return
+			}
+			err = addBalanceEvent(ctx, tx, targetID, modelstorage.EventTransferIn, merge.AmountMoved, donorID)
+			if err != nil {
+				chanEr <- err
+				return
+			}
+		}
+		_, err = tx.ExecContext(ctx, "UPDATE users SET deactivated_at = $1 WHERE user_id = $2", now, donorID)
+		if err != nil {
+			chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
+			return
+		}
+		err = addAuditEntry(ctx, tx, donorID, donorTenantID, AuditAccountMergedInto, merge)
+		if err != nil {
+			chanEr <- err
+			return
+		}
+		err = addAuditEntry(ctx, tx, targetID, targetTenantID, AuditAccountMergedFrom, merge)
+		if err != nil {
+			chanEr <- err
+			return
+		}
+		chanOk <- merge
+	}()
+	select {
+	case <-ctx.Done():
+		s.log.Error().Err(ctx.Err()).Msg(fmt.Sprintf("merging account %s into %s failed", donorID, targetID))
+		return nil, &storageErrors.ContextTimeoutExceededError{Err: ctx.Err()}
+	case methodErr := <-chanEr:
+		s.log.Error().Err(methodErr).Msg(fmt.Sprintf("merging account %s into %s failed", donorID, targetID))
+		return nil, methodErr
+	case merge := <-chanOk:
+		err = tx.Commit()
+		if err != nil {
+			return nil, &storageErrors.ExecutionPSQLError{Err: err}
+		}
+		s.log.Info().Msg(fmt.Sprintf("merging account %s into %s done", donorID, targetID))
+		for _, userID := range []string{donorID, targetID} {
+			s.cache.InvalidateOrders(ctx, userID)
+			s.cache.InvalidateBalance(ctx, userID)
+		}
+		return &merge, nil
+	}
+}
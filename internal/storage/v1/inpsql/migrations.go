@@ -0,0 +1,40 @@
+// Package inpsql provides functionality for operating a relational DB.
+
+package inpsql
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/migrate"
+	"github.com/rs/zerolog"
+)
+
+// migrationFiles holds the versioned schema migrations, new schema changes are added as new migrations while
+// applied ones are never edited.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLock serializes migrations of concurrently starting instances.
+const migrationLock = "SELECT pg_advisory_xact_lock(hashtext('schema_migrations'))"
+
+// NewMigrator initializes a migrator of the DB schema.
+func NewMigrator(db *sql.DB, log *zerolog.Logger) (*migrate.Migrator, error) {
+	migrations, err := migrate.Load(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	return migrate.NewMigrator(db, log, migrations, migrationLock), nil
+}
+
+// migrateSchema applies pending schema migrations.
+func (s *Storage) migrateSchema(ctx context.Context) error {
+	migrator, err := NewMigrator(s.DB, s.log)
+	if err != nil {
+		return err
+	}
+	_, err = migrator.Up(ctx)
+	return err
+}
@@ -0,0 +1,13 @@
+DROP TABLE IF EXISTS balance_holds;
+DROP TABLE IF EXISTS balance_snapshots;
+DROP TABLE IF EXISTS outbox_offsets;
+DROP TABLE IF EXISTS outbox_events;
+DROP TABLE IF EXISTS balance_events;
+DROP FUNCTION IF EXISTS balance_events_immutable();
+DROP TABLE IF EXISTS order_status_history;
+DROP TABLE IF EXISTS audit_log;
+DROP TABLE IF EXISTS sessions;
+DROP TABLE IF EXISTS withdrawals;
+DROP TABLE IF EXISTS balance;
+DROP TABLE IF EXISTS orders;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,280 @@
+-- the schema as created before versioned migrations, statements are idempotent so that databases created back then
+-- adopt it as is
+CREATE TABLE IF NOT EXISTS users (
+	id            BIGSERIAL   NOT NULL UNIQUE,
+	user_id       TEXT        NOT NULL UNIQUE,
+	login         TEXT        NOT NULL UNIQUE,
+	password      TEXT        NOT NULL,
+	registered_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS orders (
+	id           BIGSERIAL      NOT NULL UNIQUE,
+	user_id      TEXT           NOT NULL,
+	order_number BIGINT         NOT NULL UNIQUE,
+	status       TEXT           NOT NULL,
+	accrual      NUMERIC(10, 2) NOT NULL,
+	created_at   TIMESTAMPTZ    NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS balance (
+	id      BIGSERIAL      NOT NULL UNIQUE,
+	user_id TEXT           NOT NULL UNIQUE,
+	amount  NUMERIC(10, 2) NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS withdrawals (
+	id           BIGSERIAL      NOT NULL UNIQUE,
+	user_id      TEXT           NOT NULL,
+	order_number BIGINT         NOT NULL UNIQUE,
+	amount       NUMERIC(10, 2) NOT NULL,
+	processed_at TIMESTAMPTZ    NOT NULL
+);
+
+-- records which predate tenants belong to the default one
+ALTER TABLE users ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default';
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default';
+
+ALTER TABLE balance ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default';
+
+ALTER TABLE withdrawals ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT 'default';
+
+ALTER TABLE withdrawals ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'PROCESSED';
+
+ALTER TABLE balance ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+-- every balance write increments the version, see adjustBalance
+ALTER TABLE balance ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 0;
+
+-- payment of a single order may be split across several withdrawals
+ALTER TABLE withdrawals DROP CONSTRAINT IF EXISTS withdrawals_order_number_key;
+
+CREATE INDEX IF NOT EXISTS withdrawals_user_order_idx ON withdrawals (tenant_id, user_id, order_number);
+
+CREATE TABLE IF NOT EXISTS sessions (
+	id          BIGSERIAL   NOT NULL UNIQUE,
+	user_id     TEXT        NOT NULL,
+	tenant_id   TEXT        NOT NULL,
+	user_agent  TEXT        NOT NULL,
+	ip          TEXT        NOT NULL,
+	fingerprint TEXT        NOT NULL,
+	created_at  TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS sessions_user_fingerprint_idx ON sessions (tenant_id, user_id, fingerprint);
+
+-- sessions recorded before tokens were linked to them have no session_id and are never looked up
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS session_id TEXT, ADD COLUMN IF NOT EXISTS last_seen TIMESTAMPTZ;
+
+CREATE UNIQUE INDEX IF NOT EXISTS sessions_session_id_idx ON sessions (session_id);
+
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS refresh_hash TEXT, ADD COLUMN IF NOT EXISTS refresh_expires_at TIMESTAMPTZ, ADD COLUMN IF NOT EXISTS revoked_at TIMESTAMPTZ;
+
+CREATE UNIQUE INDEX IF NOT EXISTS sessions_refresh_hash_idx ON sessions (refresh_hash);
+
+CREATE TABLE IF NOT EXISTS audit_log (
+	id         BIGSERIAL   NOT NULL UNIQUE,
+	user_id    TEXT        NOT NULL,
+	tenant_id  TEXT        NOT NULL,
+	action     TEXT        NOT NULL,
+	details    JSONB,
+	created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS audit_log_user_idx ON audit_log (tenant_id, user_id);
+
+CREATE TABLE IF NOT EXISTS order_status_history (
+	id           BIGSERIAL   NOT NULL UNIQUE,
+	order_number BIGINT      NOT NULL REFERENCES orders (order_number) ON DELETE CASCADE,
+	tenant_id    TEXT        NOT NULL,
+	from_status  TEXT        NOT NULL,
+	to_status    TEXT        NOT NULL,
+	source       TEXT        NOT NULL,
+	changed_at   TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS order_status_history_order_idx ON order_status_history (tenant_id, order_number);
+
+-- balance events are append-only, the balance table is a projection of them which can be rebuilt by replaying the log
+CREATE TABLE IF NOT EXISTS balance_events (
+	id         BIGSERIAL      NOT NULL UNIQUE,
+	user_id    TEXT           NOT NULL,
+	tenant_id  TEXT           NOT NULL,
+	kind       TEXT           NOT NULL,
+	amount     NUMERIC(10, 2) NOT NULL,
+	reference  TEXT           NOT NULL,
+	created_at TIMESTAMPTZ    NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS balance_events_user_idx ON balance_events (user_id);
+
+CREATE OR REPLACE FUNCTION balance_events_immutable() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'balance events are immutable';
+END $$ LANGUAGE plpgsql;
+
+DO $$
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM pg_trigger WHERE tgname = 'balance_events_immutable') THEN
+		CREATE TRIGGER balance_events_immutable BEFORE UPDATE OR DELETE ON balance_events
+			FOR EACH ROW EXECUTE FUNCTION balance_events_immutable();
+	END IF;
+END $$;
+
+-- outbox events are relayed in the order of their writing transactions, see GetOutboxEvents
+CREATE TABLE IF NOT EXISTS outbox_events (
+	id         BIGSERIAL   NOT NULL UNIQUE,
+	tx_id      BIGINT      NOT NULL DEFAULT pg_current_xact_id()::text::bigint,
+	tenant_id  TEXT        NOT NULL,
+	kind       TEXT        NOT NULL,
+	payload    JSONB       NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS outbox_events_tx_idx ON outbox_events (tx_id, id);
+
+CREATE TABLE IF NOT EXISTS outbox_offsets (
+	relay      TEXT        NOT NULL UNIQUE,
+	last_tx_id BIGINT      NOT NULL,
+	last_id    BIGINT      NOT NULL,
+	updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS balance_snapshots (
+	user_id       TEXT           NOT NULL UNIQUE,
+	tenant_id     TEXT           NOT NULL,
+	amount        NUMERIC(10, 2) NOT NULL,
+	last_event_id BIGINT         NOT NULL,
+	taken_at      TIMESTAMPTZ    NOT NULL
+);
+
+-- contact details are ciphered like credentials, empty values are stored as is
+ALTER TABLE users
+	ADD COLUMN IF NOT EXISTS email TEXT NOT NULL DEFAULT '',
+	ADD COLUMN IF NOT EXISTS phone TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deactivated_at TIMESTAMPTZ;
+
+ALTER TABLE users
+	ADD COLUMN IF NOT EXISTS telegram_chat_id         BIGINT,
+	ADD COLUMN IF NOT EXISTS telegram_link_code       TEXT,
+	ADD COLUMN IF NOT EXISTS telegram_link_expires_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS users_telegram_link_code_idx ON users (telegram_link_code);
+
+-- NULL thresholds disable the corresponding alerts
+ALTER TABLE users
+	ADD COLUMN IF NOT EXISTS low_balance_threshold   NUMERIC(10, 2),
+	ADD COLUMN IF NOT EXISTS large_accrual_threshold NUMERIC(10, 2);
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS metadata JSONB;
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS channel TEXT NOT NULL DEFAULT 'unknown';
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS cashback_rule TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE orders
+	ADD COLUMN IF NOT EXISTS retry_count     INTEGER     NOT NULL DEFAULT 0,
+	ADD COLUMN IF NOT EXISTS invalid_count   INTEGER     NOT NULL DEFAULT 0,
+	ADD COLUMN IF NOT EXISTS poll_step       INTEGER     NOT NULL DEFAULT 0,
+	ADD COLUMN IF NOT EXISTS last_checked_at TIMESTAMPTZ,
+	ADD COLUMN IF NOT EXISTS retry_after_ms  BIGINT      NOT NULL DEFAULT 0;
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS rechecked_at TIMESTAMPTZ;
+
+-- logins are unique per tenant, order numbers stay globally unique as they identify queue entries
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_login_key;
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_tenant_login_idx ON users (tenant_id, login);
+
+-- logins are sealed with random nonces and looked up by their keyed digest, rotate-keys fills it for older users
+ALTER TABLE users ADD COLUMN IF NOT EXISTS login_hash TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_tenant_login_hash_idx ON users (tenant_id, login_hash);
+
+CREATE TABLE IF NOT EXISTS balance_holds (
+	id           BIGSERIAL      NOT NULL UNIQUE,
+	user_id      TEXT           NOT NULL,
+	tenant_id    TEXT           NOT NULL,
+	order_number BIGINT         NOT NULL,
+	amount       NUMERIC(10, 2) NOT NULL,
+	status       TEXT           NOT NULL,
+	created_at   TIMESTAMPTZ    NOT NULL,
+	expires_at   TIMESTAMPTZ    NOT NULL,
+	resolved_at  TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS balance_holds_user_idx ON balance_holds (user_id, status);
+
+-- an order is paid by a single purchase, so it may have a single active hold at a time
+CREATE UNIQUE INDEX IF NOT EXISTS balance_holds_active_order_idx ON balance_holds (order_number) WHERE status = 'ACTIVE';
+
+-- balances which predate the event log are carried over as opening events once, concurrent startups are serialized
+DO $$
+BEGIN
+	PERFORM pg_advisory_xact_lock(hashtext('balance_events_opening'));
+	IF NOT EXISTS (SELECT 1 FROM balance_events) THEN
+		INSERT INTO balance_events (user_id, tenant_id, kind, amount, reference, created_at)
+			SELECT user_id, tenant_id, 'opening', amount, '', now() FROM balance WHERE amount <> 0;
+	END IF;
+END $$;
+
+-- the constraint guards against concurrent withdrawals racing past the application balance check
+DO $$
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'balance_amount_nonnegative') THEN
+		ALTER TABLE balance ADD CONSTRAINT balance_amount_nonnegative CHECK (amount >= 0) NOT VALID;
+	END IF;
+END $$;
+
+-- users are never deleted, so deleting a user with financial history is rejected while sessions go along with it;
+-- constraints are added as NOT VALID to keep pre-existing orphaned rows from blocking startup
+DO $$
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'orders_user_id_fkey') THEN
+		ALTER TABLE orders ADD CONSTRAINT orders_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (user_id) ON DELETE RESTRICT NOT VALID;
+	END IF;
+END $$;
+
+DO $$
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'balance_user_id_fkey') THEN
+		ALTER TABLE balance ADD CONSTRAINT balance_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (user_id) ON DELETE RESTRICT NOT VALID;
+	END IF;
+END $$;
+
+DO $$
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'withdrawals_user_id_fkey') THEN
+		ALTER TABLE withdrawals ADD CONSTRAINT withdrawals_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (user_id) ON DELETE RESTRICT NOT VALID;
+	END IF;
+END $$;
+
+DO $$
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'audit_log_user_id_fkey') THEN
+		ALTER TABLE audit_log ADD CONSTRAINT audit_log_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (user_id) ON DELETE RESTRICT NOT VALID;
+	END IF;
+END $$;
+
+DO $$
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'balance_events_user_id_fkey') THEN
+		ALTER TABLE balance_events ADD CONSTRAINT balance_events_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (user_id) ON DELETE RESTRICT NOT VALID;
+	END IF;
+END $$;
+
+DO $$
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'balance_holds_user_id_fkey') THEN
+		ALTER TABLE balance_holds ADD CONSTRAINT balance_holds_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (user_id) ON DELETE RESTRICT NOT VALID;
+	END IF;
+END $$;
+
+DO $$
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'sessions_user_id_fkey') THEN
+		ALTER TABLE sessions ADD CONSTRAINT sessions_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (user_id) ON DELETE CASCADE NOT VALID;
+	END IF;
+END $$;
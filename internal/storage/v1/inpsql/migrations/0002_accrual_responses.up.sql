@@ -0,0 +1,8 @@
+-- the latest raw response of the accrual system per order is kept as evidence for disputes with the provider
+CREATE TABLE accrual_responses (
+	order_number BIGINT      NOT NULL UNIQUE REFERENCES orders (order_number) ON DELETE CASCADE,
+	tenant_id    TEXT        NOT NULL,
+	status_code  INTEGER     NOT NULL,
+	body         BYTEA       NOT NULL,
+	received_at  TIMESTAMPTZ NOT NULL
+);
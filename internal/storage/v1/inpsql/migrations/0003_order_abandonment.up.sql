@@ -0,0 +1,2 @@
+-- orders whose polling was abandoned upon exceeding the retry limit are skipped by stalled order rescans
+ALTER TABLE orders ADD COLUMN abandoned_at TIMESTAMPTZ;
@@ -0,0 +1,143 @@
+// Package inpsql provides functionality for operating a relational DB.
+
+package inpsql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	storageErrors "github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/errors"
+	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/modelstorage"
+	"github.com/danilovkiri/dk-go-gophermart/internal/tenant"
+)
+
+// GetOrder retrieves a single user's order from DB.
+func (s *Storage) GetOrder(ctx context.Context, userID string, orderNumber int) (*modelstorage.OrderStorageEntry, error) {
+	selectStmt, err := s.DB.PrepareContext(ctx, "SELECT id, user_id, order_number, status, accrual, created_at, COALESCE(metadata::text, '') FROM orders WHERE user_id = $1 AND order_number = $2 AND tenant_id = $3")
+	if err != nil {
+		return nil, &storageErrors.StatementPSQLError{Err: err}
+	}
+	defer selectStmt.Close()
+	chanOk := make(chan modelstorage.OrderStorageEntry)
+	chanEr := make(chan error)
+	go func() {
+		var queryOutput modelstorage.OrderStorageEntry
+		err := selectStmt.QueryRowContext(ctx, userID, orderNumber, tenant.FromContext(ctx)).Scan(&queryOutput.ID, &queryOutput.UserID, &queryOutput.OrderNumber, &queryOutput.Status, &queryOutput.Accrual, &queryOutput.CreatedAt, &queryOutput.Metadata)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				chanEr <- &storageErrors.NotFoundError{Err: err}
+				return
+			}
+			chanEr <- &storageErrors.ScanningPSQLError{Err: err}
+			return
+		}
+		chanOk <- queryOutput
+	}()
+	select {
+	case <-ctx.Done():
+		s.log.Error().Err(ctx.Err()).Msg(fmt.Sprintf("getting order failed for order %v", orderNumber))
+		return nil, &storageErrors.ContextTimeoutExceededError{Err: ctx.Err()}
+	case methodErr := <-chanEr:
+		s.log.Error().Err(methodErr).Msg(fmt.Sprintf("getting order failed for order %v", orderNumber))
+		return nil, methodErr
+	case order := <-chanOk:
+		s.log.Info().Msg(fmt.Sprintf("getting order done for order %v", orderNumber))
+		return &order, nil
+	}
+}
+
+// GetOrderStatusHistory retrieves status transitions of a single user's order from DB in chronological order.
+func (s *Storage) GetOrderStatusHistory(ctx context.Context, userID string, orderNumber int) ([]modelstorage.OrderStatusHistoryStorageEntry, error) {
+	selectStmt, err := s.DB.PrepareContext(ctx, `SELECT h.from_status, h.to_status, h.source, h.changed_at FROM order_status_history h
+		JOIN orders o ON o.order_number = h.order_number AND o.tenant_id = h.tenant_id
+		WHERE o.user_id = $1 AND o.order_number = $2 AND o.tenant_id = $3 ORDER BY h.id`)
+	if err != nil {
+		return nil, &storageErrors.StatementPSQLError{Err: err}
+	}
+	defer selectStmt.Close()
+	chanOk := make(chan []modelstorage.OrderStatusHistoryStorageEntry)
+	chanEr := make(chan error)
+	go func() {
+		rows, err := selectStmt.QueryContext(ctx, userID, orderNumber, tenant.FromContext(ctx))
+		if err != nil {
+			chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
+			return
+		}
+		defer rows.Close()
+		var queryOutput []modelstorage.OrderStatusHistoryStorageEntry
+		for rows.Next() {
+			var queryOutputRow modelstorage.OrderStatusHistoryStorageEntry
+			err = rows.Scan(&queryOutputRow.FromStatus, &queryOutputRow.ToStatus, &queryOutputRow.Source, &queryOutputRow.ChangedAt)
+			if err != nil {
+				chanEr <- &storageErrors.ScanningPSQLError{Err: err}
+				return
+			}
+			queryOutput = append(queryOutput, queryOutputRow)
+		}
+		err = rows.Err()
+		if err != nil {
+			chanEr <- &storageErrors.ScanningPSQLError{Err: err}
+			return
+		}
+		chanOk <- queryOutput
+	}()
+	select {
+	case <-ctx.Done():
+		s.log.Error().Err(ctx.Err()).Msg(fmt.Sprintf("getting order status history failed for order %v", orderNumber))
+		return nil, &storageErrors.ContextTimeoutExceededError{Err: ctx.Err()}
+	case methodErr := <-chanEr:
+		s.log.Error().Err(methodErr).Msg(fmt.Sprintf("getting order status history failed for order %v", orderNumber))
+		return nil, methodErr
+	case history := <-chanOk:
+		s.log.Info().Msg(fmt.Sprintf("getting order status history done for order %v", orderNumber))
+		return history, nil
+	}
+}
+
+// GetUserStats retrieves a user's order counts and accruals broken down by upload channel.
+func (s *Storage) GetUserStats(ctx context.Context, userID string) ([]modelstorage.ChannelStatsStorageEntry, error) {
+	selectStmt, err := s.DB.PrepareContext(ctx, "SELECT channel, COUNT(*), COALESCE(SUM(accrual), 0) FROM orders WHERE user_id = $1 AND tenant_id = $2 GROUP BY channel ORDER BY channel")
+	if err != nil {
+		return nil, &storageErrors.StatementPSQLError{Err: err}
+	}
+	defer selectStmt.Close()
+	chanOk := make(chan []modelstorage.ChannelStatsStorageEntry)
+	chanEr := make(chan error)
+	go func() {
+		rows, err := selectStmt.QueryContext(ctx, userID, tenant.FromContext(ctx))
+		if err != nil {
+			chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
+			return
+		}
+		defer rows.Close()
+		var queryOutput []modelstorage.ChannelStatsStorageEntry
+		for rows.Next() {
+			var queryOutputRow modelstorage.ChannelStatsStorageEntry
+			err = rows.Scan(&queryOutputRow.Channel, &queryOutputRow.Orders, &queryOutputRow.Accrual)
+			if err != nil {
+				chanEr <- &storageErrors.ScanningPSQLError{Err: err}
+				return
+			}
+			queryOutput = append(queryOutput, queryOutputRow)
+		}
+		err = rows.Err()
+		if err != nil {
+			chanEr <- &storageErrors.ScanningPSQLError{Err: err}
+			return
+		}
+		chanOk <- queryOutput
+	}()
+	select {
+	case <-ctx.Done():
+		s.log.Error().Err(ctx.Err()).Msg("getting user stats failed")
+		return nil, &storageErrors.ContextTimeoutExceededError{Err: ctx.Err()}
+	case methodErr := <-chanEr:
+		s.log.Error().Err(methodErr).Msg("getting user stats failed")
+		return nil, methodErr
+	case stats := <-chanOk:
+		s.log.Info().Msg("getting user stats done")
+		return stats, nil
+	}
+}
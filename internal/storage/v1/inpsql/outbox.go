@@ -0,0 +1,113 @@
+// Package inpsql provides functionality for operating a relational DB.
+
+package inpsql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	storageErrors "github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/errors"
+	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/modelstorage"
+)
+
+// outboxInsertQuery records a domain event, the ID of the writing transaction is filled in by DB.
+const outboxInsertQuery = "INSERT INTO outbox_events (tenant_id, kind, payload, created_at) VALUES ($1, $2, $3, $4)"
+
+// addOutboxEvent records a domain event within the transaction producing it, the payload is stored as JSON.
+func addOutboxEvent(ctx context.Context, tx *sql.Tx, tenantID, kind string, payload interface{}) error {
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, outboxInsertQuery, tenantID, kind, string(payloadJSON), time.Now())
+	if err != nil {
+		return &storageErrors.ExecutionPSQLError{Err: err}
+	}
+	return nil
+}
+
+// GetOutboxEvents retrieves domain events following the offset of a relay ordered by their writing transaction.
+// Only events of transactions older than any running one are returned, so that an event committed late
+// never ends up behind an already advanced offset.
+func (s *Storage) GetOutboxEvents(ctx context.Context, relay string, limit int) ([]modelstorage.OutboxEventStorageEntry, error) {
+	selectStmt, err := s.DB.PrepareContext(ctx, `SELECT e.id, e.tx_id, e.tenant_id, e.kind, e.payload::text, e.created_at
+		FROM outbox_events e LEFT JOIN outbox_offsets o ON o.relay = $1
+		WHERE (e.tx_id, e.id) > (COALESCE(o.last_tx_id, 0), COALESCE(o.last_id, 0))
+			AND e.tx_id < pg_snapshot_xmin(pg_current_snapshot())::text::bigint
+		ORDER BY e.tx_id, e.id LIMIT $2`)
+	if err != nil {
+		return nil, &storageErrors.StatementPSQLError{Err: err}
+	}
+	defer selectStmt.Close()
+	chanOk := make(chan []modelstorage.OutboxEventStorageEntry)
+	chanEr := make(chan error)
+	go func() {
+		rows, err := selectStmt.QueryContext(ctx, relay, limit)
+		if err != nil {
+			chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
+			return
+		}
+		defer rows.Close()
+		var queryOutput []modelstorage.OutboxEventStorageEntry
+		for rows.Next() {
+			var queryOutputRow modelstorage.OutboxEventStorageEntry
+			err = rows.Scan(&queryOutputRow.ID, &queryOutputRow.TxID, &queryOutputRow.TenantID, &queryOutputRow.Kind, &queryOutputRow.Payload, &queryOutputRow.CreatedAt)
+			if err != nil {
+				chanEr <- &storageErrors.ScanningPSQLError{Err: err}
+				return
+			}
+			queryOutput = append(queryOutput, queryOutputRow)
+		}
+		err = rows.Err()
+		if err != nil {
+			chanEr <- &storageErrors.ScanningPSQLError{Err: err}
+			return
+		}
+		chanOk <- queryOutput
+	}()
+	select {
+	case <-ctx.Done():
+		s.log.Error().Err(ctx.Err()).Msg(fmt.Sprintf("getting outbox events failed for relay %s", relay))
+		return nil, &storageErrors.ContextTimeoutExceededError{Err: ctx.Err()}
+	case methodErr := <-chanEr:
+		s.log.Error().Err(methodErr).Msg(fmt.Sprintf("getting outbox events failed for relay %s", relay))
+		return nil, methodErr
+	case events := <-chanOk:
+		return events, nil
+	}
+}
+
+// CommitOutboxOffset stores the position of the last event published by a relay, the offset never moves back.
+func (s *Storage) CommitOutboxOffset(ctx context.Context, relay string, txID, id int64) error {
+	upsertStmt, err := s.DB.PrepareContext(ctx, `INSERT INTO outbox_offsets (relay, last_tx_id, last_id, updated_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (relay) DO UPDATE SET last_tx_id = EXCLUDED.last_tx_id, last_id = EXCLUDED.last_id, updated_at = EXCLUDED.updated_at
+		WHERE (outbox_offsets.last_tx_id, outbox_offsets.last_id) < (EXCLUDED.last_tx_id, EXCLUDED.last_id)`)
+	if err != nil {
+		return &storageErrors.StatementPSQLError{Err: err}
+	}
+	defer upsertStmt.Close()
+	chanOk := make(chan bool)
+	chanEr := make(chan error)
+	go func() {
+		_, err := upsertStmt.ExecContext(ctx, relay, txID, id, time.Now())
+		if err != nil {
+			chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
+			return
+		}
+		chanOk <- true
+	}()
+	select {
+	case <-ctx.Done():
+		s.log.Error().Err(ctx.Err()).Msg(fmt.Sprintf("committing outbox offset failed for relay %s", relay))
+		return &storageErrors.ContextTimeoutExceededError{Err: ctx.Err()}
+	case methodErr := <-chanEr:
+		s.log.Error().Err(methodErr).Msg(fmt.Sprintf("committing outbox offset failed for relay %s", relay))
+		return methodErr
+	case <-chanOk:
+		s.log.Info().Msg(fmt.Sprintf("committing outbox offset done for relay %s at event %v", relay, id))
+		return nil
+	}
+}
@@ -0,0 +1,176 @@
+// Package inpsql provides functionality for operating a relational DB.
+
+package inpsql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/errcodes"
+	storageErrors "github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/errors"
+	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/modelstorage"
+	"github.com/danilovkiri/dk-go-gophermart/internal/tenant"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgerrcode"
+)
+
+// Audit log actions.
+const (
+	AuditProfileUpdated = "profile_updated"
+)
+
+// GetUser retrieves a user's ciphered credentials and contact details from DB.
+func (s *Storage) GetUser(ctx context.Context, userID string) (*modelstorage.UserStorageEntry, error) {
+	selectStmt, err := s.DB.PrepareContext(ctx, "SELECT id, user_id, login, password, registered_at, tenant_id, email, phone FROM users WHERE user_id = $1 AND tenant_id = $2")
+	if err != nil {
+		return nil, &storageErrors.StatementPSQLError{Err: err}
+	}
+	defer selectStmt.Close()
+	chanOk := make(chan modelstorage.UserStorageEntry)
+	chanEr := make(chan error)
+	go func() {
+		var queryOutput modelstorage.UserStorageEntry
+		err := selectStmt.QueryRowContext(ctx, userID, tenant.FromContext(ctx)).Scan(&queryOutput.ID, &queryOutput.UserID, &queryOutput.Login, &queryOutput.Password, &queryOutput.RegisteredAt, &queryOutput.TenantID, &queryOutput.Email, &queryOutput.Phone)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				chanEr <- &storageErrors.NotFoundError{Err: err}
+				return
+			}
+			chanEr <- &storageErrors.ScanningPSQLError{Err: err}
+			return
+		}
+		chanOk <- queryOutput
+	}()
+	select {
+	case <-ctx.Done():
+		s.log.Error().Err(ctx.Err()).Msg("getting user failed")
+		return nil, &storageErrors.ContextTimeoutExceededError{Err: ctx.Err()}
+	case methodErr := <-chanEr:
+		s.log.Error().Err(methodErr).Msg("getting user failed")
+		return nil, methodErr
+	case user := <-chanOk:
+		s.log.Info().Msg("getting user done")
+		return &user, nil
+	}
+}
+
+// UpdateUserProfile stores the re-ciphered login, the password and contact details of a user and records the changed fields
+// in the audit log within a single transaction. The update is rejected if the new login identified by lookup,
+// which is nil unless the login changes, belongs to another user of the tenant.
+func (s *Storage) UpdateUserProfile(ctx context.Context, userID string, profile modelstorage.UserStorageEntry, lookup *modelstorage.LoginLookup, changes []string) error {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return &storageErrors.ExecutionPSQLError{Err: err}
+	}
+	defer tx.Rollback()
+	tenantID := tenant.FromContext(ctx)
+	chanOk := make(chan bool)
+	chanEr := make(chan error)
+	go func() {
+		if lookup != nil {
+			var taken bool
+			err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM users WHERE tenant_id = $1 AND user_id <> $2
+				AND (login_hash = $3 OR (login_hash IS NULL AND login = ANY($4))))`, tenantID, userID, lookup.Hash, lookup.Legacy).Scan(&taken)
+			if err != nil {
+				chanEr <- &storageErrors.ScanningPSQLError{Err: err}
+				return
+			}
+			if taken {
+				chanEr <- &storageErrors.AlreadyExistsError{Err: errors.New("login is taken"), ID: userID, Code: errcodes.LoginTaken}
+				return
+			}
+		}
+		result, err := tx.ExecContext(ctx, "UPDATE users SET login = $1, login_hash = $2, password = $3, email = $4, phone = $5 WHERE user_id = $6 AND tenant_id = $7", profile.Login, profile.LoginHash, profile.Password, profile.Email, profile.Phone, userID, tenantID)
+		if err != nil {
+			if err, ok := err.(*pgconn.PgError); ok && err.Code == pgerrcode.UniqueViolation {
+				chanEr <- &storageErrors.AlreadyExistsError{Err: err, ID: userID, Code: errcodes.LoginTaken}
+				return
+			}
+			chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
+			return
+		}
+		updated, err := result.RowsAffected()
+		if err != nil {
+			chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
+			return
+		}
+		if updated == 0 {
+			chanEr <- &storageErrors.NotFoundError{Err: nil}
+			return
+		}
+		err = addAuditEntry(ctx, tx, userID, tenantID, AuditProfileUpdated, map[string][]string{"fields": changes})
+		if err != nil {
+			chanEr <- err
+			return
+		}
+		chanOk <- true
+	}()
+	select {
+	case <-ctx.Done():
+		s.log.Error().Err(ctx.Err()).Msg("updating user profile failed")
+		return &storageErrors.ContextTimeoutExceededError{Err: ctx.Err()}
+	case methodErr := <-chanEr:
+		s.log.Error().Err(methodErr).Msg("updating user profile failed")
+		return methodErr
+	case <-chanOk:
+		s.log.Info().Msg(fmt.Sprintf("updating user profile done for fields %v", changes))
+		return tx.Commit()
+	}
+}
+
+// UpdatePasswordHash replaces the stored password of a user with a hash of it, it is used for migrating legacy
+// ciphered passwords and for rehashing them with another cost.
+func (s *Storage) UpdatePasswordHash(ctx context.Context, userID, hash string) error {
+	updateStmt, err := s.DB.PrepareContext(ctx, "UPDATE users SET password = $1 WHERE user_id = $2 AND tenant_id = $3")
+	if err != nil {
+		return &storageErrors.StatementPSQLError{Err: err}
+	}
+	defer updateStmt.Close()
+	chanOk := make(chan bool)
+	chanEr := make(chan error)
+	go func() {
+		result, err := updateStmt.ExecContext(ctx, hash, userID, tenant.FromContext(ctx))
+		if err != nil {
+			chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
+			return
+		}
+		updated, err := result.RowsAffected()
+		if err != nil {
+			chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
+			return
+		}
+		if updated == 0 {
+			chanEr <- &storageErrors.NotFoundError{Err: nil}
+			return
+		}
+		chanOk <- true
+	}()
+	select {
+	case <-ctx.Done():
+		s.log.Error().Err(ctx.Err()).Msg(fmt.Sprintf("updating password hash failed for user %s", userID))
+		return &storageErrors.ContextTimeoutExceededError{Err: ctx.Err()}
+	case methodErr := <-chanEr:
+		s.log.Error().Err(methodErr).Msg(fmt.Sprintf("updating password hash failed for user %s", userID))
+		return methodErr
+	case <-chanOk:
+		s.log.Info().Msg(fmt.Sprintf("updating password hash done for user %s", userID))
+		return nil
+	}
+}
+
+// addAuditEntry records a user-related action in the audit log, details are stored as JSON.
+func addAuditEntry(ctx context.Context, tx *sql.Tx, userID, tenantID, action string, details interface{}) error {
+	detailsJSON, err := json.Marshal(details)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "INSERT INTO audit_log (user_id, tenant_id, action, details, created_at) VALUES ($1, $2, $3, $4, $5)", userID, tenantID, action, string(detailsJSON), time.Now())
+	if err != nil {
+		return &storageErrors.ExecutionPSQLError{Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+// Package inpsql provides functionality for operating a relational DB.
+
+package inpsql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/models/modeldto"
+	storageErrors "github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/errors"
+	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/modelstorage"
+)
+
+// RecalculateBalances recomputes every balance from orders, withdrawals and manual adjustments and stores the results
+// if apply is true.
+func (s *Storage) RecalculateBalances(ctx context.Context, apply bool) (*modeldto.ReconciliationReport, error) {
+	report, err := RecalculateBalances(ctx, s.DB, apply)
+	if err != nil {
+		s.log.Error().Err(err).Msg("recalculating balances failed")
+		return nil, err
+	}
+	if apply {
+		for _, discrepancy := range report.Discrepancies {
+			s.cache.InvalidateBalance(ctx, discrepancy.UserID)
+		}
+		s.reconcileMu.Lock()
+		s.reconcileReport = nil
+		s.reconcileMu.Unlock()
+	}
+	s.log.Info().Msg(fmt.Sprintf("recalculating balances done, %v discrepancies, applied: %v", len(report.Discrepancies), apply))
+	return report, nil
+}
+
+// RecalculateBalances recomputes every balance from orders, withdrawals and manual adjustments within a single
+// transaction locking balances, the recomputed amounts are stored only if apply is true.
+func RecalculateBalances(ctx context.Context, db *sql.DB, apply bool) (*modeldto.ReconciliationReport, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, &storageErrors.ExecutionPSQLError{Err: err}
+	}
+	defer tx.Rollback()
+	rows, err := tx.QueryContext(ctx, reconcileQuery+" FOR UPDATE")
+	if err != nil {
+		return nil, &storageErrors.ExecutionPSQLError{Err: err}
+	}
+	var entries []modelstorage.BalanceDiscrepancyStorageEntry
+	for rows.Next() {
+		var entry modelstorage.BalanceDiscrepancyStorageEntry
+		err = rows.Scan(&entry.UserID, &entry.StoredAmount, &entry.ExpectedAmount)
+		if err != nil {
+			rows.Close()
+			return nil, &storageErrors.ScanningPSQLError{Err: err}
+		}
+		entries = append(entries, entry)
+	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return nil, &storageErrors.ScanningPSQLError{Err: err}
+	}
+	report := newReconciliationReport(entries)
+	if !apply {
+		return report, nil
+	}
+	for _, discrepancy := range report.Discrepancies {
+		_, err = tx.ExecContext(ctx, "UPDATE balance SET amount = $1, version = version + 1, updated_at = $3 WHERE user_id = $2", discrepancy.ExpectedAmount, discrepancy.UserID, time.Now())
+		if err != nil {
+			return nil, &storageErrors.ExecutionPSQLError{Err: err}
+		}
+		err = addBalanceEvent(ctx, tx, discrepancy.UserID, modelstorage.EventAdjustment, -discrepancy.Difference, modelstorage.ReasonRecalculation)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		return nil, &storageErrors.ExecutionPSQLError{Err: err}
+	}
+	report.Applied = true
+	return report, nil
+}
@@ -0,0 +1,113 @@
+// Package inpsql provides functionality for operating a relational DB.
+
+package inpsql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	storageErrors "github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/errors"
+	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/modelstorage"
+	"github.com/danilovkiri/dk-go-gophermart/internal/tenant"
+)
+
+// RecheckOrder resets an INVALID order to NEW along with its retry state so that it can be queued for another
+// accrual check. A user's own order is looked up within the tenant while an empty userID looks the order up
+// by its number alone on behalf of an administrator. Rechecks are subject to limit unless it is nil: the same
+// order is rechecked at most once per interval and a user rechecks at most PerUser orders per interval.
+func (s *Storage) RecheckOrder(ctx context.Context, userID string, orderNumber int, limit *modelstorage.RecheckLimit) (*modelstorage.OrderStorageEntry, error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, &storageErrors.ExecutionPSQLError{Err: err}
+	}
+	defer tx.Rollback()
+	source := modelstorage.SourceAdminRecheck
+	if userID != "" {
+		source = modelstorage.SourceUserRecheck
+	}
+	chanOk := make(chan modelstorage.OrderStorageEntry)
+	chanEr := make(chan error)
+	go func() {
+		var order modelstorage.OrderStorageEntry
+		var recheckedAt sql.NullTime
+		var err error
+		if userID != "" {
+			// the user row serializes concurrent rechecks of the same user so that the per-user limit holds
+			_, err = tx.ExecContext(ctx, "SELECT 1 FROM users WHERE user_id = $1 FOR UPDATE", userID)
+			if err != nil {
+				chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
+				return
+			}
+			err = tx.QueryRowContext(ctx, "SELECT user_id, order_number, status, tenant_id, rechecked_at FROM orders WHERE user_id = $1 AND order_number = $2 AND tenant_id = $3 FOR UPDATE",
+				userID, orderNumber, tenant.FromContext(ctx)).Scan(&order.UserID, &order.OrderNumber, &order.Status, &order.TenantID, &recheckedAt)
+		} else {
+			err = tx.QueryRowContext(ctx, "SELECT user_id, order_number, status, tenant_id, rechecked_at FROM orders WHERE order_number = $1 FOR UPDATE",
+				orderNumber).Scan(&order.UserID, &order.OrderNumber, &order.Status, &order.TenantID, &recheckedAt)
+		}
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				chanEr <- &storageErrors.NotFoundError{Err: err}
+				return
+			}
+			chanEr <- &storageErrors.ScanningPSQLError{Err: err}
+			return
+		}
+		if order.Status != "INVALID" {
+			chanEr <- &storageErrors.RecheckNotAllowedError{ID: strconv.Itoa(orderNumber), Status: order.Status}
+			return
+		}
+		now := time.Now()
+		if limit != nil {
+			if recheckedAt.Valid && now.Sub(recheckedAt.Time) < limit.Interval {
+				chanEr <- &storageErrors.RecheckRateLimitedError{ID: strconv.Itoa(orderNumber), RetryAfter: recheckedAt.Time.Add(limit.Interval).Sub(now)}
+				return
+			}
+			var rechecked int
+			var earliest sql.NullTime
+			err = tx.QueryRowContext(ctx, "SELECT COUNT(*), MIN(rechecked_at) FROM orders WHERE user_id = $1 AND rechecked_at > $2",
+				order.UserID, now.Add(-limit.Interval)).Scan(&rechecked, &earliest)
+			if err != nil {
+				chanEr <- &storageErrors.ScanningPSQLError{Err: err}
+				return
+			}
+			if rechecked >= limit.PerUser {
+				chanEr <- &storageErrors.RecheckRateLimitedError{ID: strconv.Itoa(orderNumber), RetryAfter: earliest.Time.Add(limit.Interval).Sub(now)}
+				return
+			}
+		}
+		_, err = tx.ExecContext(ctx, `UPDATE orders SET status = 'NEW', accrual = 0, cashback_rule = '', retry_count = 0, invalid_count = 0, poll_step = 0,
+			last_checked_at = NULL, retry_after_ms = 0, rechecked_at = $1 WHERE order_number = $2`, now, orderNumber)
+		if err != nil {
+			chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
+			return
+		}
+		_, err = tx.ExecContext(ctx, "INSERT INTO order_status_history (order_number, tenant_id, from_status, to_status, source, changed_at) VALUES ($1, $2, $3, $4, $5, $6)",
+			orderNumber, order.TenantID, order.Status, "NEW", source, now)
+		if err != nil {
+			chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
+			return
+		}
+		order.Status = "NEW"
+		chanOk <- order
+	}()
+	select {
+	case <-ctx.Done():
+		s.log.Error().Err(ctx.Err()).Msg(fmt.Sprintf("rechecking order failed for order %v", orderNumber))
+		return nil, &storageErrors.ContextTimeoutExceededError{Err: ctx.Err()}
+	case methodErr := <-chanEr:
+		s.log.Error().Err(methodErr).Msg(fmt.Sprintf("rechecking order failed for order %v", orderNumber))
+		return nil, methodErr
+	case order := <-chanOk:
+		err = tx.Commit()
+		if err != nil {
+			return nil, err
+		}
+		s.log.Info().Msg(fmt.Sprintf("rechecking order done for order %v", orderNumber))
+		s.metrics.Counter("gophermart_order_rechecks_total", "source", source).Inc()
+		return &order, nil
+	}
+}
@@ -0,0 +1,122 @@
+// Package inpsql provides functionality for operating a relational DB.
+
+package inpsql
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/models/modeldto"
+	storageErrors "github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/errors"
+	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/modelstorage"
+)
+
+// reconcileQuery recomputes each user's balance as the sum of credited accruals minus the sum of processed withdrawals
+// plus the sum of manual adjustments.
+const reconcileQuery = `SELECT b.user_id, b.amount,
+	COALESCE((SELECT SUM(o.accrual) FROM orders o WHERE o.user_id = b.user_id AND o.status = 'PROCESSED'), 0) -
+	COALESCE((SELECT SUM(w.amount) FROM withdrawals w WHERE w.user_id = b.user_id AND w.status = 'PROCESSED'), 0) +
+	COALESCE((SELECT SUM(e.amount) FROM balance_events e WHERE e.user_id = b.user_id AND e.kind = '` + modelstorage.EventAdjustment + `'
+		AND e.reference <> '` + modelstorage.ReasonRecalculation + `'), 0) AS expected
+FROM balance b`
+
+// GetReconciliationReport returns the latest reconciliation report, running reconciliation if none is available yet.
+func (s *Storage) GetReconciliationReport(ctx context.Context) (*modeldto.ReconciliationReport, error) {
+	s.reconcileMu.RLock()
+	report := s.reconcileReport
+	s.reconcileMu.RUnlock()
+	if report != nil {
+		return report, nil
+	}
+	return s.reconcileBalances(ctx)
+}
+
+// reconcileBalances compares stored balances to the ones recomputed from orders, withdrawals and manual adjustments.
+func (s *Storage) reconcileBalances(ctx context.Context) (*modeldto.ReconciliationReport, error) {
+	selectStmt, err := s.DB.PrepareContext(ctx, reconcileQuery)
+	if err != nil {
+		return nil, &storageErrors.StatementPSQLError{Err: err}
+	}
+	defer selectStmt.Close()
+	chanOk := make(chan []modelstorage.BalanceDiscrepancyStorageEntry)
+	chanEr := make(chan error)
+	go func() {
+		rows, err := selectStmt.QueryContext(ctx)
+		if err != nil {
+			chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
+			return
+		}
+		defer rows.Close()
+		var queryOutput []modelstorage.BalanceDiscrepancyStorageEntry
+		for rows.Next() {
+			var queryOutputRow modelstorage.BalanceDiscrepancyStorageEntry
+			err = rows.Scan(&queryOutputRow.UserID, &queryOutputRow.StoredAmount, &queryOutputRow.ExpectedAmount)
+			if err != nil {
+				chanEr <- &storageErrors.ScanningPSQLError{Err: err}
+				return
+			}
+			queryOutput = append(queryOutput, queryOutputRow)
+		}
+		err = rows.Err()
+		if err != nil {
+			chanEr <- &storageErrors.ScanningPSQLError{Err: err}
+			return
+		}
+		chanOk <- queryOutput
+	}()
+	var entries []modelstorage.BalanceDiscrepancyStorageEntry
+	select {
+	case <-ctx.Done():
+		s.log.Error().Err(ctx.Err()).Msg("reconciling balances failed")
+		return nil, &storageErrors.ContextTimeoutExceededError{Err: ctx.Err()}
+	case methodErr := <-chanEr:
+		s.log.Error().Err(methodErr).Msg("reconciling balances failed")
+		return nil, methodErr
+	case entries = <-chanOk:
+	}
+	report := newReconciliationReport(entries)
+	s.reconcileMu.Lock()
+	s.reconcileReport = report
+	s.reconcileMu.Unlock()
+	s.metrics.Counter("gophermart_reconciliation_runs_total").Inc()
+	s.metrics.Gauge("gophermart_reconciliation_users_checked").Set(int64(report.UsersChecked))
+	s.metrics.Gauge("gophermart_reconciliation_discrepancies").Set(int64(len(report.Discrepancies)))
+	if len(report.Discrepancies) > 0 {
+		s.log.Warn().Msg(fmt.Sprintf("reconciling balances found %v discrepancies", len(report.Discrepancies)))
+	} else {
+		s.log.Info().Msg("reconciling balances done")
+	}
+	return report, nil
+}
+
+// newReconciliationReport builds a report listing balances which differ from the recomputed ones.
+func newReconciliationReport(entries []modelstorage.BalanceDiscrepancyStorageEntry) *modeldto.ReconciliationReport {
+	report := &modeldto.ReconciliationReport{
+		CheckedAt:     time.Now().Format(time.RFC3339),
+		UsersChecked:  len(entries),
+		Discrepancies: []modeldto.BalanceDiscrepancy{},
+	}
+	for _, entry := range entries {
+		// amounts are stored as NUMERIC(10, 2), anything below a cent is a float artifact
+		difference := math.Round((entry.StoredAmount-entry.ExpectedAmount)*100) / 100
+		if difference == 0 {
+			continue
+		}
+		report.Discrepancies = append(report.Discrepancies, modeldto.BalanceDiscrepancy{
+			UserID:         entry.UserID,
+			StoredAmount:   entry.StoredAmount,
+			ExpectedAmount: entry.ExpectedAmount,
+			Difference:     difference,
+		})
+	}
+	return report
+}
+
+// ReconcileBalances reconciles stored balances against orders, withdrawals and manual adjustments refreshing the latest
+// report.
+func (s *Storage) ReconcileBalances(ctx context.Context) error {
+	_, err := s.reconcileBalances(ctx)
+	return err
+}
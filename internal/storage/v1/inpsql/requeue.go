@@ -0,0 +1,100 @@
+// Package inpsql provides functionality for operating a relational DB.
+
+package inpsql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/models/modeldto"
+	storageErrors "github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/errors"
+	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/modelstorage"
+)
+
+// requeueBatchSize limits the number of orders read per batch when requeueing orders in bulk.
+const requeueBatchSize = 500
+
+// requeueQuery selects a batch of non-final orders matching a requeue filter with keyset pagination by id,
+// empty filter values match any order.
+const requeueQuery = `SELECT id, user_id, order_number, status, accrual, created_at, tenant_id, retry_count, invalid_count, poll_step,
+	COALESCE(last_checked_at, to_timestamp(0)), retry_after_ms FROM orders
+	WHERE status NOT IN ('PROCESSED', 'INVALID', 'SUSPENDED') AND ($1 = '' OR status = $1) AND created_at < $2 AND ($3 = '' OR user_id = $3)
+	AND order_number >= $4 AND ($5 = 0 OR order_number <= $5) AND id > $6
+	ORDER BY id LIMIT $7`
+
+// RequeueOrders scans non-final orders matching filter in batches and sends them to the processing queue restoring
+// their persisted retry state, orders already present in the queue are counted as matched but not requeued.
+func (s *Storage) RequeueOrders(ctx context.Context, filter modelstorage.RequeueFilter) (*modeldto.RequeueReport, error) {
+	selectStmt, err := s.DB.PrepareContext(ctx, requeueQuery)
+	if err != nil {
+		return nil, &storageErrors.StatementPSQLError{Err: err}
+	}
+	defer selectStmt.Close()
+	chanOk := make(chan modeldto.RequeueReport)
+	chanEr := make(chan error)
+	go func() {
+		var report modeldto.RequeueReport
+		var lastID uint
+		for {
+			batch, err := s.getRequeueBatch(ctx, selectStmt, filter, lastID)
+			if err != nil {
+				chanEr <- err
+				return
+			}
+			for _, order := range batch {
+				if ctx.Err() != nil {
+					chanEr <- &storageErrors.ContextTimeoutExceededError{Err: ctx.Err()}
+					return
+				}
+				report.Matched++
+				if s.isQueued(order.OrderNumber) {
+					continue
+				}
+				s.SendToQueue(stalledQueueEntry(order))
+				report.Requeued++
+			}
+			if len(batch) < requeueBatchSize {
+				break
+			}
+			lastID = batch[len(batch)-1].ID
+		}
+		chanOk <- report
+	}()
+	select {
+	case <-ctx.Done():
+		s.log.Error().Err(ctx.Err()).Msg("requeueing orders failed")
+		return nil, &storageErrors.ContextTimeoutExceededError{Err: ctx.Err()}
+	case methodErr := <-chanEr:
+		s.log.Error().Err(methodErr).Msg("requeueing orders failed")
+		return nil, methodErr
+	case report := <-chanOk:
+		s.log.Info().Msg(fmt.Sprintf("requeueing orders done, %v matched, %v requeued", report.Matched, report.Requeued))
+		s.metrics.Counter("gophermart_orders_bulk_requeued_total").Add(uint64(report.Requeued))
+		return &report, nil
+	}
+}
+
+// getRequeueBatch reads a batch of orders matching filter with ids above lastID.
+func (s *Storage) getRequeueBatch(ctx context.Context, selectStmt *sql.Stmt, filter modelstorage.RequeueFilter, lastID uint) ([]modelstorage.OrderStorageEntry, error) {
+	rows, err := selectStmt.QueryContext(ctx, filter.Status, filter.CreatedTo, filter.UserID, filter.NumberFrom, filter.NumberTo, lastID, requeueBatchSize)
+	if err != nil {
+		return nil, &storageErrors.ExecutionPSQLError{Err: err}
+	}
+	defer rows.Close()
+	var batch []modelstorage.OrderStorageEntry
+	for rows.Next() {
+		var order modelstorage.OrderStorageEntry
+		err = rows.Scan(&order.ID, &order.UserID, &order.OrderNumber, &order.Status, &order.Accrual, &order.CreatedAt, &order.TenantID,
+			&order.Retry.RetryCount, &order.Retry.InvalidCount, &order.Retry.PollStep, &order.Retry.LastCheckedAt, &order.Retry.RetryAfterMs)
+		if err != nil {
+			return nil, &storageErrors.ScanningPSQLError{Err: err}
+		}
+		batch = append(batch, order)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, &storageErrors.ScanningPSQLError{Err: err}
+	}
+	return batch, nil
+}
@@ -0,0 +1,113 @@
+// Package inpsql provides functionality for operating a relational DB.
+
+package inpsql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	storageErrors "github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/errors"
+	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/modelstorage"
+)
+
+// SaveAccrualResponse stores the raw accrual system response received for an order replacing the previous one.
+func (s *Storage) SaveAccrualResponse(ctx context.Context, response modelstorage.AccrualResponseStorageEntry) error {
+	insertStmt, err := s.DB.PrepareContext(ctx, `INSERT INTO accrual_responses (order_number, tenant_id, status_code, body, received_at) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (order_number) DO UPDATE SET status_code = EXCLUDED.status_code, body = EXCLUDED.body, received_at = EXCLUDED.received_at`)
+	if err != nil {
+		return &storageErrors.StatementPSQLError{Err: err}
+	}
+	defer insertStmt.Close()
+	chanOk := make(chan bool)
+	chanEr := make(chan error)
+	go func() {
+		_, err := insertStmt.ExecContext(ctx, response.OrderNumber, response.TenantID, response.StatusCode, response.Body, response.ReceivedAt)
+		if err != nil {
+			chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
+			return
+		}
+		chanOk <- true
+	}()
+	select {
+	case <-ctx.Done():
+		s.log.Error().Err(ctx.Err()).Msg(fmt.Sprintf("saving accrual response failed for order %v", response.OrderNumber))
+		return &storageErrors.ContextTimeoutExceededError{Err: ctx.Err()}
+	case methodErr := <-chanEr:
+		s.log.Error().Err(methodErr).Msg(fmt.Sprintf("saving accrual response failed for order %v", response.OrderNumber))
+		return methodErr
+	case <-chanOk:
+		return nil
+	}
+}
+
+// GetAccrualResponse retrieves the latest raw accrual system response received for an order of any tenant.
+func (s *Storage) GetAccrualResponse(ctx context.Context, orderNumber int) (*modelstorage.AccrualResponseStorageEntry, error) {
+	selectStmt, err := s.DB.PrepareContext(ctx, "SELECT order_number, tenant_id, status_code, body, received_at FROM accrual_responses WHERE order_number = $1")
+	if err != nil {
+		return nil, &storageErrors.StatementPSQLError{Err: err}
+	}
+	defer selectStmt.Close()
+	chanOk := make(chan modelstorage.AccrualResponseStorageEntry)
+	chanEr := make(chan error)
+	go func() {
+		var queryOutput modelstorage.AccrualResponseStorageEntry
+		err := selectStmt.QueryRowContext(ctx, orderNumber).Scan(&queryOutput.OrderNumber, &queryOutput.TenantID, &queryOutput.StatusCode, &queryOutput.Body, &queryOutput.ReceivedAt)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				chanEr <- &storageErrors.NotFoundError{Err: err}
+				return
+			}
+			chanEr <- &storageErrors.ScanningPSQLError{Err: err}
+			return
+		}
+		chanOk <- queryOutput
+	}()
+	select {
+	case <-ctx.Done():
+		s.log.Error().Err(ctx.Err()).Msg(fmt.Sprintf("getting accrual response failed for order %v", orderNumber))
+		return nil, &storageErrors.ContextTimeoutExceededError{Err: ctx.Err()}
+	case methodErr := <-chanEr:
+		s.log.Error().Err(methodErr).Msg(fmt.Sprintf("getting accrual response failed for order %v", orderNumber))
+		return nil, methodErr
+	case response := <-chanOk:
+		s.log.Info().Msg(fmt.Sprintf("getting accrual response done for order %v", orderNumber))
+		return &response, nil
+	}
+}
+
+// GetAnyOrder retrieves an order of any tenant from DB.
+func (s *Storage) GetAnyOrder(ctx context.Context, orderNumber int) (*modelstorage.OrderStorageEntry, error) {
+	selectStmt, err := s.DB.PrepareContext(ctx, "SELECT id, user_id, order_number, status, accrual, created_at, tenant_id FROM orders WHERE order_number = $1")
+	if err != nil {
+		return nil, &storageErrors.StatementPSQLError{Err: err}
+	}
+	defer selectStmt.Close()
+	chanOk := make(chan modelstorage.OrderStorageEntry)
+	chanEr := make(chan error)
+	go func() {
+		var queryOutput modelstorage.OrderStorageEntry
+		err := selectStmt.QueryRowContext(ctx, orderNumber).Scan(&queryOutput.ID, &queryOutput.UserID, &queryOutput.OrderNumber, &queryOutput.Status, &queryOutput.Accrual, &queryOutput.CreatedAt, &queryOutput.TenantID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				chanEr <- &storageErrors.NotFoundError{Err: err}
+				return
+			}
+			chanEr <- &storageErrors.ScanningPSQLError{Err: err}
+			return
+		}
+		chanOk <- queryOutput
+	}()
+	select {
+	case <-ctx.Done():
+		s.log.Error().Err(ctx.Err()).Msg(fmt.Sprintf("getting order failed for order %v", orderNumber))
+		return nil, &storageErrors.ContextTimeoutExceededError{Err: ctx.Err()}
+	case methodErr := <-chanEr:
+		s.log.Error().Err(methodErr).Msg(fmt.Sprintf("getting order failed for order %v", orderNumber))
+		return nil, methodErr
+	case order := <-chanOk:
+		s.log.Info().Msg(fmt.Sprintf("getting order done for order %v", orderNumber))
+		return &order, nil
+	}
+}
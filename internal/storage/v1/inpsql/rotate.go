@@ -0,0 +1,113 @@
+// Package inpsql provides functionality for operating a relational DB.
+
+package inpsql
+
+import (
+	"context"
+	"database/sql"
+
+	storageErrors "github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/errors"
+	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/modelstorage"
+)
+
+// RotateUserKeys re-ciphers contact details which were not ciphered with the current key, hashes passwords which are
+// still ciphered and reseals logins along with their digests within a single transaction, it returns the number
+// of updated users. Empty contact details are kept as is, resealLogin returns its arguments unchanged if the login
+// needs no update.
+func RotateUserKeys(ctx context.Context, db *sql.DB, current func(msg string) bool, recipher func(msg string) (string, error), hashed func(password string) bool, hashPassword func(msg string) (string, error), resealLogin func(msg, loginHash string) (string, string, error)) (int, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, &storageErrors.ExecutionPSQLError{Err: err}
+	}
+	defer tx.Rollback()
+	rows, err := tx.QueryContext(ctx, "SELECT id, login, COALESCE(login_hash, ''), password, email, phone FROM users FOR UPDATE")
+	if err != nil {
+		return 0, &storageErrors.ExecutionPSQLError{Err: err}
+	}
+	type userCredentials struct {
+		id        uint
+		login     string
+		loginHash string
+		password  string
+		email     string
+		phone     string
+	}
+	var stale []userCredentials
+	for rows.Next() {
+		var row userCredentials
+		err = rows.Scan(&row.id, &row.login, &row.loginHash, &row.password, &row.email, &row.phone)
+		if err != nil {
+			rows.Close()
+			return 0, &storageErrors.ScanningPSQLError{Err: err}
+		}
+		login, loginHash, err := resealLogin(row.login, row.loginHash)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if login == row.login && loginHash == row.loginHash && hashed(row.password) && (row.email == "" || current(row.email)) && (row.phone == "" || current(row.phone)) {
+			continue
+		}
+		row.login, row.loginHash = login, loginHash
+		stale = append(stale, row)
+	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return 0, &storageErrors.ScanningPSQLError{Err: err}
+	}
+	for _, row := range stale {
+		password := row.password
+		if !hashed(password) {
+			password, err = hashPassword(password)
+			if err != nil {
+				return 0, err
+			}
+		}
+		email, phone := row.email, row.phone
+		if email != "" {
+			email, err = recipher(email)
+			if err != nil {
+				return 0, err
+			}
+		}
+		if phone != "" {
+			phone, err = recipher(phone)
+			if err != nil {
+				return 0, err
+			}
+		}
+		_, err = tx.ExecContext(ctx, "UPDATE users SET login = $1, login_hash = $2, password = $3, email = $4, phone = $5 WHERE id = $6", row.login, row.loginHash, password, email, phone, row.id)
+		if err != nil {
+			return 0, &storageErrors.ExecutionPSQLError{Err: err}
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		return 0, &storageErrors.ExecutionPSQLError{Err: err}
+	}
+	return len(stale), nil
+}
+
+// ListUserLogins retrieves ciphered logins of all users.
+func ListUserLogins(ctx context.Context, db *sql.DB) ([]modelstorage.UserStorageEntry, error) {
+	rows, err := db.QueryContext(ctx, "SELECT id, user_id, login, tenant_id FROM users ORDER BY id")
+	if err != nil {
+		return nil, &storageErrors.ExecutionPSQLError{Err: err}
+	}
+	defer rows.Close()
+	var queryOutput []modelstorage.UserStorageEntry
+	for rows.Next() {
+		var queryOutputRow modelstorage.UserStorageEntry
+		err = rows.Scan(&queryOutputRow.ID, &queryOutputRow.UserID, &queryOutputRow.Login, &queryOutputRow.TenantID)
+		if err != nil {
+			return nil, &storageErrors.ScanningPSQLError{Err: err}
+		}
+		queryOutput = append(queryOutput, queryOutputRow)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, &storageErrors.ScanningPSQLError{Err: err}
+	}
+	return queryOutput, nil
+}
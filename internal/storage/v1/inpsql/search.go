@@ -0,0 +1,67 @@
+// Package inpsql provides functionality for operating a relational DB.
+
+package inpsql
+
+import (
+	"context"
+
+	storageErrors "github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/errors"
+	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/modelstorage"
+)
+
+// SearchUsers returns a page of users of any tenant matching the search, deactivated ones included, ordered by
+// registration. Logins are matched by their digests or, for users stored before digests were introduced,
+// by their legacy ciphertexts.
+func (s *Storage) SearchUsers(ctx context.Context, search modelstorage.UserSearch) ([]modelstorage.UserStorageEntry, error) {
+	var hashes, legacy []string
+	for _, lookup := range search.Lookups {
+		hashes = append(hashes, lookup.Hash)
+		legacy = append(legacy, lookup.Legacy...)
+	}
+	selectStmt, err := s.DB.PrepareContext(ctx, `SELECT id, user_id, login, COALESCE(login_hash, ''), registered_at, tenant_id, deactivated_at IS NOT NULL
+		FROM users
+		WHERE login_hash = ANY($1) OR (login_hash IS NULL AND login = ANY($2)) OR user_id = $3
+			OR user_id IN (SELECT user_id FROM orders WHERE $4 <> 0 AND order_number = $4)
+		ORDER BY registered_at, id LIMIT $5 OFFSET $6`)
+	if err != nil {
+		return nil, &storageErrors.StatementPSQLError{Err: err}
+	}
+	defer selectStmt.Close()
+	chanOk := make(chan []modelstorage.UserStorageEntry)
+	chanEr := make(chan error)
+	go func() {
+		rows, err := selectStmt.QueryContext(ctx, hashes, legacy, search.UserID, search.OrderNumber, search.Limit, search.Offset)
+		if err != nil {
+			chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
+			return
+		}
+		defer rows.Close()
+		var queryOutput []modelstorage.UserStorageEntry
+		for rows.Next() {
+			var queryOutputRow modelstorage.UserStorageEntry
+			err = rows.Scan(&queryOutputRow.ID, &queryOutputRow.UserID, &queryOutputRow.Login, &queryOutputRow.LoginHash, &queryOutputRow.RegisteredAt, &queryOutputRow.TenantID, &queryOutputRow.Deactivated)
+			if err != nil {
+				chanEr <- &storageErrors.ScanningPSQLError{Err: err}
+				return
+			}
+			queryOutput = append(queryOutput, queryOutputRow)
+		}
+		err = rows.Err()
+		if err != nil {
+			chanEr <- &storageErrors.ScanningPSQLError{Err: err}
+			return
+		}
+		chanOk <- queryOutput
+	}()
+	select {
+	case <-ctx.Done():
+		s.log.Error().Err(ctx.Err()).Msg("searching users failed")
+		return nil, &storageErrors.ContextTimeoutExceededError{Err: ctx.Err()}
+	case methodErr := <-chanEr:
+		s.log.Error().Err(methodErr).Msg("searching users failed")
+		return nil, methodErr
+	case users := <-chanOk:
+		s.log.Info().Msg("searching users done")
+		return users, nil
+	}
+}
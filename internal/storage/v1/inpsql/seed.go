@@ -0,0 +1,58 @@
+// Package inpsql provides functionality for operating a relational DB.
+
+package inpsql
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	storageErrors "github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/errors"
+	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/modelstorage"
+)
+
+// SeedUsers stores users together with their orders and withdrawals within a single transaction,
+// balances are computed from the stored accruals and processed withdrawals.
+func SeedUsers(ctx context.Context, db *sql.DB, users []modelstorage.UserStorageEntry, orders []modelstorage.OrderStorageEntry, withdrawals []modelstorage.WithdrawalStorageEntry) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return &storageErrors.ExecutionPSQLError{Err: err}
+	}
+	defer tx.Rollback()
+	for _, user := range users {
+		_, err = tx.ExecContext(ctx, "INSERT INTO users (user_id, login, login_hash, password, registered_at, tenant_id) VALUES ($1, $2, $3, $4, $5, $6)", user.UserID, user.Login, user.LoginHash, user.Password, user.RegisteredAt, user.TenantID)
+		if err != nil {
+			return &storageErrors.ExecutionPSQLError{Err: err}
+		}
+	}
+	for _, order := range orders {
+		_, err = tx.ExecContext(ctx, "INSERT INTO orders (user_id, order_number, status, accrual, created_at, tenant_id) VALUES ($1, $2, $3, $4, $5, $6)", order.UserID, order.OrderNumber, order.Status, order.Accrual, order.CreatedAt, order.TenantID)
+		if err != nil {
+			return &storageErrors.ExecutionPSQLError{Err: err}
+		}
+	}
+	for _, withdrawal := range withdrawals {
+		_, err = tx.ExecContext(ctx, "INSERT INTO withdrawals (user_id, order_number, amount, processed_at, status, tenant_id) VALUES ($1, $2, $3, $4, $5, $6)", withdrawal.UserID, withdrawal.OrderNumber, withdrawal.Amount, withdrawal.ProcessedAt, withdrawal.Status, withdrawal.TenantID)
+		if err != nil {
+			return &storageErrors.ExecutionPSQLError{Err: err}
+		}
+	}
+	for _, user := range users {
+		_, err = tx.ExecContext(ctx, `INSERT INTO balance (user_id, amount, tenant_id) VALUES ($1,
+			COALESCE((SELECT SUM(accrual) FROM orders WHERE user_id = $1), 0) -
+			COALESCE((SELECT SUM(amount) FROM withdrawals WHERE user_id = $1 AND status = $2), 0), $3)`, user.UserID, modelstorage.WithdrawalProcessed, user.TenantID)
+		if err != nil {
+			return &storageErrors.ExecutionPSQLError{Err: err}
+		}
+		_, err = tx.ExecContext(ctx, `INSERT INTO balance_events (user_id, tenant_id, kind, amount, reference, created_at)
+			SELECT user_id, tenant_id, $2, amount, 'seed', $3 FROM balance WHERE user_id = $1 AND amount <> 0`, user.UserID, modelstorage.EventOpening, time.Now())
+		if err != nil {
+			return &storageErrors.ExecutionPSQLError{Err: err}
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		return &storageErrors.ExecutionPSQLError{Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,277 @@
+// Package inpsql provides functionality for operating a relational DB.
+
+package inpsql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	storageErrors "github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/errors"
+	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/modelstorage"
+	"github.com/danilovkiri/dk-go-gophermart/internal/tenant"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgerrcode"
+)
+
+// sessionTouchInterval defines how often the activity of a session is written.
+const sessionTouchInterval = time.Minute
+
+// AddSession records an issued token, it returns true if the user has earlier sessions and none of them share its fingerprint.
+func (s *Storage) AddSession(ctx context.Context, session modelstorage.SessionStorageEntry) (bool, error) {
+	tenantID := tenant.FromContext(ctx)
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return false, &storageErrors.ExecutionPSQLError{Err: err}
+	}
+	defer tx.Rollback()
+	chanOk := make(chan bool)
+	chanEr := make(chan error)
+	go func() {
+		var total, matched int
+		err := tx.QueryRowContext(ctx, "SELECT COUNT(*), COUNT(*) FILTER (WHERE fingerprint = $3) FROM sessions WHERE tenant_id = $1 AND user_id = $2", tenantID, session.UserID, session.Fingerprint).Scan(&total, &matched)
+		if err != nil {
+			chanEr <- &storageErrors.ScanningPSQLError{Err: err}
+			return
+		}
+		now := time.Now()
+		var refreshExpiresAt sql.NullTime
+		if session.RefreshHash != "" {
+			refreshExpiresAt = sql.NullTime{Time: session.RefreshExpiresAt, Valid: true}
+		}
+		_, err = tx.ExecContext(ctx, "INSERT INTO sessions (user_id, tenant_id, user_agent, ip, fingerprint, session_id, created_at, last_seen, refresh_hash, refresh_expires_at) VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, $7, NULLIF($8, ''), $9)",
+			session.UserID, tenantID, session.UserAgent, session.IP, session.Fingerprint, session.SessionID, now, session.RefreshHash, refreshExpiresAt)
+		if err != nil {
+			if err, ok := err.(*pgconn.PgError); ok && err.Code == pgerrcode.ForeignKeyViolation {
+				chanEr <- &storageErrors.UnknownUserError{Err: err, ID: session.UserID}
+				return
+			}
+			chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
+			return
+		}
+		chanOk <- total > 0 && matched == 0
+	}()
+	select {
+	case <-ctx.Done():
+		s.log.Error().Err(ctx.Err()).Msg(fmt.Sprintf("adding session failed for user %s", session.UserID))
+		return false, &storageErrors.ContextTimeoutExceededError{Err: ctx.Err()}
+	case methodErr := <-chanEr:
+		s.log.Error().Err(methodErr).Msg(fmt.Sprintf("adding session failed for user %s", session.UserID))
+		return false, methodErr
+	case newDevice := <-chanOk:
+		s.log.Info().Msg(fmt.Sprintf("adding session done for user %s", session.UserID))
+		err = tx.Commit()
+		if err != nil {
+			return false, &storageErrors.ExecutionPSQLError{Err: err}
+		}
+		return newDevice, nil
+	}
+}
+
+// GetSessions retrieves a user's history of issued tokens from DB.
+func (s *Storage) GetSessions(ctx context.Context, userID string) ([]modelstorage.SessionStorageEntry, error) {
+	selectStmt, err := s.DB.PrepareContext(ctx, "SELECT id, user_id, user_agent, ip, fingerprint, COALESCE(session_id, ''), created_at, COALESCE(last_seen, created_at) FROM sessions WHERE tenant_id = $1 AND user_id = $2 ORDER BY created_at DESC")
+	if err != nil {
+		return nil, &storageErrors.StatementPSQLError{Err: err}
+	}
+	defer selectStmt.Close()
+	chanOk := make(chan []modelstorage.SessionStorageEntry)
+	chanEr := make(chan error)
+	go func() {
+		rows, err := selectStmt.QueryContext(ctx, tenant.FromContext(ctx), userID)
+		if err != nil {
+			chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
+			return
+		}
+		defer rows.Close()
+		var queryOutput []modelstorage.SessionStorageEntry
+		for rows.Next() {
+			var queryOutputRow modelstorage.SessionStorageEntry
+			err = rows.Scan(&queryOutputRow.ID, &queryOutputRow.UserID, &queryOutputRow.UserAgent, &queryOutputRow.IP, &queryOutputRow.Fingerprint, &queryOutputRow.SessionID, &queryOutputRow.CreatedAt, &queryOutputRow.LastSeen)
+			if err != nil {
+				chanEr <- &storageErrors.ScanningPSQLError{Err: err}
+				return
+			}
+			queryOutput = append(queryOutput, queryOutputRow)
+		}
+		err = rows.Err()
+		if err != nil {
+			chanEr <- &storageErrors.ScanningPSQLError{Err: err}
+			return
+		}
+		chanOk <- queryOutput
+	}()
+	select {
+	case <-ctx.Done():
+		s.log.Error().Err(ctx.Err()).Msg("getting sessions failed")
+		return nil, &storageErrors.ContextTimeoutExceededError{Err: ctx.Err()}
+	case methodErr := <-chanEr:
+		s.log.Error().Err(methodErr).Msg("getting sessions failed")
+		return nil, methodErr
+	case sessions := <-chanOk:
+		s.log.Info().Msg("getting sessions done")
+		return sessions, nil
+	}
+}
+
+// TouchSession verifies that a session is not revoked, neither older than lifetime nor inactive for idleTimeout
+// and records the activity, zero durations disable the corresponding check. Activity is written at most once per
+// sessionTouchInterval to spare a write on every request.
+func (s *Storage) TouchSession(ctx context.Context, userID, sessionID string, idleTimeout, lifetime time.Duration) error {
+	selectStmt, err := s.DB.PrepareContext(ctx, "SELECT created_at, COALESCE(last_seen, created_at), revoked_at IS NOT NULL FROM sessions WHERE session_id = $1 AND user_id = $2 AND tenant_id = $3")
+	if err != nil {
+		return &storageErrors.StatementPSQLError{Err: err}
+	}
+	defer selectStmt.Close()
+	chanOk := make(chan bool)
+	chanEr := make(chan error)
+	go func() {
+		var createdAt, lastSeen time.Time
+		var revoked bool
+		err := selectStmt.QueryRowContext(ctx, sessionID, userID, tenant.FromContext(ctx)).Scan(&createdAt, &lastSeen, &revoked)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				chanEr <- &storageErrors.NotFoundError{Err: err}
+				return
+			}
+			chanEr <- &storageErrors.ScanningPSQLError{Err: err}
+			return
+		}
+		if revoked {
+			chanEr <- &storageErrors.SessionExpiredError{ID: sessionID, Reason: "revocation"}
+			return
+		}
+		now := time.Now()
+		if lifetime > 0 && now.Sub(createdAt) >= lifetime {
+			chanEr <- &storageErrors.SessionExpiredError{ID: sessionID, Reason: "lifetime"}
+			return
+		}
+		if idleTimeout > 0 && now.Sub(lastSeen) >= idleTimeout {
+			chanEr <- &storageErrors.SessionExpiredError{ID: sessionID, Reason: "inactivity"}
+			return
+		}
+		if now.Sub(lastSeen) >= sessionTouchInterval {
+			_, err = s.DB.ExecContext(ctx, "UPDATE sessions SET last_seen = $1 WHERE session_id = $2 AND last_seen < $1", now, sessionID)
+			if err != nil {
+				chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
+				return
+			}
+		}
+		chanOk <- true
+	}()
+	select {
+	case <-ctx.Done():
+		s.log.Error().Err(ctx.Err()).Msg(fmt.Sprintf("touching session failed for user %s", userID))
+		return &storageErrors.ContextTimeoutExceededError{Err: ctx.Err()}
+	case methodErr := <-chanEr:
+		s.log.Error().Err(methodErr).Msg(fmt.Sprintf("touching session failed for user %s", userID))
+		return methodErr
+	case <-chanOk:
+		return nil
+	}
+}
+
+// RotateRefreshToken exchanges the refresh token with the given digest for a new one expiring at expiresAt and
+// records the session activity, so that a refresh token can be used only once. Refresh tokens of revoked sessions,
+// expired ones and those of sessions older than lifetime are rejected with SessionExpiredError, a zero lifetime
+// disables the latter check.
+func (s *Storage) RotateRefreshToken(ctx context.Context, refreshHash, newRefreshHash string, expiresAt time.Time, lifetime time.Duration) (*modelstorage.SessionStorageEntry, error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, &storageErrors.ExecutionPSQLError{Err: err}
+	}
+	defer tx.Rollback()
+	chanOk := make(chan modelstorage.SessionStorageEntry)
+	chanEr := make(chan error)
+	go func() {
+		var session modelstorage.SessionStorageEntry
+		var revoked bool
+		err := tx.QueryRowContext(ctx, "SELECT id, user_id, session_id, created_at, refresh_expires_at, revoked_at IS NOT NULL FROM sessions WHERE refresh_hash = $1 AND tenant_id = $2 FOR UPDATE",
+			refreshHash, tenant.FromContext(ctx)).Scan(&session.ID, &session.UserID, &session.SessionID, &session.CreatedAt, &session.RefreshExpiresAt, &revoked)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				chanEr <- &storageErrors.NotFoundError{Err: err}
+				return
+			}
+			chanEr <- &storageErrors.ScanningPSQLError{Err: err}
+			return
+		}
+		now := time.Now()
+		switch {
+		case revoked:
+			chanEr <- &storageErrors.SessionExpiredError{ID: session.SessionID, Reason: "revocation"}
+			return
+		case !session.RefreshExpiresAt.After(now):
+			chanEr <- &storageErrors.SessionExpiredError{ID: session.SessionID, Reason: "refresh token expiration"}
+			return
+		case lifetime > 0 && now.Sub(session.CreatedAt) >= lifetime:
+			chanEr <- &storageErrors.SessionExpiredError{ID: session.SessionID, Reason: "lifetime"}
+			return
+		}
+		_, err = tx.ExecContext(ctx, "UPDATE sessions SET refresh_hash = $1, refresh_expires_at = $2, last_seen = $3 WHERE id = $4", newRefreshHash, expiresAt, now, session.ID)
+		if err != nil {
+			chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
+			return
+		}
+		session.RefreshHash = newRefreshHash
+		session.RefreshExpiresAt = expiresAt
+		session.LastSeen = now
+		chanOk <- session
+	}()
+	select {
+	case <-ctx.Done():
+		s.log.Error().Err(ctx.Err()).Msg("rotating refresh token failed")
+		return nil, &storageErrors.ContextTimeoutExceededError{Err: ctx.Err()}
+	case methodErr := <-chanEr:
+		s.log.Error().Err(methodErr).Msg("rotating refresh token failed")
+		return nil, methodErr
+	case session := <-chanOk:
+		s.log.Info().Msg(fmt.Sprintf("rotating refresh token done for user %s", session.UserID))
+		err = tx.Commit()
+		if err != nil {
+			return nil, &storageErrors.ExecutionPSQLError{Err: err}
+		}
+		return &session, nil
+	}
+}
+
+// RevokeSession revokes a session along with its refresh token, access tokens of the session are rejected from then on.
+func (s *Storage) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	updateStmt, err := s.DB.PrepareContext(ctx, "UPDATE sessions SET revoked_at = $1, refresh_hash = NULL WHERE session_id = $2 AND user_id = $3 AND tenant_id = $4 AND revoked_at IS NULL")
+	if err != nil {
+		return &storageErrors.StatementPSQLError{Err: err}
+	}
+	defer updateStmt.Close()
+	chanOk := make(chan bool)
+	chanEr := make(chan error)
+	go func() {
+		result, err := updateStmt.ExecContext(ctx, time.Now(), sessionID, userID, tenant.FromContext(ctx))
+		if err != nil {
+			chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
+			return
+		}
+		revoked, err := result.RowsAffected()
+		if err != nil {
+			chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
+			return
+		}
+		if revoked == 0 {
+			chanEr <- &storageErrors.NotFoundError{Err: nil}
+			return
+		}
+		chanOk <- true
+	}()
+	select {
+	case <-ctx.Done():
+		s.log.Error().Err(ctx.Err()).Msg(fmt.Sprintf("revoking session failed for user %s", userID))
+		return &storageErrors.ContextTimeoutExceededError{Err: ctx.Err()}
+	case methodErr := <-chanEr:
+		s.log.Error().Err(methodErr).Msg(fmt.Sprintf("revoking session failed for user %s", userID))
+		return methodErr
+	case <-chanOk:
+		s.log.Info().Msg(fmt.Sprintf("revoking session done for user %s", userID))
+		return nil
+	}
+}
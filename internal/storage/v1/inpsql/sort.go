@@ -0,0 +1,49 @@
+// Package inpsql provides functionality for operating a relational DB.
+
+package inpsql
+
+import (
+	"fmt"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/models/modeldto"
+	storageErrors "github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/errors"
+)
+
+// Whitelisted sorting fields mapped to their columns, no other input is ever interpolated into ORDER BY clauses.
+var (
+	orderSortColumns = map[string]string{
+		"uploaded_at": "created_at",
+		"accrual":     "accrual",
+		"status":      "status",
+	}
+	withdrawalSortColumns = map[string]string{
+		"processed_at": "processed_at",
+		"sum":          "amount",
+		"status":       "status",
+	}
+	sortDirections = map[string]string{
+		"asc":  "ASC",
+		"desc": "DESC",
+	}
+)
+
+// orderByClause builds an ORDER BY clause from whitelisted sorting options, ties are broken by insertion order.
+func orderByClause(sort modeldto.Sort, columns map[string]string, defaultField string) (string, error) {
+	field := sort.Field
+	if field == "" {
+		field = defaultField
+	}
+	column, ok := columns[field]
+	if !ok {
+		return "", &storageErrors.IllegalSortError{Msg: fmt.Sprintf("unsupported sort field %q", sort.Field)}
+	}
+	order := sort.Order
+	if order == "" {
+		order = "asc"
+	}
+	direction, ok := sortDirections[order]
+	if !ok {
+		return "", &storageErrors.IllegalSortError{Msg: fmt.Sprintf("unsupported sort order %q", sort.Order)}
+	}
+	return fmt.Sprintf(" ORDER BY %s %s, id %s", column, direction, direction), nil
+}
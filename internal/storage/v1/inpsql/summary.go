@@ -0,0 +1,177 @@
+// Package inpsql provides functionality for operating a relational DB.
+
+package inpsql
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/models/modeldto"
+	storageErrors "github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/errors"
+	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/modelstorage"
+)
+
+// GetSummary collects operational counters, points issued and withdrawn are aggregated over each of the given windows.
+func (s *Storage) GetSummary(ctx context.Context, windows []time.Duration) (*modeldto.AdminSummary, error) {
+	chanOk := make(chan *modeldto.AdminSummary)
+	chanEr := make(chan error)
+	go func() {
+		now := time.Now()
+		summary := modeldto.AdminSummary{
+			GeneratedAt: now.Format(time.RFC3339),
+			Orders:      make(map[string]int),
+			Channels:    make(map[string]int),
+			Queue: modeldto.QueueSummary{
+				Orders:      s.queuedCount(),
+				Withdrawals: len(s.WithdrawalQueue),
+			},
+			Windows: []modeldto.WindowSummary{},
+		}
+		err := s.DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM users").Scan(&summary.Users)
+		if err != nil {
+			chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
+			return
+		}
+		rows, err := s.DB.QueryContext(ctx, "SELECT status, COUNT(*) FROM orders GROUP BY status")
+		if err != nil {
+			chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
+			return
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var status string
+			var count int
+			err = rows.Scan(&status, &count)
+			if err != nil {
+				chanEr <- &storageErrors.ScanningPSQLError{Err: err}
+				return
+			}
+			summary.Orders[status] = count
+		}
+		err = rows.Err()
+		if err != nil {
+			chanEr <- &storageErrors.ScanningPSQLError{Err: err}
+			return
+		}
+		channelRows, err := s.DB.QueryContext(ctx, "SELECT channel, COUNT(*) FROM orders GROUP BY channel")
+		if err != nil {
+			chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
+			return
+		}
+		defer channelRows.Close()
+		for channelRows.Next() {
+			var channel string
+			var count int
+			err = channelRows.Scan(&channel, &count)
+			if err != nil {
+				chanEr <- &storageErrors.ScanningPSQLError{Err: err}
+				return
+			}
+			summary.Channels[channel] = count
+		}
+		err = channelRows.Err()
+		if err != nil {
+			chanEr <- &storageErrors.ScanningPSQLError{Err: err}
+			return
+		}
+		for _, window := range windows {
+			since := now.Add(-window)
+			var issued, withdrawn sql.NullFloat64
+			// orders carry no processing timestamp, accruals are attributed to the order creation time
+			err = s.DB.QueryRowContext(ctx, "SELECT SUM(accrual) FROM orders WHERE status = 'PROCESSED' AND created_at >= $1", since).Scan(&issued)
+			if err != nil {
+				chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
+				return
+			}
+			err = s.DB.QueryRowContext(ctx, "SELECT SUM(amount) FROM withdrawals WHERE status = 'PROCESSED' AND processed_at >= $1", since).Scan(&withdrawn)
+			if err != nil {
+				chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
+				return
+			}
+			summary.Windows = append(summary.Windows, modeldto.WindowSummary{
+				Window:    window.String(),
+				Issued:    issued.Float64,
+				Withdrawn: withdrawn.Float64,
+			})
+		}
+		chanOk <- &summary
+	}()
+	select {
+	case <-ctx.Done():
+		s.log.Error().Err(ctx.Err()).Msg("getting summary failed")
+		return nil, &storageErrors.ContextTimeoutExceededError{Err: ctx.Err()}
+	case methodErr := <-chanEr:
+		s.log.Error().Err(methodErr).Msg("getting summary failed")
+		return nil, methodErr
+	case summary := <-chanOk:
+		s.log.Info().Msg("getting summary done")
+		return summary, nil
+	}
+}
+
+// GetReport aggregates activity between from and to: registrations, orders uploaded by their current status,
+// abandoned orders among them, i.e. still not final, and points credited as accruals and withdrawn.
+func (s *Storage) GetReport(ctx context.Context, from, to time.Time) (*modeldto.AdminReport, error) {
+	chanOk := make(chan *modeldto.AdminReport)
+	chanEr := make(chan error)
+	go func() {
+		report := modeldto.AdminReport{
+			From:   from.Format(time.RFC3339),
+			To:     to.Format(time.RFC3339),
+			Orders: make(map[string]int),
+		}
+		err := s.DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM users WHERE registered_at >= $1 AND registered_at < $2", from, to).Scan(&report.Registrations)
+		if err != nil {
+			chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
+			return
+		}
+		rows, err := s.DB.QueryContext(ctx, "SELECT status, COUNT(*) FROM orders WHERE created_at >= $1 AND created_at < $2 GROUP BY status", from, to)
+		if err != nil {
+			chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
+			return
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var status string
+			var count int
+			err = rows.Scan(&status, &count)
+			if err != nil {
+				chanEr <- &storageErrors.ScanningPSQLError{Err: err}
+				return
+			}
+			report.Orders[status] = count
+		}
+		err = rows.Err()
+		if err != nil {
+			chanEr <- &storageErrors.ScanningPSQLError{Err: err}
+			return
+		}
+		report.Abandoned = report.Orders["NEW"] + report.Orders["PROCESSING"]
+		var accrued, withdrawn sql.NullFloat64
+		err = s.DB.QueryRowContext(ctx, "SELECT SUM(amount) FROM balance_events WHERE kind = $1 AND created_at >= $2 AND created_at < $3", modelstorage.EventAccrualCredited, from, to).Scan(&accrued)
+		if err != nil {
+			chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
+			return
+		}
+		err = s.DB.QueryRowContext(ctx, "SELECT SUM(amount) FROM withdrawals WHERE status = 'PROCESSED' AND processed_at >= $1 AND processed_at < $2", from, to).Scan(&withdrawn)
+		if err != nil {
+			chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
+			return
+		}
+		report.Accrued = accrued.Float64
+		report.Withdrawn = withdrawn.Float64
+		chanOk <- &report
+	}()
+	select {
+	case <-ctx.Done():
+		s.log.Error().Err(ctx.Err()).Msg("getting report failed")
+		return nil, &storageErrors.ContextTimeoutExceededError{Err: ctx.Err()}
+	case methodErr := <-chanEr:
+		s.log.Error().Err(methodErr).Msg("getting report failed")
+		return nil, methodErr
+	case report := <-chanOk:
+		s.log.Info().Msg("getting report done")
+		return report, nil
+	}
+}
@@ -0,0 +1,199 @@
+// Package inpsql provides functionality for operating a relational DB.
+
+package inpsql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/models/modeldto"
+	storageErrors "github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/errors"
+	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/modelstorage"
+)
+
+// Audit log actions of suspended accrual reviews.
+const (
+	AuditAccrualApproved = "accrual_approved"
+	AuditAccrualRejected = "accrual_rejected"
+)
+
+// exceedsAccrualCaps returns the cap an accrual credited to a user within the transaction would exceed or an empty
+// string if it is within both caps. The daily cap covers accruals credited within the last 24 hours, the user row
+// is locked so that concurrent accruals of the same user are checked one after another.
+func (s *Storage) exceedsAccrualCaps(ctx context.Context, tx *sql.Tx, userID string, accrual float64) (string, error) {
+	if s.cfg.AccrualOrderCap > 0 && accrual > s.cfg.AccrualOrderCap {
+		return modelstorage.CapOrder, nil
+	}
+	if s.cfg.AccrualDailyCap <= 0 {
+		return "", nil
+	}
+	_, err := tx.ExecContext(ctx, "SELECT 1 FROM users WHERE user_id = $1 FOR UPDATE", userID)
+	if err != nil {
+		return "", &storageErrors.ExecutionPSQLError{Err: err}
+	}
+	var credited float64
+	err = tx.QueryRowContext(ctx, "SELECT COALESCE(SUM(amount), 0) FROM balance_events WHERE user_id = $1 AND kind = $2 AND created_at > $3",
+		userID, modelstorage.EventAccrualCredited, time.Now().Add(-24*time.Hour)).Scan(&credited)
+	if err != nil {
+		return "", &storageErrors.ScanningPSQLError{Err: err}
+	}
+	if credited+accrual > s.cfg.AccrualDailyCap {
+		return modelstorage.CapDaily, nil
+	}
+	return "", nil
+}
+
+// GetSuspendedOrders retrieves orders of all tenants awaiting accrual approval, the oldest first.
+func (s *Storage) GetSuspendedOrders(ctx context.Context) ([]modelstorage.OrderStorageEntry, error) {
+	selectStmt, err := s.DB.PrepareContext(ctx, "SELECT id, user_id, order_number, status, accrual, created_at, tenant_id FROM orders WHERE status = $1 ORDER BY created_at")
+	if err != nil {
+		return nil, &storageErrors.StatementPSQLError{Err: err}
+	}
+	defer selectStmt.Close()
+	chanOk := make(chan []modelstorage.OrderStorageEntry)
+	chanEr := make(chan error)
+	go func() {
+		rows, err := selectStmt.QueryContext(ctx, modelstorage.OrderSuspended)
+		if err != nil {
+			chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
+			return
+		}
+		defer rows.Close()
+		var queryOutput []modelstorage.OrderStorageEntry
+		for rows.Next() {
+			var queryOutputRow modelstorage.OrderStorageEntry
+			err = rows.Scan(&queryOutputRow.ID, &queryOutputRow.UserID, &queryOutputRow.OrderNumber, &queryOutputRow.Status, &queryOutputRow.Accrual, &queryOutputRow.CreatedAt, &queryOutputRow.TenantID)
+			if err != nil {
+				chanEr <- &storageErrors.ScanningPSQLError{Err: err}
+				return
+			}
+			queryOutput = append(queryOutput, queryOutputRow)
+		}
+		err = rows.Err()
+		if err != nil {
+			chanEr <- &storageErrors.ScanningPSQLError{Err: err}
+			return
+		}
+		chanOk <- queryOutput
+	}()
+	select {
+	case <-ctx.Done():
+		s.log.Error().Err(ctx.Err()).Msg("getting suspended orders failed")
+		return nil, &storageErrors.ContextTimeoutExceededError{Err: ctx.Err()}
+	case methodErr := <-chanEr:
+		s.log.Error().Err(methodErr).Msg("getting suspended orders failed")
+		return nil, methodErr
+	case orders := <-chanOk:
+		s.log.Info().Msg("getting suspended orders done")
+		return orders, nil
+	}
+}
+
+// ReviewSuspendedOrder resolves a SUSPENDED order within a single transaction: an approved order becomes PROCESSED
+// and its accrual is credited to the owner, a rejected one becomes INVALID without any accrual. The decision is
+// recorded to the order status history and the audit log of the owner.
+func (s *Storage) ReviewSuspendedOrder(ctx context.Context, orderNumber int, approve bool) (*modelstorage.OrderStorageEntry, error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, &storageErrors.ExecutionPSQLError{Err: err}
+	}
+	defer tx.Rollback()
+	status, source, action := "INVALID", modelstorage.SourceAdminRejection, AuditAccrualRejected
+	if approve {
+		status, source, action = "PROCESSED", modelstorage.SourceAdminApproval, AuditAccrualApproved
+	}
+	reference := strconv.Itoa(orderNumber)
+	chanOk := make(chan modelstorage.OrderStorageEntry)
+	chanEr := make(chan error)
+	var alerts []modeldto.Notification
+	go func() {
+		var order modelstorage.OrderStorageEntry
+		err := tx.QueryRowContext(ctx, "SELECT user_id, order_number, status, accrual, tenant_id FROM orders WHERE order_number = $1 FOR UPDATE",
+			orderNumber).Scan(&order.UserID, &order.OrderNumber, &order.Status, &order.Accrual, &order.TenantID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				chanEr <- &storageErrors.NotFoundError{Err: err}
+				return
+			}
+			chanEr <- &storageErrors.ScanningPSQLError{Err: err}
+			return
+		}
+		if order.Status != modelstorage.OrderSuspended {
+			chanEr <- &storageErrors.OrderNotSuspendedError{ID: reference, Status: order.Status}
+			return
+		}
+		details := map[string]interface{}{"order": reference, "accrual": order.Accrual}
+		if !approve {
+			order.Accrual = 0
+		}
+		now := time.Now()
+		_, err = tx.ExecContext(ctx, "UPDATE orders SET status = $1, accrual = $2 WHERE order_number = $3", status, order.Accrual, orderNumber)
+		if err != nil {
+			chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
+			return
+		}
+		_, err = tx.ExecContext(ctx, "INSERT INTO order_status_history (order_number, tenant_id, from_status, to_status, source, changed_at) VALUES ($1, $2, $3, $4, $5, $6)",
+			orderNumber, order.TenantID, order.Status, status, source, now)
+		if err != nil {
+			chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
+			return
+		}
+		order.Status = status
+		if order.Accrual > 0 {
+			err = s.adjustBalance(ctx, tx, order.UserID, order.TenantID, order.Accrual)
+			if err != nil {
+				chanEr <- err
+				return
+			}
+			err = addBalanceEvent(ctx, tx, order.UserID, modelstorage.EventAccrualCredited, order.Accrual, reference)
+			if err != nil {
+				chanEr <- err
+				return
+			}
+			alerts, err = balanceAlerts(ctx, tx, order.UserID, order.Accrual, reference)
+			if err != nil {
+				chanEr <- err
+				return
+			}
+		}
+		err = addOutboxEvent(ctx, tx, order.TenantID, modelstorage.OutboxOrderProcessed, map[string]interface{}{"user_id": order.UserID, "order": reference, "status": status, "accrual": order.Accrual})
+		if err != nil {
+			chanEr <- err
+			return
+		}
+		err = addAuditEntry(ctx, tx, order.UserID, order.TenantID, action, details)
+		if err != nil {
+			chanEr <- err
+			return
+		}
+		chanOk <- order
+	}()
+	select {
+	case <-ctx.Done():
+		s.log.Error().Err(ctx.Err()).Msg(fmt.Sprintf("reviewing suspended order failed for order %v", orderNumber))
+		return nil, &storageErrors.ContextTimeoutExceededError{Err: ctx.Err()}
+	case methodErr := <-chanEr:
+		s.log.Error().Err(methodErr).Msg(fmt.Sprintf("reviewing suspended order failed for order %v", orderNumber))
+		return nil, methodErr
+	case order := <-chanOk:
+		err = tx.Commit()
+		if err != nil {
+			return nil, &storageErrors.ExecutionPSQLError{Err: err}
+		}
+		s.log.Info().Msg(fmt.Sprintf("reviewing suspended order done for order %v, it is %s", orderNumber, order.Status))
+		s.cache.InvalidateOrders(ctx, order.UserID)
+		s.cache.InvalidateBalance(ctx, order.UserID)
+		s.emit(modeldto.Notification{Kind: "order_processed", UserID: order.UserID, Message: fmt.Sprintf("order %v is %s", orderNumber, order.Status)})
+		if order.Accrual > 0 {
+			s.emit(modeldto.Notification{Kind: "balance_changed", UserID: order.UserID, Message: fmt.Sprintf("balance credited with %v for order %v", order.Accrual, orderNumber)})
+		}
+		for _, alert := range alerts {
+			s.emit(alert)
+		}
+		return &order, nil
+	}
+}
@@ -0,0 +1,129 @@
+// Package inpsql provides functionality for operating a relational DB.
+
+package inpsql
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	storageErrors "github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/errors"
+	"github.com/danilovkiri/dk-go-gophermart/internal/tenant"
+)
+
+// hashLinkCode hashes a one-time Telegram link code so that a DB leak does not expose pending codes.
+func hashLinkCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// SetTelegramLinkCode stores a one-time code linking a Telegram chat to a user, it replaces any pending code.
+func (s *Storage) SetTelegramLinkCode(ctx context.Context, userID string, code string, expiresAt time.Time) error {
+	updStmt, err := s.DB.PrepareContext(ctx, "UPDATE users SET telegram_link_code = $1, telegram_link_expires_at = $2 WHERE user_id = $3 AND tenant_id = $4")
+	if err != nil {
+		return &storageErrors.StatementPSQLError{Err: err}
+	}
+	defer updStmt.Close()
+	chanOk := make(chan bool)
+	chanEr := make(chan error)
+	go func() {
+		res, err := updStmt.ExecContext(ctx, hashLinkCode(code), expiresAt, userID, tenant.FromContext(ctx))
+		if err != nil {
+			chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
+			return
+		}
+		affected, err := res.RowsAffected()
+		if err != nil {
+			chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
+			return
+		}
+		if affected == 0 {
+			chanEr <- &storageErrors.NotFoundError{Err: sql.ErrNoRows}
+			return
+		}
+		chanOk <- true
+	}()
+	select {
+	case <-ctx.Done():
+		s.log.Error().Err(ctx.Err()).Msg("setting telegram link code failed")
+		return &storageErrors.ContextTimeoutExceededError{Err: ctx.Err()}
+	case methodErr := <-chanEr:
+		s.log.Error().Err(methodErr).Msg("setting telegram link code failed")
+		return methodErr
+	case <-chanOk:
+		s.log.Info().Msg("setting telegram link code done")
+		return nil
+	}
+}
+
+// LinkTelegramChat redeems a non-expired link code storing the chat of the code owner and returns the owner.
+func (s *Storage) LinkTelegramChat(ctx context.Context, code string, chatID int64) (string, error) {
+	updStmt, err := s.DB.PrepareContext(ctx, `UPDATE users SET telegram_chat_id = $1, telegram_link_code = NULL, telegram_link_expires_at = NULL
+		WHERE telegram_link_code = $2 AND telegram_link_expires_at > $3 RETURNING user_id`)
+	if err != nil {
+		return "", &storageErrors.StatementPSQLError{Err: err}
+	}
+	defer updStmt.Close()
+	chanOk := make(chan string)
+	chanEr := make(chan error)
+	go func() {
+		var userID string
+		err := updStmt.QueryRowContext(ctx, chatID, hashLinkCode(code), time.Now()).Scan(&userID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				chanEr <- &storageErrors.NotFoundError{Err: err}
+				return
+			}
+			chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
+			return
+		}
+		chanOk <- userID
+	}()
+	select {
+	case <-ctx.Done():
+		s.log.Error().Err(ctx.Err()).Msg("linking telegram chat failed")
+		return "", &storageErrors.ContextTimeoutExceededError{Err: ctx.Err()}
+	case methodErr := <-chanEr:
+		s.log.Error().Err(methodErr).Msg("linking telegram chat failed")
+		return "", methodErr
+	case userID := <-chanOk:
+		s.log.Info().Msg("linking telegram chat done")
+		return userID, nil
+	}
+}
+
+// GetTelegramChatID retrieves the Telegram chat linked to a user, NotFoundError is returned if none is linked.
+func (s *Storage) GetTelegramChatID(ctx context.Context, userID string) (int64, error) {
+	selectStmt, err := s.DB.PrepareContext(ctx, "SELECT telegram_chat_id FROM users WHERE user_id = $1 AND telegram_chat_id IS NOT NULL")
+	if err != nil {
+		return 0, &storageErrors.StatementPSQLError{Err: err}
+	}
+	defer selectStmt.Close()
+	chanOk := make(chan int64)
+	chanEr := make(chan error)
+	go func() {
+		var chatID int64
+		err := selectStmt.QueryRowContext(ctx, userID).Scan(&chatID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				chanEr <- &storageErrors.NotFoundError{Err: err}
+				return
+			}
+			chanEr <- &storageErrors.ScanningPSQLError{Err: err}
+			return
+		}
+		chanOk <- chatID
+	}()
+	select {
+	case <-ctx.Done():
+		s.log.Error().Err(ctx.Err()).Msg("getting telegram chat failed")
+		return 0, &storageErrors.ContextTimeoutExceededError{Err: ctx.Err()}
+	case methodErr := <-chanEr:
+		return 0, methodErr
+	case chatID := <-chanOk:
+		return chatID, nil
+	}
+}
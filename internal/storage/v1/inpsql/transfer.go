@@ -0,0 +1,130 @@
+// Package inpsql provides functionality for operating a relational DB.
+
+package inpsql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/models/modeldto"
+	storageErrors "github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/errors"
+	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/modelstorage"
+)
+
+// Audit log actions recorded upon order transfers.
+const (
+	AuditOrderTransferredOut = "order_transferred_out"
+	AuditOrderTransferredIn  = "order_transferred_in"
+)
+
+// TransferOrder moves an order to the target account of the same tenant within a single transaction, the accrual
+// of a PROCESSED order is debited from the current owner and credited to the target and both accounts get an audit
+// record. Non-final orders are credited to their owner at the time of processing, so they are moved as is.
+func (s *Storage) TransferOrder(ctx context.Context, orderNumber int, targetID, comment string) (*modeldto.OrderTransfer, error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, &storageErrors.ExecutionPSQLError{Err: err}
+	}
+	defer tx.Rollback()
+	chanOk := make(chan modeldto.OrderTransfer)
+	chanEr := make(chan error)
+	go func() {
+		transfer := modeldto.OrderTransfer{OrderNumber: strconv.Itoa(orderNumber), ToID: targetID, Comment: comment}
+		var tenantID string
+		var accrual float64
+		err := tx.QueryRowContext(ctx, "SELECT user_id, tenant_id, status, accrual FROM orders WHERE order_number = $1 FOR UPDATE", orderNumber).Scan(&transfer.FromID, &tenantID, &transfer.Status, &accrual)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				chanEr <- &storageErrors.NotFoundError{Err: err}
+				return
+			}
+			chanEr <- &storageErrors.ScanningPSQLError{Err: err}
+			return
+		}
+		if transfer.FromID == targetID {
+			chanEr <- &storageErrors.MergeConflictError{Msg: fmt.Sprintf("order %v already belongs to account %s", orderNumber, targetID)}
+			return
+		}
+		var targetTenantID string
+		var deactivated bool
+		err = tx.QueryRowContext(ctx, "SELECT tenant_id, deactivated_at IS NOT NULL FROM users WHERE user_id = $1 FOR UPDATE", targetID).Scan(&targetTenantID, &deactivated)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				chanEr <- &storageErrors.NotFoundError{Err: err}
+				return
+			}
+			chanEr <- &storageErrors.ScanningPSQLError{Err: err}
+			return
+		}
+		if deactivated {
+			chanEr <- &storageErrors.MergeConflictError{Msg: fmt.Sprintf("account %s is deactivated", targetID)}
+			return
+		}
+		if targetTenantID != tenantID {
+			chanEr <- &storageErrors.MergeConflictError{Msg: "order and account belong to different tenants"}
+			return
+		}
+		if transfer.Status == "PROCESSED" && accrual > 0 {
+			// the owner may have spent the accrual already, in which case the transfer fails with insufficient funds
+			err = s.adjustBalance(ctx, tx, transfer.FromID, tenantID, -accrual)
+			if err != nil {
+				chanEr <- err
+				return
+			}
+			err = s.adjustBalance(ctx, tx, targetID, tenantID, accrual)
+			if err != nil {
+				chanEr <- err
+				return
+			}
+			err = addBalanceEvent(ctx, tx, transfer.FromID, modelstorage.EventTransferOut, -accrual, transfer.OrderNumber)
+			if err != nil {
+				chanEr <- err
+				return
+			}
+			err = addBalanceEvent(ctx, tx, targetID, modelstorage.EventTransferIn, accrual, transfer.OrderNumber)
+			if err != nil {
+				chanEr <- err
+				return
+			}
+			transfer.AmountMoved = accrual
+		}
+		_, err = tx.ExecContext(ctx, "UPDATE orders SET user_id = $1 WHERE order_number = $2", targetID, orderNumber)
+		if err != nil {
+			chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
+			return
+		}
+		err = addAuditEntry(ctx, tx, transfer.FromID, tenantID, AuditOrderTransferredOut, transfer)
+		if err != nil {
+			chanEr <- err
+			return
+		}
+		err = addAuditEntry(ctx, tx, targetID, tenantID, AuditOrderTransferredIn, transfer)
+		if err != nil {
+			chanEr <- err
+			return
+		}
+		chanOk <- transfer
+	}()
+	select {
+	case <-ctx.Done():
+		s.log.Error().Err(ctx.Err()).Msg(fmt.Sprintf("transferring order %v to %s failed", orderNumber, targetID))
+		return nil, &storageErrors.ContextTimeoutExceededError{Err: ctx.Err()}
+	case methodErr := <-chanEr:
+		s.log.Error().Err(methodErr).Msg(fmt.Sprintf("transferring order %v to %s failed", orderNumber, targetID))
+		return nil, methodErr
+	case transfer := <-chanOk:
+		err = tx.Commit()
+		if err != nil {
+			return nil, &storageErrors.ExecutionPSQLError{Err: err}
+		}
+		s.log.Info().Msg(fmt.Sprintf("transferring order %v to %s done", orderNumber, targetID))
+		for _, userID := range []string{transfer.FromID, targetID} {
+			s.cache.InvalidateOrders(ctx, userID)
+			s.cache.InvalidateBalance(ctx, userID)
+		}
+		return &transfer, nil
+	}
+}
@@ -0,0 +1,224 @@
+// Package inpsql provides functionality for operating a relational DB.
+
+package inpsql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/models/modeldto"
+	"github.com/danilovkiri/dk-go-gophermart/internal/models/modelqueue"
+	storageErrors "github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/errors"
+	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/modelstorage"
+	"github.com/danilovkiri/dk-go-gophermart/internal/tenant"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgerrcode"
+)
+
+// SendWithdrawalToQueue sends a pending withdrawal to the asynchronous processing queue.
+func (s *Storage) SendWithdrawalToQueue(ctx context.Context, item modelqueue.WithdrawalQueueEntry) {
+	select {
+	case s.WithdrawalQueue <- item:
+	case <-ctx.Done():
+	}
+}
+
+// AddPendingWithdrawal registers a new withdrawal in PENDING status without debiting the balance and returns its ID.
+func (s *Storage) AddPendingWithdrawal(ctx context.Context, userID string, withdrawal modeldto.NewOrderWithdrawal) (uint, error) {
+	newWithdrawalStmt, err := s.DB.PrepareContext(ctx, "INSERT INTO withdrawals (user_id, order_number, amount, processed_at, status, tenant_id) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id")
+	if err != nil {
+		return 0, &storageErrors.StatementPSQLError{Err: err}
+	}
+	defer newWithdrawalStmt.Close()
+	chanOk := make(chan uint)
+	chanEr := make(chan error)
+	go func() {
+		var withdrawalID uint
+		err := newWithdrawalStmt.QueryRowContext(ctx, userID, withdrawal.OrderNumber, withdrawal.Amount, time.Now(), modelstorage.WithdrawalPending, tenant.FromContext(ctx)).Scan(&withdrawalID)
+		if err != nil {
+			if err, ok := err.(*pgconn.PgError); ok && err.Code == pgerrcode.ForeignKeyViolation {
+				chanEr <- &storageErrors.UnknownUserError{Err: err, ID: userID}
+				return
+			}
+			chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
+			return
+		}
+		chanOk <- withdrawalID
+	}()
+	select {
+	case <-ctx.Done():
+		s.log.Error().Err(ctx.Err()).Msg("adding pending withdrawal failed")
+		return 0, &storageErrors.ContextTimeoutExceededError{Err: ctx.Err()}
+	case methodErr := <-chanEr:
+		s.log.Error().Err(methodErr).Msg("adding pending withdrawal failed")
+		return 0, methodErr
+	case withdrawalID := <-chanOk:
+		s.log.Info().Msg(fmt.Sprintf("adding pending withdrawal done for order %s", withdrawal.OrderNumber))
+		return withdrawalID, nil
+	}
+}
+
+// ensureWithdrawalOrder registers the order paid by a withdrawal unless the user has already registered it.
+func ensureWithdrawalOrder(ctx context.Context, tx *sql.Tx, userID, orderNumber, tenantID string) error {
+	_, err := tx.ExecContext(ctx, "INSERT INTO orders (user_id, order_number, status, accrual, created_at, tenant_id) VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT (order_number) DO NOTHING", userID, orderNumber, "PROCESSED", 0.0, time.Now(), tenantID)
+	if err != nil {
+		if err, ok := err.(*pgconn.PgError); ok && err.Code == pgerrcode.ForeignKeyViolation {
+			return &storageErrors.UnknownUserError{Err: err, ID: userID}
+		}
+		return &storageErrors.ExecutionPSQLError{Err: err}
+	}
+	var ownerID, ownerTenantID string
+	err = tx.QueryRowContext(ctx, "SELECT user_id, tenant_id FROM orders WHERE order_number = $1", orderNumber).Scan(&ownerID, &ownerTenantID)
+	if err != nil {
+		return &storageErrors.ScanningPSQLError{Err: err}
+	}
+	if ownerID != userID || ownerTenantID != tenantID {
+		return &storageErrors.AlreadyExistsAndViolatesError{Err: errors.New("order belongs to another user"), ID: orderNumber}
+	}
+	return nil
+}
+
+// ConfirmWithdrawal debits the balance for a pending withdrawal and marks it PROCESSED within a single transaction.
+func (s *Storage) ConfirmWithdrawal(ctx context.Context, userID string, withdrawalID uint) error {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return &storageErrors.ExecutionPSQLError{Err: err}
+	}
+	defer tx.Rollback()
+	tenantID := tenant.FromContext(ctx)
+	chanOk := make(chan bool)
+	chanEr := make(chan error)
+	var pending modelstorage.WithdrawalStorageEntry
+	var alerts []modeldto.Notification
+	go func() {
+		err := tx.QueryRowContext(ctx, "SELECT amount, order_number FROM withdrawals WHERE id = $1 AND user_id = $2 AND status = $3 AND tenant_id = $4", withdrawalID, userID, modelstorage.WithdrawalPending, tenantID).Scan(&pending.Amount, &pending.OrderNumber)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				// the withdrawal was already confirmed or failed
+				chanOk <- false
+				return
+			}
+			chanEr <- &storageErrors.ScanningPSQLError{Err: err}
+			return
+		}
+		err = ensureWithdrawalOrder(ctx, tx, userID, strconv.Itoa(pending.OrderNumber), tenantID)
+		if err != nil {
+			chanEr <- err
+			return
+		}
+		err = s.adjustBalance(ctx, tx, userID, tenantID, -pending.Amount)
+		if err != nil {
+			chanEr <- err
+			return
+		}
+		_, err = tx.ExecContext(ctx, "UPDATE withdrawals SET status = $1, processed_at = $2 WHERE id = $3", modelstorage.WithdrawalProcessed, time.Now(), withdrawalID)
+		if err != nil {
+			chanEr <- &storageErrors.ExecutionPSQLError{Err: err}
+			return
+		}
+		err = addBalanceEvent(ctx, tx, userID, modelstorage.EventWithdrawal, -pending.Amount, strconv.Itoa(pending.OrderNumber))
+		if err != nil {
+			chanEr <- err
+			return
+		}
+		alerts, err = balanceAlerts(ctx, tx, userID, -pending.Amount, strconv.Itoa(pending.OrderNumber))
+		if err != nil {
+			chanEr <- err
+			return
+		}
+		err = addOutboxEvent(ctx, tx, tenantID, modelstorage.OutboxWithdrawalMade, map[string]interface{}{"user_id": userID, "order": strconv.Itoa(pending.OrderNumber), "sum": pending.Amount})
+		if err != nil {
+			chanEr <- err
+			return
+		}
+		chanOk <- true
+	}()
+	select {
+	case <-ctx.Done():
+		s.log.Error().Err(ctx.Err()).Msg(fmt.Sprintf("confirming withdrawal failed for withdrawal %v", withdrawalID))
+		return &storageErrors.ContextTimeoutExceededError{Err: ctx.Err()}
+	case methodErr := <-chanEr:
+		s.log.Error().Err(methodErr).Msg(fmt.Sprintf("confirming withdrawal failed for withdrawal %v", withdrawalID))
+		return methodErr
+	case updated := <-chanOk:
+		if !updated {
+			s.log.Warn().Msg(fmt.Sprintf("confirming withdrawal skipped for withdrawal %v, it is not pending", withdrawalID))
+			return nil
+		}
+		s.log.Info().Msg(fmt.Sprintf("confirming withdrawal done for withdrawal %v", withdrawalID))
+		defer s.cache.InvalidateOrders(ctx, userID)
+		defer s.cache.InvalidateBalance(ctx, userID)
+		err = tx.Commit()
+		if err != nil {
+			return err
+		}
+		s.emit(modeldto.Notification{Kind: "balance_changed", UserID: userID, Message: fmt.Sprintf("balance debited with %v for order %v", pending.Amount, pending.OrderNumber)})
+		for _, alert := range alerts {
+			s.emit(alert)
+		}
+		return nil
+	}
+}
+
+// FailWithdrawal marks a pending withdrawal as FAILED.
+func (s *Storage) FailWithdrawal(ctx context.Context, userID string, withdrawalID uint) error {
+	updStmt, err := s.DB.PrepareContext(ctx, "UPDATE withdrawals SET status = $1 WHERE id = $2 AND user_id = $3 AND status = $4 AND tenant_id = $5")
+	if err != nil {
+		return &storageErrors.StatementPSQLError{Err: err}
+	}
+	defer updStmt.Close()
+	_, err = updStmt.ExecContext(ctx, modelstorage.WithdrawalFailed, withdrawalID, userID, modelstorage.WithdrawalPending, tenant.FromContext(ctx))
+	if err != nil {
+		return &storageErrors.ExecutionPSQLError{Err: err}
+	}
+	s.log.Warn().Msg(fmt.Sprintf("withdrawal %v was marked as failed", withdrawalID))
+	return nil
+}
+
+// GetWithdrawal retrieves the latest user's withdrawal against an order from DB.
+func (s *Storage) GetWithdrawal(ctx context.Context, userID, orderNumber string) (*modelstorage.WithdrawalStorageEntry, error) {
+	selectStmt, err := s.DB.PrepareContext(ctx, "SELECT id, user_id, order_number, amount, processed_at, status FROM withdrawals WHERE user_id = $1 AND order_number = $2 AND tenant_id = $3 ORDER BY id DESC LIMIT 1")
+	if err != nil {
+		return nil, &storageErrors.StatementPSQLError{Err: err}
+	}
+	defer selectStmt.Close()
+	var queryOutput modelstorage.WithdrawalStorageEntry
+	err = selectStmt.QueryRowContext(ctx, userID, orderNumber, tenant.FromContext(ctx)).Scan(&queryOutput.ID, &queryOutput.UserID, &queryOutput.OrderNumber, &queryOutput.Amount, &queryOutput.ProcessedAt, &queryOutput.Status)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, &storageErrors.NotFoundError{Err: err}
+		}
+		if ctx.Err() != nil {
+			return nil, &storageErrors.ContextTimeoutExceededError{Err: ctx.Err()}
+		}
+		return nil, &storageErrors.ScanningPSQLError{Err: err}
+	}
+	return &queryOutput, nil
+}
+
+// getPendingWithdrawals retrieves all pending withdrawals upon server startup.
+func (s *Storage) getPendingWithdrawals(ctx context.Context) ([]modelstorage.WithdrawalStorageEntry, error) {
+	rows, err := s.DB.QueryContext(ctx, "SELECT id, user_id, order_number, amount, processed_at, status, tenant_id FROM withdrawals WHERE status = $1", modelstorage.WithdrawalPending)
+	if err != nil {
+		return nil, &storageErrors.ExecutionPSQLError{Err: err}
+	}
+	defer rows.Close()
+	var queryOutput []modelstorage.WithdrawalStorageEntry
+	for rows.Next() {
+		var queryOutputRow modelstorage.WithdrawalStorageEntry
+		err = rows.Scan(&queryOutputRow.ID, &queryOutputRow.UserID, &queryOutputRow.OrderNumber, &queryOutputRow.Amount, &queryOutputRow.ProcessedAt, &queryOutputRow.Status, &queryOutputRow.TenantID)
+		if err != nil {
+			return nil, &storageErrors.ScanningPSQLError{Err: err}
+		}
+		queryOutput = append(queryOutput, queryOutputRow)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, &storageErrors.ScanningPSQLError{Err: err}
+	}
+	return queryOutput, nil
+}
@@ -4,51 +4,163 @@ package storage
 
 import (
 	"context"
-	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/modelstorage"
+	"time"
 
 	"github.com/danilovkiri/dk-go-gophermart/internal/models/modeldto"
 	"github.com/danilovkiri/dk-go-gophermart/internal/models/modelqueue"
+	"github.com/danilovkiri/dk-go-gophermart/internal/storage/v1/modelstorage"
 )
 
 // RegisterLogin defines a set of methods for types implementing RegisterLogin.
 type RegisterLogin interface {
-	AddNewUser(ctx context.Context, credentials modeldto.User, userID string) error
-	CheckUser(ctx context.Context, credentials modeldto.User) (string, error)
+	AddNewUser(ctx context.Context, credentials modeldto.User, lookup modelstorage.LoginLookup, userID string) error
+	CheckUser(ctx context.Context, lookup modelstorage.LoginLookup) (*modelstorage.UserStorageEntry, error)
+}
+
+// Sessions defines a set of methods for types implementing Sessions.
+type Sessions interface {
+	AddSession(ctx context.Context, session modelstorage.SessionStorageEntry) (bool, error)
+	GetSessions(ctx context.Context, userID string) ([]modelstorage.SessionStorageEntry, error)
+	TouchSession(ctx context.Context, userID, sessionID string, idleTimeout, lifetime time.Duration) error
+	RotateRefreshToken(ctx context.Context, refreshHash, newRefreshHash string, expiresAt time.Time, lifetime time.Duration) (*modelstorage.SessionStorageEntry, error)
+	RevokeSession(ctx context.Context, userID, sessionID string) error
+}
+
+// Profiles defines a set of methods for types implementing Profiles.
+type Profiles interface {
+	GetUser(ctx context.Context, userID string) (*modelstorage.UserStorageEntry, error)
+	SearchUsers(ctx context.Context, search modelstorage.UserSearch) ([]modelstorage.UserStorageEntry, error)
+	UpdatePasswordHash(ctx context.Context, userID, hash string) error
+	UpdateUserProfile(ctx context.Context, userID string, profile modelstorage.UserStorageEntry, lookup *modelstorage.LoginLookup, changes []string) error
+	MergeUsers(ctx context.Context, donorID, targetID string) (*modeldto.AccountMerge, error)
+	TransferOrder(ctx context.Context, orderNumber int, targetID, comment string) (*modeldto.OrderTransfer, error)
+}
+
+// TelegramChats defines a set of methods for types implementing TelegramChats.
+type TelegramChats interface {
+	SetTelegramLinkCode(ctx context.Context, userID string, code string, expiresAt time.Time) error
+	LinkTelegramChat(ctx context.Context, code string, chatID int64) (string, error)
+	GetTelegramChatID(ctx context.Context, userID string) (int64, error)
+}
+
+// Ledger defines a set of methods for types implementing Ledger.
+type Ledger interface {
+	GetBalanceEvents(ctx context.Context, userID string) ([]modelstorage.BalanceEventStorageEntry, error)
+	AddAdjustment(ctx context.Context, userID string, adjustment modeldto.BalanceAdjustmentRequest) (*modelstorage.BalanceEventStorageEntry, float64, error)
+}
+
+// Alerts defines a set of methods for types implementing Alerts.
+type Alerts interface {
+	GetAlertThresholds(ctx context.Context, userID string) (*modelstorage.AlertThresholdsStorageEntry, error)
+	SetAlertThresholds(ctx context.Context, userID string, thresholds modelstorage.AlertThresholdsStorageEntry) error
 }
 
 // CheckBalance defines a set of methods for types implementing CheckBalance.
 type CheckBalance interface {
 	GetCurrentAmount(ctx context.Context, userID string) (float64, error)
 	GetWithdrawnAmount(ctx context.Context, userID string) (float64, error)
+	GetBalanceAmounts(ctx context.Context, userID string) (*modelstorage.BalanceAmountsStorageEntry, error)
+}
+
+// Holds defines a set of methods for types implementing Holds.
+type Holds interface {
+	AddHold(ctx context.Context, userID string, orderNumber int, amount float64) (*modelstorage.BalanceHoldStorageEntry, error)
+	GetHolds(ctx context.Context, userID string) ([]modelstorage.BalanceHoldStorageEntry, error)
+	CaptureHold(ctx context.Context, userID string, holdID uint) (*modelstorage.BalanceHoldStorageEntry, error)
+	ReleaseHold(ctx context.Context, userID string, holdID uint) (*modelstorage.BalanceHoldStorageEntry, error)
+	ExpireHolds(ctx context.Context) error
 }
 
 // CheckWithdrawals defines a set of methods for types implementing CheckWithdrawals.
 type CheckWithdrawals interface {
-	GetWithdrawals(ctx context.Context, userID string) ([]modelstorage.WithdrawalStorageEntry, error)
+	GetWithdrawals(ctx context.Context, userID string, sort modeldto.Sort) ([]modelstorage.WithdrawalStorageEntry, error)
 }
 
 // CheckOrders defines a set of methods for types implementing CheckOrders.
 type CheckOrders interface {
-	GetOrders(ctx context.Context, userID string) ([]modelstorage.OrderStorageEntry, error)
+	GetOrders(ctx context.Context, userID string, sort modeldto.Sort) ([]modelstorage.OrderStorageEntry, error)
+	GetOrder(ctx context.Context, userID string, orderNumber int) (*modelstorage.OrderStorageEntry, error)
+	GetOrderStatusHistory(ctx context.Context, userID string, orderNumber int) ([]modelstorage.OrderStatusHistoryStorageEntry, error)
+	GetUserStats(ctx context.Context, userID string) ([]modelstorage.ChannelStatsStorageEntry, error)
+	RecheckOrder(ctx context.Context, userID string, orderNumber int, limit *modelstorage.RecheckLimit) (*modelstorage.OrderStorageEntry, error)
+	RequeueOrders(ctx context.Context, filter modelstorage.RequeueFilter) (*modeldto.RequeueReport, error)
 }
 
 // NewWithdrawal defines a set of methods for types implementing NewWithdrawal.
 type NewWithdrawal interface {
 	AddNewWithdrawal(ctx context.Context, userID string, withdrawal modeldto.NewOrderWithdrawal) error
+	AsyncWithdrawal
+}
+
+// AsyncWithdrawal defines a set of methods for types implementing AsyncWithdrawal.
+type AsyncWithdrawal interface {
+	AddPendingWithdrawal(ctx context.Context, userID string, withdrawal modeldto.NewOrderWithdrawal) (uint, error)
+	ConfirmWithdrawal(ctx context.Context, userID string, withdrawalID uint) error
+	FailWithdrawal(ctx context.Context, userID string, withdrawalID uint) error
+	GetWithdrawal(ctx context.Context, userID, orderNumber string) (*modelstorage.WithdrawalStorageEntry, error)
+	SendWithdrawalToQueue(ctx context.Context, item modelqueue.WithdrawalQueueEntry)
 }
 
 // NewOrder defines a set of methods for types implementing NewOrder.
 type NewOrder interface {
-	AddNewOrder(ctx context.Context, userID string, orderNumber int) error
+	AddNewOrder(ctx context.Context, userID string, orderNumber int, metadata string, channel string) error
 	SendToQueue(item modelqueue.OrderQueueEntry)
 }
 
+// SuspendedAccruals defines a set of methods for types implementing SuspendedAccruals.
+type SuspendedAccruals interface {
+	GetSuspendedOrders(ctx context.Context) ([]modelstorage.OrderStorageEntry, error)
+	ReviewSuspendedOrder(ctx context.Context, orderNumber int, approve bool) (*modelstorage.OrderStorageEntry, error)
+}
+
+// AccrualResponses defines a set of methods for types implementing AccrualResponses.
+type AccrualResponses interface {
+	SaveAccrualResponse(ctx context.Context, response modelstorage.AccrualResponseStorageEntry) error
+	GetAccrualResponse(ctx context.Context, orderNumber int) (*modelstorage.AccrualResponseStorageEntry, error)
+	GetAnyOrder(ctx context.Context, orderNumber int) (*modelstorage.OrderStorageEntry, error)
+}
+
+// AccrualCallback defines a set of methods for types implementing AccrualCallback.
+type AccrualCallback interface {
+	ResolveOrder(ctx context.Context, orderNumber int, status string, accrual float64) error
+}
+
+// HealthReporter defines a set of methods for types implementing HealthReporter.
+type HealthReporter interface {
+	Healthy() bool
+	RetryAfter() time.Duration
+}
+
+// Reconciler defines a set of methods for types implementing Reconciler.
+type Reconciler interface {
+	GetReconciliationReport(ctx context.Context) (*modeldto.ReconciliationReport, error)
+	RecalculateBalances(ctx context.Context, apply bool) (*modeldto.ReconciliationReport, error)
+}
+
+// Summarizer defines a set of methods for types implementing Summarizer.
+type Summarizer interface {
+	GetSummary(ctx context.Context, windows []time.Duration) (*modeldto.AdminSummary, error)
+	GetReport(ctx context.Context, from, to time.Time) (*modeldto.AdminReport, error)
+}
+
 // Storage defines a set of methods for types implementing Storage.
 type Storage interface {
 	RegisterLogin
+	Sessions
+	Profiles
+	TelegramChats
+	Alerts
+	Ledger
 	CheckBalance
+	Holds
 	CheckWithdrawals
 	CheckOrders
 	NewWithdrawal
 	NewOrder
+	AccrualCallback
+	SuspendedAccruals
+	AccrualResponses
+	HealthReporter
+	Reconciler
+	Summarizer
 }
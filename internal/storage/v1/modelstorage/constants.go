@@ -0,0 +1,64 @@
+// Package modelstorage provides types for querying relational DB.
+
+package modelstorage
+
+// Balance event kinds, amounts of debiting events are negative.
+const (
+	EventOpening         = "opening"
+	EventAccrualCredited = "accrual_credited"
+	EventWithdrawal      = "withdrawal"
+	EventTransferOut     = "transfer_out"
+	EventTransferIn      = "transfer_in"
+	EventAdjustment      = "adjustment"
+)
+
+// ReasonRecalculation references adjustment events correcting a balance to the recomputed amount, unlike manual
+// adjustments they are not part of the recomputed amount themselves.
+const ReasonRecalculation = "recalculation"
+
+// Withdrawal statuses.
+const (
+	WithdrawalPending   = "PENDING"
+	WithdrawalProcessed = "PROCESSED"
+	WithdrawalFailed    = "FAILED"
+)
+
+// Balance hold statuses, an ACTIVE hold past its expiration no longer counts against the balance even before
+// it is marked EXPIRED.
+const (
+	HoldActive   = "ACTIVE"
+	HoldCaptured = "CAPTURED"
+	HoldReleased = "RELEASED"
+	HoldExpired  = "EXPIRED"
+)
+
+// OrderSuspended is the status of processed orders whose accrual exceeds a cap and awaits admin approval,
+// the accrual service no longer updates them.
+const OrderSuspended = "SUSPENDED"
+
+// Accrual caps reported when an accrual is suspended.
+const (
+	CapOrder = "order"
+	CapDaily = "daily"
+)
+
+// Order status change sources of suspended accrual reviews and rechecks.
+const (
+	SourceAdminApproval  = "admin_approval"
+	SourceAdminRejection = "admin_rejection"
+	SourceUserRecheck    = "user_recheck"
+	SourceAdminRecheck   = "admin_recheck"
+)
+
+// Outbox event kinds published to the message bus.
+const (
+	OutboxUserRegistered = "user_registered"
+	OutboxOrderProcessed = "order_processed"
+	OutboxWithdrawalMade = "withdrawal_made"
+)
+
+// Alert notification kinds.
+const (
+	NotificationLowBalance   = "low_balance"
+	NotificationLargeAccrual = "large_accrual"
+)
@@ -2,12 +2,26 @@
 
 package modelstorage
 
+import "time"
+
 type UserStorageEntry struct {
-	ID           uint   `db:"id"`
-	UserID       string `db:"user_id"`
-	Login        string `db:"login"`
-	Password     string `db:"password"`
-	RegisteredAt string `db:"registered_at"`
+	ID           uint      `db:"id"`
+	UserID       string    `db:"user_id"`
+	Login        string    `db:"login"`
+	LoginHash    string    `db:"login_hash"`
+	Password     string    `db:"password"`
+	RegisteredAt time.Time `db:"registered_at"`
+	TenantID     string    `db:"tenant_id"`
+	Email        string    `db:"email"`
+	Phone        string    `db:"phone"`
+	Deactivated  bool      `db:"deactivated"`
+}
+
+// LoginLookup identifies a login by its digest, Legacy lists its deterministic ciphertexts under every known key
+// which match users stored before digests were introduced.
+type LoginLookup struct {
+	Hash   string
+	Legacy []string
 }
 
 type BalanceStorageEntry struct {
@@ -16,19 +30,135 @@ type BalanceStorageEntry struct {
 	Amount float64 `db:"amount"`
 }
 
+type BalanceAmountsStorageEntry struct {
+	CurrentAmount   float64   `db:"amount"`
+	WithdrawnAmount float64   `db:"withdrawn"`
+	HeldAmount      float64   `db:"held"`
+	UpdatedAt       time.Time `db:"updated_at"`
+}
+
+type BalanceHoldStorageEntry struct {
+	ID          uint      `db:"id"`
+	UserID      string    `db:"user_id"`
+	OrderNumber int       `db:"order_number"`
+	Amount      float64   `db:"amount"`
+	Status      string    `db:"status"`
+	CreatedAt   time.Time `db:"created_at"`
+	ExpiresAt   time.Time `db:"expires_at"`
+}
+
 type WithdrawalStorageEntry struct {
-	ID          uint    `db:"id"`
-	UserID      string  `db:"user_id"`
-	OrderNumber int     `db:"order_number"`
-	Amount      float64 `db:"amount"`
-	ProcessedAt string  `db:"processed_at"`
+	ID          uint      `db:"id"`
+	UserID      string    `db:"user_id"`
+	OrderNumber int       `db:"order_number"`
+	Amount      float64   `db:"amount"`
+	ProcessedAt time.Time `db:"processed_at"`
+	Status      string    `db:"status"`
+	TenantID    string    `db:"tenant_id"`
 }
 
 type OrderStorageEntry struct {
-	ID          uint    `db:"id"`
-	UserID      string  `db:"user_id"`
-	OrderNumber int     `db:"order_number"`
-	Status      string  `db:"status"`
-	Accrual     float64 `db:"accrual"`
-	CreatedAt   string  `db:"created_at"`
+	ID          uint      `db:"id"`
+	UserID      string    `db:"user_id"`
+	OrderNumber int       `db:"order_number"`
+	Status      string    `db:"status"`
+	Accrual     float64   `db:"accrual"`
+	CreatedAt   time.Time `db:"created_at"`
+	TenantID    string    `db:"tenant_id"`
+	Metadata    string    `db:"metadata"`
+	Retry       OrderRetryStorageEntry
+}
+
+type OrderRetryStorageEntry struct {
+	RetryCount    int       `db:"retry_count"`
+	InvalidCount  int       `db:"invalid_count"`
+	PollStep      int       `db:"poll_step"`
+	LastCheckedAt time.Time `db:"last_checked_at"`
+	RetryAfterMs  int64     `db:"retry_after_ms"`
+}
+
+type BalanceDiscrepancyStorageEntry struct {
+	UserID         string  `db:"user_id"`
+	StoredAmount   float64 `db:"amount"`
+	ExpectedAmount float64 `db:"expected"`
+}
+
+type ChannelStatsStorageEntry struct {
+	Channel string  `db:"channel"`
+	Orders  int     `db:"orders"`
+	Accrual float64 `db:"accrual"`
+}
+
+type SessionStorageEntry struct {
+	ID          uint      `db:"id"`
+	UserID      string    `db:"user_id"`
+	UserAgent   string    `db:"user_agent"`
+	IP          string    `db:"ip"`
+	Fingerprint string    `db:"fingerprint"`
+	SessionID   string    `db:"session_id"`
+	CreatedAt   time.Time `db:"created_at"`
+	LastSeen    time.Time `db:"last_seen"`
+	// RefreshHash is the digest of the current refresh token of the session, the token itself is never stored
+	RefreshHash      string    `db:"refresh_hash"`
+	RefreshExpiresAt time.Time `db:"refresh_expires_at"`
+}
+
+type OrderStatusHistoryStorageEntry struct {
+	FromStatus string    `db:"from_status"`
+	ToStatus   string    `db:"to_status"`
+	Source     string    `db:"source"`
+	ChangedAt  time.Time `db:"changed_at"`
+}
+
+type AccrualResponseStorageEntry struct {
+	OrderNumber int       `db:"order_number"`
+	TenantID    string    `db:"tenant_id"`
+	StatusCode  int       `db:"status_code"`
+	Body        []byte    `db:"body"`
+	ReceivedAt  time.Time `db:"received_at"`
+}
+
+type OutboxEventStorageEntry struct {
+	ID        int64     `db:"id"`
+	TxID      int64     `db:"tx_id"`
+	TenantID  string    `db:"tenant_id"`
+	Kind      string    `db:"kind"`
+	Payload   string    `db:"payload"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+type AlertThresholdsStorageEntry struct {
+	LowBalance   *float64 `db:"low_balance_threshold"`
+	LargeAccrual *float64 `db:"large_accrual_threshold"`
+}
+
+type BalanceEventStorageEntry struct {
+	ID        int64     `db:"id"`
+	Kind      string    `db:"kind"`
+	Amount    float64   `db:"amount"`
+	Reference string    `db:"reference"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+type RecheckLimit struct {
+	Interval time.Duration
+	PerUser  int
+}
+
+// UserSearch matches users of any tenant by any of their login lookups, their user ID or an order they own,
+// a zero OrderNumber matches no order.
+type UserSearch struct {
+	Lookups     []LoginLookup
+	UserID      string
+	OrderNumber int
+	Limit       int
+	Offset      int
+}
+
+type RequeueFilter struct {
+	Status     string
+	CreatedTo  time.Time
+	UserID     string
+	NumberFrom int
+	NumberTo   int
 }
@@ -0,0 +1,23 @@
+// Package tenant provides propagation of a tenant identifier through request contexts.
+
+package tenant
+
+import "context"
+
+// Default is the tenant assigned to requests and records which carry no tenant identifier.
+const Default = "default"
+
+type contextKey struct{}
+
+// WithTenant returns a copy of ctx carrying the given tenant identifier.
+func WithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, contextKey{}, tenantID)
+}
+
+// FromContext retrieves a tenant identifier from ctx, falling back to Default.
+func FromContext(ctx context.Context) string {
+	if tenantID, ok := ctx.Value(contextKey{}).(string); ok && tenantID != "" {
+		return tenantID
+	}
+	return Default
+}
@@ -0,0 +1,102 @@
+package tracing
+
+import (
+	"context"
+	"sync"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// maxFailedTraces bounds the number of unsampled traces tracked for having failed spans, failed spans are still
+// exported beyond it but their local ancestors are not.
+const maxFailedTraces = 4096
+
+// recordUnsampled is a sampler recording spans its base sampler drops, so that the decision to export them can be
+// taken once they end.
+type recordUnsampled struct {
+	base sdktrace.Sampler
+}
+
+// ShouldSample keeps the decision of the base sampler, except that dropped spans are recorded.
+func (s recordUnsampled) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	result := s.base.ShouldSample(p)
+	if result.Decision == sdktrace.Drop {
+		result.Decision = sdktrace.RecordOnly
+	}
+	return result
+}
+
+// Description describes the sampler.
+func (s recordUnsampled) Description() string {
+	return "RecordUnsampled{" + s.base.Description() + "}"
+}
+
+// errorProcessor hands sampled spans over to the next processor along with failed spans of unsampled traces, local
+// ancestors of a failed span which end after it are handed over as well, so that a failure is exported with the
+// operations it broke whatever the sample ratio.
+type errorProcessor struct {
+	next   sdktrace.SpanProcessor
+	mu     sync.Mutex
+	failed map[trace.TraceID]struct{}
+}
+
+// newErrorProcessor initializes a span processor exporting failed spans regardless of sampling.
+func newErrorProcessor(next sdktrace.SpanProcessor) *errorProcessor {
+	return &errorProcessor{
+		next:   next,
+		failed: make(map[trace.TraceID]struct{}),
+	}
+}
+
+// OnStart forwards started spans to the next processor.
+func (p *errorProcessor) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {
+	p.next.OnStart(parent, s)
+}
+
+// OnEnd forwards sampled spans and spans of failed unsampled traces to the next processor marked as sampled.
+func (p *errorProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	if s.SpanContext().IsSampled() {
+		p.next.OnEnd(s)
+		return
+	}
+	traceID := s.SpanContext().TraceID()
+	p.mu.Lock()
+	_, export := p.failed[traceID]
+	if !export && s.Status().Code == codes.Error {
+		export = true
+		if len(p.failed) < maxFailedTraces {
+			p.failed[traceID] = struct{}{}
+		}
+	}
+	// the local root ends last, nothing of the trace is left to export
+	if !s.Parent().IsValid() || s.Parent().IsRemote() {
+		delete(p.failed, traceID)
+	}
+	p.mu.Unlock()
+	if export {
+		p.next.OnEnd(sampledSpan{ReadOnlySpan: s})
+	}
+}
+
+// Shutdown shuts the next processor down.
+func (p *errorProcessor) Shutdown(ctx context.Context) error {
+	return p.next.Shutdown(ctx)
+}
+
+// ForceFlush flushes the next processor.
+func (p *errorProcessor) ForceFlush(ctx context.Context) error {
+	return p.next.ForceFlush(ctx)
+}
+
+// sampledSpan presents a recorded span as sampled so that processors and exporters do not discard it.
+type sampledSpan struct {
+	sdktrace.ReadOnlySpan
+}
+
+// SpanContext returns the span context with the sampled flag set.
+func (s sampledSpan) SpanContext() trace.SpanContext {
+	sc := s.ReadOnlySpan.SpanContext()
+	return sc.WithTraceFlags(sc.TraceFlags().WithSampled(true))
+}
@@ -0,0 +1,141 @@
+// Package tracing configures OpenTelemetry distributed tracing, finished spans are exported to an OTLP/HTTP collector
+// and trace context is propagated with W3C traceparent headers. Instrumentation uses the global tracer provider,
+// which stays a no-op unless a tracer is started.
+
+package tracing
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/danilovkiri/dk-go-gophermart/internal/config"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstrumentationName names the instrumentation producing the spans of the service.
+const InstrumentationName = "github.com/danilovkiri/dk-go-gophermart"
+
+// traceParentHeader is the W3C Trace Context header carrying the trace and parent span IDs.
+const traceParentHeader = "traceparent"
+
+// Span attribute keys, user IDs are never attached as is, only their hashes.
+const (
+	AttrUserIDHash  = attribute.Key("user.id_hash")
+	AttrOrderNumber = attribute.Key("order.number")
+	AttrTenantID    = attribute.Key("tenant.id")
+	AttrRetryCount  = attribute.Key("accrual.retry_count")
+)
+
+// Tracer defines attributes of a struct available to its methods.
+type Tracer struct {
+	ctx      context.Context
+	cfg      *config.TracingConfig
+	log      *zerolog.Logger
+	wg       *sync.WaitGroup
+	provider *sdktrace.TracerProvider
+}
+
+// InitTracer initializes a tracer provider exporting spans in batches to the configured OTLP/HTTP collector.
+func InitTracer(ctx context.Context, cfg *config.TracingConfig, log *zerolog.Logger, wg *sync.WaitGroup) (*Tracer, error) {
+	exporter, err := otlptracehttp.New(ctx,
+		otlptracehttp.WithEndpointURL(strings.TrimSuffix(cfg.Endpoint, "/")+"/v1/traces"),
+		otlptracehttp.WithTimeout(cfg.Timeout),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not initialize OTLP exporter: %w", err)
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(semconv.ServiceName(cfg.ServiceName)))
+	if err != nil {
+		return nil, fmt.Errorf("could not describe tracing resource: %w", err)
+	}
+	// traces started by this service are sampled at the configured ratio, incoming ones keep the caller's decision
+	sampler := sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))
+	processor := sdktrace.NewBatchSpanProcessor(exporter,
+		sdktrace.WithBatchTimeout(cfg.FlushInterval),
+		sdktrace.WithMaxExportBatchSize(cfg.BatchSize),
+		sdktrace.WithMaxQueueSize(cfg.QueueSize),
+		sdktrace.WithExportTimeout(cfg.Timeout),
+	)
+	if cfg.SampleErrors {
+		// unsampled spans are recorded too, so that failed ones can be exported once they end
+		sampler = recordUnsampled{base: sampler}
+		processor = newErrorProcessor(processor)
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sampler),
+		sdktrace.WithSpanProcessor(processor),
+	)
+	return &Tracer{ctx: ctx, cfg: cfg, log: log, wg: wg, provider: provider}, nil
+}
+
+// ListenAndExport registers the tracer provider and the W3C trace context propagator globally, spans still queued
+// upon context cancellation are flushed before the provider is shut down.
+func (t *Tracer) ListenAndExport() {
+	otel.SetTracerProvider(t.provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		t.log.Info().Msg(fmt.Sprintf("started exporting spans to %s", t.cfg.Endpoint))
+		<-t.ctx.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), t.cfg.Timeout)
+		defer cancel()
+		if err := t.provider.Shutdown(ctx); err != nil {
+			t.log.Warn().Err(err).Msg("could not flush spans")
+		}
+		t.log.Info().Msg("stopped exporting spans")
+	}()
+}
+
+// Start starts an internal span as a child of the span in ctx, no span is started if ctx carries none so that
+// background work outside of a traced request is not recorded.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	parent := trace.SpanFromContext(ctx)
+	if !parent.SpanContext().IsValid() {
+		return ctx, parent
+	}
+	return otel.Tracer(InstrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// StartFromTraceParent starts a span continuing the trace described by a traceparent header value, e.g. one stored
+// along with a queued job, a new trace is started if traceparent is empty or malformed.
+func StartFromTraceParent(ctx context.Context, traceparent, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	ctx = propagation.TraceContext{}.Extract(ctx, propagation.MapCarrier{traceParentHeader: traceparent})
+	return otel.Tracer(InstrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// TraceParent returns the traceparent header value propagating the current span of ctx or an empty string.
+func TraceParent(ctx context.Context) string {
+	carrier := propagation.MapCarrier{}
+	propagation.TraceContext{}.Inject(ctx, carrier)
+	return carrier.Get(traceParentHeader)
+}
+
+// RecordError marks the span as failed with the error, nil errors are ignored.
+func RecordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// UserIDHash builds a user ID hash attribute, the hash correlates spans of a user without exposing the ID.
+func UserIDHash(userID string) attribute.KeyValue {
+	sum := sha256.Sum256([]byte(userID))
+	return AttrUserIDHash.String(hex.EncodeToString(sum[:8]))
+}
@@ -0,0 +1,97 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recordSpans registers a tracer provider recording all spans for the duration of a test.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func TestStartWithoutParent(t *testing.T) {
+	recorder := recordSpans(t)
+	ctx, span := Start(context.Background(), "background")
+	span.End()
+	if trace.SpanContextFromContext(ctx).IsValid() || len(recorder.Ended()) != 0 {
+		t.Fatal("a span was started outside of a traced context")
+	}
+}
+
+func TestTraceParentRoundTrip(t *testing.T) {
+	recorder := recordSpans(t)
+	ctx, root := otel.Tracer(InstrumentationName).Start(context.Background(), "request")
+	traceparent := TraceParent(ctx)
+	root.End()
+	if traceparent == "" {
+		t.Fatal("got no traceparent for a traced context")
+	}
+
+	_, job := StartFromTraceParent(context.Background(), traceparent, "job", UserIDHash("user-1"))
+	job.End()
+	ended := recorder.Ended()
+	if len(ended) != 2 {
+		t.Fatalf("got %d spans, want 2", len(ended))
+	}
+	if ended[1].Parent().SpanID() != root.SpanContext().SpanID() || ended[1].SpanContext().TraceID() != root.SpanContext().TraceID() {
+		t.Fatalf("got job span %v with parent %v, want a child of %v", ended[1].SpanContext(), ended[1].Parent(), root.SpanContext())
+	}
+	for _, attr := range ended[1].Attributes() {
+		if attr.Key == AttrUserIDHash && (attr.Value.AsString() == "user-1" || len(attr.Value.AsString()) != 16) {
+			t.Fatalf("got user ID hash %q, want 8 hex encoded bytes", attr.Value.AsString())
+		}
+	}
+}
+
+func TestErrorSampling(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	processor := newErrorProcessor(sdktrace.NewSimpleSpanProcessor(exporter))
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithSampler(recordUnsampled{base: sdktrace.ParentBased(sdktrace.NeverSample())}),
+		sdktrace.WithSpanProcessor(processor),
+	)
+	tracer := provider.Tracer(InstrumentationName)
+
+	// a successful unsampled trace is dropped
+	ctx, root := tracer.Start(context.Background(), "ok")
+	_, child := tracer.Start(ctx, "ok.child")
+	child.End()
+	root.End()
+	if spans := exporter.GetSpans(); len(spans) != 0 {
+		t.Fatalf("got %d exported spans of a successful unsampled trace, want none", len(spans))
+	}
+
+	// a failed span of an unsampled trace is exported along with the local ancestors ending after it
+	ctx, root = tracer.Start(context.Background(), "failed")
+	_, sibling := tracer.Start(ctx, "failed.sibling")
+	sibling.End()
+	_, child = tracer.Start(ctx, "failed.child")
+	RecordError(child, errors.New("accrual service unavailable"))
+	child.End()
+	root.End()
+	var names []string
+	for _, span := range exporter.GetSpans() {
+		if !span.SpanContext.IsSampled() {
+			t.Fatalf("got exported span %s not marked as sampled", span.Name)
+		}
+		names = append(names, span.Name)
+	}
+	if strings.Join(names, ",") != "failed.child,failed" {
+		t.Fatalf("got exported spans %v, want the failed span and its parent", names)
+	}
+	if len(processor.failed) != 0 {
+		t.Fatal("a finished trace is still tracked")
+	}
+}